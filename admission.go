@@ -0,0 +1,75 @@
+package quic
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// admissionInfo gathers the information passed to the Config.AdmitConnection callback.
+func (s *baseServer) admissionInfo(p receivedPacket, hdr *wire.Header, addrValidated bool) *AdmissionInfo {
+	info := &AdmissionInfo{
+		RemoteAddr:        p.remoteAddr,
+		AddressValidated:  addrValidated,
+		ActiveConnections: int(atomic.LoadInt32(&s.numConns)),
+		AcceptQueueLength: int(atomic.LoadInt32(&s.connQueueLen)),
+	}
+	ch, err := peekClientHello(hdr, p.data)
+	if err != nil {
+		s.logger.Debugf("Failed to read the ClientHello from the Initial packet: %s", err)
+		return info
+	}
+	info.ServerName = ch.ServerName
+	info.ALPN = ch.ALPN
+	return info
+}
+
+// peekClientHello decrypts a copy of the first Initial packet sent by a client,
+// and parses the (beginning of the) ClientHello contained in its CRYPTO frames.
+// The packet itself is left untouched, so that it can be processed by the connection afterwards.
+func peekClientHello(hdr *wire.Header, data []byte) (*handshake.ClientHelloSummary, error) {
+	if packetLen := int(hdr.ParsedLen() + hdr.Length); packetLen < len(data) {
+		data = data[:packetLen] // cut off coalesced packets
+	}
+	data = append(make([]byte, 0, len(data)), data...)
+	_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, hdr.Version)
+	extHdr, err := unpackLongHeader(opener, hdr, data, hdr.Version)
+	if err != nil {
+		return nil, err
+	}
+	extHdrLen := extHdr.ParsedLen()
+	pn := opener.DecodePacketNumber(extHdr.PacketNumber, extHdr.PacketNumberLen)
+	payload, err := opener.Open(data[extHdrLen:extHdrLen], data[extHdrLen:], pn, data[:extHdrLen])
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []*wire.CryptoFrame
+	parser := wire.NewFrameParser(false, false, false)
+	for len(payload) > 0 {
+		l, frame, err := parser.ParseNext(payload, protocol.EncryptionInitial, hdr.Version)
+		if err != nil {
+			return nil, err
+		}
+		payload = payload[l:]
+		if f, ok := frame.(*wire.CryptoFrame); ok {
+			frames = append(frames, f)
+		}
+	}
+	// Clients might send CRYPTO frames out of order.
+	// Reassemble the data starting at offset 0, until we hit the first gap.
+	sort.Slice(frames, func(i, j int) bool { return frames[i].Offset < frames[j].Offset })
+	var clientHello []byte
+	for _, f := range frames {
+		if f.Offset > protocol.ByteCount(len(clientHello)) {
+			break
+		}
+		if end := f.Offset + protocol.ByteCount(len(f.Data)); end > protocol.ByteCount(len(clientHello)) {
+			clientHello = append(clientHello, f.Data[protocol.ByteCount(len(clientHello))-f.Offset:]...)
+		}
+	}
+	return handshake.ParseClientHello(clientHello)
+}
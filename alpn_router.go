@@ -0,0 +1,215 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
+)
+
+// An ALPNRouter accepts QUIC connections on a Transport, and dispatches them to listeners
+// registered for the application protocol (ALPN) negotiated in the handshake.
+// This allows serving multiple application protocols (e.g. "h3" and "doq") on a single UDP socket.
+type ALPNRouter struct {
+	server *baseServer
+
+	mutex     sync.Mutex
+	protos    []string // in the order the listeners were registered
+	listeners map[string]*ALPNListener
+	closed    chan struct{}
+	closeErr  error
+}
+
+// ListenALPN starts listening for incoming QUIC connections, and returns an ALPNRouter.
+// Listeners for the different application protocols are registered using ALPNRouter.Listen.
+// The NextProtos field of the tls.Config (or of the tls.Config returned by its GetConfigForClient callback)
+// is ignored, and replaced by the application protocols of the registered listeners.
+// Like for ListenEarly, connections are returned before the handshake completes.
+// There can only be a single listener on any Transport.
+func (t *Transport) ListenALPN(tlsConf *tls.Config, conf *Config) (*ALPNRouter, error) {
+	if tlsConf == nil {
+		return nil, errors.New("quic: tls.Config not set")
+	}
+	r := &ALPNRouter{
+		listeners: make(map[string]*ALPNListener),
+		closed:    make(chan struct{}),
+	}
+	s, err := t.createServer(r.tlsConfig(tlsConf), conf, true)
+	if err != nil {
+		return nil, err
+	}
+	r.server = s
+	go r.run()
+	return r, nil
+}
+
+// tlsConfig returns a tls.Config that only offers the application protocols of the registered listeners.
+func (r *ALPNRouter) tlsConfig(tlsConf *tls.Config) *tls.Config {
+	conf := tlsConf.Clone()
+	conf.NextProtos = nil
+	conf.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+		c := tlsConf
+		if tlsConf.GetConfigForClient != nil {
+			cc, err := tlsConf.GetConfigForClient(info)
+			if err != nil {
+				return nil, err
+			}
+			if cc != nil {
+				c = cc
+			}
+		}
+		c = c.Clone()
+		r.mutex.Lock()
+		c.NextProtos = append([]string(nil), r.protos...)
+		r.mutex.Unlock()
+		return c, nil
+	}
+	return conf
+}
+
+func (r *ALPNRouter) run() {
+	for {
+		conn, err := r.server.accept(context.Background())
+		if err != nil {
+			r.mutex.Lock()
+			r.closeErr = err
+			close(r.closed)
+			r.mutex.Unlock()
+			return
+		}
+		r.route(conn)
+	}
+}
+
+func (r *ALPNRouter) route(conn quicConn) {
+	proto := conn.ConnectionState().TLS.NegotiatedProtocol
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	ln, ok := r.listeners[proto]
+	if !ok {
+		// The listener was closed after the handshake started.
+		conn.closeLocal(&qerr.TransportError{ErrorCode: qerr.ConnectionRefused})
+		return
+	}
+	select {
+	case ln.queue <- conn:
+	default:
+		r.server.logger.Debugf("Rejecting new %s connection. Accept queue full.", proto)
+		conn.closeLocal(&qerr.TransportError{ErrorCode: qerr.ConnectionRefused})
+	}
+}
+
+// Listen registers a listener for the given application protocols.
+// Each application protocol can only be handled by a single listener.
+func (r *ALPNRouter) Listen(protos ...string) (*ALPNListener, error) {
+	if len(protos) == 0 {
+		return nil, errors.New("quic: no application protocols given")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	select {
+	case <-r.closed:
+		return nil, r.closeErr
+	default:
+	}
+	for _, proto := range protos {
+		if _, ok := r.listeners[proto]; ok {
+			return nil, fmt.Errorf("quic: already listening for application protocol %q", proto)
+		}
+	}
+	ln := &ALPNListener{
+		router: r,
+		protos: protos,
+		queue:  make(chan quicConn, protocol.MaxAcceptQueueSize),
+		closed: make(chan struct{}),
+	}
+	for _, proto := range protos {
+		r.listeners[proto] = ln
+		r.protos = append(r.protos, proto)
+	}
+	return ln, nil
+}
+
+func (r *ALPNRouter) removeListener(ln *ALPNListener) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, proto := range ln.protos {
+		delete(r.listeners, proto)
+	}
+	protos := r.protos[:0]
+	for _, proto := range r.protos {
+		if _, ok := r.listeners[proto]; ok {
+			protos = append(protos, proto)
+		}
+	}
+	r.protos = protos
+}
+
+// Addr returns the local network address that the router is listening on.
+func (r *ALPNRouter) Addr() net.Addr {
+	return r.server.Addr()
+}
+
+// Close closes the router and all registered listeners.
+// All active connections will be closed.
+func (r *ALPNRouter) Close() error {
+	err := r.server.Close()
+	<-r.closed
+	return err
+}
+
+// An ALPNListener returns the connections that negotiated one of its application protocols, see ALPNRouter.Listen.
+type ALPNListener struct {
+	router *ALPNRouter
+	protos []string
+
+	queue     chan quicConn
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Accept returns a new connection. It should be called in a loop.
+func (l *ALPNListener) Accept(ctx context.Context) (EarlyConnection, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case conn := <-l.queue:
+		return conn, nil
+	case <-l.closed:
+		return nil, ErrServerClosed
+	case <-l.router.closed:
+		return nil, l.router.closeErr
+	}
+}
+
+// Close unregisters the listener.
+// Connections that were routed to this listener, but haven't been accepted yet, are closed.
+// It doesn't affect the router and other listeners.
+func (l *ALPNListener) Close() error {
+	l.closeOnce.Do(func() {
+		l.router.removeListener(l)
+		close(l.closed)
+		for {
+			select {
+			case conn := <-l.queue:
+				conn.closeLocal(&qerr.TransportError{ErrorCode: qerr.ConnectionRefused})
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Addr returns the local network address that the listener is listening on.
+func (l *ALPNListener) Addr() net.Addr {
+	return l.router.Addr()
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 )
 
 type packetBuffer struct {
@@ -13,6 +14,11 @@ type packetBuffer struct {
 	// It doesn't support concurrent use.
 	// It is > 1 when used for coalesced packet.
 	refCount int
+
+	// allocator is the BufferAllocator that Data was obtained from.
+	// It is nil if Data was obtained from the internal pools.
+	allocator utils.BufferAllocator
+	class     utils.BufferSizeClass
 }
 
 // Split increases the refCount.
@@ -55,6 +61,13 @@ func (b *packetBuffer) Len() protocol.ByteCount { return protocol.ByteCount(len(
 func (b *packetBuffer) Cap() protocol.ByteCount { return protocol.ByteCount(cap(b.Data)) }
 
 func (b *packetBuffer) putBack() {
+	if b.allocator != nil {
+		b.allocator.Put(b.class, b.Data[:0])
+		b.Data = nil
+		b.allocator = nil
+		allocatedBufferPool.Put(b)
+		return
+	}
 	if cap(b.Data) == protocol.MaxPacketBufferSize {
 		bufferPool.Put(b)
 		return
@@ -66,9 +79,14 @@ func (b *packetBuffer) putBack() {
 	panic("putPacketBuffer called with packet of wrong size!")
 }
 
-var bufferPool, largeBufferPool sync.Pool
+// allocatedBufferPool holds packetBuffers without Data.
+// It is used when a custom BufferAllocator is set.
+var bufferPool, largeBufferPool, allocatedBufferPool sync.Pool
 
 func getPacketBuffer() *packetBuffer {
+	if a := utils.GetBufferAllocator(); a != nil {
+		return getAllocatedPacketBuffer(a, utils.BufferSizeClassPacket)
+	}
 	buf := bufferPool.Get().(*packetBuffer)
 	buf.refCount = 1
 	buf.Data = buf.Data[:0]
@@ -76,13 +94,26 @@ func getPacketBuffer() *packetBuffer {
 }
 
 func getLargePacketBuffer() *packetBuffer {
+	if a := utils.GetBufferAllocator(); a != nil {
+		return getAllocatedPacketBuffer(a, utils.BufferSizeClassLargePacket)
+	}
 	buf := largeBufferPool.Get().(*packetBuffer)
 	buf.refCount = 1
 	buf.Data = buf.Data[:0]
 	return buf
 }
 
+func getAllocatedPacketBuffer(a utils.BufferAllocator, class utils.BufferSizeClass) *packetBuffer {
+	buf := allocatedBufferPool.Get().(*packetBuffer)
+	buf.refCount = 1
+	buf.Data = utils.AllocateBuffer(a, class)
+	buf.allocator = a
+	buf.class = class
+	return buf
+}
+
 func init() {
+	allocatedBufferPool.New = func() any { return &packetBuffer{} }
 	bufferPool.New = func() any {
 		return &packetBuffer{Data: make([]byte, 0, protocol.MaxPacketBufferSize)}
 	}
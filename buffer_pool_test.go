@@ -56,4 +56,75 @@ var _ = Describe("Buffer Pool", func() {
 		buf.Decrement()
 		Expect(func() { buf.Decrement() }).To(Panic())
 	})
+
+	Context("using a custom allocator", func() {
+		var gets, puts map[BufferSizeClass]int
+
+		BeforeEach(func() {
+			gets = make(map[BufferSizeClass]int)
+			puts = make(map[BufferSizeClass]int)
+			SetBufferAllocator(&testBufferAllocator{gets: gets, puts: puts})
+		})
+
+		AfterEach(func() { SetBufferAllocator(nil) })
+
+		It("gets buffers from the allocator", func() {
+			buf1 := getPacketBuffer()
+			Expect(buf1.Data).To(BeEmpty())
+			Expect(buf1.Data).To(HaveCap(protocol.MaxPacketBufferSize))
+			buf2 := getLargePacketBuffer()
+			Expect(buf2.Data).To(HaveCap(protocol.MaxLargePacketBufferSize))
+			Expect(gets).To(Equal(map[BufferSizeClass]int{BufferSizeClassPacket: 1, BufferSizeClassLargePacket: 1}))
+			buf1.Release()
+			buf2.Release()
+			Expect(puts).To(Equal(map[BufferSizeClass]int{BufferSizeClassPacket: 1, BufferSizeClassLargePacket: 1}))
+		})
+
+		It("returns buffers only once all parts have been released", func() {
+			buf := getPacketBuffer()
+			buf.Split()
+			buf.Decrement()
+			buf.MaybeRelease()
+			Expect(puts).To(BeEmpty())
+			buf.Decrement()
+			buf.MaybeRelease()
+			Expect(puts).To(HaveKeyWithValue(BufferSizeClassPacket, 1))
+		})
+
+		It("returns buffers to the allocator they were obtained from", func() {
+			buf := getPacketBuffer()
+			SetBufferAllocator(nil)
+			buf.Release()
+			Expect(puts).To(HaveKeyWithValue(BufferSizeClassPacket, 1))
+		})
+
+		It("panics if the allocator returns buffers that are too small", func() {
+			SetBufferAllocator(&testBufferAllocator{size: 100})
+			Expect(func() { getPacketBuffer() }).To(Panic())
+		})
+	})
 })
+
+type testBufferAllocator struct {
+	size       int
+	gets, puts map[BufferSizeClass]int
+}
+
+var _ BufferAllocator = &testBufferAllocator{}
+
+func (a *testBufferAllocator) Get(class BufferSizeClass) []byte {
+	if a.gets != nil {
+		a.gets[class]++
+	}
+	size := a.size
+	if size == 0 {
+		size = class.Size()
+	}
+	return make([]byte, 0, size)
+}
+
+func (a *testBufferAllocator) Put(class BufferSizeClass, _ []byte) {
+	if a.puts != nil {
+		a.puts[class]++
+	}
+}
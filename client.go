@@ -26,9 +26,9 @@ type client struct {
 	srcConnID       protocol.ConnectionID
 	destConnID      protocol.ConnectionID
 
-	initialPacketNumber  protocol.PacketNumber
-	hasNegotiatedVersion bool
-	version              protocol.VersionNumber
+	initialPacketNumber protocol.PacketNumber
+	serverVersions      []protocol.VersionNumber // set when a Version Negotiation packet was received
+	version             protocol.VersionNumber
 
 	handshakeChan chan struct{}
 
@@ -59,7 +59,7 @@ func DialAddr(ctx context.Context, addr string, tlsConf *tls.Config, conf *Confi
 	if err != nil {
 		return nil, err
 	}
-	return tr.dial(ctx, udpAddr, addr, tlsConf, conf, false)
+	return tr.dial(ctx, udpAddr, addr, tlsConf, conf, false, protocol.ConnectionID{}, packetInfo{})
 }
 
 // DialAddrEarly establishes a new 0-RTT QUIC connection to a server.
@@ -77,7 +77,7 @@ func DialAddrEarly(ctx context.Context, addr string, tlsConf *tls.Config, conf *
 	if err != nil {
 		return nil, err
 	}
-	conn, err := tr.dial(ctx, udpAddr, addr, tlsConf, conf, true)
+	conn, err := tr.dial(ctx, udpAddr, addr, tlsConf, conf, true, protocol.ConnectionID{}, packetInfo{})
 	if err != nil {
 		tr.Close()
 		return nil, err
@@ -137,21 +137,29 @@ func dial(
 	ctx context.Context,
 	conn sendConn,
 	connIDGenerator ConnectionIDGenerator,
+	destConnID protocol.ConnectionID,
 	packetHandlers packetHandlerManager,
 	tlsConf *tls.Config,
 	config *Config,
 	onClose func(),
 	use0RTT bool,
+	logger utils.Logger,
 ) (quicConn, error) {
-	c, err := newClient(conn, connIDGenerator, config, tlsConf, onClose, use0RTT)
+	c, err := newClient(conn, connIDGenerator, config, tlsConf, onClose, use0RTT, logger)
 	if err != nil {
 		return nil, err
 	}
 	c.packetHandlers = packetHandlers
+	if destConnID.Len() > 0 {
+		c.destConnID = destConnID
+	}
+	c.logger = connectionLogger(c.logger, c.config, c.destConnID, c.sendConn.RemoteAddr())
 
 	c.tracingID = nextConnTracingID()
 	if c.config.Tracer != nil {
-		c.tracer = c.config.Tracer(context.WithValue(ctx, ConnectionTracingKey, c.tracingID), protocol.PerspectiveClient, c.destConnID)
+		tracerCtx := context.WithValue(ctx, ConnectionTracingKey, c.tracingID)
+		tracerCtx = context.WithValue(tracerCtx, RemoteAddrContextKey, c.sendConn.RemoteAddr())
+		c.tracer = c.config.Tracer(tracerCtx, protocol.PerspectiveClient, c.destConnID)
 	}
 	if c.tracer != nil && c.tracer.StartedConnection != nil {
 		c.tracer.StartedConnection(c.sendConn.LocalAddr(), c.sendConn.RemoteAddr(), c.srcConnID, c.destConnID)
@@ -162,7 +170,7 @@ func dial(
 	return c.conn, nil
 }
 
-func newClient(sendConn sendConn, connIDGenerator ConnectionIDGenerator, config *Config, tlsConf *tls.Config, onClose func(), use0RTT bool) (*client, error) {
+func newClient(sendConn sendConn, connIDGenerator ConnectionIDGenerator, config *Config, tlsConf *tls.Config, onClose func(), use0RTT bool, logger utils.Logger) (*client, error) {
 	srcConnID, err := connIDGenerator.GenerateConnectionID()
 	if err != nil {
 		return nil, err
@@ -182,7 +190,7 @@ func newClient(sendConn sendConn, connIDGenerator ConnectionIDGenerator, config
 		config:          config,
 		version:         config.Versions[0],
 		handshakeChan:   make(chan struct{}),
-		logger:          utils.DefaultLogger.WithPrefix("client"),
+		logger:          logger.WithPrefix("client"),
 	}
 	return c, nil
 }
@@ -200,7 +208,7 @@ func (c *client) dial(ctx context.Context) error {
 		c.tlsConf,
 		c.initialPacketNumber,
 		c.use0RTT,
-		c.hasNegotiatedVersion,
+		c.serverVersions,
 		c.tracer,
 		c.tracingID,
 		c.logger,
@@ -239,7 +247,7 @@ func (c *client) dial(ctx context.Context) error {
 	case recreateErr := <-recreateChan:
 		c.initialPacketNumber = recreateErr.nextPacketNumber
 		c.version = recreateErr.nextVersion
-		c.hasNegotiatedVersion = true
+		c.serverVersions = recreateErr.serverVersions
 		return c.dial(ctx)
 	case <-earlyConnChan:
 		// ready to send 0-RTT data
@@ -42,7 +42,7 @@ var _ = Describe("Client", func() {
 			tlsConf *tls.Config,
 			initialPacketNumber protocol.PacketNumber,
 			enable0RTT bool,
-			hasNegotiatedVersion bool,
+			serverVersions []protocol.VersionNumber,
 			tracer *logging.ConnectionTracer,
 			tracingID uint64,
 			logger utils.Logger,
@@ -121,7 +121,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				enable0RTT bool,
-				_ bool,
+				_ []protocol.VersionNumber,
 				_ *logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -135,7 +135,7 @@ var _ = Describe("Client", func() {
 				conn.EXPECT().HandshakeComplete().Return(c)
 				return conn
 			}
-			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, nil, false)
+			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, nil, false, utils.DefaultLogger)
 			Expect(err).ToNot(HaveOccurred())
 			cl.packetHandlers = manager
 			Expect(cl).ToNot(BeNil())
@@ -158,7 +158,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				enable0RTT bool,
-				_ bool,
+				_ []protocol.VersionNumber,
 				_ *logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -172,7 +172,7 @@ var _ = Describe("Client", func() {
 				return conn
 			}
 
-			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, nil, true)
+			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, nil, true, utils.DefaultLogger)
 			Expect(err).ToNot(HaveOccurred())
 			cl.packetHandlers = manager
 			Expect(cl).ToNot(BeNil())
@@ -195,7 +195,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ bool,
-				_ bool,
+				_ []protocol.VersionNumber,
 				_ *logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -208,7 +208,7 @@ var _ = Describe("Client", func() {
 				return conn
 			}
 			var closed bool
-			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, func() { closed = true }, true)
+			cl, err := newClient(packetConn, &protocol.DefaultConnectionIDGenerator{}, populateConfig(config), tlsConf, func() { closed = true }, true, utils.DefaultLogger)
 			Expect(err).ToNot(HaveOccurred())
 			cl.packetHandlers = manager
 			Expect(cl).ToNot(BeNil())
@@ -280,7 +280,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				_ protocol.PacketNumber,
 				_ bool,
-				_ bool,
+				_ []protocol.VersionNumber,
 				_ *logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -323,7 +323,7 @@ var _ = Describe("Client", func() {
 				_ *tls.Config,
 				pn protocol.PacketNumber,
 				_ bool,
-				hasNegotiatedVersion bool,
+				serverVersions []protocol.VersionNumber,
 				_ *logging.ConnectionTracer,
 				_ uint64,
 				_ utils.Logger,
@@ -333,17 +333,18 @@ var _ = Describe("Client", func() {
 				conn.EXPECT().HandshakeComplete().Return(make(chan struct{}))
 				if counter == 0 {
 					Expect(pn).To(BeZero())
-					Expect(hasNegotiatedVersion).To(BeFalse())
+					Expect(serverVersions).To(BeEmpty())
 					conn.EXPECT().run().DoAndReturn(func() error {
 						runner.Remove(connID)
 						return &errCloseForRecreating{
 							nextPacketNumber: 109,
 							nextVersion:      789,
+							serverVersions:   []protocol.VersionNumber{789, 1337},
 						}
 					})
 				} else {
 					Expect(pn).To(Equal(protocol.PacketNumber(109)))
-					Expect(hasNegotiatedVersion).To(BeTrue())
+					Expect(serverVersions).To(Equal([]protocol.VersionNumber{789, 1337}))
 					conn.EXPECT().run()
 					conn.EXPECT().destroy(gomock.Any())
 				}
@@ -19,6 +19,12 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return 2 * c.HandshakeIdleTimeout
 }
 
+// maxAckDelay is the max_ack_delay advertised to the peer.
+// Coalescing timers delays ACKs by up to the timer granularity.
+func (c *Config) maxAckDelay() time.Duration {
+	return protocol.MaxAckDelayInclGranularity + c.TimerGranularity
+}
+
 func (c *Config) maxRetryTokenAge() time.Duration {
 	return c.handshakeTimeout()
 }
@@ -40,6 +46,45 @@ func validateConfig(config *Config) error {
 	if config.MaxConnectionReceiveWindow > quicvarint.Max {
 		config.MaxConnectionReceiveWindow = quicvarint.Max
 	}
+	// The timer granularity is added to the max_ack_delay, which has an upper bound (see section 18.2 of RFC 9000).
+	if config.TimerGranularity < 0 || config.TimerGranularity > protocol.MaxMaxAckDelay-protocol.MaxAckDelayInclGranularity {
+		return fmt.Errorf("invalid timer granularity: %s", config.TimerGranularity)
+	}
+	if config.MaxPacketsPerLoopIteration < 0 {
+		return fmt.Errorf("invalid maximum number of packets per loop iteration: %d", config.MaxPacketsPerLoopIteration)
+	}
+	if config.InitialRTT < 0 {
+		return fmt.Errorf("invalid initial RTT: %s", config.InitialRTT)
+	}
+	if config.Max0RTTStreams < 0 {
+		return fmt.Errorf("invalid maximum number of 0-RTT streams: %d", config.Max0RTTStreams)
+	}
+	// The default value of the transport parameter is omitted when sending the transport parameters,
+	// which old quic-go versions interpret as 0, see https://github.com/quic-go/quic-go/pull/3806.
+	if config.ActiveConnectionIDLimit != 0 && config.ActiveConnectionIDLimit <= protocol.DefaultActiveConnectionIDLimit {
+		return fmt.Errorf("invalid active connection ID limit: %d (minimum 3)", config.ActiveConnectionIDLimit)
+	}
+	if config.ActiveConnectionIDLimit > protocol.MaxActiveConnectionIDLimit {
+		config.ActiveConnectionIDLimit = protocol.MaxActiveConnectionIDLimit
+	}
+	if config.AddressDiscovery > AddressDiscoveryProvideAndReceive {
+		return fmt.Errorf("invalid address discovery mode: %d", config.AddressDiscovery)
+	}
+	if config.ConnectionIDRetirement > RetireAllConnectionIDs {
+		return fmt.Errorf("invalid connection ID retirement: %d", config.ConnectionIDRetirement)
+	}
+	if config.MaxAckRanges < 0 {
+		return fmt.Errorf("invalid maximum number of ACK ranges: %d", config.MaxAckRanges)
+	}
+	if config.MaxAckRanges > protocol.MaxNumAckRangesLimit {
+		config.MaxAckRanges = protocol.MaxNumAckRangesLimit
+	}
+	if config.RetransmissionPolicy > RetransmitDeadlineAware {
+		return fmt.Errorf("invalid retransmission policy: %d", config.RetransmissionPolicy)
+	}
+	if err := validateExtensionFrames(config.ExtensionFrames); err != nil {
+		return err
+	}
 	// check that all QUIC versions are actually supported
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
@@ -105,6 +150,28 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	activeConnIDLimit := config.ActiveConnectionIDLimit
+	if activeConnIDLimit == 0 {
+		activeConnIDLimit = protocol.MaxActiveConnectionIDs
+	}
+	retransmissionDeadline := config.RetransmissionDeadline
+	if retransmissionDeadline == 0 {
+		retransmissionDeadline = protocol.DefaultRetransmissionDeadline
+	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges == 0 {
+		maxAckRanges = protocol.MaxNumAckRanges
+	}
+	maxIssuedConnIDs := config.MaxIssuedConnectionIDs
+	if maxIssuedConnIDs == 0 {
+		maxIssuedConnIDs = protocol.MaxIssuedConnectionIDs
+	} else if maxIssuedConnIDs < 0 {
+		maxIssuedConnIDs = 1
+	}
+	timerGranularity := config.TimerGranularity
+	if timerGranularity == 0 && config.LowPowerMode {
+		timerGranularity = protocol.LowPowerTimerGranularity
+	}
 
 	return &Config{
 		GetConfigForClient:             config.GetConfigForClient,
@@ -112,6 +179,10 @@ func populateConfig(config *Config) *Config {
 		HandshakeIdleTimeout:           handshakeIdleTimeout,
 		MaxIdleTimeout:                 idleTimeout,
 		RequireAddressValidation:       config.RequireAddressValidation,
+		AdmitConnection:                config.AdmitConnection,
+		ValidateExternalRetryToken:     config.ValidateExternalRetryToken,
+		ChooseVersion:                  config.ChooseVersion,
+		SentVersionNegotiation:         config.SentVersionNegotiation,
 		KeepAlivePeriod:                config.KeepAlivePeriod,
 		InitialStreamReceiveWindow:     initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:         maxStreamReceiveWindow,
@@ -121,9 +192,39 @@ func populateConfig(config *Config) *Config {
 		MaxIncomingStreams:             maxIncomingStreams,
 		MaxIncomingUniStreams:          maxIncomingUniStreams,
 		TokenStore:                     config.TokenStore,
+		InitialRTT:                     config.InitialRTT,
 		EnableDatagrams:                config.EnableDatagrams,
+		EnableImmediateAck:             config.EnableImmediateAck,
+		AddressDiscovery:               config.AddressDiscovery,
+		ExtensionFrames:                config.ExtensionFrames,
+		ActiveConnectionIDLimit:        activeConnIDLimit,
+		MaxIssuedConnectionIDs:         maxIssuedConnIDs,
+		ConnectionIDRotationInterval:   config.ConnectionIDRotationInterval,
+		ConnectionIDRetirement:         config.ConnectionIDRetirement,
+		ConnectionIDRetired:            config.ConnectionIDRetired,
 		DisablePathMTUDiscovery:        config.DisablePathMTUDiscovery,
 		Allow0RTT:                      config.Allow0RTT,
+		Max0RTTData:                    config.Max0RTTData,
+		Max0RTTStreams:                 config.Max0RTTStreams,
 		Tracer:                         config.Tracer,
+		Clock:                          config.Clock,
+		Logger:                         config.Logger,
+		DecryptionWorkers:              config.DecryptionWorkers,
+		HandshakeWorkerPool:            config.HandshakeWorkerPool,
+		SingleGoroutine:                config.SingleGoroutine,
+		TimerGranularity:               timerGranularity,
+		LowPowerMode:                   config.LowPowerMode,
+		MaxPacketsPerLoopIteration:     config.MaxPacketsPerLoopIteration,
+		AdaptivePacketBatching:         config.AdaptivePacketBatching,
+		SendRateLimit:                  config.SendRateLimit,
+		SendRateLimitBurst:             config.SendRateLimitBurst,
+		PersistentCongestionThreshold:  config.PersistentCongestionThreshold,
+		PersistentCongestionWindow:     config.PersistentCongestionWindow,
+		MaxAckRanges:                   maxAckRanges,
+		KeyUpdateInterval:              config.KeyUpdateInterval,
+		KeyUpdateIntervalBytes:         config.KeyUpdateIntervalBytes,
+		RetransmissionPolicy:           config.RetransmissionPolicy,
+		RetransmissionDeadline:         retransmissionDeadline,
+		PaddingPolicy:                  config.PaddingPolicy,
 	}
 }
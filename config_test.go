@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/logging"
 	"github.com/quic-go/quic-go/quicvarint"
 
@@ -16,6 +17,12 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
 var _ = Describe("Config", func() {
 	Context("validating", func() {
 		It("validates a nil config", func() {
@@ -51,6 +58,65 @@ var _ = Describe("Config", func() {
 			Expect(conf.MaxStreamReceiveWindow).To(BeEquivalentTo(uint64(quicvarint.Max)))
 			Expect(conf.MaxConnectionReceiveWindow).To(BeEquivalentTo(uint64(quicvarint.Max)))
 		})
+
+		It("errors on invalid address discovery modes", func() {
+			Expect(validateConfig(&Config{AddressDiscovery: AddressDiscoveryProvideAndReceive})).To(Succeed())
+			Expect(validateConfig(&Config{AddressDiscovery: 42})).To(MatchError("invalid address discovery mode: 42"))
+		})
+
+		It("validates the active connection ID limit", func() {
+			Expect(validateConfig(&Config{ActiveConnectionIDLimit: 3})).To(Succeed())
+			Expect(validateConfig(&Config{ActiveConnectionIDLimit: 2})).To(MatchError("invalid active connection ID limit: 2 (minimum 3)"))
+			Expect(validateConfig(&Config{ActiveConnectionIDLimit: 1})).To(MatchError("invalid active connection ID limit: 1 (minimum 3)"))
+			conf := &Config{ActiveConnectionIDLimit: 1000}
+			Expect(validateConfig(conf)).To(Succeed())
+			Expect(conf.ActiveConnectionIDLimit).To(BeEquivalentTo(protocol.MaxActiveConnectionIDLimit))
+		})
+
+		It("validates the extension frames", func() {
+			encode := func(b []byte, _ any) ([]byte, error) { return b, nil }
+			decode := func([]byte) (any, int, error) { return nil, 0, nil }
+			Expect(validateConfig(&Config{ExtensionFrames: []ExtensionFrame{
+				{Type: 0x42, TransportParameter: 0x1337, Encode: encode, Decode: decode},
+			}})).To(Succeed())
+			Expect(validateConfig(&Config{ExtensionFrames: []ExtensionFrame{
+				{Type: 0x42, TransportParameter: 0x1337},
+			}})).To(MatchError("extension frame type 0x42 requires Encode and Decode callbacks"))
+		})
+
+		It("errors on a negative number of 0-RTT streams", func() {
+			Expect(validateConfig(&Config{Max0RTTStreams: 10})).To(Succeed())
+			Expect(validateConfig(&Config{Max0RTTStreams: -1})).To(MatchError("invalid maximum number of 0-RTT streams: -1"))
+		})
+
+		It("errors on invalid run loop options", func() {
+			Expect(validateConfig(&Config{TimerGranularity: 10 * time.Millisecond, MaxPacketsPerLoopIteration: 10})).To(Succeed())
+			Expect(validateConfig(&Config{TimerGranularity: -time.Millisecond})).To(MatchError("invalid timer granularity: -1ms"))
+			maxGranularity := protocol.MaxMaxAckDelay - protocol.MaxAckDelayInclGranularity
+			Expect(validateConfig(&Config{TimerGranularity: maxGranularity})).To(Succeed())
+			Expect(populateConfig(&Config{TimerGranularity: maxGranularity}).maxAckDelay()).To(Equal(protocol.MaxMaxAckDelay))
+			Expect(validateConfig(&Config{TimerGranularity: maxGranularity + time.Millisecond})).To(MatchError(ContainSubstring("invalid timer granularity")))
+			Expect(validateConfig(&Config{MaxPacketsPerLoopIteration: -1})).To(MatchError("invalid maximum number of packets per loop iteration: -1"))
+		})
+
+		It("errors on a negative initial RTT", func() {
+			Expect(validateConfig(&Config{InitialRTT: 10 * time.Millisecond})).To(Succeed())
+			Expect(validateConfig(&Config{InitialRTT: -time.Millisecond})).To(MatchError("invalid initial RTT: -1ms"))
+		})
+
+		It("validates the maximum number of ACK ranges", func() {
+			Expect(validateConfig(&Config{MaxAckRanges: -1})).To(MatchError("invalid maximum number of ACK ranges: -1"))
+			c := &Config{MaxAckRanges: 1000}
+			Expect(validateConfig(c)).To(Succeed())
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRangesLimit))
+		})
+
+		It("errors on invalid connection ID retirement values", func() {
+			Expect(validateConfig(&Config{ConnectionIDRetirement: RetireAllConnectionIDs})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDRetirement: 42})).To(MatchError("invalid connection ID retirement: 42"))
+			Expect(validateConfig(&Config{RetransmissionPolicy: RetransmitDeadlineAware})).To(Succeed())
+			Expect(validateConfig(&Config{RetransmissionPolicy: 42})).To(MatchError("invalid retransmission policy: 42"))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -66,7 +132,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer":
+			case "GetConfigForClient", "RequireAddressValidation", "AdmitConnection", "ValidateExternalRetryToken", "ChooseVersion", "SentVersionNegotiation", "GetLogWriter", "AllowConnectionWindowIncrease", "ConnectionIDRetired", "Tracer":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -80,6 +146,12 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(time.Hour))
 			case "TokenStore":
 				f.Set(reflect.ValueOf(NewLRUTokenStore(2, 3)))
+			case "InitialRTT":
+				f.Set(reflect.ValueOf(42 * time.Millisecond))
+			case "TimerGranularity":
+				f.Set(reflect.ValueOf(5 * time.Millisecond))
+			case "MaxPacketsPerLoopIteration":
+				f.Set(reflect.ValueOf(64))
 			case "InitialStreamReceiveWindow":
 				f.Set(reflect.ValueOf(uint64(1234)))
 			case "MaxStreamReceiveWindow":
@@ -96,14 +168,60 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(&StatelessResetKey{1, 2, 3, 4}))
 			case "KeepAlivePeriod":
 				f.Set(reflect.ValueOf(time.Second))
-			case "EnableDatagrams":
+			case "EnableDatagrams", "EnableImmediateAck":
 				f.Set(reflect.ValueOf(true))
+			case "AddressDiscovery":
+				f.Set(reflect.ValueOf(AddressDiscoveryProvideAndReceive))
+			case "ExtensionFrames":
+				f.Set(reflect.ValueOf([]ExtensionFrame{{Type: 0x42, TransportParameter: 0x1337, AckEliciting: true}}))
+			case "ActiveConnectionIDLimit":
+				f.Set(reflect.ValueOf(uint64(10)))
+			case "MaxIssuedConnectionIDs":
+				f.Set(reflect.ValueOf(3))
+			case "ConnectionIDRotationInterval":
+				f.Set(reflect.ValueOf(time.Minute))
+			case "ConnectionIDRetirement":
+				f.Set(reflect.ValueOf(RetireAllConnectionIDs))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
 			case "Allow0RTT":
 				f.Set(reflect.ValueOf(true))
+			case "Max0RTTData":
+				f.Set(reflect.ValueOf(uint64(1 << 16)))
+			case "Max0RTTStreams":
+				f.Set(reflect.ValueOf(int64(10)))
+			case "Clock":
+				f.Set(reflect.ValueOf(utils.DefaultClock{}))
+			case "Logger":
+				f.Set(reflect.ValueOf(nopLogger{}))
+			case "DecryptionWorkers":
+				f.Set(reflect.ValueOf(4))
+			case "HandshakeWorkerPool":
+				f.Set(reflect.ValueOf(NewHandshakeWorkerPool(2)))
+			case "SingleGoroutine", "AdaptivePacketBatching", "LowPowerMode":
+				f.Set(reflect.ValueOf(true))
+			case "SendRateLimit":
+				f.Set(reflect.ValueOf(uint64(1 << 20)))
+			case "SendRateLimitBurst":
+				f.Set(reflect.ValueOf(uint64(1 << 16)))
+			case "PersistentCongestionThreshold":
+				f.Set(reflect.ValueOf(uint32(5)))
+			case "PersistentCongestionWindow":
+				f.Set(reflect.ValueOf(uint32(100)))
+			case "MaxAckRanges":
+				f.Set(reflect.ValueOf(64))
+			case "KeyUpdateInterval":
+				f.Set(reflect.ValueOf(uint64(1000)))
+			case "KeyUpdateIntervalBytes":
+				f.Set(reflect.ValueOf(uint64(1 << 20)))
+			case "RetransmissionPolicy":
+				f.Set(reflect.ValueOf(RetransmitDeadlineAware))
+			case "RetransmissionDeadline":
+				f.Set(reflect.ValueOf(time.Second))
+			case "PaddingPolicy":
+				f.Set(reflect.ValueOf(PadToFixedSizes(1000)))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -118,11 +236,25 @@ var _ = Describe("Config", func() {
 
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAddrValidation, calledAllowConnectionWindowIncrease, calledTracer bool
+			var calledAddrValidation, calledAdmitConnection, calledValidateToken, calledChooseVersion, calledSentVersionNegotiation, calledAllowConnectionWindowIncrease, calledConnectionIDRetired, calledTracer bool
 			c1 := &Config{
 				GetConfigForClient:            func(info *ClientHelloInfo) (*Config, error) { return nil, errors.New("nope") },
 				AllowConnectionWindowIncrease: func(Connection, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
+				ConnectionIDRetired:           func(Connection, RetiredConnectionIDInfo) { calledConnectionIDRetired = true },
 				RequireAddressValidation:      func(net.Addr) bool { calledAddrValidation = true; return true },
+				AdmitConnection: func(*AdmissionInfo) AdmissionDecision {
+					calledAdmitConnection = true
+					return AdmissionDecision{}
+				},
+				ValidateExternalRetryToken: func([]byte, net.Addr) (ConnectionID, bool) {
+					calledValidateToken = true
+					return ConnectionID{}, false
+				},
+				ChooseVersion: func(*VersionNegotiationInfo) (VersionNumber, bool) {
+					calledChooseVersion = true
+					return 0, false
+				},
+				SentVersionNegotiation: func(*VersionNegotiationInfo) { calledSentVersionNegotiation = true },
 				Tracer: func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer {
 					calledTracer = true
 					return nil
@@ -131,8 +263,18 @@ var _ = Describe("Config", func() {
 			c2 := c1.Clone()
 			c2.RequireAddressValidation(&net.UDPAddr{})
 			Expect(calledAddrValidation).To(BeTrue())
+			c2.AdmitConnection(&AdmissionInfo{})
+			Expect(calledAdmitConnection).To(BeTrue())
+			c2.ValidateExternalRetryToken(nil, &net.UDPAddr{})
+			Expect(calledValidateToken).To(BeTrue())
+			c2.ChooseVersion(&VersionNegotiationInfo{})
+			Expect(calledChooseVersion).To(BeTrue())
+			c2.SentVersionNegotiation(&VersionNegotiationInfo{})
+			Expect(calledSentVersionNegotiation).To(BeTrue())
 			c2.AllowConnectionWindowIncrease(nil, 1234)
 			Expect(calledAllowConnectionWindowIncrease).To(BeTrue())
+			c2.ConnectionIDRetired(nil, RetiredConnectionIDInfo{})
+			Expect(calledConnectionIDRetired).To(BeTrue())
 			_, err := c2.GetConfigForClient(&ClientHelloInfo{})
 			Expect(err).To(MatchError("nope"))
 			c2.Tracer(context.Background(), logging.PerspectiveClient, protocol.ConnectionID{})
@@ -160,12 +302,18 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAddrValidation bool
+			var calledAddrValidation, calledAdmitConnection, calledValidateToken bool
 			c1 := &Config{}
 			c1.RequireAddressValidation = func(net.Addr) bool { calledAddrValidation = true; return true }
+			c1.AdmitConnection = func(*AdmissionInfo) AdmissionDecision { calledAdmitConnection = true; return AdmissionDecision{} }
+			c1.ValidateExternalRetryToken = func([]byte, net.Addr) (ConnectionID, bool) { calledValidateToken = true; return ConnectionID{}, false }
 			c2 := populateConfig(c1)
 			c2.RequireAddressValidation(&net.UDPAddr{})
 			Expect(calledAddrValidation).To(BeTrue())
+			c2.AdmitConnection(&AdmissionInfo{})
+			Expect(calledAdmitConnection).To(BeTrue())
+			c2.ValidateExternalRetryToken(nil, &net.UDPAddr{})
+			Expect(calledValidateToken).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -185,6 +333,28 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
 			Expect(c.GetConfigForClient).To(BeNil())
+			Expect(c.MaxIssuedConnectionIDs).To(Equal(protocol.MaxIssuedConnectionIDs))
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+			Expect(c.ActiveConnectionIDLimit).To(BeEquivalentTo(protocol.MaxActiveConnectionIDs))
+			Expect(c.RetransmissionPolicy).To(Equal(RetransmitFirst))
+			Expect(c.RetransmissionDeadline).To(Equal(protocol.DefaultRetransmissionDeadline))
+		})
+
+		It("coalesces timers in low-power mode", func() {
+			Expect(populateConfig(&Config{}).TimerGranularity).To(BeZero())
+			Expect(populateConfig(&Config{LowPowerMode: true}).TimerGranularity).To(Equal(protocol.LowPowerTimerGranularity))
+			Expect(populateConfig(&Config{LowPowerMode: true, TimerGranularity: 5 * time.Millisecond}).TimerGranularity).To(Equal(5 * time.Millisecond))
+		})
+
+		It("includes the timer granularity in the max_ack_delay", func() {
+			Expect(populateConfig(&Config{}).maxAckDelay()).To(Equal(protocol.MaxAckDelayInclGranularity))
+			Expect(populateConfig(&Config{LowPowerMode: true}).maxAckDelay()).To(Equal(protocol.MaxAckDelayInclGranularity + protocol.LowPowerTimerGranularity))
+			Expect(populateConfig(&Config{TimerGranularity: 10 * time.Millisecond}).maxAckDelay()).To(Equal(protocol.MaxAckDelayInclGranularity + 10*time.Millisecond))
+		})
+
+		It("only uses a single connection ID, if the number of issued connection IDs is set to a negative value", func() {
+			c := populateConfig(&Config{MaxIssuedConnectionIDs: -1})
+			Expect(c.MaxIssuedConnectionIDs).To(Equal(1))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -1,17 +1,36 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
 )
 
 type connIDGenerator struct {
 	generator  ConnectionIDGenerator
 	highestSeq uint64
+	// The peer is asked to retire all connection IDs with a sequence number smaller than this value.
+	retirePriorTo uint64
+	// The number of connection IDs (including those with a sequence number smaller than retirePriorTo)
+	// that we keep active at the same time.
+	// This is the minimum of the configured limit and the peer's active_connection_id_limit.
+	maxActive uint64
+
+	maxIssued        uint64
+	rotationInterval time.Duration
+	retirement       ConnectionIDRetirement
+	nextRotation     time.Time
+
+	// Set if the generator is a RotatingConnectionIDGenerator.
+	rotatingGenerator RotatingConnectionIDGenerator
+	// The epoch of the connection IDs that the peer is not asked to retire.
+	epoch uint64
 
 	activeSrcConnIDs        map[uint64]protocol.ConnectionID
 	initialClientDestConnID *protocol.ConnectionID // nil for the client
@@ -22,6 +41,10 @@ type connIDGenerator struct {
 	retireConnectionID     func(protocol.ConnectionID)
 	replaceWithClosed      func([]protocol.ConnectionID, protocol.Perspective, []byte)
 	queueControlFrame      func(wire.Frame)
+	// called when the peer retired one of our connection IDs
+	retiredByPeer func(seq uint64, connID protocol.ConnectionID)
+
+	tracer *logging.ConnectionTracer
 }
 
 func newConnIDGenerator(
@@ -33,10 +56,22 @@ func newConnIDGenerator(
 	retireConnectionID func(protocol.ConnectionID),
 	replaceWithClosed func([]protocol.ConnectionID, protocol.Perspective, []byte),
 	queueControlFrame func(wire.Frame),
+	retiredByPeer func(seq uint64, connID protocol.ConnectionID),
 	generator ConnectionIDGenerator,
+	maxIssued int,
+	rotationInterval time.Duration,
+	retirement ConnectionIDRetirement,
+	tracer *logging.ConnectionTracer,
 ) *connIDGenerator {
+	if maxIssued < 1 {
+		maxIssued = 1
+	}
 	m := &connIDGenerator{
 		generator:              generator,
+		maxIssued:              uint64(maxIssued),
+		rotationInterval:       rotationInterval,
+		retirement:             retirement,
+		tracer:                 tracer,
 		activeSrcConnIDs:       make(map[uint64]protocol.ConnectionID),
 		addConnectionID:        addConnectionID,
 		getStatelessResetToken: getStatelessResetToken,
@@ -44,6 +79,11 @@ func newConnIDGenerator(
 		retireConnectionID:     retireConnectionID,
 		replaceWithClosed:      replaceWithClosed,
 		queueControlFrame:      queueControlFrame,
+		retiredByPeer:          retiredByPeer,
+	}
+	if g, ok := generator.(RotatingConnectionIDGenerator); ok {
+		m.rotatingGenerator = g
+		m.epoch = g.Epoch()
 	}
 	m.activeSrcConnIDs[0] = initialConnectionID
 	m.initialClientDestConnID = initialClientDestConnID
@@ -58,9 +98,16 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	// connection IDs the peer will store. This limit includes the connection ID
 	// used during the handshake, and the one sent in the preferred_address
 	// transport parameter.
-	// We currently don't send the preferred_address transport parameter,
-	// so we can issue (limit - 1) connection IDs.
-	for i := uint64(len(m.activeSrcConnIDs)); i < utils.Min(limit, protocol.MaxIssuedConnectionIDs); i++ {
+	// If we sent the preferred_address transport parameter, its connection ID is
+	// counted by numUnretired, just like all other connection IDs.
+	m.maxActive = utils.Min(limit, m.maxIssued)
+	return m.issueNewConnIDs()
+}
+
+// issueNewConnIDs issues new connection IDs until the peer has maxActive connection IDs
+// that it is not asked to retire.
+func (m *connIDGenerator) issueNewConnIDs() error {
+	for i := m.numUnretired(); i < m.maxActive; i++ {
 		if err := m.issueNewConnID(); err != nil {
 			return err
 		}
@@ -68,6 +115,16 @@ func (m *connIDGenerator) SetMaxActiveConnIDs(limit uint64) error {
 	return nil
 }
 
+func (m *connIDGenerator) numUnretired() uint64 {
+	var n uint64
+	for seq := range m.activeSrcConnIDs {
+		if seq >= m.retirePriorTo {
+			n++
+		}
+	}
+	return n
+}
+
 func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.ConnectionID) error {
 	if seq > m.highestSeq {
 		return &qerr.TransportError{
@@ -88,13 +145,40 @@ func (m *connIDGenerator) Retire(seq uint64, sentWithDestConnID protocol.Connect
 	}
 	m.retireConnectionID(connID)
 	delete(m.activeSrcConnIDs, seq)
+	if m.tracer != nil && m.tracer.RetiredConnectionID != nil {
+		m.tracer.RetiredConnectionID(seq, connID)
+	}
+	if g, ok := m.generator.(RetirementAwareConnectionIDGenerator); ok {
+		g.ConnectionIDRetired(connID)
+	}
+	m.retiredByPeer(seq, connID)
 	// Don't issue a replacement for the initial connection ID.
-	if seq == 0 {
+	// Connection IDs that we asked the peer to retire were already replaced when rotating.
+	if seq == 0 || seq < m.retirePriorTo {
 		return nil
 	}
 	return m.issueNewConnID()
 }
 
+// IssuePreferredAddressConnID issues the connection ID sent in the preferred_address transport parameter.
+// This connection ID has the sequence number 1, so it must be issued before any other connection ID.
+func (m *connIDGenerator) IssuePreferredAddressConnID() (protocol.ConnectionID, protocol.StatelessResetToken, error) {
+	if m.highestSeq != 0 {
+		return protocol.ConnectionID{}, protocol.StatelessResetToken{}, errors.New("connection IDs were already issued")
+	}
+	connID, err := m.generator.GenerateConnectionID()
+	if err != nil {
+		return protocol.ConnectionID{}, protocol.StatelessResetToken{}, err
+	}
+	m.highestSeq++
+	m.activeSrcConnIDs[m.highestSeq] = connID
+	m.addConnectionID(connID)
+	if m.tracer != nil && m.tracer.IssuedConnectionID != nil {
+		m.tracer.IssuedConnectionID(m.highestSeq, connID, m.retirePriorTo)
+	}
+	return connID, m.getStatelessResetToken(connID), nil
+}
+
 func (m *connIDGenerator) issueNewConnID() error {
 	connID, err := m.generator.GenerateConnectionID()
 	if err != nil {
@@ -104,18 +188,81 @@ func (m *connIDGenerator) issueNewConnID() error {
 	m.addConnectionID(connID)
 	m.queueControlFrame(&wire.NewConnectionIDFrame{
 		SequenceNumber:      m.highestSeq + 1,
+		RetirePriorTo:       m.retirePriorTo,
 		ConnectionID:        connID,
 		StatelessResetToken: m.getStatelessResetToken(connID),
 	})
 	m.highestSeq++
+	if m.tracer != nil && m.tracer.IssuedConnectionID != nil {
+		m.tracer.IssuedConnectionID(m.highestSeq, connID, m.retirePriorTo)
+	}
 	return nil
 }
 
-func (m *connIDGenerator) SetHandshakeComplete() {
+func (m *connIDGenerator) SetHandshakeComplete(now time.Time) {
 	if m.initialClientDestConnID != nil {
 		m.retireConnectionID(*m.initialClientDestConnID)
 		m.initialClientDestConnID = nil
 	}
+	if m.rotationInterval > 0 && m.generator.ConnectionIDLen() > 0 {
+		m.nextRotation = now.Add(m.rotationInterval)
+	}
+}
+
+// NextRotationTime returns the time when the connection IDs are rotated next.
+// It returns the zero value if connection ID rotation is disabled.
+func (m *connIDGenerator) NextRotationTime() time.Time {
+	return m.nextRotation
+}
+
+// MaybeRotate issues new connection IDs and asks the peer to retire old ones,
+// if the rotation interval has elapsed, or if the epoch of the RotatingConnectionIDGenerator changed.
+func (m *connIDGenerator) MaybeRotate(now time.Time) error {
+	if m.rotatingGenerator != nil {
+		if epoch := m.rotatingGenerator.Epoch(); epoch != m.epoch {
+			return m.rotateEpoch(now, epoch)
+		}
+	}
+	if m.nextRotation.IsZero() || now.Before(m.nextRotation) {
+		return nil
+	}
+	m.nextRotation = now.Add(m.rotationInterval)
+	if m.peerSlowToRetire() {
+		return nil
+	}
+	switch m.retirement {
+	case RetireAllConnectionIDs:
+		m.retirePriorTo = m.highestSeq + 1
+	default:
+		// retire the oldest connection ID that the peer is still allowed to use
+		for seq := m.retirePriorTo; seq <= m.highestSeq; seq++ {
+			if _, ok := m.activeSrcConnIDs[seq]; ok {
+				m.retirePriorTo = seq + 1
+				break
+			}
+		}
+	}
+	return m.issueNewConnIDs()
+}
+
+// rotateEpoch replaces all connection IDs with connection IDs of the new epoch.
+func (m *connIDGenerator) rotateEpoch(now time.Time, epoch uint64) error {
+	// Try again later. The old connection IDs can still be used in the meantime.
+	if m.peerSlowToRetire() {
+		return nil
+	}
+	m.epoch = epoch
+	m.retirePriorTo = m.highestSeq + 1
+	if !m.nextRotation.IsZero() {
+		m.nextRotation = now.Add(m.rotationInterval)
+	}
+	return m.issueNewConnIDs()
+}
+
+// Until the peer has retired the connection IDs that we asked it to retire, we need to keep them active.
+// Don't rotate if the peer is slow to retire them, so we don't keep an unbounded number of connection IDs.
+func (m *connIDGenerator) peerSlowToRetire() bool {
+	return uint64(len(m.activeSrcConnIDs))-m.numUnretired() >= m.maxActive
 }
 
 func (m *connIDGenerator) RemoveAll() {
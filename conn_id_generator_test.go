@@ -2,11 +2,15 @@ package quic
 
 import (
 	"fmt"
+	"time"
 
+	mocklogging "github.com/quic-go/quic-go/internal/mocks/logging"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/wire"
 
+	"go.uber.org/mock/gomock"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -18,6 +22,7 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs     []protocol.ConnectionID
 		replacedWithClosed []protocol.ConnectionID
 		queuedFrames       []wire.Frame
+		retiredByPeer      map[uint64]protocol.ConnectionID
 		g                  *connIDGenerator
 	)
 	initialConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7})
@@ -28,13 +33,8 @@ var _ = Describe("Connection ID Generator", func() {
 		return protocol.StatelessResetToken{b, b, b, b, b, b, b, b, b, b, b, b, b, b, b, b}
 	}
 
-	BeforeEach(func() {
-		addedConnIDs = nil
-		retiredConnIDs = nil
-		removedConnIDs = nil
-		queuedFrames = nil
-		replacedWithClosed = nil
-		g = newConnIDGenerator(
+	newGenerator := func(maxIssued int, rotationInterval time.Duration, retirement ConnectionIDRetirement) *connIDGenerator {
+		return newConnIDGenerator(
 			initialConnID,
 			&initialClientDestConnID,
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
@@ -45,8 +45,23 @@ var _ = Describe("Connection ID Generator", func() {
 				replacedWithClosed = append(replacedWithClosed, cs...)
 			},
 			func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+			func(seq uint64, c protocol.ConnectionID) { retiredByPeer[seq] = c },
 			&protocol.DefaultConnectionIDGenerator{ConnLen: initialConnID.Len()},
+			maxIssued,
+			rotationInterval,
+			retirement,
+			nil,
 		)
+	}
+
+	BeforeEach(func() {
+		addedConnIDs = nil
+		retiredConnIDs = nil
+		removedConnIDs = nil
+		queuedFrames = nil
+		replacedWithClosed = nil
+		retiredByPeer = make(map[uint64]protocol.ConnectionID)
+		g = newGenerator(protocol.MaxIssuedConnectionIDs, 0, RetireOldestConnectionID)
 	})
 
 	It("issues new connection IDs", func() {
@@ -67,6 +82,22 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("issues the connection ID for the preferred_address transport parameter", func() {
+		connID, token, err := g.IssuePreferredAddressConnID()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(token).To(Equal(connIDToToken(connID)))
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{connID}))
+		Expect(queuedFrames).To(BeEmpty())
+		// The connection ID counts towards the active_connection_id_limit.
+		Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(2))
+		Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		Expect(queuedFrames[1].(*wire.NewConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(3))
+		// It can only be issued before any other connection ID.
+		_, _, err = g.IssuePreferredAddressConnID()
+		Expect(err).To(HaveOccurred())
+	})
+
 	It("limits the number of connection IDs that it issues", func() {
 		Expect(g.SetMaxActiveConnIDs(9999999)).To(Succeed())
 		Expect(retiredConnIDs).To(BeEmpty())
@@ -74,6 +105,28 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(queuedFrames).To(HaveLen(protocol.MaxIssuedConnectionIDs - 1))
 	})
 
+	It("uses the configured limit for the number of connection IDs that it issues", func() {
+		g = newGenerator(3, 0, RetireOldestConnectionID)
+		Expect(g.SetMaxActiveConnIDs(100)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(2))
+		queuedFrames = nil
+		// retiring a connection ID leads to a replacement
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(1))
+	})
+
+	It("issues more connection IDs if the peer allows it", func() {
+		g = newGenerator(20, 0, RetireOldestConnectionID)
+		Expect(g.SetMaxActiveConnIDs(16)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(15))
+	})
+
+	It("doesn't issue any connection IDs if configured to use only a single one", func() {
+		g = newGenerator(1, 0, RetireOldestConnectionID)
+		Expect(g.SetMaxActiveConnIDs(100)).To(Succeed())
+		Expect(queuedFrames).To(BeEmpty())
+	})
+
 	// SetMaxActiveConnIDs is called twice when dialing a 0-RTT connection:
 	// once for the restored from the old connections, once when we receive the transport parameters
 	Context("dealing with 0-RTT", func() {
@@ -143,6 +196,44 @@ var _ = Describe("Connection ID Generator", func() {
 		Expect(addedConnIDs).To(BeEmpty())
 	})
 
+	It("reports connection IDs retired by the peer", func() {
+		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
+		Expect(retiredByPeer).To(BeEmpty())
+		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+		Expect(g.Retire(2, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredByPeer).To(HaveLen(2))
+		Expect(retiredByPeer).To(HaveKeyWithValue(uint64(0), initialConnID))
+		Expect(retiredByPeer).To(HaveKeyWithValue(uint64(2), addedConnIDs[1]))
+		// duplicate retirements are not reported
+		Expect(g.Retire(2, protocol.ConnectionID{})).To(Succeed())
+		Expect(retiredByPeer).To(HaveLen(2))
+	})
+
+	It("notifies the connection ID generator about retired connection IDs", func() {
+		gen := &retirementAwareConnIDGenerator{DefaultConnectionIDGenerator: protocol.DefaultConnectionIDGenerator{ConnLen: 5}}
+		g = newConnIDGenerator(
+			initialConnID,
+			nil,
+			func(protocol.ConnectionID) {},
+			connIDToToken,
+			func(protocol.ConnectionID) {},
+			func(protocol.ConnectionID) {},
+			func([]protocol.ConnectionID, protocol.Perspective, []byte) {},
+			func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+			func(uint64, protocol.ConnectionID) {},
+			gen,
+			protocol.MaxIssuedConnectionIDs,
+			0,
+			RetireOldestConnectionID,
+			nil,
+		)
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(queuedFrames).To(HaveLen(2))
+		connID := queuedFrames[0].(*wire.NewConnectionIDFrame).ConnectionID
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+		Expect(gen.retired).To(Equal([]protocol.ConnectionID{connID}))
+	})
+
 	It("handles duplicate retirements", func() {
 		Expect(g.SetMaxActiveConnIDs(11)).To(Succeed())
 		queuedFrames = nil
@@ -156,11 +247,267 @@ var _ = Describe("Connection ID Generator", func() {
 	})
 
 	It("retires the client's initial destination connection ID when the handshake completes", func() {
-		g.SetHandshakeComplete()
+		g.SetHandshakeComplete(time.Now())
 		Expect(retiredConnIDs).To(HaveLen(1))
 		Expect(retiredConnIDs[0]).To(Equal(initialClientDestConnID))
 	})
 
+	Context("rotating connection IDs", func() {
+		const interval = time.Minute
+
+		getFrames := func() []*wire.NewConnectionIDFrame {
+			frames := make([]*wire.NewConnectionIDFrame, 0, len(queuedFrames))
+			for _, f := range queuedFrames {
+				Expect(f).To(BeAssignableToTypeOf(&wire.NewConnectionIDFrame{}))
+				frames = append(frames, f.(*wire.NewConnectionIDFrame))
+			}
+			queuedFrames = nil
+			return frames
+		}
+
+		It("doesn't rotate connection IDs if no interval is configured", func() {
+			now := time.Now()
+			g.SetHandshakeComplete(now)
+			Expect(g.NextRotationTime()).To(BeZero())
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			queuedFrames = nil
+			Expect(g.MaybeRotate(now.Add(time.Hour))).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+		})
+
+		It("doesn't rotate connection IDs before the handshake completes", func() {
+			g = newGenerator(protocol.MaxIssuedConnectionIDs, interval, RetireOldestConnectionID)
+			Expect(g.SetMaxActiveConnIDs(4)).To(Succeed())
+			queuedFrames = nil
+			Expect(g.NextRotationTime()).To(BeZero())
+			Expect(g.MaybeRotate(time.Now().Add(time.Hour))).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+		})
+
+		It("doesn't rotate zero-length connection IDs", func() {
+			g = newConnIDGenerator(
+				protocol.ConnectionID{},
+				nil,
+				func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
+				connIDToToken,
+				func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
+				func(c protocol.ConnectionID) { retiredConnIDs = append(retiredConnIDs, c) },
+				func([]protocol.ConnectionID, protocol.Perspective, []byte) {},
+				func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+				func(uint64, protocol.ConnectionID) {},
+				&protocol.DefaultConnectionIDGenerator{},
+				protocol.MaxIssuedConnectionIDs,
+				interval,
+				RetireOldestConnectionID,
+				nil,
+			)
+			g.SetHandshakeComplete(time.Now())
+			Expect(g.NextRotationTime()).To(BeZero())
+		})
+
+		It("retires the oldest connection ID", func() {
+			g = newGenerator(protocol.MaxIssuedConnectionIDs, interval, RetireOldestConnectionID)
+			Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+			Expect(getFrames()).To(HaveLen(2))
+			now := time.Now()
+			g.SetHandshakeComplete(now)
+			Expect(g.NextRotationTime()).To(Equal(now.Add(interval)))
+			Expect(g.MaybeRotate(now.Add(interval / 2))).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			Expect(g.NextRotationTime()).To(Equal(now.Add(interval)))
+			frames := getFrames()
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].SequenceNumber).To(BeEquivalentTo(3))
+			Expect(frames[0].RetirePriorTo).To(BeEquivalentTo(1))
+			// The peer retires the connection ID. No replacement is issued.
+			Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			frames = getFrames()
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].SequenceNumber).To(BeEquivalentTo(4))
+			Expect(frames[0].RetirePriorTo).To(BeEquivalentTo(2))
+			// connection IDs that the peer retires on its own are still replaced
+			Expect(g.Retire(3, protocol.ConnectionID{})).To(Succeed())
+			frames = getFrames()
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].SequenceNumber).To(BeEquivalentTo(5))
+			Expect(frames[0].RetirePriorTo).To(BeEquivalentTo(2))
+		})
+
+		It("retires all connection IDs", func() {
+			g = newGenerator(protocol.MaxIssuedConnectionIDs, interval, RetireAllConnectionIDs)
+			Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+			Expect(getFrames()).To(HaveLen(2))
+			now := time.Now()
+			g.SetHandshakeComplete(now)
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			frames := getFrames()
+			Expect(frames).To(HaveLen(3))
+			for i, f := range frames {
+				Expect(f.SequenceNumber).To(BeEquivalentTo(3 + i))
+				Expect(f.RetirePriorTo).To(BeEquivalentTo(3))
+			}
+		})
+
+		It("rotates connection IDs when the peer only allows the minimum number of connection IDs", func() {
+			g = newGenerator(protocol.MaxIssuedConnectionIDs, interval, RetireOldestConnectionID)
+			Expect(g.SetMaxActiveConnIDs(protocol.DefaultActiveConnectionIDLimit)).To(Succeed())
+			Expect(getFrames()).To(HaveLen(1))
+			now := time.Now()
+			g.SetHandshakeComplete(now)
+			for i := 0; i < 5; i++ {
+				now = now.Add(interval)
+				Expect(g.MaybeRotate(now)).To(Succeed())
+				frames := getFrames()
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].SequenceNumber).To(BeEquivalentTo(i + 2))
+				Expect(frames[0].RetirePriorTo).To(BeEquivalentTo(i + 1))
+				// the peer never has more than 2 connection IDs that it's allowed to use
+				Expect(g.numUnretired()).To(BeEquivalentTo(2))
+				Expect(g.Retire(uint64(i), protocol.ConnectionID{})).To(Succeed())
+				Expect(queuedFrames).To(BeEmpty())
+			}
+		})
+
+		It("doesn't rotate while the peer hasn't retired the old connection IDs", func() {
+			g = newGenerator(protocol.MaxIssuedConnectionIDs, interval, RetireAllConnectionIDs)
+			Expect(g.SetMaxActiveConnIDs(2)).To(Succeed())
+			Expect(getFrames()).To(HaveLen(1))
+			now := time.Now()
+			g.SetHandshakeComplete(now)
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			Expect(getFrames()).To(HaveLen(2))
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+			// the peer retires the old connection IDs
+			Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+			Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+			now = now.Add(interval)
+			Expect(g.MaybeRotate(now)).To(Succeed())
+			frames := getFrames()
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].SequenceNumber).To(BeEquivalentTo(4))
+			Expect(frames[0].RetirePriorTo).To(BeEquivalentTo(4))
+		})
+	})
+
+	Context("rotating the epoch", func() {
+		var epochGenerator *rotatingConnIDGenerator
+
+		BeforeEach(func() {
+			epochGenerator = &rotatingConnIDGenerator{
+				DefaultConnectionIDGenerator: protocol.DefaultConnectionIDGenerator{ConnLen: initialConnID.Len()},
+				epoch:                        1,
+			}
+			g = newConnIDGenerator(
+				initialConnID,
+				&initialClientDestConnID,
+				func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
+				connIDToToken,
+				func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
+				func(c protocol.ConnectionID) { retiredConnIDs = append(retiredConnIDs, c) },
+				func([]protocol.ConnectionID, protocol.Perspective, []byte) {},
+				func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+				func(uint64, protocol.ConnectionID) {},
+				epochGenerator,
+				protocol.MaxIssuedConnectionIDs,
+				0,
+				RetireOldestConnectionID,
+				nil,
+			)
+			Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(2))
+			queuedFrames = nil
+			g.SetHandshakeComplete(time.Now())
+			retiredConnIDs = nil // the client's initial destination connection ID
+		})
+
+		It("doesn't rotate connection IDs as long as the epoch doesn't change", func() {
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+		})
+
+		It("replaces all connection IDs when the epoch changes", func() {
+			epochGenerator.epoch = 2
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(3))
+			for i, f := range queuedFrames {
+				nf := f.(*wire.NewConnectionIDFrame)
+				Expect(nf.SequenceNumber).To(BeEquivalentTo(3 + i))
+				Expect(nf.RetirePriorTo).To(BeEquivalentTo(3))
+			}
+			queuedFrames = nil
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+			// the old connection IDs are only retired once the peer retires them
+			Expect(retiredConnIDs).To(BeEmpty())
+			Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+			Expect(retiredConnIDs).To(HaveLen(1))
+			Expect(queuedFrames).To(BeEmpty())
+		})
+
+		It("delays the rotation while the peer hasn't retired the old connection IDs", func() {
+			epochGenerator.epoch = 2
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(3))
+			queuedFrames = nil
+			epochGenerator.epoch = 3
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(BeEmpty())
+			for seq := uint64(0); seq < 3; seq++ {
+				Expect(g.Retire(seq, protocol.ConnectionID{})).To(Succeed())
+			}
+			Expect(g.MaybeRotate(time.Now())).To(Succeed())
+			Expect(queuedFrames).To(HaveLen(3))
+			Expect(queuedFrames[0].(*wire.NewConnectionIDFrame).RetirePriorTo).To(BeEquivalentTo(6))
+		})
+	})
+
+	It("traces issued and retired connection IDs", func() {
+		tr, tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		g = newConnIDGenerator(
+			initialConnID,
+			nil,
+			func(protocol.ConnectionID) {},
+			connIDToToken,
+			func(protocol.ConnectionID) {},
+			func(protocol.ConnectionID) {},
+			func([]protocol.ConnectionID, protocol.Perspective, []byte) {},
+			func(f wire.Frame) { queuedFrames = append(queuedFrames, f) },
+			func(uint64, protocol.ConnectionID) {},
+			&protocol.DefaultConnectionIDGenerator{ConnLen: initialConnID.Len()},
+			protocol.MaxIssuedConnectionIDs,
+			time.Minute,
+			RetireOldestConnectionID,
+			tr,
+		)
+		var connIDs []protocol.ConnectionID
+		tracer.EXPECT().IssuedConnectionID(gomock.Any(), gomock.Any(), uint64(0)).Do(func(seq uint64, connID protocol.ConnectionID, _ uint64) {
+			Expect(seq).To(BeEquivalentTo(len(connIDs) + 1))
+			connIDs = append(connIDs, connID)
+		}).Times(2)
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		now := time.Now()
+		g.SetHandshakeComplete(now)
+		tracer.EXPECT().IssuedConnectionID(uint64(3), gomock.Any(), uint64(1))
+		Expect(g.MaybeRotate(now.Add(time.Minute))).To(Succeed())
+		tracer.EXPECT().RetiredConnectionID(uint64(0), initialConnID)
+		Expect(g.Retire(0, protocol.ConnectionID{})).To(Succeed())
+		tracer.EXPECT().RetiredConnectionID(uint64(1), connIDs[0])
+		tracer.EXPECT().IssuedConnectionID(uint64(4), gomock.Any(), uint64(1))
+		Expect(g.Retire(1, protocol.ConnectionID{})).To(Succeed())
+	})
+
 	It("removes all connection IDs", func() {
 		Expect(g.SetMaxActiveConnIDs(5)).To(Succeed())
 		Expect(queuedFrames).To(HaveLen(4))
@@ -187,3 +534,23 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 })
+
+type rotatingConnIDGenerator struct {
+	protocol.DefaultConnectionIDGenerator
+	epoch uint64
+}
+
+var _ RotatingConnectionIDGenerator = &rotatingConnIDGenerator{}
+
+func (g *rotatingConnIDGenerator) Epoch() uint64 { return g.epoch }
+
+type retirementAwareConnIDGenerator struct {
+	protocol.DefaultConnectionIDGenerator
+	retired []protocol.ConnectionID
+}
+
+var _ RetirementAwareConnectionIDGenerator = &retirementAwareConnIDGenerator{}
+
+func (g *retirementAwareConnIDGenerator) ConnectionIDRetired(c protocol.ConnectionID) {
+	g.retired = append(g.retired, c)
+}
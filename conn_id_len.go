@@ -0,0 +1,54 @@
+package quic
+
+import (
+	"io"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// A shortHeaderConnIDLen returns the length of the Destination Connection ID of a short header packet.
+// Short header packets don't encode the length of the connection ID, so either all connection IDs
+// have the same length, or the length can be derived from the connection ID itself.
+type shortHeaderConnIDLen func(data []byte) (int, error)
+
+func fixedConnIDLen(l int) shortHeaderConnIDLen {
+	return func([]byte) (int, error) { return l, nil }
+}
+
+// newShortHeaderConnIDLen returns the shortHeaderConnIDLen for connection IDs generated by g.
+// Unless g is a VariableLengthConnectionIDGenerator, all connection IDs have the length l.
+func newShortHeaderConnIDLen(l int, g ConnectionIDGenerator) shortHeaderConnIDLen {
+	vg, ok := g.(VariableLengthConnectionIDGenerator)
+	if !ok {
+		return fixedConnIDLen(l)
+	}
+	return func(data []byte) (int, error) {
+		if len(data) < 2 {
+			return 0, io.EOF
+		}
+		l, err := vg.ParseConnectionIDLen(data[1:])
+		if err != nil {
+			return 0, err
+		}
+		if l < 0 || l > protocol.MaxConnIDLen {
+			return 0, protocol.ErrInvalidConnectionIDLen
+		}
+		return l, nil
+	}
+}
+
+// parseConnectionID parses the Destination Connection ID of a packet.
+func parseConnectionID(data []byte, shortHdrConnIDLen shortHeaderConnIDLen) (protocol.ConnectionID, error) {
+	if len(data) == 0 {
+		return protocol.ConnectionID{}, io.EOF
+	}
+	if wire.IsLongHeaderPacket(data[0]) {
+		return wire.ParseConnectionID(data, 0)
+	}
+	l, err := shortHdrConnIDLen(data)
+	if err != nil {
+		return protocol.ConnectionID{}, err
+	}
+	return wire.ParseConnectionID(data, l)
+}
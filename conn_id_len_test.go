@@ -0,0 +1,60 @@
+package quic
+
+import (
+	"errors"
+	"io"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type selfEncodedLenConnIDGenerator struct {
+	protocol.DefaultConnectionIDGenerator
+}
+
+func (g *selfEncodedLenConnIDGenerator) ParseConnectionIDLen(b []byte) (int, error) {
+	if b[0] == 0xff {
+		return 0, errors.New("invalid length")
+	}
+	return int(b[0]), nil
+}
+
+var _ = Describe("Connection ID lengths", func() {
+	It("uses a fixed length", func() {
+		l := newShortHeaderConnIDLen(4, &protocol.DefaultConnectionIDGenerator{ConnLen: 4})
+		connID, err := parseConnectionID([]byte{0x40, 1, 2, 3, 4, 5, 6}, l)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+	})
+
+	It("uses the length derived from the connection ID", func() {
+		l := newShortHeaderConnIDLen(18, &selfEncodedLenConnIDGenerator{})
+		connID, err := parseConnectionID([]byte{0x40, 3, 2, 1, 0, 0}, l)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{3, 2, 1})))
+		connID, err = parseConnectionID([]byte{0x40, 5, 4, 3, 2, 1, 0}, l)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1})))
+	})
+
+	It("errors when the length can't be derived", func() {
+		l := newShortHeaderConnIDLen(18, &selfEncodedLenConnIDGenerator{})
+		_, err := parseConnectionID([]byte{0x40}, l)
+		Expect(err).To(MatchError(io.EOF))
+		_, err = parseConnectionID([]byte{0x40, 0xff, 1, 2}, l)
+		Expect(err).To(MatchError("invalid length"))
+		_, err = parseConnectionID([]byte{0x40, 21, 1, 2}, l)
+		Expect(err).To(MatchError(protocol.ErrInvalidConnectionIDLen))
+		_, err = parseConnectionID([]byte{0x40, 8, 1, 2}, l)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("parses the connection ID of long header packets", func() {
+		l := newShortHeaderConnIDLen(18, &selfEncodedLenConnIDGenerator{})
+		connID, err := parseConnectionID([]byte{0xc0, 0, 0, 0, 1, 2, 0xa, 0xb}, l)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{0xa, 0xb})))
+	})
+})
@@ -18,6 +18,8 @@ type newConnID struct {
 
 type connIDManager struct {
 	queue list.List[newConnID]
+	// the active_connection_id_limit that we advertised to the peer
+	activeConnIDLimit uint64
 
 	handshakeComplete         bool
 	activeSequenceNumber      uint64
@@ -35,6 +37,8 @@ type connIDManager struct {
 	addStatelessResetToken    func(protocol.StatelessResetToken)
 	removeStatelessResetToken func(protocol.StatelessResetToken)
 	queueControlFrame         func(wire.Frame)
+	// called when we retire one of the peer's connection IDs
+	retiredConnectionID func(seq uint64, connID protocol.ConnectionID)
 }
 
 func newConnIDManager(
@@ -42,12 +46,16 @@ func newConnIDManager(
 	addStatelessResetToken func(protocol.StatelessResetToken),
 	removeStatelessResetToken func(protocol.StatelessResetToken),
 	queueControlFrame func(wire.Frame),
+	retiredConnectionID func(seq uint64, connID protocol.ConnectionID),
+	activeConnIDLimit uint64,
 ) *connIDManager {
 	return &connIDManager{
 		activeConnectionID:        initialDestConnID,
+		activeConnIDLimit:         activeConnIDLimit,
 		addStatelessResetToken:    addStatelessResetToken,
 		removeStatelessResetToken: removeStatelessResetToken,
 		queueControlFrame:         queueControlFrame,
+		retiredConnectionID:       retiredConnectionID,
 	}
 }
 
@@ -59,7 +67,7 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if err := h.add(f); err != nil {
 		return err
 	}
-	if h.queue.Len() >= protocol.MaxActiveConnectionIDs {
+	if uint64(h.queue.Len()) >= h.activeConnIDLimit {
 		return &qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}
 	}
 	return nil
@@ -69,9 +77,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequence number is smaller than the currently active
 	// connection ID or if it was already retired, send the RETIRE_CONNECTION_ID frame immediately.
 	if f.SequenceNumber < h.activeSequenceNumber || f.SequenceNumber < h.highestRetired {
-		h.queueControlFrame(&wire.RetireConnectionIDFrame{
-			SequenceNumber: f.SequenceNumber,
-		})
+		h.retire(f.SequenceNumber, f.ConnectionID)
 		return nil
 	}
 
@@ -84,9 +90,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 				break
 			}
 			next = el.Next()
-			h.queueControlFrame(&wire.RetireConnectionIDFrame{
-				SequenceNumber: el.Value.SequenceNumber,
-			})
+			h.retire(el.Value.SequenceNumber, el.Value.ConnectionID)
 			h.queue.Remove(el)
 		}
 		h.highestRetired = f.RetirePriorTo
@@ -141,10 +145,13 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 	return nil
 }
 
+func (h *connIDManager) retire(seq uint64, connID protocol.ConnectionID) {
+	h.queueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: seq})
+	h.retiredConnectionID(seq, connID)
+}
+
 func (h *connIDManager) updateConnectionID() {
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: h.activeSequenceNumber,
-	})
+	h.retire(h.activeSequenceNumber, h.activeConnectionID)
 	h.highestRetired = utils.Max(h.highestRetired, h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -159,6 +166,20 @@ func (h *connIDManager) updateConnectionID() {
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
 
+// SwitchConnectionID switches to an unused connection ID, e.g. when migrating to a new path.
+// It returns false if the peer didn't provide any unused connection IDs.
+// If the peer uses zero-length connection IDs, there's no need to switch.
+func (h *connIDManager) SwitchConnectionID() bool {
+	if h.activeConnectionID.Len() == 0 {
+		return true
+	}
+	if h.queue.Len() == 0 {
+		return false
+	}
+	h.updateConnectionID()
+	return true
+}
+
 func (h *connIDManager) Close() {
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -198,7 +219,7 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 	// For later changes, only change if
 	// 1. The queue of connection IDs is filled more than 50%.
 	// 2. We sent at least PacketsPerConnectionID packets
-	return 2*h.queue.Len() >= protocol.MaxActiveConnectionIDs &&
+	return 2*uint64(h.queue.Len()) >= h.activeConnIDLimit &&
 		h.packetsSinceLastChange >= h.packetsPerConnectionID
 }
 
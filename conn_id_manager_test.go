@@ -15,6 +15,7 @@ var _ = Describe("Connection ID Manager", func() {
 		frameQueue    []wire.Frame
 		tokenAdded    *protocol.StatelessResetToken
 		removedTokens []protocol.StatelessResetToken
+		retired       []protocol.ConnectionID
 	)
 	initialConnID := protocol.ParseConnectionID([]byte{0, 0, 0, 0})
 
@@ -22,6 +23,7 @@ var _ = Describe("Connection ID Manager", func() {
 		frameQueue = nil
 		tokenAdded = nil
 		removedTokens = nil
+		retired = nil
 		m = newConnIDManager(
 			initialConnID,
 			func(token protocol.StatelessResetToken) { tokenAdded = &token },
@@ -29,7 +31,10 @@ var _ = Describe("Connection ID Manager", func() {
 			func(f wire.Frame,
 			) {
 				frameQueue = append(frameQueue, f)
-			})
+			},
+			func(_ uint64, c protocol.ConnectionID) { retired = append(retired, c) },
+			protocol.MaxActiveConnectionIDs,
+		)
 	})
 
 	get := func() (protocol.ConnectionID, protocol.StatelessResetToken) {
@@ -158,6 +163,11 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(frameQueue[1].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(13))
 		Expect(frameQueue[2].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
 		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{3, 4, 5, 6})))
+		Expect(retired).To(Equal([]protocol.ConnectionID{
+			protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			protocol.ParseConnectionID([]byte{2, 3, 4, 5}),
+			initialConnID,
+		}))
 	})
 
 	It("ignores reordered connection IDs, if their sequence number was already retired", func() {
@@ -177,6 +187,7 @@ var _ = Describe("Connection ID Manager", func() {
 		})).To(Succeed())
 		Expect(frameQueue).To(HaveLen(1))
 		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(4))
+		Expect(retired).To(ContainElement(protocol.ParseConnectionID([]byte{4, 3, 2, 1})))
 	})
 
 	It("ignores reordered connection IDs, if their sequence number was already retired or less than active", func() {
@@ -236,6 +247,56 @@ var _ = Describe("Connection ID Manager", func() {
 		})).To(MatchError(&qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}))
 	})
 
+	It("uses the configured active connection ID limit", func() {
+		const limit = 2 * protocol.MaxActiveConnectionIDs
+		m.activeConnIDLimit = limit
+		for i := uint8(1); i < limit; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(i),
+				ConnectionID:        protocol.ParseConnectionID([]byte{i, i, i, i}),
+				StatelessResetToken: protocol.StatelessResetToken{i, i, i, i, i, i, i, i, i, i, i, i, i, i, i, i},
+			})).To(Succeed())
+		}
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      limit,
+			ConnectionID:        protocol.ParseConnectionID([]byte{limit, limit, limit, limit}),
+			StatelessResetToken: protocol.StatelessResetToken{limit},
+		})).To(MatchError(&qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}))
+	})
+
+	It("doesn't count connection IDs retired by the Retire Prior To field towards the limit", func() {
+		m.activeConnIDLimit = 2
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ParseConnectionID([]byte{1, 1, 1, 1}),
+			StatelessResetToken: protocol.StatelessResetToken{1},
+		})).To(Succeed())
+		// Retiring all previous connection IDs, including the active one, makes space for two new connection IDs.
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      2,
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{2, 2, 2, 2}),
+			StatelessResetToken: protocol.StatelessResetToken{2},
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      3,
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{3, 3, 3, 3}),
+			StatelessResetToken: protocol.StatelessResetToken{3},
+		})).To(Succeed())
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{2, 2, 2, 2})))
+		Expect(frameQueue).To(ConsistOf(
+			&wire.RetireConnectionIDFrame{SequenceNumber: 0},
+			&wire.RetireConnectionIDFrame{SequenceNumber: 1},
+		))
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      4,
+			RetirePriorTo:       2,
+			ConnectionID:        protocol.ParseConnectionID([]byte{4, 4, 4, 4}),
+			StatelessResetToken: protocol.StatelessResetToken{4},
+		})).To(MatchError(&qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}))
+	})
+
 	It("initiates the first connection ID update as soon as possible", func() {
 		Expect(m.Get()).To(Equal(initialConnID))
 		m.SetHandshakeComplete()
@@ -347,6 +408,30 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	It("switches to an unused connection ID", func() {
+		Expect(m.SwitchConnectionID()).To(BeFalse())
+		Expect(frameQueue).To(BeEmpty())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      1,
+			ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			StatelessResetToken: protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+		})).To(Succeed())
+		Expect(m.SwitchConnectionID()).To(BeTrue())
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+		Expect(*tokenAdded).To(Equal(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+		Expect(retired).To(Equal([]protocol.ConnectionID{initialConnID}))
+		// no more unused connection IDs
+		Expect(m.SwitchConnectionID()).To(BeFalse())
+	})
+
+	It("doesn't need to switch zero-length connection IDs", func() {
+		m.ChangeInitialConnID(protocol.ConnectionID{})
+		Expect(m.SwitchConnectionID()).To(BeTrue())
+		Expect(frameQueue).To(BeEmpty())
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(removedTokens).To(BeEmpty())
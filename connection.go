@@ -3,12 +3,14 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"reflect"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -27,6 +29,7 @@ import (
 type unpacker interface {
 	UnpackLongHeader(hdr *wire.Header, rcvTime time.Time, data []byte, v protocol.VersionNumber) (*unpackedPacket, error)
 	UnpackShortHeader(rcvTime time.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error)
+	UnpackOpenedShortHeader(rcvTime time.Time, p *openedShortHeaderPacket) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error)
 }
 
 type streamGetter interface {
@@ -49,6 +52,8 @@ type streamManager interface {
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
+	NumStreams() (bidi, uni int)
+	BufferedBytes() (send, receive int)
 }
 
 type cryptoStreamHandler interface {
@@ -58,7 +63,9 @@ type cryptoStreamHandler interface {
 	SetHandshakeConfirmed()
 	GetSessionTicket() ([]byte, error)
 	NextEvent() handshake.Event
+	HandleWorkerResults() error
 	DiscardInitialKeys()
+	Get1RTTOpener() (handshake.ShortHeaderOpener, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
 }
@@ -73,6 +80,12 @@ type receivedPacket struct {
 	ecn protocol.ECN
 
 	info packetInfo // only valid if the contained IP address is valid
+
+	opened *openedShortHeaderPacket // set if the packet was decrypted by the decryptionPool
+
+	// The socket the packet was received on.
+	// Only set for Transports that are part of a MultihomedGroup.
+	rcvConn rawConn
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
@@ -85,6 +98,7 @@ func (p *receivedPacket) Clone() *receivedPacket {
 		buffer:     p.buffer,
 		ecn:        p.ecn,
 		info:       p.info,
+		rcvConn:    p.rcvConn,
 	}
 }
 
@@ -107,6 +121,7 @@ type closeError struct {
 type errCloseForRecreating struct {
 	nextPacketNumber protocol.PacketNumber
 	nextVersion      protocol.VersionNumber
+	serverVersions   []protocol.VersionNumber // the versions offered in the Version Negotiation packet
 }
 
 func (e *errCloseForRecreating) Error() string {
@@ -125,7 +140,7 @@ type connection struct {
 	origDestConnID protocol.ConnectionID
 	retrySrcConnID *protocol.ConnectionID // only set for the client (and if a Retry was performed)
 
-	srcConnIDLen int
+	shortHdrConnIDLen shortHeaderConnIDLen
 
 	perspective protocol.Perspective
 	version     protocol.VersionNumber
@@ -134,17 +149,32 @@ type connection struct {
 	conn      sendConn
 	sendQueue sender
 
+	// Set for server connections in a MultihomedGroup, which accept a client's migration to a new path,
+	// and for client connections, which can be rebound to a new socket.
+	migratingConn *migratingConn
+	// The socket that the connection was rebound to, see Rebind.
+	// Nil if the connection wasn't rebound.
+	reboundConn rawConn
+	// Set while the path that the client migrated (or was rebound) to is being validated.
+	pathValidation *pathValidation
+	// The largest packet number received in a 1-RTT packet. Only used if migratingConn is set.
+	largestRcvd1RTTPacketNumber protocol.PacketNumber
+	// The sequence number of the next OBSERVED_ADDRESS frame sent.
+	nextObservedAddrSeq uint64
+
 	streamsMap      streamManager
 	connIDManager   *connIDManager
 	connIDGenerator *connIDGenerator
 
 	rttStats *utils.RTTStats
+	clock    utils.Clock
 
 	cryptoStreamManager   *cryptoStreamManager
 	sentPacketHandler     ackhandler.SentPacketHandler
 	receivedPacketHandler ackhandler.ReceivedPacketHandler
 	retransmissionQueue   *retransmissionQueue
 	framer                framer
+	sendRateLimiter       *tokenBucket
 	windowUpdateQueue     *windowUpdateQueue
 	connFlowController    flowcontrol.ConnectionFlowController
 	tokenStoreKey         string                    // only set for the client
@@ -154,6 +184,8 @@ type connection struct {
 	frameParser   wire.FrameParser
 	packer        packer
 	mtuDiscoverer mtuDiscoverer // initialized when the handshake completes
+	// gsoBlackholeDetector detects if GSO batches are dropped on the path
+	gsoBlackholeDetector gsoBlackholeDetector
 
 	initialStream       cryptoStream
 	handshakeStream     cryptoStream
@@ -162,8 +194,16 @@ type connection struct {
 
 	receivedPackets  chan receivedPacket
 	sendingScheduled chan struct{}
+	// handshakeWorkerResult is signaled when handshake messages were handled on the HandshakeWorkerPool.
+	// It is nil if no HandshakeWorkerPool is used.
+	handshakeWorkerResult chan struct{}
+	// loopFuncs contains functions that need to be executed on the run loop
+	loopFuncs chan func()
 
 	closeOnce sync.Once
+	// closeWhenAcked is set by CloseWithTimeout.
+	// The connection is closed with this error once all outstanding data has been acknowledged.
+	closeWhenAcked error
 	// closeChan is used to notify the run loop that it should terminate
 	closeChan chan closeError
 
@@ -175,24 +215,48 @@ type connection struct {
 	undecryptablePackets          []receivedPacket // undecryptable packets, waiting for a change in encryption level
 	undecryptablePacketsToProcess []receivedPacket
 
-	earlyConnReadyChan chan struct{}
-	sentFirstPacket    bool
-	handshakeComplete  bool
-	handshakeConfirmed bool
+	// Set after handshake completion, if Config.DecryptionWorkers is larger than 1.
+	// Read by handlePacket, which is called from the Transport's goroutine.
+	decryptionPool atomic.Pointer[decryptionPool]
+
+	earlyConnReadyChan     chan struct{}
+	handshakeConfirmedChan chan struct{}
+	sentFirstPacket        bool
+	handshakeComplete      bool
+	handshakeConfirmed     bool
+
+	// the ACK delay of the ACK frame that is currently being processed, used to measure the RTT of Ping
+	ackDelay time.Duration
 
 	receivedRetry       bool
 	versionNegotiated   bool
 	receivedFirstPacket bool
+	// a bitmask of the logging.HandshakeMilestones that were already reached
+	handshakeMilestones uint8
+
+	// only set for the server, if 0-RTT is allowed and limits are configured
+	zeroRTTLimiter *zeroRTTLimiter
 
 	// the minimum of the max_idle_timeout values advertised by both endpoints
-	idleTimeout  time.Duration
-	creationTime time.Time
+	negotiatedIdleTimeout time.Duration
+	// the idle timeout set by SetIdleTimeout, 0 if not set
+	requestedIdleTimeout time.Duration
+	// the effective idle timeout, at most negotiatedIdleTimeout
+	idleTimeout time.Duration
+	// If the idle timeout set by SetIdleTimeout exceeds the negotiated idle timeout,
+	// keep-alive PINGs are sent until there was no activity for this duration.
+	extendedIdleTimeout time.Duration
+	// the time when the last packet containing frames other than ACK, PING and PADDING frames was sent or received
+	lastActivityTime time.Time
+	creationTime     time.Time
 	// The idle timeout is set based on the max of the time we received the last packet...
 	lastPacketReceivedTime time.Time
 	// ... and the time we sent a new ack-eliciting packet after receiving a packet.
 	firstAckElicitingPacketAfterIdleSentTime time.Time
 	// pacingDeadline is the time when the next packet should be sent
 	pacingDeadline time.Time
+	// congestionLimited is set while sending is blocked by the congestion controller
+	congestionLimited bool
 
 	peerParams *wire.TransportParameters
 
@@ -206,6 +270,17 @@ type connection struct {
 
 	connStateMutex sync.Mutex
 	connState      ConnectionState
+	// extensionFrames are the frame types registered in the config, indexed by frame type.
+	// It is not modified after the connection was created.
+	extensionFrames map[uint64]*ExtensionFrame
+	// The address of this endpoint, as reported by the peer in OBSERVED_ADDRESS frames.
+	// Protected by the connStateMutex.
+	observedAddr    net.Addr
+	observedAddrSeq uint64
+	observedAddrErr error
+	// observedAddrChan is closed when the first observed address is received,
+	// or when it's clear that the peer won't report the observed address.
+	observedAddrChan chan struct{}
 
 	logID  string
 	tracer *logging.ConnectionTracer
@@ -218,6 +293,19 @@ var (
 	_ streamSender    = &connection{}
 )
 
+// connectionLogger returns the logger used by a connection.
+// The connection ID is the original destination connection ID, which is also used for qlog.
+func connectionLogger(logger utils.Logger, config *Config, connID protocol.ConnectionID, remoteAddr net.Addr) utils.Logger {
+	if config.Logger != nil {
+		logger = utils.WithBackend(logger, config.Logger)
+	}
+	logger = logger.With("conn_id", connID.String())
+	if remoteAddr != nil {
+		logger = logger.With("remote_addr", remoteAddr.String())
+	}
+	return logger
+}
+
 var newConnection = func(
 	conn sendConn,
 	runner connRunner,
@@ -241,7 +329,7 @@ var newConnection = func(
 		conn:                conn,
 		config:              conf,
 		handshakeDestConnID: destConnID,
-		srcConnIDLen:        srcConnID.Len(),
+		shortHdrConnIDLen:   newShortHeaderConnIDLen(srcConnID.Len(), connIDGenerator),
 		tokenGenerator:      tokenGenerator,
 		oneRTTStream:        newCryptoStream(),
 		perspective:         protocol.PerspectiveServer,
@@ -259,6 +347,8 @@ var newConnection = func(
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
+		func(seq uint64, connID protocol.ConnectionID) { s.connectionIDRetired(seq, connID, false) },
+		s.config.ActiveConnectionIDLimit,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -269,21 +359,38 @@ var newConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		func(seq uint64, connID protocol.ConnectionID) { s.connectionIDRetired(seq, connID, true) },
 		connIDGenerator,
+		s.config.MaxIssuedConnectionIDs,
+		s.config.ConnectionIDRotationInterval,
+		s.config.ConnectionIDRetirement,
+		s.tracer,
 	)
 	s.preSetup()
 	s.ctx, s.ctxCancel = context.WithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.conn.capabilities()),
 		s.rttStats,
+		s.clock,
 		clientAddressValidated,
 		s.conn.capabilities().ECN,
+		s.config.PersistentCongestionThreshold,
+		s.config.PersistentCongestionWindow,
+		s.config.MaxAckRanges,
 		s.perspective,
 		s.tracer,
 		s.logger,
 	)
-	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.sentPacketHandler.SetMaxDatagramSize)
+	s.mtuDiscoverer = newMTUDiscoverer(
+		s.rttStats,
+		s.clock,
+		getInitialPacketSize(s.conn.RemoteAddr(), s.conn.capabilities()),
+		s.sentPacketHandler.SetMaxDatagramSize,
+		func() { s.framer.QueueControlFrame(&wire.PingFrame{}) },
+		s.onMTUBlackHoleDetected,
+	)
+	s.sentPacketHandler.SetPacketObserver(s.mtuDiscoverer)
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -292,7 +399,7 @@ var newConnection = func(
 		MaxIdleTimeout:                  s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:                protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                 protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                     protocol.MaxAckDelayInclGranularity,
+		MaxAckDelay:                     s.config.maxAckDelay(),
 		AckDelayExponent:                protocol.AckDelayExponent,
 		DisableActiveMigration:          true,
 		StatelessResetToken:             &statelessResetToken,
@@ -302,7 +409,7 @@ var newConnection = func(
 		// If set to the default value, it will be omitted from the transport parameters, which will make
 		// old quic-go versions interpret it as 0, instead of the default value of 2.
 		// See https://github.com/quic-go/quic-go/pull/3806.
-		ActiveConnectionIDLimit:   protocol.MaxActiveConnectionIDs,
+		ActiveConnectionIDLimit:   s.config.ActiveConnectionIDLimit,
 		InitialSourceConnectionID: srcConnID,
 		RetrySourceConnectionID:   retrySrcConnID,
 	}
@@ -311,9 +418,30 @@ var newConnection = func(
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	params.AddressDiscoveryMode = s.config.AddressDiscovery
+	params.ImmediateAck = s.config.EnableImmediateAck
+	params.CustomParameters = extensionTransportParameters(s.config.ExtensionFrames)
+	if mc, ok := conn.(*migratingConn); ok {
+		s.migratingConn = mc
+		s.largestRcvd1RTTPacketNumber = protocol.InvalidPacketNumber
+		params.DisableActiveMigration = false
+		if mc.preferredAddress != nil {
+			if connID, token, err := s.connIDGenerator.IssuePreferredAddressConnID(); err != nil {
+				s.logger.Debugf("Not sending the preferred_address transport parameter: %s", err)
+			} else {
+				preferredAddress := *mc.preferredAddress
+				preferredAddress.ConnectionID = connID
+				preferredAddress.StatelessResetToken = token
+				params.PreferredAddress = &preferredAddress
+			}
+		}
+	}
 	if s.tracer != nil && s.tracer.SentTransportParameters != nil {
 		s.tracer.SentTransportParameters(params)
 	}
+	if conf.Allow0RTT {
+		s.zeroRTTLimiter = newZeroRTTLimiter(conf.Max0RTTData, conf.Max0RTTStreams)
+	}
 	cs := handshake.NewCryptoSetupServer(
 		clientDestConnID,
 		conn.LocalAddr(),
@@ -326,9 +454,14 @@ var newConnection = func(
 		logger,
 		s.version,
 	)
+	cs.SetKeyUpdateInterval(s.config.KeyUpdateInterval, s.config.KeyUpdateIntervalBytes)
+	if s.config.HandshakeWorkerPool != nil {
+		s.handshakeWorkerResult = make(chan struct{}, 1)
+		cs.UseWorkerPool(s.config.HandshakeWorkerPool, s.scheduleHandshakeWorkerResult)
+	}
 	s.cryptoStreamHandler = cs
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective)
-	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective, s.config.PaddingPolicy)
+	s.unpacker = newPacketUnpacker(cs, s.shortHdrConnIDLen)
 	s.cryptoStreamManager = newCryptoStreamManager(cs, s.initialStream, s.handshakeStream, s.oneRTTStream)
 	return s
 }
@@ -344,30 +477,40 @@ var newClientConnection = func(
 	tlsConf *tls.Config,
 	initialPacketNumber protocol.PacketNumber,
 	enable0RTT bool,
-	hasNegotiatedVersion bool,
+	serverVersions []protocol.VersionNumber, // only set if a Version Negotiation packet was received
 	tracer *logging.ConnectionTracer,
 	tracingID uint64,
 	logger utils.Logger,
 	v protocol.VersionNumber,
 ) quicConn {
+	// Wrap the connection, such that it can be rebound to a different socket.
+	var mc *migratingConn
+	if c, ok := conn.(*sconn); ok {
+		mc = &migratingConn{}
+		mc.current.Store(c)
+		conn = mc
+	}
 	s := &connection{
 		conn:                conn,
 		config:              conf,
 		origDestConnID:      destConnID,
 		handshakeDestConnID: destConnID,
-		srcConnIDLen:        srcConnID.Len(),
+		shortHdrConnIDLen:   newShortHeaderConnIDLen(srcConnID.Len(), connIDGenerator),
 		perspective:         protocol.PerspectiveClient,
 		logID:               destConnID.String(),
 		logger:              logger,
 		tracer:              tracer,
-		versionNegotiated:   hasNegotiatedVersion,
+		versionNegotiated:   len(serverVersions) > 0,
 		version:             v,
+		migratingConn:       mc,
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
+		func(seq uint64, connID protocol.ConnectionID) { s.connectionIDRetired(seq, connID, false) },
+		s.config.ActiveConnectionIDLimit,
 	)
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
@@ -378,21 +521,42 @@ var newClientConnection = func(
 		runner.Retire,
 		runner.ReplaceWithClosed,
 		s.queueControlFrame,
+		func(seq uint64, connID protocol.ConnectionID) { s.connectionIDRetired(seq, connID, true) },
 		connIDGenerator,
+		s.config.MaxIssuedConnectionIDs,
+		s.config.ConnectionIDRotationInterval,
+		s.config.ConnectionIDRetirement,
+		s.tracer,
 	)
 	s.preSetup()
+	if s.config.InitialRTT > 0 {
+		s.rttStats.SetInitialRTT(s.config.InitialRTT)
+	}
+	s.connState.ServerVersions = serverVersions
 	s.ctx, s.ctxCancel = context.WithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
-		getMaxPacketSize(s.conn.RemoteAddr()),
+		getInitialPacketSize(s.conn.RemoteAddr(), s.conn.capabilities()),
 		s.rttStats,
+		s.clock,
 		false, // has no effect
 		s.conn.capabilities().ECN,
+		s.config.PersistentCongestionThreshold,
+		s.config.PersistentCongestionWindow,
+		s.config.MaxAckRanges,
 		s.perspective,
 		s.tracer,
 		s.logger,
 	)
-	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.sentPacketHandler.SetMaxDatagramSize)
+	s.mtuDiscoverer = newMTUDiscoverer(
+		s.rttStats,
+		s.clock,
+		getInitialPacketSize(s.conn.RemoteAddr(), s.conn.capabilities()),
+		s.sentPacketHandler.SetMaxDatagramSize,
+		func() { s.framer.QueueControlFrame(&wire.PingFrame{}) },
+		s.onMTUBlackHoleDetected,
+	)
+	s.sentPacketHandler.SetPacketObserver(s.mtuDiscoverer)
 	oneRTTStream := newCryptoStream()
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -402,7 +566,7 @@ var newClientConnection = func(
 		MaxIdleTimeout:                 s.config.MaxIdleTimeout,
 		MaxBidiStreamNum:               protocol.StreamNum(s.config.MaxIncomingStreams),
 		MaxUniStreamNum:                protocol.StreamNum(s.config.MaxIncomingUniStreams),
-		MaxAckDelay:                    protocol.MaxAckDelayInclGranularity,
+		MaxAckDelay:                    s.config.maxAckDelay(),
 		AckDelayExponent:               protocol.AckDelayExponent,
 		DisableActiveMigration:         true,
 		// For interoperability with quic-go versions before May 2023, this value must be set to a value
@@ -410,7 +574,7 @@ var newClientConnection = func(
 		// If set to the default value, it will be omitted from the transport parameters, which will make
 		// old quic-go versions interpret it as 0, instead of the default value of 2.
 		// See https://github.com/quic-go/quic-go/pull/3806.
-		ActiveConnectionIDLimit:   protocol.MaxActiveConnectionIDs,
+		ActiveConnectionIDLimit:   s.config.ActiveConnectionIDLimit,
 		InitialSourceConnectionID: srcConnID,
 	}
 	if s.config.EnableDatagrams {
@@ -418,6 +582,9 @@ var newClientConnection = func(
 	} else {
 		params.MaxDatagramFrameSize = protocol.InvalidByteCount
 	}
+	params.AddressDiscoveryMode = s.config.AddressDiscovery
+	params.ImmediateAck = s.config.EnableImmediateAck
+	params.CustomParameters = extensionTransportParameters(s.config.ExtensionFrames)
 	if s.tracer != nil && s.tracer.SentTransportParameters != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -431,10 +598,15 @@ var newClientConnection = func(
 		logger,
 		s.version,
 	)
+	cs.SetKeyUpdateInterval(s.config.KeyUpdateInterval, s.config.KeyUpdateIntervalBytes)
+	if s.config.HandshakeWorkerPool != nil {
+		s.handshakeWorkerResult = make(chan struct{}, 1)
+		cs.UseWorkerPool(s.config.HandshakeWorkerPool, s.scheduleHandshakeWorkerResult)
+	}
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(cs, s.initialStream, s.handshakeStream, oneRTTStream)
-	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective)
+	s.unpacker = newPacketUnpacker(cs, s.shortHdrConnIDLen)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective, s.config.PaddingPolicy)
 	if len(tlsConf.ServerName) > 0 {
 		s.tokenStoreKey = tlsConf.ServerName
 	} else {
@@ -449,45 +621,80 @@ var newClientConnection = func(
 }
 
 func (s *connection) preSetup() {
+	s.clock = s.config.Clock
+	if s.clock == nil {
+		s.clock = utils.DefaultClock{}
+	}
 	s.initialStream = newCryptoStream()
 	s.handshakeStream = newCryptoStream()
-	s.sendQueue = newSendQueue(s.conn)
+	if s.config.SingleGoroutine {
+		s.sendQueue = newInlineSender(s.conn, s.destroyImpl)
+	} else {
+		s.sendQueue = newSendQueue(s.conn)
+	}
 	s.retransmissionQueue = newRetransmissionQueue()
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.config.AddressDiscovery.Receives(), s.config.EnableImmediateAck)
+	if len(s.config.ExtensionFrames) > 0 {
+		s.extensionFrames = make(map[uint64]*ExtensionFrame, len(s.config.ExtensionFrames))
+		for i := range s.config.ExtensionFrames {
+			f := &s.config.ExtensionFrames[i]
+			s.extensionFrames[f.Type] = f
+			s.frameParser.RegisterExtensionFrame(f.Type, f.AckEliciting, f.Decode)
+		}
+	}
 	s.rttStats = &utils.RTTStats{}
+	// When the window is auto-tuned, make sure that the socket buffers can hold the new window.
+	var socketBuffers *socketBufferTuner
+	if c, ok := s.conn.(interface{ socketBufferTuner() *socketBufferTuner }); ok {
+		socketBuffers = c.socketBufferTuner()
+	}
+	receiveWindow := protocol.ByteCount(s.config.InitialConnectionReceiveWindow)
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		protocol.ByteCount(s.config.MaxConnectionReceiveWindow),
 		s.onHasConnectionWindowUpdate,
 		func(size protocol.ByteCount) bool {
-			if s.config.AllowConnectionWindowIncrease == nil {
-				return true
+			if s.config.AllowConnectionWindowIncrease != nil && !s.config.AllowConnectionWindowIncrease(s, uint64(size)) {
+				return false
 			}
-			return s.config.AllowConnectionWindowIncrease(s, uint64(size))
+			receiveWindow += size
+			if socketBuffers != nil {
+				socketBuffers.EnsureWindow(receiveWindow)
+			}
+			return true
 		},
 		s.rttStats,
 		s.logger,
 	)
 	s.earlyConnReadyChan = make(chan struct{})
+	s.handshakeConfirmedChan = make(chan struct{})
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.RetransmissionPolicy,
+		s.config.RetransmissionDeadline,
+		s.clock,
 		s.perspective,
 	)
-	s.framer = newFramer(s.streamsMap)
+	s.sendRateLimiter = newTokenBucket(s.config.SendRateLimit, s.config.SendRateLimitBurst, s.clock)
+	s.framer = newFramer(s.streamsMap, s.sendRateLimiter)
 	s.receivedPackets = make(chan receivedPacket, protocol.MaxConnUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	s.loopFuncs = make(chan func())
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
-	now := time.Now()
+	now := s.clock.Now()
 	s.lastPacketReceivedTime = now
+	s.lastActivityTime = now
 	s.creationTime = now
+	s.connState.HandshakeTimeline.Start = now
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
-	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
+	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.tracer, s.logger)
+	s.observedAddrChan = make(chan struct{})
 	s.connState.Version = s.version
 }
 
@@ -498,7 +705,11 @@ func (s *connection) run() error {
 		s.ctxCancel(closeErr.err)
 	}()
 
-	s.timer = *newTimer()
+	if liveConns.Add(s) {
+		defer liveConns.Remove(s)
+	}
+
+	s.timer = *newTimer(s.clock, s.config.TimerGranularity)
 
 	if err := s.cryptoStreamHandler.StartHandshake(); err != nil {
 		return err
@@ -506,11 +717,14 @@ func (s *connection) run() error {
 	if err := s.handleHandshakeEvents(); err != nil {
 		return err
 	}
-	go func() {
-		if err := s.sendQueue.Run(); err != nil {
-			s.destroyImpl(err)
-		}
-	}()
+	// The inline sender writes packets from the run loop, and doesn't need a goroutine.
+	if !s.config.SingleGoroutine {
+		go func() {
+			if err := s.sendQueue.Run(); err != nil {
+				s.destroyImpl(err)
+			}
+		}()
+	}
 
 	if s.perspective == protocol.PerspectiveClient {
 		s.scheduleSending() // so the ClientHello actually gets sent
@@ -558,7 +772,23 @@ runLoop:
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-sendQueueAvailable:
+			case f := <-s.loopFuncs:
+				f()
+			case <-s.handshakeWorkerResult:
+				if err := s.handleHandshakeWorkerResult(); err != nil {
+					s.closeLocal(err)
+				}
 			case firstPacket := <-s.receivedPackets:
+				if s.config.AdaptivePacketBatching && runtime.GOMAXPROCS(0) == 1 {
+					// Give the goroutine reading from the socket a chance to queue more packets.
+					runtime.Gosched()
+				}
+				// If more packets are already queued (e.g. when they were received using GRO or recvmmsg),
+				// process the ACK frames they contain in a single batch.
+				batchAcks := s.handshakeComplete && len(s.receivedPackets) > 0
+				if batchAcks {
+					s.sentPacketHandler.StartAckBatch()
+				}
 				wasProcessed := s.handlePacketImpl(firstPacket)
 				// Don't set timers and send packets if the packet made us close the connection.
 				select {
@@ -571,6 +801,10 @@ runLoop:
 					// Limit the number of packets to the length of the receivedPackets channel,
 					// so we eventually get a chance to send out an ACK when receiving a lot of packets.
 					numPackets := len(s.receivedPackets)
+					if limit := s.config.MaxPacketsPerLoopIteration; limit > 0 && numPackets > limit-1 {
+						// the first packet was already processed
+						numPackets = limit - 1
+					}
 				receiveLoop:
 					for i := 0; i < numPackets; i++ {
 						select {
@@ -588,6 +822,11 @@ runLoop:
 						}
 					}
 				}
+				if batchAcks {
+					if err := s.sentPacketHandler.FinishAckBatch(); err != nil {
+						s.closeLocal(err)
+					}
+				}
 				// Only reset the timers if this packet was actually processed.
 				// This avoids modifying any state when handling undecryptable packets,
 				// which could be injected by an attacker.
@@ -597,7 +836,7 @@ runLoop:
 			}
 		}
 
-		now := time.Now()
+		now := s.clock.Now()
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
@@ -623,6 +862,20 @@ runLoop:
 			}
 		}
 
+		if s.handshakeComplete {
+			if err := s.connIDGenerator.MaybeRotate(now); err != nil {
+				s.closeLocal(err)
+			}
+		}
+		if s.pathValidation != nil && !now.Before(s.pathValidation.deadline) {
+			s.abandonPathValidation()
+		}
+
+		if s.closeWhenAcked != nil && !s.hasOutstandingData() {
+			s.closeLocal(s.closeWhenAcked)
+			continue
+		}
+
 		if s.sendQueue.WouldBlock() {
 			// The send queue is still busy sending out packets.
 			// Wait until there's space to enqueue new packets.
@@ -642,6 +895,7 @@ runLoop:
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close() // close the send queue before sending the CONNECTION_CLOSE
 	s.handleCloseError(&closeErr)
+	s.closeReboundConns()
 	if s.tracer != nil && s.tracer.Close != nil {
 		if e := (&errCloseForRecreating{}); !errors.As(closeErr.err, &e) {
 			s.tracer.Close()
@@ -649,6 +903,9 @@ runLoop:
 	}
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.timer.Stop()
+	if pool := s.decryptionPool.Load(); pool != nil {
+		pool.close()
+	}
 	return closeErr.err
 }
 
@@ -661,6 +918,10 @@ func (s *connection) HandshakeComplete() <-chan struct{} {
 	return s.handshakeCtx.Done()
 }
 
+func (s *connection) HandshakeConfirmed() <-chan struct{} {
+	return s.handshakeConfirmedChan
+}
+
 func (s *connection) Context() context.Context {
 	return s.ctx
 }
@@ -669,31 +930,135 @@ func (s *connection) supportsDatagrams() bool {
 	return s.peerParams.MaxDatagramFrameSize > 0
 }
 
+// runInLoop executes f on the run loop, and waits for it to return.
+// It returns false if the connection was closed before f could be executed.
+// It must not be called from the run loop.
+func (s *connection) runInLoop(f func()) bool {
+	done := make(chan struct{})
+	select {
+	case s.loopFuncs <- func() { f(); close(done) }:
+	case <-s.ctx.Done():
+		return false
+	}
+	<-done
+	return true
+}
+
+// reachedHandshakeMilestone records the time when a handshake milestone was reached.
+// Only the first time a milestone is reached is recorded.
+func (s *connection) reachedHandshakeMilestone(milestone logging.HandshakeMilestone, t time.Time) {
+	if s.handshakeMilestones&(1<<milestone) != 0 {
+		return
+	}
+	s.handshakeMilestones |= 1 << milestone
+
+	s.connStateMutex.Lock()
+	timeline := &s.connState.HandshakeTimeline
+	switch milestone {
+	case logging.HandshakeMilestoneFirstInitialSent:
+		timeline.FirstInitialSent = t
+	case logging.HandshakeMilestoneRetryReceived:
+		timeline.RetryReceived = t
+	case logging.HandshakeMilestoneFirstHandshakePacketReceived:
+		timeline.FirstHandshakePacketReceived = t
+	case logging.HandshakeMilestoneHandshakeComplete:
+		timeline.HandshakeComplete = t
+	case logging.HandshakeMilestoneHandshakeConfirmed:
+		timeline.HandshakeConfirmed = t
+	case logging.HandshakeMilestoneFirstAppDataSent:
+		timeline.FirstAppDataSent = t
+	case logging.HandshakeMilestoneFirstAppDataReceived:
+		timeline.FirstAppDataReceived = t
+	}
+	s.connStateMutex.Unlock()
+
+	if s.tracer != nil && s.tracer.ReachedHandshakeMilestone != nil {
+		s.tracer.ReachedHandshakeMilestone(milestone, t)
+	}
+}
+
 func (s *connection) ConnectionState() ConnectionState {
 	s.connStateMutex.Lock()
 	defer s.connStateMutex.Unlock()
 	cs := s.cryptoStreamHandler.ConnectionState()
 	s.connState.TLS = cs.ConnectionState
 	s.connState.Used0RTT = cs.Used0RTT
+	s.connState.KeyUpdates = KeyUpdateStats{
+		KeyPhase:      cs.LocalKeyUpdates + cs.RemoteKeyUpdates,
+		Initiated:     cs.LocalKeyUpdates,
+		PeerInitiated: cs.RemoteKeyUpdates,
+	}
 	s.connState.GSO = s.conn.capabilities().GSO
 	s.connState.LatestRTT = s.rttStats.LatestRTT()
+	s.connState.Bandwidth.DeliveryRate, s.connState.Bandwidth.AppLimited = s.sentPacketHandler.BandwidthEstimate()
 	return s.connState
 }
 
+func (s *connection) RTTStats() RTTStats {
+	snapshot := s.rttStats.Snapshot()
+	stats := RTTStats{
+		LatestRTT:    snapshot.LatestRTT,
+		SmoothedRTT:  snapshot.SmoothedRTT,
+		MinRTT:       snapshot.MinRTT,
+		RTTVariation: snapshot.MeanDeviation,
+	}
+	if len(snapshot.Samples) > 0 {
+		stats.Samples = make([]RTTSample, 0, len(snapshot.Samples))
+		for _, sample := range snapshot.Samples {
+			stats.Samples = append(stats.Samples, RTTSample{Time: sample.Time, RTT: sample.RTT})
+		}
+	}
+	return stats
+}
+
 // Time when the connection should time out
 func (s *connection) nextIdleTimeoutTime() time.Time {
 	idleTimeout := utils.Max(s.idleTimeout, s.rttStats.PTO(true)*3)
-	return s.idleTimeoutStartTime().Add(idleTimeout)
+	t := s.idleTimeoutStartTime().Add(idleTimeout)
+	if s.extendedIdleTimeout > 0 {
+		// Keep-alives don't count as activity.
+		t = utils.MinTime(t, s.lastActivityTime.Add(utils.Max(s.extendedIdleTimeout, s.rttStats.PTO(true)*3)))
+	}
+	return t
 }
 
 // Time when the next keep-alive packet should be sent.
 // It returns a zero time if no keep-alive should be sent.
 func (s *connection) nextKeepAliveTime() time.Time {
-	if s.config.KeepAlivePeriod == 0 || s.keepAlivePingSent || !s.firstAckElicitingPacketAfterIdleSentTime.IsZero() {
+	if (s.config.KeepAlivePeriod == 0 && s.extendedIdleTimeout == 0) || s.keepAlivePingSent || !s.firstAckElicitingPacketAfterIdleSentTime.IsZero() {
 		return time.Time{}
 	}
 	keepAliveInterval := utils.Max(s.keepAliveInterval, s.rttStats.PTO(true)*3/2)
-	return s.lastPacketReceivedTime.Add(keepAliveInterval)
+	t := s.lastPacketReceivedTime.Add(keepAliveInterval)
+	// When extending the idle timeout, there's no need to keep the connection alive beyond the extended idle timeout.
+	if s.config.KeepAlivePeriod == 0 && !t.Before(s.nextIdleTimeoutTime()) {
+		return time.Time{}
+	}
+	return t
+}
+
+// applyIdleTimeout sets the idle timeout and the keep-alive interval,
+// based on the negotiated idle timeout and the idle timeout set by SetIdleTimeout.
+func (s *connection) applyIdleTimeout() {
+	s.idleTimeout = s.negotiatedIdleTimeout
+	s.extendedIdleTimeout = 0
+	if t := s.requestedIdleTimeout; t > 0 {
+		if t <= s.negotiatedIdleTimeout {
+			s.idleTimeout = t
+		} else if s.config.KeepAlivePeriod == 0 {
+			s.extendedIdleTimeout = t
+		}
+	}
+	keepAlivePeriod := s.config.KeepAlivePeriod
+	if keepAlivePeriod == 0 && s.extendedIdleTimeout > 0 {
+		keepAlivePeriod = protocol.MaxKeepAliveInterval
+	}
+	// In low-power mode, send keep-alives as late as possible, while leaving enough time for the PING to arrive.
+	maxKeepAliveInterval := s.idleTimeout / 2
+	if s.config.LowPowerMode {
+		maxKeepAliveInterval = s.idleTimeout * 3 / 4
+	}
+	s.keepAliveInterval = utils.Min(keepAlivePeriod, utils.Min(maxKeepAliveInterval, protocol.MaxKeepAliveInterval))
 }
 
 func (s *connection) maybeResetTimer() {
@@ -704,11 +1069,19 @@ func (s *connection) maybeResetTimer() {
 			s.idleTimeoutStartTime().Add(s.config.HandshakeIdleTimeout),
 		)
 	} else {
+		deadline = s.nextIdleTimeoutTime()
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() {
-			deadline = keepAliveTime
-		} else {
-			deadline = s.nextIdleTimeoutTime()
+			deadline = utils.MinTime(deadline, keepAliveTime)
 		}
+		if rotationTime := s.connIDGenerator.NextRotationTime(); !rotationTime.IsZero() {
+			deadline = utils.MinTime(deadline, rotationTime)
+		}
+		if s.pathValidation != nil {
+			deadline = utils.MinTime(deadline, s.pathValidation.deadline)
+		}
+	}
+	if sendTime := s.sendRateLimiter.NextSendTime(); !sendTime.IsZero() && s.framer.HasData() {
+		deadline = utils.MinTime(deadline, sendTime)
 	}
 
 	s.timer.SetTimer(
@@ -728,16 +1101,21 @@ func (s *connection) handleHandshakeComplete() error {
 	// Once the handshake completes, we have derived 1-RTT keys.
 	// There's no point in queueing undecryptable packets for later decryption anymore.
 	s.undecryptablePackets = nil
+	s.startDecryptionPool()
 
 	s.connIDManager.SetHandshakeComplete()
-	s.connIDGenerator.SetHandshakeComplete()
+	now := s.clock.Now()
+	s.reachedHandshakeMilestone(logging.HandshakeMilestoneHandshakeComplete, now)
+	s.connIDGenerator.SetHandshakeComplete(now)
 
 	// The server applies transport parameters right away, but the client side has to wait for handshake completion.
 	// During a 0-RTT connection, the client is only allowed to use the new transport parameters for 1-RTT packets.
 	if s.perspective == protocol.PerspectiveClient {
 		s.applyTransportParameters()
+		s.startAddressDiscovery()
 		return nil
 	}
+	s.startAddressDiscovery()
 
 	// All these only apply to the server side.
 	if err := s.handleHandshakeConfirmed(); err != nil {
@@ -763,27 +1141,74 @@ func (s *connection) handleHandshakeComplete() error {
 	return nil
 }
 
+// startAddressDiscovery is called when the handshake completes.
+// It reports the peer's address, if the peer asked for it.
+func (s *connection) startAddressDiscovery() {
+	s.maybeQueueObservedAddress()
+	if s.config.AddressDiscovery.Receives() && !s.peerParams.AddressDiscoveryMode.Provides() {
+		s.connStateMutex.Lock()
+		s.observedAddrErr = errors.New("peer doesn't provide observed addresses")
+		close(s.observedAddrChan)
+		s.connStateMutex.Unlock()
+	}
+}
+
+// maybeQueueObservedAddress reports the peer's current address, if the peer asked for it.
+// The address is reported when the handshake completes, and again every time the peer migrates to a new path.
+func (s *connection) maybeQueueObservedAddress() {
+	if !s.config.AddressDiscovery.Provides() || !s.peerParams.AddressDiscoveryMode.Receives() {
+		return
+	}
+	if addr, ok := s.conn.RemoteAddr().(*net.UDPAddr); ok {
+		s.queueControlFrame(&wire.ObservedAddressFrame{SequenceNumber: s.nextObservedAddrSeq, Address: addr.AddrPort()})
+		s.nextObservedAddrSeq++
+	}
+}
+
 func (s *connection) handleHandshakeConfirmed() error {
 	if err := s.dropEncryptionLevel(protocol.EncryptionHandshake); err != nil {
 		return err
 	}
 
 	s.handshakeConfirmed = true
+	s.reachedHandshakeMilestone(logging.HandshakeMilestoneHandshakeConfirmed, s.clock.Now())
 	s.sentPacketHandler.SetHandshakeConfirmed()
 	s.cryptoStreamHandler.SetHandshakeConfirmed()
+	// For the server, the handshake is confirmed as soon as it completes.
+	// Make sure that the HandshakeComplete channel is closed first.
+	s.handshakeCtxCancel()
+	close(s.handshakeConfirmedChan)
 
 	if !s.config.DisablePathMTUDiscovery && s.conn.capabilities().DF {
 		maxPacketSize := s.peerParams.MaxUDPPayloadSize
 		if maxPacketSize == 0 {
 			maxPacketSize = protocol.MaxByteCount
 		}
+		if limit := s.conn.capabilities().MaxPacketSize; limit > 0 {
+			maxPacketSize = utils.Min(maxPacketSize, limit)
+		}
 		s.mtuDiscoverer.Start(utils.Min(maxPacketSize, protocol.MaxPacketBufferSize))
 	}
 	return nil
 }
 
+// onMTUBlackHoleDetected is called when packets of the current size stopped being delivered.
+func (s *connection) onMTUBlackHoleDetected(oldSize, newSize protocol.ByteCount) {
+	if s.logger.Debug() {
+		s.logger.Debugf("PMTU black hole detected. Decreasing the MTU from %d to %d bytes.", oldSize, newSize)
+	}
+	s.sentPacketHandler.SetMaxDatagramSize(newSize)
+	if s.tracer != nil && s.tracer.DetectedMTUBlackHole != nil {
+		s.tracer.DetectedMTUBlackHole(oldSize, newSize)
+	}
+}
+
 func (s *connection) handlePacketImpl(rp receivedPacket) bool {
-	s.sentPacketHandler.ReceivedBytes(rp.Size())
+	// While the server validates the path the client migrated to,
+	// only bytes received on that path count towards the anti-amplification limit.
+	if s.pathValidation == nil || s.perspective == protocol.PerspectiveClient || s.migratingConn.isCurrentPath(rp.rcvConn, rp.remoteAddr) {
+		s.sentPacketHandler.ReceivedBytes(rp.Size())
+	}
 
 	if wire.IsVersionNegotiationPacket(rp.data) {
 		s.handleVersionNegotiationPacket(rp)
@@ -802,7 +1227,7 @@ func (s *connection) handlePacketImpl(rp receivedPacket) bool {
 			p.data = data
 
 			var err error
-			destConnID, err = wire.ParseConnectionID(p.data, s.srcConnIDLen)
+			destConnID, err = parseConnectionID(p.data, s.shortHdrConnIDLen)
 			if err != nil {
 				if s.tracer != nil && s.tracer.DroppedPacket != nil {
 					s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, protocol.ByteCount(len(data)), logging.PacketDropHeaderParseError)
@@ -881,7 +1306,18 @@ func (s *connection) handleShortHeaderPacket(p receivedPacket, destConnID protoc
 		}
 	}()
 
-	pn, pnLen, keyPhase, data, err := s.unpacker.UnpackShortHeader(p.rcvTime, p.data)
+	var pn protocol.PacketNumber
+	var pnLen protocol.PacketNumberLen
+	var keyPhase protocol.KeyPhaseBit
+	var data []byte
+	var err error
+	if p.opened != nil {
+		pn, pnLen, keyPhase, data, err = s.unpacker.UnpackOpenedShortHeader(p.rcvTime, p.opened)
+		putOpenedShortHeaderPacket(p.opened)
+		p.opened = nil
+	} else {
+		pn, pnLen, keyPhase, data, err = s.unpacker.UnpackShortHeader(p.rcvTime, p.data)
+	}
 	if err != nil {
 		wasQueued = s.handleUnpackError(err, p, logging.PacketType1RTT)
 		return false
@@ -916,10 +1352,14 @@ func (s *connection) handleShortHeaderPacket(p receivedPacket, destConnID protoc
 			)
 		}
 	}
-	if err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log); err != nil {
+	isNonProbing, err := s.handleUnpackedShortHeaderPacket(destConnID, pn, data, p.ecn, p.rcvTime, log)
+	if err != nil {
 		s.closeLocal(err)
 		return false
 	}
+	if s.migratingConn != nil && s.perspective == protocol.PerspectiveServer {
+		s.maybeMigrate(p, pn, isNonProbing)
+	}
 	return true
 }
 
@@ -974,6 +1414,15 @@ func (s *connection) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header)
 	}
 
 	if err := s.handleUnpackedLongHeaderPacket(packet, p.ecn, p.rcvTime, p.Size()); err != nil {
+		if err == errExceeded0RTTLimits {
+			// Don't acknowledge the packet.
+			// The client will declare it lost, and retransmit the frames in 1-RTT packets.
+			if s.tracer != nil && s.tracer.DroppedPacket != nil {
+				s.tracer.DroppedPacket(logging.PacketType0RTT, p.Size(), logging.PacketDropDOSPrevention)
+			}
+			s.logger.Debugf("Dropping 0-RTT packet (%d bytes) that exceeds the 0-RTT limits.", p.Size())
+			return false
+		}
 		s.closeLocal(err)
 		return false
 	}
@@ -1069,6 +1518,7 @@ func (s *connection) handleRetryPacket(hdr *wire.Header, data []byte, rcvTime ti
 	}
 	newDestConnID := hdr.SrcConnectionID
 	s.receivedRetry = true
+	s.reachedHandshakeMilestone(logging.HandshakeMilestoneRetryReceived, rcvTime)
 	if err := s.sentPacketHandler.ResetForRetry(rcvTime); err != nil {
 		s.closeLocal(err)
 		return false
@@ -1115,7 +1565,7 @@ func (s *connection) handleVersionNegotiationPacket(p receivedPacket) {
 	if s.tracer != nil && s.tracer.ReceivedVersionNegotiationPacket != nil {
 		s.tracer.ReceivedVersionNegotiationPacket(dest, src, supportedVersions)
 	}
-	newVersion, ok := protocol.ChooseSupportedVersion(s.config.Versions, supportedVersions)
+	newVersion, ok := s.chooseVersion(supportedVersions)
 	if !ok {
 		s.destroyImpl(&VersionNegotiationError{
 			Ours:   s.config.Versions,
@@ -1133,9 +1583,30 @@ func (s *connection) handleVersionNegotiationPacket(p receivedPacket) {
 	s.destroyImpl(&errCloseForRecreating{
 		nextPacketNumber: nextPN,
 		nextVersion:      newVersion,
+		serverVersions:   supportedVersions,
 	})
 }
 
+// chooseVersion chooses the QUIC version to restart the handshake with, after receiving a Version Negotiation packet.
+func (s *connection) chooseVersion(serverVersions []protocol.VersionNumber) (protocol.VersionNumber, bool) {
+	if s.config.ChooseVersion == nil {
+		return protocol.ChooseSupportedVersion(s.config.Versions, serverVersions)
+	}
+	v, ok := s.config.ChooseVersion(&VersionNegotiationInfo{
+		RemoteAddr:     s.conn.RemoteAddr(),
+		ClientVersion:  s.version,
+		ServerVersions: serverVersions,
+	})
+	if !ok {
+		return 0, false
+	}
+	if !protocol.IsSupportedVersion(protocol.SupportedVersions, v) || !protocol.IsSupportedVersion(serverVersions, v) {
+		s.logger.Infof("ChooseVersion returned a version that is not supported by both endpoints: %s", v)
+		return 0, false
+	}
+	return v, true
+}
+
 func (s *connection) handleUnpackedLongHeaderPacket(
 	packet *unpackedPacket,
 	ecn protocol.ECN,
@@ -1190,6 +1661,10 @@ func (s *connection) handleUnpackedLongHeaderPacket(
 		}
 	}
 
+	if packet.encryptionLevel == protocol.EncryptionHandshake {
+		s.reachedHandshakeMilestone(logging.HandshakeMilestoneFirstHandshakePacketReceived, rcvTime)
+	}
+
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
@@ -1200,7 +1675,7 @@ func (s *connection) handleUnpackedLongHeaderPacket(
 			s.tracer.ReceivedLongHeaderPacket(packet.hdr, packetSize, ecn, frames)
 		}
 	}
-	isAckEliciting, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
+	isAckEliciting, _, err := s.handleFrames(packet.data, packet.hdr.DestConnectionID, packet.encryptionLevel, log)
 	if err != nil {
 		return err
 	}
@@ -1214,16 +1689,16 @@ func (s *connection) handleUnpackedShortHeaderPacket(
 	ecn protocol.ECN,
 	rcvTime time.Time,
 	log func([]logging.Frame),
-) error {
+) (isNonProbing bool, _ error) {
 	s.lastPacketReceivedTime = rcvTime
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
-	isAckEliciting, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
+	isAckEliciting, isNonProbing, err := s.handleFrames(data, destConnID, protocol.Encryption1RTT, log)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
+	return isNonProbing, s.receivedPacketHandler.ReceivedPacket(pn, ecn, protocol.Encryption1RTT, rcvTime, isAckEliciting)
 }
 
 func (s *connection) handleFrames(
@@ -1231,11 +1706,12 @@ func (s *connection) handleFrames(
 	destConnID protocol.ConnectionID,
 	encLevel protocol.EncryptionLevel,
 	log func([]logging.Frame),
-) (isAckEliciting bool, _ error) {
+) (isAckEliciting, isNonProbing bool, _ error) {
 	// Only used for tracing.
 	// If we're not tracing, this slice will always remain empty.
 	var frames []logging.Frame
-	if log != nil {
+	traceFrames := log != nil && !s.tracer.OmitFrames
+	if traceFrames {
 		frames = make([]logging.Frame, 0, 4)
 	}
 	handshakeWasComplete := s.handshakeComplete
@@ -1243,7 +1719,7 @@ func (s *connection) handleFrames(
 	for len(data) > 0 {
 		l, frame, err := s.frameParser.ParseNext(data, encLevel, s.version)
 		if err != nil {
-			return false, err
+			return false, false, err
 		}
 		data = data[l:]
 		if frame == nil {
@@ -1252,7 +1728,13 @@ func (s *connection) handleFrames(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
-		if log != nil {
+		if isActivityFrame(frame) {
+			s.lastActivityTime = s.lastPacketReceivedTime
+		}
+		if !wire.IsProbingFrame(frame) {
+			isNonProbing = true
+		}
+		if traceFrames {
 			frames = append(frames, logutils.ConvertFrame(frame))
 		}
 		// An error occurred handling a previous frame.
@@ -1262,7 +1744,7 @@ func (s *connection) handleFrames(
 		}
 		if err := s.handleFrame(frame, encLevel, destConnID); err != nil {
 			if log == nil {
-				return false, err
+				return false, false, err
 			}
 			// If we're logging, we need to keep parsing (but not handling) all frames.
 			handleErr = err
@@ -1272,7 +1754,7 @@ func (s *connection) handleFrames(
 	if log != nil {
 		log(frames)
 		if handleErr != nil {
-			return false, handleErr
+			return false, false, handleErr
 		}
 	}
 
@@ -1282,7 +1764,7 @@ func (s *connection) handleFrames(
 	// and an ACK serialized after that CRYPTO frame. In this case, we still want to process the ACK frame.
 	if !handshakeWasComplete && s.handshakeComplete {
 		if err := s.handleHandshakeComplete(); err != nil {
-			return false, err
+			return false, false, err
 		}
 	}
 
@@ -1290,13 +1772,19 @@ func (s *connection) handleFrames(
 }
 
 func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, destConnID protocol.ConnectionID) error {
+	if encLevel == protocol.Encryption0RTT && s.zeroRTTLimiter != nil && !s.zeroRTTLimiter.Allow(f) {
+		return errExceeded0RTTLimits
+	}
 	var err error
 	wire.LogFrame(s.logger, f, false)
 	switch frame := f.(type) {
 	case *wire.CryptoFrame:
 		err = s.handleCryptoFrame(frame, encLevel)
 	case *wire.StreamFrame:
-		err = s.handleStreamFrame(frame)
+		if encLevel == protocol.Encryption1RTT {
+			s.reachedHandshakeMilestone(logging.HandshakeMilestoneFirstAppDataReceived, s.lastPacketReceivedTime)
+		}
+		err = s.handleStreamFrame(frame, encLevel)
 	case *wire.AckFrame:
 		err = s.handleAckFrame(frame, encLevel)
 	case *wire.ConnectionCloseFrame:
@@ -1318,8 +1806,7 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1329,7 +1816,17 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.HandshakeDoneFrame:
 		err = s.handleHandshakeDoneFrame()
 	case *wire.DatagramFrame:
+		if encLevel == protocol.Encryption1RTT {
+			s.reachedHandshakeMilestone(logging.HandshakeMilestoneFirstAppDataReceived, s.lastPacketReceivedTime)
+		}
 		err = s.handleDatagramFrame(frame)
+	case *wire.ObservedAddressFrame:
+		err = s.handleObservedAddressFrame(frame)
+	case *wire.ImmediateAckFrame:
+		// The packet is acknowledged once all frames were handled.
+		s.receivedPacketHandler.ImmediateAckRequested(encLevel)
+	case *wire.ExtensionFrame:
+		err = s.handleExtensionFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1338,17 +1835,54 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 
 // handlePacket is called by the server with a new packet
 func (s *connection) handlePacket(p receivedPacket) {
+	if s.config.Clock != nil {
+		// The receive time was taken from the system clock when reading the packet from the socket.
+		p.rcvTime = s.clock.Now()
+	}
+	if pool := s.decryptionPool.Load(); pool != nil {
+		if !pool.submit(p) {
+			s.dropQueuedPacket(p)
+		}
+		return
+	}
+	s.queueReceivedPacket(p)
+}
+
+func (s *connection) queueReceivedPacket(p receivedPacket) {
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxConnUnprocessedPackets
 	select {
 	case s.receivedPackets <- p:
 	default:
-		if s.tracer != nil && s.tracer.DroppedPacket != nil {
-			s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
-		}
+		s.dropQueuedPacket(p)
+	}
+}
+
+func (s *connection) dropQueuedPacket(p receivedPacket) {
+	if p.opened != nil {
+		putOpenedShortHeaderPacket(p.opened)
+	}
+	if s.tracer != nil && s.tracer.DroppedPacket != nil {
+		s.tracer.DroppedPacket(logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
 	}
 }
 
+// startDecryptionPool starts decrypting 1-RTT packets on multiple goroutines, if configured.
+func (s *connection) startDecryptionPool() {
+	if s.config.DecryptionWorkers <= 1 {
+		return
+	}
+	opener, err := s.cryptoStreamHandler.Get1RTTOpener()
+	if err != nil {
+		return
+	}
+	concurrentOpener, ok := opener.(handshake.ConcurrentShortHeaderOpener)
+	if !ok {
+		return
+	}
+	s.decryptionPool.Store(newDecryptionPool(concurrentOpener, s.config.DecryptionWorkers, s.shortHdrConnIDLen, s.queueReceivedPacket))
+}
+
 func (s *connection) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
 	if frame.IsApplicationError {
 		s.closeRemote(&qerr.ApplicationError{
@@ -1373,6 +1907,28 @@ func (s *connection) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protoco
 	return s.handleHandshakeEvents()
 }
 
+// scheduleHandshakeWorkerResult is called by the HandshakeWorkerPool when handshake messages were handled.
+func (s *connection) scheduleHandshakeWorkerResult() {
+	select {
+	case s.handshakeWorkerResult <- struct{}{}:
+	default:
+	}
+}
+
+func (s *connection) handleHandshakeWorkerResult() error {
+	handshakeWasComplete := s.handshakeComplete
+	if err := s.cryptoStreamHandler.HandleWorkerResults(); err != nil {
+		return err
+	}
+	if err := s.handleHandshakeEvents(); err != nil {
+		return err
+	}
+	if !handshakeWasComplete && s.handshakeComplete {
+		return s.handleHandshakeComplete()
+	}
+	return nil
+}
+
 func (s *connection) handleHandshakeEvents() error {
 	for {
 		ev := s.cryptoStreamHandler.NextEvent()
@@ -1406,7 +1962,7 @@ func (s *connection) handleHandshakeEvents() error {
 	}
 }
 
-func (s *connection) handleStreamFrame(frame *wire.StreamFrame) error {
+func (s *connection) handleStreamFrame(frame *wire.StreamFrame, encLevel protocol.EncryptionLevel) error {
 	str, err := s.streamsMap.GetOrOpenReceiveStream(frame.StreamID)
 	if err != nil {
 		return err
@@ -1416,6 +1972,11 @@ func (s *connection) handleStreamFrame(frame *wire.StreamFrame) error {
 		// ignore this StreamFrame
 		return nil
 	}
+	// Record the data received in 0-RTT before handling the frame,
+	// such that it's marked before the application can read it.
+	if encLevel == protocol.Encryption0RTT && frame.DataLen() > 0 {
+		str.receivedEarlyData(frame.Offset, frame.Offset+frame.DataLen())
+	}
 	return str.handleStreamFrame(frame)
 }
 
@@ -1469,6 +2030,78 @@ func (s *connection) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *connection) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	if s.migratingConn == nil {
+		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	// PATH_RESPONSEs for earlier path validations are ignored.
+	if s.pathValidation != nil && frame.Data == s.pathValidation.challenge {
+		s.logger.Debugf("Validated path to %s.", s.conn.RemoteAddr())
+		s.sentPacketHandler.ValidatedPath()
+		if s.pathValidation.rebound != nil {
+			s.reboundPathValidated(s.pathValidation.rebound)
+		}
+		s.pathValidation = nil
+	}
+	return nil
+}
+
+// maybeMigrate migrates the connection to the path a packet was received on.
+// Following section 9.3 of RFC 9000, only a non-probing packet that has the largest packet number
+// received so far causes a migration, and only once the handshake is confirmed.
+func (s *connection) maybeMigrate(p receivedPacket, pn protocol.PacketNumber, isNonProbing bool) {
+	isLargest := pn > s.largestRcvd1RTTPacketNumber
+	if isLargest {
+		s.largestRcvd1RTTPacketNumber = pn
+	}
+	if p.rcvConn == nil || !isLargest || !isNonProbing || !s.handshakeConfirmed {
+		return
+	}
+	if s.migratingConn.isCurrentPath(p.rcvConn, p.remoteAddr) {
+		return
+	}
+	s.logger.Debugf("Peer migrated from %s (local %s) to %s (local %s).", s.conn.RemoteAddr(), s.conn.LocalAddr(), p.remoteAddr, p.rcvConn.LocalAddr())
+	// A change of only the port is likely the result of a NAT rebinding,
+	// in which case the congestion controller and the RTT estimate are kept (section 9.4 of RFC 9000).
+	ipChanged := !isSameIP(s.conn.RemoteAddr(), p.remoteAddr) || !isSameIP(s.conn.LocalAddr(), p.rcvConn.LocalAddr())
+	prev := s.migratingConn.switchPath(newSendConn(p.rcvConn, p.remoteAddr, p.info, s.logger))
+	// If the peer migrates again before the new path was validated,
+	// keep the last validated path, since that's the one we fall back to.
+	if s.pathValidation != nil {
+		prev = s.pathValidation.previous
+	}
+	pto := s.rttStats.PTO(true)
+	// Until the new path is validated, we can send at most 3x the amount of data received on it.
+	s.sentPacketHandler.MigratedPath(p.Size(), ipChanged)
+	s.pathValidation = &pathValidation{
+		previous: prev,
+		deadline: s.clock.Now().Add(3 * utils.Max(pto, s.rttStats.PTO(true))),
+	}
+	rand.Read(s.pathValidation.challenge[:])
+	s.queueControlFrame(&wire.PathChallengeFrame{Data: s.pathValidation.challenge})
+	s.maybeQueueObservedAddress()
+}
+
+// abandonPathValidation is called when the new path can't be validated in time.
+// The connection falls back to the last validated path.
+func (s *connection) abandonPathValidation() {
+	s.logger.Debugf("Path validation for %s failed. Falling back to %s.", s.conn.RemoteAddr(), s.pathValidation.previous.RemoteAddr())
+	s.migratingConn.switchPath(s.pathValidation.previous)
+	s.sentPacketHandler.ValidatedPath()
+	if s.pathValidation.rebound != nil {
+		s.pathValidation.rebound.Close()
+		// The connection ID was already used on the new path.
+		// Continuing to use it on the old path would allow linking the two paths.
+		// The connection ID used before the rebind was retired, so switch to a new one.
+		// Since the peer replaces retired connection IDs, one is usually available by now.
+		if !s.connIDManager.SwitchConnectionID() {
+			s.logger.Debugf("No unused connection ID available. Keeping the connection ID used on the abandoned path.")
+		}
+	}
+	s.pathValidation = nil
+}
+
 func (s *connection) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1490,6 +2123,16 @@ func (s *connection) handleRetireConnectionIDFrame(f *wire.RetireConnectionIDFra
 	return s.connIDGenerator.Retire(f.SequenceNumber, destConnID)
 }
 
+func (s *connection) connectionIDRetired(seq uint64, connID protocol.ConnectionID, byPeer bool) {
+	if s.config.ConnectionIDRetired != nil {
+		s.config.ConnectionIDRetired(s, RetiredConnectionIDInfo{
+			SequenceNumber: seq,
+			ConnectionID:   connID,
+			RetiredByPeer:  byPeer,
+		})
+	}
+}
+
 func (s *connection) handleHandshakeDoneFrame() error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1504,10 +2147,17 @@ func (s *connection) handleHandshakeDoneFrame() error {
 }
 
 func (s *connection) handleAckFrame(frame *wire.AckFrame, encLevel protocol.EncryptionLevel) error {
+	s.ackDelay = utils.Min(frame.DelayTime, s.rttStats.MaxAckDelay())
 	acked1RTTPacket, err := s.sentPacketHandler.ReceivedAck(frame, encLevel, s.lastPacketReceivedTime)
 	if err != nil {
 		return err
 	}
+	if encLevel == protocol.Encryption1RTT && s.gsoBlackholeDetector.ReceivedAck(frame) {
+		if s.logger.Debug() {
+			s.logger.Debugf("GSO batches are being dropped. Disabling GSO.")
+		}
+		s.conn.disableGSO(logging.GSODisabledBlackholed)
+	}
 	if !acked1RTTPacket {
 		return nil
 	}
@@ -1533,6 +2183,49 @@ func (s *connection) handleDatagramFrame(f *wire.DatagramFrame) error {
 	return nil
 }
 
+func (s *connection) handleObservedAddressFrame(f *wire.ObservedAddressFrame) error {
+	if !s.peerParams.AddressDiscoveryMode.Provides() {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "received OBSERVED_ADDRESS frame, although the peer doesn't provide observed addresses",
+		}
+	}
+
+	s.connStateMutex.Lock()
+	defer s.connStateMutex.Unlock()
+	if s.observedAddr != nil && f.SequenceNumber <= s.observedAddrSeq {
+		// OBSERVED_ADDRESS frames might be reordered, and only the most recent one is relevant
+		return nil
+	}
+	if s.observedAddr == nil {
+		close(s.observedAddrChan)
+	}
+	s.observedAddr = net.UDPAddrFromAddrPort(f.Address)
+	s.observedAddrSeq = f.SequenceNumber
+	return nil
+}
+
+func (s *connection) handleExtensionFrame(f *wire.ExtensionFrame) error {
+	ef := s.extensionFrames[f.FrameType]
+	if _, ok := s.peerParams.CustomParameters[ef.TransportParameter]; !ok {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("received extension frame of type %#x, although the peer doesn't support it", f.FrameType),
+		}
+	}
+	if ef.Handle == nil {
+		return nil
+	}
+	if err := ef.Handle(s, f.Value); err != nil {
+		return &qerr.TransportError{
+			FrameType:    f.FrameType,
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return nil
+}
+
 // closeLocal closes the connection and send a CONNECTION_CLOSE containing the error
 func (s *connection) closeLocal(e error) {
 	s.closeOnce.Do(func() {
@@ -1585,6 +2278,35 @@ func (s *connection) CloseWithError(code ApplicationErrorCode, desc string) erro
 	return nil
 }
 
+func (s *connection) CloseWithTimeout(code ApplicationErrorCode, desc string, timeout time.Duration) error {
+	e := &qerr.ApplicationError{
+		ErrorCode:    code,
+		ErrorMessage: desc,
+	}
+	if ok := s.runInLoop(func() { s.closeWhenAcked = e }); !ok {
+		return nil
+	}
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.ctx.Done():
+		return nil
+	case <-timer.Chan():
+		s.closeLocal(e)
+		<-s.ctx.Done()
+		return ErrCloseTimeout
+	}
+}
+
+// hasOutstandingData says if there's any data that was not yet sent or not yet acknowledged.
+func (s *connection) hasOutstandingData() bool {
+	return s.framer.HasData() ||
+		s.retransmissionQueue.HasInitialData() ||
+		s.retransmissionQueue.HasHandshakeData() ||
+		s.retransmissionQueue.HasAppData() ||
+		s.sentPacketHandler.GetBytesInFlight() > 0
+}
+
 func (s *connection) handleCloseError(closeErr *closeError) {
 	e := closeErr.err
 	if e == nil {
@@ -1616,6 +2338,9 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 			ErrorMessage: e.Error(),
 		}
 	}
+	if !s.handshakeComplete && !errors.As(e, &recreateErr) {
+		e = s.newHandshakeError(e)
+	}
 
 	s.streamsMap.CloseWithError(e)
 	s.connIDManager.Close()
@@ -1649,6 +2374,54 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	s.connIDGenerator.ReplaceWithClosed(s.perspective, connClosePacket)
 }
 
+// newHandshakeError classifies the error that caused the handshake to fail,
+// and reports the failure to the tracer.
+func (s *connection) newHandshakeError(e error) *HandshakeError {
+	hsErr := &HandshakeError{
+		Reason:          HandshakeFailureOther,
+		EncryptionLevel: protocol.EncryptionInitial,
+		Err:             e,
+	}
+	if s.handshakeMilestones&(1<<logging.HandshakeMilestoneFirstHandshakePacketReceived) != 0 {
+		hsErr.EncryptionLevel = protocol.EncryptionHandshake
+	}
+	var (
+		versionNegotiationErr *VersionNegotiationError
+		certErr               *tls.CertificateVerificationError
+		transportErr          *TransportError
+	)
+	switch {
+	case errors.As(e, &versionNegotiationErr):
+		hsErr.Reason = HandshakeFailureNoCompatibleVersion
+	case errors.Is(e, qerr.ErrHandshakeTimeout), errors.Is(e, qerr.ErrIdleTimeout):
+		hsErr.Reason = HandshakeFailureTimeout
+		if s.sentPacketHandler.IsAmplificationLimited() {
+			hsErr.Reason = HandshakeFailureAmplificationLimited
+		}
+	case errors.As(e, &certErr):
+		hsErr.Reason = HandshakeFailureCertificate
+		hsErr.Certificates = certErr.UnverifiedCertificates
+	case errors.As(e, &transportErr) && transportErr.ErrorCode.IsCryptoError():
+		// crypto errors carry the TLS alert, see section 20.1 of RFC 9000
+		switch transportErr.ErrorCode - 0x100 {
+		case 120: // no_application_protocol
+			hsErr.Reason = HandshakeFailureALPNMismatch
+		case 42, // bad_certificate
+			43,  // unsupported_certificate
+			44,  // certificate_revoked
+			45,  // certificate_expired
+			46,  // certificate_unknown
+			48,  // unknown_ca
+			116: // certificate_required
+			hsErr.Reason = HandshakeFailureCertificate
+		}
+	}
+	if s.tracer != nil && s.tracer.FailedHandshake != nil {
+		s.tracer.FailedHandshake(hsErr.Reason, hsErr.EncryptionLevel, e)
+	}
+	return hsErr
+}
+
 func (s *connection) dropEncryptionLevel(encLevel protocol.EncryptionLevel) error {
 	if s.tracer != nil && s.tracer.DroppedEncryptionLevel != nil {
 		s.tracer.DroppedEncryptionLevel(encLevel)
@@ -1751,8 +2524,8 @@ func (s *connection) checkTransportParameters(params *wire.TransportParameters)
 func (s *connection) applyTransportParameters() {
 	params := s.peerParams
 	// Our local idle timeout will always be > 0.
-	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
-	s.keepAliveInterval = utils.Min(s.config.KeepAlivePeriod, utils.Min(s.idleTimeout/2, protocol.MaxKeepAliveInterval))
+	s.negotiatedIdleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
+	s.applyIdleTimeout()
 	s.streamsMap.UpdateLimits(params)
 	s.frameParser.SetAckDelayExponent(params.AckDelayExponent)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
@@ -1761,25 +2534,37 @@ func (s *connection) applyTransportParameters() {
 	if params.StatelessResetToken != nil {
 		s.connIDManager.SetStatelessResetToken(*params.StatelessResetToken)
 	}
-	// We don't support connection migration yet, so we don't have any use for the preferred_address.
+	// Clients don't migrate to the preferred_address on their own (they can be moved using Rebind),
+	// but the connection ID is used like any other connection ID issued by the server.
 	if params.PreferredAddress != nil {
 		// Retire the connection ID.
 		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
+	s.connStateMutex.Lock()
+	s.connState.PeerTransportParameters = newTransportParameters(params)
+	if len(s.extensionFrames) > 0 {
+		s.connState.ExtensionFrames = supportedExtensionFrames(s.extensionFrames, params)
+	}
+	s.connStateMutex.Unlock()
+	if s.config.EnableImmediateAck && params.ImmediateAck {
+		s.packer.EnableImmediateAck()
+	}
 }
 
 func (s *connection) triggerSending() error {
 	s.pacingDeadline = time.Time{}
-	now := time.Now()
+	now := s.clock.Now()
 
 	sendMode := s.sentPacketHandler.SendMode(now)
 	//nolint:exhaustive // No need to handle pacing limited here.
 	switch sendMode {
 	case ackhandler.SendAny:
+		s.setCongestionLimited(false)
 		return s.sendPackets(now)
 	case ackhandler.SendNone:
 		return nil
 	case ackhandler.SendPacingLimited:
+		s.setCongestionLimited(false)
 		deadline := s.sentPacketHandler.TimeUntilSend()
 		if deadline.IsZero() {
 			deadline = deadlineSendImmediately
@@ -1793,6 +2578,9 @@ func (s *connection) triggerSending() error {
 		// We can at most send a single ACK only packet.
 		// There will only be a new ACK after receiving new packets.
 		// SendAck is only returned when we're congestion limited, so we don't need to set the pacinggs timer.
+		if sendMode == ackhandler.SendAck {
+			s.setCongestionLimited(true)
+		}
 		return s.maybeSendAckOnlyPacket(now)
 	case ackhandler.SendPTOInitial:
 		if err := s.sendProbePacket(protocol.EncryptionInitial, now); err != nil {
@@ -1826,6 +2614,17 @@ func (s *connection) triggerSending() error {
 	}
 }
 
+// setCongestionLimited notifies the streams with data to send when the connection becomes congestion limited.
+func (s *connection) setCongestionLimited(limited bool) {
+	if limited == s.congestionLimited {
+		return
+	}
+	s.congestionLimited = limited
+	if limited {
+		s.framer.OnCongestionLimited()
+	}
+}
+
 func (s *connection) sendPackets(now time.Time) error {
 	// Path MTU Discovery
 	// Can't use GSO, since we need to send a single packet that's larger than our current maximum size.
@@ -1882,9 +2681,10 @@ func (s *connection) sendPacketsWithoutGSO(now time.Time) error {
 	for {
 		buf := getPacketBuffer()
 		ecn := s.sentPacketHandler.ECNMode(true)
-		if _, err := s.appendOneShortHeaderPacket(buf, s.mtuDiscoverer.CurrentSize(), ecn, now); err != nil {
+		if _, _, err := s.appendOneShortHeaderPacket(buf, s.mtuDiscoverer.CurrentSize(), ecn, now); err != nil {
 			if err == errNothingToPack {
 				buf.Release()
+				s.sentPacketHandler.OnAppLimited()
 				return nil
 			}
 			return err
@@ -1901,6 +2701,7 @@ func (s *connection) sendPacketsWithoutGSO(now time.Time) error {
 			return nil
 		}
 		if sendMode != ackhandler.SendAny {
+			s.setCongestionLimited(sendMode == ackhandler.SendAck)
 			return nil
 		}
 		// Prioritize receiving of packets over sending out more packets.
@@ -1916,13 +2717,22 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 	maxSize := s.mtuDiscoverer.CurrentSize()
 
 	ecn := s.sentPacketHandler.ECNMode(true)
+	firstPN := protocol.InvalidPacketNumber
+	var lastPN protocol.PacketNumber
 	for {
 		var dontSendMore bool
-		size, err := s.appendOneShortHeaderPacket(buf, maxSize, ecn, now)
+		pn, size, err := s.appendOneShortHeaderPacket(buf, maxSize, ecn, now)
+		if err == nil {
+			if firstPN == protocol.InvalidPacketNumber {
+				firstPN = pn
+			}
+			lastPN = pn
+		}
 		if err != nil {
 			if err != errNothingToPack {
 				return err
 			}
+			s.sentPacketHandler.OnAppLimited()
 			if buf.Len() == 0 {
 				buf.Release()
 				return nil
@@ -1936,6 +2746,7 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 				s.resetPacingDeadline()
 			}
 			if sendMode != ackhandler.SendAny {
+				s.setCongestionLimited(sendMode == ackhandler.SendAck)
 				dontSendMore = true
 			}
 		}
@@ -1953,6 +2764,10 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 		}
 
 		s.sendQueue.Send(buf, uint16(maxSize), ecn)
+		if lastPN > firstPN {
+			s.gsoBlackholeDetector.SentBatch(firstPN, lastPN)
+		}
+		firstPN = protocol.InvalidPacketNumber
 
 		if dontSendMore {
 			return nil
@@ -1989,7 +2804,7 @@ func (s *connection) maybeSendAckOnlyPacket(now time.Time) error {
 		if packet == nil {
 			return nil
 		}
-		return s.sendPackedCoalescedPacket(packet, ecn, time.Now())
+		return s.sendPackedCoalescedPacket(packet, ecn, s.clock.Now())
 	}
 
 	ecn := s.sentPacketHandler.ECNMode(true)
@@ -2038,43 +2853,90 @@ func (s *connection) sendProbePacket(encLevel protocol.EncryptionLevel, now time
 }
 
 // appendOneShortHeaderPacket appends a new packet to the given packetBuffer.
-// If there was nothing to pack, the returned size is 0.
-func (s *connection) appendOneShortHeaderPacket(buf *packetBuffer, maxSize protocol.ByteCount, ecn protocol.ECN, now time.Time) (protocol.ByteCount, error) {
+// It returns the packet number and the size of the packet.
+func (s *connection) appendOneShortHeaderPacket(buf *packetBuffer, maxSize protocol.ByteCount, ecn protocol.ECN, now time.Time) (protocol.PacketNumber, protocol.ByteCount, error) {
 	startLen := buf.Len()
 	p, err := s.packer.AppendPacket(buf, maxSize, s.version)
 	if err != nil {
-		return 0, err
+		return protocol.InvalidPacketNumber, 0, err
 	}
 	size := buf.Len() - startLen
 	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, size, false)
 	s.registerPackedShortHeaderPacket(p, ecn, now)
-	return size, nil
+	return p.PacketNumber, size, nil
 }
 
 func (s *connection) registerPackedShortHeaderPacket(p shortHeaderPacket, ecn protocol.ECN, now time.Time) {
 	if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && (len(p.StreamFrames) > 0 || ackhandler.HasAckElicitingFrames(p.Frames)) {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
+	s.maybeUpdateActivity(p, now)
+	s.maybeReachFirstAppDataSent(p, now)
 
 	largestAcked := protocol.InvalidPacketNumber
 	if p.Ack != nil {
 		largestAcked = p.Ack.LargestAcked()
 	}
 	s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
+	sentPingFrames(p.Frames, now)
 	s.connIDManager.SentPacket()
 }
 
+// maybeUpdateActivity updates the time of the last activity, unless the packet only contains PING frames
+// (or frames that aren't ack-eliciting).
+func (s *connection) maybeUpdateActivity(p shortHeaderPacket, now time.Time) {
+	if len(p.StreamFrames) > 0 {
+		s.lastActivityTime = now
+		return
+	}
+	for _, f := range p.Frames {
+		if isActivityFrame(f.Frame) {
+			s.lastActivityTime = now
+			return
+		}
+	}
+}
+
+// isActivityFrame says if a frame keeps an idle connection from timing out,
+// when the idle timeout was extended using SetIdleTimeout.
+func isActivityFrame(f wire.Frame) bool {
+	if _, ok := f.(*wire.PingFrame); ok {
+		return false
+	}
+	return ackhandler.IsFrameAckEliciting(f)
+}
+
+func (s *connection) maybeReachFirstAppDataSent(p shortHeaderPacket, now time.Time) {
+	if s.handshakeMilestones&(1<<logging.HandshakeMilestoneFirstAppDataSent) != 0 {
+		return
+	}
+	hasAppData := len(p.StreamFrames) > 0
+	for _, f := range p.Frames {
+		if _, ok := f.Frame.(*wire.DatagramFrame); ok {
+			hasAppData = true
+			break
+		}
+	}
+	if hasAppData {
+		s.reachedHandshakeMilestone(logging.HandshakeMilestoneFirstAppDataSent, now)
+	}
+}
+
 func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn protocol.ECN, now time.Time) error {
 	s.logCoalescedPacket(packet, ecn)
 	for _, p := range packet.longHdrPackets {
 		if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
 			s.firstAckElicitingPacketAfterIdleSentTime = now
 		}
+		if p.EncryptionLevel() == protocol.EncryptionInitial {
+			s.reachedHandshakeMilestone(logging.HandshakeMilestoneFirstInitialSent, now)
+		}
 		largestAcked := protocol.InvalidPacketNumber
 		if p.ack != nil {
 			largestAcked = p.ack.LargestAcked()
 		}
 		s.sentPacketHandler.SentPacket(now, p.header.PacketNumber, largestAcked, p.streamFrames, p.frames, p.EncryptionLevel(), ecn, p.length, false)
+		sentPingFrames(p.frames, now)
 		if s.perspective == protocol.PerspectiveClient && p.EncryptionLevel() == protocol.EncryptionHandshake {
 			// On the client side, Initial keys are dropped as soon as the first Handshake packet is sent.
 			// See Section 4.9.1 of RFC 9001.
@@ -2087,11 +2949,14 @@ func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn prot
 		if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
 			s.firstAckElicitingPacketAfterIdleSentTime = now
 		}
+		s.maybeUpdateActivity(*p, now)
+		s.maybeReachFirstAppDataSent(*p, now)
 		largestAcked := protocol.InvalidPacketNumber
 		if p.Ack != nil {
 			largestAcked = p.Ack.LargestAcked()
 		}
 		s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
+		sentPingFrames(p.Frames, now)
 	}
 	s.connIDManager.SentPacket()
 	s.sendQueue.Send(packet.buffer, 0, ecn)
@@ -2138,16 +3003,19 @@ func (s *connection) logLongHeaderPacket(p *longHeaderPacket, ecn protocol.ECN)
 
 	// tracing
 	if s.tracer != nil && s.tracer.SentLongHeaderPacket != nil {
-		frames := make([]logging.Frame, 0, len(p.frames))
-		for _, f := range p.frames {
-			frames = append(frames, logutils.ConvertFrame(f.Frame))
-		}
-		for _, f := range p.streamFrames {
-			frames = append(frames, logutils.ConvertFrame(f.Frame))
-		}
+		var frames []logging.Frame
 		var ack *logging.AckFrame
-		if p.ack != nil {
-			ack = logutils.ConvertAckFrame(p.ack)
+		if !s.tracer.OmitFrames {
+			frames = make([]logging.Frame, 0, len(p.frames))
+			for _, f := range p.frames {
+				frames = append(frames, logutils.ConvertFrame(f.Frame))
+			}
+			for _, f := range p.streamFrames {
+				frames = append(frames, logutils.ConvertFrame(f.Frame))
+			}
+			if p.ack != nil {
+				ack = logutils.ConvertAckFrame(p.ack)
+			}
 		}
 		s.tracer.SentLongHeaderPacket(p.header, p.length, ecn, ack, frames)
 	}
@@ -2184,16 +3052,19 @@ func (s *connection) logShortHeaderPacket(
 
 	// tracing
 	if s.tracer != nil && s.tracer.SentShortHeaderPacket != nil {
-		fs := make([]logging.Frame, 0, len(frames)+len(streamFrames))
-		for _, f := range frames {
-			fs = append(fs, logutils.ConvertFrame(f.Frame))
-		}
-		for _, f := range streamFrames {
-			fs = append(fs, logutils.ConvertFrame(f.Frame))
-		}
+		var fs []logging.Frame
 		var ack *logging.AckFrame
-		if ackFrame != nil {
-			ack = logutils.ConvertAckFrame(ackFrame)
+		if !s.tracer.OmitFrames {
+			fs = make([]logging.Frame, 0, len(frames)+len(streamFrames))
+			for _, f := range frames {
+				fs = append(fs, logutils.ConvertFrame(f.Frame))
+			}
+			for _, f := range streamFrames {
+				fs = append(fs, logutils.ConvertFrame(f.Frame))
+			}
+			if ackFrame != nil {
+				ack = logutils.ConvertAckFrame(ackFrame)
+			}
 		}
 		s.tracer.SentShortHeaderPacket(
 			&logging.ShortHeader{
@@ -2338,6 +3209,12 @@ func (s *connection) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *connection) onStreamDataRetransmission(id protocol.StreamID, offset, length protocol.ByteCount) {
+	if s.tracer != nil && s.tracer.RetransmittedStreamData != nil {
+		s.tracer.RetransmittedStreamData(id, offset, length)
+	}
+}
+
 func (s *connection) onStreamCompleted(id protocol.StreamID) {
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
@@ -2365,6 +3242,108 @@ func (s *connection) ReceiveMessage(ctx context.Context) ([]byte, error) {
 	return s.datagramQueue.Receive(ctx)
 }
 
+func (s *connection) SendExtensionFrame(typ uint64, frame any) error {
+	ef, ok := s.extensionFrames[typ]
+	if !ok {
+		return fmt.Errorf("unknown extension frame type %#x", typ)
+	}
+	var supported bool
+	s.connStateMutex.Lock()
+	for _, t := range s.connState.ExtensionFrames {
+		if t == typ {
+			supported = true
+			break
+		}
+	}
+	s.connStateMutex.Unlock()
+	if !supported {
+		return fmt.Errorf("peer doesn't support extension frame type %#x", typ)
+	}
+	data, err := ef.Encode(nil, frame)
+	if err != nil {
+		return err
+	}
+	f := &wire.ExtensionFrame{
+		FrameType:        typ,
+		Data:             data,
+		AckEliciting:     ef.AckEliciting,
+		RetransmitOnLoss: ef.RetransmitOnLoss,
+	}
+	if f.Length(s.version) > protocol.MaxExtensionFrameSize {
+		return errors.New("extension frame too large")
+	}
+	s.queueControlFrame(f)
+	return nil
+}
+
+func (s *connection) Ping(ctx context.Context) (time.Duration, error) {
+	r := newPingRequest(s.clock, func() time.Duration { return s.ackDelay }, s.queuePing)
+	s.queuePing(r)
+	select {
+	case <-r.done:
+		return r.rtt, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-s.ctx.Done():
+		return 0, context.Cause(s.ctx)
+	}
+}
+
+func (s *connection) queuePing(r *pingRequest) {
+	s.framer.QueueControlFrameWithHandler(&wire.PingFrame{}, r)
+	s.scheduleSending()
+}
+
+func (s *connection) ProbeMTU(ctx context.Context, size int) error {
+	if s.config.DisablePathMTUDiscovery || !s.conn.capabilities().DF {
+		return errors.New("path MTU discovery disabled")
+	}
+	if size < protocol.MinInitialPacketSize {
+		return fmt.Errorf("MTU probe size must be at least %d bytes", protocol.MinInitialPacketSize)
+	}
+	r := s.mtuDiscoverer.RequestProbe(protocol.ByteCount(size))
+	s.scheduleSending()
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.ctx.Done():
+		return context.Cause(s.ctx)
+	}
+}
+
+func (s *connection) ObservedAddress(ctx context.Context) (net.Addr, error) {
+	if !s.config.AddressDiscovery.Receives() {
+		return nil, errors.New("address discovery disabled")
+	}
+	select {
+	case <-s.observedAddrChan:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.ctx.Done():
+		return nil, context.Cause(s.ctx)
+	}
+	s.connStateMutex.Lock()
+	defer s.connStateMutex.Unlock()
+	return s.observedAddr, s.observedAddrErr
+}
+
+func (s *connection) SetSendRateLimit(bytesPerSecond, burst uint64) {
+	s.sendRateLimiter.SetLimit(bytesPerSecond, burst)
+	s.scheduleSending()
+}
+
+func (s *connection) SetIdleTimeout(timeout time.Duration) {
+	s.runInLoop(func() {
+		s.requestedIdleTimeout = timeout
+		// If the handshake hasn't completed yet, the idle timeout is applied when processing the transport parameters.
+		if s.peerParams != nil {
+			s.applyIdleTimeout()
+		}
+	})
+}
+
 func (s *connection) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
@@ -0,0 +1,54 @@
+package quic
+
+// ConnectionLimitBehavior determines how a connection attempt that exceeds
+// Transport.MaxConnections or Transport.MaxConcurrentHandshakes is handled.
+type ConnectionLimitBehavior uint8
+
+const (
+	// RefuseConnections refuses connection attempts exceeding the limits,
+	// by sending a CONNECTION_CLOSE frame with the CONNECTION_REFUSED error code.
+	RefuseConnections ConnectionLimitBehavior = iota
+	// QueueConnections queues connection attempts exceeding the limits, and handles them once
+	// connections are closed or handshakes complete.
+	// Connection attempts that can't be handled within the HandshakeIdleTimeout are dropped,
+	// and attempts that don't fit into the queue are refused.
+	QueueConnections
+)
+
+// ConnectionStats are statistics about the incoming connections of a Transport.
+type ConnectionStats struct {
+	// Connections is the number of incoming connections that haven't been closed yet,
+	// including connections that are still handshaking.
+	Connections int
+	// Handshakes is the number of incoming connections that are not yet ready to be accepted.
+	Handshakes int
+	// QueuedConnectionAttempts is the number of connection attempts that are currently queued,
+	// see QueueConnections.
+	QueuedConnectionAttempts int
+
+	// TotalConnections is the total number of incoming connections.
+	TotalConnections uint64
+	// RefusedConnectionAttempts is the total number of connection attempts
+	// that were refused because of the connection limits.
+	RefusedConnectionAttempts uint64
+	// DroppedConnectionAttempts is the total number of queued connection attempts
+	// that were dropped because they couldn't be handled in time.
+	DroppedConnectionAttempts uint64
+}
+
+type connectionLimits struct {
+	maxConns      int
+	maxHandshakes int
+	behavior      ConnectionLimitBehavior
+}
+
+// The state of a connection handled by the server.
+type serverConnState uint8
+
+const (
+	serverConnHandshaking serverConnState = iota
+	// the connection is ready, and queued for Accept
+	serverConnReady
+	// the connection was returned from Accept (or claimed by DialPeer)
+	serverConnAccepted
+)
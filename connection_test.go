@@ -9,8 +9,10 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/netip"
 	"runtime/pprof"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
@@ -106,6 +108,7 @@ var _ = Describe("Connection", func() {
 		tracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
+		tracer.EXPECT().ReachedHandshakeMilestone(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
 		conn = newConnection(
 			mconn,
@@ -151,7 +154,22 @@ var _ = Describe("Connection", func() {
 				str := NewMockReceiveStreamI(mockCtrl)
 				str.EXPECT().handleStreamFrame(f)
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
-				Expect(conn.handleStreamFrame(f)).To(Succeed())
+				Expect(conn.handleStreamFrame(f, protocol.Encryption1RTT)).To(Succeed())
+			})
+
+			It("marks data received in 0-RTT", func() {
+				f := &wire.StreamFrame{
+					StreamID: 5,
+					Offset:   10,
+					Data:     []byte{0xde, 0xca, 0xfb, 0xad},
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				gomock.InOrder(
+					str.EXPECT().receivedEarlyData(protocol.ByteCount(10), protocol.ByteCount(14)),
+					str.EXPECT().handleStreamFrame(f),
+				)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				Expect(conn.handleStreamFrame(f, protocol.Encryption0RTT)).To(Succeed())
 			})
 
 			It("returns errors", func() {
@@ -163,7 +181,7 @@ var _ = Describe("Connection", func() {
 				str := NewMockReceiveStreamI(mockCtrl)
 				str.EXPECT().handleStreamFrame(f).Return(testErr)
 				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
-				Expect(conn.handleStreamFrame(f)).To(MatchError(testErr))
+				Expect(conn.handleStreamFrame(f, protocol.Encryption1RTT)).To(MatchError(testErr))
 			})
 
 			It("ignores STREAM frames for closed streams", func() {
@@ -171,7 +189,7 @@ var _ = Describe("Connection", func() {
 				Expect(conn.handleStreamFrame(&wire.StreamFrame{
 					StreamID: 5,
 					Data:     []byte("foobar"),
-				})).To(Succeed())
+				}, protocol.Encryption1RTT)).To(Succeed())
 			})
 		})
 
@@ -184,6 +202,38 @@ var _ = Describe("Connection", func() {
 				err := conn.handleAckFrame(f, protocol.EncryptionHandshake)
 				Expect(err).ToNot(HaveOccurred())
 			})
+
+			It("records the ACK delay, capped by max_ack_delay, for PING requests", func() {
+				conn.rttStats.SetMaxAckDelay(25 * time.Millisecond)
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				conn.sentPacketHandler = sph
+				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}, DelayTime: 10 * time.Millisecond}
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any()).Do(func(*wire.AckFrame, protocol.EncryptionLevel, time.Time) {
+					Expect(conn.ackDelay).To(Equal(10 * time.Millisecond))
+				})
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				f = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 5}}, DelayTime: time.Second}
+				sph.EXPECT().ReceivedAck(f, protocol.Encryption1RTT, gomock.Any()).Do(func(*wire.AckFrame, protocol.EncryptionLevel, time.Time) {
+					Expect(conn.ackDelay).To(Equal(25 * time.Millisecond))
+				})
+				Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+			})
+
+			It("disables GSO when GSO batches are blackholed", func() {
+				sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().ReceivedAck(gomock.Any(), protocol.Encryption1RTT, gomock.Any()).Times(gsoBlackholeThreshold)
+				conn.sentPacketHandler = sph
+				for i := protocol.PacketNumber(0); i < gsoBlackholeThreshold; i++ {
+					conn.gsoBlackholeDetector.SentBatch(10*i, 10*i+5)
+				}
+				for i := protocol.PacketNumber(0); i < gsoBlackholeThreshold; i++ {
+					if i == gsoBlackholeThreshold-1 {
+						mconn.EXPECT().disableGSO(logging.GSODisabledBlackholed)
+					}
+					f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10*i + 6, Largest: 10*i + 6}}}
+					Expect(conn.handleAckFrame(f, protocol.Encryption1RTT)).To(Succeed())
+				}
+			})
 		})
 
 		Context("handling RESET_STREAM frames", func() {
@@ -303,6 +353,13 @@ var _ = Describe("Connection", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("handles IMMEDIATE_ACK frames", func() {
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			conn.receivedPacketHandler = rph
+			rph.EXPECT().ImmediateAckRequested(protocol.Encryption1RTT)
+			Expect(conn.handleFrame(&wire.ImmediateAckFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+		})
+
 		It("rejects PATH_RESPONSE frames", func() {
 			err := conn.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
@@ -316,6 +373,172 @@ var _ = Describe("Connection", func() {
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PathResponseFrame{Data: data}}}))
 		})
 
+		Context("address discovery", func() {
+			It("handles OBSERVED_ADDRESS frames", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryReceive
+				conn.peerParams = &wire.TransportParameters{AddressDiscoveryMode: protocol.AddressDiscoveryProvide}
+				Expect(conn.handleFrame(&wire.ObservedAddressFrame{
+					SequenceNumber: 1,
+					Address:        netip.MustParseAddrPort("1.2.3.4:1234"),
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				addr, err := conn.ObservedAddress(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr).To(Equal(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To4(), Port: 1234}))
+				// reordered frames are ignored
+				Expect(conn.handleFrame(&wire.ObservedAddressFrame{
+					SequenceNumber: 0,
+					Address:        netip.MustParseAddrPort("5.6.7.8:5678"),
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				addr, err = conn.ObservedAddress(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr).To(Equal(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To4(), Port: 1234}))
+				Expect(conn.handleFrame(&wire.ObservedAddressFrame{
+					SequenceNumber: 2,
+					Address:        netip.MustParseAddrPort("[2001:db8::1]:4321"),
+				}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				addr, err = conn.ObservedAddress(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(addr).To(Equal(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4321}))
+			})
+
+			It("rejects OBSERVED_ADDRESS frames if the peer doesn't provide observed addresses", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryReceive
+				conn.peerParams = &wire.TransportParameters{AddressDiscoveryMode: protocol.AddressDiscoveryReceive}
+				err := conn.handleFrame(&wire.ObservedAddressFrame{Address: netip.MustParseAddrPort("1.2.3.4:1234")}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
+				Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			})
+
+			It("blocks until the observed address is received", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryReceive
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				_, err := conn.ObservedAddress(ctx)
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+			})
+
+			It("errors when address discovery is disabled", func() {
+				_, err := conn.ObservedAddress(context.Background())
+				Expect(err).To(MatchError("address discovery disabled"))
+			})
+
+			It("reports the observed address when the handshake completes", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryProvide
+				conn.peerParams = &wire.TransportParameters{AddressDiscoveryMode: protocol.AddressDiscoveryReceive}
+				conn.startAddressDiscovery()
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000, protocol.Version1)
+				Expect(frames).To(HaveLen(1))
+				Expect(frames[0].Frame).To(BeAssignableToTypeOf(&wire.ObservedAddressFrame{}))
+				Expect(frames[0].Frame.(*wire.ObservedAddressFrame).Address).To(Equal(remoteAddr.AddrPort()))
+			})
+
+			It("doesn't report the observed address if the peer didn't ask for it", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryProvide
+				conn.peerParams = &wire.TransportParameters{AddressDiscoveryMode: protocol.AddressDiscoveryProvide}
+				conn.startAddressDiscovery()
+				Expect(conn.framer.HasData()).To(BeFalse())
+			})
+
+			It("errors if the peer doesn't provide observed addresses", func() {
+				conn.config.AddressDiscovery = AddressDiscoveryReceive
+				conn.peerParams = &wire.TransportParameters{}
+				conn.startAddressDiscovery()
+				_, err := conn.ObservedAddress(context.Background())
+				Expect(err).To(MatchError("peer doesn't provide observed addresses"))
+			})
+		})
+
+		Context("extension frames", func() {
+			var received []any
+
+			BeforeEach(func() {
+				received = nil
+				conn.extensionFrames = map[uint64]*ExtensionFrame{
+					0x42: {
+						Type:               0x42,
+						TransportParameter: 0x1337,
+						AckEliciting:       true,
+						RetransmitOnLoss:   true,
+						Encode: func(b []byte, f any) ([]byte, error) {
+							s, ok := f.(string)
+							if !ok {
+								return nil, errors.New("not a string")
+							}
+							return append(b, s...), nil
+						},
+						Handle: func(c Connection, f any) error {
+							Expect(c).To(Equal(conn))
+							if f == "error" {
+								return errors.New("invalid frame")
+							}
+							received = append(received, f)
+							return nil
+						},
+					},
+				}
+			})
+
+			It("handles extension frames", func() {
+				conn.peerParams = &wire.TransportParameters{CustomParameters: map[uint64][]byte{0x1337: {}}}
+				Expect(conn.handleFrame(&wire.ExtensionFrame{FrameType: 0x42, Value: "foo"}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(conn.handleFrame(&wire.ExtensionFrame{FrameType: 0x42, Value: "bar"}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(received).To(Equal([]any{"foo", "bar"}))
+			})
+
+			It("rejects extension frames if the peer doesn't support the frame type", func() {
+				conn.peerParams = &wire.TransportParameters{}
+				err := conn.handleFrame(&wire.ExtensionFrame{FrameType: 0x42, Value: "foo"}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "received extension frame of type 0x42, although the peer doesn't support it",
+				}))
+				Expect(received).To(BeEmpty())
+			})
+
+			It("closes the connection if handling an extension frame fails", func() {
+				conn.peerParams = &wire.TransportParameters{CustomParameters: map[uint64][]byte{0x1337: {}}}
+				err := conn.handleFrame(&wire.ExtensionFrame{FrameType: 0x42, Value: "error"}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError(&qerr.TransportError{
+					FrameType:    0x42,
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "invalid frame",
+				}))
+			})
+
+			It("sends extension frames", func() {
+				conn.connState.ExtensionFrames = []uint64{0x42}
+				Expect(conn.SendExtensionFrame(0x42, "foobar")).To(Succeed())
+				frames, _ := conn.framer.AppendControlFrames(nil, 1000, protocol.Version1)
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.ExtensionFrame{
+					FrameType:        0x42,
+					Data:             []byte("foobar"),
+					AckEliciting:     true,
+					RetransmitOnLoss: true,
+				}}}))
+			})
+
+			It("refuses to send extension frames of unknown types", func() {
+				Expect(conn.SendExtensionFrame(0x43, "foobar")).To(MatchError("unknown extension frame type 0x43"))
+			})
+
+			It("refuses to send extension frames if the peer doesn't support the frame type", func() {
+				Expect(conn.SendExtensionFrame(0x42, "foobar")).To(MatchError("peer doesn't support extension frame type 0x42"))
+				Expect(conn.framer.HasData()).To(BeFalse())
+			})
+
+			It("returns errors when encoding fails", func() {
+				conn.connState.ExtensionFrames = []uint64{0x42}
+				Expect(conn.SendExtensionFrame(0x42, 1337)).To(MatchError("not a string"))
+				Expect(conn.framer.HasData()).To(BeFalse())
+			})
+
+			It("refuses to send extension frames that are too large", func() {
+				conn.connState.ExtensionFrames = []uint64{0x42}
+				Expect(conn.SendExtensionFrame(0x42, strings.Repeat("a", int(protocol.MaxExtensionFrameSize)))).To(MatchError("extension frame too large"))
+				Expect(conn.framer.HasData()).To(BeFalse())
+			})
+		})
+
 		It("rejects NEW_TOKEN frames", func() {
 			err := conn.handleNewTokenFrame(&wire.NewTokenFrame{})
 			Expect(err).To(HaveOccurred())
@@ -412,6 +635,11 @@ var _ = Describe("Connection", func() {
 		Expect(conn.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("traces retransmitted stream data", func() {
+		tracer.EXPECT().RetransmittedStreamData(protocol.StreamID(4), protocol.ByteCount(100), protocol.ByteCount(42))
+		conn.onStreamDataRetransmission(4, 100, 42)
+	})
+
 	Context("closing", func() {
 		var (
 			runErr         chan error
@@ -505,6 +733,68 @@ var _ = Describe("Connection", func() {
 			Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
 		})
 
+		Context("closing with a timeout", func() {
+			var (
+				sph           *mockackhandler.MockSentPacketHandler
+				bytesInFlight atomic.Int64
+				expectedErr   = &qerr.ApplicationError{ErrorCode: 0x1337, ErrorMessage: "test error"}
+			)
+
+			BeforeEach(func() {
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
+				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone).AnyTimes()
+				sph.EXPECT().TimeUntilSend().AnyTimes()
+				sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+				bytesInFlight.Store(1000)
+				sph.EXPECT().GetBytesInFlight().DoAndReturn(func() protocol.ByteCount {
+					return protocol.ByteCount(bytesInFlight.Load())
+				}).AnyTimes()
+				conn.sentPacketHandler = sph
+				streamManager.EXPECT().CloseWithError(expectedErr)
+				expectReplaceWithClosed()
+				cryptoSetup.EXPECT().Close()
+				packer.EXPECT().PackApplicationClose(expectedErr, gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+				mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+				tracer.EXPECT().ClosedConnection(expectedErr)
+				tracer.EXPECT().Close()
+			})
+
+			It("waits until all data has been acknowledged", func() {
+				runConn()
+				errChan := make(chan error, 1)
+				go func() { errChan <- conn.CloseWithTimeout(0x1337, "test error", time.Hour) }()
+				Consistently(errChan).ShouldNot(Receive())
+				Expect(conn.Context().Done()).ToNot(BeClosed())
+				bytesInFlight.Store(0)
+				conn.scheduleSending()
+				Eventually(errChan).Should(Receive(BeNil()))
+				Expect(conn.Context().Done()).To(BeClosed())
+				Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
+			})
+
+			It("closes the connection when the timeout expires", func() {
+				runConn()
+				Expect(conn.CloseWithTimeout(0x1337, "test error", scaleDuration(50*time.Millisecond))).To(MatchError(ErrCloseTimeout))
+				Expect(conn.Context().Done()).To(BeClosed())
+				Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
+			})
+
+			It("uses the configured clock for the timeout", func() {
+				clock := &manualClock{now: time.Now()}
+				conn.clock = clock
+				runConn()
+				errChan := make(chan error, 1)
+				go func() { errChan <- conn.CloseWithTimeout(0x1337, "test error", time.Nanosecond) }()
+				Eventually(clock.Timer).ShouldNot(BeNil())
+				Consistently(errChan).ShouldNot(Receive())
+				Expect(clock.Timer().duration).To(Equal(time.Nanosecond))
+				clock.Timer().fire()
+				Eventually(errChan).Should(Receive(MatchError(ErrCloseTimeout)))
+				Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
+			})
+		})
+
 		It("includes the frame type in transport-level close frames", func() {
 			runConn()
 			expectedErr := &qerr.TransportError{
@@ -612,6 +902,7 @@ var _ = Describe("Connection", func() {
 			sconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
 			conn.sendQueue = newSendQueue(sconn)
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
 			sph.EXPECT().ECNMode(true).Return(protocol.ECT1).AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
@@ -805,6 +1096,43 @@ var _ = Describe("Connection", func() {
 			Expect(conn.handlePacketImpl(packet)).To(BeTrue())
 		})
 
+		It("drops 0-RTT packets that exceed the 0-RTT limits", func() {
+			conn.zeroRTTLimiter = newZeroRTTLimiter(0, 1)
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					Type:             protocol.PacketType0RTT,
+					DestConnectionID: srcConnID,
+					Version:          protocol.Version1,
+					Length:           1,
+				},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			packet := getLongHeaderPacket(hdr, nil)
+			b, err := (&wire.StreamFrame{StreamID: 0, Data: []byte("foo"), DataLenPresent: true}).Append(nil, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			b, err = (&wire.StreamFrame{StreamID: 4, Data: []byte("bar")}).Append(b, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any(), conn.version).Return(&unpackedPacket{
+				encryptionLevel: protocol.Encryption0RTT,
+				hdr:             hdr,
+				data:            b,
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x37), protocol.Encryption0RTT)
+			// the packet is not acknowledged
+			rph.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			conn.receivedPacketHandler = rph
+			str := NewMockReceiveStreamI(mockCtrl)
+			streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(0)).Return(str, nil)
+			str.EXPECT().receivedEarlyData(protocol.ByteCount(0), protocol.ByteCount(3))
+			str.EXPECT().handleStreamFrame(gomock.Any())
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedLongHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().DroppedPacket(logging.PacketType0RTT, packet.Size(), logging.PacketDropDOSPrevention)
+			Expect(conn.handlePacketImpl(packet)).To(BeFalse())
+		})
+
 		It("drops duplicate packets", func() {
 			packet := getShortHeaderPacket(srcConnID, 0x37, nil)
 			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2, protocol.KeyPhaseOne, []byte("foobar"), nil)
@@ -882,6 +1210,87 @@ var _ = Describe("Connection", func() {
 			Eventually(conn.Context().Done()).Should(BeClosed())
 		})
 
+		It("limits the number of packets processed per loop iteration", func() {
+			conn.config.MaxPacketsPerLoopIteration = 2
+			conn.creationTime = time.Now()
+			var pn protocol.PacketNumber
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(func(rcvTime time.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				pn++
+				return pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{0} /* PADDING frame */, nil
+			}).Times(4)
+			tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).Times(2)
+
+			for i := 0; i < 4; i++ {
+				conn.handlePacket(getShortHeaderPacket(srcConnID, 0x1337+protocol.PacketNumber(i), []byte("foobar")))
+			}
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				conn.run()
+			}()
+			Consistently(conn.Context().Done()).ShouldNot(BeClosed())
+
+			// make the go routine return
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			conn.closeLocal(errors.New("close"))
+			Eventually(conn.Context().Done()).Should(BeClosed())
+		})
+
+		It("processes the ACKs of multiple received packets in a batch", func() {
+			conn.creationTime = time.Now()
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			sph.EXPECT().ReceivedBytes(gomock.Any()).AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone).AnyTimes()
+			var pn protocol.PacketNumber
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(func(rcvTime time.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				pn++
+				b, err := (&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: pn, Largest: pn}}}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				return pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil
+			}).Times(3)
+			tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			gomock.InOrder(
+				sph.EXPECT().StartAckBatch(),
+				sph.EXPECT().ReceivedAck(gomock.Any(), protocol.Encryption1RTT, gomock.Any()).Times(3),
+				sph.EXPECT().FinishAckBatch(),
+			)
+
+			for i := 0; i < 3; i++ {
+				conn.handlePacket(getShortHeaderPacket(srcConnID, 0x1337+protocol.PacketNumber(i), []byte("foobar")))
+			}
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				conn.run()
+			}()
+			Consistently(conn.Context().Done()).ShouldNot(BeClosed())
+
+			// make the go routine return
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			conn.closeLocal(errors.New("close"))
+			Eventually(conn.Context().Done()).Should(BeClosed())
+		})
+
 		It("doesn't processes multiple received packets before sending one before handshake completion", func() {
 			conn.handshakeComplete = false
 			conn.creationTime = time.Now()
@@ -910,6 +1319,7 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().Close()
 			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 			expectReplaceWithClosed()
+			tracer.EXPECT().FailedHandshake(logging.HandshakeFailureOther, protocol.EncryptionInitial, gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
 			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
@@ -1240,6 +1650,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(true).Return(protocol.ECNNon).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			runConn()
 			p := shortHeaderPacket{
 				DestConnID:      protocol.ParseConnectionID([]byte{1, 2, 3}),
@@ -1267,6 +1678,8 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(true).AnyTimes()
+			// the sent packet handler is notified that the connection is application-limited
+			sph.EXPECT().OnAppLimited().MinTimes(1)
 			runConn()
 			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack).AnyTimes()
 			conn.receivedPacketHandler.ReceivedPacket(0x035e, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)
@@ -1293,6 +1706,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
 			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			fc := mocks.NewMockConnectionFlowController(mockCtrl)
 			fc.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(1337))
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 13}, []byte("foobar"))
@@ -1399,6 +1813,7 @@ var _ = Describe("Connection", func() {
 		BeforeEach(func() {
 			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			conn.handshakeConfirmed = true
 			conn.handshakeComplete = true
@@ -1814,6 +2229,7 @@ var _ = Describe("Connection", func() {
 
 		It("sends when scheduleSending is called", func() {
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().TimeUntilSend().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
@@ -1844,6 +2260,7 @@ var _ = Describe("Connection", func() {
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 1234}, []byte("packet1234"))
 			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack)
 			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().OnAppLimited().AnyTimes()
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
@@ -1932,6 +2349,7 @@ var _ = Describe("Connection", func() {
 		packer.EXPECT().PackApplicationClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
 		cryptoSetup.EXPECT().Close()
 		mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+		tracer.EXPECT().FailedHandshake(logging.HandshakeFailureOther, protocol.EncryptionInitial, gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		conn.shutdown()
@@ -2010,6 +2428,7 @@ var _ = Describe("Connection", func() {
 
 	It("sends a HANDSHAKE_DONE frame when the handshake completes", func() {
 		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().OnAppLimited().AnyTimes()
 		sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 		sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
@@ -2039,7 +2458,10 @@ var _ = Describe("Connection", func() {
 			cryptoSetup.EXPECT().SetHandshakeConfirmed()
 			cryptoSetup.EXPECT().GetSessionTicket()
 			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			Expect(conn.HandshakeConfirmed()).ToNot(BeClosed())
 			Expect(conn.handleHandshakeComplete()).To(Succeed())
+			Expect(conn.HandshakeComplete()).To(BeClosed())
+			Expect(conn.HandshakeConfirmed()).To(BeClosed())
 			conn.run()
 		}()
 		Eventually(done).Should(BeClosed())
@@ -2116,9 +2538,15 @@ var _ = Describe("Connection", func() {
 			streamManager.EXPECT().UpdateLimits(params)
 			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).MaxTimes(3)
 			Expect(conn.earlyConnReady()).ToNot(BeClosed())
+			Expect(conn.connState.PeerTransportParameters).To(BeNil())
 			tracer.EXPECT().ReceivedTransportParameters(params)
 			conn.handleTransportParameters(params)
 			Expect(conn.earlyConnReady()).To(BeClosed())
+			peerParams := conn.connState.PeerTransportParameters
+			Expect(peerParams).ToNot(BeNil())
+			Expect(peerParams.MaxIdleTimeout).To(Equal(90 * time.Second))
+			Expect(peerParams.InitialMaxData).To(BeEquivalentTo(0x5000))
+			Expect(peerParams.ActiveConnectionIDLimit).To(BeEquivalentTo(3))
 		})
 	})
 
@@ -2172,6 +2600,20 @@ var _ = Describe("Connection", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("sends a PING as a keep-alive after 3/4 of the idle timeout in low-power mode", func() {
+			conn.config.LowPowerMode = true
+			setRemoteIdleTimeout(4 * time.Second)
+			Expect(conn.keepAliveInterval).To(Equal(3 * time.Second))
+			conn.lastPacketReceivedTime = time.Now().Add(-3 * time.Second)
+			sent := make(chan struct{})
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).Do(func(bool, protocol.ByteCount, protocol.VersionNumber) (*coalescedPacket, error) {
+				close(sent)
+				return nil, nil
+			})
+			runConn()
+			Eventually(sent).Should(BeClosed())
+		})
+
 		It("sends a PING after a maximum of protocol.MaxKeepAliveInterval", func() {
 			conn.config.MaxIdleTimeout = time.Hour
 			setRemoteIdleTimeout(time.Hour)
@@ -2200,6 +2642,7 @@ var _ = Describe("Connection", func() {
 			// Needs to be shorter than our idle timeout.
 			// Otherwise we'll try to send a CONNECTION_CLOSE.
 			conn.lastPacketReceivedTime = time.Now().Add(-20 * time.Second)
+			tracer.EXPECT().FailedHandshake(gomock.Any(), gomock.Any(), gomock.Any())
 			runConn()
 			// don't EXPECT() any calls to mconn.Write()
 			time.Sleep(50 * time.Millisecond)
@@ -2220,6 +2663,231 @@ var _ = Describe("Connection", func() {
 		})
 	})
 
+	Context("adjusting the idle timeout", func() {
+		BeforeEach(func() {
+			conn.config.KeepAlivePeriod = 0
+			conn.negotiatedIdleTimeout = 10 * time.Second
+			conn.lastPacketReceivedTime = time.Now()
+			conn.lastActivityTime = conn.lastPacketReceivedTime
+		})
+
+		It("uses the negotiated idle timeout by default", func() {
+			conn.applyIdleTimeout()
+			Expect(conn.idleTimeout).To(Equal(10 * time.Second))
+			Expect(conn.nextIdleTimeoutTime()).To(Equal(conn.lastPacketReceivedTime.Add(10 * time.Second)))
+			Expect(conn.nextKeepAliveTime()).To(BeZero())
+		})
+
+		It("shortens the idle timeout", func() {
+			conn.requestedIdleTimeout = 5 * time.Second
+			conn.applyIdleTimeout()
+			Expect(conn.idleTimeout).To(Equal(5 * time.Second))
+			Expect(conn.nextIdleTimeoutTime()).To(Equal(conn.lastPacketReceivedTime.Add(5 * time.Second)))
+			Expect(conn.nextKeepAliveTime()).To(BeZero())
+		})
+
+		It("extends the idle timeout using keep-alives", func() {
+			conn.requestedIdleTimeout = time.Minute
+			conn.applyIdleTimeout()
+			Expect(conn.idleTimeout).To(Equal(10 * time.Second))
+			Expect(conn.nextKeepAliveTime()).To(Equal(conn.lastPacketReceivedTime.Add(5 * time.Second)))
+			// packets containing only ACK and PING frames don't count as activity
+			now := conn.lastActivityTime.Add(58 * time.Second)
+			conn.lastPacketReceivedTime = now
+			Expect(conn.nextIdleTimeoutTime()).To(Equal(conn.lastActivityTime.Add(time.Minute)))
+			// no keep-alive is sent after the extended idle timeout
+			Expect(conn.nextKeepAliveTime()).To(BeZero())
+			// restore the negotiated idle timeout
+			conn.requestedIdleTimeout = 0
+			conn.applyIdleTimeout()
+			Expect(conn.nextIdleTimeoutTime()).To(Equal(now.Add(10 * time.Second)))
+		})
+
+		It("doesn't extend the idle timeout if keep-alives are enabled", func() {
+			conn.config.KeepAlivePeriod = 3 * time.Second
+			conn.requestedIdleTimeout = time.Minute
+			conn.applyIdleTimeout()
+			Expect(conn.idleTimeout).To(Equal(10 * time.Second))
+			Expect(conn.extendedIdleTimeout).To(BeZero())
+			Expect(conn.nextKeepAliveTime()).To(Equal(conn.lastPacketReceivedTime.Add(3 * time.Second)))
+		})
+
+		It("tracks activity", func() {
+			start := conn.lastActivityTime
+			conn.maybeUpdateActivity(shortHeaderPacket{Frames: []ackhandler.Frame{{Frame: &wire.PingFrame{}}}}, start.Add(time.Second))
+			Expect(conn.lastActivityTime).To(Equal(start))
+			conn.maybeUpdateActivity(shortHeaderPacket{Frames: []ackhandler.Frame{{Frame: &wire.MaxDataFrame{}}}}, start.Add(2*time.Second))
+			Expect(conn.lastActivityTime).To(Equal(start.Add(2 * time.Second)))
+			conn.maybeUpdateActivity(shortHeaderPacket{StreamFrames: []ackhandler.StreamFrame{{Frame: &wire.StreamFrame{}}}}, start.Add(3*time.Second))
+			Expect(conn.lastActivityTime).To(Equal(start.Add(3 * time.Second)))
+		})
+	})
+
+	Context("rebinding", func() {
+		It("doesn't rebind server connections", func() {
+			c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer c.Close()
+			Expect(conn.Rebind(c)).To(MatchError("quic: connection can't be rebound"))
+		})
+
+		It("doesn't rebind before the handshake is confirmed", func() {
+			Expect(conn.rebind(nil)).To(MatchError("quic: can't rebind before the handshake is confirmed"))
+		})
+
+		It("doesn't rebind if the peer disabled active migration", func() {
+			conn.handshakeConfirmed = true
+			conn.peerParams = &wire.TransportParameters{DisableActiveMigration: true}
+			Expect(conn.rebind(nil)).To(MatchError("quic: peer disabled active migration"))
+		})
+
+		It("doesn't rebind while a path validation is in progress", func() {
+			conn.handshakeConfirmed = true
+			conn.peerParams = &wire.TransportParameters{}
+			conn.pathValidation = &pathValidation{}
+			Expect(conn.rebind(nil)).To(MatchError("quic: path validation in progress"))
+		})
+
+		It("doesn't rebind without an unused connection ID", func() {
+			conn.handshakeConfirmed = true
+			conn.peerParams = &wire.TransportParameters{}
+			Expect(conn.rebind(nil)).To(MatchError("quic: no unused connection ID available"))
+		})
+
+		Context("switching paths", func() {
+			var (
+				sph     *mockackhandler.MockSentPacketHandler
+				oldConn *MockRawConn
+			)
+			connID1 := protocol.ParseConnectionID([]byte{1, 1, 1, 1})
+			connID2 := protocol.ParseConnectionID([]byte{2, 2, 2, 2})
+
+			BeforeEach(func() {
+				sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				conn.sentPacketHandler = sph
+				conn.handshakeConfirmed = true
+				conn.peerParams = &wire.TransportParameters{}
+				oldConn = NewMockRawConn(mockCtrl)
+				oldConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+				conn.migratingConn = (&MultihomedGroup{}).newSendConn(newSendConn(oldConn, remoteAddr, packetInfo{}, utils.DefaultLogger))
+				conn.conn = conn.migratingConn
+				connRunner.EXPECT().AddResetToken(gomock.Any(), gomock.Any()).AnyTimes()
+				connRunner.EXPECT().RemoveResetToken(gomock.Any()).AnyTimes()
+				Expect(conn.connIDManager.Add(&wire.NewConnectionIDFrame{SequenceNumber: 1, ConnectionID: connID1})).To(Succeed())
+			})
+
+			newRawConn := func(addr net.Addr) *MockRawConn {
+				c := NewMockRawConn(mockCtrl)
+				c.EXPECT().LocalAddr().Return(addr).AnyTimes()
+				return c
+			}
+
+			It("keeps the congestion state if only the local port changed", func() {
+				sph.EXPECT().MigratedPath(protocol.ByteCount(0), false)
+				Expect(conn.rebind(newRawConn(&net.UDPAddr{IP: localAddr.IP, Port: 1234}))).To(Succeed())
+				Expect(conn.LocalAddr()).To(Equal(&net.UDPAddr{IP: localAddr.IP, Port: 1234}))
+				Expect(conn.connIDManager.Get()).To(Equal(connID1))
+			})
+
+			It("resets the congestion state if the local IP changed", func() {
+				sph.EXPECT().MigratedPath(protocol.ByteCount(0), true)
+				Expect(conn.rebind(newRawConn(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: localAddr.Port}))).To(Succeed())
+				Expect(conn.connIDManager.Get()).To(Equal(connID1))
+			})
+
+			It("switches to a new connection ID when falling back to the old path", func() {
+				sph.EXPECT().MigratedPath(gomock.Any(), gomock.Any())
+				c := newRawConn(&net.UDPAddr{IP: localAddr.IP, Port: 1234})
+				Expect(conn.rebind(c)).To(Succeed())
+				Expect(conn.connIDManager.Add(&wire.NewConnectionIDFrame{SequenceNumber: 2, ConnectionID: connID2})).To(Succeed())
+				sph.EXPECT().ValidatedPath()
+				c.EXPECT().Close()
+				conn.abandonPathValidation()
+				Expect(conn.LocalAddr()).To(Equal(localAddr))
+				Expect(conn.connIDManager.Get()).To(Equal(connID2))
+			})
+		})
+	})
+
+	Context("migration", func() {
+		var (
+			sph     *mockackhandler.MockSentPacketHandler
+			rcvConn *MockRawConn
+		)
+
+		BeforeEach(func() {
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			conn.handshakeConfirmed = true
+			conn.peerParams = &wire.TransportParameters{}
+			rcvConn = NewMockRawConn(mockCtrl)
+			rcvConn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			conn.migratingConn = (&MultihomedGroup{}).newSendConn(newSendConn(rcvConn, remoteAddr, packetInfo{}, utils.DefaultLogger))
+			conn.conn = conn.migratingConn
+		})
+
+		getControlFrames := func() []wire.Frame {
+			frames, _ := conn.framer.AppendControlFrames(nil, 1000, protocol.Version1)
+			fs := make([]wire.Frame, 0, len(frames))
+			for _, f := range frames {
+				fs = append(fs, f.Frame)
+			}
+			return fs
+		}
+
+		It("keeps the congestion state if only the port changed", func() {
+			newAddr := &net.UDPAddr{IP: remoteAddr.IP, Port: 4242}
+			sph.EXPECT().MigratedPath(protocol.ByteCount(6), false)
+			conn.maybeMigrate(receivedPacket{rcvConn: rcvConn, remoteAddr: newAddr, data: []byte("foobar")}, 10, true)
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+			Expect(conn.pathValidation).ToNot(BeNil())
+			Expect(getControlFrames()).To(Equal([]wire.Frame{&wire.PathChallengeFrame{Data: conn.pathValidation.challenge}}))
+		})
+
+		It("resets the congestion state and reports the new address if the IP changed", func() {
+			conn.config.AddressDiscovery = AddressDiscoveryProvide
+			conn.peerParams = &wire.TransportParameters{AddressDiscoveryMode: protocol.AddressDiscoveryReceive}
+			conn.startAddressDiscovery()
+			Expect(getControlFrames()).To(Equal([]wire.Frame{&wire.ObservedAddressFrame{Address: remoteAddr.AddrPort()}}))
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: remoteAddr.Port}
+			sph.EXPECT().MigratedPath(protocol.ByteCount(6), true)
+			conn.maybeMigrate(receivedPacket{rcvConn: rcvConn, remoteAddr: newAddr, data: []byte("foobar")}, 10, true)
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+			Expect(getControlFrames()).To(ConsistOf(
+				&wire.PathChallengeFrame{Data: conn.pathValidation.challenge},
+				&wire.ObservedAddressFrame{SequenceNumber: 1, Address: newAddr.AddrPort()},
+			))
+		})
+
+		It("doesn't migrate for probing packets", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: remoteAddr.Port}
+			conn.maybeMigrate(receivedPacket{rcvConn: rcvConn, remoteAddr: newAddr, data: []byte("foobar")}, 10, false)
+			Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
+			Expect(conn.pathValidation).To(BeNil())
+		})
+
+		It("lifts the anti-amplification limit when the new path is validated", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: remoteAddr.Port}
+			sph.EXPECT().MigratedPath(protocol.ByteCount(6), true)
+			conn.maybeMigrate(receivedPacket{rcvConn: rcvConn, remoteAddr: newAddr, data: []byte("foobar")}, 10, true)
+			Expect(conn.pathValidation).ToNot(BeNil())
+			sph.EXPECT().ValidatedPath()
+			Expect(conn.handlePathResponseFrame(&wire.PathResponseFrame{Data: conn.pathValidation.challenge})).To(Succeed())
+			Expect(conn.pathValidation).To(BeNil())
+			Expect(conn.RemoteAddr()).To(Equal(newAddr))
+		})
+
+		It("falls back to the previous path if the new path can't be validated", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: remoteAddr.Port}
+			sph.EXPECT().MigratedPath(protocol.ByteCount(6), true)
+			conn.maybeMigrate(receivedPacket{rcvConn: rcvConn, remoteAddr: newAddr, data: []byte("foobar")}, 10, true)
+			sph.EXPECT().ValidatedPath()
+			conn.abandonPathValidation()
+			Expect(conn.pathValidation).To(BeNil())
+			Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
+		})
+	})
+
 	Context("timeouts", func() {
 		BeforeEach(func() {
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -2252,12 +2920,17 @@ var _ = Describe("Connection", func() {
 
 		It("times out due to non-completed handshake", func() {
 			conn.handshakeComplete = false
+			conn.sentPacketHandler.ReceivedBytes(protocol.MinInitialPacketSize)
 			conn.creationTime = time.Now().Add(-2 * protocol.DefaultHandshakeIdleTimeout).Add(-time.Second)
 			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
 			cryptoSetup.EXPECT().Close()
 			gomock.InOrder(
+				tracer.EXPECT().FailedHandshake(logging.HandshakeFailureTimeout, protocol.EncryptionInitial, qerr.ErrHandshakeTimeout),
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					Expect(e).To(MatchError(&HandshakeTimeoutError{}))
+					var hsErr *HandshakeError
+					Expect(errors.As(e, &hsErr)).To(BeTrue())
+					Expect(hsErr.Reason).To(Equal(HandshakeFailureTimeout))
 				}),
 				tracer.EXPECT().Close(),
 			)
@@ -2276,6 +2949,34 @@ var _ = Describe("Connection", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("detects when the handshake stalls because of the amplification limit", func() {
+			conn.handshakeComplete = false
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().IsAmplificationLimited().Return(true)
+			conn.creationTime = time.Now().Add(-2 * protocol.DefaultHandshakeIdleTimeout).Add(-time.Second)
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().FailedHandshake(logging.HandshakeFailureAmplificationLimited, protocol.EncryptionInitial, qerr.ErrHandshakeTimeout),
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				var hsErr *HandshakeError
+				Expect(errors.As(err, &hsErr)).To(BeTrue())
+				Expect(hsErr.Reason).To(Equal(HandshakeFailureAmplificationLimited))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("does not use the idle timeout before the handshake complete", func() {
 			conn.handshakeComplete = false
 			conn.config.HandshakeIdleTimeout = 9999 * time.Second
@@ -2286,6 +2987,7 @@ var _ = Describe("Connection", func() {
 				return &coalescedPacket{buffer: getPacketBuffer()}, nil
 			})
 			gomock.InOrder(
+				tracer.EXPECT().FailedHandshake(logging.HandshakeFailureOther, protocol.EncryptionInitial, gomock.Any()),
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					idleTimeout := &IdleTimeoutError{}
 					handshakeTimeout := &HandshakeTimeoutError{}
@@ -2317,6 +3019,7 @@ var _ = Describe("Connection", func() {
 			connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
 			cryptoSetup.EXPECT().Close()
 			gomock.InOrder(
+				tracer.EXPECT().FailedHandshake(gomock.Any(), protocol.EncryptionInitial, qerr.ErrIdleTimeout),
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					Expect(e).To(MatchError(&IdleTimeoutError{}))
 				}),
@@ -2437,6 +3140,110 @@ var _ = Describe("Connection", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("uses the configured clock for the receive time of packets", func() {
+		clock := &fakeClock{now: time.Now().Add(-time.Hour)}
+		conn.config.Clock = clock
+		conn.clock = clock
+		conn.handlePacket(receivedPacket{data: []byte("foobar"), rcvTime: time.Now()})
+		var p receivedPacket
+		Expect(conn.receivedPackets).To(Receive(&p))
+		Expect(p.rcvTime).To(Equal(clock.now))
+	})
+
+	Context("pinging", func() {
+		It("sends a PING frame and returns the RTT", func() {
+			clock := &fakeClock{now: time.Now()}
+			conn.clock = clock
+			type result struct {
+				rtt time.Duration
+				err error
+			}
+			resChan := make(chan result, 1)
+			go func() {
+				defer GinkgoRecover()
+				rtt, err := conn.Ping(context.Background())
+				resChan <- result{rtt: rtt, err: err}
+			}()
+			var frames []ackhandler.Frame
+			Eventually(func() []ackhandler.Frame {
+				frames, _ = conn.framer.AppendControlFrames(nil, 1000, protocol.Version1)
+				return frames
+			}).Should(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			Expect(frames[0].Handler).ToNot(BeNil())
+			sentPingFrames(frames, clock.now)
+			Consistently(resChan).ShouldNot(Receive())
+			clock.now = clock.now.Add(123 * time.Millisecond)
+			frames[0].Handler.OnAcked(frames[0].Frame)
+			var res result
+			Eventually(resChan).Should(Receive(&res))
+			Expect(res.err).ToNot(HaveOccurred())
+			Expect(res.rtt).To(Equal(123 * time.Millisecond))
+		})
+
+		It("returns when the context is canceled", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := conn.Ping(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
+	Context("congestion limiting", func() {
+		It("notifies the streams when it becomes congestion limited", func() {
+			conn.framer = newFramer(streamManager, nil)
+			str := NewMockSendStreamI(mockCtrl)
+			streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(str, nil)
+			str.EXPECT().onCongestionLimited()
+			conn.framer.AddActiveStream(4)
+			conn.setCongestionLimited(true)
+			conn.setCongestionLimited(true)
+			conn.setCongestionLimited(false)
+			// the stream didn't send any data since it was notified
+			conn.setCongestionLimited(true)
+		})
+	})
+
+	Context("probing the MTU", func() {
+		It("errors when Path MTU Discovery is disabled", func() {
+			conn.config.DisablePathMTUDiscovery = true
+			Expect(conn.ProbeMTU(context.Background(), 1400)).To(MatchError("path MTU discovery disabled"))
+		})
+
+		It("errors when the DF bit can't be set", func() {
+			conn.config.DisablePathMTUDiscovery = false
+			Expect(conn.ProbeMTU(context.Background(), 1400)).To(MatchError("path MTU discovery disabled"))
+		})
+
+		It("rejects too small sizes", func() {
+			capabilities = connCapabilities{DF: true}
+			conn.config.DisablePathMTUDiscovery = false
+			Expect(conn.ProbeMTU(context.Background(), 1000)).To(MatchError("MTU probe size must be at least 1200 bytes"))
+		})
+
+		It("returns the result of the probe", func() {
+			capabilities = connCapabilities{DF: true}
+			conn.config.DisablePathMTUDiscovery = false
+			mtuDiscoverer := NewMockMTUDiscoverer(mockCtrl)
+			conn.mtuDiscoverer = mtuDiscoverer
+			r := &mtuProbeRequest{size: 1400, done: make(chan struct{})}
+			mtuDiscoverer.EXPECT().RequestProbe(protocol.ByteCount(1400)).Return(r)
+			errChan := make(chan error, 1)
+			go func() { errChan <- conn.ProbeMTU(context.Background(), 1400) }()
+			Consistently(errChan).ShouldNot(Receive())
+			r.complete(errMTUProbeLost)
+			Eventually(errChan).Should(Receive(MatchError(errMTUProbeLost)))
+		})
+
+		It("returns when the context is canceled", func() {
+			capabilities = connCapabilities{DF: true}
+			conn.config.DisablePathMTUDiscovery = false
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(conn.ProbeMTU(ctx, 1400)).To(MatchError(context.DeadlineExceeded))
+		})
+	})
+
 	Context("getting streams", func() {
 		It("opens streams", func() {
 			mstr := NewMockStreamI(mockCtrl)
@@ -2498,6 +3305,22 @@ var _ = Describe("Connection", func() {
 	It("returns the remote address", func() {
 		Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
 	})
+
+	It("returns the RTT statistics", func() {
+		Expect(conn.RTTStats()).To(Equal(RTTStats{}))
+		now := time.Now()
+		conn.rttStats.UpdateRTT(100*time.Millisecond, 0, now)
+		conn.rttStats.UpdateRTT(50*time.Millisecond, 0, now.Add(time.Second))
+		stats := conn.RTTStats()
+		Expect(stats.LatestRTT).To(Equal(50 * time.Millisecond))
+		Expect(stats.MinRTT).To(Equal(50 * time.Millisecond))
+		Expect(stats.SmoothedRTT).To(Equal(conn.rttStats.SmoothedRTT()))
+		Expect(stats.RTTVariation).To(Equal(conn.rttStats.MeanDeviation()))
+		Expect(stats.Samples).To(Equal([]RTTSample{
+			{Time: now, RTT: 100 * time.Millisecond},
+			{Time: now.Add(time.Second), RTT: 50 * time.Millisecond},
+		}))
+	})
 })
 
 var _ = Describe("Client Connection", func() {
@@ -2546,6 +3369,7 @@ var _ = Describe("Client Connection", func() {
 		tracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
 		tracer.EXPECT().SentTransportParameters(gomock.Any())
 		tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
+		tracer.EXPECT().ReachedHandshakeMilestone(gomock.Any(), gomock.Any()).AnyTimes()
 		tracer.EXPECT().UpdatedCongestionState(gomock.Any())
 		conn = newClientConnection(
 			mconn,
@@ -2557,7 +3381,7 @@ var _ = Describe("Client Connection", func() {
 			tlsConf,
 			42, // initial packet number
 			false,
-			false,
+			nil,
 			tr,
 			1234,
 			utils.DefaultLogger,
@@ -2570,6 +3394,21 @@ var _ = Describe("Client Connection", func() {
 		conn.sentFirstPacket = true
 	})
 
+	It("doesn't set an initial RTT by default", func() {
+		Expect(conn.rttStats.SmoothedRTT()).To(BeZero())
+	})
+
+	Context("with an initial RTT", func() {
+		BeforeEach(func() {
+			quicConf.InitialRTT = 25 * time.Millisecond
+		})
+
+		It("uses the initial RTT from the config", func() {
+			Expect(conn.rttStats.SmoothedRTT()).To(Equal(25 * time.Millisecond))
+			Expect(conn.rttStats.PTO(false)).To(BeNumerically("<", 50*time.Millisecond))
+		})
+	})
+
 	It("changes the connection ID when receiving the first packet from the server", func() {
 		unpacker := NewMockUnpacker(mockCtrl)
 		unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any(), conn.version).DoAndReturn(func(hdr *wire.Header, _ time.Time, data []byte, _ protocol.VersionNumber) (*unpackedPacket, error) {
@@ -2607,6 +3446,7 @@ var _ = Describe("Client Connection", func() {
 		cryptoSetup.EXPECT().Close()
 		connRunner.EXPECT().ReplaceWithClosed([]protocol.ConnectionID{srcConnID}, gomock.Any(), gomock.Any())
 		mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
+		tracer.EXPECT().FailedHandshake(gomock.Any(), gomock.Any(), gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		conn.shutdown()
@@ -2649,7 +3489,27 @@ var _ = Describe("Client Connection", func() {
 		sph.EXPECT().DropPackets(protocol.EncryptionHandshake)
 		sph.EXPECT().SetHandshakeConfirmed()
 		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		Expect(conn.HandshakeConfirmed()).ToNot(BeClosed())
 		Expect(conn.handleHandshakeDoneFrame()).To(Succeed())
+		Expect(conn.HandshakeConfirmed()).To(BeClosed())
+	})
+
+	It("processes the results of the handshake worker pool", func() {
+		conn.undecryptablePackets = []receivedPacket{{data: []byte("foobar")}}
+		gomock.InOrder(
+			cryptoSetup.EXPECT().HandleWorkerResults(),
+			cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventReceivedReadKeys}),
+			cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent}),
+		)
+		Expect(conn.handleHandshakeWorkerResult()).To(Succeed())
+		Expect(conn.undecryptablePackets).To(BeEmpty())
+		Expect(conn.undecryptablePacketsToProcess).To(HaveLen(1))
+	})
+
+	It("returns errors that occurred on the handshake worker pool", func() {
+		testErr := errors.New("handshake failed")
+		cryptoSetup.EXPECT().HandleWorkerResults().Return(testErr)
+		Expect(conn.handleHandshakeWorkerResult()).To(MatchError(testErr))
 	})
 
 	It("interprets an ACK for 1-RTT packets as confirmation of the handshake", func() {
@@ -2668,6 +3528,7 @@ var _ = Describe("Client Connection", func() {
 
 	It("doesn't send a CONNECTION_CLOSE when no packet was sent", func() {
 		conn.sentFirstPacket = false
+		tracer.EXPECT().FailedHandshake(logging.HandshakeFailureOther, protocol.EncryptionInitial, gomock.Any())
 		tracer.EXPECT().ClosedConnection(gomock.Any())
 		tracer.EXPECT().Close()
 		running := make(chan struct{})
@@ -2765,6 +3626,7 @@ var _ = Describe("Client Connection", func() {
 			connRunner.EXPECT().Remove(srcConnID).MaxTimes(1)
 			gomock.InOrder(
 				tracer.EXPECT().ReceivedVersionNegotiationPacket(gomock.Any(), gomock.Any(), gomock.Any()),
+				tracer.EXPECT().FailedHandshake(logging.HandshakeFailureNoCompatibleVersion, protocol.EncryptionInitial, gomock.Any()),
 				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					var vnErr *VersionNegotiationError
 					Expect(errors.As(e, &vnErr)).To(BeTrue())
@@ -2781,6 +3643,64 @@ var _ = Describe("Client Connection", func() {
 			Expect(err.Error()).To(ContainSubstring("no compatible QUIC version found"))
 		})
 
+		It("uses the ChooseVersion callback", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			conn.sentPacketHandler = sph
+			sph.EXPECT().ReceivedBytes(gomock.Any())
+			sph.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(128), protocol.PacketNumberLen4)
+			var info *VersionNegotiationInfo
+			conn.config.ChooseVersion = func(i *VersionNegotiationInfo) (VersionNumber, bool) {
+				info = i
+				return protocol.Version2, true
+			}
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				errChan <- conn.run()
+			}()
+			connRunner.EXPECT().Remove(srcConnID)
+			tracer.EXPECT().ReceivedVersionNegotiationPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			Expect(conn.handlePacketImpl(getVNP(1337, protocol.Version2))).To(BeFalse())
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(err).To(BeAssignableToTypeOf(&errCloseForRecreating{}))
+			recreateErr := err.(*errCloseForRecreating)
+			Expect(recreateErr.nextVersion).To(Equal(protocol.Version2))
+			Expect(recreateErr.serverVersions).To(ContainElements(protocol.VersionNumber(1337), protocol.Version2))
+			Expect(info.RemoteAddr).To(Equal(conn.RemoteAddr()))
+			Expect(info.ClientVersion).To(Equal(protocol.Version1))
+			Expect(info.ServerVersions).To(Equal(recreateErr.serverVersions))
+		})
+
+		It("closes when ChooseVersion returns a version that isn't offered by the server", func() {
+			conn.config.ChooseVersion = func(*VersionNegotiationInfo) (VersionNumber, bool) { return protocol.Version2, true }
+			errChan := make(chan error, 1)
+			packer.EXPECT().PackCoalescedPacket(gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				errChan <- conn.run()
+			}()
+			connRunner.EXPECT().Remove(srcConnID).MaxTimes(1)
+			gomock.InOrder(
+				tracer.EXPECT().ReceivedVersionNegotiationPacket(gomock.Any(), gomock.Any(), gomock.Any()),
+				tracer.EXPECT().FailedHandshake(logging.HandshakeFailureNoCompatibleVersion, protocol.EncryptionInitial, gomock.Any()),
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			cryptoSetup.EXPECT().Close()
+			Expect(conn.handlePacketImpl(getVNP(1337))).To(BeFalse())
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			var vnErr *VersionNegotiationError
+			Expect(errors.As(err, &vnErr)).To(BeTrue())
+			Expect(vnErr.Theirs).To(ContainElement(protocol.VersionNumber(1337)))
+		})
+
 		It("ignores Version Negotiation packets that offer the current version", func() {
 			p := getVNP(conn.version)
 			tracer.EXPECT().DroppedPacket(logging.PacketTypeVersionNegotiation, p.Size(), logging.PacketDropUnexpectedVersion)
@@ -11,10 +11,12 @@ var deadlineSendImmediately = time.Time{}.Add(42 * time.Millisecond) // any valu
 type connectionTimer struct {
 	timer *utils.Timer
 	last  time.Time
+	// If set, deadlines are rounded up to a multiple of the granularity.
+	granularity time.Duration
 }
 
-func newTimer() *connectionTimer {
-	return &connectionTimer{timer: utils.NewTimer()}
+func newTimer(clock utils.Clock, granularity time.Duration) *connectionTimer {
+	return &connectionTimer{timer: utils.NewTimerWithClock(clock), granularity: granularity}
 }
 
 func (t *connectionTimer) SetRead() {
@@ -43,6 +45,12 @@ func (t *connectionTimer) SetTimer(idleTimeoutOrKeepAlive, ackAlarm, lossTime, p
 	if !pacing.IsZero() && pacing.Before(deadline) {
 		deadline = pacing
 	}
+	if t.granularity > 0 && deadline != deadlineSendImmediately {
+		// Round up, such that the timer never fires before the deadline.
+		if rounded := deadline.Truncate(t.granularity); rounded.Before(deadline) {
+			deadline = rounded.Add(t.granularity)
+		}
+	}
 	t.timer.Reset(deadline)
 }
 
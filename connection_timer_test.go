@@ -3,44 +3,53 @@ package quic
 import (
 	"time"
 
+	"github.com/quic-go/quic-go/internal/utils"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
 func (t *connectionTimer) Deadline() time.Time { return t.timer.Deadline() }
 
+type fakeClock struct {
+	utils.DefaultClock
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
 var _ = Describe("Timer", func() {
 	It("sets an idle timeout", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), time.Time{}, time.Time{}, time.Time{})
 		Expect(t.Deadline()).To(Equal(now.Add(time.Hour)))
 	})
 
 	It("sets an ACK timer", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), time.Time{}, time.Time{})
 		Expect(t.Deadline()).To(Equal(now.Add(time.Minute)))
 	})
 
 	It("sets a loss timer", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), now.Add(time.Second), time.Time{})
 		Expect(t.Deadline()).To(Equal(now.Add(time.Second)))
 	})
 
 	It("sets a pacing timer", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), now.Add(time.Second), now.Add(time.Millisecond))
 		Expect(t.Deadline()).To(Equal(now.Add(time.Millisecond)))
 	})
 
 	It("doesn't reset to an earlier time", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), time.Time{}, time.Time{})
 		Expect(t.Deadline()).To(Equal(now.Add(time.Minute)))
 		t.SetRead()
@@ -51,7 +60,7 @@ var _ = Describe("Timer", func() {
 
 	It("allows the pacing timer to be set to send immediately", func() {
 		now := time.Now()
-		t := newTimer()
+		t := newTimer(utils.DefaultClock{}, 0)
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), time.Time{}, time.Time{})
 		Expect(t.Deadline()).To(Equal(now.Add(time.Minute)))
 		t.SetRead()
@@ -59,4 +68,19 @@ var _ = Describe("Timer", func() {
 		t.SetTimer(now.Add(time.Hour), now.Add(time.Minute), time.Time{}, deadlineSendImmediately)
 		Expect(t.Deadline()).To(Equal(deadlineSendImmediately))
 	})
+
+	It("rounds deadlines up to the granularity", func() {
+		now := time.Now().Truncate(10 * time.Millisecond)
+		t := newTimer(utils.DefaultClock{}, 10*time.Millisecond)
+		t.SetTimer(now.Add(time.Hour), now.Add(time.Millisecond), time.Time{}, time.Time{})
+		Expect(t.Deadline()).To(Equal(now.Add(10 * time.Millisecond)))
+		t.SetTimer(now.Add(time.Hour), now.Add(20*time.Millisecond), now.Add(19*time.Millisecond), time.Time{})
+		Expect(t.Deadline()).To(Equal(now.Add(20 * time.Millisecond)))
+		// deadlines that are already a multiple of the granularity are not changed
+		t.SetTimer(now.Add(time.Hour), now.Add(30*time.Millisecond), time.Time{}, time.Time{})
+		Expect(t.Deadline()).To(Equal(now.Add(30 * time.Millisecond)))
+		// sending immediately is not delayed
+		t.SetTimer(now.Add(time.Hour), time.Time{}, time.Time{}, deadlineSendImmediately)
+		Expect(t.Deadline()).To(Equal(deadlineSendImmediately))
+	})
 })
@@ -7,6 +7,7 @@ import (
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
 )
 
 type datagramQueue struct {
@@ -16,6 +17,9 @@ type datagramQueue struct {
 	rcvMx    sync.Mutex
 	rcvQueue [][]byte
 	rcvd     chan struct{} // used to notify Receive that a new datagram was received
+	// rcvQueueBytes is the number of bytes in the rcvQueue.
+	// It is accounted against the global memory budget.
+	rcvQueueBytes int
 
 	closeErr error
 	closed   chan struct{}
@@ -24,16 +28,18 @@ type datagramQueue struct {
 
 	dequeued chan struct{}
 
+	tracer *logging.ConnectionTracer
 	logger utils.Logger
 }
 
-func newDatagramQueue(hasData func(), logger utils.Logger) *datagramQueue {
+func newDatagramQueue(hasData func(), tracer *logging.ConnectionTracer, logger utils.Logger) *datagramQueue {
 	return &datagramQueue{
 		hasData:   hasData,
 		sendQueue: make(chan *wire.DatagramFrame, 1),
 		rcvd:      make(chan struct{}, 1),
 		dequeued:  make(chan struct{}),
 		closed:    make(chan struct{}),
+		tracer:    tracer,
 		logger:    logger,
 	}
 }
@@ -84,8 +90,10 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	copy(data, f.Data)
 	var queued bool
 	h.rcvMx.Lock()
-	if len(h.rcvQueue) < protocol.DatagramRcvQueueLen {
+	// drop the datagram if the receive queue is full, or if the memory budget is used up
+	if len(h.rcvQueue) < protocol.DatagramRcvQueueLen && utils.GlobalMemoryBudget.TryAcquire(len(data)) {
 		h.rcvQueue = append(h.rcvQueue, data)
+		h.rcvQueueBytes += len(data)
 		queued = true
 		select {
 		case h.rcvd <- struct{}{}:
@@ -93,9 +101,15 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 		}
 	}
 	h.rcvMx.Unlock()
-	if !queued && h.logger.Debug() {
+	if queued {
+		return
+	}
+	if h.logger.Debug() {
 		h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload)", len(f.Data))
 	}
+	if h.tracer != nil && h.tracer.DroppedDatagram != nil {
+		h.tracer.DroppedDatagram(protocol.ByteCount(len(f.Data)))
+	}
 }
 
 // Receive gets a received DATAGRAM frame.
@@ -105,6 +119,9 @@ func (h *datagramQueue) Receive(ctx context.Context) ([]byte, error) {
 		if len(h.rcvQueue) > 0 {
 			data := h.rcvQueue[0]
 			h.rcvQueue = h.rcvQueue[1:]
+			if h.rcvQueueBytes > 0 {
+				h.releaseRcvQueueBytes(len(data))
+			}
 			h.rcvMx.Unlock()
 			return data, nil
 		}
@@ -120,7 +137,25 @@ func (h *datagramQueue) Receive(ctx context.Context) ([]byte, error) {
 	}
 }
 
+// ReceiveQueueBytes returns the number of bytes of datagrams that were received, but not yet read by the application.
+func (h *datagramQueue) ReceiveQueueBytes() int {
+	h.rcvMx.Lock()
+	defer h.rcvMx.Unlock()
+	return h.rcvQueueBytes
+}
+
+// must be called after locking the rcvMx
+func (h *datagramQueue) releaseRcvQueueBytes(n int) {
+	h.rcvQueueBytes -= n
+	utils.GlobalMemoryBudget.Release(n)
+}
+
 func (h *datagramQueue) CloseWithError(e error) {
 	h.closeErr = e
+	// Datagrams that were already received can still be read after the queue was closed,
+	// but they're not accounted against the memory budget any more.
+	h.rcvMx.Lock()
+	h.releaseRcvQueueBytes(h.rcvQueueBytes)
+	h.rcvMx.Unlock()
 	close(h.closed)
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 
+	mocklogging "github.com/quic-go/quic-go/internal/mocks/logging"
+	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
@@ -17,7 +19,7 @@ var _ = Describe("Datagram Queue", func() {
 
 	BeforeEach(func() {
 		queued = make(chan struct{}, 100)
-		queue = newDatagramQueue(func() { queued <- struct{}{} }, utils.DefaultLogger)
+		queue = newDatagramQueue(func() { queued <- struct{}{} }, nil, utils.DefaultLogger)
 	})
 
 	Context("sending", func() {
@@ -65,6 +67,44 @@ var _ = Describe("Datagram Queue", func() {
 			Expect(f.Data).To(Equal([]byte("bar")))
 		})
 
+		It("accounts received DATAGRAM frames against the memory budget", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 9))
+			data, err := queue.Receive(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+			// closing releases the memory, but datagrams can still be received
+			queue.CloseWithError(errors.New("test error"))
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+			data, err = queue.Receive(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		})
+
+		It("drops DATAGRAM frames when the memory budget is used up", func() {
+			utils.GlobalMemoryBudget.SetLimit(utils.GlobalMemoryBudget.Used() + 5)
+			defer utils.GlobalMemoryBudget.SetLimit(0)
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			data, err := queue.Receive(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foo")))
+		})
+
+		It("traces dropped DATAGRAM frames", func() {
+			tr, tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+			queue = newDatagramQueue(func() {}, tr, utils.DefaultLogger)
+			for i := 0; i < protocol.DatagramRcvQueueLen; i++ {
+				queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			}
+			tracer.EXPECT().DroppedDatagram(protocol.ByteCount(6))
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+		})
+
 		It("closes", func() {
 			errChan := make(chan error, 1)
 			go func() {
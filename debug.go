@@ -0,0 +1,210 @@
+package quic
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// ConnectionInfo is a snapshot of the state of a live connection.
+// It is intended for debugging and introspection purposes.
+type ConnectionInfo struct {
+	// TracingID is the ID of the connection, as used in the ConnectionTracingKey.
+	TracingID   uint64
+	Perspective logging.Perspective
+	LocalAddr   net.Addr
+	RemoteAddr  net.Addr
+	Version     VersionNumber
+	// CreationTime is the time when the connection was created.
+	CreationTime       time.Time
+	HandshakeComplete  bool
+	HandshakeConfirmed bool
+
+	SmoothedRTT   time.Duration
+	MinRTT        time.Duration
+	LatestRTT     time.Duration
+	MeanDeviation time.Duration
+
+	CongestionWindow uint64
+	BytesInFlight    uint64
+
+	// OpenBidiStreams is the number of open bidirectional streams, opened by either peer.
+	OpenBidiStreams int
+	// OpenUniStreams is the number of open unidirectional streams, opened by either peer.
+	OpenUniStreams int
+
+	// IdleTime is the time since the last activity on the connection.
+	IdleTime time.Duration
+	// IdleTimeout is the idle timeout negotiated with the peer.
+	IdleTimeout time.Duration
+
+	// Memory is a breakdown of the memory held by the connection.
+	Memory ConnectionMemoryUsage
+}
+
+// ConnectionMemoryUsage is a breakdown of the memory held by a connection, in bytes.
+// Some of the values are estimates, they're intended to find out which part of a connection uses a lot of memory.
+type ConnectionMemoryUsage struct {
+	// StreamReceiveBuffers is the data received on streams that hasn't been read by the application yet,
+	// including data that was received out of order.
+	StreamReceiveBuffers int64
+	// StreamSendBuffers is the data written to streams that hasn't been acknowledged by the peer yet.
+	StreamSendBuffers int64
+	// DatagramReceiveQueue is the size of the datagrams that haven't been read by the application yet.
+	DatagramReceiveQueue int64
+	// SentPacketHistory is the (estimated) memory used to track packets that haven't been acknowledged yet,
+	// excluding the stream data, which is accounted for in StreamSendBuffers.
+	SentPacketHistory int64
+	// SendQueue is the size of the buffers of packets that are waiting to be written to the socket.
+	SendQueue int64
+	// ReceiveQueue is the (estimated) size of the packets that were received, but not yet processed,
+	// including packets that can't be decrypted yet.
+	ReceiveQueue int64
+}
+
+// Total returns the total number of bytes held by the connection.
+func (u ConnectionMemoryUsage) Total() int64 {
+	return u.StreamReceiveBuffers + u.StreamSendBuffers + u.DatagramReceiveQueue + u.SentPacketHistory + u.SendQueue + u.ReceiveQueue
+}
+
+// TrackLiveConnections enables keeping track of the connections running in this process,
+// such that they can be inspected using LiveConnections and LiveConnection.
+// This is done by the quicdebug package when its handler or expvar variable is installed.
+// Tracking is disabled by default, since every connection then needs to register in a process-wide registry.
+// Connections started before tracking was enabled are not tracked.
+func TrackLiveConnections() {
+	liveConns.enabled.Store(true)
+}
+
+// LiveConnections returns a snapshot of the state of all connections that are currently running in this process,
+// if tracking was enabled using TrackLiveConnections.
+// The connections are sorted by their tracing ID.
+func LiveConnections() []ConnectionInfo {
+	conns := liveConns.Connections()
+	infos := make([]ConnectionInfo, 0, len(conns))
+	for _, c := range conns {
+		if info, ok := c.debugInfo(); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// LiveConnection returns a snapshot of the state of the connection with the given tracing ID.
+// It returns false if no such connection is currently running.
+func LiveConnection(tracingID uint64) (ConnectionInfo, bool) {
+	c, ok := liveConns.Get(tracingID)
+	if !ok {
+		return ConnectionInfo{}, false
+	}
+	return c.debugInfo()
+}
+
+// debugInfo collects a snapshot of the connection state.
+// It must not be called from the run loop.
+func (s *connection) debugInfo() (ConnectionInfo, bool) {
+	var info ConnectionInfo
+	ok := s.runInLoop(func() {
+		now := s.clock.Now()
+		info = ConnectionInfo{
+			TracingID:          s.tracingID(),
+			Perspective:        s.perspective,
+			LocalAddr:          s.conn.LocalAddr(),
+			RemoteAddr:         s.conn.RemoteAddr(),
+			Version:            s.version,
+			CreationTime:       s.creationTime,
+			HandshakeComplete:  s.handshakeComplete,
+			HandshakeConfirmed: s.handshakeConfirmed,
+			SmoothedRTT:        s.rttStats.SmoothedRTT(),
+			MinRTT:             s.rttStats.MinRTT(),
+			LatestRTT:          s.rttStats.LatestRTT(),
+			MeanDeviation:      s.rttStats.MeanDeviation(),
+			CongestionWindow:   uint64(s.sentPacketHandler.GetCongestionWindow()),
+			BytesInFlight:      uint64(s.sentPacketHandler.GetBytesInFlight()),
+			IdleTime:           now.Sub(s.idleTimeoutStartTime()),
+			IdleTimeout:        s.idleTimeout,
+		}
+		info.OpenBidiStreams, info.OpenUniStreams = s.streamsMap.NumStreams()
+		info.Memory = s.memoryUsage()
+	})
+	return info, ok
+}
+
+// memoryUsage must be called from the run loop.
+func (s *connection) memoryUsage() ConnectionMemoryUsage {
+	var u ConnectionMemoryUsage
+	send, receive := s.streamsMap.BufferedBytes()
+	u.StreamSendBuffers = int64(send)
+	u.StreamReceiveBuffers = int64(receive)
+	if s.datagramQueue != nil {
+		u.DatagramReceiveQueue = int64(s.datagramQueue.ReceiveQueueBytes())
+	}
+	u.SentPacketHistory = int64(s.sentPacketHandler.MemoryUsage())
+	u.SendQueue = int64(s.sendQueue.QueuedBytes())
+	// The packets in the channel can't be inspected without dequeueing them.
+	u.ReceiveQueue = int64(len(s.receivedPackets) * protocol.MaxPacketBufferSize)
+	for _, p := range s.undecryptablePackets {
+		u.ReceiveQueue += int64(len(p.data))
+	}
+	for _, p := range s.undecryptablePacketsToProcess {
+		u.ReceiveQueue += int64(len(p.data))
+	}
+	return u
+}
+
+func (s *connection) tracingID() uint64 {
+	id, _ := s.ctx.Value(ConnectionTracingKey).(uint64)
+	return id
+}
+
+// liveConns keeps track of all running connections, for introspection purposes.
+// Connections are only tracked once tracking was enabled using TrackLiveConnections.
+var liveConns = connectionRegistry{conns: make(map[uint64]*connection)}
+
+type connectionRegistry struct {
+	enabled atomic.Bool
+
+	mutex sync.Mutex
+	conns map[uint64]*connection // indexed by the tracing ID
+}
+
+// Add adds a connection, if tracking is enabled.
+// It returns false if the connection was not added.
+func (r *connectionRegistry) Add(c *connection) bool {
+	if !r.enabled.Load() {
+		return false
+	}
+	r.mutex.Lock()
+	r.conns[c.tracingID()] = c
+	r.mutex.Unlock()
+	return true
+}
+
+func (r *connectionRegistry) Remove(c *connection) {
+	r.mutex.Lock()
+	delete(r.conns, c.tracingID())
+	r.mutex.Unlock()
+}
+
+func (r *connectionRegistry) Get(id uint64) (*connection, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, ok := r.conns[id]
+	return c, ok
+}
+
+func (r *connectionRegistry) Connections() []*connection {
+	r.mutex.Lock()
+	conns := make([]*connection, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	r.mutex.Unlock()
+	sort.Slice(conns, func(i, j int) bool { return conns[i].tracingID() < conns[j].tracingID() })
+	return conns
+}
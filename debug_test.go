@@ -0,0 +1,39 @@
+package quic
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Registry", func() {
+	newConn := func(id uint64) *connection {
+		return &connection{ctx: context.WithValue(context.Background(), ConnectionTracingKey, id)}
+	}
+
+	It("doesn't track connections unless enabled", func() {
+		r := connectionRegistry{conns: make(map[uint64]*connection)}
+		Expect(r.Add(newConn(1))).To(BeFalse())
+		Expect(r.Connections()).To(BeEmpty())
+		_, ok := r.Get(1)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("tracks connections by their tracing ID", func() {
+		r := connectionRegistry{conns: make(map[uint64]*connection)}
+		r.enabled.Store(true)
+		c1 := newConn(2)
+		c2 := newConn(1)
+		Expect(r.Add(c1)).To(BeTrue())
+		Expect(r.Add(c2)).To(BeTrue())
+		c, ok := r.Get(2)
+		Expect(ok).To(BeTrue())
+		Expect(c).To(Equal(c1))
+		Expect(r.Connections()).To(Equal([]*connection{c2, c1}))
+		r.Remove(c1)
+		_, ok = r.Get(2)
+		Expect(ok).To(BeFalse())
+		Expect(r.Connections()).To(Equal([]*connection{c2}))
+	})
+})
@@ -0,0 +1,173 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// An openedShortHeaderPacket is a short header packet that was opened by a decryption worker.
+// It still needs to be passed to the unpacker (UnpackOpenedShortHeader) on the connection's goroutine.
+type openedShortHeaderPacket struct {
+	pn       protocol.PacketNumber
+	pnLen    protocol.PacketNumberLen
+	kp       protocol.KeyPhaseBit
+	keyPhase protocol.KeyPhase
+	data     []byte
+
+	parseErr error // nil or wire.ErrInvalidReservedBits
+	openErr  error // the error returned by the worker's Open
+}
+
+var openedShortHeaderPacketPool = sync.Pool{New: func() any { return &openedShortHeaderPacket{} }}
+
+func getOpenedShortHeaderPacket() *openedShortHeaderPacket {
+	return openedShortHeaderPacketPool.Get().(*openedShortHeaderPacket)
+}
+
+func putOpenedShortHeaderPacket(p *openedShortHeaderPacket) {
+	*p = openedShortHeaderPacket{}
+	openedShortHeaderPacketPool.Put(p)
+}
+
+// openShortHeaderPacket removes header protection and decrypts a short header packet.
+// It returns nil if the packet needs to be unpacked by the connection instead:
+// if the header can't be parsed, if the packet is protected with a different key phase,
+// or if the packet number is too short to be decoded reliably,
+// since the worker might not know the highest packet number received so far.
+// In that case, data is not modified.
+func openShortHeaderPacket(w handshake.ShortHeaderOpenerWorker, data []byte, connIDLen int) *openedShortHeaderPacket {
+	hdrLen := 1 /* first header byte */ + connIDLen
+	if len(data) < hdrLen+4+16 {
+		return nil
+	}
+	firstByte := data[0]
+	var origPNBytes [4]byte
+	copy(origPNBytes[:], data[hdrLen:hdrLen+4])
+	restoreHeader := func() {
+		data[0] = firstByte
+		copy(data[hdrLen:hdrLen+4], origPNBytes[:])
+	}
+
+	l, pn, pnLen, kp, parseErr := unpackShortHeader(w, data, connIDLen)
+	if (parseErr != nil && parseErr != wire.ErrInvalidReservedBits) || pnLen == protocol.PacketNumberLen1 {
+		restoreHeader()
+		return nil
+	}
+	pn = w.DecodePacketNumber(pn, pnLen)
+	decrypted, keyPhase, err := w.Open(data[l:l], data[l:], pn, kp, data[:l])
+	if err == handshake.ErrKeyPhaseMismatch {
+		restoreHeader()
+		return nil
+	}
+	p := getOpenedShortHeaderPacket()
+	p.pn = pn
+	p.pnLen = pnLen
+	p.kp = kp
+	p.keyPhase = keyPhase
+	p.data = decrypted
+	p.parseErr = parseErr
+	p.openErr = err
+	return p
+}
+
+type decryptionJob struct {
+	p    receivedPacket
+	done chan struct{} // receives a value when the job has been processed
+}
+
+var decryptionJobPool = sync.Pool{New: func() any { return &decryptionJob{done: make(chan struct{}, 1)} }}
+
+// The decryptionPool decrypts short header packets on multiple goroutines.
+// Packets are delivered in the order they were submitted.
+type decryptionPool struct {
+	connIDLen shortHeaderConnIDLen
+	deliver   func(receivedPacket)
+
+	mutex  sync.Mutex
+	closed bool
+	// jobs is read by the workers
+	jobs chan *decryptionJob
+	// ordered contains all jobs, in the order they were submitted.
+	// Since a job is only removed from ordered after it has been processed,
+	// jobs never contains more jobs than ordered.
+	ordered chan *decryptionJob
+}
+
+func newDecryptionPool(opener handshake.ConcurrentShortHeaderOpener, numWorkers int, connIDLen shortHeaderConnIDLen, deliver func(receivedPacket)) *decryptionPool {
+	p := &decryptionPool{
+		connIDLen: connIDLen,
+		deliver:   deliver,
+		jobs:      make(chan *decryptionJob, protocol.MaxConnUnprocessedPackets),
+		ordered:   make(chan *decryptionJob, protocol.MaxConnUnprocessedPackets),
+	}
+	for i := 0; i < numWorkers; i++ {
+		go p.runWorker(opener.NewWorker())
+	}
+	go p.runDelivery()
+	return p
+}
+
+// submit submits a packet for decryption.
+// Long header packets are not decrypted, but delivered in order with all other packets.
+// It returns false if the packet was dropped, because too many packets are queued.
+func (p *decryptionPool) submit(rp receivedPacket) bool {
+	job := decryptionJobPool.Get().(*decryptionJob)
+	job.p = rp
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		job.p = receivedPacket{}
+		decryptionJobPool.Put(job)
+		return false
+	}
+	select {
+	case p.ordered <- job:
+	default:
+		job.p = receivedPacket{}
+		decryptionJobPool.Put(job)
+		return false
+	}
+	if len(rp.data) > 0 && !wire.IsLongHeaderPacket(rp.data[0]) {
+		p.jobs <- job
+	} else {
+		job.done <- struct{}{}
+	}
+	return true
+}
+
+func (p *decryptionPool) runWorker(w handshake.ShortHeaderOpenerWorker) {
+	for job := range p.jobs {
+		if connIDLen, err := p.connIDLen(job.p.data); err == nil {
+			job.p.opened = openShortHeaderPacket(w, job.p.data, connIDLen)
+		}
+		job.done <- struct{}{}
+	}
+}
+
+func (p *decryptionPool) runDelivery() {
+	for job := range p.ordered {
+		<-job.done
+		rp := job.p
+		job.p = receivedPacket{}
+		decryptionJobPool.Put(job)
+		p.deliver(rp)
+	}
+}
+
+// close stops the workers. Packets that were already submitted are still delivered.
+func (p *decryptionPool) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.jobs)
+	close(p.ordered)
+}
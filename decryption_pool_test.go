@@ -0,0 +1,223 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The fakeOpenerWorker "protects" the packet number by flipping all bits,
+// and "encrypts" the payload by appending a 16 byte tag of zeros.
+type fakeOpenerWorker struct{}
+
+var _ handshake.ShortHeaderOpenerWorker = &fakeOpenerWorker{}
+
+func (w *fakeOpenerWorker) DecryptHeader(_ []byte, _ *byte, pnBytes []byte) {
+	for i := range pnBytes {
+		pnBytes[i] ^= 0xff
+	}
+}
+
+func (w *fakeOpenerWorker) DecodePacketNumber(wirePN protocol.PacketNumber, _ protocol.PacketNumberLen) protocol.PacketNumber {
+	return wirePN
+}
+
+func (w *fakeOpenerWorker) Open(dst, src []byte, _ protocol.PacketNumber, kp protocol.KeyPhaseBit, _ []byte) ([]byte, protocol.KeyPhase, error) {
+	if kp != protocol.KeyPhaseZero {
+		return nil, 0, handshake.ErrKeyPhaseMismatch
+	}
+	// make sure that packets are processed by the workers out of order
+	time.Sleep(time.Duration(rand.Intn(100)) * time.Microsecond)
+	if src[len(src)-1] != 0 {
+		return nil, 0, handshake.ErrDecryptionFailed
+	}
+	return append(dst, src[:len(src)-16]...), 0, nil
+}
+
+type fakeConcurrentOpener struct{}
+
+func (o *fakeConcurrentOpener) NewWorker() handshake.ShortHeaderOpenerWorker {
+	return &fakeOpenerWorker{}
+}
+func (o *fakeConcurrentOpener) FinishOpen(time.Time, protocol.PacketNumber, protocol.KeyPhase, error) error {
+	return nil
+}
+
+var _ = Describe("Decryption Pool", func() {
+	connID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+
+	getPacket := func(pn protocol.PacketNumber, pnLen protocol.PacketNumberLen, kp protocol.KeyPhaseBit, payload []byte) []byte {
+		b, err := wire.AppendShortHeader(nil, connID, pn, pnLen, kp)
+		Expect(err).ToNot(HaveOccurred())
+		hdrLen := 1 + connID.Len()
+		b = append(b, payload...)
+		b = append(b, make([]byte, 16)...)
+		for i := hdrLen; i < hdrLen+int(pnLen); i++ {
+			b[i] ^= 0xff
+		}
+		return b
+	}
+
+	It("decrypts short header packets and delivers all packets in order", func() {
+		delivered := make(chan receivedPacket, 1000)
+		pool := newDecryptionPool(&fakeConcurrentOpener{}, 4, fixedConnIDLen(connID.Len()), func(p receivedPacket) { delivered <- p })
+		defer pool.close()
+
+		const num = protocol.MaxConnUnprocessedPackets
+		for i := 0; i < num; i++ {
+			var data []byte
+			if i%10 == 0 {
+				data = []byte{0xc0, byte(i >> 8), byte(i)} // a long header packet
+			} else {
+				data = getPacket(protocol.PacketNumber(i), protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte{byte(i >> 8), byte(i), 1, 2, 3, 4})
+			}
+			Expect(pool.submit(receivedPacket{data: data})).To(BeTrue())
+		}
+		for i := 0; i < num; i++ {
+			var p receivedPacket
+			Eventually(delivered).Should(Receive(&p))
+			if i%10 == 0 {
+				Expect(p.opened).To(BeNil())
+				Expect(p.data).To(Equal([]byte{0xc0, byte(i >> 8), byte(i)}))
+				continue
+			}
+			Expect(p.opened).ToNot(BeNil())
+			Expect(p.opened.openErr).ToNot(HaveOccurred())
+			Expect(p.opened.pn).To(Equal(protocol.PacketNumber(i)))
+			Expect(p.opened.pnLen).To(Equal(protocol.PacketNumberLen2))
+			Expect(p.opened.data).To(Equal([]byte{byte(i >> 8), byte(i), 1, 2, 3, 4}))
+		}
+	})
+
+	It("leaves packets for the connection to unpack", func() {
+		delivered := make(chan receivedPacket, 10)
+		pool := newDecryptionPool(&fakeConcurrentOpener{}, 2, fixedConnIDLen(connID.Len()), func(p receivedPacket) { delivered <- p })
+		defer pool.close()
+
+		packets := [][]byte{
+			// different key phase
+			getPacket(1, protocol.PacketNumberLen2, protocol.KeyPhaseOne, []byte("foobar")),
+			// the worker might not be able to decode 1 byte packet numbers
+			getPacket(2, protocol.PacketNumberLen1, protocol.KeyPhaseZero, []byte("foobar")),
+			// too short
+			{0x40, 0xde, 0xad, 0xbe, 0xef, 1, 2, 3},
+		}
+		for _, data := range packets {
+			Expect(pool.submit(receivedPacket{data: append([]byte{}, data...)})).To(BeTrue())
+		}
+		for _, data := range packets {
+			var p receivedPacket
+			Eventually(delivered).Should(Receive(&p))
+			Expect(p.opened).To(BeNil())
+			Expect(p.data).To(Equal(data))
+		}
+	})
+
+	It("passes on decryption errors", func() {
+		delivered := make(chan receivedPacket, 10)
+		pool := newDecryptionPool(&fakeConcurrentOpener{}, 2, fixedConnIDLen(connID.Len()), func(p receivedPacket) { delivered <- p })
+		defer pool.close()
+
+		data := getPacket(1, protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte("foobar"))
+		data[len(data)-1] ^= 0x42 // invalidate the "tag"
+		Expect(pool.submit(receivedPacket{data: data})).To(BeTrue())
+		var p receivedPacket
+		Eventually(delivered).Should(Receive(&p))
+		Expect(p.opened).ToNot(BeNil())
+		Expect(p.opened.openErr).To(MatchError(handshake.ErrDecryptionFailed))
+	})
+
+	It("drops packets if too many packets are queued", func() {
+		unblock := make(chan struct{})
+		delivered := make(chan receivedPacket, 2*protocol.MaxConnUnprocessedPackets)
+		pool := newDecryptionPool(&fakeConcurrentOpener{}, 2, fixedConnIDLen(connID.Len()), func(p receivedPacket) {
+			<-unblock
+			delivered <- p
+		})
+		defer pool.close()
+
+		var numSubmitted int
+		for {
+			data := getPacket(protocol.PacketNumber(numSubmitted), protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte("foobar"))
+			if !pool.submit(receivedPacket{data: data}) {
+				break
+			}
+			numSubmitted++
+			Expect(numSubmitted).To(BeNumerically("<=", protocol.MaxConnUnprocessedPackets+1))
+		}
+		Expect(numSubmitted).To(BeNumerically(">=", protocol.MaxConnUnprocessedPackets))
+		close(unblock)
+		for i := 0; i < numSubmitted; i++ {
+			var p receivedPacket
+			Eventually(delivered).Should(Receive(&p))
+			Expect(p.opened.pn).To(Equal(protocol.PacketNumber(i)))
+		}
+	})
+
+	It("doesn't accept packets after it was closed", func() {
+		pool := newDecryptionPool(&fakeConcurrentOpener{}, 2, fixedConnIDLen(connID.Len()), func(receivedPacket) {})
+		pool.close()
+		pool.close() // it's ok to call close multiple times
+		Expect(pool.submit(receivedPacket{data: getPacket(1, protocol.PacketNumberLen2, protocol.KeyPhaseZero, []byte("foobar"))})).To(BeFalse())
+	})
+
+	It("transfers data when decrypting on multiple goroutines", func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"decryption-pool"}
+		ln, err := ListenAddr("127.0.0.1:0", tlsConf, &Config{DecryptionWorkers: 4})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		// echo all data sent on the first stream
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			str, err := conn.AcceptStream(context.Background())
+			if err != nil {
+				return
+			}
+			io.Copy(str, str)
+			str.Close()
+		}()
+
+		conn, err := DialAddr(
+			context.Background(),
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"decryption-pool"}},
+			&Config{DecryptionWorkers: 4},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(conn.LocalAddr()).To(BeAssignableToTypeOf(&net.UDPAddr{}))
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		str.SetDeadline(time.Now().Add(10 * time.Second))
+
+		// This is enough data to trigger multiple key updates.
+		data := make([]byte, 2<<20)
+		rand.Read(data)
+		go func() {
+			defer GinkgoRecover()
+			_, err := str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+		received, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+	})
+})
@@ -0,0 +1,184 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// A DialSource selects the local address and the network interface used by a connection.
+// On multi-homed hosts, this allows choosing a different route for every connection,
+// for example sending some connections via a VPN and others directly via the WAN.
+type DialSource struct {
+	// IP is the source IP address of the packets sent on the connection.
+	// It needs to use the same IP version as the remote address.
+	IP net.IP
+	// Interface is the name of the network interface that packets are sent on.
+	Interface string
+	// BindToDevice binds the socket to the Interface,
+	// using SO_BINDTODEVICE on Linux and IP_BOUND_IF / IPV6_BOUND_IF on macOS.
+	// This also restricts the packets received to packets arriving on that interface.
+	// Since it applies to all packets sent and received on the socket,
+	// it is only supported by DialAddrFrom, which creates a new socket for the connection.
+	BindToDevice bool
+}
+
+// DialAddrFrom establishes a new QUIC connection to a server,
+// using the local address and network interface selected by the DialSource.
+// It creates a new UDP socket bound to the local address,
+// and to the network interface, if BindToDevice is set.
+// When the QUIC connection is closed, this UDP socket is closed.
+// See DialAddr for more details.
+func DialAddrFrom(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, src *DialSource) (Connection, error) {
+	return dialAddrFrom(ctx, addr, tlsConf, conf, src, false)
+}
+
+// DialAddrEarlyFrom establishes a new 0-RTT QUIC connection to a server,
+// using the local address and network interface selected by the DialSource.
+// See DialAddrFrom for more details.
+func DialAddrEarlyFrom(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, src *DialSource) (EarlyConnection, error) {
+	return dialAddrFrom(ctx, addr, tlsConf, conf, src, true)
+}
+
+func dialAddrFrom(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, src *DialSource, use0RTT bool) (EarlyConnection, error) {
+	if src == nil {
+		src = &DialSource{}
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := listenUDPFrom(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := setupTransport(udpConn, tlsConf, true)
+	if err != nil {
+		udpConn.Close()
+		return nil, err
+	}
+	if err := tr.init(true); err != nil {
+		return nil, err
+	}
+	// The socket is already bound to the local address.
+	// Unless it's bound to the network interface, the interface is set on every packet.
+	var info packetInfo
+	if !src.BindToDevice {
+		info, err = (&DialSource{Interface: src.Interface}).packetInfo(tr.conn, udpAddr)
+		if err != nil {
+			tr.Close()
+			return nil, err
+		}
+	}
+	conn, err := tr.dial(ctx, udpAddr, addr, tlsConf, conf, use0RTT, protocol.ConnectionID{}, info)
+	if err != nil {
+		tr.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// listenUDPFrom creates a UDP socket bound to the local address (and optionally the network interface) of the DialSource.
+func listenUDPFrom(ctx context.Context, src *DialSource) (*net.UDPConn, error) {
+	laddr := &net.UDPAddr{IP: net.IPv4zero}
+	if src.IP != nil {
+		laddr.IP = src.IP
+	}
+	var lc net.ListenConfig
+	if src.BindToDevice {
+		if src.Interface == "" {
+			return nil, errors.New("quic: BindToDevice requires an Interface")
+		}
+		iface, err := net.InterfaceByName(src.Interface)
+		if err != nil {
+			return nil, err
+		}
+		lc.Control = func(network, _ string, c syscall.RawConn) error {
+			return bindToDevice(c, network, iface)
+		}
+	}
+	conn, err := lc.ListenPacket(ctx, "udp", laddr.String())
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*net.UDPConn), nil
+}
+
+// DialFrom dials a new connection, using the local address and network interface selected by the DialSource.
+// This allows connections sharing a single Transport to take different routes.
+// The source address and the interface are set on every packet sent (using IP_PKTINFO / IPV6_PKTINFO),
+// which requires the Transport to use a *net.UDPConn, and is supported on Linux and macOS.
+// If the socket is bound to a specific IP address, only that address can be used as the source address.
+// BindToDevice is not supported, since it would affect all connections using the Transport.
+func (t *Transport) DialFrom(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config, src *DialSource) (Connection, error) {
+	return t.dialFrom(ctx, addr, tlsConf, conf, src, false)
+}
+
+// DialEarlyFrom dials a new connection, attempting to use 0-RTT if possible,
+// using the local address and network interface selected by the DialSource.
+// See DialFrom for more details.
+func (t *Transport) DialEarlyFrom(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config, src *DialSource) (EarlyConnection, error) {
+	return t.dialFrom(ctx, addr, tlsConf, conf, src, true)
+}
+
+func (t *Transport) dialFrom(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config, src *DialSource, use0RTT bool) (EarlyConnection, error) {
+	if src != nil && src.BindToDevice {
+		return nil, errors.New("quic: BindToDevice requires a dedicated socket, use DialAddrFrom")
+	}
+	if err := t.init(t.isSingleUse); err != nil {
+		return nil, err
+	}
+	info, err := src.packetInfo(t.conn, addr)
+	if err != nil {
+		return nil, err
+	}
+	return t.dial(ctx, addr, "", tlsConf, conf, use0RTT, protocol.ConnectionID{}, info)
+}
+
+// packetInfo returns the packet info used when sending packets from the socket c to the remote address.
+func (s *DialSource) packetInfo(c rawConn, remote net.Addr) (packetInfo, error) {
+	if s == nil || (s.IP == nil && s.Interface == "") {
+		return packetInfo{}, nil
+	}
+	if !c.capabilities().PacketInfo {
+		return packetInfo{}, errors.New("quic: selecting the local address or interface is not supported on this connection")
+	}
+	remoteAddr, ok := remote.(*net.UDPAddr)
+	if !ok {
+		return packetInfo{}, fmt.Errorf("quic: remote address is not a UDP address: %s", remote)
+	}
+	isIPv4 := remoteAddr.IP.To4() != nil
+	addr := netip.IPv6Unspecified()
+	if isIPv4 {
+		addr = netip.IPv4Unspecified()
+	}
+	if s.IP != nil {
+		ip, ok := netip.AddrFromSlice(s.IP)
+		if !ok {
+			return packetInfo{}, fmt.Errorf("quic: invalid local IP address: %s", s.IP)
+		}
+		ip = ip.Unmap()
+		if ip.Is4() != isIPv4 {
+			return packetInfo{}, fmt.Errorf("quic: local address %s and remote address %s use different IP versions", s.IP, remoteAddr.IP)
+		}
+		if localAddr, ok := c.LocalAddr().(*net.UDPAddr); ok && !localAddr.IP.IsUnspecified() && !localAddr.IP.Equal(s.IP) {
+			return packetInfo{}, fmt.Errorf("quic: cannot use local address %s on a socket bound to %s", s.IP, localAddr.IP)
+		}
+		addr = ip
+	}
+	var ifIndex uint32
+	if s.Interface != "" {
+		iface, err := net.InterfaceByName(s.Interface)
+		if err != nil {
+			return packetInfo{}, err
+		}
+		ifIndex = uint32(iface.Index)
+	}
+	return newPacketInfo(addr, ifIndex), nil
+}
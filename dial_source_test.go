@@ -0,0 +1,79 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/netip"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dial Source", func() {
+	remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 200), Port: 1337}
+
+	newRawConn := func(localAddr *net.UDPAddr, supportsPacketInfo bool) rawConn {
+		c := NewMockRawConn(mockCtrl)
+		c.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+		c.EXPECT().capabilities().Return(connCapabilities{PacketInfo: supportsPacketInfo}).AnyTimes()
+		return c
+	}
+
+	It("doesn't set packet info if no source is selected", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv4zero, Port: 1234}, false)
+		var src *DialSource
+		Expect(src.packetInfo(c, remoteAddr)).To(Equal(packetInfo{}))
+		Expect((&DialSource{}).packetInfo(c, remoteAddr)).To(Equal(packetInfo{}))
+	})
+
+	It("sets the source address", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv4zero, Port: 1234}, true)
+		info, err := (&DialSource{IP: net.IPv4(10, 0, 0, 1)}).packetInfo(c, remoteAddr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.addr).To(Equal(netip.AddrFrom4([4]byte{10, 0, 0, 1})))
+	})
+
+	It("uses an unspecified address if only the interface is selected", func() {
+		ifaces, err := net.Interfaces()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ifaces).ToNot(BeEmpty())
+		c := newRawConn(&net.UDPAddr{IP: net.IPv6unspecified, Port: 1234}, true)
+		info, err := (&DialSource{Interface: ifaces[0].Name}).packetInfo(c, remoteAddr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.addr).To(Equal(netip.IPv4Unspecified()))
+		info, err = (&DialSource{Interface: ifaces[0].Name}).packetInfo(c, &net.UDPAddr{IP: net.IPv6loopback, Port: 1337})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.addr).To(Equal(netip.IPv6Unspecified()))
+	})
+
+	It("errors if the connection doesn't support setting packet info", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv4zero, Port: 1234}, false)
+		_, err := (&DialSource{IP: net.IPv4(10, 0, 0, 1)}).packetInfo(c, remoteAddr)
+		Expect(err).To(MatchError("quic: selecting the local address or interface is not supported on this connection"))
+	})
+
+	It("errors if the IP versions don't match", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv6unspecified, Port: 1234}, true)
+		_, err := (&DialSource{IP: net.IPv6loopback}).packetInfo(c, remoteAddr)
+		Expect(err).To(MatchError("quic: local address ::1 and remote address 192.168.100.200 use different IP versions"))
+	})
+
+	It("errors if the socket is bound to a different address", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1234}, true)
+		_, err := (&DialSource{IP: net.IPv4(10, 0, 0, 1)}).packetInfo(c, remoteAddr)
+		Expect(err).To(MatchError("quic: cannot use local address 10.0.0.1 on a socket bound to 10.0.0.2"))
+	})
+
+	It("errors if the interface doesn't exist", func() {
+		c := newRawConn(&net.UDPAddr{IP: net.IPv4zero, Port: 1234}, true)
+		_, err := (&DialSource{Interface: "does-not-exist"}).packetInfo(c, remoteAddr)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("refuses to bind a shared Transport to a device", func() {
+		tr := &Transport{}
+		_, err := tr.DialFrom(context.Background(), remoteAddr, &tls.Config{}, nil, &DialSource{Interface: "lo", BindToDevice: true})
+		Expect(err).To(MatchError("quic: BindToDevice requires a dedicated socket, use DialAddrFrom"))
+	})
+})
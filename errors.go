@@ -13,12 +13,15 @@ type (
 	StatelessResetError     = qerr.StatelessResetError
 	IdleTimeoutError        = qerr.IdleTimeoutError
 	HandshakeTimeoutError   = qerr.HandshakeTimeoutError
+	HandshakeError          = qerr.HandshakeError
 )
 
 type (
 	TransportErrorCode   = qerr.TransportErrorCode
 	ApplicationErrorCode = qerr.ApplicationErrorCode
 	StreamErrorCode      = qerr.StreamErrorCode
+
+	HandshakeFailureReason = qerr.HandshakeFailureReason
 )
 
 const (
@@ -41,6 +44,15 @@ const (
 	NoViablePathError         = qerr.NoViablePathError
 )
 
+const (
+	HandshakeFailureOther                = qerr.HandshakeFailureOther
+	HandshakeFailureNoCompatibleVersion  = qerr.HandshakeFailureNoCompatibleVersion
+	HandshakeFailureALPNMismatch         = qerr.HandshakeFailureALPNMismatch
+	HandshakeFailureCertificate          = qerr.HandshakeFailureCertificate
+	HandshakeFailureTimeout              = qerr.HandshakeFailureTimeout
+	HandshakeFailureAmplificationLimited = qerr.HandshakeFailureAmplificationLimited
+)
+
 // A StreamError is used for Stream.CancelRead and Stream.CancelWrite.
 // It is also returned from Stream.Read and Stream.Write if the peer canceled reading or writing.
 type StreamError struct {
@@ -49,6 +61,8 @@ type StreamError struct {
 	Remote    bool
 }
 
+// Is reports if the error matches target.
+// A StreamError matches every other StreamError, regardless of the stream ID and the error code.
 func (e *StreamError) Is(target error) bool {
 	_, ok := target.(*StreamError)
 	return ok
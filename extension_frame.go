@@ -0,0 +1,90 @@
+package quic
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// An ExtensionFrame defines a frame type of a QUIC extension, see Config.ExtensionFrames.
+//
+// Support for the frame type is negotiated using a transport parameter:
+// Frames are only sent after both endpoints sent this transport parameter during the handshake.
+// Extension frames are only sent and accepted in 1-RTT packets.
+type ExtensionFrame struct {
+	// Type is the frame type.
+	// It must not be a frame type defined by QUIC, or by an extension implemented by quic-go.
+	Type uint64
+	// TransportParameter is the ID of the transport parameter used to negotiate support for this frame type.
+	// The transport parameter is sent with an empty value.
+	// Multiple frame types can share the same transport parameter.
+	TransportParameter uint64
+	// AckEliciting says if the frame is ack-eliciting (see section 2 of RFC 9002).
+	// Packets that only contain frames which are not ack-eliciting are not acknowledged right away
+	// and don't count towards the congestion window.
+	AckEliciting bool
+	// RetransmitOnLoss says if the frame is sent again when the packet containing it is declared lost.
+	// Only ack-eliciting frames can be retransmitted.
+	RetransmitOnLoss bool
+	// Encode appends the payload of the frame (i.e. everything following the frame type) to b.
+	// It is called with the frame passed to Connection.SendExtensionFrame.
+	Encode func(b []byte, frame any) ([]byte, error)
+	// Decode parses the payload of a frame from the beginning of b.
+	// It returns the decoded frame and the number of bytes consumed.
+	// Since the frame isn't length-prefixed, the encoding needs to allow Decode to determine where the frame ends.
+	Decode func(b []byte) (frame any, n int, err error)
+	// Handle is called for every received frame, with the frame returned by Decode.
+	// It is called from the connection's run loop, and must not block.
+	// If it returns an error, the connection is closed with a PROTOCOL_VIOLATION.
+	// If nil, received frames are dropped.
+	Handle func(conn Connection, frame any) error
+}
+
+func validateExtensionFrames(frames []ExtensionFrame) error {
+	types := make(map[uint64]struct{}, len(frames))
+	for _, f := range frames {
+		if wire.IsKnownFrameType(f.Type) {
+			return fmt.Errorf("invalid extension frame type %#x: frame type is already defined", f.Type)
+		}
+		if _, ok := types[f.Type]; ok {
+			return fmt.Errorf("duplicate extension frame type %#x", f.Type)
+		}
+		types[f.Type] = struct{}{}
+		if wire.IsKnownTransportParameter(f.TransportParameter) || f.TransportParameter%31 == 27 {
+			return fmt.Errorf("invalid transport parameter %#x for extension frame type %#x", f.TransportParameter, f.Type)
+		}
+		if f.Encode == nil || f.Decode == nil {
+			return fmt.Errorf("extension frame type %#x requires Encode and Decode callbacks", f.Type)
+		}
+		if f.RetransmitOnLoss && !f.AckEliciting {
+			return fmt.Errorf("extension frame type %#x: only ack-eliciting frames can be retransmitted", f.Type)
+		}
+	}
+	return nil
+}
+
+// extensionTransportParameters returns the transport parameters to be sent for the extension frames.
+func extensionTransportParameters(frames []ExtensionFrame) map[uint64][]byte {
+	if len(frames) == 0 {
+		return nil
+	}
+	params := make(map[uint64][]byte, len(frames))
+	for _, f := range frames {
+		params[f.TransportParameter] = []byte{}
+	}
+	return params
+}
+
+// supportedExtensionFrames returns the extension frame types that the peer supports,
+// based on the transport parameters it sent.
+func supportedExtensionFrames(frames map[uint64]*ExtensionFrame, peerParams *wire.TransportParameters) []uint64 {
+	var types []uint64
+	for typ, f := range frames {
+		if _, ok := peerParams.CustomParameters[f.TransportParameter]; ok {
+			types = append(types, typ)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
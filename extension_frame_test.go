@@ -0,0 +1,72 @@
+package quic
+
+import (
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Extension frames", func() {
+	encode := func(b []byte, _ any) ([]byte, error) { return b, nil }
+	decode := func([]byte) (any, int, error) { return nil, 0, nil }
+
+	It("accepts valid extension frames", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 0x1337, Encode: encode, Decode: decode},
+			{Type: 0x43, TransportParameter: 0x1337, AckEliciting: true, RetransmitOnLoss: true, Encode: encode, Decode: decode},
+		})).To(Succeed())
+	})
+
+	It("rejects frame types defined by QUIC", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x1e, TransportParameter: 0x1337, Encode: encode, Decode: decode},
+		})).To(MatchError("invalid extension frame type 0x1e: frame type is already defined"))
+	})
+
+	It("rejects duplicate frame types", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 0x1337, Encode: encode, Decode: decode},
+			{Type: 0x42, TransportParameter: 0x1338, Encode: encode, Decode: decode},
+		})).To(MatchError("duplicate extension frame type 0x42"))
+	})
+
+	It("rejects transport parameters defined by QUIC", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 0x4, Encode: encode, Decode: decode},
+		})).To(MatchError("invalid transport parameter 0x4 for extension frame type 0x42"))
+	})
+
+	It("rejects reserved transport parameters", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 27 + 31*2, Encode: encode, Decode: decode},
+		})).To(MatchError("invalid transport parameter 0x59 for extension frame type 0x42"))
+	})
+
+	It("rejects retransmissions of frames that are not ack-eliciting", func() {
+		Expect(validateExtensionFrames([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 0x1337, RetransmitOnLoss: true, Encode: encode, Decode: decode},
+		})).To(MatchError("extension frame type 0x42: only ack-eliciting frames can be retransmitted"))
+	})
+
+	It("determines the transport parameters", func() {
+		Expect(extensionTransportParameters(nil)).To(BeNil())
+		Expect(extensionTransportParameters([]ExtensionFrame{
+			{Type: 0x42, TransportParameter: 0x1337},
+			{Type: 0x43, TransportParameter: 0x1337},
+			{Type: 0x44, TransportParameter: 0x1338},
+		})).To(Equal(map[uint64][]byte{0x1337: {}, 0x1338: {}}))
+	})
+
+	It("determines the frame types supported by the peer", func() {
+		frames := map[uint64]*ExtensionFrame{
+			0x44: {Type: 0x44, TransportParameter: 0x1338},
+			0x42: {Type: 0x42, TransportParameter: 0x1337},
+			0x43: {Type: 0x43, TransportParameter: 0x1337},
+		}
+		Expect(supportedExtensionFrames(frames, &wire.TransportParameters{})).To(BeEmpty())
+		Expect(supportedExtensionFrames(frames, &wire.TransportParameters{
+			CustomParameters: map[uint64][]byte{0x1337: {}},
+		})).To(Equal([]uint64{0x42, 0x43}))
+	})
+})
@@ -0,0 +1,215 @@
+// Package fatelog implements a connection tracer that records the fate of every packet sent on a QUIC connection:
+// when it was sent, how large it was, whether it was sent after a PTO expired, and whether it was acknowledged,
+// declared lost, or discarded when its packet number space was dropped.
+//
+// The log is intended for offline analysis, e.g. for congestion control research.
+// Compared to qlog, it only records the events needed to reconstruct the fate of packets,
+// and uses a compact binary encoding. Frames are never constructed.
+//
+// The log starts with a header:
+//
+//	Magic (32): "qfl" followed by the format version (1)
+//	Perspective (8): the logging.Perspective
+//	Connection ID Length (8)
+//	Connection ID (0..160): the original destination connection ID
+//	Start Time (64): the time when the log was started, in nanoseconds since the Unix epoch
+//
+// It is followed by the records:
+//
+//	Type (6): the EventType
+//	Packet Number Space (2): the Space
+//	Time Delta (i): the time since the previous record (or the start of the log), in microseconds
+//	Packet Number (i)
+//	Size (i): only present for EventSent
+//	PTO Count (i): only present for EventSent
+//	Loss Reason (8): only present for EventLost
+//
+// Records of type EventDiscarded don't have a packet number.
+// All variable-length integers (i) use the QUIC variable-length integer encoding.
+package fatelog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+const version = 1
+
+var magic = [4]byte{'q', 'f', 'l', version}
+
+// An EventType is the type of a record.
+type EventType uint8
+
+const (
+	// EventSent is recorded when a packet is sent.
+	EventSent EventType = iota
+	// EventAcked is recorded when a packet is acknowledged.
+	EventAcked
+	// EventLost is recorded when a packet is declared lost.
+	EventLost
+	// EventDiscarded is recorded when a packet number space is dropped.
+	// All packets in this packet number space that were neither acknowledged nor declared lost are discarded.
+	EventDiscarded
+)
+
+// A Space is a packet number space.
+type Space uint8
+
+const (
+	// SpaceInitial is the Initial packet number space.
+	SpaceInitial Space = iota
+	// SpaceHandshake is the Handshake packet number space.
+	SpaceHandshake
+	// SpaceAppData is the application data packet number space, used by 0-RTT and 1-RTT packets.
+	SpaceAppData
+)
+
+func spaceFromEncryptionLevel(encLevel logging.EncryptionLevel) Space {
+	//nolint:exhaustive // 0-RTT and 1-RTT packets share the same packet number space.
+	switch encLevel {
+	case logging.EncryptionInitial:
+		return SpaceInitial
+	case logging.EncryptionHandshake:
+		return SpaceHandshake
+	default:
+		return SpaceAppData
+	}
+}
+
+type connectionTracer struct {
+	mutex sync.Mutex
+
+	w        io.WriteCloser
+	bw       *bufio.Writer
+	writeErr error
+	buf      []byte
+
+	lastEvent time.Time
+	ptoCount  uint32
+}
+
+// NewConnectionTracer creates a new tracer that writes the packet fate log of a connection to w.
+func NewConnectionTracer(w io.WriteCloser, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+	t := &connectionTracer{w: w, bw: bufio.NewWriter(w)}
+	t.writeHeader(p, odcid, time.Now())
+	return &logging.ConnectionTracer{
+		SentLongHeaderPacket: func(hdr *logging.ExtendedHeader, size logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, _ []logging.Frame) {
+			space := SpaceAppData
+			//nolint:exhaustive // Only Initial and Handshake packets use a different packet number space.
+			switch hdr.Type {
+			case protocol.PacketTypeInitial:
+				space = SpaceInitial
+			case protocol.PacketTypeHandshake:
+				space = SpaceHandshake
+			}
+			t.sentPacket(space, hdr.PacketNumber, size)
+		},
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, _ []logging.Frame) {
+			t.sentPacket(SpaceAppData, hdr.PacketNumber, size)
+		},
+		AcknowledgedPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber) {
+			t.writeRecord(Record{Type: EventAcked, Space: spaceFromEncryptionLevel(encLevel), PacketNumber: pn})
+		},
+		LostPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, reason logging.PacketLossReason) {
+			t.writeRecord(Record{Type: EventLost, Space: spaceFromEncryptionLevel(encLevel), PacketNumber: pn, LossReason: reason})
+		},
+		DroppedEncryptionLevel: func(encLevel logging.EncryptionLevel) {
+			// Dropping 0-RTT keys doesn't drop the application data packet number space.
+			if encLevel == logging.Encryption0RTT {
+				return
+			}
+			t.writeRecord(Record{Type: EventDiscarded, Space: spaceFromEncryptionLevel(encLevel)})
+		},
+		UpdatedPTOCount: func(value uint32) {
+			t.mutex.Lock()
+			t.ptoCount = value
+			t.mutex.Unlock()
+		},
+		Close:      func() { t.Close() },
+		OmitFrames: true,
+	}
+}
+
+func (t *connectionTracer) writeHeader(p logging.Perspective, odcid logging.ConnectionID, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	b := make([]byte, 0, 4+1+1+20+8)
+	b = append(b, magic[:]...)
+	b = append(b, uint8(p), uint8(odcid.Len()))
+	b = append(b, odcid.Bytes()...)
+	b = binary.BigEndian.AppendUint64(b, uint64(now.UnixNano()))
+	t.write(b)
+	t.lastEvent = now
+}
+
+func (t *connectionTracer) sentPacket(space Space, pn logging.PacketNumber, size logging.ByteCount) {
+	t.mutex.Lock()
+	ptoCount := t.ptoCount
+	t.mutex.Unlock()
+	t.writeRecord(Record{Type: EventSent, Space: space, PacketNumber: pn, Size: size, PTOCount: ptoCount})
+}
+
+func (t *connectionTracer) writeRecord(r Record) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writeErr != nil {
+		return
+	}
+	delta := time.Since(t.lastEvent).Microseconds()
+	if delta < 0 {
+		delta = 0
+	}
+	// Advance by the encoded delta (and not to the current time),
+	// so that the rounding errors don't accumulate when the reader adds up the deltas.
+	t.lastEvent = t.lastEvent.Add(time.Duration(delta) * time.Microsecond)
+
+	b := t.buf[:0]
+	b = append(b, uint8(r.Type)<<2|uint8(r.Space))
+	b = quicvarint.Append(b, uint64(delta))
+	if r.Type != EventDiscarded {
+		b = quicvarint.Append(b, uint64(r.PacketNumber))
+	}
+	switch r.Type {
+	case EventSent:
+		b = quicvarint.Append(b, uint64(r.Size))
+		b = quicvarint.Append(b, uint64(r.PTOCount))
+	case EventLost:
+		b = append(b, uint8(r.LossReason))
+	}
+	t.write(b)
+	t.buf = b
+}
+
+func (t *connectionTracer) write(b []byte) {
+	if t.writeErr != nil {
+		return
+	}
+	if _, err := t.bw.Write(b); err != nil {
+		t.writeErr = err
+	}
+}
+
+func (t *connectionTracer) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writeErr == nil {
+		t.writeErr = t.bw.Flush()
+	}
+	if t.writeErr != nil {
+		log.Printf("writing packet fate log failed: %s\n", t.writeErr)
+	}
+	if err := t.w.Close(); err != nil {
+		log.Printf("closing packet fate log writer failed: %s\n", err)
+	}
+}
@@ -0,0 +1,174 @@
+package fatelog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type nopWriteCloserImpl struct{ io.Writer }
+
+func (nopWriteCloserImpl) Close() error { return nil }
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) { return 0, errors.New("writer broken") }
+func (errorWriter) Close() error              { return nil }
+
+var _ = Describe("Packet fate log", func() {
+	var (
+		buf    *bytes.Buffer
+		tracer *logging.ConnectionTracer
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		tracer = NewConnectionTracer(
+			nopWriteCloserImpl{Writer: buf},
+			protocol.PerspectiveServer,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+	})
+
+	readAll := func() (Header, []Record) {
+		r, err := NewReader(bytes.NewReader(buf.Bytes()))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		var records []Record
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			records = append(records, rec)
+		}
+		return r.Header(), records
+	}
+
+	It("writes the header", func() {
+		tracer.Close()
+		hdr, records := readAll()
+		Expect(hdr.Perspective).To(Equal(protocol.PerspectiveServer))
+		Expect(hdr.ConnectionID).To(Equal(protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})))
+		Expect(hdr.StartTime).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+		Expect(records).To(BeEmpty())
+	})
+
+	It("doesn't construct frames", func() {
+		Expect(tracer.OmitFrames).To(BeTrue())
+	})
+
+	It("records sent, acknowledged and lost packets", func() {
+		tracer.SentLongHeaderPacket(
+			&logging.ExtendedHeader{Header: logging.Header{Type: protocol.PacketTypeInitial}, PacketNumber: 0},
+			1200, logging.ECNUnsupported, nil, nil,
+		)
+		tracer.SentLongHeaderPacket(
+			&logging.ExtendedHeader{Header: logging.Header{Type: protocol.PacketTypeHandshake}, PacketNumber: 1},
+			500, logging.ECNUnsupported, nil, nil,
+		)
+		tracer.SentLongHeaderPacket(
+			&logging.ExtendedHeader{Header: logging.Header{Type: protocol.PacketType0RTT}, PacketNumber: 2},
+			1000, logging.ECNUnsupported, nil, nil,
+		)
+		time.Sleep(scaleDuration(5 * time.Millisecond))
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 1337}, 1400, logging.ECT0, nil, nil)
+		tracer.AcknowledgedPacket(logging.EncryptionInitial, 0)
+		tracer.AcknowledgedPacket(logging.Encryption1RTT, 1337)
+		tracer.LostPacket(logging.EncryptionHandshake, 1, logging.PacketLossTimeThreshold)
+		tracer.LostPacket(logging.Encryption0RTT, 2, logging.PacketLossReorderingThreshold)
+		tracer.Close()
+
+		_, records := readAll()
+		Expect(records).To(HaveLen(8))
+		Expect(records[0]).To(Equal(Record{Type: EventSent, Time: records[0].Time, Space: SpaceInitial, PacketNumber: 0, Size: 1200}))
+		Expect(records[1]).To(Equal(Record{Type: EventSent, Time: records[1].Time, Space: SpaceHandshake, PacketNumber: 1, Size: 500}))
+		Expect(records[2]).To(Equal(Record{Type: EventSent, Time: records[2].Time, Space: SpaceAppData, PacketNumber: 2, Size: 1000}))
+		Expect(records[3]).To(Equal(Record{Type: EventSent, Time: records[3].Time, Space: SpaceAppData, PacketNumber: 1337, Size: 1400}))
+		Expect(records[3].Time - records[2].Time).To(BeNumerically(">=", scaleDuration(5*time.Millisecond)))
+		Expect(records[4]).To(Equal(Record{Type: EventAcked, Time: records[4].Time, Space: SpaceInitial, PacketNumber: 0}))
+		Expect(records[5]).To(Equal(Record{Type: EventAcked, Time: records[5].Time, Space: SpaceAppData, PacketNumber: 1337}))
+		Expect(records[6]).To(Equal(Record{Type: EventLost, Time: records[6].Time, Space: SpaceHandshake, PacketNumber: 1, LossReason: logging.PacketLossTimeThreshold}))
+		Expect(records[7]).To(Equal(Record{Type: EventLost, Time: records[7].Time, Space: SpaceAppData, PacketNumber: 2, LossReason: logging.PacketLossReorderingThreshold}))
+		for i := 1; i < len(records); i++ {
+			Expect(records[i].Time).To(BeNumerically(">=", records[i-1].Time))
+		}
+	})
+
+	It("records which PTO a packet was sent for", func() {
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 1}, 1000, logging.ECNUnsupported, nil, nil)
+		tracer.UpdatedPTOCount(1)
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 2}, 1000, logging.ECNUnsupported, nil, nil)
+		tracer.UpdatedPTOCount(2)
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 3}, 1000, logging.ECNUnsupported, nil, nil)
+		tracer.UpdatedPTOCount(0)
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 4}, 1000, logging.ECNUnsupported, nil, nil)
+		tracer.Close()
+
+		_, records := readAll()
+		Expect(records).To(HaveLen(4))
+		var ptoCounts []uint32
+		for _, r := range records {
+			ptoCounts = append(ptoCounts, r.PTOCount)
+		}
+		Expect(ptoCounts).To(Equal([]uint32{0, 1, 2, 0}))
+	})
+
+	It("records when packet number spaces are discarded", func() {
+		tracer.DroppedEncryptionLevel(logging.EncryptionInitial)
+		tracer.DroppedEncryptionLevel(logging.Encryption0RTT)
+		tracer.DroppedEncryptionLevel(logging.EncryptionHandshake)
+		tracer.Close()
+
+		_, records := readAll()
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Type).To(Equal(EventDiscarded))
+		Expect(records[0].Space).To(Equal(SpaceInitial))
+		Expect(records[1].Type).To(Equal(EventDiscarded))
+		Expect(records[1].Space).To(Equal(SpaceHandshake))
+	})
+
+	It("uses a compact encoding", func() {
+		const headerLen = 4 + 1 + 1 + 4 + 8
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 1000}, 1200, logging.ECNUnsupported, nil, nil)
+		tracer.AcknowledgedPacket(logging.Encryption1RTT, 1000)
+		tracer.Close()
+		// type / space (1), time delta (<= 4), packet number (2), size (2), PTO count (1)
+		// type / space (1), time delta (<= 4), packet number (2)
+		Expect(buf.Len() - headerLen).To(BeNumerically("<=", 10+7))
+	})
+
+	It("rejects data that isn't a packet fate log", func() {
+		_, err := NewReader(bytes.NewReader([]byte("foobar")))
+		Expect(err).To(MatchError("fatelog: not a packet fate log"))
+	})
+
+	It("errors on truncated records", func() {
+		tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 1000}, 1200, logging.ECNUnsupported, nil, nil)
+		tracer.Close()
+		r, err := NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = r.Next()
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+	})
+
+	It("logs a write error when closing", func() {
+		logBuf := &bytes.Buffer{}
+		log.SetOutput(logBuf)
+		defer log.SetOutput(os.Stdout)
+
+		t := NewConnectionTracer(errorWriter{}, protocol.PerspectiveClient, protocol.ConnectionID{})
+		t.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 1}, 1200, logging.ECNUnsupported, nil, nil)
+		t.Close()
+		Expect(logBuf.String()).To(ContainSubstring("writing packet fate log failed: writer broken"))
+	})
+})
@@ -0,0 +1,138 @@
+package fatelog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// The Header contains the information stored at the beginning of a packet fate log.
+type Header struct {
+	Perspective  logging.Perspective
+	ConnectionID logging.ConnectionID
+	StartTime    time.Time
+}
+
+// A Record is a single event of a packet fate log.
+type Record struct {
+	Type EventType
+	// Time is the time of the event, relative to the StartTime of the log.
+	Time         time.Duration
+	Space        Space
+	PacketNumber logging.PacketNumber
+	// Size is the size of the packet. Only set for EventSent.
+	Size logging.ByteCount
+	// PTOCount is the number of consecutive PTOs that had expired when the packet was sent.
+	// It is 0 for packets that were not sent as a result of a PTO. Only set for EventSent.
+	PTOCount uint32
+	// LossReason is the reason why the packet was declared lost. Only set for EventLost.
+	LossReason logging.PacketLossReason
+}
+
+// A Reader reads a packet fate log.
+type Reader struct {
+	r      *bufio.Reader
+	header Header
+	time   time.Duration
+}
+
+// NewReader creates a new Reader, and reads the header of the log.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	var m [4]byte
+	if _, err := io.ReadFull(br, m[:]); err != nil {
+		return nil, err
+	}
+	if m[0] != magic[0] || m[1] != magic[1] || m[2] != magic[2] {
+		return nil, errors.New("fatelog: not a packet fate log")
+	}
+	if m[3] != version {
+		return nil, fmt.Errorf("fatelog: unsupported version %d", m[3])
+	}
+	var b [2]byte
+	if _, err := io.ReadFull(br, b[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	connID, err := protocol.ReadConnectionID(br, int(b[1]))
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	var ts [8]byte
+	if _, err := io.ReadFull(br, ts[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return &Reader{
+		r: br,
+		header: Header{
+			Perspective:  logging.Perspective(b[0]),
+			ConnectionID: connID,
+			StartTime:    time.Unix(0, int64(binary.BigEndian.Uint64(ts[:]))),
+		},
+	}, nil
+}
+
+// Header returns the header of the log.
+func (r *Reader) Header() Header { return r.header }
+
+// Next reads the next record.
+// It returns io.EOF when the end of the log is reached.
+func (r *Reader) Next() (Record, error) {
+	first, err := r.r.ReadByte()
+	if err != nil {
+		return Record{}, err
+	}
+	rec := Record{Type: EventType(first >> 2), Space: Space(first & 0x3)}
+	if rec.Type > EventDiscarded {
+		return Record{}, fmt.Errorf("fatelog: unknown event type %d", rec.Type)
+	}
+	delta, err := quicvarint.Read(r.r)
+	if err != nil {
+		return Record{}, unexpectedEOF(err)
+	}
+	r.time += time.Duration(delta) * time.Microsecond
+	rec.Time = r.time
+	if rec.Type == EventDiscarded {
+		return rec, nil
+	}
+	pn, err := quicvarint.Read(r.r)
+	if err != nil {
+		return Record{}, unexpectedEOF(err)
+	}
+	rec.PacketNumber = logging.PacketNumber(pn)
+	switch rec.Type {
+	case EventSent:
+		size, err := quicvarint.Read(r.r)
+		if err != nil {
+			return Record{}, unexpectedEOF(err)
+		}
+		ptoCount, err := quicvarint.Read(r.r)
+		if err != nil {
+			return Record{}, unexpectedEOF(err)
+		}
+		rec.Size = logging.ByteCount(size)
+		rec.PTOCount = uint32(ptoCount)
+	case EventLost:
+		reason, err := r.r.ReadByte()
+		if err != nil {
+			return Record{}, unexpectedEOF(err)
+		}
+		rec.LossReason = logging.PacketLossReason(reason)
+	}
+	return rec, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF:
+// io.EOF is only returned if the log ends between two records.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
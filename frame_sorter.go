@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	list "github.com/quic-go/quic-go/internal/utils/linkedlist"
 )
 
@@ -30,20 +31,25 @@ type frameSorter struct {
 	queue   map[protocol.ByteCount]frameSorterEntry
 	readPos protocol.ByteCount
 	gaps    *list.List[byteInterval] // 双链表
+	// bufferedBytes is the number of bytes held in the queue.
+	// It is accounted against the global memory budget.
+	bufferedBytes int
 }
 
 var errDuplicateStreamData = errors.New("duplicate stream data")
 
-// 初始化frameSorter
+// newFrameSorter creates a new frameSorter.
+// The zero value is ready to use as well: the gaps and the queue are only allocated once data is pushed.
 func newFrameSorter() *frameSorter {
-	s := frameSorter{
-		// 建立一个双链表，返回的是一个根节点。
-		gaps:  list.NewWithPool[byteInterval](&byteIntervalElementPool),
-		queue: make(map[protocol.ByteCount]frameSorterEntry),
-	}
-	// 插入头结点
-	s.gaps.PushFront(byteInterval{Start: 0, End: protocol.MaxByteCount})
-	return &s
+	return &frameSorter{}
+}
+
+// init allocates the gaps and the queue.
+// All data before the read position has already been consumed.
+func (s *frameSorter) init() {
+	s.gaps = list.NewWithPool[byteInterval](&byteIntervalElementPool)
+	s.gaps.PushFront(byteInterval{Start: s.readPos, End: protocol.MaxByteCount})
+	s.queue = make(map[protocol.ByteCount]frameSorterEntry)
 }
 
 func (s *frameSorter) Push(data []byte, offset protocol.ByteCount, doneCb func()) error {
@@ -62,6 +68,9 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 	if len(data) == 0 {
 		return errDuplicateStreamData
 	}
+	if s.gaps == nil {
+		s.init()
+	}
 
 	// 计算要存入数据的开始和结束的位置
 	start := offset
@@ -103,7 +112,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
-			delete(s.queue, pos)
+			s.removeEntry(pos, oldEntry)
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -185,9 +194,17 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 	}
 
 	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.bufferedBytes += len(data)
+	utils.GlobalMemoryBudget.Acquire(len(data))
 	return nil
 }
 
+func (s *frameSorter) removeEntry(pos protocol.ByteCount, entry frameSorterEntry) {
+	delete(s.queue, pos)
+	s.bufferedBytes -= len(entry.Data)
+	utils.GlobalMemoryBudget.Release(len(entry.Data))
+}
+
 func (s *frameSorter) findStartGap(offset protocol.ByteCount) (*list.Element[byteInterval], bool) {
 	// 遍历链表
 	for gap := s.gaps.Front(); gap != nil; gap = gap.Next() {
@@ -227,7 +244,7 @@ func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 			break
 		}
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
-		delete(s.queue, pos)
+		s.removeEntry(pos, oldEntry)
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
@@ -240,7 +257,7 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	if !ok {
 		return s.readPos, nil, nil
 	}
-	delete(s.queue, s.readPos)
+	s.removeEntry(s.readPos, entry)
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
 	if s.gaps.Front().Value.End <= s.readPos {
@@ -253,3 +270,21 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
 }
+
+// Discard drops all queued frames, and releases the memory held by the frameSorter.
+// It is used when the data won't be read anymore.
+func (s *frameSorter) Discard() {
+	for pos, entry := range s.queue {
+		s.removeEntry(pos, entry)
+		if entry.DoneCb != nil {
+			entry.DoneCb()
+		}
+	}
+	if s.gaps != nil {
+		for s.gaps.Len() > 0 {
+			s.gaps.Remove(s.gaps.Front())
+		}
+	}
+	s.gaps = nil
+	s.queue = nil
+}
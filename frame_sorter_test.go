@@ -9,6 +9,7 @@ import (
 	"golang.org/x/exp/rand"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -65,6 +66,29 @@ var _ = Describe("frame sorter", func() {
 		s = newFrameSorter()
 	})
 
+	It("only allocates memory once data is pushed", func() {
+		Expect(s.gaps).To(BeNil())
+		Expect(s.queue).To(BeNil())
+		Expect(s.Push([]byte("foobar"), 0, nil)).To(Succeed())
+		checkGaps([]byteInterval{{Start: 6, End: protocol.MaxByteCount}})
+	})
+
+	It("releases the memory when discarding", func() {
+		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+		Expect(s.Push([]byte("bar"), 6, nil)).To(Succeed())
+		_, data, _ := s.Pop()
+		Expect(data).To(Equal([]byte("foo")))
+		s.Discard()
+		Expect(s.gaps).To(BeNil())
+		Expect(s.queue).To(BeNil())
+		Expect(s.bufferedBytes).To(BeZero())
+		// data that was already read is detected as duplicate
+		cb, t := getCallback()
+		Expect(s.Push([]byte("foo"), 0, cb)).To(Succeed())
+		checkCallbackCalled(t)
+		Expect(s.HasMoreData()).To(BeFalse())
+	})
+
 	It("returns nil when empty", func() {
 		_, data, doneCb := s.Pop()
 		Expect(data).To(BeNil())
@@ -124,6 +148,33 @@ var _ = Describe("frame sorter", func() {
 		Expect(s.HasMoreData()).To(BeFalse())
 	})
 
+	It("tracks the number of buffered bytes", func() {
+		Expect(s.Push([]byte("foobar"), 0, nil)).To(Succeed())
+		Expect(s.Push([]byte("baz"), 10, nil)).To(Succeed())
+		Expect(s.bufferedBytes).To(Equal(9))
+		// this frame replaces the first one
+		Expect(s.Push([]byte("foobarfoo"), 0, nil)).To(Succeed())
+		Expect(s.bufferedBytes).To(Equal(12))
+		_, data, _ := s.Pop()
+		Expect(data).To(Equal([]byte("foobarfoo")))
+		Expect(s.bufferedBytes).To(Equal(3))
+	})
+
+	It("discards all frames", func() {
+		cb1, t1 := getCallback()
+		cb2, t2 := getCallback()
+		used := utils.GlobalMemoryBudget.Used()
+		Expect(s.Push([]byte("foo"), 0, cb1)).To(Succeed())
+		Expect(s.Push([]byte("bar"), 10, cb2)).To(Succeed())
+		Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+		s.Discard()
+		Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		Expect(s.bufferedBytes).To(BeZero())
+		Expect(s.HasMoreData()).To(BeFalse())
+		checkCallbackCalled(t1)
+		checkCallbackCalled(t2)
+	})
+
 	Context("Gap handling", func() {
 		var dataCounter uint8
 
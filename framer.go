@@ -15,10 +15,13 @@ type framer interface {
 	HasData() bool
 
 	QueueControlFrame(wire.Frame)
+	QueueControlFrameWithHandler(wire.Frame, ackhandler.FrameHandler)
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount, protocol.VersionNumber) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.StreamFrame, protocol.ByteCount, protocol.VersionNumber) ([]ackhandler.StreamFrame, protocol.ByteCount)
+	// OnCongestionLimited is called when sending is blocked by the congestion controller.
+	OnCongestionLimited()
 
 	Handle0RTTRejection() error
 }
@@ -27,20 +30,27 @@ type framerI struct {
 	mutex sync.Mutex
 
 	streamGetter streamGetter
+	rateLimiter  *tokenBucket // nil if no rate limit is applied
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   ringbuffer.RingBuffer[protocol.StreamID]
+	// congestionCandidates are the active streams that became active or sent data since the last call to OnCongestionLimited.
+	// All other active streams were already notified, and don't need to be notified again.
+	// This avoids iterating over all active streams every time sending is congestion limited.
+	congestionCandidates map[protocol.StreamID]struct{}
 
 	controlFrameMutex sync.Mutex
-	controlFrames     []wire.Frame
+	controlFrames     []ackhandler.Frame
 }
 
 var _ framer = &framerI{}
 
-func newFramer(streamGetter streamGetter) framer {
+func newFramer(streamGetter streamGetter, rateLimiter *tokenBucket) framer {
 	return &framerI{
-		streamGetter:  streamGetter,
-		activeStreams: make(map[protocol.StreamID]struct{}),
+		streamGetter:         streamGetter,
+		rateLimiter:          rateLimiter,
+		activeStreams:        make(map[protocol.StreamID]struct{}),
+		congestionCandidates: make(map[protocol.StreamID]struct{}),
 	}
 }
 
@@ -58,8 +68,14 @@ func (f *framerI) HasData() bool {
 }
 
 func (f *framerI) QueueControlFrame(frame wire.Frame) {
+	f.QueueControlFrameWithHandler(frame, nil)
+}
+
+// QueueControlFrameWithHandler queues a control frame that is handled by the given handler when it is acknowledged or lost.
+// Control frames queued without a handler use the handler of the retransmission queue.
+func (f *framerI) QueueControlFrameWithHandler(frame wire.Frame, handler ackhandler.FrameHandler) {
 	f.controlFrameMutex.Lock()
-	f.controlFrames = append(f.controlFrames, frame)
+	f.controlFrames = append(f.controlFrames, ackhandler.Frame{Frame: frame, Handler: handler})
 	f.controlFrameMutex.Unlock()
 }
 
@@ -68,11 +84,11 @@ func (f *framerI) AppendControlFrames(frames []ackhandler.Frame, maxLen protocol
 	f.controlFrameMutex.Lock()
 	for len(f.controlFrames) > 0 {
 		frame := f.controlFrames[len(f.controlFrames)-1]
-		frameLen := frame.Length(v)
+		frameLen := frame.Frame.Length(v)
 		if length+frameLen > maxLen {
 			break
 		}
-		frames = append(frames, ackhandler.Frame{Frame: frame})
+		frames = append(frames, frame)
 		length += frameLen
 		f.controlFrames = f.controlFrames[:len(f.controlFrames)-1]
 	}
@@ -85,11 +101,18 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	if _, ok := f.activeStreams[id]; !ok {
 		f.streamQueue.PushBack(id)
 		f.activeStreams[id] = struct{}{}
+		f.congestionCandidates[id] = struct{}{}
 	}
 	f.mutex.Unlock()
 }
 
 func (f *framerI) AppendStreamFrames(frames []ackhandler.StreamFrame, maxLen protocol.ByteCount, v protocol.VersionNumber) ([]ackhandler.StreamFrame, protocol.ByteCount) {
+	if f.rateLimiter != nil {
+		maxLen = f.rateLimiter.Allowance(maxLen)
+		if maxLen < protocol.MinStreamFrameSize {
+			return frames, 0
+		}
+	}
 	startLen := len(frames)
 	var length protocol.ByteCount
 	f.mutex.Lock()
@@ -112,10 +135,11 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.StreamFrame, maxLen pro
 		// For the last STREAM frame, we'll remove the DataLen field later.
 		// Therefore, we can pretend to have more bytes available when popping
 		// the STREAM frame (which will always have the DataLen set).
-		remainingLen += quicvarint.Len(uint64(remainingLen))
+		remainingLen += protocol.ByteCount(quicvarint.Len(uint64(remainingLen)))
 		frame, ok, hasMoreData := str.popStreamFrame(remainingLen, v)
 		if hasMoreData { // put the stream back in the queue (at the end)
 			f.streamQueue.PushBack(id)
+			f.congestionCandidates[id] = struct{}{}
 		} else { // no more data to send. Stream is not active
 			delete(f.activeStreams, id)
 		}
@@ -135,9 +159,34 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.StreamFrame, maxLen pro
 		frames[len(frames)-1].Frame.DataLenPresent = false
 		length += frames[len(frames)-1].Frame.Length(v) - l
 	}
+	if f.rateLimiter != nil {
+		f.rateLimiter.Consume(length)
+	}
 	return frames, length
 }
 
+func (f *framerI) OnCongestionLimited() {
+	f.mutex.Lock()
+	ids := make([]protocol.StreamID, 0, len(f.congestionCandidates))
+	for id := range f.congestionCandidates {
+		if _, ok := f.activeStreams[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	// Maps don't shrink, and iterating over a map takes time proportional to its size.
+	// Replace the map, to make sure that the next call is cheap if only a few streams were added.
+	f.congestionCandidates = make(map[protocol.StreamID]struct{})
+	f.mutex.Unlock()
+
+	for _, id := range ids {
+		str, err := f.streamGetter.GetOrOpenSendStream(id)
+		if str == nil || err != nil {
+			continue
+		}
+		str.onCongestionLimited()
+	}
+}
+
 func (f *framerI) Handle0RTTRejection() error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -147,9 +196,10 @@ func (f *framerI) Handle0RTTRejection() error {
 	for id := range f.activeStreams {
 		delete(f.activeStreams, id)
 	}
+	f.congestionCandidates = make(map[protocol.StreamID]struct{})
 	var j int
 	for i, frame := range f.controlFrames {
-		switch frame.(type) {
+		switch frame.Frame.(type) {
 		case *wire.MaxDataFrame, *wire.MaxStreamDataFrame, *wire.MaxStreamsFrame:
 			return errors.New("didn't expect MAX_DATA / MAX_STREAM_DATA / MAX_STREAMS frame to be sent in 0-RTT")
 		case *wire.DataBlockedFrame, *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
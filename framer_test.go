@@ -3,6 +3,8 @@ package quic
 import (
 	"bytes"
 	"math/rand"
+	"testing"
+	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -32,7 +34,7 @@ var _ = Describe("Framer", func() {
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
-		framer = newFramer(streamGetter)
+		framer = newFramer(streamGetter, nil)
 	})
 
 	Context("handling control frames", func() {
@@ -49,6 +51,17 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(mdf.Length(version) + msf.Length(version)))
 		})
 
+		It("keeps the handler of control frames", func() {
+			handler := newPingRequest(nil, nil, nil)
+			framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 0x42})
+			framer.QueueControlFrameWithHandler(&wire.PingFrame{}, handler)
+			frames, _ := framer.AppendControlFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			Expect(frames[0].Handler).To(Equal(handler))
+			Expect(frames[1].Handler).To(BeNil())
+		})
+
 		It("says if it has data", func() {
 			Expect(framer.HasData()).To(BeFalse())
 			f := &wire.MaxDataFrame{MaximumData: 0x42}
@@ -383,5 +396,121 @@ var _ = Describe("Framer", func() {
 			Expect(fs).To(BeEmpty())
 			Expect(length).To(BeZero())
 		})
+
+		It("notifies active streams when the connection is congestion limited", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(nil, nil)
+			stream1.EXPECT().onCongestionLimited()
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			framer.OnCongestionLimited()
+		})
+
+		It("only notifies streams that became active or sent data since the last notification", func() {
+			// once for every call to OnCongestionLimited that notifies stream 1, and once for AppendStreamFrames
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(3)
+			stream1.EXPECT().onCongestionLimited()
+			framer.AddActiveStream(id1)
+			framer.OnCongestionLimited()
+			// stream 1 was already notified
+			framer.OnCongestionLimited()
+
+			f := &wire.StreamFrame{StreamID: id1, Data: []byte("foobar"), DataLenPresent: true}
+			stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f}, true, true)
+			fs, _ := framer.AppendStreamFrames(nil, 1000, protocol.Version1)
+			Expect(fs).To(HaveLen(1))
+			stream2.EXPECT().onCongestionLimited()
+			stream1.EXPECT().onCongestionLimited()
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			framer.AddActiveStream(id2)
+			framer.OnCongestionLimited()
+		})
+	})
+
+	Context("rate limiting", func() {
+		var clock *fakeClock
+
+		BeforeEach(func() {
+			clock = &fakeClock{now: time.Now()}
+			framer = newFramer(streamGetter, newTokenBucket(10000, 2000, clock))
+		})
+
+		It("limits the size of STREAM frames to the available tokens", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).AnyTimes()
+			stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).DoAndReturn(func(size protocol.ByteCount, v protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
+				f := &wire.StreamFrame{StreamID: id1, DataLenPresent: true}
+				f.Data = make([]byte, f.MaxDataLen(size, v))
+				return ackhandler.StreamFrame{Frame: f}, true, true
+			}).Times(3)
+			framer.AddActiveStream(id1)
+			// the bucket starts out full
+			_, length := framer.AppendStreamFrames(nil, 1500, protocol.Version1)
+			Expect(length).To(Equal(protocol.ByteCount(1500)))
+			// only 500 bytes left, so we can't send a full packet
+			frames, length := framer.AppendStreamFrames(nil, 1500, protocol.Version1)
+			Expect(frames).To(BeEmpty())
+			Expect(length).To(BeZero())
+			Expect(framer.HasData()).To(BeTrue())
+			clock.now = clock.now.Add(100 * time.Millisecond)
+			_, length = framer.AppendStreamFrames(nil, 1500, protocol.Version1)
+			Expect(length).To(Equal(protocol.ByteCount(1500)))
+			// after one second, the bucket is full again
+			clock.now = clock.now.Add(time.Second)
+			_, length = framer.AppendStreamFrames(nil, 1500, protocol.Version1)
+			Expect(length).To(Equal(protocol.ByteCount(1500)))
+		})
+
+		It("doesn't limit control frames", func() {
+			framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 0x42})
+			framer.(*framerI).rateLimiter.Consume(2000)
+			frames, _ := framer.AppendControlFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(1))
+		})
 	})
 })
+
+type benchmarkSendStream struct {
+	sendStreamI
+	id protocol.StreamID
+}
+
+func (s *benchmarkSendStream) popStreamFrame(protocol.ByteCount, protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
+	f := &wire.StreamFrame{StreamID: s.id, Data: make([]byte, 100), DataLenPresent: true}
+	return ackhandler.StreamFrame{Frame: f}, true, true
+}
+
+func (s *benchmarkSendStream) onCongestionLimited() {}
+
+type benchmarkStreamGetter map[protocol.StreamID]*benchmarkSendStream
+
+func (g benchmarkStreamGetter) GetOrOpenReceiveStream(protocol.StreamID) (receiveStreamI, error) {
+	return nil, nil
+}
+
+func (g benchmarkStreamGetter) GetOrOpenSendStream(id protocol.StreamID) (sendStreamI, error) {
+	return g[id], nil
+}
+
+// BenchmarkFramerActiveStreams packs STREAM frames and reports congestion limitation,
+// as a connection with a large number of active streams does when it is congestion limited.
+func BenchmarkFramerActiveStreams(b *testing.B) {
+	const numStreams = 100000
+	streams := make(benchmarkStreamGetter, numStreams)
+	framer := newFramer(streams, nil)
+	for i := 0; i < numStreams; i++ {
+		id := protocol.StreamNum(i+1).StreamID(protocol.StreamTypeBidi, protocol.PerspectiveClient)
+		streams[id] = &benchmarkSendStream{id: id}
+		framer.AddActiveStream(id)
+	}
+	framer.OnCongestionLimited()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frames, _ := framer.AppendStreamFrames(nil, protocol.MaxPacketBufferSize, protocol.Version1)
+		if len(frames) == 0 {
+			b.Fatal("expected STREAM frames")
+		}
+		framer.OnCongestionLimited()
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"net/netip"
 	"time"
 
 	"golang.org/x/exp/rand"
@@ -248,6 +249,22 @@ func getFrames() []wire.Frame {
 		Data: data2,
 	})
 
+	var ipv4 [4]byte
+	copy(ipv4[:], getRandomData(4))
+	var ipv6 [16]byte
+	copy(ipv6[:], getRandomData(16))
+	frames = append(frames, []wire.Frame{
+		&wire.ObservedAddressFrame{
+			SequenceNumber: getRandomNumber(),
+			Address:        netip.AddrPortFrom(netip.AddrFrom4(ipv4), uint16(rand.Intn(65536))),
+		},
+		&wire.ObservedAddressFrame{
+			SequenceNumber: getRandomNumber(),
+			Address:        netip.AddrPortFrom(netip.AddrFrom16(ipv6), uint16(rand.Intn(65536))),
+		},
+		&wire.ImmediateAckFrame{},
+	}...)
+
 	return frames
 }
 
@@ -33,7 +33,7 @@ func Fuzz(data []byte) int {
 	encLevel := toEncLevel(data[0])
 	data = data[PrefixLen:]
 
-	parser := wire.NewFrameParser(true)
+	parser := wire.NewFrameParser(true, true, true)
 	parser.SetAckDelayExponent(protocol.DefaultAckDelayExponent)
 
 	var numFrames int
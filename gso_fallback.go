@@ -0,0 +1,142 @@
+package quic
+
+import (
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// An OffloadState describes if Generic Segmentation Offload (GSO) is used by a Transport.
+type OffloadState struct {
+	// GSOSupported says if the OS and the socket support GSO.
+	GSOSupported bool
+	// GSOEnabled says if GSO is currently used to send packets.
+	GSOEnabled bool
+	// GSODisabledReason is the reason why GSO was disabled at runtime.
+	// It is only set if GSO is supported, but was disabled.
+	GSODisabledReason logging.GSODisabledReason
+}
+
+// The gsoFallbackConn allows disabling GSO on a rawConn at runtime.
+// This is necessary since some network interfaces and drivers fail to send GSO batches,
+// either by returning an error or by silently dropping the packets.
+type gsoFallbackConn struct {
+	rawConn
+
+	disabledReason atomic.Uint32 // the logging.GSODisabledReason, 0 as long as GSO is not disabled
+	onDisabled     func(logging.GSODisabledReason)
+}
+
+func newGSOFallbackConn(c rawConn, onDisabled func(logging.GSODisabledReason)) *gsoFallbackConn {
+	return &gsoFallbackConn{rawConn: c, onDisabled: onDisabled}
+}
+
+// socketBufferTuner returns the socketBufferTuner of the underlying rawConn, if any.
+func (c *gsoFallbackConn) socketBufferTuner() *socketBufferTuner {
+	if t, ok := c.rawConn.(interface{ socketBufferTuner() *socketBufferTuner }); ok {
+		return t.socketBufferTuner()
+	}
+	return nil
+}
+
+func (c *gsoFallbackConn) capabilities() connCapabilities {
+	capabilities := c.rawConn.capabilities()
+	if c.disabledReason.Load() != 0 {
+		capabilities.GSO = false
+	}
+	return capabilities
+}
+
+// disableGSO disables GSO for all connections using this conn.
+// Only the first call has an effect.
+func (c *gsoFallbackConn) disableGSO(reason logging.GSODisabledReason) {
+	if !c.rawConn.capabilities().GSO {
+		return
+	}
+	if !c.disabledReason.CompareAndSwap(0, uint32(reason)) {
+		return
+	}
+	if c.onDisabled != nil {
+		c.onDisabled(reason)
+	}
+}
+
+func (c *gsoFallbackConn) offloadState() OffloadState {
+	reason := logging.GSODisabledReason(c.disabledReason.Load())
+	supported := c.rawConn.capabilities().GSO
+	return OffloadState{
+		GSOSupported:      supported,
+		GSOEnabled:        supported && reason == 0,
+		GSODisabledReason: reason,
+	}
+}
+
+// gsoBlackholeThreshold is the number of GSO batches that need to be lost,
+// while packets sent after them were acknowledged, before GSO is considered broken.
+const gsoBlackholeThreshold = 3
+
+// The gsoBlackholeDetector detects if GSO batches are dropped on the path,
+// which happens if the NIC or driver silently fails to segment the batch.
+// Once a packet sent in a GSO batch is acknowledged, GSO is known to work.
+type gsoBlackholeDetector struct {
+	verified bool
+	// the packet number ranges of the GSO batches sent before GSO was verified
+	batches    []wire.AckRange
+	lostCount  int
+	blackholed bool
+}
+
+// SentBatch is called when a GSO batch containing more than one packet is sent.
+func (d *gsoBlackholeDetector) SentBatch(first, last protocol.PacketNumber) {
+	if d.verified || d.blackholed {
+		return
+	}
+	d.batches = append(d.batches, wire.AckRange{Smallest: first, Largest: last})
+}
+
+// ReceivedAck is called for every ACK frame received for 1-RTT packets.
+// It returns true if it was detected that GSO batches are blackholed.
+// This only happens once.
+func (d *gsoBlackholeDetector) ReceivedAck(ack *wire.AckFrame) bool {
+	if d.verified || d.blackholed || len(d.batches) == 0 {
+		return false
+	}
+	largestAcked := ack.LargestAcked()
+	var remaining int
+	for _, b := range d.batches {
+		if ackRangesOverlap(ack.AckRanges, b) {
+			d.verified = true
+			d.batches = nil
+			return false
+		}
+		if b.Largest < largestAcked {
+			// Packets sent after this batch were acknowledged, but none of the packets in the batch.
+			d.lostCount++
+			continue
+		}
+		d.batches[remaining] = b
+		remaining++
+	}
+	d.batches = d.batches[:remaining]
+	if d.lostCount >= gsoBlackholeThreshold {
+		d.blackholed = true
+		d.batches = nil
+		return true
+	}
+	return false
+}
+
+// ackRangesOverlap says if any of the (descending) ACK ranges overlaps with r.
+func ackRangesOverlap(ranges []wire.AckRange, r wire.AckRange) bool {
+	for _, ar := range ranges {
+		if ar.Largest < r.Smallest {
+			return false
+		}
+		if ar.Smallest <= r.Largest {
+			return true
+		}
+	}
+	return false
+}
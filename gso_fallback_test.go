@@ -0,0 +1,97 @@
+package quic
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GSO fallback", func() {
+	Context("conn", func() {
+		It("disables GSO", func() {
+			rawConn := NewMockRawConn(mockCtrl)
+			rawConn.EXPECT().capabilities().Return(connCapabilities{GSO: true, ECN: true}).AnyTimes()
+			var reasons []logging.GSODisabledReason
+			c := newGSOFallbackConn(rawConn, func(r logging.GSODisabledReason) { reasons = append(reasons, r) })
+			Expect(c.capabilities()).To(Equal(connCapabilities{GSO: true, ECN: true}))
+			Expect(c.offloadState()).To(Equal(OffloadState{GSOSupported: true, GSOEnabled: true}))
+
+			c.disableGSO(logging.GSODisabledSendError)
+			Expect(c.capabilities()).To(Equal(connCapabilities{ECN: true}))
+			Expect(c.offloadState()).To(Equal(OffloadState{GSOSupported: true, GSODisabledReason: logging.GSODisabledSendError}))
+			Expect(reasons).To(Equal([]logging.GSODisabledReason{logging.GSODisabledSendError}))
+
+			// only the first call has an effect
+			c.disableGSO(logging.GSODisabledBlackholed)
+			Expect(c.offloadState().GSODisabledReason).To(Equal(logging.GSODisabledSendError))
+			Expect(reasons).To(HaveLen(1))
+		})
+
+		It("returns the socket buffer tuner of the underlying conn", func() {
+			tuner := &socketBufferTuner{}
+			c := newGSOFallbackConn(&socketBufferConn{rawConn: NewMockRawConn(mockCtrl), tuner: tuner}, nil)
+			Expect(c.socketBufferTuner()).To(Equal(tuner))
+			Expect(newGSOFallbackConn(NewMockRawConn(mockCtrl), nil).socketBufferTuner()).To(BeNil())
+		})
+
+		It("doesn't disable GSO if it's not supported", func() {
+			rawConn := NewMockRawConn(mockCtrl)
+			rawConn.EXPECT().capabilities().Return(connCapabilities{}).AnyTimes()
+			var called bool
+			c := newGSOFallbackConn(rawConn, func(logging.GSODisabledReason) { called = true })
+			c.disableGSO(logging.GSODisabledSendError)
+			Expect(called).To(BeFalse())
+			Expect(c.offloadState()).To(BeZero())
+		})
+	})
+
+	Context("blackhole detection", func() {
+		ackFrame := func(ranges ...wire.AckRange) *wire.AckFrame { return &wire.AckFrame{AckRanges: ranges} }
+
+		It("detects that GSO batches are blackholed", func() {
+			var d gsoBlackholeDetector
+			d.SentBatch(10, 14)
+			d.SentBatch(16, 20)
+			d.SentBatch(22, 26)
+			// packet 15 was acknowledged, but none of the packets of the first batch
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 15, Largest: 15}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 21, Largest: 21}, wire.AckRange{Smallest: 15, Largest: 15}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(
+				wire.AckRange{Smallest: 27, Largest: 27},
+				wire.AckRange{Smallest: 21, Largest: 21},
+				wire.AckRange{Smallest: 15, Largest: 15},
+			))).To(BeTrue())
+			// only reported once
+			d.SentBatch(30, 34)
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 35, Largest: 35}))).To(BeFalse())
+		})
+
+		It("doesn't consider batches lost that were sent after the largest acknowledged packet", func() {
+			var d gsoBlackholeDetector
+			for i := protocol.PacketNumber(0); i < 5; i++ {
+				d.SentBatch(10*i+10, 10*i+15)
+			}
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 1, Largest: 9}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 16, Largest: 16}, wire.AckRange{Smallest: 1, Largest: 9}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 26, Largest: 26}, wire.AckRange{Smallest: 1, Largest: 9}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 36, Largest: 36}, wire.AckRange{Smallest: 1, Largest: 9}))).To(BeTrue())
+		})
+
+		It("stops once a packet sent in a GSO batch is acknowledged", func() {
+			var d gsoBlackholeDetector
+			d.SentBatch(10, 14)
+			d.SentBatch(16, 20)
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 15, Largest: 15}))).To(BeFalse())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 18, Largest: 21}, wire.AckRange{Smallest: 15, Largest: 15}))).To(BeFalse())
+			Expect(d.verified).To(BeTrue())
+			for i := protocol.PacketNumber(0); i < 5; i++ {
+				d.SentBatch(10*i+30, 10*i+35)
+			}
+			Expect(d.batches).To(BeEmpty())
+			Expect(d.ReceivedAck(ackFrame(wire.AckRange{Smallest: 100, Largest: 100}))).To(BeFalse())
+		})
+	})
+})
@@ -81,11 +81,15 @@ type hijackableBody struct {
 	// either when Read() errors, or when Close() is called.
 	reqDone       chan<- struct{}
 	reqDoneClosed bool
+
+	// only set if datagrams are enabled
+	datagrams *datagrammer
 }
 
 var (
 	_ Hijacker     = &hijackableBody{}
 	_ HTTPStreamer = &hijackableBody{}
+	_ Datagrammer  = &hijackableBody{}
 )
 
 func newResponseBody(str Stream, conn quic.Connection, done chan<- struct{}) *hijackableBody {
@@ -106,10 +110,31 @@ func (r *hijackableBody) Read(b []byte) (int, error) {
 	n, err := r.str.Read(b)
 	if err != nil {
 		r.requestDone()
+		r.datagramsDone()
 	}
 	return n, maybeReplaceError(err)
 }
 
+func (r *hijackableBody) SendDatagram(b []byte) error {
+	if r.datagrams == nil {
+		return errNoDatagrams
+	}
+	return r.datagrams.send(r.str.StreamID(), b)
+}
+
+func (r *hijackableBody) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if r.datagrams == nil {
+		return nil, errNoDatagrams
+	}
+	return r.datagrams.receive(ctx, r.str.StreamID())
+}
+
+func (r *hijackableBody) datagramsDone() {
+	if r.datagrams != nil {
+		r.datagrams.removeStream(r.str.StreamID())
+	}
+}
+
 func (r *hijackableBody) requestDone() {
 	if r.reqDoneClosed || r.reqDone == nil {
 		return
@@ -126,6 +151,7 @@ func (r *body) StreamID() quic.StreamID {
 
 func (r *hijackableBody) Close() error {
 	r.requestDone()
+	r.datagramsDone()
 	// If the EOF was read, CancelRead() is a no-op.
 	r.str.CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
 	return nil
@@ -1,7 +1,9 @@
 package http3
 
 import (
+	"context"
 	"errors"
+	"net"
 
 	"github.com/quic-go/quic-go"
 	mockquic "github.com/quic-go/quic-go/internal/mocks/quic"
@@ -51,4 +53,24 @@ var _ = Describe("Response Body", func() {
 		Expect(reqDone).To(BeClosed())
 		Expect(rb.Close()).To(Succeed())
 	})
+
+	It("errors when sending and receiving datagrams, if datagrams are not enabled", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		rb := newResponseBody(str, nil, reqDone)
+		Expect(rb.SendDatagram([]byte("foobar"))).To(MatchError(errNoDatagrams))
+		_, err := rb.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errNoDatagrams))
+	})
+
+	It("stops receiving datagrams when the response is closed", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+		rb := newResponseBody(str, nil, reqDone)
+		rb.datagrams = newDatagrammer(nil)
+		rb.datagrams.addStream(4)
+		str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+		Expect(rb.Close()).To(Succeed())
+		_, err := rb.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(net.ErrClosed))
+	})
 })
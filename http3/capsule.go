@@ -25,14 +25,7 @@ func (r *exactReader) Read(b []byte) (int, error) {
 // It returns an io.LimitedReader that can be used to read the Capsule value.
 // The Capsule value must be read entirely (i.e. until the io.EOF) before using r again.
 func ParseCapsule(r quicvarint.Reader) (CapsuleType, io.Reader, error) {
-	ct, err := quicvarint.Read(r)
-	if err != nil {
-		if err == io.EOF {
-			return 0, nil, io.ErrUnexpectedEOF
-		}
-		return 0, nil, err
-	}
-	l, err := quicvarint.Read(r)
+	ct, l, err := quicvarint.ReadFrameHeader(r, 0)
 	if err != nil {
 		if err == io.EOF {
 			return 0, nil, io.ErrUnexpectedEOF
@@ -44,9 +37,7 @@ func ParseCapsule(r quicvarint.Reader) (CapsuleType, io.Reader, error) {
 
 // WriteCapsule writes a capsule
 func WriteCapsule(w quicvarint.Writer, ct CapsuleType, value []byte) error {
-	b := make([]byte, 0, 16)
-	b = quicvarint.Append(b, uint64(ct))
-	b = quicvarint.Append(b, uint64(len(value)))
+	b := quicvarint.AppendFrameHeader(make([]byte, 0, 16), uint64(ct), uint64(len(value)))
 	if _, err := w.Write(b); err != nil {
 		return err
 	}
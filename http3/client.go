@@ -1,6 +1,7 @@
 package http3
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -23,6 +24,9 @@ import (
 
 // MethodGet0RTT allows a GET request to be sent using 0-RTT.
 // Note that 0-RTT data doesn't provide replay protection.
+// If the server rejects 0-RTT, the request is sent again after the handshake completes.
+// To allow this, the request body is obtained using the request's GetBody function,
+// or, if GetBody is not set, it is buffered in memory.
 const MethodGet0RTT = "GET_0RTT"
 
 const (
@@ -43,9 +47,14 @@ type roundTripperOpts struct {
 	DisableCompression bool
 	EnableDatagram     bool
 	MaxHeaderBytes     int64
-	AdditionalSettings map[uint64]uint64
-	StreamHijacker     func(FrameType, quic.Connection, quic.Stream, error) (hijacked bool, err error)
-	UniStreamHijacker  func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
+	MaxHeaderFields    int
+	// timeouts for receiving the response, see the RoundTripper for details
+	ResponseHeaderTimeout time.Duration
+	ResponseBodyTimeout   time.Duration
+	AdditionalSettings    map[uint64]uint64
+	StreamHijacker        func(FrameType, quic.Connection, quic.Stream, error) (hijacked bool, err error)
+	UniStreamHijacker     func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
+	UniStreamHandlers     map[StreamType]func(quic.Connection, quic.ReceiveStream)
 }
 
 // client is a HTTP3 client doing requests
@@ -65,6 +74,11 @@ type client struct {
 	hostname string
 	conn     atomic.Pointer[quic.EarlyConnection]
 
+	nextConnOnce sync.Once // used to switch to the connection returned by NextConnection, if 0-RTT is rejected
+	nextConnErr  error
+
+	datagrams *datagrammer // only set if datagrams are enabled
+
 	logger utils.Logger
 }
 
@@ -85,6 +99,12 @@ func newClient(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, con
 		conf.MaxIncomingStreams = -1 // don't allow any bidirectional streams
 	}
 	conf.EnableDatagrams = opts.EnableDatagram
+	for st := range opts.UniStreamHandlers {
+		switch st {
+		case streamTypeControlStream, streamTypePushStream, streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
+			return nil, fmt.Errorf("http3: can't register a handler for stream type %d, it is used by HTTP/3", st)
+		}
+	}
 	logger := utils.DefaultLogger.WithPrefix("h3 client")
 
 	if tlsConf == nil {
@@ -127,7 +147,16 @@ func (c *client) dial(ctx context.Context) error {
 		return err
 	}
 	c.conn.Store(&conn)
+	if c.opts.EnableDatagram {
+		c.datagrams = newDatagrammer(conn)
+		go c.datagrams.run()
+	}
+	c.startConn(conn)
+	return nil
+}
 
+// startConn opens the control stream and starts handling the streams opened by the server.
+func (c *client) startConn(conn quic.EarlyConnection) {
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
 		if err := c.setupConn(conn); err != nil {
@@ -140,7 +169,30 @@ func (c *client) dial(ctx context.Context) error {
 		go c.handleBidirectionalStreams(conn)
 	}
 	go c.handleUnidirectionalStreams(conn)
-	return nil
+}
+
+// useNextConnection is called when the server rejected 0-RTT.
+// It blocks until the handshake completes, and then switches to the connection returned by NextConnection.
+// Since all streams opened in 0-RTT were closed, the control stream is opened again.
+func (c *client) useNextConnection(ctx context.Context, conn quic.EarlyConnection) (quic.EarlyConnection, error) {
+	select {
+	case <-conn.HandshakeComplete():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.nextConnOnce.Do(func() {
+		next, ok := conn.NextConnection().(quic.EarlyConnection)
+		if !ok {
+			c.nextConnErr = errors.New("http3: 0-RTT rejected, and the next connection doesn't support HTTP/3")
+			return
+		}
+		c.conn.Store(&next)
+		c.startConn(next)
+	})
+	if c.nextConnErr != nil {
+		return nil, c.nextConnErr
+	}
+	return *c.conn.Load(), nil
 }
 
 func (c *client) setupConn(conn quic.EarlyConnection) error {
@@ -152,7 +204,11 @@ func (c *client) setupConn(conn quic.EarlyConnection) error {
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream)
 	// send the SETTINGS frame
-	b = (&settingsFrame{Datagram: c.opts.EnableDatagram, Other: c.opts.AdditionalSettings}).Append(b)
+	b = (&settingsFrame{
+		Datagram:            c.opts.EnableDatagram,
+		MaxFieldSectionSize: c.maxHeaderBytes(),
+		Other:               c.opts.AdditionalSettings,
+	}).Append(b)
 	_, err = str.Write(b)
 	return err
 }
@@ -208,6 +264,10 @@ func (c *client) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 				conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeIDError), "")
 				return
 			default:
+				if handler, ok := c.opts.UniStreamHandlers[StreamType(streamType)]; ok {
+					handler(conn, str)
+					return
+				}
 				if c.opts.UniStreamHijacker != nil && c.opts.UniStreamHijacker(StreamType(streamType), conn, str, nil) {
 					return
 				}
@@ -224,6 +284,9 @@ func (c *client) handleUnidirectionalStreams(conn quic.EarlyConnection) {
 				conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeMissingSettings), "")
 				return
 			}
+			if c.datagrams != nil {
+				c.datagrams.onSettings(sf.Datagram)
+			}
 			if !sf.Datagram {
 				return
 			}
@@ -252,6 +315,24 @@ func (c *client) maxHeaderBytes() uint64 {
 	return uint64(c.opts.MaxHeaderBytes)
 }
 
+// Connection returns the QUIC connection, dialing it if necessary.
+// It blocks until the handshake completes.
+func (c *client) Connection(ctx context.Context) (quic.EarlyConnection, error) {
+	c.dialOnce.Do(func() {
+		c.handshakeErr = c.dial(ctx)
+	})
+	if c.handshakeErr != nil {
+		return nil, c.handshakeErr
+	}
+	conn := *c.conn.Load()
+	select {
+	case <-conn.HandshakeComplete():
+		return conn, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // RoundTripOpt executes a request and returns a response
 func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Response, error) {
 	if authorityAddr("https", hostnameFromRequest(req)) != c.hostname {
@@ -271,6 +352,12 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 	// Immediately send out this request, if this is a 0-RTT request.
 	if req.Method == MethodGet0RTT {
 		req.Method = http.MethodGet
+		// The body needs to be sent again if the server rejects 0-RTT.
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			if err := bufferRequestBody(req); err != nil {
+				return nil, err
+			}
+		}
 	} else {
 		// wait for the handshake to complete
 		select {
@@ -280,10 +367,34 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 		}
 	}
 
+	rsp, err := c.roundTrip(req, conn, opt)
+	// If the server rejected 0-RTT, none of the data sent in 0-RTT was processed by the server.
+	// It's therefore safe to replay the request after the handshake completed.
+	if err != nil && errors.Is(err, quic.Err0RTTRejected) {
+		c.logger.Debugf("0-RTT rejected. Retrying request.")
+		if conn, err = c.useNextConnection(req.Context(), conn); err != nil {
+			return nil, err
+		}
+		if req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		return c.roundTrip(req, conn, opt)
+	}
+	return rsp, err
+}
+
+func (c *client) roundTrip(req *http.Request, conn quic.EarlyConnection, opt RoundTripOpt) (*http.Response, error) {
 	str, err := conn.OpenStreamSync(req.Context())
 	if err != nil {
 		return nil, err
 	}
+	if c.datagrams != nil {
+		c.datagrams.addStream(str.StreamID())
+	}
 
 	// Request Cancellation:
 	// This go routine keeps running even after RoundTripOpt() returns.
@@ -308,6 +419,9 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 	if rerr.err != nil { // if any error occurred
 		close(reqDone)
 		<-done
+		if c.datagrams != nil {
+			c.datagrams.removeStream(str.StreamID())
+		}
 		if rerr.streamErr != 0 { // if it was a stream error
 			str.CancelWrite(quic.StreamErrorCode(rerr.streamErr))
 		}
@@ -327,6 +441,21 @@ func (c *client) RoundTripOpt(req *http.Request, opt RoundTripOpt) (*http.Respon
 	return rsp, maybeReplaceError(rerr.err)
 }
 
+// bufferRequestBody reads the request body into memory,
+// such that it can be obtained again using GetBody.
+func bufferRequestBody(req *http.Request) error {
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	return nil
+}
+
 // cancelingReader reads from the io.Reader.
 // It cancels writing on the stream if any error other than io.EOF occurs.
 type cancelingReader struct {
@@ -366,11 +495,18 @@ func (c *client) sendRequestBody(str Stream, body io.ReadCloser, contentLength i
 	return err
 }
 
+func (c *client) isResponseHeaderTimeout(err error) bool {
+	return c.opts.ResponseHeaderTimeout > 0 && isTimeoutError(err)
+}
+
 func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str quic.Stream, opt RoundTripOpt, reqDone chan<- struct{}) (*http.Response, requestError) {
 	var requestGzip bool
 	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
 		requestGzip = true
 	}
+	if trace := ContextClientTrace(req.Context()); trace != nil {
+		trace.traceUploadBlocking(str)
+	}
 	if err := c.requestWriter.WriteRequestHeader(str, req, requestGzip); err != nil {
 		return nil, newStreamError(ErrCodeInternalError, err)
 	}
@@ -378,6 +514,9 @@ func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str qui
 	if req.Body == nil && !opt.DontCloseRequestStream {
 		str.Close()
 	}
+	if c.opts.ResponseHeaderTimeout > 0 {
+		str.SetReadDeadline(time.Now().Add(c.opts.ResponseHeaderTimeout))
+	}
 
 	hstr := newStream(str, func() { conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeFrameUnexpected), "") })
 	if req.Body != nil {
@@ -400,6 +539,10 @@ func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str qui
 
 	frame, err := parseNextFrame(str, nil)
 	if err != nil {
+		if c.isResponseHeaderTimeout(err) {
+			str.CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+			return nil, newStreamError(ErrCodeRequestCanceled, errResponseHeaderTimeout)
+		}
 		return nil, newStreamError(ErrCodeFrameError, err)
 	}
 	hf, ok := frame.(*headersFrame)
@@ -411,6 +554,10 @@ func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str qui
 	}
 	headerBlock := make([]byte, hf.Length)
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		if c.isResponseHeaderTimeout(err) {
+			str.CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+			return nil, newStreamError(ErrCodeRequestCanceled, errResponseHeaderTimeout)
+		}
 		return nil, newStreamError(ErrCodeRequestIncomplete, err)
 	}
 	hfs, err := c.decoder.DecodeFull(headerBlock)
@@ -418,6 +565,15 @@ func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str qui
 		// TODO: use the right error code
 		return nil, newConnError(ErrCodeGeneralProtocolError, err)
 	}
+	// The header timeout doesn't apply to the response body.
+	if c.opts.ResponseBodyTimeout > 0 {
+		str.SetReadDeadline(time.Now().Add(c.opts.ResponseBodyTimeout))
+	} else if c.opts.ResponseHeaderTimeout > 0 {
+		str.SetReadDeadline(time.Time{})
+	}
+	if err := checkFieldSection(hfs, c.maxHeaderBytes(), c.opts.MaxHeaderFields); err != nil {
+		return nil, newStreamError(ErrCodeExcessiveLoad, err)
+	}
 
 	res, err := responseFromHeaders(hfs)
 	if err != nil {
@@ -435,6 +591,7 @@ func (c *client) doRequest(req *http.Request, conn quic.EarlyConnection, str qui
 		httpStr = hstr
 	}
 	respBody := newResponseBody(httpStr, conn, reqDone)
+	respBody.datagrams = c.datagrams
 
 	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
 	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
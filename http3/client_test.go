@@ -8,7 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -164,6 +167,48 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("refuses to register handlers for HTTP/3 stream types", func() {
+		_, err := newClient("localhost:1337", nil, &roundTripperOpts{
+			UniStreamHandlers: map[StreamType]func(quic.Connection, quic.ReceiveStream){
+				streamTypeQPACKEncoderStream: func(quic.Connection, quic.ReceiveStream) {},
+			},
+		}, nil, nil)
+		Expect(err).To(MatchError("http3: can't register a handler for stream type 2, it is used by HTTP/3"))
+	})
+
+	It("returns the connection once the handshake completes", func() {
+		cl, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		conn := mockquic.NewMockEarlyConnection(mockCtrl)
+		conn.EXPECT().OpenUniStream().Return(nil, errors.New("done")).AnyTimes()
+		conn.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).AnyTimes()
+		conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).AnyTimes()
+		handshakeCompleted := make(chan struct{})
+		conn.EXPECT().HandshakeComplete().Return(handshakeCompleted)
+		var dialed int
+		dialAddr = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error) {
+			dialed++
+			return conn, nil
+		}
+		connChan := make(chan quic.EarlyConnection, 1)
+		go func() {
+			defer GinkgoRecover()
+			c, err := cl.(*client).Connection(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			connChan <- c
+		}()
+		Consistently(connChan).ShouldNot(Receive())
+		close(handshakeCompleted)
+		Eventually(connChan).Should(Receive(Equal(conn)))
+
+		// the connection is reused
+		conn.EXPECT().HandshakeComplete().Return(handshakeCompleted)
+		c, err := cl.(*client).Connection(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(conn))
+		Expect(dialed).To(Equal(1))
+	})
+
 	It("errors when dialing fails", func() {
 		testErr := errors.New("handshake error")
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
@@ -384,6 +429,30 @@ var _ = Describe("Client", func() {
 			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
 		})
 
+		It("passes unidirectional streams of a registered stream type to the handler", func() {
+			handlerStr := make(chan quic.ReceiveStream, 1)
+			cl.opts.UniStreamHandlers = map[StreamType]func(quic.Connection, quic.ReceiveStream){
+				0x54: func(_ quic.Connection, str quic.ReceiveStream) { handlerStr <- str },
+			}
+			cl.opts.UniStreamHijacker = func(StreamType, quic.Connection, quic.ReceiveStream, error) bool {
+				Fail("didn't expect the hijacker to be called")
+				return false
+			}
+
+			buf := bytes.NewBuffer(quicvarint.Append(nil, 0x54))
+			unknownStr := mockquic.NewMockStream(mockCtrl)
+			unknownStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			conn.EXPECT().AcceptUniStream(gomock.Any()).Return(unknownStr, nil)
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := cl.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(handlerStr).Should(Receive(Equal(unknownStr)))
+			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
+		})
+
 		It("handles errors that occur when reading the stream type", func() {
 			testErr := errors.New("test error")
 			done := make(chan struct{})
@@ -439,6 +508,7 @@ var _ = Describe("Client", func() {
 			req                  *http.Request
 			conn                 *mockquic.MockEarlyConnection
 			settingsFrameWritten chan struct{}
+			settingsData         []byte
 		)
 		testDone := make(chan struct{})
 
@@ -447,6 +517,7 @@ var _ = Describe("Client", func() {
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
 				defer GinkgoRecover()
+				settingsData = append([]byte{}, b...)
 				close(settingsFrameWritten)
 			})
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
@@ -484,6 +555,24 @@ var _ = Describe("Client", func() {
 			time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
 		})
 
+		It("advertises the maximum size of the header section", func() {
+			conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			_, err := cl.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("done"))
+			Eventually(settingsFrameWritten).Should(BeClosed())
+			r := bytes.NewReader(settingsData)
+			streamType, err := quicvarint.Read(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+			f, err := parseNextFrame(r, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+			Expect(f.(*settingsFrame).MaxFieldSectionSize).To(BeEquivalentTo(1337))
+		})
+
 		for _, t := range []uint64{streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream} {
 			streamType := t
 			name := "encoder"
@@ -615,6 +704,7 @@ var _ = Describe("Client", func() {
 				return nil, errors.New("test done")
 			})
 			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{SupportsDatagrams: false})
+			conn.EXPECT().ReceiveMessage(gomock.Any()).Return(nil, errors.New("test done")).MaxTimes(1)
 			done := make(chan struct{})
 			conn.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
 				defer GinkgoRecover()
@@ -723,6 +813,104 @@ var _ = Describe("Client", func() {
 			Expect(decodeHeader(buf)).To(HaveKeyWithValue(":method", "GET"))
 		})
 
+		Context("0-RTT rejection", func() {
+			// expectNextConnection sets up the expectations for switching to the next connection,
+			// which is the same connection object when using quic-go.
+			// It returns a channel that is closed when the control stream is opened.
+			expectNextConnection := func() <-chan struct{} {
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(b []byte) (int, error) { return len(b), nil })
+				conn.EXPECT().HandshakeComplete().Return(handshakeChan).AnyTimes()
+				conn.EXPECT().NextConnection().Return(conn)
+				opened := make(chan struct{})
+				conn.EXPECT().OpenUniStream().DoAndReturn(func() (quic.SendStream, error) {
+					close(opened)
+					return controlStr, nil
+				})
+				conn.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("test done"))
+				return opened
+			}
+
+			It("replays a request that couldn't be opened", func() {
+				req.Method = MethodGet0RTT
+				opened := expectNextConnection()
+				rspBuf := bytes.NewBuffer(getResponse(200))
+				gomock.InOrder(
+					conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, quic.Err0RTTRejected),
+					conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := cl.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Eventually(opened).Should(BeClosed())
+			})
+
+			It("replays a request with a body", func() {
+				var err error
+				req, err = http.NewRequest(MethodGet0RTT, "https://quic.clemente.io:1337/file1.dat", io.NopCloser(strings.NewReader("foobar")))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(req.GetBody).To(BeNil())
+				opened := expectNextConnection()
+
+				rejectedStr := mockquic.NewMockStream(mockCtrl)
+				rejectedStr.EXPECT().StreamID().AnyTimes()
+				rejectedStr.EXPECT().Write(gomock.Any()).AnyTimes().Return(0, quic.Err0RTTRejected)
+				rejectedStr.EXPECT().Read(gomock.Any()).Return(0, quic.Err0RTTRejected).AnyTimes()
+				rejectedStr.EXPECT().Close().AnyTimes()
+				rejectedStr.EXPECT().CancelWrite(gomock.Any()).AnyTimes()
+
+				rspBuf := bytes.NewBuffer(getResponse(200))
+				strBuf := &bytes.Buffer{}
+				var mutex sync.Mutex
+				closed := make(chan struct{})
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					return strBuf.Write(p)
+				})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				gomock.InOrder(
+					conn.EXPECT().OpenStreamSync(context.Background()).Return(rejectedStr, nil),
+					conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				rsp, err := cl.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Eventually(closed).Should(BeClosed())
+				mutex.Lock()
+				defer mutex.Unlock()
+				Expect(decodeHeader(strBuf)).To(HaveKeyWithValue(":method", "GET"))
+				f, err := parseNextFrame(strBuf, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(Equal(&dataFrame{Length: 6}))
+				Expect(strBuf.String()).To(Equal("foobar"))
+				Eventually(opened).Should(BeClosed())
+			})
+
+			It("uses GetBody to obtain the body", func() {
+				var err error
+				req, err = http.NewRequest(MethodGet0RTT, "https://quic.clemente.io:1337/file1.dat", strings.NewReader("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				var getBodyCalled bool
+				req.GetBody = func() (io.ReadCloser, error) {
+					getBodyCalled = true
+					return nil, errors.New("GetBody failed")
+				}
+				opened := expectNextConnection()
+				conn.EXPECT().OpenStreamSync(context.Background()).Return(nil, quic.Err0RTTRejected)
+				_, err = cl.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("GetBody failed"))
+				Expect(getBodyCalled).To(BeTrue())
+				Eventually(opened).Should(BeClosed())
+			})
+		})
+
 		It("returns a response", func() {
 			rspBuf := bytes.NewBuffer(getResponse(418))
 			gomock.InOrder(
@@ -757,6 +945,78 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		It("cancels the request when the response headers aren't received in time", func() {
+			cl.opts.ResponseHeaderTimeout = time.Second
+			conn.EXPECT().HandshakeComplete().Return(handshakeChan)
+			conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			var deadline time.Time
+			str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { deadline = t })
+			str.EXPECT().Read(gomock.Any()).Return(0, os.ErrDeadlineExceeded)
+			str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+			str.EXPECT().CancelWrite(quic.StreamErrorCode(ErrCodeRequestCanceled))
+			_, err := cl.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).To(MatchError("http3: timeout awaiting response headers"))
+			var nerr net.Error
+			Expect(errors.As(err, &nerr)).To(BeTrue())
+			Expect(nerr.Timeout()).To(BeTrue())
+			Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), scaleDuration(100*time.Millisecond)))
+		})
+
+		It("sets the read deadline for the response body", func() {
+			cl.opts.ResponseHeaderTimeout = time.Second
+			cl.opts.ResponseBodyTimeout = time.Minute
+			rspBuf := bytes.NewBuffer(getResponse(418))
+			conn.EXPECT().HandshakeComplete().Return(handshakeChan)
+			conn.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+			conn.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			var deadlines []time.Time
+			str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { deadlines = append(deadlines, t) }).Times(2)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			rsp, err := cl.RoundTripOpt(req, RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rsp.StatusCode).To(Equal(418))
+			Expect(deadlines).To(HaveLen(2))
+			Expect(deadlines[0]).To(BeTemporally("~", time.Now().Add(time.Second), scaleDuration(100*time.Millisecond)))
+			Expect(deadlines[1]).To(BeTemporally("~", time.Now().Add(time.Minute), scaleDuration(100*time.Millisecond)))
+		})
+
+		It("calls the ClientTrace hooks when the upload is blocked", func() {
+			rspBuf := bytes.NewBuffer(getResponse(200))
+			gomock.InOrder(
+				conn.EXPECT().HandshakeComplete().Return(handshakeChan),
+				conn.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil),
+				conn.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+			)
+			var blockedCallback func(quic.SendBlockedReason, bool)
+			str.EXPECT().SetBlockedCallback(gomock.Any()).Do(func(cb func(quic.SendBlockedReason, bool)) { blockedCallback = cb })
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			var blockedReasons, unblockedReasons []quic.SendBlockedReason
+			var blockedFor time.Duration
+			trace := &ClientTrace{
+				UploadBlocked: func(reason quic.SendBlockedReason) { blockedReasons = append(blockedReasons, reason) },
+				UploadUnblocked: func(reason quic.SendBlockedReason, d time.Duration) {
+					unblockedReasons = append(unblockedReasons, reason)
+					blockedFor = d
+				},
+			}
+			_, err := cl.RoundTripOpt(req.WithContext(WithClientTrace(context.Background(), trace)), RoundTripOpt{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blockedCallback).ToNot(BeNil())
+
+			blockedCallback(quic.SendBlockedConnectionFlowControl, true)
+			Expect(blockedReasons).To(Equal([]quic.SendBlockedReason{quic.SendBlockedConnectionFlowControl}))
+			time.Sleep(scaleDuration(10 * time.Millisecond))
+			blockedCallback(quic.SendBlockedConnectionFlowControl, false)
+			Expect(unblockedReasons).To(Equal([]quic.SendBlockedReason{quic.SendBlockedConnectionFlowControl}))
+			Expect(blockedFor).To(BeNumerically(">=", scaleDuration(10*time.Millisecond)))
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -878,6 +1138,48 @@ var _ = Describe("Client", func() {
 				Expect(err).To(MatchError("HEADERS frame too large: 1338 bytes (max: 1337)"))
 				Eventually(closed).Should(BeClosed())
 			})
+
+			It("cancels the stream when the response has too many header fields", func() {
+				cl.opts.MaxHeaderFields = 2
+				headerBuf := &bytes.Buffer{}
+				enc := qpack.NewEncoder(headerBuf)
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":status", Value: "200"})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: "foo", Value: "bar"})).To(Succeed())
+				Expect(enc.WriteField(qpack.HeaderField{Name: "lorem", Value: "ipsum"})).To(Succeed())
+				Expect(enc.Close()).To(Succeed())
+				b := (&headersFrame{Length: uint64(headerBuf.Len())}).Append(nil)
+				b = append(b, headerBuf.Bytes()...)
+
+				r := bytes.NewReader(b)
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(ErrCodeExcessiveLoad))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				_, err := cl.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("too many header fields: 3 (max: 2)"))
+				Eventually(closed).Should(BeClosed())
+			})
+
+			It("cancels the stream when the response header section is too large", func() {
+				headerBuf := &bytes.Buffer{}
+				enc := qpack.NewEncoder(headerBuf)
+				Expect(enc.WriteField(qpack.HeaderField{Name: ":status", Value: "200"})).To(Succeed())
+				// Huffman encoding compresses this value to less than 1337 bytes
+				Expect(enc.WriteField(qpack.HeaderField{Name: "foo", Value: strings.Repeat("a", 1500)})).To(Succeed())
+				Expect(enc.Close()).To(Succeed())
+				Expect(headerBuf.Len()).To(BeNumerically("<", 1337))
+				b := (&headersFrame{Length: uint64(headerBuf.Len())}).Append(nil)
+				b = append(b, headerBuf.Bytes()...)
+
+				r := bytes.NewReader(b)
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(ErrCodeExcessiveLoad))
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+				_, err := cl.RoundTripOpt(req, RoundTripOpt{})
+				Expect(err).To(MatchError("header section too large: 1577 bytes (max: 1337)"))
+				Eventually(closed).Should(BeClosed())
+			})
 		})
 
 		Context("request cancellations", func() {
@@ -0,0 +1,79 @@
+package http3
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// When compressing a response body, compressed data is flushed to the stream
+// after every chunk of uncompressed data.
+// The chunk size is the stream's available send window, within these bounds.
+const (
+	minCompressionChunkSize = 4 << 10
+	maxCompressionChunkSize = 256 << 10
+)
+
+// A ContentEncoder compresses data written to it.
+type ContentEncoder interface {
+	io.WriteCloser
+	// Flush writes all data written so far to the underlying writer.
+	Flush() error
+}
+
+// A ContentEncoding is a content coding that the server can apply to response bodies.
+type ContentEncoding struct {
+	// Name is the name of the content coding, as used in the Accept-Encoding and Content-Encoding header fields.
+	Name string
+	// NewEncoder creates an encoder that writes the compressed data to w.
+	NewEncoder func(w io.Writer) (ContentEncoder, error)
+}
+
+// GzipEncoding is the gzip content coding, using the default compression level.
+var GzipEncoding = ContentEncoding{
+	Name:       "gzip",
+	NewEncoder: func(w io.Writer) (ContentEncoder, error) { return gzip.NewWriter(w), nil },
+}
+
+// negotiateContentEncoding selects the first of the encodings that is acceptable according to the Accept-Encoding header field.
+// It returns nil if none of the encodings is acceptable.
+func negotiateContentEncoding(acceptEncoding string, encodings []ContentEncoding) *ContentEncoding {
+	if acceptEncoding == "" || len(encodings) == 0 {
+		return nil
+	}
+	accepted := make(map[string]bool)
+	wildcard := false
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		acceptable := true
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q <= 0 {
+				acceptable = false
+			}
+		}
+		if name == "*" {
+			wildcard = acceptable
+			continue
+		}
+		accepted[name] = acceptable
+	}
+	for i, e := range encodings {
+		acceptable, ok := accepted[strings.ToLower(e.Name)]
+		if (ok && acceptable) || (!ok && wildcard) {
+			return &encodings[i]
+		}
+	}
+	return nil
+}
+
+// dataWriter writes DATA frames to the stream of a responseWriter.
+// It is the underlying writer of the ContentEncoder.
+type dataWriter struct{ w *responseWriter }
+
+func (w dataWriter) Write(p []byte) (int, error) { return w.w.writeData(p) }
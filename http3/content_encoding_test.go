@@ -0,0 +1,48 @@
+package http3
+
+import (
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Content Encoding", func() {
+	zstd := ContentEncoding{
+		Name:       "zstd",
+		NewEncoder: func(io.Writer) (ContentEncoder, error) { panic("not implemented") },
+	}
+	encodings := []ContentEncoding{zstd, GzipEncoding}
+
+	negotiate := func(acceptEncoding string) string {
+		e := negotiateContentEncoding(acceptEncoding, encodings)
+		if e == nil {
+			return ""
+		}
+		return e.Name
+	}
+
+	It("selects the encoding preferred by the server", func() {
+		Expect(negotiate("gzip, zstd")).To(Equal("zstd"))
+		Expect(negotiate("gzip")).To(Equal("gzip"))
+		Expect(negotiate("GZIP")).To(Equal("gzip"))
+		Expect(negotiate("br, gzip;q=0.5")).To(Equal("gzip"))
+	})
+
+	It("doesn't select encodings with a q-value of 0", func() {
+		Expect(negotiate("zstd;q=0, gzip")).To(Equal("gzip"))
+		Expect(negotiate("zstd; q=0.0, gzip;q=0")).To(BeEmpty())
+	})
+
+	It("handles wildcards", func() {
+		Expect(negotiate("*")).To(Equal("zstd"))
+		Expect(negotiate("zstd;q=0, *")).To(Equal("gzip"))
+		Expect(negotiate("gzip, *;q=0")).To(Equal("gzip"))
+	})
+
+	It("doesn't select an encoding if the client doesn't accept any", func() {
+		Expect(negotiate("")).To(BeEmpty())
+		Expect(negotiate("identity")).To(BeEmpty())
+		Expect(negotiateContentEncoding("gzip", nil)).To(BeNil())
+	})
+})
@@ -0,0 +1,176 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// The Datagrammer allows sending and receiving HTTP datagrams (RFC 9297) associated with a request.
+// On the client side, it is implemented by the http.Response.Body, if datagrams were enabled on the RoundTripper.
+// Datagrams are not available if the response body was decompressed by the RoundTripper.
+// On the server side, it is stored in the request's context, see DatagrammerContextKey.
+type Datagrammer interface {
+	// SendDatagram sends an HTTP datagram associated with the request.
+	// It returns an error if the peer didn't enable HTTP datagrams.
+	SendDatagram([]byte) error
+	// ReceiveDatagram gets the next HTTP datagram associated with the request.
+	ReceiveDatagram(context.Context) ([]byte, error)
+}
+
+// maxQueuedDatagramsPerStream is the number of datagrams queued per request stream.
+// Datagrams received while the queue is full are dropped.
+const maxQueuedDatagramsPerStream = 32
+
+var errNoDatagrams = errors.New("http3: HTTP datagrams not enabled")
+
+type streamDatagramQueue struct {
+	queue     [][]byte
+	available chan struct{} // a signal is sent when a datagram is queued, and the channel is closed when the stream is removed
+}
+
+// The datagrammer sends and receives HTTP datagrams on a QUIC connection.
+// Received datagrams are demultiplexed to the request streams using the quarter stream ID.
+type datagrammer struct {
+	conn quic.Connection
+
+	settingsOnce     sync.Once
+	settingsReceived chan struct{} // closed when the peer's SETTINGS frame was received
+	peerEnabled      bool          // only valid after settingsReceived is closed
+
+	closed chan struct{} // closed when receiving datagrams failed, err is set before
+
+	mutex   sync.Mutex
+	streams map[quic.StreamID]*streamDatagramQueue
+	err     error
+}
+
+func newDatagrammer(conn quic.Connection) *datagrammer {
+	return &datagrammer{
+		conn:             conn,
+		settingsReceived: make(chan struct{}),
+		closed:           make(chan struct{}),
+		streams:          make(map[quic.StreamID]*streamDatagramQueue),
+	}
+}
+
+// onSettings is called when the peer's SETTINGS frame was received.
+func (d *datagrammer) onSettings(datagramsEnabled bool) {
+	d.settingsOnce.Do(func() {
+		d.peerEnabled = datagramsEnabled
+		close(d.settingsReceived)
+	})
+}
+
+func (d *datagrammer) run() {
+	for {
+		b, err := d.conn.ReceiveMessage(context.Background())
+		if err != nil {
+			d.mutex.Lock()
+			d.err = err
+			d.mutex.Unlock()
+			close(d.closed)
+			return
+		}
+		r := bytes.NewReader(b)
+		quarterStreamID, err := quicvarint.Read(r)
+		if err != nil {
+			d.conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeDatagramError), "invalid quarter stream ID")
+			continue
+		}
+		data := b[len(b)-r.Len():]
+		d.mutex.Lock()
+		// Datagrams for unknown streams are dropped, see section 2.1 of RFC 9297.
+		if q, ok := d.streams[quic.StreamID(4*quarterStreamID)]; ok && len(q.queue) < maxQueuedDatagramsPerStream {
+			q.queue = append(q.queue, data)
+			select {
+			case q.available <- struct{}{}:
+			default:
+			}
+		}
+		d.mutex.Unlock()
+	}
+}
+
+func (d *datagrammer) addStream(id quic.StreamID) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.streams[id] = &streamDatagramQueue{available: make(chan struct{}, 1)}
+}
+
+func (d *datagrammer) removeStream(id quic.StreamID) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if q, ok := d.streams[id]; ok {
+		close(q.available)
+		delete(d.streams, id)
+	}
+}
+
+func (d *datagrammer) send(id quic.StreamID, b []byte) error {
+	select {
+	case <-d.settingsReceived:
+	case <-d.conn.Context().Done():
+		return net.ErrClosed
+	}
+	if !d.peerEnabled {
+		return errors.New("http3: peer didn't enable HTTP datagrams")
+	}
+	data := make([]byte, 0, quicvarint.Len(uint64(id/4))+len(b))
+	data = quicvarint.Append(data, uint64(id/4))
+	data = append(data, b...)
+	return d.conn.SendMessage(data)
+}
+
+func (d *datagrammer) receive(ctx context.Context, id quic.StreamID) ([]byte, error) {
+	for {
+		d.mutex.Lock()
+		q, ok := d.streams[id]
+		if !ok {
+			d.mutex.Unlock()
+			return nil, net.ErrClosed
+		}
+		if len(q.queue) > 0 {
+			b := q.queue[0]
+			q.queue = q.queue[1:]
+			d.mutex.Unlock()
+			return b, nil
+		}
+		if d.err != nil {
+			err := d.err
+			d.mutex.Unlock()
+			return nil, err
+		}
+		d.mutex.Unlock()
+
+		select {
+		case <-q.available:
+		case <-d.closed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// streamDatagrammer sends and receives the datagrams associated with a single request stream.
+type streamDatagrammer struct {
+	datagrams *datagrammer
+	streamID  quic.StreamID
+}
+
+var _ Datagrammer = &streamDatagrammer{}
+
+func (d *streamDatagrammer) SendDatagram(b []byte) error {
+	return d.datagrams.send(d.streamID, b)
+}
+
+func (d *streamDatagrammer) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return d.datagrams.receive(ctx, d.streamID)
+}
@@ -0,0 +1,158 @@
+package http3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	mockquic "github.com/quic-go/quic-go/internal/mocks/quic"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Datagrams", func() {
+	var (
+		conn       *mockquic.MockEarlyConnection
+		d          *datagrammer
+		datagrams  chan []byte
+		cancelConn context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		conn = mockquic.NewMockEarlyConnection(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelConn = cancel
+		conn.EXPECT().Context().Return(ctx).AnyTimes()
+		dgs := make(chan []byte, 10)
+		datagrams = dgs
+		conn.EXPECT().ReceiveMessage(gomock.Any()).DoAndReturn(func(context.Context) ([]byte, error) {
+			select {
+			case b := <-dgs:
+				return b, nil
+			case <-ctx.Done():
+				return nil, errors.New("connection closed")
+			}
+		}).AnyTimes()
+		d = newDatagrammer(conn)
+	})
+
+	AfterEach(func() { cancelConn() })
+
+	run := func() {
+		go d.run()
+	}
+
+	It("demultiplexes datagrams using the quarter stream ID", func() {
+		d.addStream(0)
+		d.addStream(8)
+		run()
+		datagrams <- append(quicvarint.Append(nil, 2), []byte("foo")...)
+		datagrams <- append(quicvarint.Append(nil, 0), []byte("bar")...)
+		datagrams <- append(quicvarint.Append(nil, 2), []byte("baz")...)
+
+		b, err := d.receive(context.Background(), 8)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("foo")))
+		b, err = d.receive(context.Background(), 8)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("baz")))
+		b, err = d.receive(context.Background(), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("bar")))
+	})
+
+	It("drops datagrams for unknown streams", func() {
+		d.addStream(4)
+		run()
+		datagrams <- append(quicvarint.Append(nil, 0), []byte("foo")...)
+		datagrams <- append(quicvarint.Append(nil, 1), []byte("bar")...)
+		b, err := d.receive(context.Background(), 4)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte("bar")))
+	})
+
+	It("drops datagrams when the queue is full", func() {
+		d.addStream(4)
+		run()
+		for i := 0; i < maxQueuedDatagramsPerStream+5; i++ {
+			datagrams <- append(quicvarint.Append(nil, 1), byte(i))
+		}
+		Eventually(datagrams).Should(BeEmpty())
+		time.Sleep(scaleDuration(10 * time.Millisecond)) // wait for the last datagram to be processed
+		for i := 0; i < maxQueuedDatagramsPerStream; i++ {
+			b, err := d.receive(context.Background(), 4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte{byte(i)}))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+		defer cancel()
+		_, err := d.receive(ctx, 4)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("closes the connection when receiving a datagram without a quarter stream ID", func() {
+		done := make(chan struct{})
+		conn.EXPECT().CloseWithError(quic.ApplicationErrorCode(ErrCodeDatagramError), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) {
+			close(done)
+		})
+		run()
+		datagrams <- []byte{}
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("returns an error when receiving on a removed stream", func() {
+		d.addStream(4)
+		run()
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := d.receive(context.Background(), 4)
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		d.removeStream(4)
+		Eventually(errChan).Should(Receive(MatchError(net.ErrClosed)))
+	})
+
+	It("returns an error when receiving datagrams fails", func() {
+		d.addStream(4)
+		run()
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := d.receive(context.Background(), 4)
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		cancelConn()
+		Eventually(errChan).Should(Receive(MatchError("connection closed")))
+	})
+
+	It("sends datagrams with the quarter stream ID", func() {
+		d.onSettings(true)
+		conn.EXPECT().SendMessage(append(quicvarint.Append(nil, 3), []byte("foobar")...))
+		Expect(d.send(12, []byte("foobar"))).To(Succeed())
+	})
+
+	It("waits for the SETTINGS before sending datagrams", func() {
+		errChan := make(chan error, 1)
+		go func() { errChan <- d.send(4, []byte("foobar")) }()
+		Consistently(errChan).ShouldNot(Receive())
+		conn.EXPECT().SendMessage(gomock.Any())
+		d.onSettings(true)
+		Eventually(errChan).Should(Receive(BeNil()))
+	})
+
+	It("refuses to send datagrams if the peer didn't enable them", func() {
+		d.onSettings(false)
+		Expect(d.send(4, []byte("foobar"))).To(MatchError("http3: peer didn't enable HTTP datagrams"))
+	})
+
+	It("doesn't block sending when the connection is closed before the SETTINGS are received", func() {
+		cancelConn()
+		Expect(d.send(4, []byte("foobar"))).To(MatchError(net.ErrClosed))
+	})
+})
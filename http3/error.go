@@ -3,6 +3,7 @@ package http3
 import (
 	"errors"
 	"fmt"
+	"net"
 
 	"github.com/quic-go/quic-go"
 )
@@ -56,3 +57,20 @@ func maybeReplaceError(err error) error {
 	}
 	return &e
 }
+
+type timeoutError struct{ msg string }
+
+var _ net.Error = &timeoutError{}
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// errResponseHeaderTimeout is returned by the client if the response headers weren't received in time.
+var errResponseHeaderTimeout error = &timeoutError{msg: "http3: timeout awaiting response headers"}
+
+// isTimeoutError says if the error was caused by an expired stream deadline.
+func isTimeoutError(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/quicvarint"
 )
 
@@ -75,8 +74,7 @@ type dataFrame struct {
 }
 
 func (f *dataFrame) Append(b []byte) []byte {
-	b = quicvarint.Append(b, 0x0)
-	return quicvarint.Append(b, f.Length)
+	return quicvarint.AppendFrameHeader(b, 0x0, f.Length)
 }
 
 type headersFrame struct {
@@ -84,15 +82,18 @@ type headersFrame struct {
 }
 
 func (f *headersFrame) Append(b []byte) []byte {
-	b = quicvarint.Append(b, 0x1)
-	return quicvarint.Append(b, f.Length)
+	return quicvarint.AppendFrameHeader(b, 0x1, f.Length)
 }
 
-const settingDatagram = 0x33
+const (
+	settingMaxFieldSectionSize = 0x6
+	settingDatagram            = 0x33
+)
 
 type settingsFrame struct {
-	Datagram bool
-	Other    map[uint64]uint64 // all settings that we don't explicitly recognize
+	Datagram            bool
+	MaxFieldSectionSize uint64            // 0 if not set
+	Other               map[uint64]uint64 // all settings that we don't explicitly recognize
 }
 
 func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
@@ -108,7 +109,7 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 	}
 	frame := &settingsFrame{}
 	b := bytes.NewReader(buf)
-	var readDatagram bool
+	var readDatagram, readMaxFieldSectionSize bool
 	for b.Len() > 0 {
 		id, err := quicvarint.Read(b)
 		if err != nil { // should not happen. We allocated the whole frame already.
@@ -129,6 +130,12 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 				return nil, fmt.Errorf("invalid value for H3_DATAGRAM: %d", val)
 			}
 			frame.Datagram = val == 1
+		case settingMaxFieldSectionSize:
+			if readMaxFieldSectionSize {
+				return nil, fmt.Errorf("duplicate setting: %d", id)
+			}
+			readMaxFieldSectionSize = true
+			frame.MaxFieldSectionSize = val
 		default:
 			if _, ok := frame.Other[id]; ok {
 				return nil, fmt.Errorf("duplicate setting: %d", id)
@@ -144,18 +151,25 @@ func parseSettingsFrame(r io.Reader, l uint64) (*settingsFrame, error) {
 
 func (f *settingsFrame) Append(b []byte) []byte {
 	b = quicvarint.Append(b, 0x4)
-	var l protocol.ByteCount
+	var l int
 	for id, val := range f.Other {
 		l += quicvarint.Len(id) + quicvarint.Len(val)
 	}
 	if f.Datagram {
 		l += quicvarint.Len(settingDatagram) + quicvarint.Len(1)
 	}
+	if f.MaxFieldSectionSize > 0 {
+		l += quicvarint.Len(settingMaxFieldSectionSize) + quicvarint.Len(f.MaxFieldSectionSize)
+	}
 	b = quicvarint.Append(b, uint64(l))
 	if f.Datagram {
 		b = quicvarint.Append(b, settingDatagram)
 		b = quicvarint.Append(b, 1)
 	}
+	if f.MaxFieldSectionSize > 0 {
+		b = quicvarint.Append(b, settingMaxFieldSectionSize)
+		b = quicvarint.Append(b, f.MaxFieldSectionSize)
+	}
 	for id, val := range f.Other {
 		b = quicvarint.Append(b, id)
 		b = quicvarint.Append(b, val)
@@ -170,6 +170,41 @@ var _ = Describe("Frames", func() {
 				Expect(frame).To(Equal(sf))
 			})
 		})
+
+		Context("SETTINGS_MAX_FIELD_SECTION_SIZE", func() {
+			It("reads the SETTINGS_MAX_FIELD_SECTION_SIZE value", func() {
+				settings := quicvarint.Append(nil, settingMaxFieldSectionSize)
+				settings = quicvarint.Append(settings, 1337)
+				data := quicvarint.Append(nil, 4) // type byte
+				data = quicvarint.Append(data, uint64(len(settings)))
+				data = append(data, settings...)
+				f, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				sf := f.(*settingsFrame)
+				Expect(sf.MaxFieldSectionSize).To(BeEquivalentTo(1337))
+				Expect(sf.Other).To(BeEmpty())
+			})
+
+			It("rejects duplicate SETTINGS_MAX_FIELD_SECTION_SIZE entries", func() {
+				settings := quicvarint.Append(nil, settingMaxFieldSectionSize)
+				settings = quicvarint.Append(settings, 1337)
+				settings = quicvarint.Append(settings, settingMaxFieldSectionSize)
+				settings = quicvarint.Append(settings, 1338)
+				data := quicvarint.Append(nil, 4) // type byte
+				data = quicvarint.Append(data, uint64(len(settings)))
+				data = append(data, settings...)
+				_, err := parseNextFrame(bytes.NewReader(data), nil)
+				Expect(err).To(MatchError(fmt.Sprintf("duplicate setting: %d", settingMaxFieldSectionSize)))
+			})
+
+			It("writes the SETTINGS_MAX_FIELD_SECTION_SIZE setting", func() {
+				sf := &settingsFrame{MaxFieldSectionSize: 1 << 20, Datagram: true, Other: map[uint64]uint64{99: 999}}
+				frame, err := parseNextFrame(bytes.NewReader(sf.Append(nil)), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame).To(Equal(sf))
+			})
+		})
 	})
 
 	Context("hijacking", func() {
@@ -101,6 +101,23 @@ func parseHeaders(headers []qpack.HeaderField, isRequest bool) (header, error) {
 	return hdr, nil
 }
 
+// checkFieldSection checks that a field section doesn't exceed the configured limits.
+// The size of a field section is calculated as defined in section 4.2.2 of RFC 9114.
+// If maxFields is zero or negative, the number of fields is not limited.
+func checkFieldSection(headerFields []qpack.HeaderField, maxSize uint64, maxFields int) error {
+	if maxFields > 0 && len(headerFields) > maxFields {
+		return fmt.Errorf("too many header fields: %d (max: %d)", len(headerFields), maxFields)
+	}
+	var size uint64
+	for _, hf := range headerFields {
+		size += uint64(len(hf.Name)+len(hf.Value)) + 32
+	}
+	if size > maxSize {
+		return fmt.Errorf("header section too large: %d bytes (max: %d)", size, maxSize)
+	}
+	return nil
+}
+
 func requestFromHeaders(headerFields []qpack.HeaderField) (*http.Request, error) {
 	hdr, err := parseHeaders(headerFields, true)
 	if err != nil {
@@ -355,3 +355,23 @@ var _ = Describe("Response", func() {
 		Expect(err).To(MatchError("invalid response pseudo header: :method"))
 	})
 })
+
+var _ = Describe("Field section limits", func() {
+	headers := []qpack.HeaderField{
+		{Name: ":status", Value: "200"},   // 32 + 7 + 3 bytes
+		{Name: "foo", Value: "foobarbaz"}, // 32 + 3 + 9 bytes
+	}
+
+	It("accepts field sections within the limits", func() {
+		Expect(checkFieldSection(headers, 86, 2)).To(Succeed())
+		Expect(checkFieldSection(headers, 86, 0)).To(Succeed())
+	})
+
+	It("rejects field sections that are too large", func() {
+		Expect(checkFieldSection(headers, 85, 0)).To(MatchError("header section too large: 86 bytes (max: 85)"))
+	})
+
+	It("rejects field sections with too many fields", func() {
+		Expect(checkFieldSection(headers, 1000, 1)).To(MatchError("too many header fields: 2 (max: 1)"))
+	})
+})
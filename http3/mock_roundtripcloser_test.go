@@ -9,9 +9,11 @@
 package http3
 
 import (
+	context "context"
 	http "net/http"
 	reflect "reflect"
 
+	quic "github.com/quic-go/quic-go"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -52,6 +54,21 @@ func (mr *MockRoundTripCloserMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRoundTripCloser)(nil).Close))
 }
 
+// Connection mocks base method.
+func (m *MockRoundTripCloser) Connection(arg0 context.Context) (quic.EarlyConnection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Connection", arg0)
+	ret0, _ := ret[0].(quic.EarlyConnection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Connection indicates an expected call of Connection.
+func (mr *MockRoundTripCloserMockRecorder) Connection(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connection", reflect.TypeOf((*MockRoundTripCloser)(nil).Connection), arg0)
+}
+
 // HandshakeComplete mocks base method.
 func (m *MockRoundTripCloser) HandshakeComplete() bool {
 	m.ctrl.T.Helper()
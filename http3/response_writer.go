@@ -70,6 +70,14 @@ type responseWriter struct {
 	headerWritten bool
 	contentLen    int64 // if handler set valid Content-Length header
 	numWritten    int64 // bytes written
+
+	// encoding is the content coding negotiated for the response, if any.
+	// It is only applied if the handler doesn't set a Content-Encoding itself.
+	encoding    *ContentEncoding
+	compressing bool
+	encoder     ContentEncoder // created on the first Write, if compressing
+	// the number of bytes written to the encoder since it was last flushed
+	pendingCompressed int
 }
 
 var (
@@ -108,6 +116,14 @@ func (w *responseWriter) WriteHeader(status int) {
 
 	if status >= 200 {
 		w.headerWritten = true
+		if w.encoding != nil && w.header.Get("Content-Encoding") == "" && w.header.Get("Content-Range") == "" &&
+			status != http.StatusPartialContent && bodyAllowedForStatus(status) {
+			w.compressing = true
+			w.header.Set("Content-Encoding", w.encoding.Name)
+			w.header.Add("Vary", "Accept-Encoding")
+			// the length of the compressed body is not known in advance
+			w.header.Del("Content-Length")
+		}
 		// Add Date header.
 		// This is what the standard library does.
 		// Can be disabled by setting the Date header to nil.
@@ -156,7 +172,14 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	if !bodyAllowed {
 		return 0, http.ErrBodyNotAllowed
 	}
+	if w.compressing {
+		return w.writeCompressed(p)
+	}
+	return w.writeData(p)
+}
 
+// writeData writes p in a DATA frame.
+func (w *responseWriter) writeData(p []byte) (int, error) {
 	w.numWritten += int64(len(p))
 	if w.contentLen != 0 && w.numWritten > w.contentLen {
 		return 0, http.ErrContentLength
@@ -172,10 +195,72 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	return n, maybeReplaceError(err)
 }
 
+// writeCompressed compresses p.
+// The compressed data is flushed to the stream in chunks sized to the stream's available send window.
+// Writing to the stream blocks while the stream is blocked by flow control,
+// so no more than one chunk of data is held in memory when the peer reads slowly.
+func (w *responseWriter) writeCompressed(p []byte) (int, error) {
+	if w.encoder == nil {
+		enc, err := w.encoding.NewEncoder(dataWriter{w: w})
+		if err != nil {
+			return 0, err
+		}
+		w.encoder = enc
+	}
+	var n int
+	for len(p) > 0 {
+		chunkSize := int(utils.Min(utils.Max(w.str.AvailableSendWindow(), minCompressionChunkSize), maxCompressionChunkSize))
+		l := utils.Min(len(p), utils.Max(chunkSize-w.pendingCompressed, 1))
+		nn, err := w.encoder.Write(p[:l])
+		n += nn
+		if err != nil {
+			return n, maybeReplaceError(err)
+		}
+		p = p[l:]
+		w.pendingCompressed += l
+		if w.pendingCompressed >= chunkSize {
+			if err := w.encoder.Flush(); err != nil {
+				return n, maybeReplaceError(err)
+			}
+			w.pendingCompressed = 0
+		}
+	}
+	return n, nil
+}
+
+// closeBody finishes the compressed body, if the response is compressed.
+// It must be called after the handler returned.
+func (w *responseWriter) closeBody() error {
+	if !w.compressing {
+		return nil
+	}
+	if w.encoder == nil {
+		// Nothing was written. If the header wasn't sent yet, the response can be sent uncompressed.
+		if !w.written {
+			w.compressing = false
+			w.header.Del("Content-Encoding")
+			return nil
+		}
+		enc, err := w.encoding.NewEncoder(dataWriter{w: w})
+		if err != nil {
+			return err
+		}
+		w.encoder = enc
+	}
+	w.pendingCompressed = 0
+	return maybeReplaceError(w.encoder.Close())
+}
+
 func (w *responseWriter) FlushError() error {
 	if !w.headerWritten {
 		w.WriteHeader(http.StatusOK)
 	}
+	if w.encoder != nil && w.pendingCompressed > 0 {
+		if err := w.encoder.Flush(); err != nil {
+			return maybeReplaceError(err)
+		}
+		w.pendingCompressed = 0
+	}
 	if !w.written {
 		if err := w.writeHeader(); err != nil {
 			return maybeReplaceError(err)
@@ -2,6 +2,8 @@ package http3
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/rand"
 	"io"
 	"net/http"
 	"time"
@@ -183,4 +185,123 @@ var _ = Describe("Response Writer", func() {
 		Expect(func() { rw.WriteHeader(99) }).To(Panic())
 		Expect(func() { rw.WriteHeader(1000) }).To(Panic())
 	})
+
+	Context("compression", func() {
+		var sendWindow uint64
+
+		BeforeEach(func() {
+			sendWindow = 0
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(strBuf.Write).AnyTimes()
+			str.EXPECT().AvailableSendWindow().DoAndReturn(func() uint64 { return sendWindow }).AnyTimes()
+			rw = newResponseWriter(str, nil, utils.DefaultLogger)
+			rw.encoding = &GzipEncoding
+		})
+
+		getBody := func(str io.Reader) []byte {
+			var body []byte
+			for {
+				frame, err := parseNextFrame(str, nil)
+				if err == io.EOF {
+					return body
+				}
+				Expect(err).ToNot(HaveOccurred())
+				df := frame.(*dataFrame)
+				data := make([]byte, df.Length)
+				_, err = io.ReadFull(str, data)
+				Expect(err).ToNot(HaveOccurred())
+				body = append(body, data...)
+			}
+		}
+
+		decompress := func(b []byte) []byte {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			return data
+		}
+
+		It("compresses the body", func() {
+			rw.Header().Set("Content-Length", "12")
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rw.Write([]byte("raboof"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.closeBody()).To(Succeed())
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("content-encoding", []string{"gzip"}))
+			Expect(fields).To(HaveKeyWithValue("vary", []string{"Accept-Encoding"}))
+			Expect(fields).To(HaveKeyWithValue("content-type", []string{"text/plain; charset=utf-8"}))
+			Expect(fields).ToNot(HaveKey("content-length"))
+			Expect(decompress(getBody(strBuf))).To(Equal([]byte("foobarraboof")))
+		})
+
+		It("flushes compressed data when a chunk of the size of the send window was written", func() {
+			sendWindow = 10 << 10
+			data := make([]byte, 25<<10)
+			rand.Read(data)
+			_, err := rw.Write(data[:8<<10])
+			Expect(err).ToNot(HaveOccurred())
+			// the compressed data is still buffered
+			Expect(strBuf.Len()).To(BeZero())
+			_, err = rw.Write(data[8<<10:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strBuf.Len()).To(BeNumerically(">", 10<<10))
+			Expect(rw.closeBody()).To(Succeed())
+			decodeHeader(strBuf)
+			Expect(decompress(getBody(strBuf))).To(Equal(data))
+		})
+
+		It("flushes the compressed data when the handler flushes", func() {
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(strBuf.Len()).To(BeZero())
+			rw.Flush()
+			decodeHeader(strBuf)
+			body := getBody(strBuf)
+			r, err := gzip.NewReader(bytes.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, 6)
+			_, err = io.ReadFull(r, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't compress empty bodies", func() {
+			rw.WriteHeader(http.StatusOK)
+			Expect(rw.closeBody()).To(Succeed())
+			fields := decodeHeader(strBuf)
+			Expect(fields).ToNot(HaveKey("content-encoding"))
+		})
+
+		It("writes a valid compressed body if the header was flushed before", func() {
+			rw.WriteHeader(http.StatusOK)
+			rw.Flush()
+			Expect(rw.closeBody()).To(Succeed())
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("content-encoding", []string{"gzip"}))
+			Expect(decompress(getBody(strBuf))).To(BeEmpty())
+		})
+
+		It("doesn't compress if the handler set a Content-Encoding", func() {
+			rw.Header().Set("Content-Encoding", "br")
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.closeBody()).To(Succeed())
+			fields := decodeHeader(strBuf)
+			Expect(fields).To(HaveKeyWithValue("content-encoding", []string{"br"}))
+			Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		})
+
+		It("doesn't compress partial content", func() {
+			rw.WriteHeader(http.StatusPartialContent)
+			_, err := rw.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rw.closeBody()).To(Succeed())
+			fields := decodeHeader(strBuf)
+			Expect(fields).ToNot(HaveKey("content-encoding"))
+			Expect(getData(strBuf)).To(Equal([]byte("foobar")))
+		})
+	})
 })
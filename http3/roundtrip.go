@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"golang.org/x/net/http/httpguts"
 
@@ -19,6 +20,7 @@ import (
 
 type roundTripCloser interface {
 	RoundTripOpt(*http.Request, RoundTripOpt) (*http.Response, error)
+	Connection(context.Context) (quic.EarlyConnection, error)
 	HandshakeComplete() bool
 	io.Closer
 }
@@ -53,6 +55,8 @@ type RoundTripper struct {
 	// Enable support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
 	// See https://datatracker.ietf.org/doc/html/rfc9297.
+	// Datagrams associated with a request are sent and received using the Datagrammer
+	// implemented by the http.Response.Body.
 	EnableDatagrams bool
 
 	// Additional HTTP/3 settings.
@@ -73,6 +77,14 @@ type RoundTripper struct {
 	// In that case, the stream type will not be set.
 	UniStreamHijacker func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
 
+	// UniStreamHandlers registers handlers for unidirectional streams of the given stream types.
+	// This allows a sibling protocol (negotiated by the application) to use the HTTP/3 connection,
+	// see StreamCreator for opening streams.
+	// The handler is called after the stream type was read, and is responsible for the stream from then on.
+	// Handlers take precedence over the UniStreamHijacker.
+	// It is invalid to register a handler for a stream type defined by HTTP/3 or QPACK.
+	UniStreamHandlers map[StreamType]func(quic.Connection, quic.ReceiveStream)
+
 	// Dial specifies an optional dial function for creating QUIC
 	// connections for requests.
 	// If Dial is nil, a UDPConn will be created at the first request
@@ -82,8 +94,25 @@ type RoundTripper struct {
 	// MaxResponseHeaderBytes specifies a limit on how many response bytes are
 	// allowed in the server's response header.
 	// Zero means to use a default limit.
+	// It is advertised to the server using the SETTINGS_MAX_FIELD_SECTION_SIZE setting.
 	MaxResponseHeaderBytes int64
 
+	// MaxResponseHeaderFields is the maximum number of header fields (including pseudo-header fields)
+	// allowed in the server's response header.
+	// If zero or negative, the number of header fields is not limited.
+	MaxResponseHeaderFields int
+
+	// ResponseHeaderTimeout, if non-zero, specifies the amount of time to wait for the server's
+	// response headers after writing the request headers.
+	// If it expires, the request is canceled with H3_REQUEST_CANCELLED, and RoundTrip returns a timeout error.
+	ResponseHeaderTimeout time.Duration
+
+	// ResponseBodyTimeout, if non-zero, specifies the amount of time allowed to receive the full
+	// response body, starting when the response headers were received.
+	// Once it expires, reading the response body returns a timeout error.
+	// Closing the body then cancels the request with H3_REQUEST_CANCELLED.
+	ResponseBodyTimeout time.Duration
+
 	newClient func(hostname string, tlsConf *tls.Config, opts *roundTripperOpts, conf *quic.Config, dialer dialFunc) (roundTripCloser, error) // so we can mock it in tests
 	clients   map[string]*roundTripCloserWithCount
 	transport *quic.Transport
@@ -164,6 +193,28 @@ func (r *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return r.RoundTripOpt(req, RoundTripOpt{})
 }
 
+// StreamCreator returns the QUIC connection used for requests to authority (host:port),
+// dialing a new connection if necessary. It blocks until the handshake completes.
+// This allows opening additional QUIC streams for a sibling protocol on the HTTP/3 connection.
+// Unidirectional streams must start with a stream type, and bidirectional streams with a frame type,
+// that is not used by HTTP/3 (see section 9 of RFC 9114).
+// Streams opened by the server can be handled using UniStreamHandlers and StreamHijacker.
+// Note that CloseIdleConnections closes the connection if there are no requests in flight.
+func (r *RoundTripper) StreamCreator(ctx context.Context, authority string) (StreamCreator, error) {
+	hostname := authorityAddr("https", authority)
+	cl, _, err := r.getClient(hostname, false)
+	if err != nil {
+		return nil, err
+	}
+	defer cl.useCount.Add(-1)
+	conn, err := cl.Connection(ctx)
+	if err != nil {
+		r.removeClient(hostname)
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (r *RoundTripper) getClient(hostname string, onlyCached bool) (rtc *roundTripCloserWithCount, isReused bool, err error) {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
@@ -197,11 +248,15 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (rtc *roundTr
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
-				StreamHijacker:     r.StreamHijacker,
-				UniStreamHijacker:  r.UniStreamHijacker,
+				EnableDatagram:        r.EnableDatagrams,
+				DisableCompression:    r.DisableCompression,
+				MaxHeaderBytes:        r.MaxResponseHeaderBytes,
+				MaxHeaderFields:       r.MaxResponseHeaderFields,
+				ResponseHeaderTimeout: r.ResponseHeaderTimeout,
+				ResponseBodyTimeout:   r.ResponseBodyTimeout,
+				StreamHijacker:        r.StreamHijacker,
+				UniStreamHijacker:     r.UniStreamHijacker,
+				UniStreamHandlers:     r.UniStreamHandlers,
 			},
 			r.QuicConfig,
 			dial,
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go"
+	mockquic "github.com/quic-go/quic-go/internal/mocks/quic"
 	"github.com/quic-go/quic-go/internal/qerr"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -239,6 +240,44 @@ var _ = Describe("RoundTripper", func() {
 		})
 	})
 
+	Context("opening streams on the HTTP/3 connection", func() {
+		It("returns the connection of the client used for requests", func() {
+			conn := mockquic.NewMockEarlyConnection(mockCtrl)
+			var count int
+			rt.newClient = func(hostname string, _ *tls.Config, _ *roundTripperOpts, _ *quic.Config, _ dialFunc) (roundTripCloser, error) {
+				count++
+				Expect(hostname).To(Equal("quic.clemente.io:443"))
+				cl := NewMockRoundTripCloser(mockCtrl)
+				cl.EXPECT().Connection(gomock.Any()).Return(conn, nil).Times(2)
+				cl.EXPECT().HandshakeComplete().Return(true)
+				return cl, nil
+			}
+			str, err := rt.StreamCreator(context.Background(), "quic.clemente.io")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(conn))
+			str, err = rt.StreamCreator(context.Background(), "quic.clemente.io:443")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(conn))
+			Expect(count).To(Equal(1))
+		})
+
+		It("removes the client when dialing fails", func() {
+			testErr := errors.New("handshake error")
+			var count int
+			rt.newClient = func(string, *tls.Config, *roundTripperOpts, *quic.Config, dialFunc) (roundTripCloser, error) {
+				count++
+				cl := NewMockRoundTripCloser(mockCtrl)
+				cl.EXPECT().Connection(gomock.Any()).Return(nil, testErr)
+				return cl, nil
+			}
+			_, err := rt.StreamCreator(context.Background(), "quic.clemente.io")
+			Expect(err).To(MatchError(testErr))
+			_, err = rt.StreamCreator(context.Background(), "quic.clemente.io")
+			Expect(err).To(MatchError(testErr))
+			Expect(count).To(Equal(2))
+		})
+	})
+
 	Context("validating request", func() {
 		It("rejects plain HTTP requests", func() {
 			req, err := http.NewRequest("GET", "http://www.example.org/", nil)
@@ -115,6 +115,20 @@ func (k *contextKey) String() string { return "quic-go/http3 context value " + k
 // type *http3.Server.
 var ServerContextKey = &contextKey{"http3-server"}
 
+// DatagrammerContextKey is a context key. It can be used in HTTP handlers with Context.Value
+// to send and receive HTTP datagrams (RFC 9297) associated with the request.
+// The associated value will be of type Datagrammer.
+// It is only set if EnableDatagrams is set on the Server.
+var DatagrammerContextKey = &contextKey{"http3-datagrammer"}
+
+// EarlyDataContextKey is a context key. It can be used in HTTP handlers with Context.Value
+// to find out if the request was received in 0-RTT data, which can be replayed by an attacker
+// (see section 8 of RFC 9001).
+// The associated value will be of type <-chan struct{}. The channel is closed when the handshake completes.
+// Handlers should wait for this before performing non-idempotent operations.
+// It is only set if (parts of) the request were received in 0-RTT.
+var EarlyDataContextKey = &contextKey{"http3-early-data"}
+
 type requestError struct {
 	err       error
 	streamErr ErrCode
@@ -173,6 +187,8 @@ type Server struct {
 
 	// EnableDatagrams enables support for HTTP/3 datagrams.
 	// If set to true, QuicConfig.EnableDatagram will be set.
+	// Handlers can send and receive datagrams associated with a request using the Datagrammer
+	// stored in the request's context, see DatagrammerContextKey.
 	// See https://datatracker.ietf.org/doc/html/rfc9297.
 	EnableDatagrams bool
 
@@ -180,8 +196,36 @@ type Server struct {
 	// read parsing the request HEADERS frame. It does not limit the size of
 	// the request body. If zero or negative, http.DefaultMaxHeaderBytes is
 	// used.
+	// It is also the maximum size of the request header section (as defined in section 4.2.2 of RFC 9114),
+	// which is advertised to the client using the SETTINGS_MAX_FIELD_SECTION_SIZE setting.
+	// Requests exceeding it are rejected with a 431 (Request Header Fields Too Large) response.
 	MaxHeaderBytes int
 
+	// MaxHeaderFields is the maximum number of header fields (including pseudo-header fields) of a request.
+	// Requests exceeding it are rejected with a 431 (Request Header Fields Too Large) response.
+	// If zero or negative, the number of header fields is not limited.
+	MaxHeaderFields int
+
+	// ReadHeaderTimeout is the amount of time allowed to read the request headers,
+	// starting when the request stream is accepted.
+	// If the headers are not received in time, the stream is reset with H3_REQUEST_REJECTED.
+	// If zero, the value of ReadTimeout is used. If both are zero, there is no timeout.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the maximum duration for reading the entire request, including the body,
+	// starting when the request stream is accepted.
+	// Once it expires, reading the request body returns a timeout error,
+	// and the stream is reset with H3_REQUEST_INCOMPLETE.
+	// If zero, there is no timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the maximum duration for writing the response,
+	// starting when the request headers were read.
+	// Once it expires, writing the response returns a timeout error,
+	// and the stream is reset with H3_REQUEST_CANCELLED.
+	// If zero, there is no timeout.
+	WriteTimeout time.Duration
+
 	// AdditionalSettings specifies additional HTTP/3 settings.
 	// It is invalid to specify any settings defined by the HTTP/3 draft and the datagram draft.
 	AdditionalSettings map[uint64]uint64
@@ -200,6 +244,14 @@ type Server struct {
 	// In that case, the stream type will not be set.
 	UniStreamHijacker func(StreamType, quic.Connection, quic.ReceiveStream, error) (hijacked bool)
 
+	// ContentEncodings enables compression of response bodies, in order of preference (e.g. GzipEncoding).
+	// A response body is compressed using the first encoding that the request's Accept-Encoding header field accepts,
+	// unless the handler sets the Content-Encoding header field itself.
+	// Compressed data is flushed to the stream in chunks sized to the stream's available send window,
+	// so that large amounts of compressed data aren't buffered in memory when the client reads slowly.
+	// Other codings, e.g. zstd, can be used by providing a ContentEncoding that wraps a third-party encoder.
+	ContentEncodings []ContentEncoding
+
 	mutex     sync.RWMutex
 	listeners map[*QUICEarlyListener]listenerInfo
 
@@ -434,10 +486,20 @@ func (s *Server) handleConn(conn quic.Connection) error {
 	}
 	b := make([]byte, 0, 64)
 	b = quicvarint.Append(b, streamTypeControlStream) // stream type
-	b = (&settingsFrame{Datagram: s.EnableDatagrams, Other: s.AdditionalSettings}).Append(b)
+	b = (&settingsFrame{
+		Datagram:            s.EnableDatagrams,
+		MaxFieldSectionSize: s.maxHeaderBytes(),
+		Other:               s.AdditionalSettings,
+	}).Append(b)
 	str.Write(b)
 
-	go s.handleUnidirectionalStreams(conn)
+	var datagrams *datagrammer
+	if s.EnableDatagrams {
+		datagrams = newDatagrammer(conn)
+		go datagrams.run()
+	}
+
+	go s.handleUnidirectionalStreams(conn, datagrams)
 
 	// Process all requests immediately.
 	// It's the client's responsibility to decide which requests are eligible for 0-RTT.
@@ -451,7 +513,7 @@ func (s *Server) handleConn(conn quic.Connection) error {
 			return fmt.Errorf("accepting stream failed: %w", err)
 		}
 		go func() {
-			rerr := s.handleRequest(conn, str, decoder, func() {
+			rerr := s.handleRequest(conn, str, datagrams, decoder, func() {
 				conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeFrameUnexpected), "")
 			})
 			if rerr.err == errHijacked {
@@ -476,7 +538,7 @@ func (s *Server) handleConn(conn quic.Connection) error {
 	}
 }
 
-func (s *Server) handleUnidirectionalStreams(conn quic.Connection) {
+func (s *Server) handleUnidirectionalStreams(conn quic.Connection, datagrams *datagrammer) {
 	for {
 		str, err := conn.AcceptUniStream(context.Background())
 		if err != nil {
@@ -520,6 +582,9 @@ func (s *Server) handleUnidirectionalStreams(conn quic.Connection) {
 				conn.CloseWithError(quic.ApplicationErrorCode(ErrCodeMissingSettings), "")
 				return
 			}
+			if datagrams != nil {
+				datagrams.onSettings(sf.Datagram)
+			}
 			if !sf.Datagram {
 				return
 			}
@@ -540,7 +605,23 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
+func (s *Server) readHeaderTimeout() time.Duration {
+	if s.ReadHeaderTimeout > 0 {
+		return s.ReadHeaderTimeout
+	}
+	return s.ReadTimeout
+}
+
+func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, datagrams *datagrammer, decoder *qpack.Decoder, onFrameError func()) requestError {
+	if datagrams != nil {
+		// Datagrams might arrive before the request has been parsed.
+		datagrams.addStream(str.StreamID())
+		defer datagrams.removeStream(str.StreamID())
+	}
+	start := time.Now()
+	if d := s.readHeaderTimeout(); d > 0 {
+		str.SetReadDeadline(start.Add(d))
+	}
 	var ufh unknownFrameHandlerFunc
 	if s.StreamHijacker != nil {
 		ufh = func(ft FrameType, e error) (processed bool, err error) { return s.StreamHijacker(ft, conn, str, e) }
@@ -550,6 +631,9 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		if err == errHijacked {
 			return requestError{err: errHijacked}
 		}
+		if s.readHeaderTimeout() > 0 && isTimeoutError(err) {
+			return newStreamError(ErrCodeRequestRejected, err)
+		}
 		return newStreamError(ErrCodeRequestIncomplete, err)
 	}
 	hf, ok := frame.(*headersFrame)
@@ -561,6 +645,9 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	}
 	headerBlock := make([]byte, hf.Length)
 	if _, err := io.ReadFull(str, headerBlock); err != nil {
+		if s.readHeaderTimeout() > 0 && isTimeoutError(err) {
+			return newStreamError(ErrCodeRequestRejected, err)
+		}
 		return newStreamError(ErrCodeRequestIncomplete, err)
 	}
 	hfs, err := decoder.DecodeFull(headerBlock)
@@ -568,6 +655,25 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 		// TODO: use the right error code
 		return newConnError(ErrCodeGeneralProtocolError, err)
 	}
+	// The header timeout doesn't apply to the request body.
+	var readDeadline time.Time
+	if s.ReadTimeout > 0 {
+		readDeadline = start.Add(s.ReadTimeout)
+	}
+	if s.ReadHeaderTimeout > 0 || s.ReadTimeout > 0 {
+		str.SetReadDeadline(readDeadline)
+	}
+	if s.WriteTimeout > 0 {
+		str.SetWriteDeadline(time.Now().Add(s.WriteTimeout))
+	}
+	if err := checkFieldSection(hfs, s.maxHeaderBytes(), s.MaxHeaderFields); err != nil {
+		s.logger.Debugf("Rejecting request: %s", err)
+		r := newResponseWriter(str, conn, s.logger)
+		r.WriteHeader(http.StatusRequestHeaderFieldsTooLarge)
+		r.Flush()
+		str.CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+		return requestError{}
+	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		return newStreamError(ErrCodeMessageError, err)
@@ -597,8 +703,20 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 	ctx := str.Context()
 	ctx = context.WithValue(ctx, ServerContextKey, s)
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, conn.LocalAddr())
+	if datagrams != nil {
+		ctx = context.WithValue(ctx, DatagrammerContextKey, Datagrammer(&streamDatagrammer{datagrams: datagrams, streamID: str.StreamID()}))
+	}
+	// The HEADERS frame is the first frame on the stream.
+	// If any data was received in 0-RTT, this includes (at least parts of) the HEADERS frame.
+	// 0-RTT is only possible on connections accepted by an EarlyListener.
+	if earlyConn, ok := conn.(quic.EarlyConnection); ok && len(str.EarlyData()) > 0 {
+		ctx = context.WithValue(ctx, EarlyDataContextKey, earlyConn.HandshakeComplete())
+	}
 	req = req.WithContext(ctx)
 	r := newResponseWriter(str, conn, s.logger)
+	if req.Method != http.MethodHead {
+		r.encoding = negotiateContentEncoding(req.Header.Get("Accept-Encoding"), s.ContentEncodings)
+	}
 	handler := s.Handler
 	if handler == nil {
 		handler = http.DefaultServeMux
@@ -628,16 +746,32 @@ func (s *Server) handleRequest(conn quic.Connection, str quic.Stream, decoder *q
 
 	// only write response when there is no panic
 	if !panicked {
-		// response not written to the client yet, set Content-Length
-		if !r.written {
-			if _, haveCL := r.header["Content-Length"]; !haveCL {
-				r.header.Set("Content-Length", strconv.FormatInt(r.numWritten, 10))
+		// finish the compressed body (if any) first, so that it's included in the Content-Length
+		err := r.closeBody()
+		if err == nil {
+			// response not written to the client yet, set Content-Length
+			if !r.written {
+				if _, haveCL := r.header["Content-Length"]; !haveCL {
+					r.header.Set("Content-Length", strconv.FormatInt(r.numWritten, 10))
+				}
 			}
+			err = r.FlushError()
+		}
+		if err != nil {
+			// The response might have been truncated. Reset the stream, so the client doesn't mistake it for a complete response.
+			if s.WriteTimeout > 0 && isTimeoutError(err) {
+				str.CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+				return newStreamError(ErrCodeRequestCanceled, err)
+			}
+			s.logger.Errorf("could not flush to stream: %s", err.Error())
 		}
-		r.Flush()
 	}
 	// If the EOF was read by the handler, CancelRead() is a no-op.
-	str.CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+	if !readDeadline.IsZero() && time.Now().After(readDeadline) {
+		str.CancelRead(quic.StreamErrorCode(ErrCodeRequestIncomplete))
+	} else {
+		str.CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+	}
 	return requestError{}
 }
 
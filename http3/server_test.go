@@ -9,7 +9,9 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
 	"runtime"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -137,6 +139,7 @@ var _ = Describe("Server", func() {
 
 			qpackDecoder = qpack.NewDecoder(nil)
 			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().EarlyData().AnyTimes()
 			conn = mockquic.NewMockEarlyConnection(mockCtrl)
 			addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
 			conn.EXPECT().RemoteAddr().Return(addr).AnyTimes()
@@ -157,12 +160,99 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(conn, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(conn, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
 			Expect(req.RemoteAddr).To(Equal("127.0.0.1:1337"))
 			Expect(req.Context().Value(ServerContextKey)).To(Equal(s))
+			Expect(req.Context().Value(EarlyDataContextKey)).To(BeNil())
+		})
+
+		It("marks requests received in 0-RTT", func() {
+			requestChan := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				requestChan <- r
+			})
+
+			str = mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().EarlyData().Return([]quic.EarlyDataRange{{Start: 0, End: 100}})
+			handshakeComplete := make(chan struct{})
+			conn.EXPECT().HandshakeComplete().Return(handshakeComplete)
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(conn, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
+			var req *http.Request
+			Eventually(requestChan).Should(Receive(&req))
+			ch, ok := req.Context().Value(EarlyDataContextKey).(<-chan struct{})
+			Expect(ok).To(BeTrue())
+			Expect(ch).ToNot(BeClosed())
+			close(handshakeComplete)
+			Expect(ch).To(BeClosed())
+		})
+
+		It("sends and receives datagrams associated with the request", func() {
+			conn.EXPECT().Context().Return(context.Background()).AnyTimes()
+			datagrams := newDatagrammer(conn)
+			datagrams.onSettings(true)
+			received := make(chan []byte, 1)
+			conn.EXPECT().ReceiveMessage(gomock.Any()).DoAndReturn(func(context.Context) ([]byte, error) {
+				return <-received, nil
+			}).AnyTimes()
+			go datagrams.run()
+
+			handlerDone := make(chan struct{})
+			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				defer close(handlerDone)
+				d, ok := r.Context().Value(DatagrammerContextKey).(Datagrammer)
+				Expect(ok).To(BeTrue())
+				received <- append(quicvarint.Append(nil, 4/4), []byte("foobar")...)
+				b, err := d.ReceiveDatagram(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte("foobar")))
+				conn.EXPECT().SendMessage(append(quicvarint.Append(nil, 4/4), []byte("raboof")...))
+				Expect(d.SendDatagram([]byte("raboof"))).To(Succeed())
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(conn, str, datagrams, qpackDecoder, nil)).To(Equal(requestError{}))
+			Eventually(handlerDone).Should(BeClosed())
+			// the stream is removed once the request is done
+			datagrams.mutex.Lock()
+			Expect(datagrams.streams).To(BeEmpty())
+			datagrams.mutex.Unlock()
+		})
+
+		It("doesn't set the Datagrammer if datagrams are disabled", func() {
+			requestChan := make(chan *http.Request, 1)
+			s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				requestChan <- r
+			})
+
+			setRequest(encodeRequest(exampleGetRequest))
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(conn, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
+			var req *http.Request
+			Eventually(requestChan).Should(Receive(&req))
+			Expect(req.Context().Value(DatagrammerContextKey)).To(BeNil())
 		})
 
 		It("returns 200 with an empty handler", func() {
@@ -174,7 +264,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -191,7 +281,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -213,7 +303,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -232,7 +322,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Expect(responseBuf.Bytes()).To(HaveLen(0))
 		})
@@ -248,7 +338,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Expect(responseBuf.Bytes()).To(HaveLen(0))
 		})
@@ -452,13 +542,16 @@ var _ = Describe("Server", func() {
 		})
 
 		Context("control stream handling", func() {
-			var conn *mockquic.MockEarlyConnection
+			var (
+				conn         *mockquic.MockEarlyConnection
+				settingsData []byte
+			)
 			testDone := make(chan struct{})
 
 			BeforeEach(func() {
 				conn = mockquic.NewMockEarlyConnection(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
-				controlStr.EXPECT().Write(gomock.Any())
+				controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) { settingsData = append([]byte{}, b...) })
 				conn.EXPECT().OpenUniStream().Return(controlStr, nil)
 				conn.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
 				conn.EXPECT().RemoteAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
@@ -484,6 +577,23 @@ var _ = Describe("Server", func() {
 				time.Sleep(scaleDuration(20 * time.Millisecond)) // don't EXPECT any calls to conn.CloseWithError
 			})
 
+			It("advertises the maximum size of the header section", func() {
+				s.MaxHeaderBytes = 1234
+				conn.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				s.handleConn(conn)
+				r := bytes.NewReader(settingsData)
+				streamType, err := quicvarint.Read(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+				f, err := parseNextFrame(r, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				Expect(f.(*settingsFrame).MaxFieldSectionSize).To(BeEquivalentTo(1234))
+			})
+
 			for _, t := range []uint64{streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream} {
 				streamType := t
 				name := "encoder"
@@ -599,6 +709,7 @@ var _ = Describe("Server", func() {
 
 			It("errors when the client advertises datagram support (and we enabled support for it)", func() {
 				s.EnableDatagrams = true
+				conn.EXPECT().ReceiveMessage(gomock.Any()).Return(nil, errors.New("datagrams not supported")).MaxTimes(1)
 				b := quicvarint.Append(nil, streamTypeControlStream)
 				b = (&settingsFrame{Datagram: true}).Append(b)
 				r := bytes.NewReader(b)
@@ -710,6 +821,137 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("rejects requests with too many header fields", func() {
+				s.MaxHeaderFields = 3
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Fail("Handler should not be called.")
+				})
+
+				setRequest(encodeRequest(exampleGetRequest))
+				responseBuf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				hfs := decodeHeader(responseBuf)
+				Expect(hfs).To(HaveKeyWithValue(":status", []string{"431"}))
+			})
+
+			It("rejects requests with a too large header section", func() {
+				s.MaxHeaderBytes = 1000
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					Fail("Handler should not be called.")
+				})
+
+				// Huffman encoding compresses this header to less than 1000 bytes
+				req, err := http.NewRequest(http.MethodGet, "https://www.example.com", nil)
+				Expect(err).ToNot(HaveOccurred())
+				req.Header.Set("foo", strings.Repeat("a", 1000))
+				setRequest(encodeRequest(req))
+				responseBuf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				hfs := decodeHeader(responseBuf)
+				Expect(hfs).To(HaveKeyWithValue(":status", []string{"431"}))
+			})
+
+			It("rejects requests when the headers aren't received in time", func() {
+				s.ReadHeaderTimeout = time.Second
+				handlerCalled := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					close(handlerCalled)
+				})
+
+				done := make(chan struct{})
+				var deadline time.Time
+				str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { deadline = t })
+				str.EXPECT().Read(gomock.Any()).Return(0, os.ErrDeadlineExceeded)
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(ErrCodeRequestRejected)).Do(func(quic.StreamErrorCode) { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), scaleDuration(100*time.Millisecond)))
+				Consistently(handlerCalled).ShouldNot(BeClosed())
+			})
+
+			It("sets the read deadline for the request body", func() {
+				s.ReadHeaderTimeout = time.Second
+				s.ReadTimeout = time.Minute
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+				setRequest(encodeRequest(exampleGetRequest))
+				var deadlines []time.Time
+				str.EXPECT().SetReadDeadline(gomock.Any()).Do(func(t time.Time) { deadlines = append(deadlines, t) }).Times(2)
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeNoError))
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				Expect(deadlines).To(HaveLen(2))
+				Expect(deadlines[0]).To(BeTemporally("~", time.Now().Add(time.Second), scaleDuration(100*time.Millisecond)))
+				Expect(deadlines[1]).To(BeTemporally("~", time.Now().Add(time.Minute), scaleDuration(100*time.Millisecond)))
+			})
+
+			It("resets the stream when the request body isn't received in time", func() {
+				s.ReadTimeout = 50 * time.Millisecond
+				errChan := make(chan error, 1)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					time.Sleep(100 * time.Millisecond) // make sure the deadline has expired
+					_, err := io.ReadAll(r.Body)
+					errChan <- err
+				})
+
+				requestData := encodeRequest(examplePostRequest)
+				buf := bytes.NewBuffer(requestData)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					if buf.Len() == 0 {
+						return 0, os.ErrDeadlineExceeded
+					}
+					return buf.Read(p)
+				}).AnyTimes()
+				str.EXPECT().SetReadDeadline(gomock.Any()).Times(2)
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) { return len(p), nil }).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeRequestIncomplete))
+				done := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				Expect(errChan).To(Receive(MatchError(os.ErrDeadlineExceeded)))
+			})
+
+			It("resets the stream when the response isn't written in time", func() {
+				s.WriteTimeout = time.Minute
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("foobar"))
+				})
+
+				setRequest(encodeRequest(exampleGetRequest))
+				var deadline time.Time
+				str.EXPECT().SetWriteDeadline(gomock.Any()).Do(func(t time.Time) { deadline = t })
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).Return(0, os.ErrDeadlineExceeded).AnyTimes()
+				str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeRequestCanceled))
+				done := make(chan struct{})
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(ErrCodeRequestCanceled)).Do(func(quic.StreamErrorCode) { close(done) })
+
+				s.handleConn(conn)
+				Eventually(done).Should(BeClosed())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Minute), scaleDuration(100*time.Millisecond)))
+			})
+
 			It("handles a request for which the client immediately resets the stream", func() {
 				handlerCalled := make(chan struct{})
 				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -787,7 +1029,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -810,7 +1052,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(ErrCodeNoError))
 
-			serr := s.handleRequest(conn, str, qpackDecoder, nil)
+			serr := s.handleRequest(conn, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
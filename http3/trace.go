@@ -0,0 +1,58 @@
+package http3
+
+import (
+	"context"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ClientTrace is a set of hooks to run at various stages of an outgoing HTTP/3 request.
+// It complements net/http/httptrace.ClientTrace with events that are specific to the QUIC transport.
+// Any particular hook may be nil.
+// The hooks are called from the QUIC connection's run loop, and therefore must not block.
+type ClientTrace struct {
+	// UploadBlocked is called when sending the request (headers or body) is blocked
+	// by stream or connection flow control, or by congestion control.
+	// This allows distinguishing transport backpressure from a slow server.
+	UploadBlocked func(reason quic.SendBlockedReason)
+	// UploadUnblocked is called when sending the request resumes after it was blocked,
+	// with the time sending was blocked for.
+	UploadUnblocked func(reason quic.SendBlockedReason, blockedFor time.Duration)
+}
+
+type clientTraceContextKey struct{}
+
+// WithClientTrace returns a new context based on the provided parent ctx.
+// HTTP/3 requests made with the returned context will use the provided trace hooks.
+func WithClientTrace(ctx context.Context, trace *ClientTrace) context.Context {
+	return context.WithValue(ctx, clientTraceContextKey{}, trace)
+}
+
+// ContextClientTrace returns the ClientTrace associated with the provided context.
+// If none, it returns nil.
+func ContextClientTrace(ctx context.Context) *ClientTrace {
+	trace, _ := ctx.Value(clientTraceContextKey{}).(*ClientTrace)
+	return trace
+}
+
+// traceUploadBlocking calls the trace hooks when sending on the request stream is blocked.
+func (t *ClientTrace) traceUploadBlocking(str quic.SendStream) {
+	if t.UploadBlocked == nil && t.UploadUnblocked == nil {
+		return
+	}
+	// the callback is only called from the connection's run loop
+	var blockedSince time.Time
+	str.SetBlockedCallback(func(reason quic.SendBlockedReason, blocked bool) {
+		if blocked {
+			blockedSince = time.Now()
+			if t.UploadBlocked != nil {
+				t.UploadBlocked(reason)
+			}
+			return
+		}
+		if t.UploadUnblocked != nil {
+			t.UploadUnblocked(reason, time.Since(blockedSince))
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package quic
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// PacketImpairment describes how a packet is treated by the Transport.
+// It is returned by the Transport.ImpairIncomingPacket and Transport.ImpairOutgoingPacket callbacks.
+// The zero value passes the packet on unmodified.
+type PacketImpairment struct {
+	// Drop drops the packet.
+	Drop bool
+	// Delay delays the packet (and all its duplicates) by the given duration.
+	Delay time.Duration
+	// Duplicates is the number of additional copies of the packet that are sent / received.
+	Duplicates int
+}
+
+// The delayQueue runs functions after a delay.
+// Once it is closed, pending functions are dropped.
+type delayQueue struct {
+	mutex  sync.Mutex
+	closed bool
+	timers map[*time.Timer]func() // the function to call when the timer is stopped
+}
+
+// AfterFunc calls run after the delay.
+// If the queue is closed before that, drop is called instead (if non-nil).
+func (q *delayQueue) AfterFunc(d time.Duration, run, drop func()) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		if drop != nil {
+			drop()
+		}
+		return
+	}
+	if q.timers == nil {
+		q.timers = make(map[*time.Timer]func())
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		q.mutex.Lock()
+		if q.closed {
+			q.mutex.Unlock()
+			return
+		}
+		delete(q.timers, timer)
+		q.mutex.Unlock()
+		run()
+	})
+	q.timers[timer] = drop
+}
+
+func (q *delayQueue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for timer, drop := range q.timers {
+		if timer.Stop() && drop != nil {
+			drop()
+		}
+	}
+	q.timers = nil
+}
+
+// handleIncomingPacket applies the impairments returned by the ImpairIncomingPacket callback to a received packet.
+func (t *Transport) handleIncomingPacket(p receivedPacket) {
+	imp := t.ImpairIncomingPacket(p.remoteAddr, p.data)
+	if imp.Drop {
+		p.buffer.Release()
+		return
+	}
+	packets := make([]receivedPacket, 0, 1+imp.Duplicates)
+	packets = append(packets, p)
+	for i := 0; i < imp.Duplicates; i++ {
+		buf := getPacketBuffer()
+		buf.Data = append(buf.Data, p.data...)
+		dup := p
+		dup.buffer = buf
+		dup.data = buf.Data
+		packets = append(packets, dup)
+	}
+	if imp.Delay <= 0 {
+		for _, p := range packets {
+			t.handlePacket(p)
+		}
+		return
+	}
+	t.delayedPackets.AfterFunc(
+		imp.Delay,
+		func() {
+			now := time.Now()
+			for _, p := range packets {
+				p.rcvTime = now
+				t.handlePacket(p)
+			}
+		},
+		func() {
+			for _, p := range packets {
+				p.buffer.Release()
+			}
+		},
+	)
+}
+
+// The impairedConn applies the impairments returned by the ImpairOutgoingPacket callback to sent packets.
+type impairedConn struct {
+	rawConn
+
+	impair  func(remoteAddr net.Addr, data []byte) PacketImpairment
+	delayed *delayQueue
+	logger  utils.Logger
+}
+
+var _ rawConn = &impairedConn{}
+
+func (c *impairedConn) WritePacket(b []byte, addr net.Addr, packetInfoOOB []byte, gsoSize uint16, ecn protocol.ECN) (int, error) {
+	// Packets are impaired one by one, so we need to split GSO batches.
+	n := len(b)
+	size := len(b)
+	if gsoSize > 0 {
+		size = int(gsoSize)
+	}
+	for len(b) > 0 {
+		l := size
+		if l > len(b) {
+			l = len(b)
+		}
+		// copy the packet, so that the callback can modify it
+		data := make([]byte, l)
+		copy(data, b[:l])
+		b = b[l:]
+
+		imp := c.impair(addr, data)
+		if imp.Drop {
+			continue
+		}
+		if imp.Delay <= 0 {
+			for i := 0; i <= imp.Duplicates; i++ {
+				if _, err := c.rawConn.WritePacket(data, addr, packetInfoOOB, 0, ecn); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		oob := append([]byte(nil), packetInfoOOB...)
+		duplicates := imp.Duplicates
+		c.delayed.AfterFunc(imp.Delay, func() {
+			for i := 0; i <= duplicates; i++ {
+				if _, err := c.rawConn.WritePacket(data, addr, oob, 0, ecn); err != nil {
+					c.logger.Debugf("Sending delayed packet to %s failed: %s", addr, err)
+					return
+				}
+			}
+		}, nil)
+	}
+	return n, nil
+}
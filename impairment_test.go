@@ -0,0 +1,295 @@
+package quic
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Packet Impairment", func() {
+	Context("delay queue", func() {
+		It("runs functions after the delay", func() {
+			var q delayQueue
+			done := make(chan struct{})
+			q.AfterFunc(scaleDuration(10*time.Millisecond), func() { close(done) }, func() { Fail("shouldn't drop") })
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("drops pending functions when closed", func() {
+			var q delayQueue
+			var dropped int
+			q.AfterFunc(scaleDuration(25*time.Millisecond), func() { Fail("shouldn't run") }, func() { dropped++ })
+			q.AfterFunc(scaleDuration(25*time.Millisecond), func() { Fail("shouldn't run") }, nil)
+			q.Close()
+			Expect(dropped).To(Equal(1))
+			// functions added after closing are dropped right away
+			q.AfterFunc(scaleDuration(25*time.Millisecond), func() { Fail("shouldn't run") }, func() { dropped++ })
+			Expect(dropped).To(Equal(2))
+			time.Sleep(scaleDuration(50 * time.Millisecond))
+		})
+	})
+
+	Context("incoming packets", func() {
+		var (
+			packetChan chan []byte
+			tr         *Transport
+			phm        *MockPacketHandlerManager
+			handled    chan receivedPacket
+			connID     = protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+		)
+
+		BeforeEach(func() {
+			packetChan = make(chan []byte)
+			conn := NewMockPacketConn(mockCtrl)
+			conn.EXPECT().LocalAddr().Return(&net.UDPAddr{}).AnyTimes()
+			conn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(b []byte) (int, net.Addr, error) {
+				data, ok := <-packetChan
+				if !ok {
+					return 0, nil, errors.New("closed")
+				}
+				return copy(b, data), remoteAddr, nil
+			}).AnyTimes()
+			conn.EXPECT().SetReadDeadline(gomock.Any()).AnyTimes()
+			tr = &Transport{Conn: conn}
+			handled = make(chan receivedPacket, 10)
+			phm = NewMockPacketHandlerManager(mockCtrl)
+			handler := NewMockPacketHandler(mockCtrl)
+			handler.EXPECT().handlePacket(gomock.Any()).Do(func(p receivedPacket) { handled <- p }).AnyTimes()
+			phm.EXPECT().Get(connID).Return(handler, true).AnyTimes()
+		})
+
+		AfterEach(func() {
+			phm.EXPECT().Close(gomock.Any())
+			close(packetChan)
+			tr.Close()
+		})
+
+		getPacket := func() []byte {
+			b, err := (&wire.ExtendedHeader{
+				Header: wire.Header{
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: connID,
+					Length:           2,
+					Version:          protocol.Version1,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}).Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			return b
+		}
+
+		start := func() {
+			tr.init(true)
+			tr.handlerMap = phm
+		}
+
+		It("drops packets", func() {
+			var counter int
+			tr.ImpairIncomingPacket = func(addr net.Addr, data []byte) PacketImpairment {
+				Expect(addr).To(Equal(remoteAddr))
+				counter++
+				return PacketImpairment{Drop: counter%2 == 0}
+			}
+			start()
+			for i := 0; i < 4; i++ {
+				packetChan <- getPacket()
+			}
+			Eventually(handled).Should(HaveLen(2))
+			Consistently(handled).Should(HaveLen(2))
+		})
+
+		It("duplicates packets", func() {
+			tr.ImpairIncomingPacket = func(net.Addr, []byte) PacketImpairment { return PacketImpairment{Duplicates: 2} }
+			start()
+			packetChan <- getPacket()
+			Eventually(handled).Should(HaveLen(3))
+			p1 := <-handled
+			p2 := <-handled
+			Expect(p1.data).To(Equal(p2.data))
+			Expect(p1.buffer).ToNot(BeIdenticalTo(p2.buffer))
+		})
+
+		It("delays packets", func() {
+			tr.ImpairIncomingPacket = func(net.Addr, []byte) PacketImpairment {
+				return PacketImpairment{Delay: scaleDuration(50 * time.Millisecond)}
+			}
+			start()
+			sendTime := time.Now()
+			packetChan <- getPacket()
+			var p receivedPacket
+			Eventually(handled).Should(Receive(&p))
+			Expect(time.Since(sendTime)).To(BeNumerically(">=", scaleDuration(50*time.Millisecond)))
+			Expect(p.rcvTime.Sub(sendTime)).To(BeNumerically(">=", scaleDuration(50*time.Millisecond)))
+		})
+
+		It("drops delayed packets when closed", func() {
+			tr.ImpairIncomingPacket = func(net.Addr, []byte) PacketImpairment {
+				return PacketImpairment{Delay: scaleDuration(50 * time.Millisecond)}
+			}
+			start()
+			packetChan <- getPacket()
+			Eventually(func() int {
+				tr.delayedPackets.mutex.Lock()
+				defer tr.delayedPackets.mutex.Unlock()
+				return len(tr.delayedPackets.timers)
+			}).Should(Equal(1))
+			tr.delayedPackets.Close()
+			Consistently(handled, scaleDuration(100*time.Millisecond)).ShouldNot(Receive())
+		})
+
+		It("corrupts packets", func() {
+			tr.ImpairIncomingPacket = func(_ net.Addr, data []byte) PacketImpairment {
+				data[len(data)-1] ^= 0xff
+				return PacketImpairment{}
+			}
+			start()
+			data := getPacket()
+			packetChan <- data
+			var p receivedPacket
+			Eventually(handled).Should(Receive(&p))
+			Expect(p.data[:len(data)-1]).To(Equal(data[:len(data)-1]))
+			Expect(p.data[len(data)-1]).To(Equal(data[len(data)-1] ^ 0xff))
+		})
+	})
+
+	Context("outgoing packets", func() {
+		var (
+			rawConn    *MockRawConn
+			remoteAddr = &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 1337}
+		)
+
+		BeforeEach(func() {
+			rawConn = NewMockRawConn(mockCtrl)
+		})
+
+		It("splits GSO batches", func() {
+			var sizes []int
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(_ net.Addr, data []byte) PacketImpairment {
+					sizes = append(sizes, len(data))
+					return PacketImpairment{}
+				},
+			}
+			rawConn.EXPECT().WritePacket(gomock.Any(), remoteAddr, []byte("oob"), uint16(0), protocol.ECT1).Return(0, nil).Times(3)
+			n, err := conn.WritePacket(make([]byte, 25), remoteAddr, []byte("oob"), 10, protocol.ECT1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(25))
+			Expect(sizes).To(Equal([]int{10, 10, 5}))
+		})
+
+		It("drops and duplicates packets", func() {
+			var counter int
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(net.Addr, []byte) PacketImpairment {
+					counter++
+					if counter == 1 {
+						return PacketImpairment{Drop: true}
+					}
+					return PacketImpairment{Duplicates: 1}
+				},
+			}
+			rawConn.EXPECT().WritePacket([]byte("bar"), remoteAddr, nil, uint16(0), protocol.ECNUnsupported).Times(2)
+			_, err := conn.WritePacket([]byte("foobar"), remoteAddr, nil, 3, protocol.ECNUnsupported)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("delays packets", func() {
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(net.Addr, []byte) PacketImpairment {
+					return PacketImpairment{Delay: scaleDuration(50 * time.Millisecond)}
+				},
+				delayed: &delayQueue{},
+				logger:  utils.DefaultLogger,
+			}
+			written := make(chan time.Time, 1)
+			rawConn.EXPECT().WritePacket([]byte("foobar"), remoteAddr, nil, uint16(0), protocol.ECNUnsupported).Do(
+				func([]byte, net.Addr, []byte, uint16, protocol.ECN) { written <- time.Now() },
+			)
+			start := time.Now()
+			_, err := conn.WritePacket([]byte("foobar"), remoteAddr, nil, 0, protocol.ECNUnsupported)
+			Expect(err).ToNot(HaveOccurred())
+			var writeTime time.Time
+			Eventually(written).Should(Receive(&writeTime))
+			Expect(writeTime.Sub(start)).To(BeNumerically(">=", scaleDuration(50*time.Millisecond)))
+		})
+
+		It("doesn't send delayed packets after closing", func() {
+			var q delayQueue
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(net.Addr, []byte) PacketImpairment {
+					return PacketImpairment{Delay: scaleDuration(25 * time.Millisecond)}
+				},
+				delayed: &q,
+				logger:  utils.DefaultLogger,
+			}
+			written := make(chan struct{}, 1)
+			rawConn.EXPECT().WritePacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(
+				func([]byte, net.Addr, []byte, uint16, protocol.ECN) { written <- struct{}{} },
+			).AnyTimes()
+			_, err := conn.WritePacket([]byte("foobar"), remoteAddr, nil, 0, protocol.ECNUnsupported)
+			Expect(err).ToNot(HaveOccurred())
+			q.Close()
+			Consistently(written, scaleDuration(75*time.Millisecond)).ShouldNot(Receive())
+		})
+
+		It("stops sending duplicates of delayed packets when writing fails", func() {
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(net.Addr, []byte) PacketImpairment {
+					return PacketImpairment{Delay: scaleDuration(10 * time.Millisecond), Duplicates: 2}
+				},
+				delayed: &delayQueue{},
+				logger:  utils.DefaultLogger,
+			}
+			written := make(chan struct{}, 3)
+			rawConn.EXPECT().WritePacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func([]byte, net.Addr, []byte, uint16, protocol.ECN) (int, error) {
+					written <- struct{}{}
+					return 0, errors.New("test error")
+				},
+			).AnyTimes()
+			_, err := conn.WritePacket([]byte("foobar"), remoteAddr, nil, 0, protocol.ECNUnsupported)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(written).Should(Receive())
+			Consistently(written, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+		})
+
+		It("doesn't modify the original buffer when corrupting packets", func() {
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair: func(_ net.Addr, data []byte) PacketImpairment {
+					data[0] = 'x'
+					return PacketImpairment{}
+				},
+			}
+			rawConn.EXPECT().WritePacket([]byte("xoobar"), remoteAddr, nil, uint16(0), protocol.ECNUnsupported)
+			b := []byte("foobar")
+			_, err := conn.WritePacket(b, remoteAddr, nil, 0, protocol.ECNUnsupported)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar")))
+		})
+
+		It("returns write errors", func() {
+			conn := &impairedConn{
+				rawConn: rawConn,
+				impair:  func(net.Addr, []byte) PacketImpairment { return PacketImpairment{} },
+			}
+			rawConn.EXPECT().WritePacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(0, errors.New("test error"))
+			_, err := conn.WritePacket([]byte("foobar"), remoteAddr, nil, 0, protocol.ECNUnsupported)
+			Expect(err).To(MatchError("test error"))
+		})
+	})
+})
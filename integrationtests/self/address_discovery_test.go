@@ -0,0 +1,66 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Address Discovery", func() {
+	dial := func(serverMode, clientMode quic.AddressDiscoveryMode) (client, server quic.Connection, ln *quic.Listener) {
+		ln, err := quic.ListenAddr("127.0.0.1:0", getTLSConfig(), getQuicConfig(&quic.Config{AddressDiscovery: serverMode}))
+		Expect(err).ToNot(HaveOccurred())
+
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("127.0.0.1:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{AddressDiscovery: clientMode}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(serverConnChan).Should(Receive(&server))
+		return conn, server, ln
+	}
+
+	It("learns the observed addresses", func() {
+		client, server, ln := dial(quic.AddressDiscoveryProvideAndReceive, quic.AddressDiscoveryProvideAndReceive)
+		defer ln.Close()
+		defer client.CloseWithError(0, "")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		addr, err := client.ObservedAddress(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr.(*net.UDPAddr).IP.IsLoopback()).To(BeTrue())
+		Expect(addr.(*net.UDPAddr).Port).To(Equal(client.LocalAddr().(*net.UDPAddr).Port))
+
+		addr, err = server.ObservedAddress(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(addr.String()).To(Equal(server.LocalAddr().String()))
+	})
+
+	It("errors if the peer doesn't provide the observed address", func() {
+		client, _, ln := dial(quic.AddressDiscoveryReceive, quic.AddressDiscoveryReceive)
+		defer ln.Close()
+		defer client.CloseWithError(0, "")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := client.ObservedAddress(ctx)
+		Expect(err).To(MatchError("peer doesn't provide observed addresses"))
+	})
+})
@@ -0,0 +1,136 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Admission", func() {
+	It("rejects connection attempts based on the ClientHello", func() {
+		var mutex sync.Mutex
+		var infos []*quic.AdmissionInfo
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				AdmitConnection: func(info *quic.AdmissionInfo) quic.AdmissionDecision {
+					mutex.Lock()
+					infos = append(infos, info)
+					mutex.Unlock()
+					if info.ServerName == "blocked.quic-go.net" {
+						return quic.AdmissionDecision{Action: quic.AdmissionReject, ErrorCode: 0x42}
+					}
+					return quic.AdmissionDecision{Action: quic.AdmissionAccept}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		tlsConf := getTLSClientConfig()
+		tlsConf.ServerName = "blocked.quic-go.net"
+		_, err = quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			tlsConf,
+			getQuicConfig(nil),
+		)
+		Expect(err).To(HaveOccurred())
+		var transportErr *quic.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(BeEquivalentTo(0x42))
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(infos).To(HaveLen(2))
+		Expect(infos[0].ServerName).To(Equal("blocked.quic-go.net"))
+		Expect(infos[0].ALPN).To(Equal([]string{alpn}))
+		Expect(infos[0].RemoteAddr.(*net.UDPAddr).IP.IsLoopback()).To(BeTrue())
+		Expect(infos[1].ServerName).To(Equal("localhost"))
+	})
+
+	It("requires a Retry", func() {
+		infoChan := make(chan *quic.AdmissionInfo, 2)
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				AdmitConnection: func(info *quic.AdmissionInfo) quic.AdmissionDecision {
+					infoChan <- info
+					return quic.AdmissionDecision{Action: quic.AdmissionRequireRetry}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		var info *quic.AdmissionInfo
+		Expect(infoChan).To(Receive(&info))
+		Expect(info.AddressValidated).To(BeFalse())
+		Expect(infoChan).To(Receive(&info))
+		Expect(info.AddressValidated).To(BeTrue())
+		Expect(infoChan).ToNot(Receive())
+	})
+
+	It("reports the server load", func() {
+		infoChan := make(chan *quic.AdmissionInfo, 3)
+		ln, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				AdmitConnection: func(info *quic.AdmissionInfo) quic.AdmissionDecision {
+					infoChan <- info
+					return quic.AdmissionDecision{Action: quic.AdmissionAccept}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		for i := 0; i < 3; i++ {
+			conn, err := quic.DialAddr(
+				context.Background(),
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+		}
+
+		var info *quic.AdmissionInfo
+		Expect(infoChan).To(Receive(&info))
+		Expect(info.ActiveConnections).To(BeZero())
+		Expect(infoChan).To(Receive(&info))
+		Expect(info.ActiveConnections).To(Equal(1))
+		Expect(infoChan).To(Receive(&info))
+		Expect(info.ActiveConnections).To(Equal(2))
+	})
+})
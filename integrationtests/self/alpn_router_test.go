@@ -0,0 +1,132 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ALPN routing", func() {
+	var (
+		udpConn *net.UDPConn
+		tr      *quic.Transport
+		router  *quic.ALPNRouter
+	)
+
+	BeforeEach(func() {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		udpConn, err = net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		tr = &quic.Transport{Conn: udpConn}
+		router, err = tr.ListenALPN(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(router.Close()).To(Succeed())
+		Expect(tr.Close()).To(Succeed())
+		Expect(udpConn.Close()).To(Succeed())
+	})
+
+	dial := func(proto string) (quic.Connection, error) {
+		tlsConf := getTLSClientConfig()
+		tlsConf.NextProtos = []string{proto}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		return quic.DialAddr(ctx, router.Addr().String(), tlsConf, getQuicConfig(nil))
+	}
+
+	accept := func(ln *quic.ALPNListener) quic.Connection {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		return conn
+	}
+
+	It("routes connections by the negotiated application protocol", func() {
+		ln1, err := router.Listen("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		ln2, err := router.Listen("proto2", "proto3")
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, proto := range []string{"proto1", "proto2", "proto3"} {
+			conn, err := dial(proto)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.ConnectionState().TLS.NegotiatedProtocol).To(Equal(proto))
+			ln := ln2
+			if proto == "proto1" {
+				ln = ln1
+			}
+			sconn := accept(ln)
+			Expect(sconn.ConnectionState().TLS.NegotiatedProtocol).To(Equal(proto))
+			conn.CloseWithError(0, "")
+		}
+	})
+
+	It("rejects duplicate registrations", func() {
+		_, err := router.Listen("proto1", "proto2")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = router.Listen("proto2")
+		Expect(err).To(MatchError(`quic: already listening for application protocol "proto2"`))
+	})
+
+	It("fails the handshake for unregistered application protocols", func() {
+		_, err := router.Listen("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = dial("unknown")
+		Expect(err).To(HaveOccurred())
+		var transportErr *quic.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeTrue())
+		Expect(transportErr.ErrorCode.IsCryptoError()).To(BeTrue())
+	})
+
+	It("closes a single listener", func() {
+		ln1, err := router.Listen("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		ln2, err := router.Listen("proto2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln1.Close()).To(Succeed())
+		_, err = ln1.Accept(context.Background())
+		Expect(err).To(MatchError(quic.ErrServerClosed))
+
+		_, err = dial("proto1")
+		Expect(err).To(HaveOccurred())
+		conn, err := dial("proto2")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		accept(ln2)
+
+		// the application protocol can be registered again
+		ln1, err = router.Listen("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		conn, err = dial("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		accept(ln1)
+	})
+
+	It("returns an error from Accept when the router is closed", func() {
+		ln, err := router.Listen("proto1")
+		Expect(err).ToNot(HaveOccurred())
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := ln.Accept(context.Background())
+			Expect(err).To(MatchError(quic.ErrServerClosed))
+		}()
+		Expect(router.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		_, err = router.Listen("proto2")
+		Expect(err).To(MatchError(quic.ErrServerClosed))
+	})
+})
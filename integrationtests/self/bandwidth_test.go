@@ -0,0 +1,57 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bandwidth Estimate", func() {
+	It("estimates the bandwidth while sending data", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		var estimate quic.BandwidthEstimate
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRDataLong)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			<-conn.Context().Done()
+			estimate = conn.ConnectionState().Bandwidth
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong))
+		// The client only sent a few packets, so it's application-limited.
+		Eventually(func() bool { return conn.ConnectionState().Bandwidth.AppLimited }).Should(BeTrue())
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(estimate.DeliveryRate).ToNot(BeZero())
+		// After sending all the data, the server ran out of data to send.
+		Expect(estimate.AppLimited).To(BeTrue())
+	})
+})
@@ -7,9 +7,12 @@ import (
 	"io"
 	mrand "math/rand"
 	"net"
+	"sync/atomic"
+	"time"
 
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,6 +34,30 @@ func (c *connIDGenerator) ConnectionIDLen() int {
 	return c.length
 }
 
+// variableLengthConnIDGenerator generates connection IDs of random length,
+// and encodes the length in the first byte of the connection ID.
+type variableLengthConnIDGenerator struct{}
+
+var _ quic.VariableLengthConnectionIDGenerator = &variableLengthConnIDGenerator{}
+
+func (c *variableLengthConnIDGenerator) GenerateConnectionID() (quic.ConnectionID, error) {
+	b := make([]byte, 4+mrand.Intn(15))
+	if _, err := rand.Read(b); err != nil {
+		fmt.Fprintf(GinkgoWriter, "generating conn ID failed: %s", err)
+	}
+	b[0] = byte(len(b))
+	return protocol.ParseConnectionID(b), nil
+}
+
+func (c *variableLengthConnIDGenerator) ConnectionIDLen() int { return 18 }
+
+func (c *variableLengthConnIDGenerator) ParseConnectionIDLen(b []byte) (int, error) {
+	if l := int(b[0]); l >= 4 && l <= 18 {
+		return l, nil
+	}
+	return 0, fmt.Errorf("invalid connection ID length: %d", b[0])
+}
+
 var _ = Describe("Connection ID lengths tests", func() {
 	randomConnIDLen := func() int { return 4 + int(mrand.Int31n(15)) }
 
@@ -114,6 +141,42 @@ var _ = Describe("Connection ID lengths tests", func() {
 		runClient(ln.Addr(), 0, nil)
 	})
 
+	It("downloads a file using zero-length connection IDs on a Transport", func() {
+		ln, closeFn := runServer(randomConnIDLen(), nil)
+		defer closeFn()
+
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		tr := &quic.Transport{Conn: conn, ZeroLengthConnectionIDs: true}
+		defer tr.Close()
+		srcConnIDChan := make(chan quic.ConnectionID, 1)
+		cl, err := tr.Dial(
+			context.Background(),
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: ln.Addr().(*net.UDPAddr).Port},
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+					return &logging.ConnectionTracer{
+						StartedConnection: func(_, _ net.Addr, srcConnID, _ logging.ConnectionID) { srcConnIDChan <- srcConnID },
+					}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(srcConnIDChan).To(Receive(Equal(quic.ConnectionID{})))
+		str, err := cl.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		// only a single connection can be dialed at a time
+		_, err = tr.Dial(context.Background(), ln.Addr(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).To(HaveOccurred())
+		cl.CloseWithError(0, "")
+	})
+
 	It("downloads a file when both client and server use a random connection ID length", func() {
 		ln, closeFn := runServer(randomConnIDLen(), nil)
 		defer closeFn()
@@ -125,4 +188,110 @@ var _ = Describe("Connection ID lengths tests", func() {
 		defer closeFn()
 		runClient(ln.Addr(), 0, &connIDGenerator{length: randomConnIDLen()})
 	})
+
+	It("downloads a file when both client and server use connection IDs of variable length", func() {
+		ln, closeFn := runServer(0, &variableLengthConnIDGenerator{})
+		defer closeFn()
+		runClient(ln.Addr(), 0, &variableLengthConnIDGenerator{})
+	})
+
+	It("rotates connection IDs", func() {
+		var numIssued, numRetired, numRetiredByPeer, numRetiredByClient atomic.Int32
+		tracer := func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+			return &logging.ConnectionTracer{
+				IssuedConnectionID:  func(uint64, logging.ConnectionID, uint64) { numIssued.Add(1) },
+				RetiredConnectionID: func(uint64, logging.ConnectionID) { numRetired.Add(1) },
+			}
+		}
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			MaxIssuedConnectionIDs:       2,
+			ConnectionIDRotationInterval: 5 * time.Millisecond,
+			ConnectionIDRetirement:       quic.RetireAllConnectionIDs,
+			ConnectionIDRetired: func(_ quic.Connection, info quic.RetiredConnectionIDInfo) {
+				if info.RetiredByPeer {
+					numRetiredByPeer.Add(1)
+				}
+			},
+			Tracer: tracer,
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			io.Copy(str, str)
+			str.Close()
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				ConnectionIDRetired: func(_ quic.Connection, info quic.RetiredConnectionIDInfo) {
+					if !info.RetiredByPeer {
+						numRetiredByClient.Add(1)
+					}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		b := make([]byte, 6)
+		for i := 0; i < 20; i++ {
+			_, err := str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal([]byte("foobar")))
+			time.Sleep(10 * time.Millisecond)
+		}
+		// The client switches to the new connection IDs and retires the old ones.
+		Expect(numIssued.Load()).To(BeNumerically(">", 2))
+		Expect(numRetired.Load()).To(BeNumerically(">", 2))
+		Expect(numRetiredByClient.Load()).To(BeNumerically(">", 2))
+		Expect(numRetiredByPeer.Load()).To(BeNumerically(">", 2))
+	})
+
+	It("issues as many connection IDs as the peer allows", func() {
+		var numIssued atomic.Int32
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			MaxIssuedConnectionIDs: 20,
+			Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+				return &logging.ConnectionTracer{
+					IssuedConnectionID: func(uint64, logging.ConnectionID, uint64) { numIssued.Add(1) },
+				}
+			},
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		activeConnIDLimit := make(chan uint64, 1)
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				ActiveConnectionIDLimit: 16,
+				Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+					return &logging.ConnectionTracer{
+						SentTransportParameters: func(tp *logging.TransportParameters) { activeConnIDLimit <- tp.ActiveConnectionIDLimit },
+					}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(activeConnIDLimit).To(Receive(BeEquivalentTo(16)))
+		// the connection ID used during the handshake counts towards the limit
+		Eventually(func() int32 { return numIssued.Load() }).Should(BeEquivalentTo(15))
+		Consistently(func() int32 { return numIssued.Load() }, 50*time.Millisecond).Should(BeEquivalentTo(15))
+	})
 })
@@ -0,0 +1,92 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Limits", func() {
+	listen := func(limitBehavior quic.ConnectionLimitBehavior) (*quic.Transport, *quic.Listener) {
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		udpConn, err := net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+		tr := &quic.Transport{
+			Conn:                    udpConn,
+			MaxConnections:          1,
+			ConnectionLimitBehavior: limitBehavior,
+		}
+		DeferCleanup(udpConn.Close)
+		ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		return tr, ln
+	}
+
+	It("refuses connections exceeding the limit", func() {
+		tr, ln := listen(quic.RefuseConnections)
+		defer tr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		_, err = ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).To(HaveOccurred())
+		var transportErr *quic.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(Equal(quic.ConnectionRefused))
+
+		stats := tr.ConnectionStats()
+		Expect(stats.Connections).To(Equal(1))
+		Expect(stats.Handshakes).To(BeZero())
+		Expect(stats.TotalConnections).To(BeEquivalentTo(1))
+		Expect(stats.RefusedConnectionAttempts).To(BeNumerically(">=", 1))
+	})
+
+	It("queues connections exceeding the limit", func() {
+		tr, ln := listen(quic.QueueConnections)
+		defer tr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		dialed := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+			Expect(err).ToNot(HaveOccurred())
+			dialed <- conn
+		}()
+		Eventually(func() int { return tr.ConnectionStats().QueuedConnectionAttempts }).Should(Equal(1))
+		Consistently(dialed, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+
+		// closing the first connection frees up capacity for the queued connection attempt
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+		var conn2 quic.Connection
+		Eventually(dialed, 3*time.Second).Should(Receive(&conn2))
+		defer conn2.CloseWithError(0, "")
+		_, err = ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		stats := tr.ConnectionStats()
+		Expect(stats.Connections).To(Equal(1))
+		Expect(stats.QueuedConnectionAttempts).To(BeZero())
+		Expect(stats.TotalConnections).To(BeEquivalentTo(2))
+		Expect(stats.RefusedConnectionAttempts).To(BeZero())
+	})
+})
@@ -0,0 +1,84 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dial Source", func() {
+	// On Linux, the whole 127.0.0.0/8 subnet is routed to the loopback interface.
+	// That makes it possible to select a source address other than 127.0.0.1.
+	const loopbackInterface = "lo"
+	sourceIP := net.IPv4(127, 0, 0, 2)
+
+	BeforeEach(func() {
+		if runtime.GOOS != "linux" {
+			Skip("only supported on Linux")
+		}
+	})
+
+	listen := func() *quic.Listener {
+		ln, err := quic.ListenAddr("127.0.0.1:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		return ln
+	}
+
+	It("selects the source address for connections on a shared Transport", func() {
+		ln := listen()
+		defer ln.Close()
+
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &quic.Transport{Conn: conn}
+		defer tr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, src := range []*quic.DialSource{
+			nil,
+			{IP: sourceIP},
+			{IP: sourceIP, Interface: loopbackInterface},
+		} {
+			By(fmt.Sprintf("dialing from %+v", src))
+			clientConn, err := tr.DialFrom(ctx, ln.Addr(), getTLSClientConfig(), getQuicConfig(nil), src)
+			Expect(err).ToNot(HaveOccurred())
+			serverConn, err := ln.Accept(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			expectedIP := net.IPv4(127, 0, 0, 1)
+			if src != nil {
+				expectedIP = sourceIP
+				Expect(clientConn.LocalAddr().(*net.UDPAddr).IP.Equal(sourceIP)).To(BeTrue())
+			}
+			Expect(serverConn.RemoteAddr().(*net.UDPAddr).IP.Equal(expectedIP)).To(BeTrue())
+			clientConn.CloseWithError(0, "")
+		}
+	})
+
+	It("dials from a socket bound to an interface", func() {
+		ln := listen()
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		clientConn, err := quic.DialAddrFrom(
+			ctx,
+			ln.Addr().String(),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+			&quic.DialSource{IP: sourceIP, Interface: loopbackInterface, BindToDevice: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer clientConn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverConn.RemoteAddr().(*net.UDPAddr).IP.Equal(sourceIP)).To(BeTrue())
+	})
+})
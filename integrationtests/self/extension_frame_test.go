@@ -0,0 +1,184 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Extension frames", func() {
+	const (
+		counterFrameType      = 0x3f5eb1
+		counterTransportParam = 0x3f5eb2
+	)
+
+	// counterFrame returns the definition of a frame carrying a varint-encoded counter.
+	counterFrame := func(handle func(quic.Connection, any) error) quic.ExtensionFrame {
+		return quic.ExtensionFrame{
+			Type:               counterFrameType,
+			TransportParameter: counterTransportParam,
+			AckEliciting:       true,
+			RetransmitOnLoss:   true,
+			Encode: func(b []byte, f any) ([]byte, error) {
+				n, ok := f.(uint64)
+				if !ok {
+					return nil, errors.New("not a counter")
+				}
+				return quicvarint.Append(b, n), nil
+			},
+			Decode: func(b []byte) (any, int, error) {
+				r := bytes.NewReader(b)
+				n, err := quicvarint.Read(r)
+				if err != nil {
+					return nil, 0, err
+				}
+				return n, len(b) - r.Len(), nil
+			},
+			Handle: handle,
+		}
+	}
+
+	dial := func(serverConf, clientConf *quic.Config) (client, server quic.Connection, ln *quic.Listener) {
+		ln, err := quic.ListenAddr("127.0.0.1:0", getTLSConfig(), getQuicConfig(serverConf))
+		Expect(err).ToNot(HaveOccurred())
+
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("127.0.0.1:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(clientConf),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(serverConnChan).Should(Receive(&server))
+		return conn, server, ln
+	}
+
+	It("exchanges extension frames", func() {
+		// the server increments the counter and sends it back
+		serverConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{
+			counterFrame(func(conn quic.Connection, f any) error {
+				return conn.SendExtensionFrame(counterFrameType, f.(uint64)+1)
+			}),
+		}}
+		received := make(chan uint64, 10)
+		clientConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{
+			counterFrame(func(_ quic.Connection, f any) error {
+				received <- f.(uint64)
+				return nil
+			}),
+		}}
+		client, server, ln := dial(serverConf, clientConf)
+		defer ln.Close()
+		defer client.CloseWithError(0, "")
+
+		Expect(client.ConnectionState().ExtensionFrames).To(Equal([]uint64{counterFrameType}))
+		Eventually(func() []uint64 { return server.ConnectionState().ExtensionFrames }).Should(Equal([]uint64{counterFrameType}))
+		for i := uint64(0); i < 5; i++ {
+			Expect(client.SendExtensionFrame(counterFrameType, i*100)).To(Succeed())
+			var n uint64
+			Eventually(received).Should(Receive(&n))
+			Expect(n).To(Equal(i*100 + 1))
+		}
+		Expect(client.SendExtensionFrame(counterFrameType, "foobar")).To(MatchError("not a counter"))
+	})
+
+	It("doesn't send extension frames if the peer doesn't support them", func() {
+		clientConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{counterFrame(nil)}}
+		client, _, ln := dial(nil, clientConf)
+		defer ln.Close()
+		defer client.CloseWithError(0, "")
+
+		Expect(client.ConnectionState().ExtensionFrames).To(BeEmpty())
+		Expect(client.SendExtensionFrame(counterFrameType, uint64(42))).To(MatchError(fmt.Sprintf("peer doesn't support extension frame type %#x", counterFrameType)))
+	})
+
+	It("closes the connection when handling an extension frame fails", func() {
+		serverConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{
+			counterFrame(func(quic.Connection, any) error { return errors.New("unexpected counter") }),
+		}}
+		clientConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{counterFrame(nil)}}
+		client, _, ln := dial(serverConf, clientConf)
+		defer ln.Close()
+
+		Expect(client.SendExtensionFrame(counterFrameType, uint64(42))).To(Succeed())
+		Eventually(client.Context().Done()).Should(BeClosed())
+		var transportErr *quic.TransportError
+		Expect(errors.As(context.Cause(client.Context()), &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(Equal(quic.ProtocolViolation))
+		Expect(transportErr.ErrorMessage).To(Equal("unexpected counter"))
+	})
+
+	It("retransmits lost extension frames", func() {
+		received := make(chan uint64, 10)
+		serverConf := &quic.Config{ExtensionFrames: []quic.ExtensionFrame{
+			counterFrame(func(_ quic.Connection, f any) error {
+				received <- f.(uint64)
+				return nil
+			}),
+		}}
+		ln, err := quic.ListenAddr("127.0.0.1:0", getTLSConfig(), getQuicConfig(serverConf))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		// drop every other 1-RTT packet sent by the client
+		var numShortHeaderPackets atomic.Int32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, packet []byte) bool {
+				if dir != quicproxy.DirectionIncoming || wire.IsLongHeaderPacket(packet[0]) {
+					return false
+				}
+				return numShortHeaderPackets.Add(1)%2 == 0
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		client, err := quic.DialAddr(
+			ctx,
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{ExtensionFrames: []quic.ExtensionFrame{counterFrame(nil)}}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+
+		const num = 10
+		for i := uint64(0); i < num; i++ {
+			Expect(client.SendExtensionFrame(counterFrameType, i)).To(Succeed())
+			time.Sleep(time.Millisecond) // make sure the frames are sent in separate packets
+		}
+		seen := make(map[uint64]struct{})
+		for len(seen) < num {
+			select {
+			case n := <-received:
+				seen[n] = struct{}{}
+			case <-ctx.Done():
+				Fail(fmt.Sprintf("timeout, received %d frames", len(seen)))
+			}
+		}
+	})
+})
@@ -0,0 +1,118 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/fatelog"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+	closed chan struct{}
+}
+
+func (b *bufferWriteCloser) Close() error {
+	close(b.closed)
+	return nil
+}
+
+var _ = Describe("Packet fate log", func() {
+	It("records the fate of every packet", func() {
+		buf := &bufferWriteCloser{closed: make(chan struct{})}
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(_ context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+					return fatelog.NewConnectionTracer(buf, p, connID)
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		var count atomic.Int32
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(dir quicproxy.Direction, _ []byte) bool {
+				// drop every 10th packet sent by the server, after the handshake
+				return dir == quicproxy.DirectionOutgoing && count.Add(1) > 10 && count.Load()%10 == 0
+			},
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return 5 * time.Millisecond },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		conn.CloseWithError(0, "")
+		Eventually(buf.closed).Should(BeClosed())
+
+		r, err := fatelog.NewReader(&buf.Buffer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(r.Header().Perspective).To(Equal(logging.PerspectiveServer))
+		type packetID struct {
+			space fatelog.Space
+			pn    logging.PacketNumber
+		}
+		sent := make(map[packetID]struct{})
+		var numAcked, numLost, numDiscarded int
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+			id := packetID{space: rec.Space, pn: rec.PacketNumber}
+			switch rec.Type {
+			case fatelog.EventSent:
+				Expect(rec.Size).ToNot(BeZero())
+				sent[id] = struct{}{}
+			case fatelog.EventAcked:
+				Expect(sent).To(HaveKey(id))
+				numAcked++
+			case fatelog.EventLost:
+				Expect(sent).To(HaveKey(id))
+				numLost++
+			case fatelog.EventDiscarded:
+				numDiscarded++
+			}
+		}
+		Expect(numAcked).To(BeNumerically(">", len(PRData)/1500))
+		Expect(numLost).ToNot(BeZero())
+		Expect(numDiscarded).To(Equal(2)) // Initial and Handshake
+	})
+})
@@ -102,6 +102,63 @@ var _ = Describe("Handshake tests", func() {
 		Expect(err).To(MatchError("application cancelled"))
 	})
 
+	It("performs the handshakes on a worker pool", func() {
+		serverConfig.HandshakeWorkerPool = quic.NewHandshakeWorkerPool(2)
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		const numConns = 10
+		go func() {
+			defer GinkgoRecover()
+			for i := 0; i < numConns; i++ {
+				conn, err := ln.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					str, err := conn.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+			}
+		}()
+
+		clientPool := quic.NewHandshakeWorkerPool(2)
+		errChan := make(chan error, numConns)
+		for i := 0; i < numConns; i++ {
+			go func() {
+				conn, err := quic.DialAddr(
+					context.Background(),
+					fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{HandshakeWorkerPool: clientPool}),
+				)
+				if err != nil {
+					errChan <- err
+					return
+				}
+				defer conn.CloseWithError(0, "")
+				str, err := conn.AcceptUniStream(context.Background())
+				if err != nil {
+					errChan <- err
+					return
+				}
+				data, err := io.ReadAll(str)
+				if err == nil && string(data) != "foobar" {
+					err = fmt.Errorf("unexpected data: %q", data)
+				}
+				errChan <- err
+			}()
+		}
+		for i := 0; i < numConns; i++ {
+			var err error
+			Eventually(errChan, 5*time.Second).Should(Receive(&err))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	})
+
 	Context("using different cipher suites", func() {
 		for n, id := range map[string]uint16{
 			"TLS_AES_128_GCM_SHA256":       tls.TLS_AES_128_GCM_SHA256,
@@ -225,6 +282,12 @@ var _ = Describe("Handshake tests", func() {
 			Expect(transportErr.Error()).To(ContainSubstring("x509: certificate is valid for localhost, not foo.bar"))
 			var certErr *tls.CertificateVerificationError
 			Expect(errors.As(transportErr, &certErr)).To(BeTrue())
+			var hsErr *quic.HandshakeError
+			Expect(errors.As(err, &hsErr)).To(BeTrue())
+			Expect(hsErr.Reason).To(Equal(quic.HandshakeFailureCertificate))
+			Expect(hsErr.EncryptionLevel).To(Equal(protocol.EncryptionHandshake))
+			Expect(hsErr.Certificates).ToNot(BeEmpty())
+			Expect(hsErr.Certificates[0].DNSNames).To(ContainElement("localhost"))
 		})
 
 		It("fails the handshake if the client fails to provide the requested client cert", func() {
@@ -421,9 +484,82 @@ var _ = Describe("Handshake tests", func() {
 			Expect(errors.As(err, &transportErr)).To(BeTrue())
 			Expect(transportErr.ErrorCode.IsCryptoError()).To(BeTrue())
 			Expect(transportErr.Error()).To(ContainSubstring("no application protocol"))
+			var hsErr *quic.HandshakeError
+			Expect(errors.As(err, &hsErr)).To(BeTrue())
+			Expect(hsErr.Reason).To(Equal(quic.HandshakeFailureALPNMismatch))
 		})
 	})
 
+	It("records the handshake timeline", func() {
+		serverConfig.RequireAddressValidation = func(net.Addr) bool { return true }
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		Eventually(func() time.Time { return conn.ConnectionState().HandshakeTimeline.HandshakeConfirmed }).ShouldNot(BeZero())
+
+		timeline := conn.ConnectionState().HandshakeTimeline
+		Expect(timeline.Start).ToNot(BeZero())
+		Expect(timeline.FirstInitialSent).ToNot(BeTemporally("<", timeline.Start))
+		Expect(timeline.RetryReceived).To(BeTemporally(">=", timeline.FirstInitialSent))
+		Expect(timeline.FirstHandshakePacketReceived).To(BeTemporally(">=", timeline.RetryReceived))
+		Expect(timeline.HandshakeComplete).To(BeTemporally(">=", timeline.FirstHandshakePacketReceived))
+		Expect(timeline.HandshakeConfirmed).To(BeTemporally(">=", timeline.HandshakeComplete))
+		Expect(timeline.FirstAppDataSent).To(BeTemporally(">=", timeline.HandshakeComplete))
+		Expect(timeline.FirstAppDataReceived).To(BeTemporally(">=", timeline.FirstAppDataSent))
+	})
+
+	It("signals when the handshake is confirmed", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer serverConn.CloseWithError(0, "")
+
+		// the server confirms the handshake when it completes
+		Expect(serverConn.HandshakeConfirmed()).To(BeClosed())
+		// the client confirms the handshake when it receives the HANDSHAKE_DONE frame
+		Eventually(conn.HandshakeConfirmed()).Should(BeClosed())
+		Expect(conn.ConnectionState().HandshakeTimeline.HandshakeConfirmed).ToNot(BeZero())
+	})
+
 	Context("using tokens", func() {
 		It("uses tokens provided in NEW_TOKEN frames", func() {
 			server, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
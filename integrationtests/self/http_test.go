@@ -12,12 +12,14 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -276,6 +278,46 @@ var _ = Describe("HTTP tests", func() {
 		Expect(body).To(Equal(PRData))
 	})
 
+	It("reports when an upload is blocked by flow control", func() {
+		const delay = 200 * time.Millisecond
+		mux.HandleFunc("/slow-echo", func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			time.Sleep(delay) // don't read the request body, thereby blocking the upload
+			body, err := io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			w.Write(body) // don't check the error here. Stream may be reset.
+		})
+
+		// record the longest time the upload was blocked
+		var mutex sync.Mutex
+		var longestReason quic.SendBlockedReason
+		var longest time.Duration
+		ctx := http3.WithClientTrace(context.Background(), &http3.ClientTrace{
+			UploadUnblocked: func(reason quic.SendBlockedReason, blockedFor time.Duration) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if blockedFor > longest {
+					longest = blockedFor
+					longestReason = reason
+				}
+			},
+		})
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://localhost:%d/slow-echo", port), bytes.NewReader(PRDataLong))
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := client.Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 5*time.Second))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(Equal(PRDataLong))
+
+		// the upload was blocked (at least) until the handler started reading the request body
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(longestReason).To(Equal(quic.SendBlockedStreamFlowControl))
+		Expect(longest).To(BeNumerically(">", delay/2))
+	})
+
 	It("uses gzip compression", func() {
 		mux.HandleFunc("/gzipped/hello", func(w http.ResponseWriter, r *http.Request) {
 			defer GinkgoRecover()
@@ -432,6 +474,218 @@ var _ = Describe("HTTP tests", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("cancels requests when the response headers aren't received in time", func() {
+		handlerDone := make(chan struct{})
+		mux.HandleFunc("/slow-headers", func(w http.ResponseWriter, r *http.Request) {
+			defer close(handlerDone)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		})
+
+		rt.ResponseHeaderTimeout = deadlineDelay
+		start := time.Now()
+		_, err := client.Get(fmt.Sprintf("https://localhost:%d/slow-headers", port))
+		Expect(err).To(MatchError(ContainSubstring("http3: timeout awaiting response headers")))
+		var nerr net.Error
+		Expect(errors.As(err, &nerr)).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeTrue())
+		Expect(time.Since(start)).To(BeNumerically(">=", deadlineDelay))
+		// the server is notified that the request was canceled
+		Eventually(handlerDone).WithTimeout(2 * time.Second).Should(BeClosed())
+	})
+
+	It("resets the stream when the server's write timeout expires", func() {
+		tlsConf := getTLSConfig()
+		tlsConf.NextProtos = []string{http3.NextProtoH3}
+		ln, err := quic.ListenAddr("localhost:0", tlsConf, getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		handlerErr := make(chan error, 1)
+		s := &http3.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err := io.Copy(w, neverEnding('a'))
+				handlerErr <- err
+			}),
+			WriteTimeout: deadlineDelay,
+		}
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			s.ServeQUICConn(conn)
+		}()
+
+		resp, err := client.Get(fmt.Sprintf("https://localhost:%d/", ln.Addr().(*net.UDPAddr).Port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		_, err = io.ReadAll(gbytes.TimeoutReader(resp.Body, 4*deadlineDelay))
+		var http3Err *http3.Error
+		Expect(errors.As(err, &http3Err)).To(BeTrue())
+		Expect(http3Err.ErrorCode).To(Equal(http3.ErrCode(0x10c)))
+		Expect(http3Err.Remote).To(BeTrue())
+		Eventually(handlerErr).Should(Receive(MatchError(os.ErrDeadlineExceeded)))
+	})
+
+	It("compresses responses", func() {
+		tlsConf := getTLSConfig()
+		tlsConf.NextProtos = []string{http3.NextProtoH3}
+		ln, err := quic.ListenAddr("localhost:0", tlsConf, getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		data := bytes.Repeat([]byte("foobar"), 1<<16)
+		s := &http3.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Header.Get("Accept-Encoding")).To(Equal("gzip"))
+				_, err := w.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+			}),
+			ContentEncodings: []http3.ContentEncoding{http3.GzipEncoding},
+		}
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			s.ServeQUICConn(conn)
+		}()
+
+		client.Transport.(*http3.RoundTripper).DisableCompression = false
+		resp, err := client.Get(fmt.Sprintf("https://localhost:%d/", ln.Addr().(*net.UDPAddr).Port))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		// the client transparently decompresses the body
+		Expect(resp.Uncompressed).To(BeTrue())
+		body, err := io.ReadAll(gbytes.TimeoutReader(resp.Body, 5*time.Second))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(body).To(Equal(data))
+	})
+
+	It("sends and receives HTTP datagrams associated with a request", func() {
+		tlsConf := getTLSConfig()
+		tlsConf.NextProtos = []string{http3.NextProtoH3}
+		ln, err := quic.ListenAddr("localhost:0", tlsConf, getQuicConfig(&quic.Config{EnableDatagrams: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		s := &http3.Server{
+			EnableDatagrams: true,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				datagrammer, ok := r.Context().Value(http3.DatagrammerContextKey).(http3.Datagrammer)
+				Expect(ok).To(BeTrue())
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				// echo datagrams
+				for {
+					b, err := datagrammer.ReceiveDatagram(r.Context())
+					if err != nil {
+						return
+					}
+					if err := datagrammer.SendDatagram(append(b, []byte("-echo")...)); err != nil {
+						return
+					}
+				}
+			}),
+		}
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			s.ServeQUICConn(conn)
+		}()
+
+		drt := &http3.RoundTripper{
+			TLSClientConfig: getTLSClientConfigWithoutServerName(),
+			EnableDatagrams: true,
+			QuicConfig:      getQuicConfig(nil),
+		}
+		defer drt.Close()
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://localhost:%d/", ln.Addr().(*net.UDPAddr).Port), nil)
+		Expect(err).ToNot(HaveOccurred())
+		rsp, err := drt.RoundTrip(req)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rsp.StatusCode).To(Equal(http.StatusOK))
+		datagrammer, ok := rsp.Body.(http3.Datagrammer)
+		Expect(ok).To(BeTrue())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		for i := 0; i < 3; i++ {
+			msg := fmt.Sprintf("foo%d", i)
+			Expect(datagrammer.SendDatagram([]byte(msg))).To(Succeed())
+			b, err := datagrammer.ReceiveDatagram(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal(msg + "-echo"))
+		}
+		Expect(rsp.Body.Close()).To(Succeed())
+	})
+
+	It("opens raw QUIC streams on the HTTP/3 connection", func() {
+		const clientStreamType, serverStreamType = 0x54, 0x55
+		tlsConf := getTLSConfig()
+		tlsConf.NextProtos = []string{http3.NextProtoH3}
+		ln, err := quic.ListenAddr("localhost:0", tlsConf, getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		s := &http3.Server{
+			Handler: mux,
+			// echo the data received on the client's stream on a new stream
+			UniStreamHijacker: func(st http3.StreamType, conn quic.Connection, str quic.ReceiveStream, err error) bool {
+				if err != nil || st != clientStreamType {
+					return false
+				}
+				go func() {
+					defer GinkgoRecover()
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					rstr, err := conn.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = rstr.Write(append(quicvarint.Append(nil, serverStreamType), data...))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(rstr.Close()).To(Succeed())
+				}()
+				return true
+			},
+		}
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			s.ServeQUICConn(conn)
+		}()
+
+		received := make(chan []byte, 1)
+		srt := &http3.RoundTripper{
+			TLSClientConfig: getTLSClientConfigWithoutServerName(),
+			QuicConfig:      getQuicConfig(nil),
+			UniStreamHandlers: map[http3.StreamType]func(quic.Connection, quic.ReceiveStream){
+				serverStreamType: func(_ quic.Connection, str quic.ReceiveStream) {
+					defer GinkgoRecover()
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					received <- data
+				},
+			},
+		}
+		defer srt.Close()
+		authority := fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port)
+		sc, err := srt.StreamCreator(context.Background(), authority)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sc.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(append(quicvarint.Append(nil, clientStreamType), []byte("foobar")...))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+
+		// the connection is also used for HTTP requests
+		resp, err := (&http.Client{Transport: srt}).Get(fmt.Sprintf("https://%s/hello", authority))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(resp.Body.(http3.Hijacker).StreamCreator()).To(Equal(sc))
+	})
+
 	if go120 {
 		It("supports read deadlines", func() {
 			mux.HandleFunc("/read-deadline", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,91 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IMMEDIATE_ACK", func() {
+	// transfer sends PRData from the server to the client,
+	// and returns the short header packets sent by the server.
+	transfer := func(serverEnabled, clientEnabled bool) []shortHeaderPacket {
+		serverCounter, serverTracer := newPacketTracer()
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				EnableImmediateAck: serverEnabled,
+				Tracer:             newTracer(serverTracer),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			<-conn.Context().Done()
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableImmediateAck: clientEnabled}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		return serverCounter.getSentShortHeaderPackets()
+	}
+
+	containsImmediateAck := func(p shortHeaderPacket) bool {
+		for _, f := range p.frames {
+			if _, ok := f.(*logging.ImmediateAckFrame); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	It("requests an immediate ACK for the last packet of a transfer", func() {
+		packets := transfer(true, true)
+		var lastStreamPacket *shortHeaderPacket
+		for i, p := range packets {
+			for _, f := range p.frames {
+				if _, ok := f.(*logging.StreamFrame); ok {
+					lastStreamPacket = &packets[i]
+				}
+			}
+		}
+		Expect(lastStreamPacket).ToNot(BeNil())
+		Expect(containsImmediateAck(*lastStreamPacket)).To(BeTrue())
+	})
+
+	It("doesn't send IMMEDIATE_ACK frames if the peer doesn't support them", func() {
+		for _, p := range transfer(true, false) {
+			Expect(containsImmediateAck(p)).To(BeFalse())
+		}
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"sync"
 
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/internal/handshake"
@@ -89,4 +90,61 @@ var _ = Describe("Key Update tests", func() {
 		Expect(keyPhasesReceived).To(BeNumerically(">", 10))
 		Expect(keyPhasesReceived).To(BeNumerically("~", keyPhasesSent, 2))
 	})
+
+	It("updates keys after the configured number of bytes", func() {
+		var mutex sync.Mutex
+		var reasons []logging.KeyUpdateReason
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				KeyUpdateIntervalBytes: 64 * 1024,
+				Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+					return &logging.ConnectionTracer{
+						InitiatedKeyUpdate: func(_ logging.KeyPhase, reason logging.KeyUpdateReason, _, _ uint64, _ logging.ByteCount) {
+							mutex.Lock()
+							defer mutex.Unlock()
+							reasons = append(reasons, reason)
+						},
+					}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- conn
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			defer str.Close()
+			_, err = str.Write(PRData)
+			Expect(err).ToNot(HaveOccurred())
+		}()
+
+		conn, err := quic.DialAddr(context.Background(), server.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		var serverConn quic.Connection
+		Expect(serverConnChan).To(Receive(&serverConn))
+		serverStats := serverConn.ConnectionState().KeyUpdates
+		fmt.Fprintf(GinkgoWriter, "Server initiated %d key updates.\n", serverStats.Initiated)
+		// The first key update is initiated after 100 packets, subsequent ones every 64 KB.
+		Expect(serverStats.Initiated).To(BeNumerically(">", 2))
+		Expect(serverStats.KeyPhase).To(Equal(serverStats.Initiated + serverStats.PeerInitiated))
+		mutex.Lock()
+		Expect(reasons).To(ContainElement(logging.KeyUpdateByteInterval))
+		mutex.Unlock()
+		Eventually(func() uint64 { return conn.ConnectionState().KeyUpdates.PeerInitiated }).Should(Equal(serverStats.Initiated))
+	})
 })
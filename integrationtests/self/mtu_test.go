@@ -0,0 +1,126 @@
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Path MTU Discovery", func() {
+	It("sends MTU probes requested by the application", func() {
+		const mtu = 1300
+
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		// the proxy drops all packets exceeding the MTU
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(_ quicproxy.Direction, b []byte) bool { return len(b) > mtu },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		Expect(conn.ProbeMTU(ctx, mtu)).To(Succeed())
+		Expect(conn.ProbeMTU(ctx, mtu+100)).To(MatchError("MTU probe packet lost"))
+		// the size that was found is still valid
+		Expect(conn.ProbeMTU(ctx, mtu-100)).To(Succeed())
+	})
+
+	It("recovers from a PMTU black hole", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		var mtu atomic.Int64
+		mtu.Store(1500)
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(_ quicproxy.Direction, b []byte) bool { return int64(len(b)) > mtu.Load() },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		blackHoleDetected := make(chan struct{}, 10)
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: newTracer(&logging.ConnectionTracer{
+					DetectedMTUBlackHole: func(_, _ logging.ByteCount) { blackHoleDetected <- struct{}{} },
+				}),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		Expect(conn.ProbeMTU(ctx, 1400)).To(Succeed())
+
+		// the path MTU drops below the size that was found
+		mtu.Store(1300)
+		data := GeneratePRData(100 * 1024)
+		serverConnChan := make(chan quic.Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			serverConn, err := server.Accept(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			serverConnChan <- serverConn
+		}()
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+
+		var serverConn quic.Connection
+		Eventually(serverConnChan, 5*time.Second).Should(Receive(&serverConn))
+		serverStr, err := serverConn.AcceptStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		received, err := io.ReadAll(serverStr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bytes.Equal(received, data)).To(BeTrue())
+		Expect(blackHoleDetected).ToNot(BeEmpty())
+	})
+
+	It("rejects MTU probes when Path MTU Discovery is disabled", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(conn.ProbeMTU(context.Background(), 1400)).To(MatchError("path MTU discovery disabled"))
+	})
+})
@@ -0,0 +1,224 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// An addressSwitchingRelay forwards packets between a client and one of the addresses of a server.
+// The address can be changed at any time, which looks to the server like the client migrated.
+type addressSwitchingRelay struct {
+	conn        *net.UDPConn
+	serverAddrs []net.Addr
+	target      atomic.Pointer[net.UDPAddr]
+	client      atomic.Pointer[net.UDPAddr]
+}
+
+func newAddressSwitchingRelay(serverAddrs []net.Addr) *addressSwitchingRelay {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	Expect(err).ToNot(HaveOccurred())
+	r := &addressSwitchingRelay{conn: conn, serverAddrs: serverAddrs}
+	r.SwitchTo(0)
+	go r.run()
+	return r
+}
+
+func (r *addressSwitchingRelay) SwitchTo(i int) {
+	r.target.Store(r.serverAddrs[i].(*net.UDPAddr))
+}
+
+func (r *addressSwitchingRelay) run() {
+	b := make([]byte, 2000)
+	for {
+		n, addr, err := r.conn.ReadFromUDP(b)
+		if err != nil {
+			return
+		}
+		var fromServer bool
+		for _, serverAddr := range r.serverAddrs {
+			if serverAddr.String() == addr.String() {
+				fromServer = true
+			}
+		}
+		if fromServer {
+			if client := r.client.Load(); client != nil {
+				r.conn.WriteToUDP(b[:n], client)
+			}
+			continue
+		}
+		r.client.Store(addr)
+		r.conn.WriteToUDP(b[:n], r.target.Load())
+	}
+}
+
+func (r *addressSwitchingRelay) Addr() net.Addr { return r.conn.LocalAddr() }
+func (r *addressSwitchingRelay) Close() error   { return r.conn.Close() }
+
+var _ = Describe("Multihomed Server", func() {
+	runEchoServer := func(ln *quic.MultihomedListener, conns chan<- quic.Connection) {
+		for {
+			conn, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				defer GinkgoRecover()
+				str, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				io.Copy(str, str)
+				str.Close()
+			}()
+		}
+	}
+
+	It("accepts a client's migration to a different address", func() {
+		g, err := quic.NewMultihomedGroup([]string{"127.0.0.1:0", "127.0.0.1:0"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		ln, err := g.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverConns := make(chan quic.Connection, 1)
+		go runEchoServer(ln, serverConns)
+
+		relay := newAddressSwitchingRelay(g.Addrs())
+		defer relay.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, relay.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		var serverConn quic.Connection
+		Eventually(serverConns).Should(Receive(&serverConn))
+		Expect(serverConn.LocalAddr().(*net.UDPAddr).Port).To(Equal(g.Addrs()[0].(*net.UDPAddr).Port))
+
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		echo := func(data string) {
+			_, err := str.Write([]byte(data))
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, len(data))
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal(data))
+		}
+		echo("foo")
+		// wait for the handshake to be confirmed
+		time.Sleep(scaleDuration(20 * time.Millisecond))
+
+		relay.SwitchTo(1)
+		for i := 0; i < 10; i++ {
+			echo("bar")
+			time.Sleep(scaleDuration(5 * time.Millisecond))
+		}
+		Expect(serverConn.LocalAddr().(*net.UDPAddr).Port).To(Equal(g.Addrs()[1].(*net.UDPAddr).Port))
+		// the connection is not migrated back after the path validation deadline
+		time.Sleep(scaleDuration(200 * time.Millisecond))
+		echo("foobar")
+		Expect(serverConn.LocalAddr().(*net.UDPAddr).Port).To(Equal(g.Addrs()[1].(*net.UDPAddr).Port))
+	})
+
+	It("rebinds a client connection to a new socket", func() {
+		g, err := quic.NewMultihomedGroup([]string{"127.0.0.1:0"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		ln, err := g.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		serverConns := make(chan quic.Connection, 1)
+		go runEchoServer(ln, serverConns)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, g.Addrs()[0].String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		var serverConn quic.Connection
+		Eventually(serverConns).Should(Receive(&serverConn))
+
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		echo := func(data string) {
+			_, err := str.Write([]byte(data))
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, len(data))
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).To(Equal(data))
+		}
+		echo("foo")
+		// wait for the handshake to be confirmed
+		time.Sleep(scaleDuration(20 * time.Millisecond))
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Rebind(udpConn)).To(Succeed())
+		Expect(conn.LocalAddr()).To(Equal(udpConn.LocalAddr()))
+		for i := 0; i < 10; i++ {
+			echo("bar")
+			time.Sleep(scaleDuration(5 * time.Millisecond))
+		}
+		Expect(serverConn.RemoteAddr().String()).To(Equal(udpConn.LocalAddr().String()))
+		echo("foobar")
+	})
+
+	It("advertises an alternate address", func() {
+		g, err := quic.NewMultihomedGroup(
+			[]string{"127.0.0.1:0", "127.0.0.1:0"},
+			&quic.MultihomedConfig{AdvertiseAlternateAddresses: true},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		ln, err := g.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go runEchoServer(ln, make(chan quic.Connection, 1))
+
+		paramsChan := make(chan *logging.TransportParameters, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(
+			ctx,
+			g.Addrs()[0].String(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				Tracer: func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+					return &logging.ConnectionTracer{
+						ReceivedTransportParameters: func(p *logging.TransportParameters) { paramsChan <- p },
+					}
+				},
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		var params *logging.TransportParameters
+		Expect(paramsChan).To(Receive(&params))
+		Expect(params.DisableActiveMigration).To(BeFalse())
+		Expect(params.PreferredAddress).ToNot(BeNil())
+		Expect(params.PreferredAddress.IPv4.Equal(net.IPv4(127, 0, 0, 1))).To(BeTrue())
+		Expect(int(params.PreferredAddress.IPv4Port)).To(Equal(g.Addrs()[1].(*net.UDPAddr).Port))
+
+		// the connection can be used
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foobar"))
+	})
+})
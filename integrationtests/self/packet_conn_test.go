@@ -0,0 +1,221 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type memAddr string
+
+func (a memAddr) Network() string { return "mem" }
+func (a memAddr) String() string  { return string(a) }
+
+type memDatagram struct {
+	data []byte
+	ecn  logging.ECN
+}
+
+// memPacketConn is one end of an in-memory packet pipe.
+type memPacketConn struct {
+	addr         memAddr
+	peer         *memPacketConn
+	capabilities quic.PacketConnCapabilities
+
+	in        chan memDatagram
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mutex           sync.Mutex
+	deadline        time.Time
+	deadlineChanged chan struct{}
+
+	batches atomic.Int64
+}
+
+var (
+	_ quic.CapablePacketConn = &memPacketConn{}
+	_ quic.ECNPacketConn     = &memPacketConn{}
+	_ quic.BatchPacketConn   = &memPacketConn{}
+)
+
+func newMemPacketPipe(capabilities quic.PacketConnCapabilities) (*memPacketConn, *memPacketConn) {
+	newConn := func(addr memAddr) *memPacketConn {
+		return &memPacketConn{
+			addr:            addr,
+			capabilities:    capabilities,
+			in:              make(chan memDatagram, 1000),
+			closed:          make(chan struct{}),
+			deadlineChanged: make(chan struct{}, 1),
+		}
+	}
+	c1 := newConn("mem-1")
+	c2 := newConn("mem-2")
+	c1.peer = c2
+	c2.peer = c1
+	return c1, c2
+}
+
+func (c *memPacketConn) Capabilities() quic.PacketConnCapabilities { return c.capabilities }
+
+func (c *memPacketConn) ReadFromECN(b []byte) (int, net.Addr, logging.ECN, error) {
+	for {
+		c.mutex.Lock()
+		deadline := c.deadline
+		c.mutex.Unlock()
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case d := <-c.in:
+			return copy(b, d.data), c.peer.addr, d.ecn, nil
+		case <-c.closed:
+			return 0, nil, logging.ECNUnsupported, net.ErrClosed
+		case <-timeout:
+			return 0, nil, logging.ECNUnsupported, os.ErrDeadlineExceeded
+		case <-c.deadlineChanged:
+		}
+	}
+}
+
+func (c *memPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, _, err := c.ReadFromECN(b)
+	return n, addr, err
+}
+
+func (c *memPacketConn) WriteToECN(b []byte, _ net.Addr, ecn logging.ECN) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	select {
+	case c.peer.in <- memDatagram{data: append([]byte{}, b...), ecn: ecn}:
+	default: // drop the packet if the queue is full
+	}
+	return len(b), nil
+}
+
+func (c *memPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.WriteToECN(b, addr, logging.ECNUnsupported)
+}
+
+func (c *memPacketConn) WriteBatch(b []byte, segmentSize int, addr net.Addr, ecn logging.ECN) (int, error) {
+	c.batches.Add(1)
+	var n int
+	for len(b) > 0 {
+		l := segmentSize
+		if l > len(b) {
+			l = len(b)
+		}
+		if _, err := c.WriteToECN(b[:l], addr, ecn); err != nil {
+			return n, err
+		}
+		n += l
+		b = b[l:]
+	}
+	return n, nil
+}
+
+func (c *memPacketConn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	c.deadline = t
+	c.mutex.Unlock()
+	select {
+	case c.deadlineChanged <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *memPacketConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *memPacketConn) LocalAddr() net.Addr              { return c.addr }
+func (c *memPacketConn) SetDeadline(t time.Time) error    { return c.SetReadDeadline(t) }
+func (c *memPacketConn) SetWriteDeadline(time.Time) error { return nil }
+
+var _ = Describe("Capable PacketConns", func() {
+	It("runs QUIC over an in-memory pipe, using the declared capabilities", func() {
+		const maxDatagramSize = 1400
+		serverConn, clientConn := newMemPacketPipe(quic.PacketConnCapabilities{
+			MaxDatagramSize: maxDatagramSize,
+			ECN:             true,
+			Batching:        true,
+		})
+		defer serverConn.Close()
+		defer clientConn.Close()
+
+		var mutex sync.Mutex
+		var maxPacketSize logging.ByteCount
+		var ecnMarked int
+		ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(&quic.Config{
+			Tracer: newTracer(&logging.ConnectionTracer{
+				ReceivedShortHeaderPacket: func(_ *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, _ []logging.Frame) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					if size > maxPacketSize {
+						maxPacketSize = size
+					}
+					if ecn == logging.ECT0 || ecn == logging.ECT1 {
+						ecnMarked++
+					}
+				},
+			}),
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		data := GeneratePRData(500 << 10)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			b, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(data))
+			conn.CloseWithError(0, "")
+		}()
+
+		conn, err := quic.Dial(context.Background(), clientConn, serverConn.LocalAddr(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(conn.Context().Done(), 5*time.Second).Should(BeClosed())
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		// the packet size is the declared maximum datagram size, without running Path MTU Discovery
+		Expect(maxPacketSize).To(BeEquivalentTo(maxDatagramSize))
+		Expect(ecnMarked).ToNot(BeZero())
+		Expect(clientConn.batches.Load()).ToNot(BeZero())
+	})
+
+	It("rejects PacketConns declaring an invalid maximum datagram size", func() {
+		conn, _ := newMemPacketPipe(quic.PacketConnCapabilities{MaxDatagramSize: 1000})
+		defer conn.Close()
+		_, err := quic.Listen(conn, getTLSConfig(), getQuicConfig(nil))
+		Expect(err).To(MatchError(ContainSubstring("invalid maximum datagram size")))
+	})
+})
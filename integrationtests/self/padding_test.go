@@ -0,0 +1,106 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sizeRecordingConn records the sizes of the datagrams containing 1-RTT packets it receives.
+type sizeRecordingConn struct {
+	net.PacketConn
+
+	mutex sync.Mutex
+	sizes []int
+}
+
+func (c *sizeRecordingConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(p)
+	if err == nil && n > 0 && p[0]&0x80 == 0 { // only record short header packets
+		c.mutex.Lock()
+		c.sizes = append(c.sizes, n)
+		c.mutex.Unlock()
+	}
+	return n, addr, err
+}
+
+func (c *sizeRecordingConn) Sizes() []int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]int{}, c.sizes...)
+}
+
+var _ = Describe("Padding", func() {
+	It("pads packets according to the padding policy", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		conn := &sizeRecordingConn{PacketConn: udpConn}
+		ln, err := quic.Listen(conn, getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		const paddedSize = 600
+		data := GeneratePRData(50 << 10)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			b, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(data))
+			conn.CloseWithError(0, "")
+		}()
+
+		cl, err := quic.DialAddr(
+			context.Background(),
+			ln.Addr().String(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{
+				DisablePathMTUDiscovery: true,
+				PaddingPolicy:           quic.PadToFixedSizes(paddedSize),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer cl.CloseWithError(0, "")
+		str, err := cl.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		// send some small writes, to make sure that small packets are sent
+		for i := 0; i < 10; i++ {
+			_, err = str.Write(data[i*10 : (i+1)*10])
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err = str.Write(data[100:])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+
+		sizes := conn.Sizes()
+		Expect(sizes).ToNot(BeEmpty())
+		var maxSize int
+		for _, s := range sizes {
+			if s > maxSize {
+				maxSize = s
+			}
+		}
+		Expect(maxSize).To(BeNumerically(">", paddedSize))
+		var numPadded int
+		for _, s := range sizes {
+			// all packets are either padded to the fixed size, or have the maximum size
+			Expect(s).To(Or(Equal(paddedSize), Equal(maxSize)))
+			if s == paddedSize {
+				numPadded++
+			}
+		}
+		Expect(numPadded).ToNot(BeZero())
+	})
+})
@@ -0,0 +1,199 @@
+package self_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection Pool", func() {
+	// runServer runs an echo server.
+	// If drop is set, all incoming packets are dropped.
+	runServer := func(drop *atomic.Bool) (net.Addr, <-chan quic.Connection, func()) {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &quic.Transport{
+			Conn: udpConn,
+			FilterIncomingPacket: func(net.Addr, byte) bool {
+				return drop == nil || !drop.Load()
+			},
+		}
+		ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		connChan := make(chan quic.Connection, 10)
+		go func() {
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				connChan <- conn
+				go func() {
+					for {
+						str, err := conn.AcceptStream(context.Background())
+						if err != nil {
+							return
+						}
+						go func() {
+							io.Copy(str, str)
+							str.Close()
+						}()
+					}
+				}()
+			}
+		}()
+		return ln.Addr(), connChan, func() {
+			ln.Close()
+			tr.Close()
+		}
+	}
+
+	newPool := func() *quic.ConnectionPool {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		return &quic.ConnectionPool{
+			Transport: &quic.Transport{Conn: udpConn},
+			TLSConfig: getTLSClientConfig(),
+			Config:    getQuicConfig(nil),
+		}
+	}
+
+	echo := func(str *quic.PooledStream) {
+		_, err := str.Write([]byte("foobar"))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, data).To(Equal([]byte("foobar")))
+	}
+
+	It("reuses connections", func() {
+		addr, connChan, closeServer := runServer(nil)
+		defer closeServer()
+		pool := newPool()
+		defer pool.Transport.Close()
+		defer pool.Close()
+
+		str1, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		echo(str1)
+		str1.Return()
+		str2, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		echo(str2)
+		str2.Return()
+		Expect(str2.Connection()).To(Equal(str1.Connection()))
+		Eventually(connChan).Should(HaveLen(1))
+		Consistently(connChan, scaleDuration(50*time.Millisecond)).Should(HaveLen(1))
+	})
+
+	It("dials additional connections and limits the number of checked out streams", func() {
+		addr, connChan, closeServer := runServer(nil)
+		defer closeServer()
+		pool := newPool()
+		pool.MaxConnectionsPerAddr = 2
+		pool.MaxStreamsPerConnection = 1
+		defer pool.Transport.Close()
+		defer pool.Close()
+
+		str1, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		str2, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str2.Connection()).ToNot(Equal(str1.Connection()))
+		Eventually(connChan).Should(HaveLen(2))
+
+		strChan := make(chan *quic.PooledStream, 1)
+		go func() {
+			defer GinkgoRecover()
+			str, err := pool.Checkout(context.Background(), addr)
+			Expect(err).ToNot(HaveOccurred())
+			strChan <- str
+		}()
+		Consistently(strChan, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+		echo(str2)
+		str2.Return()
+		var str3 *quic.PooledStream
+		Eventually(strChan).Should(Receive(&str3))
+		Expect(str3.Connection()).To(Equal(str2.Connection()))
+		echo(str3)
+		echo(str1)
+
+		// Checkout respects the context
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+		defer cancel()
+		_, err = pool.Checkout(ctx, addr)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("re-dials connections that were closed", func() {
+		addr, connChan, closeServer := runServer(nil)
+		defer closeServer()
+		pool := newPool()
+		defer pool.Transport.Close()
+		defer pool.Close()
+
+		str, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		echo(str)
+		str.Return()
+		var serverConn quic.Connection
+		Eventually(connChan).Should(Receive(&serverConn))
+		serverConn.CloseWithError(0, "")
+		Eventually(str.Connection().Context().Done()).Should(BeClosed())
+		// a new connection is dialed in the background
+		Eventually(connChan).Should(Receive())
+
+		str, err = pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		echo(str)
+		str.Return()
+		Consistently(connChan, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+	})
+
+	It("closes connections that fail the health check", func() {
+		var drop atomic.Bool
+		addr, connChan, closeServer := runServer(&drop)
+		defer closeServer()
+		pool := newPool()
+		pool.HealthCheckPeriod = scaleDuration(50 * time.Millisecond)
+		defer pool.Transport.Close()
+		defer pool.Close()
+
+		str, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		echo(str)
+		str.Return()
+		Eventually(connChan).Should(Receive())
+		// the connection passes the health checks
+		Consistently(str.Connection().Context().Done(), scaleDuration(200*time.Millisecond)).ShouldNot(BeClosed())
+
+		drop.Store(true)
+		Eventually(str.Connection().Context().Done()).Should(BeClosed())
+		Expect(context.Cause(str.Connection().Context())).To(MatchError(&quic.ApplicationError{Remote: false, ErrorMessage: "health check failed"}))
+		drop.Store(false)
+		// a new connection is dialed in the background
+		Eventually(connChan, 5*time.Second).Should(Receive())
+	})
+
+	It("closes all connections", func() {
+		addr, _, closeServer := runServer(nil)
+		defer closeServer()
+		pool := newPool()
+		defer pool.Transport.Close()
+
+		str, err := pool.Checkout(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.Close()).To(Succeed())
+		Eventually(str.Connection().Context().Done()).Should(BeClosed())
+		_, err = pool.Checkout(context.Background(), addr)
+		Expect(err).To(MatchError(quic.ErrPoolClosed))
+	})
+})
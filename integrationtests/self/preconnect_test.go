@@ -0,0 +1,114 @@
+package self_test
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preconnecting", func() {
+	runServer := func() (*quic.Listener, <-chan quic.Connection) {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		connChan := make(chan quic.Connection, 10)
+		go func() {
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				connChan <- conn
+			}
+		}()
+		return ln, connChan
+	}
+
+	newTransport := func() *quic.Transport {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		return &quic.Transport{Conn: udpConn}
+	}
+
+	It("establishes connections ahead of time and keeps them alive", func() {
+		const idleTimeout = 200 * time.Millisecond
+		ln1, _ := runServer()
+		defer ln1.Close()
+		ln2, _ := runServer()
+		defer ln2.Close()
+
+		tr := newTransport()
+		defer tr.Close()
+
+		addrs := []net.Addr{ln1.Addr(), ln2.Addr()}
+		Expect(tr.PreconnectedConnection(addrs[0])).To(BeNil())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(tr.Preconnect(ctx, addrs, getTLSClientConfig(), getQuicConfig(&quic.Config{MaxIdleTimeout: idleTimeout}))).To(Succeed())
+
+		conns := make([]quic.Connection, 0, len(addrs))
+		for _, addr := range addrs {
+			conn := tr.PreconnectedConnection(addr)
+			Expect(conn).ToNot(BeNil())
+			Expect(conn.ConnectionState().TLS.HandshakeComplete).To(BeTrue())
+			conns = append(conns, conn)
+		}
+		Expect(conns[0]).ToNot(Equal(conns[1]))
+
+		// the connections survive the idle timeout
+		time.Sleep(3 * idleTimeout)
+		for i, addr := range addrs {
+			Expect(conns[i].Context().Done()).ToNot(BeClosed())
+			Expect(tr.PreconnectedConnection(addr)).To(Equal(conns[i]))
+		}
+
+		// preconnecting again doesn't create new connections
+		Expect(tr.Preconnect(ctx, addrs, getTLSClientConfig(), getQuicConfig(nil))).To(Succeed())
+		Expect(tr.PreconnectedConnection(addrs[0])).To(Equal(conns[0]))
+
+		str, err := conns[0].OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+	})
+
+	It("removes connections that are closed", func() {
+		ln, connChan := runServer()
+		defer ln.Close()
+
+		tr := newTransport()
+		defer tr.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		Expect(tr.Preconnect(ctx, []net.Addr{ln.Addr()}, getTLSClientConfig(), getQuicConfig(nil))).To(Succeed())
+		Expect(tr.PreconnectedConnection(ln.Addr())).ToNot(BeNil())
+
+		var serverConn quic.Connection
+		Eventually(connChan).Should(Receive(&serverConn))
+		Expect(serverConn.CloseWithError(0, "")).To(Succeed())
+		Eventually(func() quic.Connection { return tr.PreconnectedConnection(ln.Addr()) }).Should(BeNil())
+	})
+
+	It("reports errors", func() {
+		ln, _ := runServer()
+		defer ln.Close()
+
+		tr := newTransport()
+		defer tr.Close()
+
+		unreachable := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		err := tr.Preconnect(ctx, []net.Addr{ln.Addr(), unreachable}, getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("preconnecting to 127.0.0.1:1 failed"))
+		Expect(tr.PreconnectedConnection(ln.Addr())).ToNot(BeNil())
+		Expect(tr.PreconnectedConnection(unreachable)).To(BeNil())
+	})
+})
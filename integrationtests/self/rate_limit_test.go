@@ -0,0 +1,132 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Send Rate Limit", func() {
+	It("limits the sending rate", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		received := make(chan []byte, 2)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				str, err := conn.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				received <- data
+			}
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{SendRateLimit: 500_000}), // the burst defaults to 50 kB
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		send := func(data []byte) time.Duration {
+			start := time.Now()
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			var b []byte
+			Eventually(received, 10*time.Second).Should(Receive(&b))
+			Expect(b).To(Equal(data))
+			return time.Since(start)
+		}
+
+		// the first 50 kB are sent as a burst, the remaining 150 kB take (at least) 300ms
+		Expect(send(GeneratePRData(200_000))).To(BeNumerically(">=", 300*time.Millisecond))
+
+		// lower the limit, and allow a smaller burst
+		conn.SetSendRateLimit(100_000, 10_000)
+		Expect(send(GeneratePRData(50_000))).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+
+	It("limits the sending rate of a single stream", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		type result struct {
+			streamID quic.StreamID
+			data     []byte
+			time     time.Time
+		}
+		received := make(chan result, 2)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				str, err := conn.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					received <- result{streamID: str.StreamID(), data: data, time: time.Now()}
+				}()
+			}
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		limitedData := GeneratePRData(50_000)
+		start := time.Now()
+		limited, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		limited.SetSendRateLimit(100_000, 10_000)
+		unlimited, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		for _, s := range []struct {
+			str  quic.SendStream
+			data []byte
+		}{{limited, limitedData}, {unlimited, PRData}} {
+			go func(str quic.SendStream, data []byte) {
+				defer GinkgoRecover()
+				_, err := str.Write(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}(s.str, s.data)
+		}
+
+		var res1, res2 result
+		Eventually(received, 10*time.Second).Should(Receive(&res1))
+		Eventually(received, 10*time.Second).Should(Receive(&res2))
+		// the unlimited stream isn't slowed down by the limited stream
+		Expect(res1.streamID).To(Equal(unlimited.StreamID()))
+		Expect(res1.data).To(Equal(PRData))
+		Expect(res2.streamID).To(Equal(limited.StreamID()))
+		Expect(res2.data).To(Equal(limitedData))
+		// the first 10 kB are sent as a burst, the remaining 40 kB take (at least) 400ms
+		Expect(res2.time.Sub(start)).To(BeNumerically(">=", 400*time.Millisecond))
+	})
+})
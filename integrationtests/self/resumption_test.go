@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net"
 	"time"
 
@@ -171,4 +172,57 @@ var _ = Describe("TLS session resumption", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(serverConn.ConnectionState().TLS.DidResume).To(BeFalse())
 	})
+
+	It("resumes sessions across servers that share session ticket keys", func() {
+		var closers []io.Closer
+		defer func() {
+			for _, c := range closers {
+				c.Close()
+			}
+		}()
+		listen := func(keys quic.SessionTicketKeyProvider) *quic.Listener {
+			udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+			Expect(err).ToNot(HaveOccurred())
+			tr := &quic.Transport{Conn: udpConn, SessionTicketKeys: keys}
+			closers = append(closers, tr)
+			ln, err := tr.Listen(getTLSConfig(), getQuicConfig(nil))
+			Expect(err).ToNot(HaveOccurred())
+			return ln
+		}
+		fleetKeys := quic.StaticSessionTicketKeys{
+			{ID: "old", Key: [32]byte{1}, NotBefore: time.Now().Add(-2 * time.Hour)},
+			{ID: "current", Key: [32]byte{2}, NotBefore: time.Now().Add(-time.Hour)},
+		}
+
+		puts := make(chan string, 100)
+		tlsConf := getTLSClientConfig()
+		tlsConf.ClientSessionCache = newClientSessionCache(tls.NewLRUClientSessionCache(10), make(chan string, 100), puts)
+		dial := func(ln *quic.Listener) (didResume bool) {
+			conn, err := quic.DialAddr(
+				context.Background(),
+				fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+				tlsConf,
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			serverConn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(serverConn.ConnectionState().TLS.DidResume).To(Equal(conn.ConnectionState().TLS.DidResume))
+			if !conn.ConnectionState().TLS.DidResume {
+				Eventually(puts).Should(Receive())
+			}
+			return conn.ConnectionState().TLS.DidResume
+		}
+
+		Expect(dial(listen(fleetKeys))).To(BeFalse())
+		// A different server of the fleet accepts the session ticket,
+		// even if it hasn't started encrypting session tickets with the newest key yet.
+		Expect(dial(listen(quic.StaticSessionTicketKeys{
+			fleetKeys[0],
+			{ID: "current", Key: [32]byte{2}, NotBefore: time.Now().Add(time.Hour)},
+		}))).To(BeTrue())
+		// A server using a different key doesn't.
+		Expect(dial(listen(quic.StaticSessionTicketKeys{{ID: "other", Key: [32]byte{3}}}))).To(BeFalse())
+	})
 })
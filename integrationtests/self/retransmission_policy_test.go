@@ -0,0 +1,66 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+
+	"github.com/quic-go/quic-go"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retransmission policies", func() {
+	for _, p := range []quic.RetransmissionPolicy{quic.RetransmitFirst, quic.RetransmitProportional, quic.RetransmitDeadlineAware} {
+		policy := p
+
+		It(fmt.Sprintf("transfers data reliably, with retransmission policy %d", policy), func() {
+			server, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{RetransmissionPolicy: policy}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			// drop 5% of the packets sent by the server, to trigger retransmissions
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+				RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				DropPacket: func(d quicproxy.Direction, _ []byte) bool {
+					return d == quicproxy.DirectionIncoming && rand.Intn(20) == 0
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+
+			go func() {
+				defer GinkgoRecover()
+				conn, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				str, err := conn.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRDataLong)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			}()
+
+			conn, err := quic.DialAddr(
+				context.Background(),
+				fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+				getTLSClientConfig(),
+				getQuicConfig(nil),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.CloseWithError(0, "")
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRDataLong))
+		})
+	}
+})
@@ -0,0 +1,132 @@
+package self_test
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry offloading", func() {
+	// runFrontend runs a minimal frontend, which sends Retry packets for all Initial packets that don't carry a token,
+	// and forwards all other packets to the backend.
+	// It only supports a single client.
+	runFrontend := func(key quic.TokenGeneratorKey, backend net.Addr, numRetries, numBackendRetries *atomic.Int32) *net.UDPConn {
+		frontend, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		backendConn, err := net.DialUDP("udp", nil, backend.(*net.UDPAddr))
+		Expect(err).ToNot(HaveOccurred())
+		clientAddrChan := make(chan net.Addr, 1)
+
+		go func() {
+			defer GinkgoRecover()
+			defer backendConn.Close()
+			b := make([]byte, protocol.MaxPacketBufferSize)
+			for {
+				n, addr, err := frontend.ReadFrom(b)
+				if err != nil {
+					return
+				}
+				select {
+				case clientAddrChan <- addr:
+				default:
+				}
+				data := b[:n]
+				if wire.IsLongHeaderPacket(data[0]) {
+					hdr, _, _, err := wire.ParsePacket(data)
+					Expect(err).ToNot(HaveOccurred())
+					if hdr.Type == protocol.PacketTypeInitial && len(hdr.Token) == 0 {
+						numRetries.Add(1)
+						srcConnID := make([]byte, 8)
+						rand.Read(srcConnID)
+						token, err := quic.NewRetryToken(key, addr, hdr.DestConnectionID, quic.ConnectionIDFromBytes(srcConnID))
+						Expect(err).ToNot(HaveOccurred())
+						retry, err := quic.AppendRetryPacket(nil, hdr.Version, hdr.SrcConnectionID, quic.ConnectionIDFromBytes(srcConnID), hdr.DestConnectionID, token)
+						Expect(err).ToNot(HaveOccurred())
+						_, err = frontend.WriteTo(retry, addr)
+						Expect(err).ToNot(HaveOccurred())
+						continue
+					}
+				}
+				if _, err := backendConn.Write(data); err != nil {
+					return
+				}
+			}
+		}()
+
+		go func() {
+			defer GinkgoRecover()
+			b := make([]byte, protocol.MaxPacketBufferSize)
+			var clientAddr net.Addr
+			for {
+				n, err := backendConn.Read(b)
+				if err != nil {
+					return
+				}
+				if clientAddr == nil {
+					clientAddr = <-clientAddrChan
+				}
+				data := b[:n]
+				if wire.IsLongHeaderPacket(data[0]) {
+					if hdr, _, _, err := wire.ParsePacket(data); err == nil && hdr.Type == protocol.PacketTypeRetry {
+						numBackendRetries.Add(1)
+					}
+				}
+				if _, err := frontend.WriteTo(data, clientAddr); err != nil {
+					return
+				}
+			}
+		}()
+		return frontend
+	}
+
+	It("accepts Retry tokens minted by a frontend", func() {
+		var key quic.TokenGeneratorKey
+		rand.Read(key[:])
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		tr := &quic.Transport{Conn: udpConn, TokenGeneratorKey: &key}
+		defer tr.Close()
+		ln, err := tr.Listen(getTLSConfig(), getQuicConfig(&quic.Config{
+			RequireAddressValidation: func(net.Addr) bool { return true },
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		var numRetries, numBackendRetries atomic.Int32
+		frontend := runFrontend(key, ln.Addr(), &numRetries, &numBackendRetries)
+		defer frontend.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, frontend.LocalAddr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		serverConn, err := ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		sstr, err := serverConn.AcceptUniStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(sstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData))
+
+		Expect(numRetries.Load()).To(BeEquivalentTo(1))
+		Expect(numBackendRetries.Load()).To(BeZero())
+	})
+})
@@ -2,6 +2,7 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/quic-go/quic-go"
 	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+	"github.com/quic-go/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -110,4 +112,91 @@ var _ = Describe("non-zero RTT", func() {
 			downloadFile(proxy.LocalPort())
 		})
 	}
+	It("exposes the RTT samples", func() {
+		const rtt = 20 * time.Millisecond
+		ln := runServer()
+		defer ln.Close()
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return rtt / 2 },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+
+		stats := conn.RTTStats()
+		Expect(stats.MinRTT).To(BeNumerically(">=", rtt))
+		Expect(stats.SmoothedRTT).To(BeNumerically(">=", rtt))
+		Expect(stats.Samples).ToNot(BeEmpty())
+		Expect(len(stats.Samples)).To(BeNumerically("<=", 64))
+		Expect(stats.Samples[len(stats.Samples)-1].RTT).To(Equal(stats.LatestRTT))
+		for i, s := range stats.Samples {
+			Expect(s.RTT).To(BeNumerically(">=", rtt))
+			if i > 0 {
+				Expect(s.Time).ToNot(BeTemporally("<", stats.Samples[i-1].Time))
+			}
+		}
+	})
+
+	It("measures the RTT using PING frames", func() {
+		const rtt = 20 * time.Millisecond
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr:  fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			DelayPacket: func(quicproxy.Direction, []byte) time.Duration { return rtt / 2 },
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			measured, err := conn.Ping(ctx)
+			cancel()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(measured).To(BeNumerically(">=", rtt))
+			// the peer might delay the acknowledgment by up to max_ack_delay
+			Expect(measured).To(BeNumerically("<", rtt+protocol.MaxAckDelay+scaleDuration(50*time.Millisecond)))
+		}
+	})
+
+	It("returns an error from Ping when the connection is closed", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.CloseWithError(0x1337, "")).To(Succeed())
+		_, err = conn.Ping(context.Background())
+		var appErr *quic.ApplicationError
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(appErr.ErrorCode).To(BeEquivalentTo(0x1337))
+	})
 })
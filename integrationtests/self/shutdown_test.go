@@ -0,0 +1,84 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Graceful Shutdown", func() {
+	const goAwayErrorCode = 0x42
+
+	It("waits for connections to drain", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		serverConn, err := ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		var notified []quic.Connection
+		shutdownDone := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			shutdownDone <- ln.Shutdown(context.Background(), func(c quic.Connection) {
+				notified = append(notified, c)
+				// signal the client to go away
+				str, err := c.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write([]byte("goaway"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+			})
+		}()
+
+		// Accept returns right away, and new connections are refused.
+		_, err = ln.Accept(ctx)
+		Expect(err).To(MatchError(quic.ErrServerClosed))
+		_, err = quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).To(HaveOccurred())
+		var transportErr *quic.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(Equal(quic.ConnectionRefused))
+
+		// the existing connection is still usable
+		str, err := conn.AcceptUniStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("goaway"))
+		Consistently(shutdownDone, scaleDuration(50*time.Millisecond)).ShouldNot(Receive())
+
+		Expect(conn.CloseWithError(goAwayErrorCode, "")).To(Succeed())
+		Eventually(shutdownDone).Should(Receive(BeNil()))
+		Expect(notified).To(Equal([]quic.Connection{serverConn}))
+	})
+
+	It("closes connections that don't drain before the deadline", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := quic.DialAddr(ctx, ln.Addr().String(), getTLSClientConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+		defer shutdownCancel()
+		Expect(ln.Shutdown(shutdownCtx, nil)).To(MatchError(context.DeadlineExceeded))
+		Eventually(conn.Context().Done()).Should(BeClosed())
+	})
+})
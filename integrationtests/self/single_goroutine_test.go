@@ -0,0 +1,87 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"time"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Single goroutine connections", func() {
+	It("transfers data", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{SingleGoroutine: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRDataLong)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{SingleGoroutine: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong))
+	})
+
+	It("uses a single goroutine per idle connection", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{SingleGoroutine: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &quic.Transport{Conn: udpConn}
+		defer tr.Close()
+		// start the Transport's goroutines before counting
+		_, err = tr.ListenEarly(getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		time.Sleep(scaleDuration(10 * time.Millisecond))
+		numGoroutines := runtime.NumGoroutine()
+
+		const numConns = 20
+		var conns []quic.Connection
+		for i := 0; i < numConns; i++ {
+			conn, err := tr.Dial(
+				context.Background(),
+				server.Addr(),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{SingleGoroutine: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			conns = append(conns, conn)
+			_, err = server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		defer func() {
+			for _, conn := range conns {
+				conn.CloseWithError(0, "")
+			}
+		}()
+
+		// one goroutine for every client and every server connection
+		Eventually(func() int { return runtime.NumGoroutine() - numGoroutines }).Should(BeNumerically("<=", 2*numConns))
+	})
+})
@@ -0,0 +1,105 @@
+//go:build go1.21
+
+package self_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type syncedJSONBuffer struct {
+	mutex sync.Mutex
+	bytes.Buffer
+}
+
+func (b *syncedJSONBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *syncedJSONBuffer) Records() []map[string]any {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var records []map[string]any
+	dec := json.NewDecoder(bytes.NewReader(b.Bytes()))
+	for dec.More() {
+		var r map[string]any
+		Expect(dec.Decode(&r)).To(Succeed())
+		records = append(records, r)
+	}
+	return records
+}
+
+var _ = Describe("Structured Logging", func() {
+	It("logs using slog", func() {
+		var serverLog, clientLog syncedJSONBuffer
+		opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+		serverUDPConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		serverTr := &quic.Transport{
+			Conn:      serverUDPConn,
+			Logger:    slog.New(slog.NewJSONHandler(&serverLog, opts)),
+			LogLevels: map[string]quic.LogLevel{"server": quic.LogLevelInfo},
+		}
+		defer serverTr.Close()
+		ln, err := serverTr.Listen(getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+
+		clientUDPConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		clientTr := &quic.Transport{Conn: clientUDPConn}
+		defer clientTr.Close()
+		// set the logger on the Config, instead of the Transport
+		conn, err := clientTr.Dial(
+			context.Background(),
+			ln.Addr(),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{Logger: slog.New(slog.NewJSONHandler(&clientLog, opts))}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		conn.CloseWithError(0, "")
+		Eventually(serverConn.Context().Done()).Should(BeClosed())
+
+		serverRecords := serverLog.Records()
+		Expect(serverRecords).ToNot(BeEmpty())
+		var numConnRecords int
+		for _, r := range serverRecords {
+			Expect(r).To(HaveKey("msg"))
+			if r["subsystem"] != "server" {
+				continue
+			}
+			Expect(r["level"]).ToNot(Equal("DEBUG"))
+			if _, ok := r["conn_id"]; ok {
+				numConnRecords++
+				Expect(r).To(HaveKeyWithValue("remote_addr", clientUDPConn.LocalAddr().String()))
+			}
+		}
+		Expect(numConnRecords).ToNot(BeZero())
+
+		clientRecords := clientLog.Records()
+		Expect(clientRecords).ToNot(BeEmpty())
+		var hasDebug bool
+		for _, r := range clientRecords {
+			Expect(r).To(HaveKeyWithValue("subsystem", "client"))
+			Expect(r).To(HaveKey("conn_id"))
+			Expect(r).To(HaveKeyWithValue("remote_addr", ln.Addr().String()))
+			if r["level"] == "DEBUG" {
+				hasDebug = true
+			}
+		}
+		Expect(hasDebug).To(BeTrue())
+	})
+})
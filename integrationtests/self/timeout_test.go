@@ -2,6 +2,7 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	mrand "math/rand"
@@ -64,6 +65,10 @@ var _ = Describe("Timeout tests", func() {
 		var err error
 		Eventually(errChan).Should(Receive(&err))
 		checkTimeoutError(err)
+		var hsErr *quic.HandshakeError
+		Expect(errors.As(err, &hsErr)).To(BeTrue())
+		Expect(hsErr.Reason).To(Equal(quic.HandshakeFailureTimeout))
+		Expect(hsErr.EncryptionLevel).To(Equal(logging.EncryptionInitial))
 	})
 
 	It("returns the context error when the context expires", func() {
@@ -314,65 +319,106 @@ var _ = Describe("Timeout tests", func() {
 		})
 	})
 
-	It("does not time out if keepalive is set", func() {
-		const idleTimeout = 500 * time.Millisecond
+	It("extends the idle timeout at runtime", func() {
+		idleTimeout := scaleDuration(100 * time.Millisecond)
 
-		server, err := quic.ListenAddr(
-			"localhost:0",
-			getTLSConfig(),
-			getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
-		)
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
 		Expect(err).ToNot(HaveOccurred())
 		defer server.Close()
 
-		serverConnClosed := make(chan struct{})
+		serverConnChan := make(chan quic.Connection, 1)
 		go func() {
 			defer GinkgoRecover()
 			conn, err := server.Accept(context.Background())
 			Expect(err).ToNot(HaveOccurred())
-			conn.AcceptStream(context.Background()) // blocks until the connection is closed
-			close(serverConnClosed)
+			serverConnChan <- conn
 		}()
 
-		var drop atomic.Bool
-		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
-			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
-			DropPacket: func(quicproxy.Direction, []byte) bool {
-				return drop.Load()
-			},
-		})
-		Expect(err).ToNot(HaveOccurred())
-		defer proxy.Close()
-
 		conn, err := quic.DialAddr(
 			context.Background(),
-			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
 			getTLSClientConfig(),
-			getQuicConfig(&quic.Config{
-				MaxIdleTimeout:          idleTimeout,
-				KeepAlivePeriod:         idleTimeout / 2,
-				DisablePathMTUDiscovery: true,
-			}),
+			getQuicConfig(&quic.Config{MaxIdleTimeout: idleTimeout}),
 		)
 		Expect(err).ToNot(HaveOccurred())
+		start := time.Now()
+		conn.SetIdleTimeout(8 * idleTimeout)
+		var serverConn quic.Connection
+		Eventually(serverConnChan).Should(Receive(&serverConn))
+
+		// the connection is kept alive using keep-alives...
+		Consistently(conn.Context().Done(), 4*idleTimeout).ShouldNot(BeClosed())
+		Expect(serverConn.Context().Done()).ToNot(BeClosed())
+		// ... until there was no activity for the extended idle timeout
+		Eventually(conn.Context().Done(), 8*idleTimeout).Should(BeClosed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 8*idleTimeout))
+		checkTimeoutError(context.Cause(conn.Context()))
+	})
 
-		// wait longer than the idle timeout
-		time.Sleep(3 * idleTimeout)
-		str, err := conn.OpenUniStream()
-		Expect(err).ToNot(HaveOccurred())
-		_, err = str.Write([]byte("foobar"))
-		Expect(err).ToNot(HaveOccurred())
-		Consistently(serverConnClosed).ShouldNot(BeClosed())
+	for _, lp := range []bool{false, true} {
+		lowPower := lp
 
-		// idle timeout will still kick in if pings are dropped
-		drop.Store(true)
-		time.Sleep(2 * idleTimeout)
-		_, err = str.Write([]byte("foobar"))
-		checkTimeoutError(err)
+		It(fmt.Sprintf("does not time out if keepalive is set (low-power mode: %t)", lowPower), func() {
+			const idleTimeout = 500 * time.Millisecond
 
-		Expect(server.Close()).To(Succeed())
-		Eventually(serverConnClosed).Should(BeClosed())
-	})
+			server, err := quic.ListenAddr(
+				"localhost:0",
+				getTLSConfig(),
+				getQuicConfig(&quic.Config{DisablePathMTUDiscovery: true}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer server.Close()
+
+			serverConnClosed := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				conn, err := server.Accept(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				conn.AcceptStream(context.Background()) // blocks until the connection is closed
+				close(serverConnClosed)
+			}()
+
+			var drop atomic.Bool
+			proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+				RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				DropPacket: func(quicproxy.Direction, []byte) bool {
+					return drop.Load()
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer proxy.Close()
+
+			conn, err := quic.DialAddr(
+				context.Background(),
+				fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{
+					MaxIdleTimeout:          idleTimeout,
+					KeepAlivePeriod:         idleTimeout / 2,
+					LowPowerMode:            lowPower,
+					DisablePathMTUDiscovery: true,
+				}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			// wait longer than the idle timeout
+			time.Sleep(3 * idleTimeout)
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(serverConnClosed).ShouldNot(BeClosed())
+
+			// idle timeout will still kick in if pings are dropped
+			drop.Store(true)
+			time.Sleep(2 * idleTimeout)
+			_, err = str.Write([]byte("foobar"))
+			checkTimeoutError(err)
+
+			Expect(server.Close()).To(Succeed())
+			Eventually(serverConnClosed).Should(BeClosed())
+		})
+	}
 
 	Context("faulty packet conns", func() {
 		const handshakeTimeout = time.Second / 2
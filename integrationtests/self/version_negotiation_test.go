@@ -0,0 +1,96 @@
+package self_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version Negotiation", func() {
+	var (
+		ln                    *quic.Listener
+		infoChan              chan *quic.VersionNegotiationInfo
+		origSupportedVersions []quic.VersionNumber
+	)
+
+	BeforeEach(func() {
+		// the test suite only enables the QUIC version under test
+		origSupportedVersions = protocol.SupportedVersions
+		protocol.SupportedVersions = []quic.VersionNumber{quic.Version1, quic.Version2}
+		infoChan = make(chan *quic.VersionNegotiationInfo, 10)
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{
+			Versions:               []quic.VersionNumber{quic.Version1},
+			SentVersionNegotiation: func(info *quic.VersionNegotiationInfo) { infoChan <- info },
+		}))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(ln.Close()).To(Succeed())
+		protocol.SupportedVersions = origSupportedVersions
+	})
+
+	dial := func(conf *quic.Config) (quic.Connection, error) {
+		return quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(conf),
+		)
+	}
+
+	It("fails the dial if there's no common version", func() {
+		_, err := dial(&quic.Config{Versions: []quic.VersionNumber{quic.Version2}})
+		Expect(err).To(HaveOccurred())
+		var vnErr *quic.VersionNegotiationError
+		Expect(errors.As(err, &vnErr)).To(BeTrue())
+		Expect(vnErr.Theirs).To(ContainElement(quic.Version1))
+
+		var info *quic.VersionNegotiationInfo
+		Eventually(infoChan).Should(Receive(&info))
+		Expect(info.ClientVersion).To(Equal(quic.Version2))
+		Expect(info.ServerVersions).To(Equal([]quic.VersionNumber{quic.Version1}))
+		Expect(info.RemoteAddr.(*net.UDPAddr).IP.IsLoopback()).To(BeTrue())
+	})
+
+	It("retries with the version chosen by the ChooseVersion callback", func() {
+		var clientInfo *quic.VersionNegotiationInfo
+		conn, err := dial(&quic.Config{
+			Versions: []quic.VersionNumber{quic.Version2},
+			ChooseVersion: func(info *quic.VersionNegotiationInfo) (quic.VersionNumber, bool) {
+				clientInfo = info
+				return quic.Version1, true
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(conn.ConnectionState().Version).To(Equal(quic.Version1))
+		Expect(conn.ConnectionState().ServerVersions).To(ContainElement(quic.Version1))
+
+		Expect(clientInfo).ToNot(BeNil())
+		Expect(clientInfo.ClientVersion).To(Equal(quic.Version2))
+		Expect(clientInfo.ServerVersions).To(ContainElement(quic.Version1))
+		Eventually(infoChan).Should(Receive())
+
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer serverConn.CloseWithError(0, "")
+		Expect(serverConn.ConnectionState().ServerVersions).To(BeEmpty())
+	})
+
+	It("doesn't report server versions if no Version Negotiation happened", func() {
+		conn, err := dial(nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(conn.ConnectionState().ServerVersions).To(BeEmpty())
+		Consistently(infoChan).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,69 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	quicproxy "github.com/quic-go/quic-go/integrationtests/tools/proxy"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Writing application-owned buffers", func() {
+	It("transfers data from application-owned buffers, with packet loss", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		// drop 2% of the packets sent by the server, to trigger retransmissions
+		proxy, err := quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+			RemoteAddr: fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			DropPacket: func(d quicproxy.Direction, _ []byte) bool {
+				return d == quicproxy.DirectionIncoming && rand.Intn(50) == 0
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer proxy.Close()
+
+		const numBuffers = 10
+		var numReleased atomic.Int32
+		go func() {
+			defer GinkgoRecover()
+			conn, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			chunkSize := len(PRDataLong) / numBuffers
+			for i := 0; i < numBuffers; i++ {
+				buf := PRDataLong[i*chunkSize : (i+1)*chunkSize]
+				n, err := str.WriteBuffer(buf, func() { numReleased.Add(1) })
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(chunkSize))
+			}
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong[:len(PRDataLong)/numBuffers*numBuffers]))
+		// all buffers are released once the data has been acknowledged
+		Eventually(numReleased.Load, 5*time.Second).Should(BeEquivalentTo(numBuffers))
+	})
+})
@@ -298,6 +298,54 @@ var _ = Describe("0-RTT", func() {
 		})
 	}
 
+	It("marks stream data received in 0-RTT", func() {
+		tlsConf := getTLSConfig()
+		clientTLSConf := getTLSClientConfig()
+		dialAndReceiveSessionTicket(tlsConf, nil, clientTLSConf)
+
+		ln, err := quic.ListenAddrEarly("localhost:0", tlsConf, getQuicConfig(&quic.Config{Allow0RTT: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		proxy, _ := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+		defer proxy.Close()
+
+		conn, err := quic.DialAddrEarly(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", proxy.LocalPort()),
+			clientTLSConf,
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		<-conn.HandshakeComplete()
+		Expect(conn.ConnectionState().Used0RTT).To(BeTrue())
+		str2, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str2.Write([]byte("raboof"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str2.Close()).To(Succeed())
+
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		rstr, err := serverConn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foobar"))
+		Expect(rstr.EarlyData()).To(Equal([]quic.EarlyDataRange{{Start: 0, End: 6}}))
+		rstr, err = serverConn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err = io.ReadAll(rstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("raboof"))
+		Expect(rstr.EarlyData()).To(BeEmpty())
+	})
+
 	// Test that data intended to be sent with 1-RTT protection is not sent in 0-RTT packets.
 	It("waits for a connection until the handshake is done", func() {
 		tlsConf := getTLSConfig()
@@ -445,6 +493,38 @@ var _ = Describe("0-RTT", func() {
 		Expect(get0RTTPackets(counter.getRcvdLongHeaderPackets())).ToNot(BeEmpty())
 	})
 
+	It("limits the amount of 0-RTT data processed by the server", func() {
+		const max0RTTData = 5000
+		tlsConf := getTLSConfig()
+		clientConf := getTLSClientConfig()
+		dialAndReceiveSessionTicket(tlsConf, nil, clientConf)
+
+		var num0RTTDropped atomic.Uint32
+		ln, err := quic.ListenAddrEarly(
+			"localhost:0",
+			tlsConf,
+			getQuicConfig(&quic.Config{
+				Allow0RTT:   true,
+				Max0RTTData: max0RTTData,
+				Tracer: newTracer(&logging.ConnectionTracer{
+					DroppedPacket: func(pt logging.PacketType, _ logging.ByteCount, reason logging.PacketDropReason) {
+						if pt == logging.PacketType0RTT && reason == logging.PacketDropDOSPrevention {
+							num0RTTDropped.Add(1)
+						}
+					},
+				}),
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		proxy, _ := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
+		defer proxy.Close()
+
+		// all data is transferred, the data exceeding the limit is retransmitted after the handshake
+		transfer0RTTData(ln, proxy.LocalPort(), protocol.DefaultConnectionIDLength, clientConf, nil, PRData)
+		Expect(num0RTTDropped.Load()).ToNot(BeZero())
+	})
+
 	It("retransmits all 0-RTT data when the server performs a Retry", func() {
 		var mutex sync.Mutex
 		var firstConnID, secondConnID *protocol.ConnectionID
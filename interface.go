@@ -10,6 +10,7 @@ import (
 
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/logging"
 )
 
@@ -26,6 +27,20 @@ const (
 	Version2 = protocol.Version2
 )
 
+// An AddressDiscoveryMode configures the QUIC Address Discovery extension, see Config.AddressDiscovery.
+type AddressDiscoveryMode = protocol.AddressDiscoveryMode
+
+const (
+	// AddressDiscoveryDisabled disables the QUIC Address Discovery extension.
+	AddressDiscoveryDisabled = protocol.AddressDiscoveryDisabled
+	// AddressDiscoveryProvide reports the observed address to peers that request it.
+	AddressDiscoveryProvide = protocol.AddressDiscoveryProvide
+	// AddressDiscoveryReceive requests the peer to report the address it observes, see Connection.ObservedAddress.
+	AddressDiscoveryReceive = protocol.AddressDiscoveryReceive
+	// AddressDiscoveryProvideAndReceive combines AddressDiscoveryProvide and AddressDiscoveryReceive.
+	AddressDiscoveryProvideAndReceive = protocol.AddressDiscoveryProvideAndReceive
+)
+
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
@@ -50,6 +65,9 @@ type TokenStore interface {
 // when the server rejects a 0-RTT connection attempt.
 var Err0RTTRejected = errors.New("0-RTT rejected")
 
+// ErrCloseTimeout is returned by CloseWithTimeout if the peer didn't acknowledge all stream data before the timeout.
+var ErrCloseTimeout = errors.New("timeout waiting for stream data to be acknowledged")
+
 // ConnectionTracingKey can be used to associate a ConnectionTracer with a Connection.
 // It is set on the Connection.Context() context,
 // as well as on the context passed to logging.Tracer.NewConnectionTracer.
@@ -57,10 +75,28 @@ var ConnectionTracingKey = connTracingCtxKey{}
 
 type connTracingCtxKey struct{}
 
+// RemoteAddrContextKey can be used to find out the remote address of a connection
+// from the context passed to Config.Tracer.
+// The value is a net.Addr.
+var RemoteAddrContextKey = remoteAddrCtxKey{}
+
+type remoteAddrCtxKey struct{}
+
 // QUICVersionContextKey can be used to find out the QUIC version of a TLS handshake from the
 // context returned by tls.Config.ClientHelloInfo.Context.
 var QUICVersionContextKey = handshake.QUICVersionContextKey
 
+// A HandshakeWorkerPool performs the expensive operations of TLS handshakes, see Config.HandshakeWorkerPool.
+type HandshakeWorkerPool = handshake.WorkerPool
+
+// NewHandshakeWorkerPool creates a new HandshakeWorkerPool,
+// that performs the handshake operations of at most workers connections concurrently.
+// Workers are only started when there are operations to perform, and exit when they're done.
+// If too many operations are queued, they are performed on the connection's goroutine instead.
+func NewHandshakeWorkerPool(workers int) *HandshakeWorkerPool {
+	return handshake.NewWorkerPool(workers)
+}
+
 // Stream is the interface implemented by QUIC streams
 // In addition to the errors listed on the Connection,
 // calls to stream functions can return a StreamError if the stream is canceled.
@@ -95,6 +131,17 @@ type ReceiveStream interface {
 	// A zero value for t means Read will not time out.
 
 	SetReadDeadline(t time.Time) error
+	// EarlyData returns the byte ranges of stream data that were received in 0-RTT packets, sorted by offset.
+	// It returns nil if none of the data on this stream was received in 0-RTT.
+	// 0-RTT data can be replayed by an attacker (see section 8 of RFC 9001).
+	// Servers should defer non-idempotent processing of this data until the handshake completes.
+	EarlyData() []EarlyDataRange
+}
+
+// An EarlyDataRange is a range of stream data that was received in 0-RTT packets.
+// It starts at offset Start (inclusive) and ends at offset End (exclusive).
+type EarlyDataRange struct {
+	Start, End uint64
 }
 
 // A SendStream is a unidirectional Send Stream.
@@ -109,6 +156,14 @@ type SendStream interface {
 	// If the connection was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
+	// WriteBuffer writes data from an application-owned buffer to the stream.
+	// Unlike Write, it doesn't copy p: STREAM frames reference p until the data was acknowledged by the peer.
+	// This reduces memory bandwidth when sending large amounts of data.
+	// It blocks until all data has been sent out (or an error occurs), and returns the number of bytes written.
+	// Once the stream doesn't reference p any more, release is called (from any goroutine),
+	// after which the application may reuse p. It must not modify p before that.
+	// This happens when all data written has been acknowledged, or when the stream is canceled or the connection is closed.
+	WriteBuffer(p []byte, release func()) (int, error)
 	// Close closes the write-direction of the stream.
 	// Future calls to Write are not permitted after calling Close.
 	// It must not be called concurrently with Write.
@@ -131,8 +186,38 @@ type SendStream interface {
 	// some data was successfully written.
 	// A zero value for t means Write will not time out.
 	SetWriteDeadline(t time.Time) error
+	// SetSendRateLimit limits the rate (in bytes per second) at which data is sent on this stream,
+	// regardless of the headroom in the congestion window.
+	// The burst is the amount of data (in bytes) that can be sent at once after the stream was idle.
+	// If it is 0, it defaults to 100ms worth of data (but at least a full packet).
+	// This limit applies in addition to the limit of the connection (see Connection.SetSendRateLimit).
+	// A rate of 0 removes the limit.
+	SetSendRateLimit(bytesPerSecond, burst uint64)
+	// SetBlockedCallback sets a callback that is called when sending new data on this stream
+	// becomes blocked by flow control or by congestion control, and when it is unblocked again.
+	// It is called from the connection's run loop, and therefore must not block.
+	// A nil callback removes the callback.
+	SetBlockedCallback(func(reason SendBlockedReason, blocked bool))
+	// AvailableSendWindow returns the number of bytes that flow control currently allows to be sent on this stream,
+	// i.e. the minimum of the stream's and the connection's send window.
+	// The value is updated when data is sent on the stream and when the peer increases the stream's flow control limit,
+	// so it can lag behind increases of the connection's flow control limit.
+	// It is 0 until the stream first had data to send.
+	AvailableSendWindow() uint64
 }
 
+// A SendBlockedReason is the reason why sending data on a stream is blocked.
+type SendBlockedReason uint8
+
+const (
+	// SendBlockedStreamFlowControl means that the stream's flow control limit was reached.
+	SendBlockedStreamFlowControl SendBlockedReason = 1 + iota
+	// SendBlockedConnectionFlowControl means that the connection's flow control limit was reached.
+	SendBlockedConnectionFlowControl
+	// SendBlockedCongestion means that the congestion window doesn't allow sending more data.
+	SendBlockedCongestion
+)
+
 // A Connection is a QUIC connection between two peers.
 // Calls to the connection (and to streams) can return the following types of errors:
 // * ApplicationError: for errors triggered by the application running on top of QUIC
@@ -178,7 +263,13 @@ type Connection interface {
 	RemoteAddr() net.Addr
 	// CloseWithError closes the connection with an error.
 	// The error string will be sent to the peer.
+	// It returns once the CONNECTION_CLOSE has been written to the underlying net.PacketConn.
+	// Stream data that has not yet been acknowledged by the peer might be lost.
 	CloseWithError(ApplicationErrorCode, string) error
+	// CloseWithTimeout closes the connection with an error, once all stream data has been acknowledged by the peer.
+	// If the data is not acknowledged within the timeout, the connection is closed anyway, and ErrCloseTimeout is returned.
+	// Like CloseWithError, it returns once the CONNECTION_CLOSE has been written to the underlying net.PacketConn.
+	CloseWithTimeout(code ApplicationErrorCode, desc string, timeout time.Duration) error
 	// Context returns a context that is cancelled when the connection is closed.
 	// The cancellation cause is set to the error that caused the connection to
 	// close, or `context.Canceled` in case the listener is closed first.
@@ -186,11 +277,64 @@ type Connection interface {
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// HandshakeConfirmed returns a channel that is closed when the handshake is confirmed (see section 4.1.2 of RFC 9001).
+	// For the server, the handshake is confirmed as soon as it completes.
+	// The client only confirms the handshake when it receives a HANDSHAKE_DONE frame
+	// (or an acknowledgment for a 1-RTT packet), i.e. about one round trip after completion.
+	// Only then, the peer is guaranteed to have 1-RTT keys, and the Handshake keys are discarded.
+	// If the connection is closed before the handshake is confirmed, the channel is never closed.
+	HandshakeConfirmed() <-chan struct{}
+	// RTTStats returns the round-trip time statistics of the connection,
+	// including the most recent RTT samples.
+	RTTStats() RTTStats
+	// Ping sends a PING frame and blocks until it is acknowledged by the peer.
+	// It returns the round-trip time measured for the PING frame.
+	// This includes the time the peer waited before sending the acknowledgment (at most its max_ack_delay).
+	// If the packet carrying the PING frame is lost, the PING frame is retransmitted.
+	Ping(context.Context) (time.Duration, error)
+	// ProbeMTU sends a Path MTU Discovery probe packet of the given size (the size of the UDP payload) right away,
+	// instead of waiting for the next scheduled probe, e.g. when the application learns that the path changed.
+	// It blocks until the probe packet is acknowledged, in which case the packet size is raised to size,
+	// or declared lost, in which case an error is returned.
+	// If the current packet size is already at least size, it returns immediately.
+	// Probing only starts once the handshake is confirmed, and is not available if DisablePathMTUDiscovery is set.
+	ProbeMTU(ctx context.Context, size int) error
 
 	// SendMessage sends a message as a datagram, as specified in RFC 9221.
 	SendMessage([]byte) error
 	// ReceiveMessage gets a message received in a datagram, as specified in RFC 9221.
 	ReceiveMessage(context.Context) ([]byte, error)
+	// ObservedAddress returns the address of this endpoint, as observed by the peer.
+	// It uses the QUIC Address Discovery extension, which needs to be enabled on both endpoints (see Config.AddressDiscovery).
+	// It blocks until the peer reported the address, the context is canceled, or the connection is closed.
+	ObservedAddress(context.Context) (net.Addr, error)
+	// SendExtensionFrame queues a frame of a type registered in Config.ExtensionFrames.
+	// The frame is encoded using the ExtensionFrame.Encode callback.
+	// It returns an error if the peer didn't signal support for the frame type,
+	// see ConnectionState.ExtensionFrames.
+	SendExtensionFrame(typ uint64, frame any) error
+	// SetSendRateLimit sets the maximum rate (in bytes per second) at which STREAM data is sent,
+	// and the size of the burst (in bytes), overriding Config.SendRateLimit and Config.SendRateLimitBurst.
+	// A rate of 0 removes the limit.
+	SetSendRateLimit(bytesPerSecond, burst uint64)
+	// SetIdleTimeout changes the idle timeout of the connection, e.g. to allow idle connections in a connection pool
+	// to live longer than connections that are actively used.
+	// The idle timeout can't be longer than the max_idle_timeout advertised by the peer (and by us).
+	// A longer idle timeout is emulated by sending keep-alive PINGs, until there was no activity
+	// (i.e. no packets containing frames other than PING and ACK frames) for the duration of the timeout.
+	// A timeout of 0 restores the negotiated idle timeout.
+	// If Config.KeepAlivePeriod is set, keep-alives keep the connection alive regardless of the idle timeout.
+	SetIdleTimeout(time.Duration)
+	// Rebind moves the connection to a new local socket, e.g. to a new ephemeral port or to a different interface.
+	// This is useful for privacy-motivated port rotation: packets sent on the new socket use a new connection ID,
+	// such that on-path observers can't link them to the packets sent before.
+	// Packets are sent on the new socket right away, while the new path is validated (see section 8.2 of RFC 9000).
+	// If path validation fails, the connection falls back to the previous socket.
+	// The connection takes ownership of pconn: it is closed when path validation fails,
+	// when the connection is rebound again, or when the connection is closed.
+	// Only clients can rebind, and only after the handshake was confirmed.
+	// The peer needs to have issued an unused connection ID, and must not have disabled active migration.
+	Rebind(pconn net.PacketConn) error
 }
 
 // An EarlyConnection is a connection that is handshaking.
@@ -215,6 +359,75 @@ type StatelessResetKey [32]byte
 // TokenGeneratorKey is a key used to encrypt session resumption tokens.
 type TokenGeneratorKey = handshake.TokenProtectorKey
 
+// A Clock is a source of time, see Config.Clock.
+// Implementations must be safe for concurrent use.
+type Clock = utils.Clock
+
+// A ClockTimer is a timer created by a Clock.
+type ClockTimer = utils.ClockTimer
+
+// A Logger logs structured messages, see Transport.Logger.
+// It is implemented by *slog.Logger.
+type Logger = utils.StructuredLogger
+
+// LogLevel is the level of log messages, see Transport.LogLevels.
+type LogLevel = utils.LogLevel
+
+const (
+	// LogLevelNothing disables logging.
+	LogLevelNothing = utils.LogLevelNothing
+	// LogLevelError only logs errors.
+	LogLevelError = utils.LogLevelError
+	// LogLevelInfo logs errors and informational messages, e.g. about sent and received packets.
+	LogLevelInfo = utils.LogLevelInfo
+	// LogLevelDebug logs all messages, e.g. the contents of sent and received packets.
+	LogLevelDebug = utils.LogLevelDebug
+)
+
+// A BufferAllocator allocates the buffers used for sending and receiving packets,
+// and for holding the data of received STREAM frames, see SetBufferAllocator.
+// Implementations must be safe for concurrent use.
+type BufferAllocator = utils.BufferAllocator
+
+// A BufferSizeClass identifies the size of a buffer requested from a BufferAllocator.
+type BufferSizeClass = utils.BufferSizeClass
+
+const (
+	// BufferSizeClassPacket is used for buffers holding a single QUIC packet, and for the data of STREAM frames.
+	BufferSizeClassPacket = utils.BufferSizeClassPacket
+	// BufferSizeClassLargePacket is used for buffers holding a batch of packets sent using GSO.
+	BufferSizeClassLargePacket = utils.BufferSizeClassLargePacket
+)
+
+// SetBufferAllocator sets the BufferAllocator used by all Transports and connections.
+// By default, buffers are taken from an internal sync.Pool.
+// A custom allocator makes it possible to use memory from an arena, or buffers that were pre-registered with the kernel or a NIC.
+// Buffers are always returned to the allocator that they were obtained from,
+// but the allocator should be set before any Transport is started.
+// Setting it to nil restores the default allocator.
+func SetBufferAllocator(a BufferAllocator) {
+	utils.SetBufferAllocator(a)
+}
+
+// SetMemoryBudget sets the number of bytes that all connections together may use for buffering data:
+// STREAM data that was received but not read by the application yet,
+// STREAM data that was sent but not acknowledged by the peer yet, and DATAGRAMs that were not read by the application yet.
+// The budget applies in addition to the per-connection flow control limits.
+// When the budget is almost used up, connections stop increasing their flow control windows,
+// and grant smaller flow control credit to their peers.
+// Once it is used up, incoming DATAGRAMs are dropped.
+// The budget is a soft limit: it can be exceeded by data that the peer is allowed to send under flow control.
+// Setting it to 0 (the default) disables the limit.
+func SetMemoryBudget(bytes int64) {
+	utils.GlobalMemoryBudget.SetLimit(bytes)
+}
+
+// MemoryUsage returns the number of bytes currently accounted against the memory budget, see SetMemoryBudget.
+// Memory usage is tracked even if no memory budget is set.
+func MemoryUsage() int64 {
+	return utils.GlobalMemoryBudget.Used()
+}
+
 // A ConnectionID is a QUIC Connection ID, as defined in RFC 9000.
 // It is not able to handle QUIC Connection IDs longer than 20 bytes,
 // as they are allowed by RFC 8999.
@@ -229,7 +442,8 @@ func ConnectionIDFromBytes(b []byte) ConnectionID {
 // A ConnectionIDGenerator is an interface that allows clients to implement their own format
 // for the Connection IDs that servers/clients use as SrcConnectionID in QUIC packets.
 //
-// Connection IDs generated by an implementation should always produce IDs of constant size.
+// Connection IDs generated by an implementation should always produce IDs of constant size,
+// unless the implementation is a VariableLengthConnectionIDGenerator.
 type ConnectionIDGenerator interface {
 	// GenerateConnectionID generates a new ConnectionID.
 	// Generated ConnectionIDs should be unique and observers should not be able to correlate two ConnectionIDs.
@@ -239,11 +453,96 @@ type ConnectionIDGenerator interface {
 	// this interface.
 	// Effectively, this means that implementations of ConnectionIDGenerator must always return constant-size
 	// connection IDs. Valid lengths are between 0 and 20 and calls to GenerateConnectionID.
+	// For a VariableLengthConnectionIDGenerator, this is the maximum length of the generated ConnectionIDs.
 	// 0-length ConnectionsIDs can be used when an endpoint (server or client) does not require multiplexing connections
 	// in the presence of a connection migration environment.
 	ConnectionIDLen() int
 }
 
+// A RotatingConnectionIDGenerator is a ConnectionIDGenerator that encodes routing information into the connection IDs,
+// for example the server ID and key epoch of a QUIC-LB load balancer configuration, and that can change this information.
+//
+// When the epoch changes, every connection issues a new set of connection IDs, and asks the peer to retire
+// all connection IDs issued in earlier epochs. Packets sent to the old connection IDs are still accepted
+// until the peer has retired them, so a load balancer key rollover doesn't drop any connections.
+// The length of the connection IDs must not change between epochs,
+// unless the generator is also a VariableLengthConnectionIDGenerator.
+type RotatingConnectionIDGenerator interface {
+	ConnectionIDGenerator
+
+	// Epoch returns the epoch of the connection IDs currently returned by GenerateConnectionID.
+	// It is called frequently, and must be safe for concurrent use.
+	Epoch() uint64
+}
+
+// A VariableLengthConnectionIDGenerator is a ConnectionIDGenerator that generates connection IDs of different lengths,
+// for example for QUIC-LB configurations that use different connection ID lengths.
+// The length may differ between connections, and between the connection IDs of a single connection.
+//
+// Short header packets don't encode the length of the Destination Connection ID.
+// The length therefore needs to be derived from the connection ID itself,
+// for example from the self-encoded length in the first byte of a QUIC-LB connection ID.
+type VariableLengthConnectionIDGenerator interface {
+	ConnectionIDGenerator
+
+	// ParseConnectionIDLen returns the length of the connection ID that b starts with.
+	// b might be longer than the connection ID.
+	// It is called for every short header packet received, and must be safe for concurrent use.
+	ParseConnectionIDLen(b []byte) (int, error)
+}
+
+// A RetirementAwareConnectionIDGenerator is a ConnectionIDGenerator that is notified when the peer retires
+// connection IDs generated by it, for example to release state associated with these connection IDs.
+// Connection IDs that are still active when the connection is closed are not reported.
+type RetirementAwareConnectionIDGenerator interface {
+	ConnectionIDGenerator
+
+	// ConnectionIDRetired is called when the peer retired a connection ID returned by GenerateConnectionID.
+	// It is called from the connection's run loop, and must be safe for concurrent use.
+	ConnectionIDRetired(ConnectionID)
+}
+
+// RetiredConnectionIDInfo describes a connection ID that was retired, see Config.ConnectionIDRetired.
+type RetiredConnectionIDInfo struct {
+	// SequenceNumber is the sequence number of the connection ID.
+	SequenceNumber uint64
+	// ConnectionID is the connection ID that was retired.
+	ConnectionID ConnectionID
+	// RetiredByPeer says if the connection ID was issued by us, and retired by the peer.
+	// Otherwise, the connection ID was issued by the peer, and we retired it.
+	RetiredByPeer bool
+}
+
+// ConnectionIDRetirement determines which connection IDs the peer is asked to retire
+// when connection IDs are rotated, see Config.ConnectionIDRotationInterval.
+type ConnectionIDRetirement uint8
+
+const (
+	// RetireOldestConnectionID asks the peer to retire the oldest connection ID on every rotation,
+	// and issues a single new connection ID to replace it.
+	RetireOldestConnectionID ConnectionIDRetirement = iota
+	// RetireAllConnectionIDs asks the peer to retire all connection IDs on every rotation,
+	// and issues a completely new set of connection IDs.
+	// The peer has to switch to a new connection ID right away.
+	RetireAllConnectionIDs
+)
+
+// A RetransmissionPolicy determines how retransmissions of lost STREAM data are scheduled
+// relative to new data on the same stream, see Config.RetransmissionPolicy.
+type RetransmissionPolicy uint8
+
+const (
+	// RetransmitFirst sends all lost data before sending any new data.
+	RetransmitFirst RetransmissionPolicy = iota
+	// RetransmitProportional interleaves retransmissions and new data,
+	// such that equal amounts of lost data and new data are sent while both are available.
+	RetransmitProportional
+	// RetransmitDeadlineAware sends lost data before new data, unless it was originally sent more than
+	// Config.RetransmissionDeadline ago. Such stale data is only retransmitted when there's no new data to send.
+	// This is useful for real-time applications, which prefer sending fresh data over stale data.
+	RetransmitDeadlineAware
+)
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// GetConfigForClient is called for incoming connections.
@@ -268,11 +567,45 @@ type Config struct {
 	// See https://datatracker.ietf.org/doc/html/rfc9000#section-8 for details.
 	// If not set, every client is forced to prove its remote address.
 	RequireAddressValidation func(net.Addr) bool
+	// AdmitConnection is called for every new connection attempt, before the handshake is processed.
+	// It allows the server to reject connection attempts (or to require a Retry) without
+	// spending any CPU on the cryptographic handshake, e.g. to enforce per-tenant connection limits.
+	// It is called on the server's main loop, and therefore must not block.
+	// If not set, all connection attempts are admitted.
+	// Only valid for the server.
+	AdmitConnection func(*AdmissionInfo) AdmissionDecision
+	// ValidateExternalRetryToken is called for tokens that weren't created by quic-go.
+	// It allows accepting Retry tokens minted outside of this process, e.g. by a frontend that
+	// sends Retry packets on behalf of this server. If the token is valid for the remote address,
+	// it returns the Destination Connection ID of the client's first Initial packet and true.
+	// A client presenting a valid token is considered to have validated its address.
+	// Tokens created with NewRetryToken (using the Transport's TokenGeneratorKey) don't need this callback.
+	// Only valid for the server.
+	ValidateExternalRetryToken func(token []byte, remoteAddr net.Addr) (origDestConnID ConnectionID, ok bool)
+	// ChooseVersion is called by the client when it receives a Version Negotiation packet.
+	// It returns the QUIC version to restart the handshake with, or false to fail the dial with a VersionNegotiationError.
+	// The version must be supported by quic-go and offered by the server, but it doesn't need to be listed in Versions.
+	// If not set, the first version in Versions that is offered by the server is used.
+	// Only valid for the client.
+	ChooseVersion func(*VersionNegotiationInfo) (VersionNumber, bool)
+	// SentVersionNegotiation is called when the server sends a Version Negotiation packet,
+	// because a client offered an unsupported QUIC version.
+	// It must not block.
+	// Only valid for the server.
+	SentVersionNegotiation func(*VersionNegotiationInfo)
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
 	// otherwise the token is associated with the server's IP address.
 	TokenStore TokenStore
+	// InitialRTT is the RTT estimate used before the first RTT sample is taken,
+	// for example the RTT measured on a previous connection to the same server.
+	// It determines the retransmission timeout for the Initial and Handshake packets,
+	// and it is used for pacing during the handshake.
+	// If the connection is resumed using a session ticket, the RTT stored in the ticket takes precedence.
+	// If this value is zero, an initial RTT of 100ms is used.
+	// Only valid for the client.
+	InitialRTT time.Duration
 	// InitialStreamReceiveWindow is the initial size of the stream-level flow control window for receiving data.
 	// If the application is consuming data quickly enough, the flow control auto-tuning algorithm
 	// will increase the window up to MaxStreamReceiveWindow.
@@ -322,15 +655,215 @@ type Config struct {
 	// Allow0RTT allows the application to decide if a 0-RTT connection attempt should be accepted.
 	// Only valid for the server.
 	Allow0RTT bool
+	// Max0RTTData is the maximum amount of stream data (in bytes) that the server processes from 0-RTT packets,
+	// limiting the exposure to replay attacks and the memory used for data received before the handshake completes.
+	// Max0RTTStreams is the maximum number of streams that the client can open using 0-RTT packets.
+	// 0-RTT packets that would exceed these limits are dropped without being acknowledged,
+	// and the client retransmits their contents once the handshake completes.
+	// If 0, the amount of 0-RTT data and streams is only limited by flow control and stream limits.
+	// Only valid for the server.
+	Max0RTTData    uint64
+	Max0RTTStreams int64
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
-	Tracer          func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer
+	// AddressDiscovery enables the QUIC Address Discovery extension (draft-ietf-quic-address-discovery).
+	// It allows endpoints to report the address that they observe for the peer, e.g. to learn the reflexive address of an endpoint behind a NAT.
+	// If not set, the extension is disabled.
+	AddressDiscovery AddressDiscoveryMode
+	// EnableImmediateAck enables support for IMMEDIATE_ACK frames (draft-ietf-quic-ack-frequency),
+	// without the rest of the ACK frequency extension.
+	// If the peer enabled it as well, an IMMEDIATE_ACK frame is sent in PTO probe packets,
+	// and in the last packet before the send queue runs empty.
+	// This speeds up loss detection at the end of a transfer, since the peer doesn't delay its acknowledgement.
+	EnableImmediateAck bool
+	// ExtensionFrames registers frame types defined by protocol extensions, see ExtensionFrame.
+	// This allows experimenting with new frame types without modifying quic-go.
+	ExtensionFrames []ExtensionFrame
+	// ActiveConnectionIDLimit is the maximum number of connection IDs that the peer is allowed to issue to us,
+	// and that we store at the same time. It is sent to the peer in the active_connection_id_limit transport parameter.
+	// A larger value allows the peer to provide more connection IDs, e.g. if it wants to change connection IDs frequently.
+	// If not set, it will default to 4.
+	// Values smaller than 3 are invalid, values larger than 64 will be clipped to that value.
+	// The protocol allows a value of 2, but it is omitted from the transport parameters,
+	// which breaks interoperability with quic-go versions before May 2023.
+	ActiveConnectionIDLimit uint64
+	// MaxIssuedConnectionIDs is the maximum number of connection IDs that are issued to the peer at the same time,
+	// including the connection ID used during the handshake.
+	// The actual number is also limited by the peer's active_connection_id_limit transport parameter.
+	// If not set, it will default to 6.
+	// If set to a negative value, no connection IDs are issued after the handshake.
+	MaxIssuedConnectionIDs int
+	// ConnectionIDRotationInterval is the interval at which new connection IDs are issued,
+	// asking the peer to retire old ones (using the Retire Prior To field of the NEW_CONNECTION_ID frame).
+	// This makes it harder for on-path observers to correlate packets sent at different times.
+	// If set to 0, connection IDs are only replaced when the peer retires them.
+	// Rotation is disabled when using zero-length connection IDs.
+	ConnectionIDRotationInterval time.Duration
+	// ConnectionIDRetirement determines which connection IDs the peer is asked to retire
+	// every ConnectionIDRotationInterval.
+	// If not set, the oldest connection ID is retired.
+	ConnectionIDRetirement ConnectionIDRetirement
+	// ConnectionIDRetired is called when a connection ID is retired, both when the peer retires a connection ID
+	// that we issued (by sending a RETIRE_CONNECTION_ID frame), and when we retire a connection ID issued by the peer.
+	// It is called from the connection's run loop, and must not block.
+	ConnectionIDRetired func(Connection, RetiredConnectionIDInfo)
+	Tracer              func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer
+	// Clock is the source of time used by the connection, e.g. for loss detection, pacing,
+	// the idle timeout and the handshake timeout.
+	// It allows tests to advance time artificially.
+	// If nil, the system clock is used.
+	Clock Clock
+	// Logger is used for logging by the connection, overriding Transport.Logger.
+	// The connection ID and the remote address are attached to all messages.
+	Logger Logger
+	// DecryptionWorkers is the number of goroutines used to decrypt 1-RTT packets of a single connection.
+	// This allows a connection to receive at a higher rate than a single core can decrypt.
+	// Packets are still processed in the order they were received.
+	// If 0 or 1, packets are decrypted on the connection's goroutine.
+	DecryptionWorkers int
+	// HandshakeWorkerPool is used to perform the expensive operations of the TLS handshake
+	// (certificate verification, signature generation and key exchange) off the connection's goroutine.
+	// The pool should be shared between connections: it limits the number of handshake operations performed concurrently,
+	// such that a burst of new connections doesn't delay the processing of packets on established connections.
+	// If nil, the handshake is performed on the connection's goroutine.
+	HandshakeWorkerPool *HandshakeWorkerPool
+	// SingleGoroutine makes the connection write packets to the socket from the goroutine that also handles
+	// received packets and timers, instead of using a separate goroutine for sending.
+	// An established connection then only uses a single goroutine, which is useful for servers
+	// that need to hold a very large number of mostly idle connections.
+	// It reduces the throughput of a single connection, since no new packets can be packed while packets are being sent.
+	SingleGoroutine bool
+	// TimerGranularity is the granularity of the timer that drives the connection's run loop.
+	// Timer deadlines (e.g. for loss detection, delayed ACKs and pacing) are rounded up to a multiple of this value,
+	// such that multiple timers expiring at almost the same time only cause a single wakeup.
+	// This reduces power consumption on battery-powered devices, at the cost of reacting to timers with a small delay.
+	// Since this also delays ACKs, the max_ack_delay advertised to the peer is increased by the granularity.
+	// Values that would raise the max_ack_delay above its maximum of 2^14-1 ms are rejected.
+	// If 0, timers are not coalesced.
+	TimerGranularity time.Duration
+	// LowPowerMode reduces the number of wakeups of idle and lightly loaded connections,
+	// at the cost of slightly increased latency. This is useful for clients on battery-powered devices.
+	// Timers are coalesced using the TimerGranularity, which defaults to 25ms in low-power mode.
+	// Keep-alive PINGs (see KeepAlivePeriod) are sent after 3/4 of the idle timeout instead of after half of it.
+	LowPowerMode bool
+	// MaxPacketsPerLoopIteration is the maximum number of received packets that are processed
+	// before the run loop checks if it can send packets (e.g. an ACK) and resets its timers.
+	// Larger values reduce the per-packet overhead, smaller values reduce the latency of sending ACKs
+	// when receiving a lot of packets.
+	// If 0, all packets that were already queued when the run loop started processing packets are processed.
+	MaxPacketsPerLoopIteration int
+	// AdaptivePacketBatching makes the run loop take GOMAXPROCS into account when processing received packets.
+	// If only a single goroutine can run at a time (GOMAXPROCS is 1), the goroutine reading from the socket
+	// and the connection's run loop can't run in parallel. The run loop then yields before processing a packet,
+	// such that packets that were already received are processed (and acknowledged) in a single batch.
+	// It has no effect if GOMAXPROCS is larger than 1.
+	AdaptivePacketBatching bool
+	// SendRateLimit is the maximum rate (in bytes per second) at which STREAM data is sent on a connection.
+	// It is enforced using a token bucket, independent of congestion control.
+	// This is useful to enforce bandwidth quotas, for example per tenant.
+	// The limit can be adjusted for a running connection using Connection.SetSendRateLimit.
+	// If 0, the sending rate is only limited by congestion control.
+	SendRateLimit uint64
+	// SendRateLimitBurst is the size (in bytes) of the token bucket used to enforce the SendRateLimit,
+	// i.e. the amount of data that can be sent in a burst after the connection was idle.
+	// If not set, it defaults to 100ms worth of data (but at least a full packet).
+	SendRateLimitBurst uint64
+	// PersistentCongestionThreshold is the multiple of the PTO used to detect persistent congestion:
+	// If all packets sent during this period are lost, the congestion window is collapsed (see RFC 9002, section 7.6).
+	// Larger values make the congestion controller more tolerant of brief outages.
+	// If 0, the value of 3 recommended by RFC 9002 is used.
+	PersistentCongestionThreshold uint32
+	// PersistentCongestionWindow is the congestion window (in packets) used after persistent congestion was detected.
+	// Resetting to the minimum congestion window can severely impact transfers on long fat networks,
+	// since it takes many round trips to grow the congestion window again.
+	// The congestion window is never increased when persistent congestion is detected.
+	// If 0, the congestion window is reset to the minimum congestion window (2 packets), as recommended by RFC 9002.
+	PersistentCongestionWindow uint32
+	// MaxAckRanges is the maximum number of ACK ranges that are tracked for received 1-RTT packets,
+	// and that are sent in ACK frames.
+	// On paths with extreme reordering (e.g. bonded links), a larger value prevents ACK ranges
+	// from being dropped before the peer learned about them, which would cause spurious retransmissions.
+	// When the limit is reached, the range that was already reported in the largest number of ACK frames is dropped.
+	// If 0, it defaults to 32. Values larger than 256 are clipped to that value.
+	MaxAckRanges int
+	// KeyUpdateInterval is the number of packets sent or received using the same 1-RTT keys,
+	// after which a key update is initiated (see section 6 of RFC 9001).
+	// Independent of this value, keys are updated before the confidentiality limit of the cipher suite is reached.
+	// If the limit is reached nevertheless (because the peer doesn't acknowledge the previous key update),
+	// the connection is closed with an AEAD_LIMIT_REACHED error.
+	// If 0, keys are updated every 100,000 packets.
+	KeyUpdateInterval uint64
+	// KeyUpdateIntervalBytes is the number of bytes sent using the same 1-RTT keys, after which a key update is initiated.
+	// If 0, the number of bytes sent doesn't trigger key updates.
+	KeyUpdateIntervalBytes uint64
+	// RetransmissionPolicy determines how retransmissions of lost STREAM data are scheduled relative to new STREAM data.
+	// Stream data is always delivered reliably, the policy only changes the order in which data is sent.
+	// The default is RetransmitFirst.
+	RetransmissionPolicy RetransmissionPolicy
+	// RetransmissionDeadline is the time after which data that was lost is considered stale by RetransmitDeadlineAware.
+	// If 0, a default value of 200ms is used.
+	RetransmissionDeadline time.Duration
+	// PaddingPolicy determines how much padding is added to packets, in order to reduce the information leaked to
+	// an observer analyzing the packet sizes.
+	// If nil, packets are only padded where required by the protocol.
+	PaddingPolicy PaddingPolicy
 }
 
 type ClientHelloInfo struct {
 	RemoteAddr net.Addr
 }
 
+// AdmissionInfo contains information about a new connection attempt, see Config.AdmitConnection.
+type AdmissionInfo struct {
+	// RemoteAddr is the address of the client.
+	RemoteAddr net.Addr
+	// ServerName is the server name (SNI) requested by the client.
+	// The ServerName and the ALPN are read from the ClientHello contained in the first Initial packet.
+	// If the client splits the ClientHello across multiple packets, they might not be available.
+	ServerName string
+	// ALPN is the list of application protocols offered by the client.
+	ALPN []string
+	// AddressValidated says if the client's address was validated, i.e. if the client presented a valid token.
+	AddressValidated bool
+	// ActiveConnections is the number of connections currently handled by the server,
+	// including connections that are still handshaking.
+	ActiveConnections int
+	// AcceptQueueLength is the number of connections that completed the handshake,
+	// but haven't been accepted by the application yet.
+	AcceptQueueLength int
+}
+
+// An AdmissionAction is the action taken for a new connection attempt, see Config.AdmitConnection.
+type AdmissionAction uint8
+
+const (
+	// AdmissionAccept accepts the connection attempt.
+	AdmissionAccept AdmissionAction = iota
+	// AdmissionReject rejects the connection attempt, by sending a CONNECTION_CLOSE frame.
+	AdmissionReject
+	// AdmissionRequireRetry sends a Retry packet, forcing the client to prove its address.
+	// If the client's address was already validated, the connection attempt is accepted.
+	AdmissionRequireRetry
+)
+
+// An AdmissionDecision is returned by Config.AdmitConnection.
+type AdmissionDecision struct {
+	Action AdmissionAction
+	// ErrorCode is the error code sent to the client when rejecting a connection attempt.
+	// If not set, CONNECTION_REFUSED is used.
+	ErrorCode TransportErrorCode
+}
+
+// VersionNegotiationInfo describes a Version Negotiation packet, see section 6 of RFC 9000.
+type VersionNegotiationInfo struct {
+	// RemoteAddr is the address of the peer.
+	RemoteAddr net.Addr
+	// ClientVersion is the QUIC version offered by the client, which is not supported by the server.
+	ClientVersion VersionNumber
+	// ServerVersions are the QUIC versions listed in the Version Negotiation packet.
+	ServerVersions []VersionNumber
+}
+
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
 	// TLS contains information about the TLS connection state, incl. the tls.ConnectionState.
@@ -340,12 +873,101 @@ type ConnectionState struct {
 	// If datagram support was negotiated, datagrams can be sent and received using the
 	// SendMessage and ReceiveMessage methods on the Connection.
 	SupportsDatagrams bool
+	// ExtensionFrames are the frame types registered in Config.ExtensionFrames that the peer supports as well.
+	// Frames of these types can be sent using the SendExtensionFrame method on the Connection.
+	// It is set once the handshake completes.
+	ExtensionFrames []uint64
 	// Used0RTT says if 0-RTT resumption was used.
 	Used0RTT bool
 	// Version is the QUIC version of the QUIC connection.
 	Version VersionNumber
+	// ServerVersions are the QUIC versions offered by the server in a Version Negotiation packet.
+	// Only set for clients that received a Version Negotiation packet.
+	ServerVersions []VersionNumber
 	// GSO says if generic segmentation offload is used
 	GSO bool
 	// LatestRTT is the latest RTT measurement
 	LatestRTT time.Duration
+	// HandshakeTimeline contains the times at which the handshake reached its milestones.
+	HandshakeTimeline HandshakeTimeline
+	// KeyUpdates contains statistics about the 1-RTT key updates (see section 6 of RFC 9001).
+	KeyUpdates KeyUpdateStats
+	// Bandwidth is the sender's current estimate of the bandwidth available on the path to the peer.
+	Bandwidth BandwidthEstimate
+	// PeerTransportParameters are the transport parameters sent by the peer.
+	// It is set once the transport parameters were applied,
+	// i.e. once the handshake completes for clients, and once the client's transport parameters were received for servers.
+	// When using 0-RTT, the transport parameters remembered from the previous connection are not included.
+	PeerTransportParameters *TransportParameters
+}
+
+// BandwidthEstimate is an estimate of the bandwidth available for sending data to the peer.
+// It is derived from the rate at which sent packets were acknowledged (the delivery rate).
+type BandwidthEstimate struct {
+	// DeliveryRate is the maximum delivery rate sampled over the last 10 smoothed RTTs, in bytes per second.
+	// It is 0 until enough packets were acknowledged to take the first sample.
+	DeliveryRate uint64
+	// AppLimited says if the sender is application-limited, i.e. it recently ran out of data to send
+	// before the congestion controller prevented sending more.
+	// Delivery rate samples taken while the sender is application-limited underestimate the available bandwidth,
+	// so the DeliveryRate should be treated as a lower bound.
+	AppLimited bool
+}
+
+// KeyUpdateStats contains statistics about the key updates of a connection.
+type KeyUpdateStats struct {
+	// KeyPhase is the current key phase. It is incremented with every key update.
+	KeyPhase uint64
+	// Initiated is the number of key updates initiated by this endpoint.
+	Initiated uint64
+	// PeerInitiated is the number of key updates initiated by the peer.
+	PeerInitiated uint64
+}
+
+// HandshakeTimeline contains the times at which a connection reached the milestones of the handshake.
+// This is useful to understand where the latency of a connection establishment is spent.
+// Milestones that haven't been reached (yet) have the zero value.
+type HandshakeTimeline struct {
+	// Start is the time when the connection was created.
+	Start time.Time
+	// FirstInitialSent is the time when the first Initial packet was sent.
+	FirstInitialSent time.Time
+	// RetryReceived is the time when a Retry packet was received. Only set for clients.
+	RetryReceived time.Time
+	// FirstHandshakePacketReceived is the time when the first Handshake packet was received.
+	FirstHandshakePacketReceived time.Time
+	// HandshakeComplete is the time when the handshake completed.
+	HandshakeComplete time.Time
+	// HandshakeConfirmed is the time when the handshake was confirmed (see section 4.1.2 of RFC 9001).
+	HandshakeConfirmed time.Time
+	// FirstAppDataSent is the time when the first 1-RTT packet carrying application data
+	// (STREAM or DATAGRAM frames) was sent.
+	FirstAppDataSent time.Time
+	// FirstAppDataReceived is the time when the first 1-RTT packet carrying application data
+	// (STREAM or DATAGRAM frames) was received.
+	FirstAppDataReceived time.Time
+}
+
+// RTTStats contains the round-trip time (RTT) statistics of a connection, see section 5 of RFC 9002.
+// All values are zero until the first RTT sample was taken.
+type RTTStats struct {
+	// LatestRTT is the most recent RTT sample, adjusted for the peer's acknowledgment delay.
+	LatestRTT time.Duration
+	// SmoothedRTT is the exponentially weighted moving average of the RTT samples.
+	SmoothedRTT time.Duration
+	// MinRTT is the minimum RTT observed on the connection.
+	MinRTT time.Duration
+	// RTTVariation is the mean deviation of the RTT samples.
+	RTTVariation time.Duration
+	// Samples contains the most recent RTT samples (up to 64), oldest first.
+	// This allows calculating the jitter and percentiles of the RTT.
+	Samples []RTTSample
+}
+
+// An RTTSample is an RTT measurement.
+type RTTSample struct {
+	// Time is the time when the acknowledgment that produced the sample was received.
+	Time time.Time
+	// RTT is the RTT, adjusted for the peer's acknowledgment delay.
+	RTT time.Duration
 }
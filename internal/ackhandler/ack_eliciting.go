@@ -4,6 +4,9 @@ import "github.com/quic-go/quic-go/internal/wire"
 
 // IsFrameAckEliciting returns true if the frame is ack-eliciting.
 func IsFrameAckEliciting(f wire.Frame) bool {
+	if ef, ok := f.(*wire.ExtensionFrame); ok {
+		return ef.AckEliciting
+	}
 	_, isAck := f.(*wire.AckFrame)
 	_, isConnectionClose := f.(*wire.ConnectionCloseFrame)
 	return !isAck && !isConnectionClose
@@ -32,4 +32,10 @@ var _ = Describe("ack-eliciting frames", func() {
 			Expect(HasAckElicitingFrames([]Frame{{Frame: f}})).To(Equal(e))
 		})
 	}
+
+	It("works for extension frames", func() {
+		Expect(IsFrameAckEliciting(&wire.ExtensionFrame{AckEliciting: true})).To(BeTrue())
+		Expect(IsFrameAckEliciting(&wire.ExtensionFrame{AckEliciting: false})).To(BeFalse())
+		Expect(HasAckElicitingFrames([]Frame{{Frame: &wire.ExtensionFrame{}}})).To(BeFalse())
+	})
 })
@@ -9,16 +9,23 @@ import (
 // NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
 // clientAddressValidated indicates whether the address was validated beforehand by an address validation token.
 // clientAddressValidated has no effect for a client.
+// persistentCongestionThreshold and persistentCongestionWindow (in packets) configure the reaction to persistent congestion,
+// the default behavior defined in RFC 9002 is used for 0 values.
+// maxAckRanges is the number of ACK ranges tracked for the application data packet number space.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
+	clock utils.Clock,
 	clientAddressValidated bool,
 	enableECN bool,
+	persistentCongestionThreshold uint32,
+	persistentCongestionWindow uint32,
+	maxAckRanges int,
 	pers protocol.Perspective,
 	tracer *logging.ConnectionTracer,
 	logger utils.Logger,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clientAddressValidated, enableECN, pers, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger)
+	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clock, clientAddressValidated, enableECN, persistentCongestionThreshold, persistentCongestionWindow, pers, tracer, logger)
+	return sph, newReceivedPacketHandler(sph, rttStats, clock, maxAckRanges, logger)
 }
@@ -0,0 +1,163 @@
+package ackhandler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/congestion"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// The bandwidth estimate is the maximum delivery rate sampled within this number of smoothed RTTs.
+const deliveryRateWindowRTTs = 10
+
+// deliveryRateEstimator estimates the delivery rate,
+// following draft-cheng-iccrg-delivery-rate-estimation.
+// When a packet is sent, the number of bytes delivered so far is stored with the packet.
+// When it is acknowledged, the delivery rate is sampled over the interval between sending the packet
+// and receiving the acknowledgement.
+type deliveryRateEstimator struct {
+	rttStats *utils.RTTStats
+
+	delivered     protocol.ByteCount // total number of bytes acknowledged
+	deliveredTime time.Time          // the time when delivered was last updated
+	firstSentTime time.Time          // the send time of the packet that was most recently acknowledged
+	// If the sender is application-limited, this is the value of delivered at which
+	// the packets sent while being application-limited will have been acknowledged.
+	// 0 if the sender is not application-limited.
+	appLimitedUntil protocol.ByteCount
+
+	maxFilter windowedMaxFilter
+
+	// The estimate is read by BandwidthEstimate, which is called from a different go routine.
+	estimate   atomic.Uint64 // in bytes per second
+	appLimited atomic.Bool
+}
+
+func newDeliveryRateEstimator(rttStats *utils.RTTStats) *deliveryRateEstimator {
+	return &deliveryRateEstimator{rttStats: rttStats}
+}
+
+// OnPacketSent must be called for every ack-eliciting packet, before adding it to the bytes in flight.
+func (e *deliveryRateEstimator) OnPacketSent(p *packet, bytesInFlight protocol.ByteCount) {
+	if bytesInFlight == 0 {
+		e.firstSentTime = p.SendTime
+		e.deliveredTime = p.SendTime
+	}
+	p.delivered = e.delivered
+	p.deliveredTime = e.deliveredTime
+	p.firstSentTime = e.firstSentTime
+	p.isAppLimited = e.appLimitedUntil != 0
+}
+
+// OnAppLimited is called when the sender runs out of data to send,
+// although the congestion controller would allow sending more.
+func (e *deliveryRateEstimator) OnAppLimited(bytesInFlight protocol.ByteCount) {
+	e.appLimitedUntil = utils.Max(e.delivered+bytesInFlight, 1)
+	e.appLimited.Store(true)
+}
+
+// OnPacketsAcked takes a delivery rate sample from the packets acknowledged by an ACK frame.
+func (e *deliveryRateEstimator) OnPacketsAcked(packets []*packet, now time.Time) {
+	var (
+		sampled        bool
+		priorDelivered protocol.ByteCount
+		priorTime      time.Time
+		sendElapsed    time.Duration
+		isAppLimited   bool
+	)
+	for _, p := range packets {
+		if p.deliveredTime.IsZero() { // not ack-eliciting
+			continue
+		}
+		e.delivered += p.Length
+		e.deliveredTime = now
+		// Use the most recently sent packet to take the sample.
+		if !sampled || p.delivered >= priorDelivered {
+			sampled = true
+			priorDelivered = p.delivered
+			priorTime = p.deliveredTime
+			sendElapsed = p.SendTime.Sub(p.firstSentTime)
+			isAppLimited = p.isAppLimited
+			e.firstSentTime = p.SendTime
+		}
+	}
+	if e.appLimitedUntil != 0 && e.delivered > e.appLimitedUntil {
+		e.appLimitedUntil = 0
+		e.appLimited.Store(false)
+	}
+	if !sampled {
+		return
+	}
+	// Use the longer of the send and the ACK interval, to avoid overestimating the delivery rate
+	// when ACKs are compressed or packets are sent in bursts.
+	interval := utils.Max(sendElapsed, now.Sub(priorTime))
+	if interval <= 0 || interval < e.rttStats.MinRTT() {
+		return
+	}
+	bw := congestion.BandwidthFromDelta(e.delivered-priorDelivered, interval)
+	// Application-limited samples underestimate the available bandwidth.
+	// They're only used if they exceed the current estimate.
+	if isAppLimited && bw < e.maxFilter.Get() {
+		return
+	}
+	e.maxFilter.Update(bw, now, deliveryRateWindowRTTs*e.rttStats.SmoothedRTT())
+	e.estimate.Store(uint64(e.maxFilter.Get() / congestion.BytesPerSecond))
+}
+
+// BandwidthEstimate returns the estimated bandwidth, in bytes per second,
+// and if the sender is currently application-limited.
+// It is safe to call from any go routine.
+func (e *deliveryRateEstimator) BandwidthEstimate() (uint64, bool) {
+	return e.estimate.Load(), e.appLimited.Load()
+}
+
+type bandwidthSample struct {
+	bw   congestion.Bandwidth
+	time time.Time
+}
+
+// windowedMaxFilter tracks the maximum bandwidth sampled within a time window.
+// It keeps the best, second best and third best sample, such that a new maximum
+// is available when the best sample expires (Kathleen Nichols' algorithm).
+type windowedMaxFilter struct {
+	samples [3]bandwidthSample
+}
+
+func (f *windowedMaxFilter) Get() congestion.Bandwidth { return f.samples[0].bw }
+
+func (f *windowedMaxFilter) Update(bw congestion.Bandwidth, now time.Time, window time.Duration) {
+	sample := bandwidthSample{bw: bw, time: now}
+	// Reset all samples if there's a new maximum, or if nothing was sampled within the window.
+	if f.samples[0].time.IsZero() || bw >= f.samples[0].bw || now.Sub(f.samples[2].time) > window {
+		f.samples = [3]bandwidthSample{sample, sample, sample}
+		return
+	}
+	if bw >= f.samples[1].bw {
+		f.samples[1] = sample
+		f.samples[2] = sample
+	} else if bw >= f.samples[2].bw {
+		f.samples[2] = sample
+	}
+	// Expire the best sample, and shift the other samples.
+	if now.Sub(f.samples[0].time) > window {
+		f.samples[0] = f.samples[1]
+		f.samples[1] = f.samples[2]
+		f.samples[2] = sample
+		if now.Sub(f.samples[0].time) > window {
+			f.samples[0] = f.samples[1]
+			f.samples[1] = f.samples[2]
+		}
+		return
+	}
+	// Make sure the second and third best samples are from different quarters of the window.
+	if f.samples[1].time.Equal(f.samples[0].time) && now.Sub(f.samples[1].time) > window/4 {
+		f.samples[1] = sample
+		f.samples[2] = sample
+		return
+	}
+	if f.samples[2].time.Equal(f.samples[1].time) && now.Sub(f.samples[2].time) > window/2 {
+		f.samples[2] = sample
+	}
+}
@@ -0,0 +1,149 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/congestion"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Delivery Rate Estimator", func() {
+	const rtt = 100 * time.Millisecond
+	var (
+		e             *deliveryRateEstimator
+		bytesInFlight protocol.ByteCount
+		start         time.Time
+	)
+
+	sendPackets := func(t time.Time, num int) []*packet {
+		var packets []*packet
+		for i := 0; i < num; i++ {
+			p := &packet{SendTime: t.Add(time.Duration(i) * time.Millisecond), Length: 1000}
+			e.OnPacketSent(p, bytesInFlight)
+			bytesInFlight += p.Length
+			packets = append(packets, p)
+		}
+		return packets
+	}
+
+	ackPackets := func(packets []*packet, t time.Time) {
+		e.OnPacketsAcked(packets, t)
+		for _, p := range packets {
+			bytesInFlight -= p.Length
+		}
+	}
+
+	BeforeEach(func() {
+		rttStats := &utils.RTTStats{}
+		rttStats.UpdateRTT(rtt, 0, time.Now())
+		e = newDeliveryRateEstimator(rttStats)
+		bytesInFlight = 0
+		start = time.Now()
+	})
+
+	It("doesn't have an estimate initially", func() {
+		bw, appLimited := e.BandwidthEstimate()
+		Expect(bw).To(BeZero())
+		Expect(appLimited).To(BeFalse())
+	})
+
+	It("estimates the delivery rate", func() {
+		packets := sendPackets(start, 10)
+		ackPackets(packets, start.Add(rtt))
+		bw, appLimited := e.BandwidthEstimate()
+		Expect(bw).To(BeEquivalentTo(10 * 1000 * time.Second / rtt))
+		Expect(appLimited).To(BeFalse())
+	})
+
+	It("uses the send interval, if it's longer than the ACK interval", func() {
+		// send 10 packets over 2 RTTs, and receive the ACKs in a short interval
+		var packets []*packet
+		for i := 0; i < 10; i++ {
+			packets = append(packets, sendPackets(start.Add(time.Duration(i)*rtt/5), 1)...)
+		}
+		ackPackets(packets[:1], start.Add(rtt))
+		ackPackets(packets[1:], start.Add(rtt+rtt/10))
+		bw, _ := e.BandwidthEstimate()
+		// The last packet was sent 9/5 RTT after the first packet.
+		Expect(bw).To(BeEquivalentTo(congestion.BandwidthFromDelta(10*1000, 9*rtt/5) / congestion.BytesPerSecond))
+	})
+
+	It("ignores samples taken over intervals shorter than the minimum RTT", func() {
+		packets := sendPackets(start, 10)
+		ackPackets(packets, start.Add(rtt/2))
+		bw, _ := e.BandwidthEstimate()
+		Expect(bw).To(BeZero())
+	})
+
+	It("ignores packets that aren't ack-eliciting", func() {
+		packets := sendPackets(start, 10)
+		ackPackets(append(packets, &packet{Length: 1000}), start.Add(rtt))
+		bw, _ := e.BandwidthEstimate()
+		Expect(bw).To(BeEquivalentTo(10 * 1000 * time.Second / rtt))
+	})
+
+	It("only uses application-limited samples if they increase the estimate", func() {
+		ackPackets(sendPackets(start, 10), start.Add(rtt))
+		bw, appLimited := e.BandwidthEstimate()
+		Expect(bw).To(BeEquivalentTo(10 * 1000 * time.Second / rtt))
+		Expect(appLimited).To(BeFalse())
+
+		// the application only sends a single packet
+		now := start.Add(2 * rtt)
+		packets := sendPackets(now, 1)
+		e.OnAppLimited(bytesInFlight)
+		_, appLimited = e.BandwidthEstimate()
+		Expect(appLimited).To(BeTrue())
+		ackPackets(packets, now.Add(rtt))
+		bw, appLimited = e.BandwidthEstimate()
+		Expect(bw).To(BeEquivalentTo(10 * 1000 * time.Second / rtt))
+		Expect(appLimited).To(BeTrue())
+
+		// Packets sent while being application-limited are marked as such.
+		// The sender is not application-limited anymore once data sent after these packets was acknowledged.
+		now = now.Add(2 * rtt)
+		packets = sendPackets(now, 20)
+		Expect(packets[0].isAppLimited).To(BeTrue())
+		ackPackets(packets, now.Add(rtt))
+		bw, appLimited = e.BandwidthEstimate()
+		Expect(bw).To(BeEquivalentTo(20 * 1000 * time.Second / rtt))
+		Expect(appLimited).To(BeFalse())
+	})
+
+	Context("windowed max filter", func() {
+		const window = 10 * time.Second
+
+		It("returns the maximum", func() {
+			var f windowedMaxFilter
+			f.Update(100, start, window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(100)))
+			f.Update(50, start.Add(time.Second), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(100)))
+			f.Update(200, start.Add(2*time.Second), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(200)))
+		})
+
+		It("expires old samples", func() {
+			var f windowedMaxFilter
+			f.Update(100, start, window)
+			f.Update(50, start.Add(window/2), window)
+			f.Update(20, start.Add(window*9/10), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(100)))
+			f.Update(10, start.Add(window+time.Second), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(50)))
+			f.Update(10, start.Add(window*3/2+time.Second), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(10)))
+		})
+
+		It("resets when nothing was sampled within the window", func() {
+			var f windowedMaxFilter
+			f.Update(100, start, window)
+			f.Update(10, start.Add(2*window), window)
+			Expect(f.Get()).To(Equal(congestion.Bandwidth(10)))
+		})
+	})
+})
@@ -1,6 +1,8 @@
 package ackhandler
 
 import (
+	"sync"
+
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -19,3 +21,56 @@ type StreamFrame struct {
 	Frame   *wire.StreamFrame
 	Handler FrameHandler
 }
+
+// Only the slices holding the frames of a sent packet are pooled here.
+// STREAM frames are pooled by the wire package (see wire.GetStreamFrame),
+// and ACK frames don't need to be pooled, since every receivedPacketTracker reuses its ACK frame.
+
+// The capacity of the slices handed out by GetFrames and GetStreamFrames.
+// Slices that grow beyond this capacity are returned to the pool as well.
+const (
+	framesPoolCapacity       = 16
+	streamFramesPoolCapacity = 8
+)
+
+// The pools hold pointers to arrays (and not slices), so that putting a slice back doesn't allocate.
+var (
+	framesPool       = sync.Pool{New: func() any { return new([framesPoolCapacity]Frame) }}
+	streamFramesPool = sync.Pool{New: func() any { return new([streamFramesPoolCapacity]StreamFrame) }}
+)
+
+// GetFrames returns an empty slice of Frames from a pool.
+// The slice is returned to the pool once the packet it was sent in is acknowledged or declared lost.
+func GetFrames() []Frame {
+	return framesPool.Get().(*[framesPoolCapacity]Frame)[:0]
+}
+
+// PutFrames returns a slice obtained from GetFrames to the pool.
+func PutFrames(frames []Frame) {
+	if cap(frames) < framesPoolCapacity {
+		return
+	}
+	frames = frames[:cap(frames)]
+	for i := range frames {
+		frames[i] = Frame{}
+	}
+	framesPool.Put((*[framesPoolCapacity]Frame)(frames))
+}
+
+// GetStreamFrames returns an empty slice of StreamFrames from a pool.
+// The slice is returned to the pool once the packet it was sent in is acknowledged or declared lost.
+func GetStreamFrames() []StreamFrame {
+	return streamFramesPool.Get().(*[streamFramesPoolCapacity]StreamFrame)[:0]
+}
+
+// PutStreamFrames returns a slice obtained from GetStreamFrames to the pool.
+func PutStreamFrames(frames []StreamFrame) {
+	if cap(frames) < streamFramesPoolCapacity {
+		return
+	}
+	frames = frames[:cap(frames)]
+	for i := range frames {
+		frames[i] = StreamFrame{}
+	}
+	streamFramesPool.Put((*[streamFramesPoolCapacity]StreamFrame)(frames))
+}
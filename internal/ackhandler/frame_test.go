@@ -0,0 +1,54 @@
+package ackhandler
+
+import (
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Frame pools", func() {
+	It("hands out empty slices of frames", func() {
+		frames := GetFrames()
+		Expect(frames).To(BeEmpty())
+		Expect(cap(frames)).To(Equal(framesPoolCapacity))
+		frames = append(frames, Frame{Frame: &wire.PingFrame{}})
+		PutFrames(frames)
+		Expect(frames[0]).To(Equal(Frame{}))
+	})
+
+	It("hands out empty slices of stream frames", func() {
+		frames := GetStreamFrames()
+		Expect(frames).To(BeEmpty())
+		Expect(cap(frames)).To(Equal(streamFramesPoolCapacity))
+		frames = append(frames, StreamFrame{Frame: &wire.StreamFrame{}})
+		PutStreamFrames(frames)
+		Expect(frames[0]).To(Equal(StreamFrame{}))
+	})
+
+	It("clears frames beyond the length of the slice", func() {
+		frames := GetFrames()
+		frames = append(frames, Frame{Frame: &wire.PingFrame{}}, Frame{Frame: &wire.PingFrame{}})
+		PutFrames(frames[:1])
+		Expect(frames[1]).To(Equal(Frame{}))
+	})
+
+	It("accepts slices that grew beyond the pool capacity", func() {
+		frames := GetFrames()
+		for i := 0; i <= framesPoolCapacity; i++ {
+			frames = append(frames, Frame{Frame: &wire.PingFrame{}})
+		}
+		Expect(cap(frames)).To(BeNumerically(">", framesPoolCapacity))
+		PutFrames(frames)
+		for _, f := range frames {
+			Expect(f).To(Equal(Frame{}))
+		}
+	})
+
+	It("ignores slices that weren't allocated by the pool", func() {
+		PutFrames([]Frame{{Frame: &wire.PingFrame{}}})
+		PutFrames(nil)
+		PutStreamFrames([]StreamFrame{{Frame: &wire.StreamFrame{}}})
+		PutStreamFrames(nil)
+	})
+})
@@ -14,6 +14,12 @@ type SentPacketHandler interface {
 	// ReceivedAck processes an ACK frame.
 	// It does not store a copy of the frame.
 	ReceivedAck(f *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime time.Time) (bool /* 1-RTT packet acked */, error)
+	// StartAckBatch starts a batch of ACK frames, e.g. for packets received in a single GRO / recvmmsg call.
+	// Until FinishAckBatch is called, loss detection and congestion controller updates
+	// for ACKs received at the 1-RTT encryption level are deferred, and then performed once for the whole batch.
+	StartAckBatch()
+	// FinishAckBatch finishes the batch started by StartAckBatch.
+	FinishAckBatch() error
 	ReceivedBytes(protocol.ByteCount)
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry(rcvTime time.Time) error
@@ -25,6 +31,16 @@ type SentPacketHandler interface {
 	// It is used for pacing packets.
 	TimeUntilSend() time.Time
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// IsAmplificationLimited says if sending is blocked by the anti-amplification limit.
+	// It always returns false for a client.
+	IsAmplificationLimited() bool
+	// MigratedPath is called when the connection starts sending on a new path, before that path is validated.
+	// For a server, the anti-amplification limit applies to the new path until ValidatedPath is called,
+	// with bytesReceived being the number of bytes already received on the new path.
+	// If resetCongestion is set, the congestion controller and the RTT estimate are reset, see section 9.4 of RFC 9000.
+	MigratedPath(bytesReceived protocol.ByteCount, resetCongestion bool)
+	// ValidatedPath is called when the path that the connection is using was validated.
+	ValidatedPath()
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -35,6 +51,29 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+
+	GetCongestionWindow() protocol.ByteCount
+	GetBytesInFlight() protocol.ByteCount
+
+	// OnAppLimited is called when there's no more data to send, although the congestion controller would allow sending.
+	OnAppLimited()
+	// BandwidthEstimate returns the estimated bandwidth in bytes per second, and if the sender is application-limited.
+	// It is safe to call from any go routine.
+	BandwidthEstimate() (bytesPerSecond uint64, appLimited bool)
+	// MemoryUsage estimates the number of bytes used to track sent packets.
+	MemoryUsage() int
+
+	// SetPacketObserver sets the PacketObserver that is notified about acknowledged and lost 1-RTT packets.
+	SetPacketObserver(PacketObserver)
+}
+
+// A PacketObserver is notified about the fate of 1-RTT packets.
+// Path MTU probe packets are not reported.
+type PacketObserver interface {
+	OnPacketAcked(pn protocol.PacketNumber, size protocol.ByteCount)
+	OnPacketLost(pn protocol.PacketNumber, size protocol.ByteCount)
+	// OnPTO is called when the PTO timer for the application data packet number space fires.
+	OnPTO(ptoCount uint32)
 }
 
 type sentPacketTracker interface {
@@ -46,6 +85,8 @@ type sentPacketTracker interface {
 type ReceivedPacketHandler interface {
 	IsPotentiallyDuplicate(protocol.PacketNumber, protocol.EncryptionLevel) bool
 	ReceivedPacket(pn protocol.PacketNumber, ecn protocol.ECN, encLevel protocol.EncryptionLevel, rcvTime time.Time, ackEliciting bool) error
+	// ImmediateAckRequested must be called before ReceivedPacket, if the packet contains an IMMEDIATE_ACK frame.
+	ImmediateAckRequested(protocol.EncryptionLevel)
 	DropPackets(protocol.EncryptionLevel)
 
 	GetAlarmTimeout() time.Time
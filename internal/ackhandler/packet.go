@@ -22,6 +22,12 @@ type packet struct {
 	includedInBytesInFlight bool
 	declaredLost            bool
 	skippedPacket           bool
+
+	// state used for delivery rate estimation, see deliveryRateEstimator
+	delivered     protocol.ByteCount
+	deliveredTime time.Time
+	firstSentTime time.Time
+	isAppLimited  bool
 }
 
 func (p *packet) outstanding() bool {
@@ -43,12 +49,18 @@ func getPacket() *packet {
 	p.includedInBytesInFlight = false
 	p.declaredLost = false
 	p.skippedPacket = false
+	p.delivered = 0
+	p.deliveredTime = time.Time{}
+	p.firstSentTime = time.Time{}
+	p.isAppLimited = false
 	return p
 }
 
 // We currently only return Packets back into the pool when they're acknowledged (not when they're lost).
 // This simplifies the code, and gives the vast majority of the performance benefit we can gain from using the pool.
 func putPacket(p *packet) {
+	PutFrames(p.Frames)
+	PutStreamFrames(p.StreamFrames)
 	p.Frames = nil
 	p.StreamFrames = nil
 	packetPool.Put(p)
@@ -24,13 +24,15 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
 	rttStats *utils.RTTStats,
+	clock utils.Clock,
+	maxAckRanges int,
 	logger utils.Logger,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger),
+		initialPackets:   newReceivedPacketTracker(rttStats, clock, protocol.MaxNumAckRanges, logger),
+		handshakePackets: newReceivedPacketTracker(rttStats, clock, protocol.MaxNumAckRanges, logger),
+		appDataPackets:   newReceivedPacketTracker(rttStats, clock, maxAckRanges, logger),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
@@ -72,6 +74,16 @@ func (h *receivedPacketHandler) ReceivedPacket(
 	}
 }
 
+func (h *receivedPacketHandler) ImmediateAckRequested(encLevel protocol.EncryptionLevel) {
+	//nolint:exhaustive // IMMEDIATE_ACK frames are only allowed in 0-RTT and 1-RTT packets.
+	switch encLevel {
+	case protocol.Encryption0RTT, protocol.Encryption1RTT:
+		h.appDataPackets.ImmediateAckRequested()
+	default:
+		panic(fmt.Sprintf("IMMEDIATE_ACK frame at unexpected encryption level: %s", encLevel))
+	}
+}
+
 func (h *receivedPacketHandler) DropPackets(encLevel protocol.EncryptionLevel) {
 	//nolint:exhaustive // 1-RTT packet number space is never dropped.
 	switch encLevel {
@@ -21,6 +21,8 @@ var _ = Describe("Received Packet Handler", func() {
 		handler = newReceivedPacketHandler(
 			sentPackets,
 			&utils.RTTStats{},
+			utils.DefaultClock{},
+			protocol.MaxNumAckRanges,
 			utils.DefaultLogger,
 		)
 	})
@@ -76,6 +78,20 @@ var _ = Describe("Received Packet Handler", func() {
 		Expect(ack.AckRanges[0]).To(Equal(wire.AckRange{Smallest: 2, Largest: 3}))
 	})
 
+	It("acknowledges packets containing an IMMEDIATE_ACK frame right away", func() {
+		sentPackets.EXPECT().GetLowestPacketNotConfirmedAcked().AnyTimes()
+		sentPackets.EXPECT().ReceivedPacket(protocol.Encryption1RTT).Times(3)
+		Expect(handler.ReceivedPacket(1, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT, true)).ToNot(BeNil())
+		Expect(handler.ReceivedPacket(2, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
+		Expect(handler.GetAckFrame(protocol.Encryption1RTT, true)).To(BeNil())
+		handler.ImmediateAckRequested(protocol.Encryption1RTT)
+		Expect(handler.ReceivedPacket(3, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
+		ack := handler.GetAckFrame(protocol.Encryption1RTT, true)
+		Expect(ack).ToNot(BeNil())
+		Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 1, Largest: 3}}))
+	})
+
 	It("rejects 0-RTT packets with higher packet numbers than 1-RTT packets", func() {
 		sentPackets.EXPECT().ReceivedPacket(gomock.Any()).Times(3)
 		sentPackets.EXPECT().GetLowestPacketNotConfirmedAcked().AnyTimes()
@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"math"
 	"sync"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -12,6 +13,8 @@ import (
 type interval struct {
 	Start protocol.PacketNumber
 	End   protocol.PacketNumber
+	// the number of ACK frames this interval was included in (since it was last modified)
+	numReported uint32
 }
 
 var intervalElementPool sync.Pool
@@ -24,14 +27,16 @@ func init() {
 // It generates ACK ranges which can be used to assemble an ACK frame.
 // It does not store packet contents.
 type receivedPacketHistory struct {
-	ranges *list.List[interval]
+	ranges    *list.List[interval]
+	maxRanges int
 
 	deletedBelow protocol.PacketNumber
 }
 
-func newReceivedPacketHistory() *receivedPacketHistory {
+func newReceivedPacketHistory(maxRanges int) *receivedPacketHistory {
 	return &receivedPacketHistory{
-		ranges: list.NewWithPool[interval](&intervalElementPool),
+		ranges:    list.NewWithPool[interval](&intervalElementPool),
+		maxRanges: maxRanges,
 	}
 }
 
@@ -60,14 +65,17 @@ func (h *receivedPacketHistory) addToRanges(p protocol.PacketNumber) bool /* is
 
 		if el.Value.End == p-1 { // extend a range at the end
 			el.Value.End = p
+			el.Value.numReported = 0
 			return true
 		}
 		if el.Value.Start == p+1 { // extend a range at the beginning
 			el.Value.Start = p
+			el.Value.numReported = 0
 
 			prev := el.Prev()
 			if prev != nil && prev.Value.End+1 == el.Value.Start { // merge two ranges
 				prev.Value.End = el.Value.End
+				prev.Value.numReported = 0
 				h.ranges.Remove(el)
 			}
 			return true
@@ -85,11 +93,23 @@ func (h *receivedPacketHistory) addToRanges(p protocol.PacketNumber) bool /* is
 	return true
 }
 
-// Delete old ranges, if we're tracking more than 500 of them.
+// Delete ranges, if we're tracking more than maxRanges of them.
 // This is a DoS defense against a peer that sends us too many gaps.
+// On paths with a lot of reordering, packets arriving late create new ranges below the newest range.
+// Deleting the oldest range would drop exactly this information before the peer learned about it,
+// causing the peer to (spuriously) retransmit the data.
+// Instead, we delete the range that was already reported in the largest number of ACK frames
+// (the oldest one in case of a tie), since it's most likely that the peer already received one of these.
+// The newest range is never deleted.
 func (h *receivedPacketHistory) maybeDeleteOldRanges() {
-	for h.ranges.Len() > protocol.MaxNumAckRanges {
-		h.ranges.Remove(h.ranges.Front())
+	for h.ranges.Len() > h.maxRanges {
+		victim := h.ranges.Front()
+		for el := victim.Next(); el != nil && el != h.ranges.Back(); el = el.Next() {
+			if el.Value.numReported > victim.Value.numReported {
+				victim = el
+			}
+		}
+		h.ranges.Remove(victim)
 	}
 }
 
@@ -125,6 +145,17 @@ func (h *receivedPacketHistory) AppendAckRanges(ackRanges []wire.AckRange) []wir
 	return ackRanges
 }
 
+// ReportedAckRanges is called when an ACK frame is sent that contains the n newest ranges.
+// Ranges that didn't fit into the ACK frame are not counted as reported.
+func (h *receivedPacketHistory) ReportedAckRanges(n int) {
+	for el := h.ranges.Back(); el != nil && n > 0; el = el.Prev() {
+		if el.Value.numReported < math.MaxUint32 {
+			el.Value.numReported++
+		}
+		n--
+	}
+}
+
 func (h *receivedPacketHistory) GetHighestAckRange() wire.AckRange {
 	ackRange := wire.AckRange{}
 	if h.ranges.Len() > 0 {
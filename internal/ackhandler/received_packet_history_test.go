@@ -16,7 +16,7 @@ var _ = Describe("receivedPacketHistory", func() {
 	var hist *receivedPacketHistory
 
 	BeforeEach(func() {
-		hist = newReceivedPacketHistory()
+		hist = newReceivedPacketHistory(protocol.MaxNumAckRanges)
 	})
 
 	Context("ranges", func() {
@@ -200,6 +200,70 @@ var _ = Describe("receivedPacketHistory", func() {
 			Expect(hist.ranges.Len()).To(Equal(protocol.MaxNumAckRanges))
 			Expect(hist.ranges.Front().Value).To(Equal(interval{Start: 2, End: 2}))
 		})
+
+		It("uses the configured number of ranges", func() {
+			hist = newReceivedPacketHistory(100)
+			for i := protocol.PacketNumber(0); i < 200; i++ {
+				hist.ReceivedPacket(2 * i)
+			}
+			Expect(hist.ranges.Len()).To(Equal(100))
+			Expect(hist.ranges.Front().Value.Start).To(Equal(protocol.PacketNumber(200)))
+		})
+
+		It("deletes the range that was reported most often", func() {
+			hist = newReceivedPacketHistory(3)
+			Expect(hist.ReceivedPacket(10)).To(BeTrue())
+			Expect(hist.ReceivedPacket(20)).To(BeTrue())
+			Expect(hist.ReceivedPacket(30)).To(BeTrue())
+			hist.ReportedAckRanges(3)
+			// a belated packet, not reported yet
+			Expect(hist.ReceivedPacket(5)).To(BeTrue())
+			Expect(hist.ranges.Len()).To(Equal(3))
+			Expect(hist.AppendAckRanges(nil)).To(Equal([]wire.AckRange{
+				{Smallest: 30, Largest: 30},
+				{Smallest: 20, Largest: 20},
+				{Smallest: 5, Largest: 5},
+			}))
+			hist.ReportedAckRanges(3)
+			// extending a range resets its counter
+			Expect(hist.ReceivedPacket(21)).To(BeTrue())
+			Expect(hist.ReceivedPacket(40)).To(BeTrue())
+			Expect(hist.AppendAckRanges(nil)).To(Equal([]wire.AckRange{
+				{Smallest: 40, Largest: 40},
+				{Smallest: 20, Largest: 21},
+				{Smallest: 5, Largest: 5},
+			}))
+		})
+
+		It("only counts ranges that were included in the ACK frame as reported", func() {
+			hist = newReceivedPacketHistory(3)
+			Expect(hist.ReceivedPacket(10)).To(BeTrue())
+			Expect(hist.ReceivedPacket(20)).To(BeTrue())
+			Expect(hist.ReceivedPacket(30)).To(BeTrue())
+			// the range containing packet 10 didn't fit into the ACK frames
+			hist.ReportedAckRanges(2)
+			hist.ReportedAckRanges(2)
+			Expect(hist.ReceivedPacket(40)).To(BeTrue())
+			Expect(hist.AppendAckRanges(nil)).To(Equal([]wire.AckRange{
+				{Smallest: 40, Largest: 40},
+				{Smallest: 30, Largest: 30},
+				{Smallest: 10, Largest: 10},
+			}))
+		})
+
+		It("never deletes the newest range", func() {
+			hist = newReceivedPacketHistory(2)
+			Expect(hist.ReceivedPacket(10)).To(BeTrue())
+			Expect(hist.ReceivedPacket(20)).To(BeTrue())
+			for i := 0; i < 3; i++ {
+				hist.ReportedAckRanges(2)
+			}
+			Expect(hist.ReceivedPacket(5)).To(BeTrue())
+			Expect(hist.AppendAckRanges(nil)).To(Equal([]wire.AckRange{
+				{Smallest: 20, Largest: 20},
+				{Smallest: 5, Largest: 5},
+			}))
+		})
 	})
 
 	Context("ACK range export", func() {
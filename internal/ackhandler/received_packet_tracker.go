@@ -22,10 +22,13 @@ type receivedPacketTracker struct {
 
 	maxAckDelay time.Duration
 	rttStats    *utils.RTTStats
+	clock       utils.Clock
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
 
+	immediateAckRequested bool // true if the packet that is currently being processed contains an IMMEDIATE_ACK frame
+
 	ackElicitingPacketsReceivedSinceLastAck int
 	ackAlarm                                time.Time
 	lastAck                                 *wire.AckFrame
@@ -35,12 +38,15 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *utils.RTTStats,
+	clock utils.Clock,
+	maxAckRanges int,
 	logger utils.Logger,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
+		packetHistory: newReceivedPacketHistory(maxAckRanges),
 		maxAckDelay:   protocol.MaxAckDelay,
 		rttStats:      rttStats,
+		clock:         clock,
 		logger:        logger,
 	}
 }
@@ -62,6 +68,14 @@ func (h *receivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber, ecn pro
 	if ackEliciting {
 		h.maybeQueueACK(pn, rcvTime, isMissing)
 	}
+	if h.immediateAckRequested {
+		h.immediateAckRequested = false
+		if h.logger.Debug() && !h.ackQueued {
+			h.logger.Debugf("\tQueueing ACK because packet %d contained an IMMEDIATE_ACK frame.", pn)
+		}
+		h.ackQueued = true
+		h.ackAlarm = time.Time{}
+	}
 	//nolint:exhaustive // Only need to count ECT(0), ECT(1) and ECNCE.
 	switch ecn {
 	case protocol.ECT0:
@@ -74,6 +88,12 @@ func (h *receivedPacketTracker) ReceivedPacket(pn protocol.PacketNumber, ecn pro
 	return nil
 }
 
+// ImmediateAckRequested is called when the packet that is currently being processed contains an IMMEDIATE_ACK frame.
+// It must be called before ReceivedPacket is called for this packet.
+func (h *receivedPacketTracker) ImmediateAckRequested() {
+	h.immediateAckRequested = true
+}
+
 // IgnoreBelow sets a lower limit for acknowledging packets.
 // Packets with packet numbers smaller than p will not be acked.
 func (h *receivedPacketTracker) IgnoreBelow(pn protocol.PacketNumber) {
@@ -159,7 +179,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	if !h.hasNewAck {
 		return nil
 	}
-	now := time.Now()
+	now := h.clock.Now()
 	if onlyIfQueued {
 		if !h.ackQueued && (h.ackAlarm.IsZero() || h.ackAlarm.After(now)) {
 			return nil
@@ -180,6 +200,7 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	ack.ECT1 = h.ect1
 	ack.ECNCE = h.ecnce
 	ack.AckRanges = h.packetHistory.AppendAckRanges(ack.AckRanges)
+	h.packetHistory.ReportedAckRanges(ack.NumEncodableAckRanges())
 
 	h.lastAck = ack
 	h.ackAlarm = time.Time{}
@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"testing"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -19,7 +20,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger)
+		tracker = newReceivedPacketTracker(rttStats, utils.DefaultClock{}, protocol.MaxNumAckRanges, utils.DefaultLogger)
 	})
 
 	Context("accepting packets", func() {
@@ -72,6 +73,21 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.GetAckFrame(true).DelayTime).To(BeNumerically("~", 0, time.Second))
 			})
 
+			It("reuses the ACK frame", func() {
+				now := time.Now()
+				Expect(tracker.ReceivedPacket(0, protocol.ECNNon, now, true)).To(Succeed())
+				ack := tracker.GetAckFrame(false)
+				pn := protocol.PacketNumber(1)
+				var reused bool
+				allocs := testing.AllocsPerRun(100, func() {
+					tracker.ReceivedPacket(pn, protocol.ECNNon, now, true)
+					pn++
+					reused = tracker.GetAckFrame(false) == ack
+				})
+				Expect(reused).To(BeTrue())
+				Expect(allocs).To(BeZero())
+			})
+
 			It("sets ECN flags", func() {
 				Expect(tracker.ReceivedPacket(0, protocol.ECT0, time.Now(), true)).To(Succeed())
 				pn := protocol.PacketNumber(1)
@@ -138,6 +154,23 @@ var _ = Describe("Received Packet Tracker", func() {
 				Expect(tracker.ackQueued).To(BeTrue())
 			})
 
+			It("queues an ACK if the packet contains an IMMEDIATE_ACK frame", func() {
+				receiveAndAck10Packets()
+				Expect(tracker.ReceivedPacket(11, protocol.ECNNon, time.Now(), true)).To(Succeed())
+				Expect(tracker.ackQueued).To(BeFalse())
+				Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+				tracker.ImmediateAckRequested()
+				Expect(tracker.ReceivedPacket(13, protocol.ECNNon, time.Now(), true)).To(Succeed())
+				Expect(tracker.ackQueued).To(BeTrue())
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				ack := tracker.GetAckFrame(true)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(13)))
+				// only applies to a single packet
+				Expect(tracker.ReceivedPacket(14, protocol.ECNNon, time.Now(), true)).To(Succeed())
+				Expect(tracker.ackQueued).To(BeFalse())
+			})
+
 			It("doesn't recognize in-order packets as out-of-order after raising the threshold", func() {
 				receiveAndAck10Packets()
 				Expect(tracker.lastAck.LargestAcked()).To(Equal(protocol.PacketNumber(10)))
@@ -276,6 +309,30 @@ var _ = Describe("Received Packet Tracker", func() {
 					}))
 				})
 
+				It("only counts the ranges that fit into the ACK frame as reported", func() {
+					tracker = newReceivedPacketTracker(rttStats, utils.DefaultClock{}, protocol.MaxNumAckRangesLimit, utils.DefaultLogger)
+					// large gaps between the ranges, so not all of them fit into the ACK frame
+					for i := 0; i < protocol.MaxNumAckRangesLimit; i++ {
+						Expect(tracker.ReceivedPacket(protocol.PacketNumber(i*20000), protocol.ECNNon, time.Now(), true)).To(Succeed())
+					}
+					ack := tracker.GetAckFrame(false)
+					Expect(ack).ToNot(BeNil())
+					Expect(ack.AckRanges).To(HaveLen(protocol.MaxNumAckRangesLimit))
+					n := ack.NumEncodableAckRanges()
+					Expect(n).To(BeNumerically("<", protocol.MaxNumAckRangesLimit))
+					var numReported []uint32
+					for el := tracker.packetHistory.ranges.Back(); el != nil; el = el.Prev() {
+						numReported = append(numReported, el.Value.numReported)
+					}
+					for i, r := range numReported {
+						if i < n {
+							Expect(r).To(BeEquivalentTo(1))
+						} else {
+							Expect(r).To(BeZero())
+						}
+					}
+				})
+
 				It("errors when called with an old packet", func() {
 					tracker.IgnoreBelow(7)
 					Expect(tracker.IsPotentiallyDuplicate(4)).To(BeTrue())
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/congestion"
+	"github.com/quic-go/quic-go/internal/logutils"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
@@ -25,6 +26,9 @@ const (
 	minRTTAfterRetry = 5 * time.Millisecond
 	// The PTO duration uses exponential backoff, but is truncated to a maximum value, as allowed by RFC 8961, section 4.4.
 	maxPTODuration = 60 * time.Second
+	// Persistent congestion is declared if all packets sent during this multiple of the PTO duration were lost.
+	// Used unless a different threshold is configured.
+	defaultPersistentCongestionThreshold = 3
 )
 
 type packetNumberSpace struct {
@@ -33,6 +37,9 @@ type packetNumberSpace struct {
 
 	lossTime                   time.Time
 	lastAckElicitingPacketTime time.Time
+	// The latest send time of all packets acknowledged in this packet number space.
+	// Used to detect persistent congestion.
+	lastAckedSendTime time.Time
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
@@ -77,10 +84,24 @@ type sentPacketHandler struct {
 
 	ackedPackets []*packet // to avoid allocations in detectAndRemoveAckedPackets
 
+	// Set between StartAckBatch and FinishAckBatch.
+	inAckBatch bool
+	// 1-RTT packets acknowledged during the current batch, in the order they were acknowledged.
+	batchAckedPackets  []*packet
+	batchPriorInFlight protocol.ByteCount
+	batchRcvTime       time.Time
+
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
+	congestion   congestion.SendAlgorithmWithDebugInfos
+	deliveryRate *deliveryRateEstimator
+	rttStats     *utils.RTTStats
+	clock        utils.Clock
+	// The time when the first RTT sample was obtained.
+	// Only packets sent after this time are considered when detecting persistent congestion.
+	firstRTTSampleTime time.Time
+	// Persistent congestion is declared if all packets sent during this multiple of the PTO duration were lost.
+	persistentCongestionThreshold uint32
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -97,6 +118,8 @@ type sentPacketHandler struct {
 
 	perspective protocol.Perspective
 
+	packetObserver PacketObserver
+
 	tracer *logging.ConnectionTracer
 	logger utils.Logger
 }
@@ -112,19 +135,26 @@ func newSentPacketHandler(
 	initialPN protocol.PacketNumber,
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
+	clock utils.Clock,
 	clientAddressValidated bool,
 	enableECN bool,
+	persistentCongestionThreshold uint32,
+	persistentCongestionWindow uint32,
 	pers protocol.Perspective,
 	tracer *logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
 	congestion := congestion.NewCubicSender(
-		congestion.DefaultClock{},
+		clock,
 		rttStats,
 		initialMaxDatagramSize,
 		true, // use Reno
+		persistentCongestionWindow,
 		tracer,
 	)
+	if persistentCongestionThreshold == 0 {
+		persistentCongestionThreshold = defaultPersistentCongestionThreshold
+	}
 
 	h := &sentPacketHandler{
 		peerCompletedAddressValidation: pers == protocol.PerspectiveServer,
@@ -133,7 +163,10 @@ func newSentPacketHandler(
 		handshakePackets:               newPacketNumberSpace(0, false),
 		appDataPackets:                 newPacketNumberSpace(0, true),
 		rttStats:                       rttStats,
+		clock:                          clock,
 		congestion:                     congestion,
+		deliveryRate:                   newDeliveryRateEstimator(rttStats),
+		persistentCongestionThreshold:  persistentCongestionThreshold,
 		perspective:                    pers,
 		tracer:                         tracer,
 		logger:                         logger,
@@ -251,8 +284,9 @@ func (h *sentPacketHandler) SentPacket(
 	}
 
 	pnSpace.largestSent = pn
-	isAckEliciting := len(streamFrames) > 0 || len(frames) > 0
+	isAckEliciting := len(streamFrames) > 0 || HasAckElicitingFrames(frames)
 
+	priorInFlight := h.bytesInFlight
 	if isAckEliciting {
 		pnSpace.lastAckElicitingPacketTime = t
 		h.bytesInFlight += size
@@ -284,6 +318,7 @@ func (h *sentPacketHandler) SentPacket(
 	p.Frames = frames
 	p.IsPathMTUProbePacket = isPathMTUProbePacket
 	p.includedInBytesInFlight = true
+	h.deliveryRate.OnPacketSent(p, priorInFlight)
 
 	pnSpace.history.SentAckElicitingPacket(p)
 	if h.tracer != nil && h.tracer.UpdatedMetrics != nil {
@@ -330,6 +365,11 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if err != nil || len(ackedPackets) == 0 {
 		return false, err
 	}
+	for _, p := range ackedPackets {
+		if p.SendTime.After(pnSpace.lastAckedSendTime) {
+			pnSpace.lastAckedSendTime = p.SendTime
+		}
+	}
 	// update the RTT, if the largest acked is newly acknowledged
 	if len(ackedPackets) > 0 {
 		if p := ackedPackets[len(ackedPackets)-1]; p.PacketNumber == ack.LargestAcked() {
@@ -339,6 +379,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 				ackDelay = utils.Min(ack.DelayTime, h.rttStats.MaxAckDelay())
 			}
 			h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackDelay, rcvTime)
+			if h.firstRTTSampleTime.IsZero() {
+				h.firstRTTSampleTime = rcvTime
+			}
 			if h.logger.Debug() {
 				h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 			}
@@ -356,9 +399,37 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 
 	pnSpace.largestAcked = utils.Max(pnSpace.largestAcked, largestAcked)
 
+	if h.inAckBatch && encLevel == protocol.Encryption1RTT {
+		// Loss detection and the congestion controller are run once, when the batch is finished.
+		var acked1RTTPacket bool
+		for _, p := range ackedPackets {
+			if p.EncryptionLevel == protocol.Encryption1RTT {
+				acked1RTTPacket = true
+			}
+		}
+		h.batchAckedPackets = append(h.batchAckedPackets, ackedPackets...)
+		h.batchRcvTime = rcvTime
+		return acked1RTTPacket, nil
+	}
+	return h.processAckedPackets(ackedPackets, encLevel, priorInFlight, rcvTime)
+}
+
+// processAckedPackets runs loss detection, informs the congestion controller about the acknowledged packets,
+// and returns them to the pool.
+func (h *sentPacketHandler) processAckedPackets(ackedPackets []*packet, encLevel protocol.EncryptionLevel, priorInFlight protocol.ByteCount, rcvTime time.Time) (bool /* contained 1-RTT packet */, error) {
+	// Report the acknowledged packets before the lost packets,
+	// so the observer knows which packets sent after a lost packet were received.
+	if h.packetObserver != nil && encLevel == protocol.Encryption1RTT {
+		for _, p := range ackedPackets {
+			if !p.IsPathMTUProbePacket {
+				h.packetObserver.OnPacketAcked(p.PacketNumber, p.Length)
+			}
+		}
+	}
 	if err := h.detectLostPackets(rcvTime, encLevel); err != nil {
 		return false, err
 	}
+	h.deliveryRate.OnPacketsAcked(ackedPackets, rcvTime)
 	var acked1RTTPacket bool
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight && !p.declaredLost {
@@ -391,6 +462,24 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return acked1RTTPacket, nil
 }
 
+func (h *sentPacketHandler) StartAckBatch() {
+	h.inAckBatch = true
+	h.batchPriorInFlight = h.bytesInFlight
+}
+
+func (h *sentPacketHandler) FinishAckBatch() error {
+	h.inAckBatch = false
+	if len(h.batchAckedPackets) == 0 {
+		return nil
+	}
+	_, err := h.processAckedPackets(h.batchAckedPackets, protocol.Encryption1RTT, h.batchPriorInFlight, h.batchRcvTime)
+	for i := range h.batchAckedPackets {
+		h.batchAckedPackets[i] = nil
+	}
+	h.batchAckedPackets = h.batchAckedPackets[:0]
+	return err
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -505,7 +594,7 @@ func (h *sentPacketHandler) getPTOTimeAndSpace() (pto time.Time, encLevel protoc
 		if h.peerCompletedAddressValidation {
 			return
 		}
-		t := time.Now().Add(h.getScaledPTO(false))
+		t := h.clock.Now().Add(h.getScaledPTO(false))
 		if h.initialPackets != nil {
 			return t, protocol.EncryptionInitial, true
 		}
@@ -617,7 +706,18 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	lostSendTime := now.Add(-lossDelay)
 
 	priorInFlight := h.bytesInFlight
-	return pnSpace.history.Iterate(func(p *packet) (bool, error) {
+	// Used to detect persistent congestion (see section 7.6.2 of RFC 9002):
+	// No packet sent between the first and the last lost packet may have been acknowledged.
+	// A run therefore consists of lost packets with consecutive packet numbers:
+	// Packets that were acknowledged (or weren't tracked, or were declared lost before) leave a gap.
+	// For every run, we track the send times of the first and the last ack-eliciting packet,
+	// and remember the run spanning the longest period.
+	var (
+		runStart, runEnd               time.Time
+		lastLostPN                     = protocol.InvalidPacketNumber
+		longestRunStart, longestRunEnd time.Time
+	)
+	if err := pnSpace.history.Iterate(func(p *packet) (bool, error) {
 		if p.PacketNumber > pnSpace.largestAcked {
 			return false, nil
 		}
@@ -651,14 +751,38 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			}
 			pnSpace.lossTime = lossTime
 		}
+		if !packetLost || lastLostPN == protocol.InvalidPacketNumber || p.PacketNumber != lastLostPN+1 {
+			runStart, runEnd = time.Time{}, time.Time{}
+		}
+		if packetLost {
+			lastLostPN = p.PacketNumber
+			// Only packets sent after the first RTT sample are considered.
+			if !p.skippedPacket && !h.firstRTTSampleTime.IsZero() && p.SendTime.After(h.firstRTTSampleTime) {
+				if runStart.IsZero() {
+					runStart = p.SendTime
+				}
+				runEnd = p.SendTime
+				if runEnd.Sub(runStart) > longestRunEnd.Sub(longestRunStart) {
+					longestRunStart, longestRunEnd = runStart, runEnd
+				}
+			}
+		} else {
+			lastLostPN = protocol.InvalidPacketNumber
+		}
 		if packetLost {
 			pnSpace.history.DeclareLost(p.PacketNumber)
 			if !p.skippedPacket {
+				if h.tracer != nil && h.tracer.LostFrames != nil {
+					h.tracer.LostFrames(p.EncryptionLevel, p.PacketNumber, convertFrames(p))
+				}
 				// the bytes in flight need to be reduced no matter if the frames in this packet will be retransmitted
 				h.removeFromBytesInFlight(p)
 				h.queueFramesForRetransmission(p)
 				if !p.IsPathMTUProbePacket {
 					h.congestion.OnCongestionEvent(p.PacketNumber, p.Length, priorInFlight)
+					if h.packetObserver != nil && encLevel == protocol.Encryption1RTT {
+						h.packetObserver.OnPacketLost(p.PacketNumber, p.Length)
+					}
 				}
 				if encLevel == protocol.Encryption1RTT && h.ecnTracker != nil {
 					h.ecnTracker.LostPacket(p.PacketNumber)
@@ -666,7 +790,52 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			}
 		}
 		return true, nil
-	})
+	}); err != nil {
+		return err
+	}
+	if period := longestRunEnd.Sub(longestRunStart); period > h.persistentCongestionDuration() && !h.ackedInOtherSpacesSince(pnSpace, longestRunStart) {
+		if h.logger.Debug() {
+			h.logger.Debugf("\tpersistent congestion detected (lost packets sent during %s)", period)
+		}
+		if h.tracer != nil && h.tracer.EnteredPersistentCongestion != nil {
+			h.tracer.EnteredPersistentCongestion()
+		}
+		h.congestion.OnRetransmissionTimeout(true)
+	}
+	return nil
+}
+
+// ackedInOtherSpacesSince says if a packet sent at or after t was acknowledged in any packet number space other than pnSpace.
+// Acknowledged packets are removed from the history, so we can't tell if the packet was sent before a given time as well.
+// This makes persistent congestion detection conservative while multiple packet number spaces are in use.
+func (h *sentPacketHandler) ackedInOtherSpacesSince(pnSpace *packetNumberSpace, t time.Time) bool {
+	for _, s := range []*packetNumberSpace{h.initialPackets, h.handshakePackets, h.appDataPackets} {
+		if s == nil || s == pnSpace {
+			continue
+		}
+		if !s.lastAckedSendTime.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// persistentCongestionDuration is the duration defined in section 7.6.1 of RFC 9002.
+func (h *sentPacketHandler) persistentCongestionDuration() time.Duration {
+	return time.Duration(h.persistentCongestionThreshold) * h.rttStats.PTO(true)
+}
+
+func convertFrames(p *packet) []logging.Frame {
+	frames := make([]logging.Frame, 0, len(p.Frames)+len(p.StreamFrames))
+	for _, f := range p.Frames {
+		if f.Frame != nil {
+			frames = append(frames, logutils.ConvertFrame(f.Frame))
+		}
+	}
+	for _, f := range p.StreamFrames {
+		frames = append(frames, logutils.ConvertFrame(f.Frame))
+	}
+	return frames
 }
 
 func (h *sentPacketHandler) OnLossDetectionTimeout() error {
@@ -680,7 +849,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 			h.tracer.LossTimerExpired(logging.TimerTypeACK, encLevel)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel)
+		return h.detectLostPackets(h.clock.Now(), encLevel)
 	}
 
 	// PTO
@@ -732,6 +901,9 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 		pn := h.PopPacketNumber(protocol.Encryption1RTT)
 		h.getPacketNumberSpace(protocol.Encryption1RTT).history.SkippedPacket(pn)
 		h.ptoMode = SendPTOAppData
+		if h.packetObserver != nil {
+			h.packetObserver.OnPTO(h.ptoCount)
+		}
 	default:
 		return fmt.Errorf("PTO timer in unexpected encryption level: %s", encLevel)
 	}
@@ -825,6 +997,61 @@ func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
 	h.congestion.SetMaxDatagramSize(s)
 }
 
+func (h *sentPacketHandler) SetPacketObserver(o PacketObserver) {
+	h.packetObserver = o
+}
+
+func (h *sentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	return h.congestion.GetCongestionWindow()
+}
+
+func (h *sentPacketHandler) GetBytesInFlight() protocol.ByteCount {
+	return h.bytesInFlight
+}
+
+func (h *sentPacketHandler) OnAppLimited() {
+	h.deliveryRate.OnAppLimited(h.bytesInFlight)
+}
+
+func (h *sentPacketHandler) BandwidthEstimate() (uint64, bool) {
+	return h.deliveryRate.BandwidthEstimate()
+}
+
+func (h *sentPacketHandler) MemoryUsage() int {
+	var n int
+	for _, pnSpace := range []*packetNumberSpace{h.initialPackets, h.handshakePackets, h.appDataPackets} {
+		if pnSpace != nil {
+			n += pnSpace.history.MemoryUsage()
+		}
+	}
+	return n
+}
+
+func (h *sentPacketHandler) MigratedPath(bytesReceived protocol.ByteCount, resetCongestion bool) {
+	if h.perspective == protocol.PerspectiveServer {
+		h.peerAddressValidated = false
+		h.bytesReceived = bytesReceived
+		h.bytesSent = 0
+	}
+	if resetCongestion {
+		h.rttStats.OnConnectionMigration()
+		h.congestion.OnConnectionMigration()
+	}
+	h.setLossDetectionTimer()
+}
+
+func (h *sentPacketHandler) ValidatedPath() {
+	if h.peerAddressValidated {
+		return
+	}
+	h.peerAddressValidated = true
+	h.setLossDetectionTimer()
+}
+
+func (h *sentPacketHandler) IsAmplificationLimited() bool {
+	return h.isAmplificationLimited()
+}
+
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
@@ -860,6 +1087,8 @@ func (h *sentPacketHandler) queueFramesForRetransmission(p *packet) {
 			f.Handler.OnLost(f.Frame)
 		}
 	}
+	PutFrames(p.Frames)
+	PutStreamFrames(p.StreamFrames)
 	p.StreamFrames = nil
 	p.Frames = nil
 }
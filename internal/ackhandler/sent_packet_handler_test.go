@@ -5,10 +5,12 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/mocks"
+	mocklogging "github.com/quic-go/quic-go/internal/mocks/logging"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,6 +33,27 @@ func (h *customFrameHandler) OnAcked(f wire.Frame) {
 	}
 }
 
+type packetObserverEvent struct {
+	pn   protocol.PacketNumber
+	size protocol.ByteCount
+	lost bool
+}
+
+type recordingPacketObserver struct {
+	events    []packetObserverEvent
+	ptoCounts []uint32
+}
+
+func (o *recordingPacketObserver) OnPacketAcked(pn protocol.PacketNumber, size protocol.ByteCount) {
+	o.events = append(o.events, packetObserverEvent{pn: pn, size: size})
+}
+
+func (o *recordingPacketObserver) OnPacketLost(pn protocol.PacketNumber, size protocol.ByteCount) {
+	o.events = append(o.events, packetObserverEvent{pn: pn, size: size, lost: true})
+}
+
+func (o *recordingPacketObserver) OnPTO(ptoCount uint32) { o.ptoCounts = append(o.ptoCounts, ptoCount) }
+
 var _ = Describe("SentPacketHandler", func() {
 	var (
 		handler     *sentPacketHandler
@@ -44,7 +67,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, false, false, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, utils.DefaultClock{}, false, false, 0, 0, perspective, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -52,12 +75,8 @@ var _ = Describe("SentPacketHandler", func() {
 	})
 
 	getPacket := func(pn protocol.PacketNumber, encLevel protocol.EncryptionLevel) *packet {
-		for _, p := range handler.getPacketNumberSpace(encLevel).history.packets {
-			if p != nil && p.PacketNumber == pn {
-				return p
-			}
-		}
-		return nil
+		p, _ := handler.getPacketNumberSpace(encLevel).history.get(pn)
+		return p
 	}
 
 	ackElicitingPacket := func(p *packet) *packet {
@@ -173,6 +192,15 @@ var _ = Describe("SentPacketHandler", func() {
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, SendTime: sendTime.Add(time.Hour), EncryptionLevel: protocol.Encryption1RTT}))
 			Expect(handler.initialPackets.lastAckElicitingPacketTime).To(Equal(sendTime))
 		})
+
+		It("doesn't count packets that only contain non-ack-eliciting extension frames as in flight", func() {
+			sentPacket(ackElicitingPacket(&packet{
+				PacketNumber: 1,
+				Frames:       []Frame{{Frame: &wire.ExtensionFrame{FrameType: 0x42}}},
+			}))
+			Expect(handler.bytesInFlight).To(BeZero())
+			expectInPacketHistory([]protocol.PacketNumber{}, protocol.Encryption1RTT)
+		})
 	})
 
 	Context("ACK processing", func() {
@@ -516,6 +544,27 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("bandwidth estimation", func() {
+		It("estimates the bandwidth from the acknowledged packets", func() {
+			sendTime := time.Now().Add(-time.Second)
+			for i := 0; i < 10; i++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: protocol.PacketNumber(i), Length: 1000, SendTime: sendTime}))
+			}
+			bw, appLimited := handler.BandwidthEstimate()
+			Expect(bw).To(BeZero())
+			Expect(appLimited).To(BeFalse())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 9}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, sendTime.Add(100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			bw, appLimited = handler.BandwidthEstimate()
+			Expect(bw).To(BeEquivalentTo(10 * 1000 * 10))
+			Expect(appLimited).To(BeFalse())
+			handler.OnAppLimited()
+			_, appLimited = handler.BandwidthEstimate()
+			Expect(appLimited).To(BeTrue())
+		})
+	})
+
 	Context("congestion", func() {
 		var cong *mocks.MockSendAlgorithmWithDebugInfos
 
@@ -628,6 +677,62 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("defers OnPacketAcked until the end of an ACK batch", func() {
+			rcvTime := time.Now()
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3}))
+			handler.StartAckBatch()
+			cong.EXPECT().MaybeExitSlowStart().Times(2)
+			acked1RTT, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}, protocol.Encryption1RTT, rcvTime.Add(-time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(acked1RTT).To(BeTrue())
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}, protocol.Encryption1RTT, rcvTime)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.appDataPackets.largestAcked).To(Equal(protocol.PacketNumber(3)))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(3)))
+			gomock.InOrder(
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(3), protocol.ByteCount(1), protocol.ByteCount(3), rcvTime),
+			)
+			Expect(handler.FinishAckBatch()).To(Succeed())
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+
+		It("runs loss detection once at the end of an ACK batch", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2}))
+			handler.StartAckBatch()
+			// Without batching, packet 1 would be declared lost when the first ACK is processed.
+			cong.EXPECT().MaybeExitSlowStart()
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			gomock.InOrder(
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(1), protocol.ByteCount(2), gomock.Any()),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(1), protocol.ByteCount(2), gomock.Any()),
+			)
+			Expect(handler.FinishAckBatch()).To(Succeed())
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+
+		It("doesn't batch ACKs for Handshake packets", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sentPacket(handshakePacket(&packet{PacketNumber: 0}))
+			handler.StartAckBatch()
+			gomock.InOrder(
+				cong.EXPECT().MaybeExitSlowStart(),
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(0), protocol.ByteCount(1), protocol.ByteCount(1), gomock.Any()),
+			)
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 0}}}, protocol.EncryptionHandshake, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.FinishAckBatch()).To(Succeed())
+		})
+
 		It("passes the bytes in flight to the congestion controller", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), gomock.Any(), protocol.ByteCount(42), true)
@@ -928,6 +1033,7 @@ var _ = Describe("SentPacketHandler", func() {
 				SendTime:        now,
 			})
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
 			sentPacket(&packet{
 				PacketNumber:    2,
 				Length:          1,
@@ -936,6 +1042,7 @@ var _ = Describe("SentPacketHandler", func() {
 				SendTime:        now,
 			})
 			Expect(handler.SendMode(time.Now())).To(Equal(SendNone))
+			Expect(handler.IsAmplificationLimited()).To(BeTrue())
 		})
 
 		It("cancels the loss detection timer when it is amplification limited, and resets it when becoming unblocked", func() {
@@ -979,12 +1086,41 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 4}}}, protocol.EncryptionHandshake, time.Now())
 			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
 		})
+
+		It("applies the limit to a new path until it is validated", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			setHandshakeConfirmed()
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
+			handler.MigratedPath(100, false)
+			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 300, SendTime: time.Now()}))
+			Expect(handler.IsAmplificationLimited()).To(BeTrue())
+			Expect(handler.SendMode(time.Now())).To(Equal(SendNone))
+			handler.ReceivedBytes(1)
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, Length: 3, SendTime: time.Now()}))
+			Expect(handler.IsAmplificationLimited()).To(BeTrue())
+			handler.ValidatedPath()
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
+			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
+		})
+
+		It("resets the congestion controller and the RTT estimate", func() {
+			cong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			handler.congestion = cong
+			updateRTT(time.Second)
+			handler.MigratedPath(100, false)
+			Expect(handler.rttStats.SmoothedRTT()).To(Equal(time.Second))
+			cong.EXPECT().OnConnectionMigration()
+			handler.MigratedPath(100, true)
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+		})
 	})
 
 	Context("amplification limit, for the server, with validated address", func() {
 		JustBeforeEach(func() {
 			rttStats := utils.NewRTTStats()
-			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, true, false, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, utils.DefaultClock{}, true, false, 0, 0, perspective, nil, utils.DefaultLogger)
 		})
 
 		It("do not limits the window", func() {
@@ -998,6 +1134,7 @@ var _ = Describe("SentPacketHandler", func() {
 				SendTime:        time.Now(),
 			})
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
 		})
 	})
 
@@ -1006,6 +1143,12 @@ var _ = Describe("SentPacketHandler", func() {
 			perspective = protocol.PerspectiveClient
 		})
 
+		It("doesn't apply the limit when migrating to a new path", func() {
+			handler.MigratedPath(0, false)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 1000, SendTime: time.Now()}))
+			Expect(handler.IsAmplificationLimited()).To(BeFalse())
+		})
+
 		It("sends an Initial packet to unblock the server", func() {
 			sentPacket(initialPacket(&packet{PacketNumber: 1}))
 			_, err := handler.ReceivedAck(
@@ -1120,6 +1263,68 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("observing packets", func() {
+		var observer *recordingPacketObserver
+
+		JustBeforeEach(func() {
+			observer = &recordingPacketObserver{}
+			handler.SetPacketObserver(observer)
+		})
+
+		It("reports acknowledged packets before lost packets", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				sendTime := now
+				if i <= 2 {
+					sendTime = now.Add(-time.Hour)
+				}
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: i, Length: 1000 + protocol.ByteCount(i), SendTime: sendTime}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 4}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(observer.events).To(Equal([]packetObserverEvent{
+				{pn: 3, size: 1003},
+				{pn: 4, size: 1004},
+				{pn: 1, size: 1001, lost: true},
+				{pn: 2, size: 1002, lost: true},
+			}))
+		})
+
+		It("doesn't report Path MTU probe packets", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 1500, SendTime: now.Add(-time.Hour), IsPathMTUProbePacket: true}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, Length: 1000, SendTime: now}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3, Length: 1500, SendTime: now, IsPathMTUProbePacket: true}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			Expect(observer.events).To(Equal([]packetObserverEvent{{pn: 2, size: 1000}}))
+		})
+
+		It("doesn't report Handshake packets", func() {
+			for i := protocol.PacketNumber(1); i <= 4; i++ {
+				sentPacket(handshakePacket(&packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}}}
+			_, err := handler.ReceivedAck(ack, protocol.EncryptionHandshake, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			Expect(observer.events).To(BeEmpty())
+		})
+
+		It("reports PTOs", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			setHandshakeConfirmed()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Minute)}))
+			handler.appDataPackets.pns.(*skippingPacketNumberGenerator).next = 2
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(observer.ptoCounts).To(Equal([]uint32{1, 2}))
+		})
+	})
+
 	Context("Delay-based loss detection", func() {
 		It("immediately detects old packets as lost when receiving an ACK", func() {
 			now := time.Now()
@@ -1208,6 +1413,170 @@ var _ = Describe("SentPacketHandler", func() {
 		})
 	})
 
+	Context("persistent congestion", func() {
+		var (
+			cong   *mocks.MockSendAlgorithmWithDebugInfos
+			tracer *mocklogging.MockConnectionTracer
+		)
+
+		JustBeforeEach(func() {
+			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().MaybeExitSlowStart().AnyTimes()
+			cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			cong.EXPECT().OnCongestionEvent(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.congestion = cong
+			var tr *logging.ConnectionTracer
+			tr, tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+			tracer.EXPECT().LostPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			handler.tracer = &logging.ConnectionTracer{
+				LostPacket:                  tr.LostPacket,
+				EnteredPersistentCongestion: tr.EnteredPersistentCongestion,
+			}
+		})
+
+		It("traces the frames of lost packets", func() {
+			handler.tracer.LostFrames = func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, frames []logging.Frame) {
+				tracer.LostFrames(encLevel, pn, frames)
+			}
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{
+				PacketNumber: 1,
+				SendTime:     now.Add(-time.Hour),
+				Frames:       []Frame{{Frame: &wire.MaxDataFrame{MaximumData: 1337}}},
+				StreamFrames: []StreamFrame{{Frame: &wire.StreamFrame{StreamID: 4, Offset: 10, Data: []byte("foobar")}}},
+			}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, SendTime: now}))
+			tracer.EXPECT().LostFrames(protocol.Encryption1RTT, protocol.PacketNumber(1), []logging.Frame{
+				&logging.MaxDataFrame{MaximumData: 1337},
+				&logging.StreamFrame{StreamID: 4, Offset: 10, Length: 6},
+			})
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("detects persistent congestion", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Hour+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			for pn := protocol.PacketNumber(2); pn <= 4; pn++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: now.Add(-time.Duration(50-pn) * time.Minute)}))
+			}
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 5, SendTime: now}))
+			gomock.InOrder(
+				tracer.EXPECT().EnteredPersistentCongestion(),
+				cong.EXPECT().OnRetransmissionTimeout(true),
+			)
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{2, 3, 4}))
+		})
+
+		It("reduces the congestion window to the persistent congestion window", func() {
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), utils.DefaultClock{}, false, false, 0, 4, perspective, nil, utils.DefaultLogger)
+			Expect(handler.GetCongestionWindow()).To(BeNumerically(">", 4*protocol.InitialPacketSizeIPv4))
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Hour+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			for pn := protocol.PacketNumber(2); pn <= 4; pn++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: now.Add(-time.Duration(50-pn) * time.Minute)}))
+			}
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 5, SendTime: now}))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{2, 3, 4}))
+			Expect(handler.GetCongestionWindow()).To(Equal(protocol.ByteCount(4 * protocol.InitialPacketSizeIPv4)))
+		})
+
+		It("uses a configurable persistent congestion threshold", func() {
+			rttStats := handler.rttStats
+			rttStats.UpdateRTT(100*time.Millisecond, 0, time.Now())
+			Expect(handler.persistentCongestionDuration()).To(Equal(3 * rttStats.PTO(true)))
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, utils.DefaultClock{}, false, false, 10, 0, perspective, nil, utils.DefaultLogger)
+			Expect(handler.persistentCongestionDuration()).To(Equal(10 * rttStats.PTO(true)))
+		})
+
+		It("doesn't detect persistent congestion if the lost packets were sent in a short period", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Hour+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			for pn := protocol.PacketNumber(2); pn <= 4; pn++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: now.Add(-time.Minute + time.Duration(pn)*time.Millisecond)}))
+			}
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 5, SendTime: now}))
+			// don't EXPECT any calls to EnteredPersistentCongestion or OnRetransmissionTimeout
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{2, 3, 4}))
+		})
+
+		It("doesn't detect persistent congestion if a packet sent in the middle of the lost packets was acknowledged", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Hour+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			// Each of the two runs of lost packets was sent in a short period,
+			// but the period between the first and the last lost packet is long.
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, SendTime: now.Add(-50 * time.Minute)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3, SendTime: now.Add(-50*time.Minute + time.Millisecond)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 4, SendTime: now.Add(-30 * time.Minute)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 5, SendTime: now.Add(-10 * time.Minute)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 6, SendTime: now.Add(-10*time.Minute + time.Millisecond)}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 7, SendTime: now}))
+			// don't EXPECT any calls to EnteredPersistentCongestion or OnRetransmissionTimeout
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 7, Largest: 7}, {Smallest: 4, Largest: 4}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{2, 3, 5, 6}))
+		})
+
+		It("doesn't detect persistent congestion if a packet was acknowledged in a different packet number space", func() {
+			now := time.Now()
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Hour)}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(-time.Hour+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			for pn := protocol.PacketNumber(2); pn <= 4; pn++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: now.Add(-time.Duration(50-pn) * time.Minute)}))
+			}
+			// a Handshake packet sent while the 1-RTT packets were lost is acknowledged
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, EncryptionLevel: protocol.EncryptionHandshake, SendTime: now.Add(-47 * time.Minute)}))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}
+			_, err = handler.ReceivedAck(ack, protocol.EncryptionHandshake, now.Add(-47*time.Minute+100*time.Millisecond))
+			Expect(err).ToNot(HaveOccurred())
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 5, SendTime: now}))
+			// don't EXPECT any calls to EnteredPersistentCongestion or OnRetransmissionTimeout
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{2, 3, 4}))
+		})
+
+		It("doesn't consider packets sent before the first RTT sample", func() {
+			now := time.Now()
+			for pn := protocol.PacketNumber(1); pn <= 3; pn++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: now.Add(-time.Duration(50-pn) * time.Minute)}))
+			}
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 4, SendTime: now}))
+			// don't EXPECT any calls to EnteredPersistentCongestion or OnRetransmissionTimeout
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+		})
+	})
+
 	Context("crypto packets", func() {
 		It("rejects an ACK that acks packets with a higher encryption level", func() {
 			sentPacket(ackElicitingPacket(&packet{
@@ -1443,7 +1812,7 @@ var _ = Describe("SentPacketHandler", func() {
 			lostPackets = nil
 			rttStats := utils.NewRTTStats()
 			rttStats.UpdateRTT(time.Hour, 0, time.Now())
-			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, false, false, perspective, nil, utils.DefaultLogger)
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, utils.DefaultClock{}, false, false, 0, 0, perspective, nil, utils.DefaultLogger)
 			handler.ecnTracker = ecnHandler
 			handler.congestion = cong
 		})
@@ -2,12 +2,23 @@ package ackhandler
 
 import (
 	"fmt"
+	"unsafe"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 )
 
+const sentPacketHistoryInitialSize = 32 // must be a power of 2
+
 type sentPacketHistory struct {
-	packets []*packet
+	// packets is a ring buffer, indexed by packet number:
+	// The packet with packet number pn is stored at packets[pn & (len(packets)-1)].
+	// Its length is always a power of 2.
+	// It contains numPackets slots, starting at firstPacketNumber.
+	// Slots for non-ack-eliciting packets, and for packets that were removed, are nil.
+	// The slot of firstPacketNumber is never nil.
+	packets           []*packet
+	firstPacketNumber protocol.PacketNumber
+	numPackets        int
 
 	numOutstanding int
 
@@ -16,7 +27,7 @@ type sentPacketHistory struct {
 
 func newSentPacketHistory() *sentPacketHistory {
 	return &sentPacketHistory{
-		packets:             make([]*packet, 0, 32),
+		packets:             make([]*packet, sentPacketHistoryInitialSize),
 		highestPacketNumber: protocol.InvalidPacketNumber,
 	}
 }
@@ -29,10 +40,40 @@ func (h *sentPacketHistory) checkSequentialPacketNumberUse(pn protocol.PacketNum
 	}
 }
 
+func (h *sentPacketHistory) slot(pn protocol.PacketNumber) int {
+	return int(pn) & (len(h.packets) - 1)
+}
+
+// append adds the slot for the next packet number.
+// Slots for non-ack-eliciting packets are only added if the history is not empty.
+func (h *sentPacketHistory) append(pn protocol.PacketNumber, p *packet) {
+	if h.numPackets == 0 {
+		if p == nil {
+			return
+		}
+		h.firstPacketNumber = pn
+	}
+	if h.numPackets == len(h.packets) {
+		h.grow()
+	}
+	h.packets[h.slot(pn)] = p
+	h.numPackets++
+}
+
+// grow doubles the size of the ring buffer.
+func (h *sentPacketHistory) grow() {
+	old := h.packets
+	h.packets = make([]*packet, 2*len(old))
+	for i := 0; i < h.numPackets; i++ {
+		pn := h.firstPacketNumber + protocol.PacketNumber(i)
+		h.packets[h.slot(pn)] = old[int(pn)&(len(old)-1)]
+	}
+}
+
 func (h *sentPacketHistory) SkippedPacket(pn protocol.PacketNumber) {
 	h.checkSequentialPacketNumberUse(pn)
 	h.highestPacketNumber = pn
-	h.packets = append(h.packets, &packet{
+	h.append(pn, &packet{
 		PacketNumber:  pn,
 		skippedPacket: true,
 	})
@@ -41,23 +82,24 @@ func (h *sentPacketHistory) SkippedPacket(pn protocol.PacketNumber) {
 func (h *sentPacketHistory) SentNonAckElicitingPacket(pn protocol.PacketNumber) {
 	h.checkSequentialPacketNumberUse(pn)
 	h.highestPacketNumber = pn
-	if len(h.packets) > 0 {
-		h.packets = append(h.packets, nil)
-	}
+	h.append(pn, nil)
 }
 
 func (h *sentPacketHistory) SentAckElicitingPacket(p *packet) {
 	h.checkSequentialPacketNumberUse(p.PacketNumber)
 	h.highestPacketNumber = p.PacketNumber
-	h.packets = append(h.packets, p)
+	h.append(p.PacketNumber, p)
 	if p.outstanding() {
 		h.numOutstanding++
 	}
 }
 
 // Iterate iterates through all packets.
+// The callback may remove packets from the history.
 func (h *sentPacketHistory) Iterate(cb func(*packet) (cont bool, err error)) error {
-	for _, p := range h.packets {
+	first, num := h.firstPacketNumber, h.numPackets
+	for i := 0; i < num; i++ {
+		p := h.packets[h.slot(first+protocol.PacketNumber(i))]
 		if p == nil {
 			continue
 		}
@@ -77,8 +119,8 @@ func (h *sentPacketHistory) FirstOutstanding() *packet {
 	if !h.HasOutstandingPackets() {
 		return nil
 	}
-	for _, p := range h.packets {
-		if p != nil && p.outstanding() {
+	for i := 0; i < h.numPackets; i++ {
+		if p := h.packets[h.slot(h.firstPacketNumber+protocol.PacketNumber(i))]; p != nil && p.outstanding() {
 			return p
 		}
 	}
@@ -86,92 +128,95 @@ func (h *sentPacketHistory) FirstOutstanding() *packet {
 }
 
 func (h *sentPacketHistory) Len() int {
-	return len(h.packets)
+	return h.numPackets
+}
+
+// MemoryUsage estimates the number of bytes used to track the packets in the history.
+// The data of STREAM frames is not included, it is held by the streams.
+func (h *sentPacketHistory) MemoryUsage() int {
+	n := cap(h.packets) * int(unsafe.Sizeof(&packet{}))
+	for _, p := range h.packets {
+		if p == nil {
+			continue
+		}
+		n += int(unsafe.Sizeof(*p)) +
+			cap(p.Frames)*int(unsafe.Sizeof(Frame{})) +
+			cap(p.StreamFrames)*int(unsafe.Sizeof(StreamFrame{}))
+	}
+	return n
 }
 
 func (h *sentPacketHistory) Remove(pn protocol.PacketNumber) error {
-	idx, ok := h.getIndex(pn)
+	p, ok := h.get(pn)
 	if !ok {
 		return fmt.Errorf("packet %d not found in sent packet history", pn)
 	}
-	p := h.packets[idx]
 	if p.outstanding() {
 		h.numOutstanding--
 		if h.numOutstanding < 0 {
 			panic("negative number of outstanding packets")
 		}
 	}
-	h.packets[idx] = nil
+	h.packets[h.slot(pn)] = nil
 	// clean up all skipped packets directly before this packet number
-	for idx > 0 {
-		idx--
-		p := h.packets[idx]
+	for pn > h.firstPacketNumber {
+		pn--
+		p := h.packets[h.slot(pn)]
 		if p == nil || !p.skippedPacket {
 			break
 		}
-		h.packets[idx] = nil
+		h.packets[h.slot(pn)] = nil
 	}
-	if idx == 0 {
+	if pn == h.firstPacketNumber {
 		h.cleanupStart()
 	}
-	if len(h.packets) > 0 && h.packets[0] == nil {
+	if h.numPackets > 0 && h.packets[h.slot(h.firstPacketNumber)] == nil {
 		panic("remove failed")
 	}
 	return nil
 }
 
-// getIndex gets the index of packet p in the packets slice.
-func (h *sentPacketHistory) getIndex(p protocol.PacketNumber) (int, bool) {
-	if len(h.packets) == 0 {
-		return 0, false
-	}
-	first := h.packets[0].PacketNumber
-	if p < first {
-		return 0, false
+// get returns the packet with packet number pn.
+func (h *sentPacketHistory) get(pn protocol.PacketNumber) (*packet, bool) {
+	if h.numPackets == 0 || pn < h.firstPacketNumber || pn >= h.firstPacketNumber+protocol.PacketNumber(h.numPackets) {
+		return nil, false
 	}
-	index := int(p - first)
-	if index > len(h.packets)-1 {
-		return 0, false
-	}
-	return index, true
+	p := h.packets[h.slot(pn)]
+	return p, p != nil
 }
 
 func (h *sentPacketHistory) HasOutstandingPackets() bool {
 	return h.numOutstanding > 0
 }
 
-// delete all nil entries at the beginning of the packets slice
+// delete all nil slots at the beginning of the ring buffer
 func (h *sentPacketHistory) cleanupStart() {
-	for i, p := range h.packets {
-		if p != nil {
-			h.packets = h.packets[i:]
-			return
-		}
+	for h.numPackets > 0 && h.packets[h.slot(h.firstPacketNumber)] == nil {
+		h.firstPacketNumber++
+		h.numPackets--
 	}
-	h.packets = h.packets[:0]
 }
 
 func (h *sentPacketHistory) LowestPacketNumber() protocol.PacketNumber {
-	if len(h.packets) == 0 {
+	if h.numPackets == 0 {
 		return protocol.InvalidPacketNumber
 	}
-	return h.packets[0].PacketNumber
+	return h.firstPacketNumber
 }
 
 func (h *sentPacketHistory) DeclareLost(pn protocol.PacketNumber) {
-	idx, ok := h.getIndex(pn)
+	p, ok := h.get(pn)
 	if !ok {
 		return
 	}
-	p := h.packets[idx]
 	if p.outstanding() {
 		h.numOutstanding--
 		if h.numOutstanding < 0 {
 			panic("negative number of outstanding packets")
 		}
 	}
-	h.packets[idx] = nil
-	if idx == 0 {
+	h.packets[h.slot(pn)] = nil
+	if pn == h.firstPacketNumber {
 		h.cleanupStart()
 	}
 }
@@ -2,6 +2,7 @@ package ackhandler
 
 import (
 	"errors"
+	"testing"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -15,11 +16,12 @@ var _ = Describe("SentPacketHistory", func() {
 
 	expectInHistory := func(expected []protocol.PacketNumber) {
 		pns := make([]protocol.PacketNumber, 0, len(expected))
-		for _, p := range hist.packets {
-			if p != nil && !p.skippedPacket {
+		hist.Iterate(func(p *packet) (bool, error) {
+			if !p.skippedPacket {
 				pns = append(pns, p.PacketNumber)
 			}
-		}
+			return true, nil
+		})
 		if len(expected) == 0 {
 			Expect(pns).To(BeEmpty())
 			return
@@ -29,11 +31,12 @@ var _ = Describe("SentPacketHistory", func() {
 
 	expectSkippedInHistory := func(expected []protocol.PacketNumber) {
 		pns := make([]protocol.PacketNumber, 0, len(expected))
-		for _, p := range hist.packets {
-			if p != nil && p.skippedPacket {
+		hist.Iterate(func(p *packet) (bool, error) {
+			if p.skippedPacket {
 				pns = append(pns, p.PacketNumber)
 			}
-		}
+			return true, nil
+		})
 		if len(expected) == 0 {
 			Expect(pns).To(BeEmpty())
 			return
@@ -87,6 +90,19 @@ var _ = Describe("SentPacketHistory", func() {
 		Expect(hist.Len()).To(Equal(3))
 	})
 
+	It("estimates the memory usage", func() {
+		empty := hist.MemoryUsage()
+		Expect(empty).To(BeNumerically(">", 0))
+		hist.SentAckElicitingPacket(&packet{PacketNumber: 0})
+		withPacket := hist.MemoryUsage()
+		Expect(withPacket).To(BeNumerically(">", empty))
+		hist.SentAckElicitingPacket(&packet{PacketNumber: 1, Frames: make([]Frame, 0, 4)})
+		Expect(hist.MemoryUsage()).To(BeNumerically(">", 2*withPacket-empty))
+		Expect(hist.Remove(0)).To(Succeed())
+		Expect(hist.Remove(1)).To(Succeed())
+		Expect(hist.MemoryUsage()).To(Equal(empty))
+	})
+
 	Context("getting the first outstanding packet", func() {
 		It("gets nil, if there are no packets", func() {
 			Expect(hist.FirstOutstanding()).To(BeNil())
@@ -174,6 +190,60 @@ var _ = Describe("SentPacketHistory", func() {
 		Expect(hist.Remove(2)).To(MatchError("packet 2 not found in sent packet history"))
 	})
 
+	It("wraps around the ring buffer", func() {
+		for pn := protocol.PacketNumber(0); pn < 20; pn++ {
+			hist.SentAckElicitingPacket(&packet{PacketNumber: pn})
+		}
+		for pn := protocol.PacketNumber(0); pn < 15; pn++ {
+			Expect(hist.Remove(pn)).To(Succeed())
+		}
+		for pn := protocol.PacketNumber(20); pn < 40; pn++ {
+			hist.SentAckElicitingPacket(&packet{PacketNumber: pn})
+		}
+		Expect(hist.packets).To(HaveLen(sentPacketHistoryInitialSize))
+		Expect(hist.LowestPacketNumber()).To(Equal(protocol.PacketNumber(15)))
+		Expect(hist.Len()).To(Equal(25))
+		var pns []protocol.PacketNumber
+		for pn := protocol.PacketNumber(15); pn < 40; pn++ {
+			pns = append(pns, pn)
+		}
+		expectInHistory(pns)
+	})
+
+	It("grows the ring buffer", func() {
+		var pns []protocol.PacketNumber
+		for pn := protocol.PacketNumber(10); pn < 10+3*sentPacketHistoryInitialSize; pn++ {
+			if pn%7 == 0 {
+				hist.SentNonAckElicitingPacket(pn)
+				continue
+			}
+			hist.SentAckElicitingPacket(&packet{PacketNumber: pn})
+			pns = append(pns, pn)
+		}
+		Expect(hist.packets).To(HaveLen(4 * sentPacketHistoryInitialSize))
+		Expect(hist.Len()).To(Equal(3 * sentPacketHistoryInitialSize))
+		expectInHistory(pns)
+		Expect(hist.Remove(10)).To(Succeed())
+		Expect(hist.LowestPacketNumber()).To(Equal(protocol.PacketNumber(11)))
+	})
+
+	It("doesn't allocate when sending and removing packets", func() {
+		p := &packet{PacketNumber: 0}
+		hist.SentAckElicitingPacket(p)
+		Expect(hist.Remove(0)).To(Succeed())
+		pn := protocol.PacketNumber(1)
+		Expect(testing.AllocsPerRun(100, func() {
+			p.PacketNumber = pn
+			hist.SentAckElicitingPacket(p)
+			hist.SentNonAckElicitingPacket(pn + 1)
+			if err := hist.Remove(pn); err != nil {
+				panic(err)
+			}
+			pn += 2
+		})).To(BeZero())
+		Expect(hist.Len()).To(BeZero())
+	})
+
 	Context("iterating", func() {
 		BeforeEach(func() {
 			hist.SkippedPacket(0)
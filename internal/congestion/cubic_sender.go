@@ -1,7 +1,6 @@
 package congestion
 
 import (
-	"fmt"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -53,6 +52,10 @@ type cubicSender struct {
 	initialCongestionWindow    protocol.ByteCount
 	initialMaxCongestionWindow protocol.ByteCount
 
+	// The congestion window (in packets) used after persistent congestion.
+	// If 0, the minimum congestion window is used.
+	persistentCongestionWindowPackets uint32
+
 	maxDatagramSize protocol.ByteCount
 
 	lastState logging.CongestionState
@@ -70,9 +73,10 @@ func NewCubicSender(
 	rttStats *utils.RTTStats,
 	initialMaxDatagramSize protocol.ByteCount,
 	reno bool,
+	persistentCongestionWindowPackets uint32,
 	tracer *logging.ConnectionTracer,
 ) *cubicSender {
-	return newCubicSender(
+	c := newCubicSender(
 		clock,
 		rttStats,
 		reno,
@@ -81,6 +85,8 @@ func NewCubicSender(
 		protocol.MaxCongestionWindowPackets*initialMaxDatagramSize,
 		tracer,
 	)
+	c.persistentCongestionWindowPackets = persistentCongestionWindowPackets
+	return c
 }
 
 func newCubicSender(
@@ -279,10 +285,19 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.hybridSlowStart.Restart()
 	c.cubic.Reset()
 	c.slowStartThreshold = c.congestionWindow / 2
-	c.congestionWindow = c.minCongestionWindow()
+	c.congestionWindow = c.persistentCongestionWindow()
+}
+
+// persistentCongestionWindow is the congestion window used after persistent congestion.
+// It is never larger than the current congestion window.
+func (c *cubicSender) persistentCongestionWindow() protocol.ByteCount {
+	if c.persistentCongestionWindowPackets == 0 {
+		return c.minCongestionWindow()
+	}
+	return utils.Max(c.minCongestionWindow(), utils.Min(c.congestionWindow, protocol.ByteCount(c.persistentCongestionWindowPackets)*c.maxDatagramSize))
 }
 
-// OnConnectionMigration is called when the connection is migrated (?)
+// OnConnectionMigration is called when the connection is migrated to a path with a different IP address.
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
@@ -304,9 +319,7 @@ func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
 }
 
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
-	if s < c.maxDatagramSize {
-		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", c.maxDatagramSize, s))
-	}
+	// The max datagram size is decreased when a PMTU black hole is detected.
 	cwndIsMinCwnd := c.congestionWindow == c.minCongestionWindow()
 	c.maxDatagramSize = s
 	if cwndIsMinCwnd {
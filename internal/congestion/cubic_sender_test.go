@@ -307,6 +307,22 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.slowStartThreshold).To(Equal(5 * maxDatagramSize))
 	})
 
+	It("RTO congestion window, with a configured persistent congestion window", func() {
+		sender.persistentCongestionWindowPackets = 6
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(6 * maxDatagramSize))
+		Expect(sender.slowStartThreshold).To(Equal(5 * maxDatagramSize))
+		Expect(sender.InSlowStart()).To(BeFalse())
+	})
+
+	It("doesn't increase the congestion window to the persistent congestion window", func() {
+		sender.persistentCongestionWindowPackets = 100
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
+	})
+
 	It("RTO congestion window no retransmission", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(defaultWindowTCP))
 
@@ -469,8 +485,11 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(initialMaxCongestionWindow))
 	})
 
-	It("doesn't allow reductions of the maximum packet size", func() {
-		Expect(func() { sender.SetMaxDatagramSize(initialMaxDatagramSize - 1) }).To(Panic())
+	It("allows reductions of the maximum packet size", func() {
+		sender.OnRetransmissionTimeout(true)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * initialMaxDatagramSize))
+		sender.SetMaxDatagramSize(initialMaxDatagramSize - 100)
+		Expect(sender.GetCongestionWindow()).To(Equal(2 * (initialMaxDatagramSize - 100)))
 	})
 
 	It("slow starts up to maximum congestion window, if larger packets are sent", func() {
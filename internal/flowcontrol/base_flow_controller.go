@@ -89,10 +89,25 @@ func (c *baseFlowController) getWindowUpdate() protocol.ByteCount {
 		return 0
 	}
 
-	// 计算是否需要调整窗口大小
-	c.maybeAdjustWindowSize()
+	windowSize := c.receiveWindowSize
+	// When the memory budget is running low, don't increase the window size,
+	// and only grant a fraction of the window to the peer.
+	switch utils.GlobalMemoryBudget.Pressure() {
+	case utils.MemoryPressureNone:
+		// 计算是否需要调整窗口大小
+		c.maybeAdjustWindowSize()
+		windowSize = c.receiveWindowSize
+	case utils.MemoryPressureHigh:
+		windowSize /= 2
+	case utils.MemoryPressureExceeded:
+		windowSize /= 4
+	}
+	newWindow := c.bytesRead + windowSize
+	if newWindow <= c.receiveWindow {
+		return 0
+	}
 	// receiveWindow 前移
-	c.receiveWindow = c.bytesRead + c.receiveWindowSize
+	c.receiveWindow = newWindow
 	return c.receiveWindow
 }
 
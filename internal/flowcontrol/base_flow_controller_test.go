@@ -126,6 +126,56 @@ var _ = Describe("Base Flow controller", func() {
 			Expect(offset).To(BeZero())
 		})
 
+		Context("memory pressure", func() {
+			AfterEach(func() {
+				utils.GlobalMemoryBudget.SetLimit(0)
+				utils.GlobalMemoryBudget.Release(int(utils.GlobalMemoryBudget.Used()))
+			})
+
+			It("grants half the window when the memory budget is almost used up", func() {
+				utils.GlobalMemoryBudget.SetLimit(1000)
+				utils.GlobalMemoryBudget.Acquire(800)
+				readPosition := receiveWindow - receiveWindowSize/3
+				controller.bytesRead = readPosition
+				offset := controller.getWindowUpdate()
+				Expect(offset).To(Equal(readPosition + receiveWindowSize/2))
+				Expect(controller.receiveWindow).To(Equal(offset))
+				Expect(controller.receiveWindowSize).To(Equal(receiveWindowSize))
+			})
+
+			It("grants a quarter of the window when the memory budget is used up", func() {
+				utils.GlobalMemoryBudget.SetLimit(1000)
+				utils.GlobalMemoryBudget.Acquire(1000)
+				readPosition := receiveWindow - receiveWindowSize/8
+				controller.bytesRead = readPosition
+				offset := controller.getWindowUpdate()
+				Expect(offset).To(Equal(readPosition + receiveWindowSize/4))
+				Expect(controller.receiveWindow).To(Equal(offset))
+			})
+
+			It("doesn't decrease the window", func() {
+				utils.GlobalMemoryBudget.SetLimit(1000)
+				utils.GlobalMemoryBudget.Acquire(1000)
+				readPosition := receiveWindow - receiveWindowSize/2
+				controller.bytesRead = readPosition
+				Expect(controller.getWindowUpdate()).To(BeZero())
+				Expect(controller.receiveWindow).To(Equal(receiveWindow))
+			})
+
+			It("doesn't increase the window size", func() {
+				utils.GlobalMemoryBudget.SetLimit(1000)
+				utils.GlobalMemoryBudget.Acquire(800)
+				controller.maxReceiveWindowSize = 5000
+				rtt := scaleDuration(50 * time.Millisecond)
+				controller.rttStats.UpdateRTT(rtt, 0, time.Now())
+				controller.epochStartOffset = controller.bytesRead
+				controller.epochStartTime = time.Now().Add(-rtt)
+				controller.addBytesRead(receiveWindowSize*2/3 + 1)
+				Expect(controller.getWindowUpdate()).ToNot(BeZero())
+				Expect(controller.receiveWindowSize).To(Equal(receiveWindowSize))
+			})
+		})
+
 		Context("receive window size auto-tuning", func() {
 			var oldWindowSize protocol.ByteCount
 
@@ -85,7 +85,7 @@ func (c *connectionFlowController) GetWindowUpdate() protocol.ByteCount {
 // it should make sure that the connection-level window is increased when a stream-level window grows
 func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCount) {
 	c.mutex.Lock()
-	if inc > c.receiveWindowSize {
+	if inc > c.receiveWindowSize && utils.GlobalMemoryBudget.Pressure() == utils.MemoryPressureNone {
 		c.logger.Debugf("Increasing receive flow control window for the connection to %d kB, in response to stream flow control window increase", c.receiveWindowSize/(1<<10))
 		newSize := utils.Min(inc, c.maxReceiveWindowSize)
 		if delta := newSize - c.receiveWindowSize; delta > 0 && c.allowWindowIncrease(delta) {
@@ -158,6 +158,17 @@ var _ = Describe("Connection Flow controller", func() {
 			controller.EnsureMinimumWindowSize(1912)
 			Expect(controller.epochStartTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
 		})
+
+		It("doesn't increase the window size when the memory budget is almost used up", func() {
+			utils.GlobalMemoryBudget.SetLimit(1000)
+			utils.GlobalMemoryBudget.Acquire(900)
+			defer func() {
+				utils.GlobalMemoryBudget.SetLimit(0)
+				utils.GlobalMemoryBudget.Release(900)
+			}()
+			controller.EnsureMinimumWindowSize(1800)
+			Expect(controller.receiveWindowSize).To(Equal(oldWindowSize))
+		})
 	})
 
 	Context("resetting", func() {
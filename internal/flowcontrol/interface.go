@@ -16,6 +16,9 @@ type flowController interface {
 // A StreamFlowController is a flow controller for a QUIC stream.
 type StreamFlowController interface {
 	flowController
+	// for sending
+	// StreamSendWindowSize returns the send window of the stream, not taking connection-level flow control into account
+	StreamSendWindowSize() protocol.ByteCount
 	// for receiving
 	// UpdateHighestReceived should be called when a new highest offset is received
 	// final has to be to true if this is the final offset of the stream,
@@ -130,6 +130,10 @@ func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 	return utils.Min(c.baseFlowController.sendWindowSize(), c.connection.SendWindowSize())
 }
 
+func (c *streamFlowController) StreamSendWindowSize() protocol.ByteCount {
+	return c.baseFlowController.sendWindowSize()
+}
+
 func (c *streamFlowController) shouldQueueWindowUpdate() bool {
 	return !c.receivedFinalOffset && c.hasWindowUpdate()
 }
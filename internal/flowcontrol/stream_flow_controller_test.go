@@ -260,6 +260,13 @@ var _ = Describe("Stream Flow controller", func() {
 			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(2)))
 		})
 
+		It("returns the stream's send window, ignoring the connection-level window", func() {
+			controller.connection.UpdateSendWindow(12)
+			controller.UpdateSendWindow(20)
+			controller.AddBytesSent(10)
+			Expect(controller.StreamSendWindowSize()).To(Equal(protocol.ByteCount(10)))
+		})
+
 		It("doesn't say that it's blocked, if only the connection is blocked", func() {
 			controller.connection.UpdateSendWindow(50)
 			controller.UpdateSendWindow(100)
@@ -19,6 +19,8 @@ type cipherSuite struct {
 	Hash   crypto.Hash
 	KeyLen int
 	AEAD   func(key, nonceMask []byte) cipher.AEAD
+
+	nullEncryption bool // see nullCipherSuite
 }
 
 func (s cipherSuite) IVLen() int { return aeadNonceLength }
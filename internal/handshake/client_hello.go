@@ -0,0 +1,93 @@
+package handshake
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+const (
+	typeClientHello = 1
+
+	extensionServerName = 0
+	extensionALPN       = 16
+)
+
+// ClientHelloSummary contains the information that can be read from a ClientHello
+// without performing the TLS handshake.
+type ClientHelloSummary struct {
+	ServerName string
+	ALPN       []string
+}
+
+// ParseClientHello parses the server name (SNI) and the application protocols (ALPN) from a ClientHello message.
+// The message may be truncated, e.g. if the ClientHello spans multiple Initial packets.
+// In that case, only the extensions contained in data are parsed.
+func ParseClientHello(data []byte) (*ClientHelloSummary, error) {
+	s := cryptobyte.String(data)
+	var msgType uint8
+	var msgLen uint32
+	if !s.ReadUint8(&msgType) || !s.ReadUint24(&msgLen) {
+		return nil, errors.New("ClientHello too short")
+	}
+	if msgType != typeClientHello {
+		return nil, errors.New("not a ClientHello")
+	}
+	if uint32(len(s)) > msgLen {
+		s = s[:msgLen]
+	}
+	var sessionID, cipherSuites, compressionMethods cryptobyte.String
+	if !s.Skip(2+32) || // legacy_version and random
+		!s.ReadUint8LengthPrefixed(&sessionID) ||
+		!s.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!s.ReadUint8LengthPrefixed(&compressionMethods) {
+		return nil, errors.New("ClientHello too short")
+	}
+	var extensionsLen uint16
+	if !s.ReadUint16(&extensionsLen) {
+		return nil, errors.New("ClientHello too short")
+	}
+	if int(extensionsLen) < len(s) {
+		s = s[:extensionsLen]
+	}
+
+	summary := &ClientHelloSummary{}
+	for !s.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !s.ReadUint16(&extType) || !s.ReadUint16LengthPrefixed(&extData) {
+			// the ClientHello was truncated
+			break
+		}
+		switch extType {
+		case extensionServerName:
+			var nameList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&nameList) {
+				return nil, errors.New("invalid server_name extension")
+			}
+			for !nameList.Empty() {
+				var nameType uint8
+				var name cryptobyte.String
+				if !nameList.ReadUint8(&nameType) || !nameList.ReadUint16LengthPrefixed(&name) {
+					return nil, errors.New("invalid server_name extension")
+				}
+				if nameType == 0 { // host_name
+					summary.ServerName = string(name)
+				}
+			}
+		case extensionALPN:
+			var protoList cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&protoList) {
+				return nil, errors.New("invalid application_layer_protocol_negotiation extension")
+			}
+			for !protoList.Empty() {
+				var proto cryptobyte.String
+				if !protoList.ReadUint8LengthPrefixed(&proto) || proto.Empty() {
+					return nil, errors.New("invalid application_layer_protocol_negotiation extension")
+				}
+				summary.ALPN = append(summary.ALPN, string(proto))
+			}
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,64 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ClientHello parsing", func() {
+	// getClientHello returns the ClientHello message (without the TLS record header) generated by crypto/tls
+	getClientHello := func(conf *tls.Config) []byte {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+		go tls.Client(c1, conf).Handshake()
+		hdr := make([]byte, 5)
+		_, err := io.ReadFull(c2, hdr)
+		Expect(err).ToNot(HaveOccurred())
+		msg := make([]byte, int(hdr[3])<<8|int(hdr[4]))
+		_, err = io.ReadFull(c2, msg)
+		Expect(err).ToNot(HaveOccurred())
+		return msg
+	}
+
+	It("parses the server name and the application protocols", func() {
+		ch := getClientHello(&tls.Config{ServerName: "quic-go.net", NextProtos: []string{"h3", "hq-interop"}})
+		summary, err := ParseClientHello(ch)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summary.ServerName).To(Equal("quic-go.net"))
+		Expect(summary.ALPN).To(Equal([]string{"h3", "hq-interop"}))
+	})
+
+	It("parses a ClientHello without SNI and ALPN", func() {
+		ch := getClientHello(&tls.Config{InsecureSkipVerify: true})
+		summary, err := ParseClientHello(ch)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(summary.ServerName).To(BeEmpty())
+		Expect(summary.ALPN).To(BeEmpty())
+	})
+
+	It("parses truncated ClientHellos", func() {
+		ch := getClientHello(&tls.Config{ServerName: "quic-go.net", NextProtos: []string{"h3"}})
+		for i := 4 + 2 + 32 + 1; i < len(ch); i++ {
+			summary, err := ParseClientHello(ch[:i])
+			if err != nil {
+				Expect(err).To(MatchError("ClientHello too short"))
+				continue
+			}
+			if summary.ServerName != "" {
+				Expect(summary.ServerName).To(Equal("quic-go.net"))
+			}
+		}
+	})
+
+	It("rejects other handshake messages", func() {
+		ch := getClientHello(&tls.Config{ServerName: "quic-go.net"})
+		ch[0] = 2 // ServerHello
+		_, err := ParseClientHello(ch)
+		Expect(err).To(MatchError("not a ClientHello"))
+	})
+})
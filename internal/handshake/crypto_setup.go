@@ -48,6 +48,19 @@ type cryptoSetup struct {
 
 	perspective protocol.Perspective
 
+	// Only set if handshake messages are handled on a WorkerPool, see UseWorkerPool.
+	workers        *WorkerPool
+	onWorkerResult func()
+	queuedMessages []workerMessage // messages waiting to be handled by the next job
+	// inWorker is set while a job is running on the WorkerPool.
+	// It is only accessed by the job, and by the crypto/tls callbacks called during the job.
+	inWorker    bool
+	workerRTT   time.Duration // the initial RTT restored from a session ticket during a job
+	workerMutex sync.Mutex
+	jobRunning  bool
+	closed      bool          // Close was called while a job was running
+	result      *workerResult // the result of the last job, waiting to be processed by HandleWorkerResults
+
 	mutex sync.Mutex // protects all members below
 
 	handshakeCompleteTime time.Time
@@ -66,6 +79,10 @@ type cryptoSetup struct {
 	aead          *updatableAEAD
 	has1RTTSealer bool
 	has1RTTOpener bool
+
+	// nullEncryption is decided when the first 1-RTT key is installed, see use1RTTNullEncryption
+	nullEncryptionDecided bool
+	nullEncryption        bool
 }
 
 var _ CryptoSetup = &cryptoSetup{}
@@ -170,6 +187,9 @@ func newCryptoSetup(
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) *cryptoSetup {
+	if nullEncryptionEnabled {
+		tp.NullEncryption = true
+	}
 	initialSealer, initialOpener := NewInitialAEAD(connID, perspective, version)
 	if tracer != nil && tracer.UpdatedKeyFromTLS != nil {
 		tracer.UpdatedKeyFromTLS(protocol.EncryptionInitial, protocol.PerspectiveClient)
@@ -199,6 +219,10 @@ func (h *cryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
 	}
 }
 
+func (h *cryptoSetup) SetKeyUpdateInterval(packets, bytes uint64) {
+	h.aead.SetKeyUpdateInterval(packets, bytes)
+}
+
 func (h *cryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) error {
 	return h.aead.SetLargestAcked(pn)
 }
@@ -231,13 +255,27 @@ func (h *cryptoSetup) StartHandshake() error {
 
 // Close closes the crypto setup.
 // It aborts the handshake, if it is still running.
+// If a job is running on the WorkerPool, the handshake is aborted once the job finishes.
 func (h *cryptoSetup) Close() error {
+	h.workerMutex.Lock()
+	if h.jobRunning {
+		h.closed = true
+		h.workerMutex.Unlock()
+		return nil
+	}
+	h.workerMutex.Unlock()
 	return h.conn.Close()
 }
 
 // HandleMessage handles a TLS handshake message.
 // It is called by the crypto streams when a new message is available.
+// If a WorkerPool is used, Initial and Handshake messages are handled asynchronously.
 func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) error {
+	if h.workers != nil && encLevel != protocol.Encryption1RTT {
+		h.queuedMessages = append(h.queuedMessages, workerMessage{data: data, encLevel: encLevel})
+		h.maybeStartJob()
+		return nil
+	}
 	if err := h.handleMessage(data, encLevel); err != nil {
 		return wrapError(err)
 	}
@@ -338,7 +376,7 @@ func (h *cryptoSetup) handleDataFromSessionStateImpl(data []byte) (*wire.Transpo
 	if err != nil {
 		return nil, err
 	}
-	h.rttStats.SetInitialRTT(time.Duration(rtt) * time.Microsecond)
+	h.setInitialRTT(time.Duration(rtt) * time.Microsecond)
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return nil, err
@@ -390,7 +428,7 @@ func (h *cryptoSetup) handleSessionTicket(sessionTicketData []byte, using0RTT bo
 		h.logger.Debugf("Unmarshalling session ticket failed: %s", err.Error())
 		return false
 	}
-	h.rttStats.SetInitialRTT(t.RTT)
+	h.setInitialRTT(t.RTT)
 	if !using0RTT {
 		return false
 	}
@@ -447,10 +485,13 @@ func (h *cryptoSetup) SetReadKey(el qtls.QUICEncryptionLevel, suiteID uint16, tr
 			h.logger.Debugf("Installed Handshake Read keys (using %s)", tls.CipherSuiteName(suite.ID))
 		}
 	case qtls.QUICEncryptionLevelApplication:
+		if h.use1RTTNullEncryption() {
+			suite = nullCipherSuite(suite)
+		}
 		h.aead.SetReadKey(suite, trafficSecret)
 		h.has1RTTOpener = true
 		if h.logger.Debug() {
-			h.logger.Debugf("Installed 1-RTT Read keys (using %s)", tls.CipherSuiteName(suite.ID))
+			h.logger.Debugf("Installed 1-RTT Read keys (using %s)", cipherSuiteName(suite))
 		}
 	default:
 		panic("unexpected read encryption level")
@@ -493,10 +534,13 @@ func (h *cryptoSetup) SetWriteKey(el qtls.QUICEncryptionLevel, suiteID uint16, t
 			h.logger.Debugf("Installed Handshake Write keys (using %s)", tls.CipherSuiteName(suite.ID))
 		}
 	case qtls.QUICEncryptionLevelApplication:
+		if h.use1RTTNullEncryption() {
+			suite = nullCipherSuite(suite)
+		}
 		h.aead.SetWriteKey(suite, trafficSecret)
 		h.has1RTTSealer = true
 		if h.logger.Debug() {
-			h.logger.Debugf("Installed 1-RTT Write keys (using %s)", tls.CipherSuiteName(suite.ID))
+			h.logger.Debugf("Installed 1-RTT Write keys (using %s)", cipherSuiteName(suite))
 		}
 		if h.zeroRTTSealer != nil {
 			// Once we receive handshake keys, we know that 0-RTT was not rejected.
@@ -516,6 +560,25 @@ func (h *cryptoSetup) SetWriteKey(el qtls.QUICEncryptionLevel, suiteID uint16, t
 	}
 }
 
+// use1RTTNullEncryption says if 1-RTT packets are protected using null encryption.
+// This is only the case if both peers support null encryption, and if a test ALPN was negotiated.
+func (h *cryptoSetup) use1RTTNullEncryption() bool {
+	if h.nullEncryptionDecided {
+		return h.nullEncryption
+	}
+	h.nullEncryptionDecided = true
+	if !nullEncryptionEnabled || !h.ourParams.NullEncryption || h.peerParams == nil || !h.peerParams.NullEncryption {
+		return false
+	}
+	if alpn := h.conn.ConnectionState().NegotiatedProtocol; !isTestALPN(alpn) {
+		h.logger.Infof("Not using null encryption with ALPN %q. Null encryption is only supported for test ALPNs.", alpn)
+		return false
+	}
+	h.logger.Infof("Using null encryption. 1-RTT packets are NOT protected.")
+	h.nullEncryption = true
+	return true
+}
+
 // WriteRecord is called when TLS writes data
 func (h *cryptoSetup) WriteRecord(encLevel qtls.QUICEncryptionLevel, p []byte) {
 	//nolint:exhaustive // handshake records can only be written for Initial and Handshake.
@@ -603,6 +666,12 @@ func (h *cryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
 	if !h.has1RTTSealer {
 		return nil, ErrKeysNotYetAvailable
 	}
+	if h.aead.confidentialityLimitReached() {
+		return nil, &qerr.TransportError{
+			ErrorCode:    qerr.AEADLimitReached,
+			ErrorMessage: "confidentiality limit reached",
+		}
+	}
 	return h.aead, nil
 }
 
@@ -664,8 +733,10 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return ConnectionState{
-		ConnectionState: h.conn.ConnectionState(),
-		Used0RTT:        h.used0RTT.Load(),
+		ConnectionState:  h.conn.ConnectionState(),
+		Used0RTT:         h.used0RTT.Load(),
+		LocalKeyUpdates:  h.aead.numLocalKeyUpdates.Load(),
+		RemoteKeyUpdates: h.aead.numRemoteKeyUpdates.Load(),
 	}
 }
 
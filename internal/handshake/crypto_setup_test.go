@@ -24,10 +24,7 @@ import (
 	"go.uber.org/mock/gomock"
 )
 
-const (
-	typeClientHello      = 1
-	typeNewSessionTicket = 4
-)
+const typeNewSessionTicket = 4
 
 var _ = Describe("Crypto Setup TLS", func() {
 	generateCert := func() tls.Certificate {
@@ -374,6 +371,109 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(serverReceivedTransportParameters.MaxIdleTimeout).To(Equal(42 * time.Second))
 		})
 
+		Context("null encryption", func() {
+			// seal1RTT seals a packet using the client's 1-RTT sealer, and checks that the server can open it
+			seal1RTT := func(client, server CryptoSetup, plaintext []byte) []byte {
+				sealer, err := client.Get1RTTSealer()
+				Expect(err).ToNot(HaveOccurred())
+				sealed := sealer.Seal(nil, plaintext, 42, []byte("header"))
+				opener, err := server.Get1RTTOpener()
+				Expect(err).ToNot(HaveOccurred())
+				opened, err := opener.Open(nil, sealed, time.Now(), 42, protocol.KeyPhaseZero, []byte("header"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(opened).To(Equal(plaintext))
+				return sealed
+			}
+
+			It("advertises support for null encryption, depending on the build tag", func() {
+				cTransportParameters := &wire.TransportParameters{ActiveConnectionIDLimit: 2}
+				sTransportParameters := &wire.TransportParameters{ActiveConnectionIDLimit: 2}
+				_, _, clientErr, _, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					cTransportParameters, sTransportParameters,
+					false,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				Expect(cTransportParameters.NullEncryption).To(Equal(nullEncryptionEnabled))
+				Expect(sTransportParameters.NullEncryption).To(Equal(nullEncryptionEnabled))
+			})
+
+			It("uses null encryption for 1-RTT packets when using a test ALPN", func() {
+				if !nullEncryptionEnabled {
+					Skip("requires the quic_nullcrypto build tag")
+				}
+				clientConf.NextProtos = []string{"quic-go-test"}
+				serverConf.NextProtos = []string{"quic-go-test"}
+				client, _, clientErr, server, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{ActiveConnectionIDLimit: 2}, &wire.TransportParameters{ActiveConnectionIDLimit: 2},
+					false,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				sealed := seal1RTT(client, server, []byte("foobar"))
+				Expect(sealed).To(Equal(append([]byte("foobar"), make([]byte, 16)...)))
+			})
+
+			It("doesn't use null encryption for other ALPNs", func() {
+				client, _, clientErr, server, _, serverErr := handshakeWithTLSConf(
+					clientConf, serverConf,
+					&utils.RTTStats{}, &utils.RTTStats{},
+					&wire.TransportParameters{ActiveConnectionIDLimit: 2}, &wire.TransportParameters{ActiveConnectionIDLimit: 2},
+					false,
+				)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				sealed := seal1RTT(client, server, []byte("foobar"))
+				Expect(sealed).To(HaveLen(6 + 16))
+				Expect(sealed[:6]).ToNot(Equal([]byte("foobar")))
+			})
+
+			It("doesn't use null encryption if the peer doesn't support it", func() {
+				if !nullEncryptionEnabled {
+					Skip("requires the quic_nullcrypto build tag")
+				}
+				clientConf.NextProtos = []string{"quic-go-test"}
+				serverConf.NextProtos = []string{"quic-go-test"}
+				// Remove the null_encryption transport parameter from the client's transport parameters,
+				// simulating a client that was built without the quic_nullcrypto build tag.
+				client := NewCryptoSetupClient(
+					protocol.ConnectionID{},
+					&wire.TransportParameters{ActiveConnectionIDLimit: 2},
+					clientConf,
+					false,
+					&utils.RTTStats{},
+					nil,
+					utils.DefaultLogger.WithPrefix("client"),
+					protocol.Version1,
+				)
+				cs := client.(*cryptoSetup)
+				cs.ourParams.NullEncryption = false
+				cs.conn.SetTransportParameters(cs.ourParams.Marshal(protocol.PerspectiveClient))
+				var token protocol.StatelessResetToken
+				server := NewCryptoSetupServer(
+					protocol.ConnectionID{},
+					&net.UDPAddr{IP: net.IPv6loopback, Port: 1234},
+					&net.UDPAddr{IP: net.IPv6loopback, Port: 4321},
+					&wire.TransportParameters{ActiveConnectionIDLimit: 2, StatelessResetToken: &token},
+					serverConf,
+					false,
+					&utils.RTTStats{},
+					nil,
+					utils.DefaultLogger.WithPrefix("server"),
+					protocol.Version1,
+				)
+				_, clientErr, _, serverErr := handshake(client, server)
+				Expect(clientErr).ToNot(HaveOccurred())
+				Expect(serverErr).ToNot(HaveOccurred())
+				sealed := seal1RTT(client, server, []byte("foobar"))
+				Expect(sealed[:6]).ToNot(Equal([]byte("foobar")))
+			})
+		})
+
 		Context("with session tickets", func() {
 			It("errors when the NewSessionTicket is sent at the wrong encryption level", func() {
 				client, _, clientErr, _, _, serverErr := handshakeWithTLSConf(
@@ -25,6 +25,9 @@ func hkdfHeaderProtectionLabel(v protocol.VersionNumber) string {
 }
 
 func newHeaderProtector(suite *cipherSuite, trafficSecret []byte, isLongHeader bool, v protocol.VersionNumber) headerProtector {
+	if suite.nullEncryption {
+		return nullHeaderProtector{}
+	}
 	hkdfLabel := hkdfHeaderProtectionLabel(v)
 	switch suite.ID {
 	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384:
@@ -20,6 +20,10 @@ var (
 	ErrKeysDropped = errors.New("CryptoSetup: keys were already dropped")
 	// ErrDecryptionFailed is returned when the AEAD fails to open the packet.
 	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrKeyPhaseMismatch is returned by a ShortHeaderOpenerWorker when the packet's key phase
+	// doesn't match the current key phase. The packet was not modified,
+	// and needs to be opened by the ShortHeaderOpener.
+	ErrKeyPhaseMismatch = errors.New("key phase mismatch")
 )
 
 type headerDecryptor interface {
@@ -40,6 +44,25 @@ type ShortHeaderOpener interface {
 	Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte) ([]byte, error)
 }
 
+// ConcurrentShortHeaderOpener allows opening short header packets on multiple goroutines.
+type ConcurrentShortHeaderOpener interface {
+	// NewWorker creates a new worker.
+	// Different workers can be used concurrently, but a single worker must only be used by one goroutine at a time.
+	NewWorker() ShortHeaderOpenerWorker
+	// FinishOpen must be called on the connection's goroutine for every packet opened by a worker
+	// (including packets that failed to decrypt), in the order the packets were received.
+	// It returns an error if the packet must not be processed.
+	FinishOpen(rcvTime time.Time, pn protocol.PacketNumber, keyPhase protocol.KeyPhase, openErr error) error
+}
+
+// ShortHeaderOpenerWorker opens short header packets protected with the current key phase.
+// Key updates are handled by the ShortHeaderOpener.
+type ShortHeaderOpenerWorker interface {
+	headerDecryptor
+	DecodePacketNumber(wirePN protocol.PacketNumber, wirePNLen protocol.PacketNumberLen) protocol.PacketNumber
+	Open(dst, src []byte, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, associatedData []byte) ([]byte, protocol.KeyPhase, error)
+}
+
 // LongHeaderSealer seals a long header packet
 type LongHeaderSealer interface {
 	Seal(dst, src []byte, packetNumber protocol.PacketNumber, associatedData []byte) []byte
@@ -56,6 +79,9 @@ type ShortHeaderSealer interface {
 type ConnectionState struct {
 	tls.ConnectionState
 	Used0RTT bool
+	// The number of 1-RTT key updates initiated by us and by the peer.
+	LocalKeyUpdates  uint64
+	RemoteKeyUpdates uint64
 }
 
 // EventKind is the kind of handshake event.
@@ -98,7 +124,10 @@ type CryptoSetup interface {
 
 	HandleMessage([]byte, protocol.EncryptionLevel) error
 	NextEvent() Event
+	UseWorkerPool(*WorkerPool, func())
+	HandleWorkerResults() error
 
+	SetKeyUpdateInterval(packets, bytes uint64)
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	DiscardInitialKeys()
 	SetHandshakeConfirmed()
@@ -0,0 +1,84 @@
+package handshake
+
+import (
+	"crypto/cipher"
+	"crypto/tls"
+	"strings"
+)
+
+// Null encryption is a debugging aid: 1-RTT packets are sent in the clear,
+// so that CPU profiles and packet captures aren't dominated (or obscured) by packet protection.
+// It is only available when building with the quic_nullcrypto build tag,
+// and it is only used if both peers support it, and if they negotiated one of the test ALPNs.
+// Packets sent during the handshake are always protected.
+
+// testALPNPrefixes are the ALPNs null encryption may be used with.
+// This list must never contain an ALPN used in production.
+var testALPNPrefixes = []string{
+	"quic-go-test",
+	"quic-go integration tests", // the ALPN used by quic-go's integration tests
+}
+
+func isTestALPN(alpn string) bool {
+	for _, prefix := range testALPNPrefixes {
+		if strings.HasPrefix(alpn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// nullCipherSuite returns a copy of the cipher suite that uses a null AEAD and no header protection.
+// The keys are still derived (and updated) using the hash of the original cipher suite.
+func nullCipherSuite(suite *cipherSuite) *cipherSuite {
+	return &cipherSuite{
+		ID:             suite.ID,
+		Hash:           suite.Hash,
+		KeyLen:         suite.KeyLen,
+		AEAD:           func([]byte, []byte) cipher.AEAD { return &nullAEAD{} },
+		nullEncryption: true,
+	}
+}
+
+func cipherSuiteName(suite *cipherSuite) string {
+	if suite.nullEncryption {
+		return "null encryption"
+	}
+	return tls.CipherSuiteName(suite.ID)
+}
+
+// nullAEAD doesn't encrypt anything.
+// It appends an all-zero tag, such that packets have the same size as when using a real AEAD,
+// and such that packets protected with a real AEAD are rejected.
+type nullAEAD struct{}
+
+var _ cipher.AEAD = &nullAEAD{}
+
+func (nullAEAD) NonceSize() int { return 8 }
+func (nullAEAD) Overhead() int  { return 16 }
+
+func (a nullAEAD) Seal(out, _, plaintext, _ []byte) []byte {
+	out = append(out, plaintext...)
+	var tag [16]byte
+	return append(out, tag[:]...)
+}
+
+func (a nullAEAD) Open(out, _, ciphertext, _ []byte) ([]byte, error) {
+	if len(ciphertext) < a.Overhead() {
+		return nil, ErrDecryptionFailed
+	}
+	tagStart := len(ciphertext) - a.Overhead()
+	for _, b := range ciphertext[tagStart:] {
+		if b != 0 {
+			return nil, ErrDecryptionFailed
+		}
+	}
+	return append(out, ciphertext[:tagStart]...), nil
+}
+
+type nullHeaderProtector struct{}
+
+var _ headerProtector = nullHeaderProtector{}
+
+func (nullHeaderProtector) EncryptHeader([]byte, *byte, []byte) {}
+func (nullHeaderProtector) DecryptHeader([]byte, *byte, []byte) {}
@@ -0,0 +1,60 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Null Encryption", func() {
+	It("only allows test ALPNs", func() {
+		Expect(isTestALPN("quic-go-test")).To(BeTrue())
+		Expect(isTestALPN("quic-go-test-foobar")).To(BeTrue())
+		Expect(isTestALPN("quic-go integration tests")).To(BeTrue())
+		Expect(isTestALPN("")).To(BeFalse())
+		Expect(isTestALPN("h3")).To(BeFalse())
+		Expect(isTestALPN("quic-go")).To(BeFalse())
+	})
+
+	It("seals and opens", func() {
+		aead := &nullAEAD{}
+		sealed := aead.Seal(nil, make([]byte, aead.NonceSize()), []byte("foobar"), []byte("aad"))
+		Expect(sealed).To(HaveLen(6 + aead.Overhead()))
+		Expect(sealed[:6]).To(Equal([]byte("foobar")))
+		opened, err := aead.Open(nil, make([]byte, aead.NonceSize()), sealed, []byte("aad"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+	})
+
+	It("seals and opens in place", func() {
+		aead := &nullAEAD{}
+		b := make([]byte, 6, 6+aead.Overhead())
+		copy(b, "foobar")
+		sealed := aead.Seal(b[:0], nil, b, nil)
+		Expect(sealed).To(Equal(append([]byte("foobar"), make([]byte, 16)...)))
+		opened, err := aead.Open(sealed[:0], nil, sealed, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+	})
+
+	It("rejects packets that weren't sealed with null encryption", func() {
+		aead := &nullAEAD{}
+		sealed := aead.Seal(nil, nil, []byte("foobar"), nil)
+		sealed[len(sealed)-1] ^= 0x1
+		_, err := aead.Open(nil, nil, sealed, nil)
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+		_, err = aead.Open(nil, nil, make([]byte, 15), nil)
+		Expect(err).To(MatchError(ErrDecryptionFailed))
+	})
+
+	It("doesn't apply header protection", func() {
+		suite := nullCipherSuite(getCipherSuite(tls.TLS_AES_128_GCM_SHA256))
+		hp := newHeaderProtector(suite, make([]byte, 32), false, 0)
+		firstByte := byte(0x42)
+		hdr := []byte{1, 2, 3, 4}
+		hp.EncryptHeader(make([]byte, 16), &firstByte, hdr)
+		Expect(firstByte).To(Equal(byte(0x42)))
+		Expect(hdr).To(Equal([]byte{1, 2, 3, 4}))
+	})
+})
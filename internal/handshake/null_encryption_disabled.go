@@ -0,0 +1,5 @@
+//go:build !quic_nullcrypto
+
+package handshake
+
+const nullEncryptionEnabled = false
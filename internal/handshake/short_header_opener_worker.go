@@ -0,0 +1,59 @@
+package handshake
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// The shortHeaderOpenerWorker opens packets on a worker goroutine.
+// It uses its own AEAD and header protector, since these are not safe for concurrent use.
+// The key phase and the highest received packet number are shared with the updatableAEAD.
+type shortHeaderOpenerWorker struct {
+	suite   *cipherSuite
+	version protocol.VersionNumber
+
+	headerDecrypter headerProtector
+
+	rcvKeys       *atomic.Pointer[rcvKeyState]
+	highestRcvdPN *atomic.Int64
+
+	// the AEAD for keyPhase, nil until the first packet is opened
+	aead     cipher.AEAD
+	keyPhase protocol.KeyPhase
+
+	// use a single slice to avoid allocations
+	nonceBuf []byte
+}
+
+var _ ShortHeaderOpenerWorker = &shortHeaderOpenerWorker{}
+
+func (w *shortHeaderOpenerWorker) DecryptHeader(sample []byte, firstByte *byte, hdrBytes []byte) {
+	w.headerDecrypter.DecryptHeader(sample, firstByte, hdrBytes)
+}
+
+func (w *shortHeaderOpenerWorker) DecodePacketNumber(wirePN protocol.PacketNumber, wirePNLen protocol.PacketNumberLen) protocol.PacketNumber {
+	return protocol.DecodePacketNumber(wirePNLen, protocol.PacketNumber(w.highestRcvdPN.Load()), wirePN)
+}
+
+// Open opens a packet protected with the current key phase.
+// It returns the key phase that was used.
+func (w *shortHeaderOpenerWorker) Open(dst, src []byte, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, protocol.KeyPhase, error) {
+	keys := w.rcvKeys.Load()
+	if kp != keys.keyPhase.Bit() {
+		return nil, 0, ErrKeyPhaseMismatch
+	}
+	if w.aead == nil || w.keyPhase != keys.keyPhase {
+		w.aead = createAEAD(w.suite, keys.trafficSecret, w.version)
+		w.keyPhase = keys.keyPhase
+		w.nonceBuf = make([]byte, w.aead.NonceSize())
+	}
+	binary.BigEndian.PutUint64(w.nonceBuf[len(w.nonceBuf)-8:], uint64(pn))
+	dec, err := w.aead.Open(dst, w.nonceBuf, src, ad)
+	if err != nil {
+		return nil, w.keyPhase, ErrDecryptionFailed
+	}
+	return dec, w.keyPhase, nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/tls"
 	"encoding/binary"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -32,6 +33,14 @@ type updatableAEAD struct {
 
 	invalidPacketLimit uint64
 	invalidPacketCount uint64
+	// The maximum number of packets that can be sent with a single key.
+	confidentialityLimit uint64
+
+	// Key updates are initiated after sending or receiving keyUpdateInterval packets,
+	// or after sending keyUpdateIntervalBytes bytes with the current key phase.
+	// If 0, the package-level KeyUpdateInterval is used, and the number of bytes doesn't trigger key updates.
+	keyUpdateInterval      uint64
+	keyUpdateIntervalBytes uint64
 
 	// Time when the keys should be dropped. Keys are dropped on the next call to Open().
 	prevRcvAEADExpiry time.Time
@@ -42,6 +51,7 @@ type updatableAEAD struct {
 	highestRcvdPN           protocol.PacketNumber // highest packet number received (which could be successfully unprotected)
 	numRcvdWithCurrentKey   uint64
 	numSentWithCurrentKey   uint64
+	bytesSentWithCurrentKey protocol.ByteCount
 	rcvAEAD                 cipher.AEAD
 	sendAEAD                cipher.AEAD
 	// caches cipher.AEAD.Overhead(). This speeds up calls to Overhead().
@@ -55,6 +65,17 @@ type updatableAEAD struct {
 	headerDecrypter headerProtector
 	headerEncrypter headerProtector
 
+	// The read traffic secret of the first key phase. It is used to derive the header protection key.
+	firstRcvTrafficSecret []byte
+	// Shared with the workers created by NewWorker.
+	rcvKeys             atomic.Pointer[rcvKeyState]
+	sharedHighestRcvdPN atomic.Int64
+
+	// The number of key updates initiated by us and by the peer.
+	// They are read when the application requests the connection state.
+	numLocalKeyUpdates  atomic.Uint64
+	numRemoteKeyUpdates atomic.Uint64
+
 	rttStats *utils.RTTStats
 
 	tracer  *logging.ConnectionTracer
@@ -66,10 +87,17 @@ type updatableAEAD struct {
 }
 
 var (
-	_ ShortHeaderOpener = &updatableAEAD{}
-	_ ShortHeaderSealer = &updatableAEAD{}
+	_ ShortHeaderOpener           = &updatableAEAD{}
+	_ ShortHeaderSealer           = &updatableAEAD{}
+	_ ConcurrentShortHeaderOpener = &updatableAEAD{}
 )
 
+// rcvKeyState is the key phase and the read traffic secret of the current key phase.
+type rcvKeyState struct {
+	keyPhase      protocol.KeyPhase
+	trafficSecret []byte
+}
+
 func newUpdatableAEAD(rttStats *utils.RTTStats, tracer *logging.ConnectionTracer, logger utils.Logger, version protocol.VersionNumber) *updatableAEAD {
 	return &updatableAEAD{
 		firstPacketNumber:       protocol.InvalidPacketNumber,
@@ -93,10 +121,12 @@ func (a *updatableAEAD) rollKeys() {
 	}
 
 	a.keyPhase++
+	a.rcvKeys.Store(&rcvKeyState{keyPhase: a.keyPhase, trafficSecret: a.nextRcvTrafficSecret})
 	a.firstRcvdWithCurrentKey = protocol.InvalidPacketNumber
 	a.firstSentWithCurrentKey = protocol.InvalidPacketNumber
 	a.numRcvdWithCurrentKey = 0
 	a.numSentWithCurrentKey = 0
+	a.bytesSentWithCurrentKey = 0
 	a.prevRcvAEAD = a.rcvAEAD
 	a.rcvAEAD = a.nextRcvAEAD
 	a.sendAEAD = a.nextSendAEAD
@@ -126,6 +156,8 @@ func (a *updatableAEAD) SetReadKey(suite *cipherSuite, trafficSecret []byte) {
 	if a.suite == nil {
 		a.setAEADParameters(a.rcvAEAD, suite)
 	}
+	a.firstRcvTrafficSecret = trafficSecret
+	a.rcvKeys.Store(&rcvKeyState{keyPhase: a.keyPhase, trafficSecret: trafficSecret})
 
 	a.nextRcvTrafficSecret = a.getNextTrafficSecret(suite.Hash, trafficSecret)
 	a.nextRcvAEAD = createAEAD(suite, a.nextRcvTrafficSecret, a.version)
@@ -151,9 +183,11 @@ func (a *updatableAEAD) setAEADParameters(aead cipher.AEAD, suite *cipherSuite)
 	a.suite = suite
 	switch suite.ID {
 	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384:
-		a.invalidPacketLimit = protocol.InvalidPacketLimitAES
+		a.invalidPacketLimit = withAEADLimitMargin(protocol.InvalidPacketLimitAES)
+		a.confidentialityLimit = protocol.ConfidentialityLimitAES
 	case tls.TLS_CHACHA20_POLY1305_SHA256:
-		a.invalidPacketLimit = protocol.InvalidPacketLimitChaCha
+		a.invalidPacketLimit = withAEADLimitMargin(protocol.InvalidPacketLimitChaCha)
+		a.confidentialityLimit = protocol.ConfidentialityLimitChaCha
 	default:
 		panic(fmt.Sprintf("unknown cipher suite %d", suite.ID))
 	}
@@ -166,18 +200,28 @@ func (a *updatableAEAD) DecodePacketNumber(wirePN protocol.PacketNumber, wirePNL
 func (a *updatableAEAD) Open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
 	dec, err := a.open(dst, src, rcvTime, pn, kp, ad)
 	if err == ErrDecryptionFailed {
-		a.invalidPacketCount++
-		if a.invalidPacketCount >= a.invalidPacketLimit {
-			return nil, &qerr.TransportError{ErrorCode: qerr.AEADLimitReached}
-		}
+		return nil, a.decryptionFailed()
 	}
 	if err == nil {
-		a.highestRcvdPN = utils.Max(a.highestRcvdPN, pn)
+		a.setHighestRcvdPN(pn)
 	}
 	return dec, err
 }
 
-func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+func (a *updatableAEAD) decryptionFailed() error {
+	a.invalidPacketCount++
+	if a.invalidPacketCount >= a.invalidPacketLimit {
+		return &qerr.TransportError{ErrorCode: qerr.AEADLimitReached}
+	}
+	return ErrDecryptionFailed
+}
+
+func (a *updatableAEAD) setHighestRcvdPN(pn protocol.PacketNumber) {
+	a.highestRcvdPN = utils.Max(a.highestRcvdPN, pn)
+	a.sharedHighestRcvdPN.Store(int64(a.highestRcvdPN))
+}
+
+func (a *updatableAEAD) dropPrevKeysIfExpired(rcvTime time.Time) {
 	if a.prevRcvAEAD != nil && !a.prevRcvAEADExpiry.IsZero() && rcvTime.After(a.prevRcvAEADExpiry) {
 		a.prevRcvAEAD = nil
 		a.logger.Debugf("Dropping key phase %d", a.keyPhase-1)
@@ -186,6 +230,10 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 			a.tracer.DroppedKey(a.keyPhase - 1)
 		}
 	}
+}
+
+func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.PacketNumber, kp protocol.KeyPhaseBit, ad []byte) ([]byte, error) {
+	a.dropPrevKeysIfExpired(rcvTime)
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	if kp != a.keyPhase.Bit() {
 		if a.keyPhase > 0 && a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber || pn < a.firstRcvdWithCurrentKey {
@@ -212,6 +260,7 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 			}
 		}
 		a.rollKeys()
+		a.numRemoteKeyUpdates.Add(1)
 		a.logger.Debugf("Peer updated keys to %d", a.keyPhase)
 		// The peer initiated this key update. It's safe to drop the keys for the previous generation now.
 		// Start a timer to drop the previous key generation.
@@ -228,6 +277,11 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 	if err != nil {
 		return dec, ErrDecryptionFailed
 	}
+	a.receivedWithCurrentKey(rcvTime, pn)
+	return dec, err
+}
+
+func (a *updatableAEAD) receivedWithCurrentKey(rcvTime time.Time, pn protocol.PacketNumber) {
 	a.numRcvdWithCurrentKey++
 	if a.firstRcvdWithCurrentKey == protocol.InvalidPacketNumber {
 		// We initiated the key updated, and now we received the first packet protected with the new key phase.
@@ -238,7 +292,45 @@ func (a *updatableAEAD) open(dst, src []byte, rcvTime time.Time, pn protocol.Pac
 		}
 		a.firstRcvdWithCurrentKey = pn
 	}
-	return dec, err
+}
+
+// NewWorker creates a worker that opens packets protected with the current key phase.
+// It must only be called after the read key was set.
+func (a *updatableAEAD) NewWorker() ShortHeaderOpenerWorker {
+	return &shortHeaderOpenerWorker{
+		suite:           a.suite,
+		version:         a.version,
+		headerDecrypter: newHeaderProtector(a.suite, a.firstRcvTrafficSecret, false, a.version),
+		rcvKeys:         &a.rcvKeys,
+		highestRcvdPN:   &a.sharedHighestRcvdPN,
+	}
+}
+
+func (a *updatableAEAD) FinishOpen(rcvTime time.Time, pn protocol.PacketNumber, keyPhase protocol.KeyPhase, openErr error) error {
+	if openErr == ErrDecryptionFailed {
+		return a.decryptionFailed()
+	}
+	if openErr != nil {
+		return openErr
+	}
+	a.dropPrevKeysIfExpired(rcvTime)
+	switch {
+	case keyPhase == a.keyPhase:
+		a.receivedWithCurrentKey(rcvTime, pn)
+	case keyPhase+1 == a.keyPhase:
+		// The keys were rolled after the worker opened this packet.
+		// Only accept it if Open would have opened it using the previous keys.
+		if a.firstRcvdWithCurrentKey != protocol.InvalidPacketNumber && pn >= a.firstRcvdWithCurrentKey {
+			return a.decryptionFailed()
+		}
+		if a.prevRcvAEAD == nil {
+			return ErrKeysDropped
+		}
+	default:
+		return ErrKeysDropped
+	}
+	a.setHighestRcvdPN(pn)
+	return nil
 }
 
 func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byte) []byte {
@@ -249,6 +341,7 @@ func (a *updatableAEAD) Seal(dst, src []byte, pn protocol.PacketNumber, ad []byt
 		a.firstPacketNumber = pn
 	}
 	a.numSentWithCurrentKey++
+	a.bytesSentWithCurrentKey += protocol.ByteCount(len(src))
 	binary.BigEndian.PutUint64(a.nonceBuf[len(a.nonceBuf)-8:], uint64(pn))
 	// The AEAD we're using here will be the qtls.aeadAESGCM13.
 	// It uses the nonce provided here and XOR it with the IV.
@@ -283,30 +376,47 @@ func (a *updatableAEAD) updateAllowed() bool {
 			a.largestAcked >= a.firstSentWithCurrentKey)
 }
 
-func (a *updatableAEAD) shouldInitiateKeyUpdate() bool {
+func (a *updatableAEAD) shouldInitiateKeyUpdate() (logging.KeyUpdateReason, bool) {
 	if !a.updateAllowed() {
-		return false
+		return 0, false
+	}
+	// Stop using a key well before its confidentiality limit is reached, see section 6.6 of RFC 9001.
+	if a.numSentWithCurrentKey >= withAEADLimitMargin(a.confidentialityLimit) {
+		a.logger.Debugf("Sent %d packets with current key phase, approaching the confidentiality limit. Initiating key update to the next key phase: %d", a.numSentWithCurrentKey, a.keyPhase+1)
+		return logging.KeyUpdateConfidentialityLimit, true
 	}
 	// Initiate the first key update shortly after the handshake, in order to exercise the key update mechanism.
 	if a.keyPhase == 0 {
 		if a.numRcvdWithCurrentKey >= FirstKeyUpdateInterval || a.numSentWithCurrentKey >= FirstKeyUpdateInterval {
-			return true
+			return logging.KeyUpdateFirst, true
 		}
 	}
-	if a.numRcvdWithCurrentKey >= KeyUpdateInterval {
+	interval := a.keyUpdateInterval
+	if interval == 0 {
+		interval = KeyUpdateInterval
+	}
+	if a.numRcvdWithCurrentKey >= interval {
 		a.logger.Debugf("Received %d packets with current key phase. Initiating key update to the next key phase: %d", a.numRcvdWithCurrentKey, a.keyPhase+1)
-		return true
+		return logging.KeyUpdatePacketInterval, true
 	}
-	if a.numSentWithCurrentKey >= KeyUpdateInterval {
+	if a.numSentWithCurrentKey >= interval {
 		a.logger.Debugf("Sent %d packets with current key phase. Initiating key update to the next key phase: %d", a.numSentWithCurrentKey, a.keyPhase+1)
-		return true
+		return logging.KeyUpdatePacketInterval, true
+	}
+	if a.keyUpdateIntervalBytes > 0 && uint64(a.bytesSentWithCurrentKey) >= a.keyUpdateIntervalBytes {
+		a.logger.Debugf("Sent %d bytes with current key phase. Initiating key update to the next key phase: %d", a.bytesSentWithCurrentKey, a.keyPhase+1)
+		return logging.KeyUpdateByteInterval, true
 	}
-	return false
+	return 0, false
 }
 
 func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
-	if a.shouldInitiateKeyUpdate() {
+	if reason, ok := a.shouldInitiateKeyUpdate(); ok {
+		if a.tracer != nil && a.tracer.InitiatedKeyUpdate != nil {
+			a.tracer.InitiatedKeyUpdate(a.keyPhase+1, reason, a.numSentWithCurrentKey, a.numRcvdWithCurrentKey, a.bytesSentWithCurrentKey)
+		}
 		a.rollKeys()
+		a.numLocalKeyUpdates.Add(1)
 		a.logger.Debugf("Initiating key update to key phase %d", a.keyPhase)
 		if a.tracer != nil && a.tracer.UpdatedKey != nil {
 			a.tracer.UpdatedKey(a.keyPhase, false)
@@ -315,6 +425,23 @@ func (a *updatableAEAD) KeyPhase() protocol.KeyPhaseBit {
 	return a.keyPhase.Bit()
 }
 
+// confidentialityLimitReached says if the confidentiality limit of the current key was reached,
+// and no key update can be initiated. In that case, no more packets can be sent.
+func (a *updatableAEAD) confidentialityLimitReached() bool {
+	return a.numSentWithCurrentKey >= a.confidentialityLimit && !a.updateAllowed()
+}
+
+// SetKeyUpdateInterval sets the number of packets and bytes after which a key update is initiated.
+func (a *updatableAEAD) SetKeyUpdateInterval(packets, bytes uint64) {
+	a.keyUpdateInterval = packets
+	a.keyUpdateIntervalBytes = bytes
+}
+
+// withAEADLimitMargin returns the limit minus a safety margin.
+func withAEADLimitMargin(limit uint64) uint64 {
+	return limit - limit/protocol.AEADLimitMarginDivisor
+}
+
 func (a *updatableAEAD) Overhead() int {
 	return a.aeadOverhead
 }
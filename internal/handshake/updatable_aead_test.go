@@ -160,6 +160,92 @@ var _ = Describe("Updatable AEAD", func() {
 							Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.AEADLimitReached))
 						})
 
+						Context("concurrent decryption", func() {
+							It("opens packets on a worker", func() {
+								w := client.NewWorker()
+								encrypted := server.Seal(nil, msg, 0x1337, ad)
+								opened, keyPhase, err := w.Open(nil, encrypted, 0x1337, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								Expect(keyPhase).To(BeZero())
+								Expect(client.FinishOpen(time.Now(), 0x1337, keyPhase, nil)).To(Succeed())
+								Expect(client.numRcvdWithCurrentKey).To(BeEquivalentTo(1))
+								Expect(client.DecodePacketNumber(0x38, protocol.PacketNumberLen1)).To(BeEquivalentTo(0x1338))
+								Expect(w.DecodePacketNumber(0x38, protocol.PacketNumberLen1)).To(BeEquivalentTo(0x1338))
+							})
+
+							It("decrypts the header on a worker", func() {
+								w := client.NewWorker()
+								sample := make([]byte, 16)
+								rand.Read(sample)
+								header := []byte{0xb5, 1, 2, 3, 4, 5, 6, 7, 8, 0xde, 0xad, 0xbe, 0xef}
+								server.EncryptHeader(sample, &header[0], header[9:13])
+								w.DecryptHeader(sample, &header[0], header[9:13])
+								Expect(header).To(Equal([]byte{0xb5, 1, 2, 3, 4, 5, 6, 7, 8, 0xde, 0xad, 0xbe, 0xef}))
+							})
+
+							It("doesn't open packets with a different key phase", func() {
+								w := client.NewWorker()
+								server.rollKeys()
+								encrypted := server.Seal(nil, msg, 0x1337, ad)
+								orig := append([]byte{}, encrypted...)
+								_, _, err := w.Open(encrypted[:0], encrypted, 0x1337, protocol.KeyPhaseOne, ad)
+								Expect(err).To(MatchError(ErrKeyPhaseMismatch))
+								Expect(encrypted).To(Equal(orig))
+							})
+
+							It("uses the new keys after a key update", func() {
+								w := client.NewWorker()
+								encrypted0 := server.Seal(nil, msg, 0x42, ad)
+								_, _, err := w.Open(nil, encrypted0, 0x42, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								server.rollKeys()
+								client.rollKeys()
+								encrypted1 := server.Seal(nil, msg, 0x43, ad)
+								opened, keyPhase, err := w.Open(nil, encrypted1, 0x43, protocol.KeyPhaseOne, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(opened).To(Equal(msg))
+								Expect(keyPhase).To(BeEquivalentTo(1))
+							})
+
+							It("accepts packets opened with the previous keys, if the connection would have accepted them", func() {
+								w := client.NewWorker()
+								now := time.Now()
+								encrypted01 := server.Seal(nil, msg, 0x42, ad)
+								encrypted02 := server.Seal(nil, msg, 0x45, ad)
+								_, keyPhase01, err := w.Open(nil, encrypted01, 0x42, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								_, keyPhase02, err := w.Open(nil, encrypted02, 0x45, protocol.KeyPhaseZero, ad)
+								Expect(err).ToNot(HaveOccurred())
+								// the peer updates keys, and the connection receives the first packet in the new key phase
+								server.rollKeys()
+								encrypted1 := server.Seal(nil, msg, 0x44, ad)
+								_, err = client.Open(nil, encrypted1, now, 0x44, protocol.KeyPhaseOne, ad)
+								Expect(err).ToNot(HaveOccurred())
+								Expect(client.keyPhase).To(BeEquivalentTo(1))
+								// packet 0x42 was sent before the first packet in the new key phase
+								Expect(client.FinishOpen(now, 0x42, keyPhase01, nil)).To(Succeed())
+								// packet 0x45 was sent after the first packet in the new key phase
+								Expect(client.FinishOpen(now, 0x45, keyPhase02, nil)).To(MatchError(ErrDecryptionFailed))
+							})
+
+							It("rejects packets opened with keys that were already dropped", func() {
+								Expect(client.FinishOpen(time.Now(), 0x42, 0, nil)).To(Succeed())
+								client.rollKeys()
+								client.rollKeys()
+								Expect(client.FinishOpen(time.Now(), 0x43, 0, nil)).To(MatchError(ErrKeysDropped))
+							})
+
+							It("counts decryption failures on workers towards the AEAD limit", func() {
+								client.invalidPacketLimit = 3
+								Expect(client.FinishOpen(time.Now(), 1, 0, ErrDecryptionFailed)).To(MatchError(ErrDecryptionFailed))
+								Expect(client.FinishOpen(time.Now(), 2, 0, ErrDecryptionFailed)).To(MatchError(ErrDecryptionFailed))
+								err := client.FinishOpen(time.Now(), 3, 0, ErrDecryptionFailed)
+								Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
+								Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.AEADLimitReached))
+							})
+						})
+
 						Context("key updates", func() {
 							Context("receiving key updates", func() {
 								It("updates keys", func() {
@@ -294,6 +380,7 @@ var _ = Describe("Updatable AEAD", func() {
 									KeyUpdateInterval = keyUpdateInterval
 									FirstKeyUpdateInterval = firstKeyUpdateInterval
 									server.SetHandshakeConfirmed()
+									serverTracer.EXPECT().InitiatedKeyUpdate(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 								})
 
 								AfterEach(func() {
@@ -529,6 +616,94 @@ var _ = Describe("Updatable AEAD", func() {
 									Expect(err).ToNot(HaveOccurred())
 								})
 							})
+
+							Context("key update policy", func() {
+								var origKeyUpdateInterval, origFirstKeyUpdateInterval uint64
+
+								BeforeEach(func() {
+									origKeyUpdateInterval = KeyUpdateInterval
+									origFirstKeyUpdateInterval = FirstKeyUpdateInterval
+									KeyUpdateInterval = 1000
+									FirstKeyUpdateInterval = 1000
+									server.SetHandshakeConfirmed()
+								})
+
+								AfterEach(func() {
+									KeyUpdateInterval = origKeyUpdateInterval
+									FirstKeyUpdateInterval = origFirstKeyUpdateInterval
+								})
+
+								It("initiates a key update after the configured number of packets", func() {
+									server.SetKeyUpdateInterval(3, 0)
+									for i := 0; i < 3; i++ {
+										Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+										server.Seal(nil, msg, protocol.PacketNumber(i), ad)
+									}
+									gomock.InOrder(
+										serverTracer.EXPECT().InitiatedKeyUpdate(protocol.KeyPhase(1), logging.KeyUpdatePacketInterval, uint64(3), uint64(0), protocol.ByteCount(3*len(msg))),
+										serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false),
+									)
+									Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+									Expect(server.numLocalKeyUpdates.Load()).To(BeEquivalentTo(1))
+									Expect(server.numRemoteKeyUpdates.Load()).To(BeZero())
+								})
+
+								It("initiates a key update after the configured number of bytes", func() {
+									server.SetKeyUpdateInterval(0, uint64(2*len(msg)))
+									for i := 0; i < 2; i++ {
+										Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+										server.Seal(nil, msg, protocol.PacketNumber(i), ad)
+									}
+									gomock.InOrder(
+										serverTracer.EXPECT().InitiatedKeyUpdate(protocol.KeyPhase(1), logging.KeyUpdateByteInterval, uint64(2), uint64(0), protocol.ByteCount(2*len(msg))),
+										serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false),
+									)
+									Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+								})
+
+								It("initiates a key update before reaching the confidentiality limit", func() {
+									server.confidentialityLimit = 32
+									// the limit minus a margin of 1/16
+									for i := 0; i < 30; i++ {
+										Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseZero))
+										server.Seal(nil, msg, protocol.PacketNumber(i), ad)
+									}
+									gomock.InOrder(
+										serverTracer.EXPECT().InitiatedKeyUpdate(protocol.KeyPhase(1), logging.KeyUpdateConfidentialityLimit, uint64(30), uint64(0), protocol.ByteCount(30*len(msg))),
+										serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), false),
+									)
+									Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+								})
+
+								It("reports when the confidentiality limit is reached and the keys can't be updated", func() {
+									server.confidentialityLimit = 32
+									server.rollKeys()
+									client.rollKeys()
+									for i := 0; i < 32; i++ {
+										Expect(server.confidentialityLimitReached()).To(BeFalse())
+										// no key update allowed before receiving an acknowledgement for the current key phase
+										Expect(server.KeyPhase()).To(Equal(protocol.KeyPhaseOne))
+										server.Seal(nil, msg, protocol.PacketNumber(i), ad)
+									}
+									Expect(server.confidentialityLimitReached()).To(BeTrue())
+									// receive an ACK for a packet sent in key phase 1
+									b := client.Seal(nil, msg, 1, ad)
+									_, err := server.Open(nil, b, time.Now(), 1, protocol.KeyPhaseOne, ad)
+									Expect(err).ToNot(HaveOccurred())
+									Expect(server.SetLargestAcked(0)).To(Succeed())
+									Expect(server.confidentialityLimitReached()).To(BeFalse())
+								})
+
+								It("counts key updates initiated by the peer", func() {
+									client.rollKeys()
+									b := client.Seal(nil, msg, 1, ad)
+									serverTracer.EXPECT().UpdatedKey(protocol.KeyPhase(1), true)
+									_, err := server.Open(nil, b, time.Now(), 1, protocol.KeyPhaseOne, ad)
+									Expect(err).ToNot(HaveOccurred())
+									Expect(server.numLocalKeyUpdates.Load()).To(BeZero())
+									Expect(server.numRemoteKeyUpdates.Load()).To(BeEquivalentTo(1))
+								})
+							})
 						})
 					})
 				})
@@ -0,0 +1,204 @@
+package handshake
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qtls"
+)
+
+// maxQueuedJobsPerWorker is the number of jobs per worker that can be queued on a WorkerPool.
+const maxQueuedJobsPerWorker = 16
+
+// A WorkerPool runs the expensive operations of TLS handshakes
+// (certificate verification, signature generation and key exchange) off the connections' run loops.
+// Workers are started when jobs are queued, and exit as soon as there are no more jobs,
+// so an idle WorkerPool doesn't use any goroutines.
+type WorkerPool struct {
+	maxWorkers int
+	jobs       chan func()
+
+	mutex   sync.Mutex
+	workers int // the number of running workers
+}
+
+// NewWorkerPool creates a new WorkerPool that runs at most workers handshake operations concurrently.
+func NewWorkerPool(workers int) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &WorkerPool{
+		maxWorkers: workers,
+		jobs:       make(chan func(), workers*maxQueuedJobsPerWorker),
+	}
+}
+
+func (p *WorkerPool) runWorker() {
+	for {
+		select {
+		case f := <-p.jobs:
+			f()
+		default:
+			p.mutex.Lock()
+			// A job might have been queued after the check above.
+			// Since run starts a new worker after queueing a job, it's enough to check the queue while holding the mutex.
+			if len(p.jobs) > 0 {
+				p.mutex.Unlock()
+				continue
+			}
+			p.workers--
+			p.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// run queues f to be run as soon as a worker is available.
+// It doesn't block. If the queue is full, f is not queued and false is returned.
+func (p *WorkerPool) run(f func()) bool {
+	select {
+	case p.jobs <- f:
+	default:
+		return false
+	}
+	p.mutex.Lock()
+	if p.workers < p.maxWorkers {
+		p.workers++
+		go p.runWorker()
+	}
+	p.mutex.Unlock()
+	return true
+}
+
+// numWorkers returns the number of running workers.
+func (p *WorkerPool) numWorkers() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.workers
+}
+
+type workerMessage struct {
+	data     []byte
+	encLevel protocol.EncryptionLevel
+}
+
+// The workerResult is the result of handling handshake messages on the WorkerPool.
+// The events are processed on the connection's run loop.
+type workerResult struct {
+	events     []qtls.QUICEvent
+	initialRTT time.Duration
+	err        error
+}
+
+// UseWorkerPool makes the crypto setup handle Initial and Handshake messages on the WorkerPool.
+// onResult is called (on the worker) when a result is available, and needs to trigger a call to HandleWorkerResults.
+// It must be called before StartHandshake.
+func (h *cryptoSetup) UseWorkerPool(p *WorkerPool, onResult func()) {
+	h.workers = p
+	h.onWorkerResult = onResult
+}
+
+// HandleWorkerResults processes the result of the last job that ran on the WorkerPool.
+// New events are then available via NextEvent.
+func (h *cryptoSetup) HandleWorkerResults() error {
+	h.workerMutex.Lock()
+	res := h.result
+	h.result = nil
+	h.workerMutex.Unlock()
+	if res == nil {
+		return nil
+	}
+
+	if res.initialRTT > 0 {
+		h.rttStats.SetInitialRTT(res.initialRTT)
+	}
+	for _, ev := range res.events {
+		if _, err := h.handleEvent(ev); err != nil {
+			return wrapError(err)
+		}
+	}
+	if res.err != nil {
+		return wrapError(res.err)
+	}
+	h.maybeStartJob()
+	return nil
+}
+
+// maybeStartJob starts handling the queued messages on the WorkerPool.
+// Jobs are run one after the other, and the next job is only started once the result of the previous one was processed.
+// If the WorkerPool's queue is full, the job is run right away, on the connection's run loop.
+func (h *cryptoSetup) maybeStartJob() {
+	h.workerMutex.Lock()
+	if h.jobRunning || h.result != nil || h.closed || len(h.queuedMessages) == 0 {
+		h.workerMutex.Unlock()
+		return
+	}
+	msgs := h.queuedMessages
+	h.queuedMessages = nil
+	h.jobRunning = true
+	h.workerMutex.Unlock()
+
+	if !h.workers.run(func() { h.runJob(msgs) }) {
+		h.logger.Debugf("Handshake worker pool queue full. Handling handshake messages on the connection's goroutine.")
+		h.runJob(msgs)
+	}
+}
+
+func (h *cryptoSetup) runJob(msgs []workerMessage) {
+	h.inWorker = true
+	res := &workerResult{}
+	for _, m := range msgs {
+		if err := h.conn.HandleData(qtls.ToTLSEncryptionLevel(m.encLevel), m.data); err != nil {
+			res.err = err
+			break
+		}
+		res.events = h.collectEvents(res.events)
+	}
+	res.initialRTT = h.workerRTT
+	h.workerRTT = 0
+	h.inWorker = false
+
+	h.workerMutex.Lock()
+	h.jobRunning = false
+	closed := h.closed
+	if !closed {
+		h.result = res
+	}
+	h.workerMutex.Unlock()
+
+	if closed {
+		h.conn.Close()
+		return
+	}
+	h.onWorkerResult()
+}
+
+// collectEvents collects the events generated by crypto/tls, such that they can be processed on the run loop.
+// The transport parameters are set right away, since crypto/tls waits for them to continue the handshake.
+func (h *cryptoSetup) collectEvents(events []qtls.QUICEvent) []qtls.QUICEvent {
+	for {
+		ev := h.conn.NextEvent()
+		switch ev.Kind {
+		case qtls.QUICNoEvent:
+			return events
+		case qtls.QUICTransportParametersRequired:
+			h.conn.SetTransportParameters(h.ourParams.Marshal(h.perspective))
+		default:
+			// The data is only valid until the next call to NextEvent.
+			ev.Data = append([]byte(nil), ev.Data...)
+			events = append(events, ev)
+		}
+	}
+}
+
+// setInitialRTT sets the initial RTT restored from a session ticket.
+// The RTTStats are only accessed on the run loop: when called during a job,
+// the RTT is set when the job's result is processed.
+func (h *cryptoSetup) setInitialRTT(rtt time.Duration) {
+	if h.inWorker {
+		h.workerRTT = rtt
+		return
+	}
+	h.rttStats.SetInitialRTT(rtt)
+}
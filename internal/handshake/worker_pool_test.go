@@ -0,0 +1,224 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Handshake Worker Pool", func() {
+	It("limits the number of concurrent jobs", func() {
+		const workers = 3
+		p := NewWorkerPool(workers)
+		var running, maxRunning atomic.Int32
+		done := make(chan struct{}, 10)
+		for i := 0; i < 10; i++ {
+			p.run(func() {
+				n := running.Add(1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				done <- struct{}{}
+			})
+		}
+		for i := 0; i < 10; i++ {
+			Eventually(done).Should(Receive())
+		}
+		Expect(maxRunning.Load()).To(BeEquivalentTo(workers))
+	})
+
+	It("only runs workers while there are jobs", func() {
+		p := NewWorkerPool(3)
+		Expect(p.numWorkers()).To(BeZero())
+		unblock := make(chan struct{})
+		done := make(chan struct{}, 5)
+		for i := 0; i < 5; i++ {
+			Expect(p.run(func() { <-unblock; done <- struct{}{} })).To(BeTrue())
+		}
+		Expect(p.numWorkers()).To(Equal(3))
+		close(unblock)
+		for i := 0; i < 5; i++ {
+			Eventually(done).Should(Receive())
+		}
+		Eventually(p.numWorkers).Should(BeZero())
+		// workers are started again for new jobs
+		Expect(p.run(func() { done <- struct{}{} })).To(BeTrue())
+		Eventually(done).Should(Receive())
+		Eventually(p.numWorkers).Should(BeZero())
+	})
+
+	It("rejects jobs when the queue is full", func() {
+		p := NewWorkerPool(1)
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		Expect(p.run(func() { close(started); <-unblock })).To(BeTrue())
+		Eventually(started).Should(BeClosed())
+		ran := make(chan struct{}, maxQueuedJobsPerWorker)
+		for i := 0; i < maxQueuedJobsPerWorker; i++ {
+			Expect(p.run(func() { ran <- struct{}{} })).To(BeTrue())
+		}
+		Expect(p.run(func() { Fail("shouldn't run") })).To(BeFalse())
+		close(unblock)
+		for i := 0; i < maxQueuedJobsPerWorker; i++ {
+			Eventually(ran).Should(Receive())
+		}
+	})
+
+	newCryptoSetups := func() (client, server CryptoSetup) {
+		serverConf := testdata.GetTLSConfig()
+		serverConf.NextProtos = []string{"crypto-setup"}
+		clientConf := &tls.Config{
+			ServerName: "localhost",
+			RootCAs:    testdata.GetRootCA(),
+			NextProtos: []string{"crypto-setup"},
+		}
+		client = NewCryptoSetupClient(
+			protocol.ConnectionID{},
+			&wire.TransportParameters{ActiveConnectionIDLimit: 2},
+			clientConf,
+			false,
+			&utils.RTTStats{},
+			nil,
+			utils.DefaultLogger.WithPrefix("client"),
+			protocol.Version1,
+		)
+		var token protocol.StatelessResetToken
+		server = NewCryptoSetupServer(
+			protocol.ConnectionID{},
+			&net.UDPAddr{IP: net.IPv6loopback, Port: 1234},
+			&net.UDPAddr{IP: net.IPv6loopback, Port: 4321},
+			&wire.TransportParameters{ActiveConnectionIDLimit: 2, StatelessResetToken: &token},
+			serverConf,
+			false,
+			&utils.RTTStats{},
+			nil,
+			utils.DefaultLogger.WithPrefix("server"),
+			protocol.Version1,
+		)
+		return client, server
+	}
+
+	// forwardEvents passes the handshake messages of one crypto setup to the other one,
+	// and returns if the handshake completed.
+	forwardEvents := func(from, to CryptoSetup) (handshakeComplete bool) {
+		for {
+			ev := from.NextEvent()
+			//nolint:exhaustive // only need to process a few events
+			switch ev.Kind {
+			case EventNoEvent:
+				return
+			case EventWriteInitialData:
+				Expect(to.HandleMessage(ev.Data, protocol.EncryptionInitial)).To(Succeed())
+			case EventWriteHandshakeData:
+				Expect(to.HandleMessage(ev.Data, protocol.EncryptionHandshake)).To(Succeed())
+			case EventHandshakeComplete:
+				handshakeComplete = true
+			}
+		}
+	}
+
+	It("handshakes on the worker pool", func() {
+		p := NewWorkerPool(1)
+		client, server := newCryptoSetups()
+		clientResult := make(chan struct{}, 1)
+		serverResult := make(chan struct{}, 1)
+		client.UseWorkerPool(p, func() { clientResult <- struct{}{} })
+		server.UseWorkerPool(p, func() { serverResult <- struct{}{} })
+
+		Expect(client.StartHandshake()).To(Succeed())
+		Expect(server.StartHandshake()).To(Succeed())
+		Expect(forwardEvents(client, server)).To(BeFalse())
+		// The ClientHello is handled on the worker pool.
+		Expect(server.NextEvent().Kind).To(Equal(EventNoEvent))
+
+		var clientComplete, serverComplete bool
+		for !clientComplete || !serverComplete {
+			select {
+			case <-serverResult:
+				Expect(server.HandleWorkerResults()).To(Succeed())
+				if forwardEvents(server, client) {
+					serverComplete = true
+				}
+			case <-clientResult:
+				Expect(client.HandleWorkerResults()).To(Succeed())
+				if forwardEvents(client, server) {
+					clientComplete = true
+				}
+			case <-time.After(time.Second):
+				Fail("timeout")
+			}
+		}
+		Expect(client.ConnectionState().NegotiatedProtocol).To(Equal("crypto-setup"))
+		Expect(server.ConnectionState().NegotiatedProtocol).To(Equal("crypto-setup"))
+	})
+
+	It("returns errors when processing the results", func() {
+		p := NewWorkerPool(1)
+		_, server := newCryptoSetups()
+		serverResult := make(chan struct{}, 1)
+		server.UseWorkerPool(p, func() { serverResult <- struct{}{} })
+		Expect(server.StartHandshake()).To(Succeed())
+
+		fakeCH := append([]byte{typeClientHello, 0, 0, 6}, []byte("foobar")...)
+		Expect(server.HandleMessage(fakeCH, protocol.EncryptionInitial)).To(Succeed())
+		Eventually(serverResult).Should(Receive())
+		err := server.HandleWorkerResults()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("tls:"))
+	})
+
+	It("handles messages on the caller's goroutine when the queue is full", func() {
+		p := NewWorkerPool(1)
+		started := make(chan struct{})
+		unblock := make(chan struct{})
+		defer close(unblock)
+		p.run(func() { close(started); <-unblock })
+		Eventually(started).Should(BeClosed())
+		for p.run(func() {}) {
+		}
+
+		client, server := newCryptoSetups()
+		serverResult := make(chan struct{}, 1)
+		server.UseWorkerPool(p, func() { serverResult <- struct{}{} })
+		Expect(client.StartHandshake()).To(Succeed())
+		Expect(server.StartHandshake()).To(Succeed())
+		forwardEvents(client, server)
+		// the ClientHello was handled synchronously
+		Expect(serverResult).To(Receive())
+		Expect(server.HandleWorkerResults()).To(Succeed())
+		Expect(server.NextEvent().Kind).ToNot(Equal(EventNoEvent))
+	})
+
+	It("closes after the running job finished", func() {
+		p := NewWorkerPool(1)
+		// block the worker pool, so the job doesn't start
+		unblock := make(chan struct{})
+		p.run(func() { <-unblock })
+
+		client, server := newCryptoSetups()
+		serverResult := make(chan struct{}, 1)
+		server.UseWorkerPool(p, func() { serverResult <- struct{}{} })
+		Expect(client.StartHandshake()).To(Succeed())
+		Expect(server.StartHandshake()).To(Succeed())
+		forwardEvents(client, server)
+		Expect(server.Close()).To(Succeed())
+		close(unblock)
+		Consistently(serverResult).ShouldNot(Receive())
+		Expect(server.HandleWorkerResults()).To(Succeed())
+		Expect(server.NextEvent().Kind).To(Equal(EventNoEvent))
+	})
+})
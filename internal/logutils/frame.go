@@ -31,6 +31,46 @@ func ConvertFrame(frame wire.Frame) logging.Frame {
 		return &logging.DatagramFrame{
 			Length: logging.ByteCount(len(f.Data)),
 		}
+	case *wire.ExtensionFrame:
+		return &logging.ExtensionFrame{
+			Type:   f.FrameType,
+			Length: logging.ByteCount(len(f.Data)),
+		}
+	// The frame parser reuses the structs for these frames.
+	// Implementations of the tracer interface may hold on to frames, so we need to make a copy here.
+	case *wire.ResetStreamFrame:
+		c := *f
+		return &c
+	case *wire.StopSendingFrame:
+		c := *f
+		return &c
+	case *wire.MaxDataFrame:
+		c := *f
+		return &c
+	case *wire.MaxStreamDataFrame:
+		c := *f
+		return &c
+	case *wire.MaxStreamsFrame:
+		c := *f
+		return &c
+	case *wire.DataBlockedFrame:
+		c := *f
+		return &c
+	case *wire.StreamDataBlockedFrame:
+		c := *f
+		return &c
+	case *wire.StreamsBlockedFrame:
+		c := *f
+		return &c
+	case *wire.RetireConnectionIDFrame:
+		c := *f
+		return &c
+	case *wire.PathChallengeFrame:
+		c := *f
+		return &c
+	case *wire.PathResponseFrame:
+		c := *f
+		return &c
 	default:
 		return logging.Frame(frame)
 	}
@@ -42,6 +42,14 @@ var _ = Describe("CRYPTO frame", func() {
 		Expect(df.Length).To(Equal(logging.ByteCount(6)))
 	})
 
+	It("converts extension frames", func() {
+		f := ConvertFrame(&wire.ExtensionFrame{FrameType: 0x1337, Data: []byte("foobar"), Value: "foobar"})
+		Expect(f).To(BeAssignableToTypeOf(&logging.ExtensionFrame{}))
+		ef := f.(*logging.ExtensionFrame)
+		Expect(ef.Type).To(BeEquivalentTo(0x1337))
+		Expect(ef.Length).To(Equal(logging.ByteCount(6)))
+	})
+
 	It("converts other frames", func() {
 		f := ConvertFrame(&wire.MaxDataFrame{MaximumData: 1234})
 		Expect(f).To(BeAssignableToTypeOf(&logging.MaxDataFrame{}))
@@ -49,4 +57,13 @@ var _ = Describe("CRYPTO frame", func() {
 		mdf := f.(*logging.MaxDataFrame)
 		Expect(mdf.MaximumData).To(Equal(logging.ByteCount(1234)))
 	})
+
+	It("copies frames that are reused by the frame parser", func() {
+		orig := &wire.MaxStreamDataFrame{StreamID: 42, MaximumStreamData: 1234}
+		f := ConvertFrame(orig)
+		Expect(f).To(Equal(orig))
+		Expect(f).ToNot(BeIdenticalTo(orig))
+		orig.MaximumStreamData = 5678
+		Expect(f.(*logging.MaxStreamDataFrame).MaximumStreamData).To(Equal(logging.ByteCount(1234)))
+	})
 })
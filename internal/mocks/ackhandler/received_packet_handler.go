@@ -80,6 +80,18 @@ func (mr *MockReceivedPacketHandlerMockRecorder) GetAlarmTimeout() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlarmTimeout", reflect.TypeOf((*MockReceivedPacketHandler)(nil).GetAlarmTimeout))
 }
 
+// ImmediateAckRequested mocks base method.
+func (m *MockReceivedPacketHandler) ImmediateAckRequested(arg0 protocol.EncryptionLevel) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ImmediateAckRequested", arg0)
+}
+
+// ImmediateAckRequested indicates an expected call of ImmediateAckRequested.
+func (mr *MockReceivedPacketHandlerMockRecorder) ImmediateAckRequested(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImmediateAckRequested", reflect.TypeOf((*MockReceivedPacketHandler)(nil).ImmediateAckRequested), arg0)
+}
+
 // IsPotentiallyDuplicate mocks base method.
 func (m *MockReceivedPacketHandler) IsPotentiallyDuplicate(arg0 protocol.PacketNumber, arg1 protocol.EncryptionLevel) bool {
 	m.ctrl.T.Helper()
@@ -41,6 +41,21 @@ func (m *MockSentPacketHandler) EXPECT() *MockSentPacketHandlerMockRecorder {
 	return m.recorder
 }
 
+// BandwidthEstimate mocks base method.
+func (m *MockSentPacketHandler) BandwidthEstimate() (uint64, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BandwidthEstimate")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// BandwidthEstimate indicates an expected call of BandwidthEstimate.
+func (mr *MockSentPacketHandlerMockRecorder) BandwidthEstimate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BandwidthEstimate", reflect.TypeOf((*MockSentPacketHandler)(nil).BandwidthEstimate))
+}
+
 // DropPackets mocks base method.
 func (m *MockSentPacketHandler) DropPackets(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -67,6 +82,48 @@ func (mr *MockSentPacketHandlerMockRecorder) ECNMode(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNMode", reflect.TypeOf((*MockSentPacketHandler)(nil).ECNMode), arg0)
 }
 
+// FinishAckBatch mocks base method.
+func (m *MockSentPacketHandler) FinishAckBatch() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FinishAckBatch")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FinishAckBatch indicates an expected call of FinishAckBatch.
+func (mr *MockSentPacketHandlerMockRecorder) FinishAckBatch() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishAckBatch", reflect.TypeOf((*MockSentPacketHandler)(nil).FinishAckBatch))
+}
+
+// GetBytesInFlight mocks base method.
+func (m *MockSentPacketHandler) GetBytesInFlight() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBytesInFlight")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// GetBytesInFlight indicates an expected call of GetBytesInFlight.
+func (mr *MockSentPacketHandlerMockRecorder) GetBytesInFlight() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBytesInFlight", reflect.TypeOf((*MockSentPacketHandler)(nil).GetBytesInFlight))
+}
+
+// GetCongestionWindow mocks base method.
+func (m *MockSentPacketHandler) GetCongestionWindow() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCongestionWindow")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// GetCongestionWindow indicates an expected call of GetCongestionWindow.
+func (mr *MockSentPacketHandlerMockRecorder) GetCongestionWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCongestionWindow", reflect.TypeOf((*MockSentPacketHandler)(nil).GetCongestionWindow))
+}
+
 // GetLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) GetLossDetectionTimeout() time.Time {
 	m.ctrl.T.Helper()
@@ -81,6 +138,58 @@ func (mr *MockSentPacketHandlerMockRecorder) GetLossDetectionTimeout() *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).GetLossDetectionTimeout))
 }
 
+// IsAmplificationLimited mocks base method.
+func (m *MockSentPacketHandler) IsAmplificationLimited() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsAmplificationLimited")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsAmplificationLimited indicates an expected call of IsAmplificationLimited.
+func (mr *MockSentPacketHandlerMockRecorder) IsAmplificationLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsAmplificationLimited", reflect.TypeOf((*MockSentPacketHandler)(nil).IsAmplificationLimited))
+}
+
+// MemoryUsage mocks base method.
+func (m *MockSentPacketHandler) MemoryUsage() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MemoryUsage")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// MemoryUsage indicates an expected call of MemoryUsage.
+func (mr *MockSentPacketHandlerMockRecorder) MemoryUsage() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MemoryUsage", reflect.TypeOf((*MockSentPacketHandler)(nil).MemoryUsage))
+}
+
+// MigratedPath mocks base method.
+func (m *MockSentPacketHandler) MigratedPath(arg0 protocol.ByteCount, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MigratedPath", arg0, arg1)
+}
+
+// MigratedPath indicates an expected call of MigratedPath.
+func (mr *MockSentPacketHandlerMockRecorder) MigratedPath(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).MigratedPath), arg0, arg1)
+}
+
+// OnAppLimited mocks base method.
+func (m *MockSentPacketHandler) OnAppLimited() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnAppLimited")
+}
+
+// OnAppLimited indicates an expected call of OnAppLimited.
+func (mr *MockSentPacketHandlerMockRecorder) OnAppLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnAppLimited", reflect.TypeOf((*MockSentPacketHandler)(nil).OnAppLimited))
+}
+
 // OnLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -229,6 +338,30 @@ func (mr *MockSentPacketHandlerMockRecorder) SetMaxDatagramSize(arg0 any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxDatagramSize), arg0)
 }
 
+// SetPacketObserver mocks base method.
+func (m *MockSentPacketHandler) SetPacketObserver(arg0 ackhandler.PacketObserver) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPacketObserver", arg0)
+}
+
+// SetPacketObserver indicates an expected call of SetPacketObserver.
+func (mr *MockSentPacketHandlerMockRecorder) SetPacketObserver(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPacketObserver", reflect.TypeOf((*MockSentPacketHandler)(nil).SetPacketObserver), arg0)
+}
+
+// StartAckBatch mocks base method.
+func (m *MockSentPacketHandler) StartAckBatch() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartAckBatch")
+}
+
+// StartAckBatch indicates an expected call of StartAckBatch.
+func (mr *MockSentPacketHandlerMockRecorder) StartAckBatch() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartAckBatch", reflect.TypeOf((*MockSentPacketHandler)(nil).StartAckBatch))
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
@@ -242,3 +375,15 @@ func (mr *MockSentPacketHandlerMockRecorder) TimeUntilSend() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TimeUntilSend", reflect.TypeOf((*MockSentPacketHandler)(nil).TimeUntilSend))
 }
+
+// ValidatedPath mocks base method.
+func (m *MockSentPacketHandler) ValidatedPath() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ValidatedPath")
+}
+
+// ValidatedPath indicates an expected call of ValidatedPath.
+func (mr *MockSentPacketHandlerMockRecorder) ValidatedPath() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidatedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).ValidatedPath))
+}
@@ -240,6 +240,20 @@ func (mr *MockCryptoSetupMockRecorder) HandleMessage(arg0, arg1 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockCryptoSetup)(nil).HandleMessage), arg0, arg1)
 }
 
+// HandleWorkerResults mocks base method.
+func (m *MockCryptoSetup) HandleWorkerResults() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleWorkerResults")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleWorkerResults indicates an expected call of HandleWorkerResults.
+func (mr *MockCryptoSetupMockRecorder) HandleWorkerResults() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleWorkerResults", reflect.TypeOf((*MockCryptoSetup)(nil).HandleWorkerResults))
+}
+
 // NextEvent mocks base method.
 func (m *MockCryptoSetup) NextEvent() handshake.Event {
 	m.ctrl.T.Helper()
@@ -266,6 +280,18 @@ func (mr *MockCryptoSetupMockRecorder) SetHandshakeConfirmed() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHandshakeConfirmed", reflect.TypeOf((*MockCryptoSetup)(nil).SetHandshakeConfirmed))
 }
 
+// SetKeyUpdateInterval mocks base method.
+func (m *MockCryptoSetup) SetKeyUpdateInterval(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetKeyUpdateInterval", arg0, arg1)
+}
+
+// SetKeyUpdateInterval indicates an expected call of SetKeyUpdateInterval.
+func (mr *MockCryptoSetupMockRecorder) SetKeyUpdateInterval(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetKeyUpdateInterval", reflect.TypeOf((*MockCryptoSetup)(nil).SetKeyUpdateInterval), arg0, arg1)
+}
+
 // SetLargest1RTTAcked mocks base method.
 func (m *MockCryptoSetup) SetLargest1RTTAcked(arg0 protocol.PacketNumber) error {
 	m.ctrl.T.Helper()
@@ -293,3 +319,15 @@ func (mr *MockCryptoSetupMockRecorder) StartHandshake() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartHandshake", reflect.TypeOf((*MockCryptoSetup)(nil).StartHandshake))
 }
+
+// UseWorkerPool mocks base method.
+func (m *MockCryptoSetup) UseWorkerPool(arg0 *handshake.WorkerPool, arg1 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UseWorkerPool", arg0, arg1)
+}
+
+// UseWorkerPool indicates an expected call of UseWorkerPool.
+func (mr *MockCryptoSetupMockRecorder) UseWorkerPool(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseWorkerPool", reflect.TypeOf((*MockCryptoSetup)(nil).UseWorkerPool), arg0, arg1)
+}
@@ -68,6 +68,27 @@ func NewMockConnectionTracer(ctrl *gomock.Controller) (*logging.ConnectionTracer
 		LostPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, reason logging.PacketLossReason) {
 			t.LostPacket(encLevel, pn, reason)
 		},
+		LostFrames: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, frames []logging.Frame) {
+			t.LostFrames(encLevel, pn, frames)
+		},
+		RetransmittedStreamData: func(id logging.StreamID, offset, length logging.ByteCount) {
+			t.RetransmittedStreamData(id, offset, length)
+		},
+		EnteredPersistentCongestion: func() {
+			t.EnteredPersistentCongestion()
+		},
+		ReachedHandshakeMilestone: func(milestone logging.HandshakeMilestone, tm time.Time) {
+			t.ReachedHandshakeMilestone(milestone, tm)
+		},
+		FailedHandshake: func(reason logging.HandshakeFailureReason, encLevel logging.EncryptionLevel, err error) {
+			t.FailedHandshake(reason, encLevel, err)
+		},
+		IssuedConnectionID: func(seq uint64, connID logging.ConnectionID, retirePriorTo uint64) {
+			t.IssuedConnectionID(seq, connID, retirePriorTo)
+		},
+		RetiredConnectionID: func(seq uint64, connID logging.ConnectionID) {
+			t.RetiredConnectionID(seq, connID)
+		},
 		UpdatedCongestionState: func(state logging.CongestionState) {
 			t.UpdatedCongestionState(state)
 		},
@@ -80,6 +101,9 @@ func NewMockConnectionTracer(ctrl *gomock.Controller) (*logging.ConnectionTracer
 		UpdatedKey: func(generation logging.KeyPhase, remote bool) {
 			t.UpdatedKey(generation, remote)
 		},
+		InitiatedKeyUpdate: func(generation logging.KeyPhase, reason logging.KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent logging.ByteCount) {
+			t.InitiatedKeyUpdate(generation, reason, packetsSent, packetsReceived, bytesSent)
+		},
 		DroppedEncryptionLevel: func(encLevel logging.EncryptionLevel) {
 			t.DroppedEncryptionLevel(encLevel)
 		},
@@ -98,6 +122,12 @@ func NewMockConnectionTracer(ctrl *gomock.Controller) (*logging.ConnectionTracer
 		ECNStateUpdated: func(state logging.ECNState, trigger logging.ECNStateTrigger) {
 			t.ECNStateUpdated(state, trigger)
 		},
+		DetectedMTUBlackHole: func(oldSize, newSize logging.ByteCount) {
+			t.DetectedMTUBlackHole(oldSize, newSize)
+		},
+		DroppedDatagram: func(length logging.ByteCount) {
+			t.DroppedDatagram(length)
+		},
 		Close: func() {
 			t.Close()
 		},
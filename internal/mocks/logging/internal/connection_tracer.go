@@ -14,6 +14,7 @@ import (
 	time "time"
 
 	protocol "github.com/quic-go/quic-go/internal/protocol"
+	qerr "github.com/quic-go/quic-go/internal/qerr"
 	utils "github.com/quic-go/quic-go/internal/utils"
 	wire "github.com/quic-go/quic-go/internal/wire"
 	logging "github.com/quic-go/quic-go/logging"
@@ -103,6 +104,30 @@ func (mr *MockConnectionTracerMockRecorder) Debug(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debug", reflect.TypeOf((*MockConnectionTracer)(nil).Debug), arg0, arg1)
 }
 
+// DetectedMTUBlackHole mocks base method.
+func (m *MockConnectionTracer) DetectedMTUBlackHole(arg0, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DetectedMTUBlackHole", arg0, arg1)
+}
+
+// DetectedMTUBlackHole indicates an expected call of DetectedMTUBlackHole.
+func (mr *MockConnectionTracerMockRecorder) DetectedMTUBlackHole(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectedMTUBlackHole", reflect.TypeOf((*MockConnectionTracer)(nil).DetectedMTUBlackHole), arg0, arg1)
+}
+
+// DroppedDatagram mocks base method.
+func (m *MockConnectionTracer) DroppedDatagram(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DroppedDatagram", arg0)
+}
+
+// DroppedDatagram indicates an expected call of DroppedDatagram.
+func (mr *MockConnectionTracerMockRecorder) DroppedDatagram(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedDatagram", reflect.TypeOf((*MockConnectionTracer)(nil).DroppedDatagram), arg0)
+}
+
 // DroppedEncryptionLevel mocks base method.
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -151,6 +176,54 @@ func (mr *MockConnectionTracerMockRecorder) ECNStateUpdated(arg0, arg1 any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ECNStateUpdated", reflect.TypeOf((*MockConnectionTracer)(nil).ECNStateUpdated), arg0, arg1)
 }
 
+// EnteredPersistentCongestion mocks base method.
+func (m *MockConnectionTracer) EnteredPersistentCongestion() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EnteredPersistentCongestion")
+}
+
+// EnteredPersistentCongestion indicates an expected call of EnteredPersistentCongestion.
+func (mr *MockConnectionTracerMockRecorder) EnteredPersistentCongestion() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnteredPersistentCongestion", reflect.TypeOf((*MockConnectionTracer)(nil).EnteredPersistentCongestion))
+}
+
+// FailedHandshake mocks base method.
+func (m *MockConnectionTracer) FailedHandshake(arg0 qerr.HandshakeFailureReason, arg1 protocol.EncryptionLevel, arg2 error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FailedHandshake", arg0, arg1, arg2)
+}
+
+// FailedHandshake indicates an expected call of FailedHandshake.
+func (mr *MockConnectionTracerMockRecorder) FailedHandshake(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailedHandshake", reflect.TypeOf((*MockConnectionTracer)(nil).FailedHandshake), arg0, arg1, arg2)
+}
+
+// InitiatedKeyUpdate mocks base method.
+func (m *MockConnectionTracer) InitiatedKeyUpdate(arg0 protocol.KeyPhase, arg1 logging.KeyUpdateReason, arg2, arg3 uint64, arg4 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InitiatedKeyUpdate", arg0, arg1, arg2, arg3, arg4)
+}
+
+// InitiatedKeyUpdate indicates an expected call of InitiatedKeyUpdate.
+func (mr *MockConnectionTracerMockRecorder) InitiatedKeyUpdate(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitiatedKeyUpdate", reflect.TypeOf((*MockConnectionTracer)(nil).InitiatedKeyUpdate), arg0, arg1, arg2, arg3, arg4)
+}
+
+// IssuedConnectionID mocks base method.
+func (m *MockConnectionTracer) IssuedConnectionID(arg0 uint64, arg1 protocol.ConnectionID, arg2 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "IssuedConnectionID", arg0, arg1, arg2)
+}
+
+// IssuedConnectionID indicates an expected call of IssuedConnectionID.
+func (mr *MockConnectionTracerMockRecorder) IssuedConnectionID(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssuedConnectionID", reflect.TypeOf((*MockConnectionTracer)(nil).IssuedConnectionID), arg0, arg1, arg2)
+}
+
 // LossTimerCanceled mocks base method.
 func (m *MockConnectionTracer) LossTimerCanceled() {
 	m.ctrl.T.Helper()
@@ -175,6 +248,18 @@ func (mr *MockConnectionTracerMockRecorder) LossTimerExpired(arg0, arg1 any) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LossTimerExpired", reflect.TypeOf((*MockConnectionTracer)(nil).LossTimerExpired), arg0, arg1)
 }
 
+// LostFrames mocks base method.
+func (m *MockConnectionTracer) LostFrames(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber, arg2 []logging.Frame) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LostFrames", arg0, arg1, arg2)
+}
+
+// LostFrames indicates an expected call of LostFrames.
+func (mr *MockConnectionTracerMockRecorder) LostFrames(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LostFrames", reflect.TypeOf((*MockConnectionTracer)(nil).LostFrames), arg0, arg1, arg2)
+}
+
 // LostPacket mocks base method.
 func (m *MockConnectionTracer) LostPacket(arg0 protocol.EncryptionLevel, arg1 protocol.PacketNumber, arg2 logging.PacketLossReason) {
 	m.ctrl.T.Helper()
@@ -199,6 +284,18 @@ func (mr *MockConnectionTracerMockRecorder) NegotiatedVersion(arg0, arg1, arg2 a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedVersion", reflect.TypeOf((*MockConnectionTracer)(nil).NegotiatedVersion), arg0, arg1, arg2)
 }
 
+// ReachedHandshakeMilestone mocks base method.
+func (m *MockConnectionTracer) ReachedHandshakeMilestone(arg0 logging.HandshakeMilestone, arg1 time.Time) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReachedHandshakeMilestone", arg0, arg1)
+}
+
+// ReachedHandshakeMilestone indicates an expected call of ReachedHandshakeMilestone.
+func (mr *MockConnectionTracerMockRecorder) ReachedHandshakeMilestone(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReachedHandshakeMilestone", reflect.TypeOf((*MockConnectionTracer)(nil).ReachedHandshakeMilestone), arg0, arg1)
+}
+
 // ReceivedLongHeaderPacket mocks base method.
 func (m *MockConnectionTracer) ReceivedLongHeaderPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 protocol.ECN, arg3 []logging.Frame) {
 	m.ctrl.T.Helper()
@@ -271,6 +368,30 @@ func (mr *MockConnectionTracerMockRecorder) RestoredTransportParameters(arg0 any
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoredTransportParameters", reflect.TypeOf((*MockConnectionTracer)(nil).RestoredTransportParameters), arg0)
 }
 
+// RetiredConnectionID mocks base method.
+func (m *MockConnectionTracer) RetiredConnectionID(arg0 uint64, arg1 protocol.ConnectionID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RetiredConnectionID", arg0, arg1)
+}
+
+// RetiredConnectionID indicates an expected call of RetiredConnectionID.
+func (mr *MockConnectionTracerMockRecorder) RetiredConnectionID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetiredConnectionID", reflect.TypeOf((*MockConnectionTracer)(nil).RetiredConnectionID), arg0, arg1)
+}
+
+// RetransmittedStreamData mocks base method.
+func (m *MockConnectionTracer) RetransmittedStreamData(arg0 protocol.StreamID, arg1, arg2 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RetransmittedStreamData", arg0, arg1, arg2)
+}
+
+// RetransmittedStreamData indicates an expected call of RetransmittedStreamData.
+func (mr *MockConnectionTracerMockRecorder) RetransmittedStreamData(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetransmittedStreamData", reflect.TypeOf((*MockConnectionTracer)(nil).RetransmittedStreamData), arg0, arg1, arg2)
+}
+
 // SentLongHeaderPacket mocks base method.
 func (m *MockConnectionTracer) SentLongHeaderPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 protocol.ECN, arg3 *wire.AckFrame, arg4 []logging.Frame) {
 	m.ctrl.T.Helper()
@@ -53,6 +53,18 @@ func (mr *MockTracerMockRecorder) DroppedPacket(arg0, arg1, arg2, arg3 any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedPacket", reflect.TypeOf((*MockTracer)(nil).DroppedPacket), arg0, arg1, arg2, arg3)
 }
 
+// GSODisabled mocks base method.
+func (m *MockTracer) GSODisabled(arg0 logging.GSODisabledReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GSODisabled", arg0)
+}
+
+// GSODisabled indicates an expected call of GSODisabled.
+func (mr *MockTracerMockRecorder) GSODisabled(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GSODisabled", reflect.TypeOf((*MockTracer)(nil).GSODisabled), arg0)
+}
+
 // SentPacket mocks base method.
 func (m *MockTracer) SentPacket(arg0 net.Addr, arg1 *wire.Header, arg2 protocol.ByteCount, arg3 []logging.Frame) {
 	m.ctrl.T.Helper()
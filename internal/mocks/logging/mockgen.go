@@ -14,6 +14,7 @@ type Tracer interface {
 	SentPacket(net.Addr, *logging.Header, logging.ByteCount, []logging.Frame)
 	SentVersionNegotiationPacket(_ net.Addr, dest, src logging.ArbitraryLenConnectionID, _ []logging.VersionNumber)
 	DroppedPacket(net.Addr, logging.PacketType, logging.ByteCount, logging.PacketDropReason)
+	GSODisabled(logging.GSODisabledReason)
 }
 
 //go:generate sh -c "go run go.uber.org/mock/mockgen -build_flags=\"-tags=gomock\" -package internal -destination internal/connection_tracer.go github.com/quic-go/quic-go/internal/mocks/logging ConnectionTracer"
@@ -39,12 +40,22 @@ type ConnectionTracer interface {
 	UpdatedPTOCount(value uint32)
 	UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)
 	UpdatedKey(generation logging.KeyPhase, remote bool)
+	InitiatedKeyUpdate(generation logging.KeyPhase, reason logging.KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent logging.ByteCount)
 	DroppedEncryptionLevel(logging.EncryptionLevel)
 	DroppedKey(generation logging.KeyPhase)
 	SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time)
 	LossTimerExpired(logging.TimerType, logging.EncryptionLevel)
 	LossTimerCanceled()
 	ECNStateUpdated(state logging.ECNState, trigger logging.ECNStateTrigger)
+	DetectedMTUBlackHole(oldSize, newSize logging.ByteCount)
+	DroppedDatagram(length logging.ByteCount)
+	LostFrames(logging.EncryptionLevel, logging.PacketNumber, []logging.Frame)
+	RetransmittedStreamData(id logging.StreamID, offset, length logging.ByteCount)
+	EnteredPersistentCongestion()
+	ReachedHandshakeMilestone(logging.HandshakeMilestone, time.Time)
+	FailedHandshake(reason logging.HandshakeFailureReason, encLevel logging.EncryptionLevel, err error)
+	IssuedConnectionID(seq uint64, connID logging.ConnectionID, retirePriorTo uint64)
+	RetiredConnectionID(seq uint64, connID logging.ConnectionID)
 	// Close is called when the connection is closed.
 	Close()
 	Debug(name, msg string)
@@ -12,6 +12,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	quic "github.com/quic-go/quic-go"
 	qerr "github.com/quic-go/quic-go/internal/qerr"
@@ -85,6 +86,20 @@ func (mr *MockEarlyConnectionMockRecorder) CloseWithError(arg0, arg1 any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockEarlyConnection)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithTimeout mocks base method.
+func (m *MockEarlyConnection) CloseWithTimeout(arg0 qerr.ApplicationErrorCode, arg1 string, arg2 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithTimeout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithTimeout indicates an expected call of CloseWithTimeout.
+func (mr *MockEarlyConnectionMockRecorder) CloseWithTimeout(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithTimeout", reflect.TypeOf((*MockEarlyConnection)(nil).CloseWithTimeout), arg0, arg1, arg2)
+}
+
 // ConnectionState mocks base method.
 func (m *MockEarlyConnection) ConnectionState() quic.ConnectionState {
 	m.ctrl.T.Helper()
@@ -127,6 +142,20 @@ func (mr *MockEarlyConnectionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlyConnection)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockEarlyConnection) HandshakeConfirmed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockEarlyConnectionMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockEarlyConnection)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlyConnection) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -155,6 +184,21 @@ func (mr *MockEarlyConnectionMockRecorder) NextConnection() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextConnection", reflect.TypeOf((*MockEarlyConnection)(nil).NextConnection))
 }
 
+// ObservedAddress mocks base method.
+func (m *MockEarlyConnection) ObservedAddress(arg0 context.Context) (net.Addr, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ObservedAddress", arg0)
+	ret0, _ := ret[0].(net.Addr)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ObservedAddress indicates an expected call of ObservedAddress.
+func (mr *MockEarlyConnectionMockRecorder) ObservedAddress(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObservedAddress", reflect.TypeOf((*MockEarlyConnection)(nil).ObservedAddress), arg0)
+}
+
 // OpenStream mocks base method.
 func (m *MockEarlyConnection) OpenStream() (quic.Stream, error) {
 	m.ctrl.T.Helper()
@@ -215,6 +259,63 @@ func (mr *MockEarlyConnectionMockRecorder) OpenUniStreamSync(arg0 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlyConnection)(nil).OpenUniStreamSync), arg0)
 }
 
+// Ping mocks base method.
+func (m *MockEarlyConnection) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockEarlyConnectionMockRecorder) Ping(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockEarlyConnection)(nil).Ping), arg0)
+}
+
+// ProbeMTU mocks base method.
+func (m *MockEarlyConnection) ProbeMTU(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProbeMTU", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProbeMTU indicates an expected call of ProbeMTU.
+func (mr *MockEarlyConnectionMockRecorder) ProbeMTU(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProbeMTU", reflect.TypeOf((*MockEarlyConnection)(nil).ProbeMTU), arg0, arg1)
+}
+
+// RTTStats mocks base method.
+func (m *MockEarlyConnection) RTTStats() quic.RTTStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RTTStats")
+	ret0, _ := ret[0].(quic.RTTStats)
+	return ret0
+}
+
+// RTTStats indicates an expected call of RTTStats.
+func (mr *MockEarlyConnectionMockRecorder) RTTStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RTTStats", reflect.TypeOf((*MockEarlyConnection)(nil).RTTStats))
+}
+
+// Rebind mocks base method.
+func (m *MockEarlyConnection) Rebind(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebind", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebind indicates an expected call of Rebind.
+func (mr *MockEarlyConnectionMockRecorder) Rebind(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebind", reflect.TypeOf((*MockEarlyConnection)(nil).Rebind), arg0)
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockEarlyConnection) ReceiveMessage(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -244,6 +345,20 @@ func (mr *MockEarlyConnectionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlyConnection)(nil).RemoteAddr))
 }
 
+// SendExtensionFrame mocks base method.
+func (m *MockEarlyConnection) SendExtensionFrame(arg0 uint64, arg1 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendExtensionFrame", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendExtensionFrame indicates an expected call of SendExtensionFrame.
+func (mr *MockEarlyConnectionMockRecorder) SendExtensionFrame(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendExtensionFrame", reflect.TypeOf((*MockEarlyConnection)(nil).SendExtensionFrame), arg0, arg1)
+}
+
 // SendMessage mocks base method.
 func (m *MockEarlyConnection) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -257,3 +372,27 @@ func (mr *MockEarlyConnectionMockRecorder) SendMessage(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlyConnection)(nil).SendMessage), arg0)
 }
+
+// SetIdleTimeout mocks base method.
+func (m *MockEarlyConnection) SetIdleTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIdleTimeout", arg0)
+}
+
+// SetIdleTimeout indicates an expected call of SetIdleTimeout.
+func (mr *MockEarlyConnectionMockRecorder) SetIdleTimeout(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdleTimeout", reflect.TypeOf((*MockEarlyConnection)(nil).SetIdleTimeout), arg0)
+}
+
+// SetSendRateLimit mocks base method.
+func (m *MockEarlyConnection) SetSendRateLimit(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0, arg1)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockEarlyConnectionMockRecorder) SetSendRateLimit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockEarlyConnection)(nil).SetSendRateLimit), arg0, arg1)
+}
@@ -13,6 +13,7 @@ import (
 	reflect "reflect"
 	time "time"
 
+	quic "github.com/quic-go/quic-go"
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	qerr "github.com/quic-go/quic-go/internal/qerr"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +42,20 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// AvailableSendWindow mocks base method.
+func (m *MockStream) AvailableSendWindow() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AvailableSendWindow")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AvailableSendWindow indicates an expected call of AvailableSendWindow.
+func (mr *MockStreamMockRecorder) AvailableSendWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AvailableSendWindow", reflect.TypeOf((*MockStream)(nil).AvailableSendWindow))
+}
+
 // CancelRead mocks base method.
 func (m *MockStream) CancelRead(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -93,6 +108,20 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// EarlyData mocks base method.
+func (m *MockStream) EarlyData() []quic.EarlyDataRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyData")
+	ret0, _ := ret[0].([]quic.EarlyDataRange)
+	return ret0
+}
+
+// EarlyData indicates an expected call of EarlyData.
+func (mr *MockStreamMockRecorder) EarlyData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyData", reflect.TypeOf((*MockStream)(nil).EarlyData))
+}
+
 // Read mocks base method.
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -108,6 +137,18 @@ func (mr *MockStreamMockRecorder) Read(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// SetBlockedCallback mocks base method.
+func (m *MockStream) SetBlockedCallback(arg0 func(quic.SendBlockedReason, bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBlockedCallback", arg0)
+}
+
+// SetBlockedCallback indicates an expected call of SetBlockedCallback.
+func (mr *MockStreamMockRecorder) SetBlockedCallback(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlockedCallback", reflect.TypeOf((*MockStream)(nil).SetBlockedCallback), arg0)
+}
+
 // SetDeadline mocks base method.
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -136,6 +177,18 @@ func (mr *MockStreamMockRecorder) SetReadDeadline(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStream)(nil).SetReadDeadline), arg0)
 }
 
+// SetSendRateLimit mocks base method.
+func (m *MockStream) SetSendRateLimit(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0, arg1)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockStreamMockRecorder) SetSendRateLimit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockStream)(nil).SetSendRateLimit), arg0, arg1)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStream) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -178,3 +231,18 @@ func (mr *MockStreamMockRecorder) Write(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStream)(nil).Write), arg0)
 }
+
+// WriteBuffer mocks base method.
+func (m *MockStream) WriteBuffer(arg0 []byte, arg1 func()) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffer", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffer indicates an expected call of WriteBuffer.
+func (mr *MockStreamMockRecorder) WriteBuffer(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffer", reflect.TypeOf((*MockStream)(nil).WriteBuffer), arg0, arg1)
+}
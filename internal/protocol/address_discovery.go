@@ -0,0 +1,41 @@
+package protocol
+
+// AddressDiscoveryMode is the mode of the QUIC Address Discovery extension (draft-ietf-quic-address-discovery),
+// as advertised in the address_discovery transport parameter.
+type AddressDiscoveryMode uint8
+
+const (
+	// AddressDiscoveryDisabled means that the extension is not used.
+	AddressDiscoveryDisabled AddressDiscoveryMode = iota
+	// AddressDiscoveryProvide means that the endpoint is willing to report the address it observes for the peer.
+	AddressDiscoveryProvide
+	// AddressDiscoveryReceive means that the endpoint wants to learn the address that the peer observes for it.
+	AddressDiscoveryReceive
+	// AddressDiscoveryProvideAndReceive combines AddressDiscoveryProvide and AddressDiscoveryReceive.
+	AddressDiscoveryProvideAndReceive
+)
+
+// Provides says if the endpoint sends OBSERVED_ADDRESS frames.
+func (m AddressDiscoveryMode) Provides() bool {
+	return m == AddressDiscoveryProvide || m == AddressDiscoveryProvideAndReceive
+}
+
+// Receives says if the endpoint accepts OBSERVED_ADDRESS frames.
+func (m AddressDiscoveryMode) Receives() bool {
+	return m == AddressDiscoveryReceive || m == AddressDiscoveryProvideAndReceive
+}
+
+func (m AddressDiscoveryMode) String() string {
+	switch m {
+	case AddressDiscoveryDisabled:
+		return "disabled"
+	case AddressDiscoveryProvide:
+		return "provide"
+	case AddressDiscoveryReceive:
+		return "receive"
+	case AddressDiscoveryProvideAndReceive:
+		return "provide and receive"
+	default:
+		return "invalid address discovery mode"
+	}
+}
@@ -0,0 +1,27 @@
+package protocol
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Address Discovery Mode", func() {
+	It("has a string representation", func() {
+		Expect(AddressDiscoveryDisabled.String()).To(Equal("disabled"))
+		Expect(AddressDiscoveryProvide.String()).To(Equal("provide"))
+		Expect(AddressDiscoveryReceive.String()).To(Equal("receive"))
+		Expect(AddressDiscoveryProvideAndReceive.String()).To(Equal("provide and receive"))
+		Expect(AddressDiscoveryMode(42).String()).To(Equal("invalid address discovery mode"))
+	})
+
+	It("says if OBSERVED_ADDRESS frames are sent and received", func() {
+		Expect(AddressDiscoveryDisabled.Provides()).To(BeFalse())
+		Expect(AddressDiscoveryDisabled.Receives()).To(BeFalse())
+		Expect(AddressDiscoveryProvide.Provides()).To(BeTrue())
+		Expect(AddressDiscoveryProvide.Receives()).To(BeFalse())
+		Expect(AddressDiscoveryReceive.Provides()).To(BeFalse())
+		Expect(AddressDiscoveryReceive.Receives()).To(BeTrue())
+		Expect(AddressDiscoveryProvideAndReceive.Provides()).To(BeTrue())
+		Expect(AddressDiscoveryProvideAndReceive.Receives()).To(BeTrue())
+	})
+})
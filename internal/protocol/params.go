@@ -36,6 +36,10 @@ const DefaultMaxReceiveStreamFlowControlWindow = 6 * (1 << 20) // 6 MB
 // DefaultMaxReceiveConnectionFlowControlWindow is the default connection-level flow control window for receiving data
 const DefaultMaxReceiveConnectionFlowControlWindow = 15 * (1 << 20) // 15 MB
 
+// MemoryBudgetPressureThreshold is the fraction of the memory budget that has to be used
+// before connections start applying backpressure.
+const MemoryBudgetPressureThreshold = 0.75
+
 // WindowUpdateThreshold is the fraction of the receive window that has to be consumed before an higher offset is advertised to the client
 const WindowUpdateThreshold = 0.25
 
@@ -58,6 +62,10 @@ const SkipPacketInitialPeriod PacketNumber = 256
 // SkipPacketMaxPeriod is the maximum period length used for packet number skipping.
 const SkipPacketMaxPeriod PacketNumber = 128 * 1024
 
+// MaxQueuedConnectionAttempts is the maximum number of connection attempts that the server queues
+// when the connection limits are reached.
+const MaxQueuedConnectionAttempts = 128
+
 // MaxAcceptQueueSize is the maximum number of connections that the server queues for accepting.
 // If the queue is full, new connection attempts will be rejected.
 const MaxAcceptQueueSize = 32
@@ -123,6 +131,10 @@ const MinStreamFrameSize ByteCount = 128
 // we send after the handshake completes.
 const MaxPostHandshakeCryptoFrameSize = 1000
 
+// MaxExtensionFrameSize is the maximum size of extension frames.
+// This makes sure that every extension frame fits into a single packet.
+const MaxExtensionFrameSize ByteCount = 1000
+
 // MaxAckFrameSize is the maximum size for an ACK frame that we write
 // Due to the varint encoding, ACK frames can grow (almost) indefinitely large.
 // The MaxAckFrameSize should be large enough to encode many ACK range,
@@ -138,9 +150,15 @@ const DatagramRcvQueueLen = 128
 
 // MaxNumAckRanges is the maximum number of ACK ranges that we send in an ACK frame.
 // It also serves as a limit for the packet history.
-// If at any point we keep track of more ranges, old ranges are discarded.
+// If at any point we keep track of more ranges, ranges are discarded.
+// It can be changed for the application data packet number space using the config.
 const MaxNumAckRanges = 32
 
+// MaxNumAckRangesLimit is the maximum value for the configurable number of ACK ranges.
+// Tracking too many ACK ranges makes processing received packets expensive,
+// and ACK frames are limited to MaxAckFrameSize anyway.
+const MaxNumAckRangesLimit = 256
+
 // MinPacingDelay is the minimum duration that is used for packet pacing
 // If the packet packing frequency is higher, multiple packets might be sent at once.
 // Example: For a packet pacing delay of 200μs, we would send 5 packets at once, wait for 1ms, and so forth.
@@ -150,10 +168,14 @@ const MinPacingDelay = time.Millisecond
 // if no other value is configured.
 const DefaultConnectionIDLength = 4
 
-// MaxActiveConnectionIDs is the number of connection IDs that we're storing.
+// MaxActiveConnectionIDs is the default number of connection IDs that we're storing.
+// This is the value of the active_connection_id_limit transport parameter we send.
 const MaxActiveConnectionIDs = 4
 
-// MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
+// MaxActiveConnectionIDLimit is the maximum value of the active_connection_id_limit transport parameter that can be configured.
+const MaxActiveConnectionIDLimit = 64
+
+// MaxIssuedConnectionIDs is the default maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
 // PacketsPerConnectionID is the number of packets we send using one connection ID.
@@ -167,6 +189,10 @@ const AckDelayExponent = 3
 // The loss detection timer will not be set to a value smaller than granularity.
 const TimerGranularity = time.Millisecond
 
+// LowPowerTimerGranularity is the granularity of the connection's timer in low-power mode,
+// if no timer granularity is configured.
+const LowPowerTimerGranularity = 25 * time.Millisecond
+
 // MaxAckDelay is the maximum time by which we delay sending ACKs.
 const MaxAckDelay = 25 * time.Millisecond
 
@@ -180,6 +206,10 @@ const KeyUpdateInterval = 100 * 1000
 // Max0RTTQueueingDuration is the maximum time that we store 0-RTT packets in order to wait for the corresponding Initial to be received.
 const Max0RTTQueueingDuration = 100 * time.Millisecond
 
+// DefaultRetransmissionDeadline is the time after which lost STREAM data is considered stale,
+// if the deadline-aware retransmission policy is used.
+const DefaultRetransmissionDeadline = 200 * time.Millisecond
+
 // Max0RTTQueues is the maximum number of connections that we buffer 0-RTT packets for.
 const Max0RTTQueues = 32
 
@@ -188,3 +218,6 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxConnUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the connection, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// MaxRTTSamples is the number of recent RTT samples that are kept for every connection.
+const MaxRTTSamples = 64
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -150,3 +151,15 @@ const InvalidPacketLimitAES = 1 << 52
 
 // InvalidPacketLimitChaCha is the maximum number of packets that we can fail to decrypt when using AEAD_CHACHA20_POLY1305.
 const InvalidPacketLimitChaCha = 1 << 36
+
+// ConfidentialityLimitAES is the maximum number of packets that we can encrypt with a single key when using
+// AEAD_AES_128_GCM or AEAD_AES_265_GCM.
+const ConfidentialityLimitAES = 1 << 23
+
+// ConfidentialityLimitChaCha is the maximum number of packets that we can encrypt with a single key when using AEAD_CHACHA20_POLY1305.
+// The limit is larger than the number of possible packet numbers.
+const ConfidentialityLimitChaCha = math.MaxUint64
+
+// AEADLimitMarginDivisor determines the safety margin applied to the confidentiality and integrity limits:
+// Keys are updated (or the connection is closed) once the limit minus 1/AEADLimitMarginDivisor of the limit is reached.
+const AEADLimitMarginDivisor = 16
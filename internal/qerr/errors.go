@@ -1,6 +1,8 @@
 package qerr
 
 import (
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net"
 
@@ -12,6 +14,9 @@ var (
 	ErrIdleTimeout      = &IdleTimeoutError{}
 )
 
+// A TransportError is a connection error using a transport error code (see section 20.1 of RFC 9000).
+// It is either caused by the peer (closing the connection with a CONNECTION_CLOSE frame of type 0x1c),
+// or by us, e.g. when the peer violated the protocol.
 type TransportError struct {
 	Remote       bool
 	FrameType    uint64
@@ -48,8 +53,18 @@ func (e *TransportError) Error() string {
 	return str + ": " + msg
 }
 
+// Is reports if the error matches target.
+// A TransportError matches net.ErrClosed, and any TransportError with the same error code.
+// If the target sets a frame type, the frame type needs to match as well.
 func (e *TransportError) Is(target error) bool {
-	return target == net.ErrClosed
+	if target == net.ErrClosed {
+		return true
+	}
+	t, ok := target.(*TransportError)
+	if !ok {
+		return false
+	}
+	return t.ErrorCode == e.ErrorCode && (t.FrameType == 0 || t.FrameType == e.FrameType)
 }
 
 func (e *TransportError) Unwrap() error {
@@ -59,13 +74,22 @@ func (e *TransportError) Unwrap() error {
 // An ApplicationErrorCode is an application-defined error code.
 type ApplicationErrorCode uint64
 
+// Is reports if the error matches target.
+// An ApplicationError matches net.ErrClosed, and any ApplicationError with the same error code.
 func (e *ApplicationError) Is(target error) bool {
-	return target == net.ErrClosed
+	if target == net.ErrClosed {
+		return true
+	}
+	t, ok := target.(*ApplicationError)
+	return ok && t.ErrorCode == e.ErrorCode
 }
 
 // A StreamErrorCode is an error code used to cancel streams.
 type StreamErrorCode uint64
 
+// An ApplicationError is a connection error using an application-defined error code.
+// It is either caused by the peer (closing the connection with a CONNECTION_CLOSE frame of type 0x1d),
+// or by us, when the application calls Connection.CloseWithError.
 type ApplicationError struct {
 	Remote       bool
 	ErrorCode    ApplicationErrorCode
@@ -81,23 +105,31 @@ func (e *ApplicationError) Error() string {
 	return fmt.Sprintf("Application error %#x (%s): %s", e.ErrorCode, getRole(e.Remote), e.ErrorMessage)
 }
 
+// An IdleTimeoutError occurs when the connection is closed because of the idle timeout.
 type IdleTimeoutError struct{}
 
 var _ error = &IdleTimeoutError{}
 
-func (e *IdleTimeoutError) Timeout() bool        { return true }
-func (e *IdleTimeoutError) Temporary() bool      { return false }
-func (e *IdleTimeoutError) Error() string        { return "timeout: no recent network activity" }
-func (e *IdleTimeoutError) Is(target error) bool { return target == net.ErrClosed }
+func (e *IdleTimeoutError) Timeout() bool   { return true }
+func (e *IdleTimeoutError) Temporary() bool { return false }
+func (e *IdleTimeoutError) Error() string   { return "timeout: no recent network activity" }
+func (e *IdleTimeoutError) Is(target error) bool {
+	_, ok := target.(*IdleTimeoutError)
+	return ok || target == net.ErrClosed
+}
 
+// A HandshakeTimeoutError occurs when the handshake doesn't complete within the handshake timeout.
 type HandshakeTimeoutError struct{}
 
 var _ error = &HandshakeTimeoutError{}
 
-func (e *HandshakeTimeoutError) Timeout() bool        { return true }
-func (e *HandshakeTimeoutError) Temporary() bool      { return false }
-func (e *HandshakeTimeoutError) Error() string        { return "timeout: handshake did not complete in time" }
-func (e *HandshakeTimeoutError) Is(target error) bool { return target == net.ErrClosed }
+func (e *HandshakeTimeoutError) Timeout() bool   { return true }
+func (e *HandshakeTimeoutError) Temporary() bool { return false }
+func (e *HandshakeTimeoutError) Error() string   { return "timeout: handshake did not complete in time" }
+func (e *HandshakeTimeoutError) Is(target error) bool {
+	_, ok := target.(*HandshakeTimeoutError)
+	return ok || target == net.ErrClosed
+}
 
 // A VersionNegotiationError occurs when the client and the server can't agree on a QUIC version.
 type VersionNegotiationError struct {
@@ -110,7 +142,82 @@ func (e *VersionNegotiationError) Error() string {
 }
 
 func (e *VersionNegotiationError) Is(target error) bool {
-	return target == net.ErrClosed
+	_, ok := target.(*VersionNegotiationError)
+	return ok || target == net.ErrClosed
+}
+
+// A HandshakeFailureReason classifies why a handshake failed.
+type HandshakeFailureReason uint8
+
+const (
+	// HandshakeFailureOther is used for failures that don't fall into any of the other categories,
+	// for example when the application closes the connection during the handshake.
+	HandshakeFailureOther HandshakeFailureReason = iota
+	// HandshakeFailureNoCompatibleVersion is used when client and server don't support a common QUIC version.
+	HandshakeFailureNoCompatibleVersion
+	// HandshakeFailureALPNMismatch is used when client and server don't support a common application protocol.
+	HandshakeFailureALPNMismatch
+	// HandshakeFailureCertificate is used when either endpoint rejected the certificate (chain) presented by its peer.
+	HandshakeFailureCertificate
+	// HandshakeFailureTimeout is used when the handshake didn't complete in time.
+	HandshakeFailureTimeout
+	// HandshakeFailureAmplificationLimited is used when the handshake didn't complete in time,
+	// and the server was blocked by the anti-amplification limit (see section 8.1 of RFC 9000).
+	// This happens if the server's flight is lost, and the client doesn't send enough data to unblock the server.
+	HandshakeFailureAmplificationLimited
+)
+
+func (r HandshakeFailureReason) String() string {
+	switch r {
+	case HandshakeFailureOther:
+		return "other"
+	case HandshakeFailureNoCompatibleVersion:
+		return "no compatible version"
+	case HandshakeFailureALPNMismatch:
+		return "ALPN mismatch"
+	case HandshakeFailureCertificate:
+		return "certificate"
+	case HandshakeFailureTimeout:
+		return "timeout"
+	case HandshakeFailureAmplificationLimited:
+		return "amplification limited"
+	default:
+		return fmt.Sprintf("unknown handshake failure reason: %d", uint8(r))
+	}
+}
+
+// A HandshakeError occurs when a connection is closed before the handshake completed.
+// It wraps the error that caused the connection to be closed.
+type HandshakeError struct {
+	Reason HandshakeFailureReason
+	// EncryptionLevel is the encryption level that the handshake reached:
+	// Handshake if a Handshake packet was received from the peer, Initial otherwise.
+	// For a timeout, this is the encryption level at which the handshake stalled.
+	EncryptionLevel protocol.EncryptionLevel
+	// Certificates is the certificate chain presented by the peer.
+	// It is only set if the chain was rejected by this endpoint.
+	Certificates []*x509.Certificate
+	Err          error
+}
+
+var _ net.Error = &HandshakeError{}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("handshake failed (%s, %s): %s", e.Reason, e.EncryptionLevel, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error { return e.Err }
+
+// Timeout and Temporary are forwarded to the wrapped error, if it is a net.Error.
+func (e *HandshakeError) Timeout() bool {
+	var nerr net.Error
+	return errors.As(e.Err, &nerr) && nerr.Timeout()
+}
+
+func (e *HandshakeError) Temporary() bool {
+	var nerr net.Error
+	//nolint:staticcheck // SA1019 Temporary is deprecated, but part of the net.Error interface
+	return errors.As(e.Err, &nerr) && nerr.Temporary()
 }
 
 // A StatelessResetError occurs when we receive a stateless reset.
@@ -124,8 +231,15 @@ func (e *StatelessResetError) Error() string {
 	return fmt.Sprintf("received a stateless reset with token %x", e.Token)
 }
 
+// Is reports if the error matches target.
+// A StatelessResetError matches net.ErrClosed, and a StatelessResetError with the same token.
+// A StatelessResetError with a zero token matches every stateless reset.
 func (e *StatelessResetError) Is(target error) bool {
-	return target == net.ErrClosed
+	if target == net.ErrClosed {
+		return true
+	}
+	t, ok := target.(*StatelessResetError)
+	return ok && (t.Token == protocol.StatelessResetToken{} || t.Token == e.Token)
 }
 
 func (e *StatelessResetError) Timeout() bool   { return false }
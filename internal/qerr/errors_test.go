@@ -114,6 +114,45 @@ var _ = Describe("QUIC Errors", func() {
 		})
 	})
 
+	Context("Handshake errors", func() {
+		It("has a string representation", func() {
+			Expect((&HandshakeError{
+				Reason:          HandshakeFailureALPNMismatch,
+				EncryptionLevel: protocol.EncryptionHandshake,
+				Err:             NewLocalCryptoError(120, errors.New("no application protocol")),
+			}).Error()).To(Equal("handshake failed (ALPN mismatch, Handshake): CRYPTO_ERROR 0x178 (local): no application protocol"))
+		})
+
+		It("unwraps errors", func() {
+			err := &HandshakeError{Err: ErrHandshakeTimeout}
+			Expect(errors.Is(err, ErrHandshakeTimeout)).To(BeTrue())
+			Expect(errors.Is(err, net.ErrClosed)).To(BeTrue())
+			var transportErr *TransportError
+			Expect(errors.As(&HandshakeError{Err: &TransportError{ErrorCode: ConnectionRefused}}, &transportErr)).To(BeTrue())
+			Expect(transportErr.ErrorCode).To(Equal(ConnectionRefused))
+		})
+
+		It("is a net.Error, if the wrapped error is a timeout", func() {
+			//nolint:gosimple // we need to assign to an interface here
+			var err error
+			err = &HandshakeError{Err: ErrHandshakeTimeout}
+			nerr, ok := err.(net.Error)
+			Expect(ok).To(BeTrue())
+			Expect(nerr.Timeout()).To(BeTrue())
+			Expect((&HandshakeError{Err: &TransportError{}}).Timeout()).To(BeFalse())
+		})
+
+		It("has a string representation for the failure reason", func() {
+			Expect(HandshakeFailureOther.String()).To(Equal("other"))
+			Expect(HandshakeFailureNoCompatibleVersion.String()).To(Equal("no compatible version"))
+			Expect(HandshakeFailureALPNMismatch.String()).To(Equal("ALPN mismatch"))
+			Expect(HandshakeFailureCertificate.String()).To(Equal("certificate"))
+			Expect(HandshakeFailureTimeout.String()).To(Equal("timeout"))
+			Expect(HandshakeFailureAmplificationLimited.String()).To(Equal("amplification limited"))
+			Expect(HandshakeFailureReason(42).String()).To(Equal("unknown handshake failure reason: 42"))
+		})
+	})
+
 	Context("Stateless Reset errors", func() {
 		token := protocol.StatelessResetToken{0x0, 0x1, 0x2, 0x3, 0x4, 0x5, 0x6, 0x7, 0x8, 0x9, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf}
 
@@ -139,4 +178,36 @@ var _ = Describe("QUIC Errors", func() {
 		Expect(errors.Is(&StatelessResetError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&VersionNegotiationError{}, net.ErrClosed)).To(BeTrue())
 	})
+
+	It("matches errors of the same type", func() {
+		Expect(errors.Is(&IdleTimeoutError{}, ErrIdleTimeout)).To(BeTrue())
+		Expect(errors.Is(&IdleTimeoutError{}, ErrHandshakeTimeout)).To(BeFalse())
+		Expect(errors.Is(&HandshakeTimeoutError{}, ErrHandshakeTimeout)).To(BeTrue())
+		Expect(errors.Is(&HandshakeTimeoutError{}, ErrIdleTimeout)).To(BeFalse())
+		Expect(errors.Is(&VersionNegotiationError{Theirs: []protocol.VersionNumber{1}}, &VersionNegotiationError{})).To(BeTrue())
+		Expect(errors.Is(fmt.Errorf("wrapped: %w", &IdleTimeoutError{}), &IdleTimeoutError{})).To(BeTrue())
+	})
+
+	It("matches transport errors by their error code", func() {
+		err := &TransportError{Remote: true, ErrorCode: FlowControlError, FrameType: 0x42, ErrorMessage: "foobar"}
+		Expect(errors.Is(err, &TransportError{ErrorCode: FlowControlError})).To(BeTrue())
+		Expect(errors.Is(err, &TransportError{ErrorCode: FlowControlError, FrameType: 0x42})).To(BeTrue())
+		Expect(errors.Is(err, &TransportError{ErrorCode: FlowControlError, FrameType: 0x43})).To(BeFalse())
+		Expect(errors.Is(err, &TransportError{ErrorCode: ProtocolViolation})).To(BeFalse())
+		Expect(errors.Is(err, &ApplicationError{ErrorCode: ApplicationErrorCode(FlowControlError)})).To(BeFalse())
+	})
+
+	It("matches application errors by their error code", func() {
+		err := &ApplicationError{Remote: true, ErrorCode: 0x1337, ErrorMessage: "foobar"}
+		Expect(errors.Is(err, &ApplicationError{ErrorCode: 0x1337})).To(BeTrue())
+		Expect(errors.Is(err, &ApplicationError{ErrorCode: 0x42})).To(BeFalse())
+		Expect(errors.Is(err, &TransportError{ErrorCode: 0x1337})).To(BeFalse())
+	})
+
+	It("matches stateless resets by their token", func() {
+		err := &StatelessResetError{Token: protocol.StatelessResetToken{1, 2, 3}}
+		Expect(errors.Is(err, &StatelessResetError{})).To(BeTrue())
+		Expect(errors.Is(err, &StatelessResetError{Token: protocol.StatelessResetToken{1, 2, 3}})).To(BeTrue())
+		Expect(errors.Is(err, &StatelessResetError{Token: protocol.StatelessResetToken{3, 2, 1}})).To(BeFalse())
+	})
 })
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// A BufferSizeClass identifies the size of a buffer requested from a BufferAllocator.
+type BufferSizeClass uint8
+
+const (
+	// BufferSizeClassPacket is used for buffers holding a single QUIC packet, and for the data of STREAM frames.
+	BufferSizeClassPacket BufferSizeClass = iota
+	// BufferSizeClassLargePacket is used for buffers holding a batch of packets sent using GSO.
+	BufferSizeClassLargePacket
+)
+
+// Size returns the minimum capacity of buffers of this size class.
+func (c BufferSizeClass) Size() int {
+	switch c {
+	case BufferSizeClassPacket:
+		return protocol.MaxPacketBufferSize
+	case BufferSizeClassLargePacket:
+		return protocol.MaxLargePacketBufferSize
+	default:
+		panic("unknown buffer size class")
+	}
+}
+
+func (c BufferSizeClass) String() string {
+	switch c {
+	case BufferSizeClassPacket:
+		return "packet"
+	case BufferSizeClassLargePacket:
+		return "large packet"
+	default:
+		return "unknown buffer size class"
+	}
+}
+
+// A BufferAllocator allocates the buffers used for sending and receiving packets,
+// and for holding the data of received STREAM frames.
+type BufferAllocator interface {
+	// Get returns a buffer of the given size class.
+	// The buffer must have a capacity of at least class.Size() bytes.
+	Get(class BufferSizeClass) []byte
+	// Put returns a buffer obtained from Get.
+	// The buffer is not accessed any more after Put was called.
+	Put(class BufferSizeClass, b []byte)
+}
+
+type bufferAllocatorHolder struct{ BufferAllocator }
+
+var bufferAllocator atomic.Pointer[bufferAllocatorHolder]
+
+// SetBufferAllocator sets the BufferAllocator.
+// Setting it to nil restores the default allocator.
+func SetBufferAllocator(a BufferAllocator) {
+	if a == nil {
+		bufferAllocator.Store(nil)
+		return
+	}
+	bufferAllocator.Store(&bufferAllocatorHolder{a})
+}
+
+// GetBufferAllocator returns the BufferAllocator.
+// It returns nil if the default allocator is used.
+func GetBufferAllocator() BufferAllocator {
+	h := bufferAllocator.Load()
+	if h == nil {
+		return nil
+	}
+	return h.BufferAllocator
+}
+
+// AllocateBuffer gets a buffer from a BufferAllocator.
+// The returned slice has zero length, and its capacity is limited to the size of the size class.
+// It panics if the buffer is too small.
+func AllocateBuffer(a BufferAllocator, class BufferSizeClass) []byte {
+	b := a.Get(class)
+	if cap(b) < class.Size() {
+		panic("BufferAllocator returned a buffer that is too small for size class " + class.String())
+	}
+	return b[:0:class.Size()]
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type testBufferAllocator struct {
+	size int
+}
+
+func (a *testBufferAllocator) Get(class BufferSizeClass) []byte { return make([]byte, 42, a.size) }
+func (a *testBufferAllocator) Put(BufferSizeClass, []byte)      {}
+
+var _ = Describe("Buffer Allocator", func() {
+	AfterEach(func() { SetBufferAllocator(nil) })
+
+	It("returns the size of size classes", func() {
+		Expect(BufferSizeClassPacket.Size()).To(Equal(protocol.MaxPacketBufferSize))
+		Expect(BufferSizeClassLargePacket.Size()).To(Equal(protocol.MaxLargePacketBufferSize))
+	})
+
+	It("sets and resets the allocator", func() {
+		Expect(GetBufferAllocator()).To(BeNil())
+		a := &testBufferAllocator{}
+		SetBufferAllocator(a)
+		Expect(GetBufferAllocator()).To(BeIdenticalTo(a))
+		SetBufferAllocator(nil)
+		Expect(GetBufferAllocator()).To(BeNil())
+	})
+
+	It("allocates zero-length buffers, limiting the capacity", func() {
+		b := AllocateBuffer(&testBufferAllocator{size: 2000}, BufferSizeClassPacket)
+		Expect(b).To(BeEmpty())
+		Expect(cap(b)).To(Equal(protocol.MaxPacketBufferSize))
+	})
+
+	It("panics if the allocator returns a buffer that is too small", func() {
+		a := &testBufferAllocator{size: protocol.MaxPacketBufferSize}
+		Expect(func() { AllocateBuffer(a, BufferSizeClassPacket) }).ToNot(Panic())
+		Expect(func() { AllocateBuffer(a, BufferSizeClassLargePacket) }).To(Panic())
+	})
+})
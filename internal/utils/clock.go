@@ -0,0 +1,40 @@
+package utils
+
+import "time"
+
+// A Clock is a source of time.
+// It is used for all timers that drive a connection, e.g. loss detection, pacing and the idle timeout.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a new timer that fires after the given duration.
+	NewTimer(time.Duration) ClockTimer
+}
+
+// A ClockTimer is a timer created by a Clock.
+// It behaves like a time.Timer.
+type ClockTimer interface {
+	// Chan returns the channel that the current time is sent on when the timer fires.
+	Chan() <-chan time.Time
+	// Reset changes the timer to expire after the given duration.
+	// It returns true if the timer had been active, false if the timer had expired or been stopped.
+	Reset(time.Duration) bool
+	// Stop prevents the timer from firing.
+	// It returns true if the call stops the timer, false if the timer has already expired or been stopped.
+	Stop() bool
+}
+
+// DefaultClock implements the Clock interface using the Go stdlib clock.
+type DefaultClock struct{}
+
+var _ Clock = DefaultClock{}
+
+// Now gets the current time
+func (DefaultClock) Now() time.Time { return time.Now() }
+
+// NewTimer creates a new time.Timer
+func (DefaultClock) NewTimer(d time.Duration) ClockTimer { return &stdlibTimer{time.NewTimer(d)} }
+
+type stdlibTimer struct{ *time.Timer }
+
+func (t *stdlibTimer) Chan() <-chan time.Time { return t.C }
@@ -29,6 +29,9 @@ type Logger interface {
 	SetLogLevel(LogLevel)
 	SetLogTimeFormat(format string)
 	WithPrefix(prefix string) Logger
+	// With returns a Logger that adds the attributes (alternating keys and values) to every message.
+	// Attributes are only logged by structured loggers.
+	With(args ...any) Logger
 	Debug() bool
 
 	Errorf(format string, args ...interface{})
@@ -104,6 +107,9 @@ func (l *defaultLogger) WithPrefix(prefix string) Logger {
 	}
 }
 
+// With returns the logger itself. The default logger doesn't log attributes.
+func (l *defaultLogger) With(...any) Logger { return l }
+
 // Debug returns true if the log level is LogLevelDebug
 func (l *defaultLogger) Debug() bool {
 	return l.logLevel == LogLevelDebug
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// MemoryPressure describes how much of the memory budget is used.
+type MemoryPressure uint8
+
+const (
+	// MemoryPressureNone means that the budget is not limited, or that less than
+	// protocol.MemoryBudgetPressureThreshold of the budget is used.
+	MemoryPressureNone MemoryPressure = iota
+	// MemoryPressureHigh means that the budget is almost used up.
+	MemoryPressureHigh
+	// MemoryPressureExceeded means that the budget is used up.
+	MemoryPressureExceeded
+)
+
+func (p MemoryPressure) String() string {
+	switch p {
+	case MemoryPressureNone:
+		return "none"
+	case MemoryPressureHigh:
+		return "high"
+	case MemoryPressureExceeded:
+		return "exceeded"
+	default:
+		return "unknown memory pressure"
+	}
+}
+
+// A MemoryBudget tracks the memory used for buffering data.
+// It is safe for concurrent use.
+type MemoryBudget struct {
+	limit atomic.Int64
+	used  atomic.Int64
+}
+
+// GlobalMemoryBudget is the memory budget shared by all connections.
+var GlobalMemoryBudget MemoryBudget
+
+// SetLimit sets the limit.
+// A limit of 0 means that memory usage is tracked, but not limited.
+func (b *MemoryBudget) SetLimit(limit int64) {
+	b.limit.Store(limit)
+}
+
+// Limit returns the limit.
+func (b *MemoryBudget) Limit() int64 {
+	return b.limit.Load()
+}
+
+// Used returns the number of bytes that are currently used.
+func (b *MemoryBudget) Used() int64 {
+	return b.used.Load()
+}
+
+// Acquire accounts n bytes against the budget.
+// It always succeeds, even if the budget is exceeded.
+func (b *MemoryBudget) Acquire(n int) {
+	b.used.Add(int64(n))
+}
+
+// TryAcquire accounts n bytes against the budget, unless this would exceed the limit.
+func (b *MemoryBudget) TryAcquire(n int) bool {
+	limit := b.limit.Load()
+	if limit <= 0 {
+		b.used.Add(int64(n))
+		return true
+	}
+	for {
+		used := b.used.Load()
+		if used+int64(n) > limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+int64(n)) {
+			return true
+		}
+	}
+}
+
+// Release returns n bytes that were previously acquired.
+func (b *MemoryBudget) Release(n int) {
+	b.used.Add(-int64(n))
+}
+
+// Pressure returns the current memory pressure.
+func (b *MemoryBudget) Pressure() MemoryPressure {
+	limit := b.limit.Load()
+	if limit <= 0 {
+		return MemoryPressureNone
+	}
+	used := b.used.Load()
+	if used >= limit {
+		return MemoryPressureExceeded
+	}
+	if float64(used) >= float64(limit)*protocol.MemoryBudgetPressureThreshold {
+		return MemoryPressureHigh
+	}
+	return MemoryPressureNone
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Budget", func() {
+	var b *MemoryBudget
+
+	BeforeEach(func() {
+		b = &MemoryBudget{}
+	})
+
+	It("tracks memory usage without a limit", func() {
+		b.Acquire(1000)
+		Expect(b.TryAcquire(1 << 30)).To(BeTrue())
+		Expect(b.Used()).To(BeEquivalentTo(1000 + 1<<30))
+		Expect(b.Pressure()).To(Equal(MemoryPressureNone))
+		b.Release(1 << 30)
+		b.Release(1000)
+		Expect(b.Used()).To(BeZero())
+	})
+
+	It("reports the memory pressure", func() {
+		b.SetLimit(1000)
+		Expect(b.Limit()).To(BeEquivalentTo(1000))
+		b.Acquire(749)
+		Expect(b.Pressure()).To(Equal(MemoryPressureNone))
+		b.Acquire(1)
+		Expect(b.Pressure()).To(Equal(MemoryPressureHigh))
+		b.Acquire(250)
+		Expect(b.Pressure()).To(Equal(MemoryPressureExceeded))
+		b.Acquire(100) // Acquire always succeeds
+		Expect(b.Used()).To(BeEquivalentTo(1100))
+		b.Release(1100)
+		Expect(b.Pressure()).To(Equal(MemoryPressureNone))
+	})
+
+	It("doesn't exceed the limit when using TryAcquire", func() {
+		b.SetLimit(1000)
+		Expect(b.TryAcquire(600)).To(BeTrue())
+		Expect(b.TryAcquire(401)).To(BeFalse())
+		Expect(b.TryAcquire(400)).To(BeTrue())
+		Expect(b.Used()).To(BeEquivalentTo(1000))
+		Expect(b.TryAcquire(1)).To(BeFalse())
+	})
+
+	It("has a string representation for the memory pressure", func() {
+		Expect(MemoryPressureNone.String()).To(Equal("none"))
+		Expect(MemoryPressureHigh.String()).To(Equal("high"))
+		Expect(MemoryPressureExceeded.String()).To(Equal("exceeded"))
+		Expect(MemoryPressure(42).String()).To(Equal("unknown memory pressure"))
+	})
+})
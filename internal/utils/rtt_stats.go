@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"sync"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -15,8 +16,28 @@ const (
 	defaultInitialRTT = 100 * time.Millisecond
 )
 
+// An RTTSample is an RTT measurement, and the time when it was taken.
+type RTTSample struct {
+	Time time.Time
+	RTT  time.Duration
+}
+
+// An RTTSnapshot is a consistent copy of the RTT statistics.
+type RTTSnapshot struct {
+	LatestRTT     time.Duration
+	SmoothedRTT   time.Duration
+	MinRTT        time.Duration
+	MeanDeviation time.Duration
+	// Samples contains the most recent RTT samples, oldest first.
+	Samples []RTTSample
+}
+
 // RTTStats provides round-trip statistics
 type RTTStats struct {
+	// The mutex protects the RTT values against concurrent reads using Snapshot.
+	// Updates (and all other reads) happen on the connection's run loop.
+	mutex sync.Mutex
+
 	hasMeasurement bool
 
 	minRTT        time.Duration
@@ -25,6 +46,11 @@ type RTTStats struct {
 	meanDeviation time.Duration
 
 	maxAckDelay time.Duration
+
+	// ring buffer of the most recent RTT samples
+	samples    [protocol.MaxRTTSamples]RTTSample
+	numSamples int
+	nextSample int
 }
 
 // NewRTTStats makes a properly initialized RTTStats object
@@ -67,6 +93,8 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 	if sendDelta == InfDuration || sendDelta <= 0 {
 		return
 	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
 	// Update r.minRTT first. r.minRTT does not use an rttSample corrected for
 	// ackDelay but the raw observed sendDelta, since poor clock granularity at
@@ -93,6 +121,37 @@ func (r *RTTStats) UpdateRTT(sendDelta, ackDelay time.Duration, now time.Time) {
 		r.meanDeviation = time.Duration(oneMinusBeta*float32(r.meanDeviation/time.Microsecond)+rttBeta*float32(AbsDuration(r.smoothedRTT-sample)/time.Microsecond)) * time.Microsecond
 		r.smoothedRTT = time.Duration((float32(r.smoothedRTT/time.Microsecond)*oneMinusAlpha)+(float32(sample/time.Microsecond)*rttAlpha)) * time.Microsecond
 	}
+	r.addSample(RTTSample{Time: now, RTT: sample})
+}
+
+func (r *RTTStats) addSample(s RTTSample) {
+	r.samples[r.nextSample] = s
+	r.nextSample = (r.nextSample + 1) % len(r.samples)
+	if r.numSamples < len(r.samples) {
+		r.numSamples++
+	}
+}
+
+// Snapshot returns a copy of the current RTT statistics, including the most recent RTT samples.
+// It is safe to call Snapshot concurrently with updates to the RTTStats.
+func (r *RTTStats) Snapshot() RTTSnapshot {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	s := RTTSnapshot{
+		LatestRTT:     r.latestRTT,
+		SmoothedRTT:   r.smoothedRTT,
+		MinRTT:        r.minRTT,
+		MeanDeviation: r.meanDeviation,
+	}
+	if r.numSamples > 0 {
+		s.Samples = make([]RTTSample, 0, r.numSamples)
+		start := (r.nextSample - r.numSamples + len(r.samples)) % len(r.samples)
+		for i := 0; i < r.numSamples; i++ {
+			s.Samples = append(s.Samples, r.samples[(start+i)%len(r.samples)])
+		}
+	}
+	return s
 }
 
 // SetMaxAckDelay sets the max_ack_delay
@@ -101,7 +160,8 @@ func (r *RTTStats) SetMaxAckDelay(mad time.Duration) {
 }
 
 // SetInitialRTT sets the initial RTT.
-// It is used during the 0-RTT handshake when restoring the RTT stats from the session state.
+// It is used when the application provides an RTT estimate,
+// and during the 0-RTT handshake when restoring the RTT stats from the session state.
 func (r *RTTStats) SetInitialRTT(t time.Duration) {
 	// On the server side, by the time we get to process the session ticket,
 	// we might already have obtained an RTT measurement.
@@ -110,12 +170,16 @@ func (r *RTTStats) SetInitialRTT(t time.Duration) {
 	if r.hasMeasurement {
 		return
 	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	r.smoothedRTT = t
 	r.latestRTT = t
 }
 
 // OnConnectionMigration is called when connection migrates and rtt measurement needs to be reset.
 func (r *RTTStats) OnConnectionMigration() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	r.latestRTT = 0
 	r.minRTT = 0
 	r.smoothedRTT = 0
@@ -126,6 +190,8 @@ func (r *RTTStats) OnConnectionMigration() {
 // is larger. The mean deviation is increased to the most recent deviation if
 // it's larger.
 func (r *RTTStats) ExpireSmoothedMetrics() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 	r.meanDeviation = Max(r.meanDeviation, AbsDuration(r.smoothedRTT-r.latestRTT))
 	r.smoothedRTT = Max(r.smoothedRTT, r.latestRTT)
 }
@@ -164,4 +164,39 @@ var _ = Describe("RTT stats", func() {
 		Expect(rttStats.LatestRTT()).To(Equal(rtt))
 		Expect(rttStats.SmoothedRTT()).To(Equal(rtt))
 	})
+
+	It("returns a snapshot with the RTT samples", func() {
+		Expect(rttStats.Snapshot()).To(Equal(RTTSnapshot{}))
+		now := time.Now()
+		rttStats.UpdateRTT(100*time.Millisecond, 0, now)
+		rttStats.UpdateRTT(200*time.Millisecond, 0, now.Add(time.Second))
+		snapshot := rttStats.Snapshot()
+		Expect(snapshot.LatestRTT).To(Equal(200 * time.Millisecond))
+		Expect(snapshot.MinRTT).To(Equal(100 * time.Millisecond))
+		Expect(snapshot.SmoothedRTT).To(Equal(rttStats.SmoothedRTT()))
+		Expect(snapshot.MeanDeviation).To(Equal(rttStats.MeanDeviation()))
+		Expect(snapshot.Samples).To(Equal([]RTTSample{
+			{Time: now, RTT: 100 * time.Millisecond},
+			{Time: now.Add(time.Second), RTT: 200 * time.Millisecond},
+		}))
+	})
+
+	It("only keeps the most recent RTT samples", func() {
+		now := time.Now()
+		for i := 1; i <= protocol.MaxRTTSamples+10; i++ {
+			rttStats.UpdateRTT(time.Duration(i)*time.Millisecond, 0, now.Add(time.Duration(i)*time.Second))
+		}
+		samples := rttStats.Snapshot().Samples
+		Expect(samples).To(HaveLen(protocol.MaxRTTSamples))
+		for i, s := range samples {
+			Expect(s.RTT).To(Equal(time.Duration(i+11) * time.Millisecond))
+			Expect(s.Time).To(Equal(now.Add(time.Duration(i+11) * time.Second)))
+		}
+	})
+
+	It("doesn't record invalid RTT samples", func() {
+		rttStats.UpdateRTT(0, 0, time.Now())
+		rttStats.UpdateRTT(InfDuration, 0, time.Now())
+		Expect(rttStats.Snapshot().Samples).To(BeEmpty())
+	})
 })
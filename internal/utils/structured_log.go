@@ -0,0 +1,114 @@
+package utils
+
+import "fmt"
+
+// A StructuredLogger logs messages with attributes.
+// The attributes are passed as alternating keys and values.
+// It is implemented by *slog.Logger.
+type StructuredLogger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// subsystemKey is the key of the attribute that holds the subsystem (i.e. the prefix) of a log message.
+const subsystemKey = "subsystem"
+
+type structuredLogger struct {
+	backend StructuredLogger
+	// levels contains the log levels per subsystem.
+	levels map[string]LogLevel
+
+	subsystem string
+	level     LogLevel
+	attrs     []any
+}
+
+var _ Logger = &structuredLogger{}
+
+// NewStructuredLogger creates a Logger that logs to backend.
+// levels sets the log level for individual subsystems.
+// The subsystem of a logger is the prefix set by WithPrefix, e.g. "server" or "client".
+// Log messages of subsystems not contained in levels are passed to the backend,
+// which is responsible for filtering them.
+func NewStructuredLogger(backend StructuredLogger, levels map[string]LogLevel) Logger {
+	l := &structuredLogger{backend: backend, levels: levels}
+	l.level = l.levelFor("")
+	return l
+}
+
+// WithBackend returns a Logger that logs to backend, using the same subsystem, log levels and attributes as l.
+func WithBackend(l Logger, backend StructuredLogger) Logger {
+	switch l := l.(type) {
+	case *structuredLogger:
+		c := *l
+		c.backend = backend
+		return &c
+	case *defaultLogger:
+		if len(l.prefix) > 0 {
+			return NewStructuredLogger(backend, nil).WithPrefix(l.prefix)
+		}
+	}
+	return NewStructuredLogger(backend, nil)
+}
+
+func (l *structuredLogger) levelFor(subsystem string) LogLevel {
+	if level, ok := l.levels[subsystem]; ok {
+		return level
+	}
+	return LogLevelDebug
+}
+
+// SetLogLevel sets the log level.
+func (l *structuredLogger) SetLogLevel(level LogLevel) {
+	l.level = level
+}
+
+// SetLogTimeFormat is a no-op. Timestamps are added by the backend.
+func (l *structuredLogger) SetLogTimeFormat(string) {}
+
+func (l *structuredLogger) WithPrefix(prefix string) Logger {
+	if len(l.subsystem) > 0 {
+		prefix = l.subsystem + " " + prefix
+	}
+	c := *l
+	c.subsystem = prefix
+	c.level = l.levelFor(prefix)
+	return &c
+}
+
+func (l *structuredLogger) With(args ...any) Logger {
+	c := *l
+	c.attrs = append(append(make([]any, 0, len(l.attrs)+len(args)), l.attrs...), args...)
+	return &c
+}
+
+// Debug returns true if debug messages are logged.
+func (l *structuredLogger) Debug() bool {
+	return l.level == LogLevelDebug && backendEnabled(l.backend, LogLevelDebug)
+}
+
+func (l *structuredLogger) Debugf(format string, args ...interface{}) {
+	if l.level == LogLevelDebug && backendEnabled(l.backend, LogLevelDebug) {
+		l.backend.Debug(fmt.Sprintf(format, args...), l.attributes()...)
+	}
+}
+
+func (l *structuredLogger) Infof(format string, args ...interface{}) {
+	if l.level >= LogLevelInfo && backendEnabled(l.backend, LogLevelInfo) {
+		l.backend.Info(fmt.Sprintf(format, args...), l.attributes()...)
+	}
+}
+
+func (l *structuredLogger) Errorf(format string, args ...interface{}) {
+	if l.level >= LogLevelError && backendEnabled(l.backend, LogLevelError) {
+		l.backend.Error(fmt.Sprintf(format, args...), l.attributes()...)
+	}
+}
+
+func (l *structuredLogger) attributes() []any {
+	if len(l.subsystem) == 0 {
+		return l.attrs
+	}
+	return append([]any{subsystemKey, l.subsystem}, l.attrs...)
+}
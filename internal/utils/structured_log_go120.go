@@ -0,0 +1,7 @@
+//go:build !go1.21
+
+package utils
+
+// backendEnabled says if the backend logs messages at the given level.
+// Before Go 1.21, there's no standard way to query the backend, so all messages are passed to it.
+func backendEnabled(StructuredLogger, LogLevel) bool { return true }
@@ -0,0 +1,27 @@
+//go:build go1.21
+
+package utils
+
+import (
+	"context"
+	"log/slog"
+)
+
+// backendEnabled says if the backend logs messages at the given level.
+// This avoids formatting messages that would be discarded by the backend.
+func backendEnabled(backend StructuredLogger, level LogLevel) bool {
+	l, ok := backend.(interface {
+		Enabled(context.Context, slog.Level) bool
+	})
+	if !ok {
+		return true
+	}
+	switch level {
+	case LogLevelDebug:
+		return l.Enabled(context.Background(), slog.LevelDebug)
+	case LogLevelInfo:
+		return l.Enabled(context.Background(), slog.LevelInfo)
+	default:
+		return l.Enabled(context.Background(), slog.LevelError)
+	}
+}
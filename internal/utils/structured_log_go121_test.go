@@ -0,0 +1,32 @@
+//go:build go1.21
+
+package utils
+
+import (
+	"bytes"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Structured Logger, using slog", func() {
+	It("logs to a slog.Logger", func() {
+		var b bytes.Buffer
+		backend := slog.New(slog.NewTextHandler(&b, &slog.HandlerOptions{
+			Level: slog.LevelInfo,
+			ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+		l := NewStructuredLogger(backend, nil).WithPrefix("server").With("conn_id", "deadbeef")
+		// Debug messages are filtered by the handler, so they are not even formatted.
+		Expect(l.Debug()).To(BeFalse())
+		l.Debugf("debug")
+		l.Infof("received %d packets", 42)
+		Expect(b.String()).To(Equal("level=INFO msg=\"received 42 packets\" subsystem=server conn_id=deadbeef\n"))
+	})
+})
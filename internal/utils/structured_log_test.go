@@ -0,0 +1,82 @@
+package utils
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type logRecord struct {
+	Level string
+	Msg   string
+	Args  []any
+}
+
+type recordingLogger struct{ records []logRecord }
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.log("debug", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.log("info", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.log("error", msg, args) }
+
+func (l *recordingLogger) log(level, msg string, args []any) {
+	l.records = append(l.records, logRecord{Level: level, Msg: msg, Args: args})
+}
+
+var _ = Describe("Structured Logger", func() {
+	It("formats messages", func() {
+		backend := &recordingLogger{}
+		l := NewStructuredLogger(backend, nil)
+		Expect(l.Debug()).To(BeTrue())
+		l.Debugf("debug %d", 1)
+		l.Infof("info %s", "foo")
+		l.Errorf("error %t", true)
+		Expect(backend.records).To(Equal([]logRecord{
+			{Level: "debug", Msg: "debug 1"},
+			{Level: "info", Msg: "info foo"},
+			{Level: "error", Msg: "error true"},
+		}))
+	})
+
+	It("adds the subsystem and attributes", func() {
+		backend := &recordingLogger{}
+		l := NewStructuredLogger(backend, nil).WithPrefix("server").With("conn_id", "deadbeef")
+		l.With("remote_addr", "127.0.0.1:1234").Infof("foo")
+		l.Infof("bar")
+		Expect(backend.records).To(Equal([]logRecord{
+			{Level: "info", Msg: "foo", Args: []any{"subsystem", "server", "conn_id", "deadbeef", "remote_addr", "127.0.0.1:1234"}},
+			{Level: "info", Msg: "bar", Args: []any{"subsystem", "server", "conn_id", "deadbeef"}},
+		}))
+	})
+
+	It("filters messages per subsystem", func() {
+		backend := &recordingLogger{}
+		l := NewStructuredLogger(backend, map[string]LogLevel{"server": LogLevelError, "muxer": LogLevelNothing})
+		server := l.WithPrefix("server")
+		Expect(server.Debug()).To(BeFalse())
+		server.Debugf("debug")
+		server.Infof("info")
+		server.Errorf("error")
+		l.WithPrefix("muxer").Errorf("error")
+		l.WithPrefix("client").Debugf("debug")
+		Expect(backend.records).To(Equal([]logRecord{
+			{Level: "error", Msg: "error", Args: []any{"subsystem", "server"}},
+			{Level: "debug", Msg: "debug", Args: []any{"subsystem", "client"}},
+		}))
+	})
+
+	It("replaces the backend", func() {
+		backend1 := &recordingLogger{}
+		backend2 := &recordingLogger{}
+		l := NewStructuredLogger(backend1, map[string]LogLevel{"server": LogLevelInfo}).WithPrefix("server").With("foo", "bar")
+		l2 := WithBackend(l, backend2)
+		l2.Debugf("debug")
+		l2.Infof("info")
+		Expect(backend1.records).To(BeEmpty())
+		Expect(backend2.records).To(Equal([]logRecord{{Level: "info", Msg: "info", Args: []any{"subsystem", "server", "foo", "bar"}}}))
+	})
+
+	It("replaces the backend of the default logger", func() {
+		backend := &recordingLogger{}
+		WithBackend(DefaultLogger.WithPrefix("client"), backend).Infof("info")
+		Expect(backend.records).To(Equal([]logRecord{{Level: "info", Msg: "info", Args: []any{"subsystem", "client"}}}))
+	})
+})
@@ -7,6 +7,13 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type fakeClock struct {
+	DefaultClock
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
 var _ = Describe("Timer", func() {
 	const d = 10 * time.Millisecond
 
@@ -92,4 +99,12 @@ var _ = Describe("Timer", func() {
 		t.Stop()
 		Consistently(t.Chan()).ShouldNot(Receive())
 	})
+	It("uses the clock to calculate the duration", func() {
+		clock := &fakeClock{now: time.Now().Add(-time.Hour)}
+		t := NewTimerWithClock(clock)
+		t.Reset(clock.now.Add(time.Hour))
+		Consistently(t.Chan()).ShouldNot(Receive())
+		t.Reset(clock.now.Add(d))
+		Eventually(t.Chan()).Should(Receive())
+	})
 })
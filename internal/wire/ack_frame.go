@@ -120,7 +120,7 @@ func (f *AckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
 	b = quicvarint.Append(b, uint64(f.LargestAcked()))
 	b = quicvarint.Append(b, encodeAckDelay(f.DelayTime))
 
-	numRanges := f.numEncodableAckRanges()
+	numRanges := f.NumEncodableAckRanges()
 	b = quicvarint.Append(b, uint64(numRanges-1))
 
 	// write the first range
@@ -145,35 +145,36 @@ func (f *AckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
 // Length of a written frame
 func (f *AckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	largestAcked := f.AckRanges[0].Largest
-	numRanges := f.numEncodableAckRanges()
+	numRanges := f.NumEncodableAckRanges()
 
-	length := 1 + quicvarint.Len(uint64(largestAcked)) + quicvarint.Len(encodeAckDelay(f.DelayTime))
+	length := 1 + protocol.ByteCount(quicvarint.Len(uint64(largestAcked))) + protocol.ByteCount(quicvarint.Len(encodeAckDelay(f.DelayTime)))
 
-	length += quicvarint.Len(uint64(numRanges - 1))
+	length += protocol.ByteCount(quicvarint.Len(uint64(numRanges - 1)))
 	lowestInFirstRange := f.AckRanges[0].Smallest
-	length += quicvarint.Len(uint64(largestAcked - lowestInFirstRange))
+	length += protocol.ByteCount(quicvarint.Len(uint64(largestAcked - lowestInFirstRange)))
 
 	for i := 1; i < numRanges; i++ {
 		gap, len := f.encodeAckRange(i)
-		length += quicvarint.Len(gap)
-		length += quicvarint.Len(len)
+		length += protocol.ByteCount(quicvarint.Len(gap))
+		length += protocol.ByteCount(quicvarint.Len(len))
 	}
 	if f.ECT0 > 0 || f.ECT1 > 0 || f.ECNCE > 0 {
-		length += quicvarint.Len(f.ECT0)
-		length += quicvarint.Len(f.ECT1)
-		length += quicvarint.Len(f.ECNCE)
+		length += protocol.ByteCount(quicvarint.Len(f.ECT0))
+		length += protocol.ByteCount(quicvarint.Len(f.ECT1))
+		length += protocol.ByteCount(quicvarint.Len(f.ECNCE))
 	}
 	return length
 }
 
-// gets the number of ACK ranges that can be encoded
-// such that the resulting frame is smaller than the maximum ACK frame size
-func (f *AckFrame) numEncodableAckRanges() int {
-	length := 1 + quicvarint.Len(uint64(f.LargestAcked())) + quicvarint.Len(encodeAckDelay(f.DelayTime))
+// NumEncodableAckRanges gets the number of ACK ranges that can be encoded
+// such that the resulting frame is smaller than the maximum ACK frame size.
+// Ranges beyond that number are not included when the frame is written.
+func (f *AckFrame) NumEncodableAckRanges() int {
+	length := 1 + protocol.ByteCount(quicvarint.Len(uint64(f.LargestAcked()))) + protocol.ByteCount(quicvarint.Len(encodeAckDelay(f.DelayTime)))
 	length += 2 // assume that the number of ranges will consume 2 bytes
 	for i := 1; i < len(f.AckRanges); i++ {
 		gap, len := f.encodeAckRange(i)
-		rangeLen := quicvarint.Len(gap) + quicvarint.Len(len)
+		rangeLen := protocol.ByteCount(quicvarint.Len(gap)) + protocol.ByteCount(quicvarint.Len(len))
 		if length+rangeLen > protocol.MaxAckFrameSize {
 			// Writing range i would exceed the MaxAckFrameSize.
 			// So encode one range less than that.
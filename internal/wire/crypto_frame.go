@@ -48,19 +48,19 @@ func (f *CryptoFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error)
 
 // Length of a written frame
 func (f *CryptoFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.Offset)) + quicvarint.Len(uint64(len(f.Data))) + protocol.ByteCount(len(f.Data))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.Offset))) + protocol.ByteCount(quicvarint.Len(uint64(len(f.Data)))) + protocol.ByteCount(len(f.Data))
 }
 
 // MaxDataLen returns the maximum data length
 func (f *CryptoFrame) MaxDataLen(maxSize protocol.ByteCount) protocol.ByteCount {
 	// pretend that the data size will be 1 bytes
 	// if it turns out that varint encoding the length will consume 2 bytes, we need to adjust the data length afterwards
-	headerLen := 1 + quicvarint.Len(uint64(f.Offset)) + 1
+	headerLen := 1 + protocol.ByteCount(quicvarint.Len(uint64(f.Offset))) + 1
 	if headerLen > maxSize {
 		return 0
 	}
 	maxDataLen := maxSize - headerLen
-	if quicvarint.Len(uint64(maxDataLen)) != 1 {
+	if protocol.ByteCount(quicvarint.Len(uint64(maxDataLen))) != 1 {
 		maxDataLen--
 	}
 	return maxDataLen
@@ -12,12 +12,13 @@ type DataBlockedFrame struct {
 	MaximumData protocol.ByteCount
 }
 
-func parseDataBlockedFrame(r *bytes.Reader, _ protocol.VersionNumber) (*DataBlockedFrame, error) {
+func parseDataBlockedFrame(frame *DataBlockedFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	offset, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &DataBlockedFrame{MaximumData: protocol.ByteCount(offset)}, nil
+	frame.MaximumData = protocol.ByteCount(offset)
+	return nil
 }
 
 func (f *DataBlockedFrame) Append(b []byte, version protocol.VersionNumber) ([]byte, error) {
@@ -27,5 +28,5 @@ func (f *DataBlockedFrame) Append(b []byte, version protocol.VersionNumber) ([]b
 
 // Length of a written frame
 func (f *DataBlockedFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.MaximumData))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.MaximumData)))
 }
@@ -16,7 +16,8 @@ var _ = Describe("DATA_BLOCKED frame", func() {
 		It("accepts sample frame", func() {
 			data := encodeVarInt(0x12345678)
 			b := bytes.NewReader(data)
-			frame, err := parseDataBlockedFrame(b, protocol.Version1)
+			frame := &DataBlockedFrame{}
+			err := parseDataBlockedFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.MaximumData).To(Equal(protocol.ByteCount(0x12345678)))
 			Expect(b.Len()).To(BeZero())
@@ -24,10 +25,10 @@ var _ = Describe("DATA_BLOCKED frame", func() {
 
 		It("errors on EOFs", func() {
 			data := encodeVarInt(0x12345678)
-			_, err := parseDataBlockedFrame(bytes.NewReader(data), protocol.Version1)
+			err := parseDataBlockedFrame(&DataBlockedFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			for i := range data {
-				_, err := parseDataBlockedFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseDataBlockedFrame(&DataBlockedFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -45,7 +46,7 @@ var _ = Describe("DATA_BLOCKED frame", func() {
 
 		It("has the correct min length", func() {
 			frame := DataBlockedFrame{MaximumData: 0x12345}
-			Expect(frame.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(0x12345)))
+			Expect(frame.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(0x12345))))
 		})
 	})
 })
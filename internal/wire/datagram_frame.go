@@ -64,7 +64,7 @@ func (f *DatagramFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.
 		return 0
 	}
 	maxDataLen := maxSize - headerLen
-	if f.DataLenPresent && quicvarint.Len(uint64(maxDataLen)) != 1 {
+	if f.DataLenPresent && protocol.ByteCount(quicvarint.Len(uint64(maxDataLen))) != 1 {
 		maxDataLen--
 	}
 	return maxDataLen
@@ -74,7 +74,7 @@ func (f *DatagramFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.
 func (f *DatagramFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	length := 1 + protocol.ByteCount(len(f.Data))
 	if f.DataLenPresent {
-		length += quicvarint.Len(uint64(len(f.Data)))
+		length += protocol.ByteCount(quicvarint.Len(uint64(len(f.Data))))
 	}
 	return length
 }
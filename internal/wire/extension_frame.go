@@ -0,0 +1,65 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// An ExtensionFrameParser parses the payload of an extension frame (i.e. everything following the frame type)
+// from the beginning of b.
+// It returns the decoded frame and the number of bytes consumed.
+type ExtensionFrameParser func(b []byte) (frame any, n int, err error)
+
+type extensionFrameType struct {
+	ackEliciting bool
+	parse        ExtensionFrameParser
+}
+
+// An ExtensionFrame is a frame of a type that was registered by the application.
+// The AckEliciting and RetransmitOnLoss flags are not sent on the wire,
+// they are properties of the registered frame type.
+type ExtensionFrame struct {
+	FrameType uint64
+	// Data is the encoded payload of the frame.
+	Data []byte
+	// Value is the frame, as decoded by the ExtensionFrameParser.
+	// It is only set for received frames.
+	Value any
+
+	AckEliciting     bool
+	RetransmitOnLoss bool
+}
+
+func parseExtensionFrame(r *bytes.Reader, b []byte, typ uint64, ft extensionFrameType) (*ExtensionFrame, error) {
+	value, n, err := ft.parse(b)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > len(b) {
+		return nil, errors.New("invalid length returned by extension frame parser")
+	}
+	f := &ExtensionFrame{
+		FrameType:    typ,
+		Data:         make([]byte, n),
+		Value:        value,
+		AckEliciting: ft.ackEliciting,
+	}
+	if _, err := io.ReadFull(r, f.Data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *ExtensionFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	b = quicvarint.Append(b, f.FrameType)
+	return append(b, f.Data...), nil
+}
+
+// Length of a written frame
+func (f *ExtensionFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(f.FrameType)) + protocol.ByteCount(len(f.Data))
+}
@@ -0,0 +1,29 @@
+package wire
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("extension frames", func() {
+	It("writes a frame", func() {
+		f := &ExtensionFrame{FrameType: 0x1337, Data: []byte("foobar")}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		expected := quicvarint.Append(nil, 0x1337)
+		expected = append(expected, []byte("foobar")...)
+		Expect(b).To(Equal(expected))
+		Expect(f.Length(protocol.Version1)).To(BeEquivalentTo(len(b)))
+	})
+
+	It("writes a frame without payload", func() {
+		f := &ExtensionFrame{FrameType: 0x42}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(Equal([]byte{0x40, 0x42}))
+		Expect(f.Length(protocol.Version1)).To(BeEquivalentTo(2))
+	})
+})
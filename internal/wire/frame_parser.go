@@ -34,42 +34,70 @@ const (
 	connectionCloseFrameType    = 0x1c
 	applicationCloseFrameType   = 0x1d
 	handshakeDoneFrameType      = 0x1e
+	// draft-ietf-quic-ack-frequency
+	immediateAckFrameType = 0x1f
+	// draft-ietf-quic-address-discovery
+	observedAddressIPv4FrameType = 0x9f81a6
+	observedAddressIPv6FrameType = 0x9f81a7
 )
 
 type frameParser struct {
 	r bytes.Reader // cached bytes.Reader, so we don't have to repeatedly allocate them
 
-	ackDelayExponent  uint8
-	supportsDatagrams bool
+	ackDelayExponent         uint8
+	supportsDatagrams        bool
+	supportsAddressDiscovery bool
+	supportsImmediateAck     bool
+	extensionFrames          map[uint64]extensionFrameType
 
 	// To avoid allocating when parsing, keep a single ACK frame struct.
 	// It is used over and over again.
 	ackFrame *AckFrame
+	// The same applies to control frames that are processed right away and not retained.
+	resetStreamFrame        ResetStreamFrame
+	stopSendingFrame        StopSendingFrame
+	maxDataFrame            MaxDataFrame
+	maxStreamDataFrame      MaxStreamDataFrame
+	maxStreamsFrame         MaxStreamsFrame
+	dataBlockedFrame        DataBlockedFrame
+	streamDataBlockedFrame  StreamDataBlockedFrame
+	streamsBlockedFrame     StreamsBlockedFrame
+	retireConnectionIDFrame RetireConnectionIDFrame
+	pathChallengeFrame      PathChallengeFrame
+	pathResponseFrame       PathResponseFrame
 }
 
 var _ FrameParser = &frameParser{}
 
 // NewFrameParser creates a new frame parser.
-func NewFrameParser(supportsDatagrams bool) *frameParser {
+// OBSERVED_ADDRESS frames are only accepted if supportsAddressDiscovery is set,
+// i.e. if we advertised that we want to receive them.
+// The same applies to IMMEDIATE_ACK frames and supportsImmediateAck.
+func NewFrameParser(supportsDatagrams, supportsAddressDiscovery, supportsImmediateAck bool) *frameParser {
 	return &frameParser{
-		r:                 *bytes.NewReader(nil),
-		supportsDatagrams: supportsDatagrams,
-		ackFrame:          &AckFrame{},
+		r:                        *bytes.NewReader(nil),
+		supportsDatagrams:        supportsDatagrams,
+		supportsAddressDiscovery: supportsAddressDiscovery,
+		supportsImmediateAck:     supportsImmediateAck,
+		ackFrame:                 &AckFrame{},
 	}
 }
 
 // ParseNext parses the next frame.
 // It skips PADDING frames.
+// ACK, RESET_STREAM, STOP_SENDING, MAX_DATA, MAX_STREAM_DATA, MAX_STREAMS, DATA_BLOCKED,
+// STREAM_DATA_BLOCKED, STREAMS_BLOCKED, RETIRE_CONNECTION_ID, PATH_CHALLENGE and PATH_RESPONSE frames
+// are only valid until the next call to ParseNext.
 func (p *frameParser) ParseNext(data []byte, encLevel protocol.EncryptionLevel, v protocol.VersionNumber) (int, Frame, error) {
 	startLen := len(data)
 	p.r.Reset(data)
-	frame, err := p.parseNext(&p.r, encLevel, v)
+	frame, err := p.parseNext(&p.r, data, encLevel, v)
 	n := startLen - p.r.Len()
 	p.r.Reset(nil)
 	return n, frame, err
 }
 
-func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLevel, v protocol.VersionNumber) (Frame, error) {
+func (p *frameParser) parseNext(r *bytes.Reader, data []byte, encLevel protocol.EncryptionLevel, v protocol.VersionNumber) (Frame, error) {
 	for r.Len() != 0 {
 		typ, err := quicvarint.Read(r)
 		if err != nil {
@@ -82,7 +110,7 @@ func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 			continue
 		}
 
-		f, err := p.parseFrame(r, typ, encLevel, v)
+		f, err := p.parseFrame(r, data[len(data)-r.Len():], typ, encLevel, v)
 		if err != nil {
 			return nil, &qerr.TransportError{
 				FrameType:    typ,
@@ -95,7 +123,9 @@ func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 	return nil, nil
 }
 
-func (p *frameParser) parseFrame(r *bytes.Reader, typ uint64, encLevel protocol.EncryptionLevel, v protocol.VersionNumber) (Frame, error) {
+// parseFrame parses the frame following the frame type.
+// b contains the same bytes that are left in r.
+func (p *frameParser) parseFrame(r *bytes.Reader, b []byte, typ uint64, encLevel protocol.EncryptionLevel, v protocol.VersionNumber) (Frame, error) {
 	var frame Frame
 	var err error
 	if typ&0xf8 == 0x8 {
@@ -113,44 +143,71 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typ uint64, encLevel protocol.
 			err = parseAckFrame(p.ackFrame, r, typ, ackDelayExponent, v)
 			frame = p.ackFrame
 		case resetStreamFrameType:
-			frame, err = parseResetStreamFrame(r, v)
+			err = parseResetStreamFrame(&p.resetStreamFrame, r, v)
+			frame = &p.resetStreamFrame
 		case stopSendingFrameType:
-			frame, err = parseStopSendingFrame(r, v)
+			err = parseStopSendingFrame(&p.stopSendingFrame, r, v)
+			frame = &p.stopSendingFrame
 		case cryptoFrameType:
 			frame, err = parseCryptoFrame(r, v)
 		case newTokenFrameType:
 			frame, err = parseNewTokenFrame(r, v)
 		case maxDataFrameType:
-			frame, err = parseMaxDataFrame(r, v)
+			err = parseMaxDataFrame(&p.maxDataFrame, r, v)
+			frame = &p.maxDataFrame
 		case maxStreamDataFrameType:
-			frame, err = parseMaxStreamDataFrame(r, v)
+			err = parseMaxStreamDataFrame(&p.maxStreamDataFrame, r, v)
+			frame = &p.maxStreamDataFrame
 		case bidiMaxStreamsFrameType, uniMaxStreamsFrameType:
-			frame, err = parseMaxStreamsFrame(r, typ, v)
+			err = parseMaxStreamsFrame(&p.maxStreamsFrame, r, typ, v)
+			frame = &p.maxStreamsFrame
 		case dataBlockedFrameType:
-			frame, err = parseDataBlockedFrame(r, v)
+			err = parseDataBlockedFrame(&p.dataBlockedFrame, r, v)
+			frame = &p.dataBlockedFrame
 		case streamDataBlockedFrameType:
-			frame, err = parseStreamDataBlockedFrame(r, v)
+			err = parseStreamDataBlockedFrame(&p.streamDataBlockedFrame, r, v)
+			frame = &p.streamDataBlockedFrame
 		case bidiStreamBlockedFrameType, uniStreamBlockedFrameType:
-			frame, err = parseStreamsBlockedFrame(r, typ, v)
+			err = parseStreamsBlockedFrame(&p.streamsBlockedFrame, r, typ, v)
+			frame = &p.streamsBlockedFrame
 		case newConnectionIDFrameType:
 			frame, err = parseNewConnectionIDFrame(r, v)
 		case retireConnectionIDFrameType:
-			frame, err = parseRetireConnectionIDFrame(r, v)
+			err = parseRetireConnectionIDFrame(&p.retireConnectionIDFrame, r, v)
+			frame = &p.retireConnectionIDFrame
 		case pathChallengeFrameType:
-			frame, err = parsePathChallengeFrame(r, v)
+			err = parsePathChallengeFrame(&p.pathChallengeFrame, r, v)
+			frame = &p.pathChallengeFrame
 		case pathResponseFrameType:
-			frame, err = parsePathResponseFrame(r, v)
+			err = parsePathResponseFrame(&p.pathResponseFrame, r, v)
+			frame = &p.pathResponseFrame
 		case connectionCloseFrameType, applicationCloseFrameType:
 			frame, err = parseConnectionCloseFrame(r, typ, v)
 		case handshakeDoneFrameType:
 			frame = &HandshakeDoneFrame{}
+		case immediateAckFrameType:
+			if p.supportsImmediateAck {
+				frame = &ImmediateAckFrame{}
+				break
+			}
+			err = errors.New("unknown frame type")
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, typ, v)
 				break
 			}
-			fallthrough
+			err = errors.New("unknown frame type")
+		case observedAddressIPv4FrameType, observedAddressIPv6FrameType:
+			if p.supportsAddressDiscovery {
+				frame, err = parseObservedAddressFrame(r, typ, v)
+				break
+			}
+			err = errors.New("unknown frame type")
 		default:
+			if ft, ok := p.extensionFrames[typ]; ok {
+				frame, err = parseExtensionFrame(r, b, typ, ft)
+				break
+			}
 			err = errors.New("unknown frame type")
 		}
 	}
@@ -174,7 +231,7 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 		}
 	case protocol.Encryption0RTT:
 		switch f.(type) {
-		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame, *ExtensionFrame:
 			return false
 		default:
 			return true
@@ -189,3 +246,28 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 func (p *frameParser) SetAckDelayExponent(exp uint8) {
 	p.ackDelayExponent = exp
 }
+
+// RegisterExtensionFrame registers a frame type that is not defined by QUIC.
+// Frames of this type are parsed using the ExtensionFrameParser, and returned as ExtensionFrames.
+// They are only accepted in 1-RTT packets.
+func (p *frameParser) RegisterExtensionFrame(typ uint64, ackEliciting bool, parse ExtensionFrameParser) {
+	if p.extensionFrames == nil {
+		p.extensionFrames = make(map[uint64]extensionFrameType)
+	}
+	p.extensionFrames[typ] = extensionFrameType{ackEliciting: ackEliciting, parse: parse}
+}
+
+// IsKnownFrameType says if the frame type is defined by QUIC, or by one of the extensions implemented by quic-go.
+// These frame types can't be registered as extension frames.
+func IsKnownFrameType(typ uint64) bool {
+	switch typ {
+	case 0x0, pingFrameType, ackFrameType, ackECNFrameType, resetStreamFrameType, stopSendingFrameType,
+		cryptoFrameType, newTokenFrameType, maxDataFrameType, maxStreamDataFrameType,
+		bidiMaxStreamsFrameType, uniMaxStreamsFrameType, dataBlockedFrameType, streamDataBlockedFrameType,
+		bidiStreamBlockedFrameType, uniStreamBlockedFrameType, newConnectionIDFrameType, retireConnectionIDFrameType,
+		pathChallengeFrameType, pathResponseFrameType, connectionCloseFrameType, applicationCloseFrameType,
+		handshakeDoneFrameType, immediateAckFrameType, 0x30, 0x31, observedAddressIPv4FrameType, observedAddressIPv6FrameType:
+		return true
+	}
+	return typ&0xf8 == 0x8 // STREAM frames
+}
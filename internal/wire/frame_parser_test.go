@@ -1,10 +1,15 @@
 package wire
 
 import (
+	"crypto/rand"
+	"io"
+	"net/netip"
+	"testing"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -14,7 +19,7 @@ var _ = Describe("Frame parsing", func() {
 	var parser FrameParser
 
 	BeforeEach(func() {
-		parser = NewFrameParser(true)
+		parser = NewFrameParser(true, true, true)
 	})
 
 	It("returns nil if there's nothing more to read", func() {
@@ -24,6 +29,14 @@ var _ = Describe("Frame parsing", func() {
 		Expect(f).To(BeNil())
 	})
 
+	It("says if a frame is a probing frame", func() {
+		Expect(IsProbingFrame(&PathChallengeFrame{})).To(BeTrue())
+		Expect(IsProbingFrame(&PathResponseFrame{})).To(BeTrue())
+		Expect(IsProbingFrame(&NewConnectionIDFrame{})).To(BeTrue())
+		Expect(IsProbingFrame(&PingFrame{})).To(BeFalse())
+		Expect(IsProbingFrame(&StreamFrame{})).To(BeFalse())
+	})
+
 	It("skips PADDING frames", func() {
 		b := []byte{0, 0} // 2 PADDING frames
 		b, err := (&PingFrame{}).Append(b, protocol.Version1)
@@ -54,6 +67,20 @@ var _ = Describe("Frame parsing", func() {
 		Expect(l).To(Equal(1))
 	})
 
+	It("reuses the structs of control frames", func() {
+		b, err := (&MaxDataFrame{MaximumData: 1000}).Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		b, err = (&MaxDataFrame{MaximumData: 2000}).Append(b, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, f1, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f1).To(Equal(&MaxDataFrame{MaximumData: 1000}))
+		_, f2, err := parser.ParseNext(b[l:], protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f2).To(Equal(&MaxDataFrame{MaximumData: 2000}))
+		Expect(f2).To(BeIdenticalTo(f1))
+	})
+
 	It("unpacks ACK frames", func() {
 		f := &AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 0x13}}}
 		b, err := f.Append(nil, protocol.Version1)
@@ -315,7 +342,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("errors when DATAGRAM frames are not supported", func() {
-		parser = NewFrameParser(false)
+		parser = NewFrameParser(false, false, false)
 		f := &DatagramFrame{Data: []byte("foobar")}
 		b, err := f.Append(nil, protocol.Version1)
 		Expect(err).ToNot(HaveOccurred())
@@ -327,6 +354,129 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	It("unpacks OBSERVED_ADDRESS frames", func() {
+		f := &ObservedAddressFrame{SequenceNumber: 42, Address: netip.MustParseAddrPort("[2001:db8::1]:1234")}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("errors when OBSERVED_ADDRESS frames are not supported", func() {
+		parser = NewFrameParser(true, false, true)
+		f := &ObservedAddressFrame{Address: netip.MustParseAddrPort("1.2.3.4:1234")}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    observedAddressIPv4FrameType,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
+	It("unpacks IMMEDIATE_ACK frames", func() {
+		f := &ImmediateAckFrame{}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+		Expect(l).To(Equal(len(b)))
+	})
+
+	It("errors when IMMEDIATE_ACK frames are not supported", func() {
+		parser = NewFrameParser(true, true, false)
+		f := &ImmediateAckFrame{}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    immediateAckFrameType,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
+	Context("extension frames", func() {
+		// parseFoo parses a frame payload consisting of a one-byte length, followed by the data
+		parseFoo := func(b []byte) (any, int, error) {
+			if len(b) == 0 || len(b)-1 < int(b[0]) {
+				return nil, 0, io.EOF
+			}
+			return string(b[1 : 1+b[0]]), 1 + int(b[0]), nil
+		}
+
+		BeforeEach(func() {
+			parser.RegisterExtensionFrame(0x1337, true, parseFoo)
+		})
+
+		It("unpacks extension frames", func() {
+			b := quicvarint.Append(nil, 0x1337)
+			b = append(b, 3)
+			b = append(b, []byte("foo")...)
+			b = append(b, 0x1) // PING frame
+			l, frame, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&ExtensionFrame{
+				FrameType:    0x1337,
+				Data:         append([]byte{3}, []byte("foo")...),
+				Value:        "foo",
+				AckEliciting: true,
+			}))
+			Expect(l).To(Equal(len(b) - 1))
+			_, frame, err = parser.ParseNext(b[l:], protocol.Encryption1RTT, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(Equal(&PingFrame{}))
+		})
+
+		It("errors when the extension frame parser fails", func() {
+			b := quicvarint.Append(nil, 0x1337)
+			b = append(b, 5)
+			b = append(b, []byte("foo")...)
+			_, _, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    0x1337,
+				ErrorMessage: io.EOF.Error(),
+			}))
+		})
+
+		It("errors when the extension frame parser consumes more bytes than available", func() {
+			parser.RegisterExtensionFrame(0x1338, false, func(b []byte) (any, int, error) { return nil, len(b) + 1, nil })
+			b := quicvarint.Append(nil, 0x1338)
+			_, _, err := parser.ParseNext(b, protocol.Encryption1RTT, protocol.Version1)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    0x1338,
+				ErrorMessage: "invalid length returned by extension frame parser",
+			}))
+		})
+
+		It("errors on unregistered extension frames", func() {
+			_, _, err := parser.ParseNext(quicvarint.Append(nil, 0x1338), protocol.Encryption1RTT, protocol.Version1)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.FrameEncodingError,
+				FrameType:    0x1338,
+				ErrorMessage: "unknown frame type",
+			}))
+		})
+
+		It("knows which frame types are defined by QUIC", func() {
+			Expect(IsKnownFrameType(0x0)).To(BeTrue())
+			Expect(IsKnownFrameType(0x8)).To(BeTrue())
+			Expect(IsKnownFrameType(0xf)).To(BeTrue())
+			Expect(IsKnownFrameType(handshakeDoneFrameType)).To(BeTrue())
+			Expect(IsKnownFrameType(0x31)).To(BeTrue())
+			Expect(IsKnownFrameType(observedAddressIPv6FrameType)).To(BeTrue())
+			Expect(IsKnownFrameType(immediateAckFrameType)).To(BeTrue())
+			Expect(IsKnownFrameType(0x20)).To(BeFalse())
+			Expect(IsKnownFrameType(0x1337)).To(BeFalse())
+		})
+	})
+
 	It("errors on invalid type", func() {
 		_, _, err := parser.ParseNext(encodeVarInt(0x42), protocol.Encryption1RTT, protocol.Version1)
 		Expect(err).To(MatchError(&qerr.TransportError{
@@ -370,11 +520,15 @@ var _ = Describe("Frame parsing", func() {
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
 			&DatagramFrame{},
+			&ObservedAddressFrame{Address: netip.MustParseAddrPort("1.2.3.4:1234")},
+			&ImmediateAckFrame{},
+			&ExtensionFrame{FrameType: 0x1337, Data: []byte("foo")},
 		}
 
 		var framesSerialized [][]byte
 
 		BeforeEach(func() {
+			parser.RegisterExtensionFrame(0x1337, true, func(b []byte) (any, int, error) { return nil, len(b), nil })
 			framesSerialized = nil
 			for _, frame := range frames {
 				b, err := frame.Append(nil, protocol.Version1)
@@ -411,11 +565,11 @@ var _ = Describe("Frame parsing", func() {
 			}
 		})
 
-		It("rejects all frames but ACK, CRYPTO, CONNECTION_CLOSE, NEW_TOKEN, PATH_RESPONSE and RETIRE_CONNECTION_ID in 0-RTT packets", func() {
+		It("rejects ACK, CRYPTO, CONNECTION_CLOSE, NEW_TOKEN, PATH_RESPONSE, RETIRE_CONNECTION_ID and extension frames in 0-RTT packets", func() {
 			for i, b := range framesSerialized {
 				_, _, err := parser.ParseNext(b, protocol.Encryption0RTT, protocol.Version1)
 				switch frames[i].(type) {
-				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame, *ExtensionFrame:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
 					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level 0-RTT"))
@@ -433,3 +587,113 @@ var _ = Describe("Frame parsing", func() {
 		})
 	})
 })
+
+func parseFrames(b *testing.B, parser FrameParser, data []byte, frames ...Frame) {
+	for _, expectedFrame := range frames {
+		l, frame, err := parser.ParseNext(data, protocol.Encryption1RTT, protocol.Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		data = data[l:]
+		if frame == nil {
+			break
+		}
+		// Use a type switch instead of reflect.TypeOf, to avoid allocations.
+		switch f := frame.(type) {
+		case *StreamFrame:
+			if _, ok := expectedFrame.(*StreamFrame); !ok {
+				b.Fatalf("expected %T, got %T", expectedFrame, frame)
+			}
+			f.PutBack()
+		case *AckFrame:
+			if _, ok := expectedFrame.(*AckFrame); !ok {
+				b.Fatalf("expected %T, got %T", expectedFrame, frame)
+			}
+		case *MaxDataFrame, *MaxStreamDataFrame, *MaxStreamsFrame, *PingFrame, *ResetStreamFrame, *StopSendingFrame,
+			*DataBlockedFrame, *StreamDataBlockedFrame, *StreamsBlockedFrame, *RetireConnectionIDFrame,
+			*PathChallengeFrame, *PathResponseFrame, *HandshakeDoneFrame:
+		default:
+			b.Fatalf("unexpected frame type: %T", frame)
+		}
+	}
+}
+
+func BenchmarkParseStreamAndACK(b *testing.B) {
+	ack := &AckFrame{
+		AckRanges: []AckRange{
+			{Smallest: 5000, Largest: 5200},
+			{Smallest: 1, Largest: 4200},
+		},
+		DelayTime: 42 * time.Millisecond,
+		ECT0:      5000,
+		ECT1:      0,
+		ECNCE:     10,
+	}
+	sf := &StreamFrame{
+		StreamID:       1337,
+		Offset:         1e7,
+		Data:           make([]byte, 200),
+		DataLenPresent: true,
+	}
+	rand.Read(sf.Data)
+
+	data, err := ack.Append([]byte{}, protocol.Version1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err = sf.Append(data, protocol.Version1)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	parser := NewFrameParser(false, false, false)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseFrames(b, parser, data, ack, sf)
+	}
+}
+
+func BenchmarkParseOtherFrames(b *testing.B) {
+	maxDataFrame := &MaxDataFrame{MaximumData: 123456}
+	maxStreamsFrame := &MaxStreamsFrame{MaxStreamNum: 10}
+	maxStreamDataFrame := &MaxStreamDataFrame{StreamID: 1337, MaximumStreamData: 1e6}
+	resetStreamFrame := &ResetStreamFrame{StreamID: 87654, ErrorCode: 1234, FinalSize: 1e8}
+	frames := []Frame{
+		maxDataFrame,
+		maxStreamsFrame,
+		maxStreamDataFrame,
+		&DataBlockedFrame{MaximumData: 1000},
+		&StreamDataBlockedFrame{StreamID: 1337, MaximumStreamData: 100},
+		&StreamsBlockedFrame{StreamLimit: 10},
+		&PingFrame{},
+		resetStreamFrame,
+		&StopSendingFrame{StreamID: 1337, ErrorCode: 42},
+		&RetireConnectionIDFrame{SequenceNumber: 10},
+		&PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}},
+		&HandshakeDoneFrame{},
+	}
+	var buf []byte
+	for i, frame := range frames {
+		var err error
+		buf, err = frame.Append(buf, protocol.Version1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if i == len(frames)/2 {
+			// add 3 PADDING frames
+			buf = append(buf, 0)
+			buf = append(buf, 0)
+			buf = append(buf, 0)
+		}
+	}
+
+	parser := NewFrameParser(false, false, false)
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseFrames(b, parser, buf, frames...)
+	}
+}
@@ -0,0 +1,19 @@
+package wire
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// An ImmediateAckFrame is an IMMEDIATE_ACK frame (draft-ietf-quic-ack-frequency).
+// It asks the receiver to acknowledge the packet right away.
+type ImmediateAckFrame struct{}
+
+func (f *ImmediateAckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	return quicvarint.Append(b, immediateAckFrameType), nil
+}
+
+// Length of a written frame
+func (f *ImmediateAckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
+	return protocol.ByteCount(quicvarint.Len(immediateAckFrameType))
+}
@@ -14,4 +14,17 @@ type Frame interface {
 type FrameParser interface {
 	ParseNext([]byte, protocol.EncryptionLevel, protocol.VersionNumber) (int, Frame, error)
 	SetAckDelayExponent(uint8)
+	RegisterExtensionFrame(typ uint64, ackEliciting bool, parse ExtensionFrameParser)
+}
+
+// IsProbingFrame says if a frame is a probing frame, see section 9.1 of RFC 9000.
+// Packets that only contain probing frames don't cause the receiver to migrate to a new path.
+// PADDING frames are also probing frames, but they are never returned by the FrameParser.
+func IsProbingFrame(f Frame) bool {
+	switch f.(type) {
+	case *PathChallengeFrame, *PathResponseFrame, *NewConnectionIDFrame:
+		return true
+	default:
+		return false
+	}
 }
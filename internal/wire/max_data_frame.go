@@ -13,14 +13,13 @@ type MaxDataFrame struct {
 }
 
 // parseMaxDataFrame parses a MAX_DATA frame
-func parseMaxDataFrame(r *bytes.Reader, _ protocol.VersionNumber) (*MaxDataFrame, error) {
-	frame := &MaxDataFrame{}
+func parseMaxDataFrame(frame *MaxDataFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	byteOffset, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	frame.MaximumData = protocol.ByteCount(byteOffset)
-	return frame, nil
+	return nil
 }
 
 func (f *MaxDataFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -31,5 +30,5 @@ func (f *MaxDataFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error
 
 // Length of a written frame
 func (f *MaxDataFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.MaximumData))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.MaximumData)))
 }
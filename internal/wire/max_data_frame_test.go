@@ -16,7 +16,8 @@ var _ = Describe("MAX_DATA frame", func() {
 		It("accepts sample frame", func() {
 			data := encodeVarInt(0xdecafbad123456) // byte offset
 			b := bytes.NewReader(data)
-			frame, err := parseMaxDataFrame(b, protocol.Version1)
+			frame := &MaxDataFrame{}
+			err := parseMaxDataFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.MaximumData).To(Equal(protocol.ByteCount(0xdecafbad123456)))
 			Expect(b.Len()).To(BeZero())
@@ -24,10 +25,10 @@ var _ = Describe("MAX_DATA frame", func() {
 
 		It("errors on EOFs", func() {
 			data := encodeVarInt(0xdecafbad1234567) // byte offset
-			_, err := parseMaxDataFrame(bytes.NewReader(data), protocol.Version1)
+			err := parseMaxDataFrame(&MaxDataFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseMaxDataFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseMaxDataFrame(&MaxDataFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -38,7 +39,7 @@ var _ = Describe("MAX_DATA frame", func() {
 			f := &MaxDataFrame{
 				MaximumData: 0xdeadbeef,
 			}
-			Expect(f.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(0xdeadbeef)))
+			Expect(f.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(0xdeadbeef))))
 		})
 
 		It("writes a MAX_DATA frame", func() {
@@ -17,7 +17,8 @@ var _ = Describe("MAX_STREAM_DATA frame", func() {
 			data := encodeVarInt(0xdeadbeef)                 // Stream ID
 			data = append(data, encodeVarInt(0x12345678)...) // Offset
 			b := bytes.NewReader(data)
-			frame, err := parseMaxStreamDataFrame(b, protocol.Version1)
+			frame := &MaxStreamDataFrame{}
+			err := parseMaxStreamDataFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
 			Expect(frame.MaximumStreamData).To(Equal(protocol.ByteCount(0x12345678)))
@@ -28,10 +29,10 @@ var _ = Describe("MAX_STREAM_DATA frame", func() {
 			data := encodeVarInt(0xdeadbeef)                 // Stream ID
 			data = append(data, encodeVarInt(0x12345678)...) // Offset
 			b := bytes.NewReader(data)
-			_, err := parseMaxStreamDataFrame(b, protocol.Version1)
+			err := parseMaxStreamDataFrame(&MaxStreamDataFrame{}, b, protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseMaxStreamDataFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseMaxStreamDataFrame(&MaxStreamDataFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -43,7 +44,7 @@ var _ = Describe("MAX_STREAM_DATA frame", func() {
 				StreamID:          0x1337,
 				MaximumStreamData: 0xdeadbeef,
 			}
-			Expect(f.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.MaximumStreamData))))
+			Expect(f.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID))) + protocol.ByteCount(quicvarint.Len(uint64(f.MaximumStreamData)))))
 		})
 
 		It("writes a sample frame", func() {
@@ -14,8 +14,7 @@ type MaxStreamsFrame struct {
 	MaxStreamNum protocol.StreamNum
 }
 
-func parseMaxStreamsFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNumber) (*MaxStreamsFrame, error) {
-	f := &MaxStreamsFrame{}
+func parseMaxStreamsFrame(f *MaxStreamsFrame, r *bytes.Reader, typ uint64, _ protocol.VersionNumber) error {
 	switch typ {
 	case bidiMaxStreamsFrameType:
 		f.Type = protocol.StreamTypeBidi
@@ -24,13 +23,13 @@ func parseMaxStreamsFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNumber)
 	}
 	streamID, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	f.MaxStreamNum = protocol.StreamNum(streamID)
 	if f.MaxStreamNum > protocol.MaxStreamCount {
-		return nil, fmt.Errorf("%d exceeds the maximum stream count", f.MaxStreamNum)
+		return fmt.Errorf("%d exceeds the maximum stream count", f.MaxStreamNum)
 	}
-	return f, nil
+	return nil
 }
 
 func (f *MaxStreamsFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -46,5 +45,5 @@ func (f *MaxStreamsFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, er
 
 // Length of a written frame
 func (f *MaxStreamsFrame) Length(protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.MaxStreamNum))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.MaxStreamNum)))
 }
@@ -17,7 +17,8 @@ var _ = Describe("MAX_STREAMS frame", func() {
 		It("accepts a frame for a bidirectional stream", func() {
 			data := encodeVarInt(0xdecaf)
 			b := bytes.NewReader(data)
-			f, err := parseMaxStreamsFrame(b, bidiMaxStreamsFrameType, protocol.Version1)
+			f := &MaxStreamsFrame{}
+			err := parseMaxStreamsFrame(f, b, bidiMaxStreamsFrameType, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(f.Type).To(Equal(protocol.StreamTypeBidi))
 			Expect(f.MaxStreamNum).To(BeEquivalentTo(0xdecaf))
@@ -27,7 +28,8 @@ var _ = Describe("MAX_STREAMS frame", func() {
 		It("accepts a frame for a bidirectional stream", func() {
 			data := encodeVarInt(0xdecaf)
 			b := bytes.NewReader(data)
-			f, err := parseMaxStreamsFrame(b, uniMaxStreamsFrameType, protocol.Version1)
+			f := &MaxStreamsFrame{}
+			err := parseMaxStreamsFrame(f, b, uniMaxStreamsFrameType, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(f.Type).To(Equal(protocol.StreamTypeUni))
 			Expect(f.MaxStreamNum).To(BeEquivalentTo(0xdecaf))
@@ -37,10 +39,10 @@ var _ = Describe("MAX_STREAMS frame", func() {
 		It("errors on EOFs", func() {
 			const typ = 0x1d
 			data := encodeVarInt(0xdeadbeefcafe13)
-			_, err := parseMaxStreamsFrame(bytes.NewReader(data), typ, protocol.Version1)
+			err := parseMaxStreamsFrame(&MaxStreamsFrame{}, bytes.NewReader(data), typ, protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err = parseMaxStreamsFrame(bytes.NewReader(data[:i]), typ, protocol.Version1)
+				err = parseMaxStreamsFrame(&MaxStreamsFrame{}, bytes.NewReader(data[:i]), typ, protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -58,8 +60,8 @@ var _ = Describe("MAX_STREAMS frame", func() {
 				r := bytes.NewReader(b)
 				typ, err := quicvarint.Read(r)
 				Expect(err).ToNot(HaveOccurred())
-				frame, err := parseMaxStreamsFrame(r, typ, protocol.Version1)
-				Expect(err).ToNot(HaveOccurred())
+				frame := &MaxStreamsFrame{}
+				Expect(parseMaxStreamsFrame(frame, r, typ, protocol.Version1)).To(Succeed())
 				Expect(frame).To(Equal(f))
 			})
 
@@ -73,7 +75,7 @@ var _ = Describe("MAX_STREAMS frame", func() {
 				r := bytes.NewReader(b)
 				typ, err := quicvarint.Read(r)
 				Expect(err).ToNot(HaveOccurred())
-				_, err = parseMaxStreamsFrame(r, typ, protocol.Version1)
+				err = parseMaxStreamsFrame(&MaxStreamsFrame{}, r, typ, protocol.Version1)
 				Expect(err).To(MatchError(fmt.Sprintf("%d exceeds the maximum stream count", protocol.MaxStreamCount+1)))
 			})
 		}
@@ -106,7 +108,7 @@ var _ = Describe("MAX_STREAMS frame", func() {
 
 		It("has the correct length", func() {
 			frame := MaxStreamsFrame{MaxStreamNum: 0x1337}
-			Expect(frame.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(0x1337)))
+			Expect(frame.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(0x1337))))
 		})
 	})
 })
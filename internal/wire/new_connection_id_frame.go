@@ -69,5 +69,5 @@ func (f *NewConnectionIDFrame) Append(b []byte, _ protocol.VersionNumber) ([]byt
 
 // Length of a written frame
 func (f *NewConnectionIDFrame) Length(protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(f.SequenceNumber) + quicvarint.Len(f.RetirePriorTo) + 1 /* connection ID length */ + protocol.ByteCount(f.ConnectionID.Len()) + 16
+	return 1 + protocol.ByteCount(quicvarint.Len(f.SequenceNumber)) + protocol.ByteCount(quicvarint.Len(f.RetirePriorTo)) + 1 /* connection ID length */ + protocol.ByteCount(f.ConnectionID.Len()) + 16
 }
@@ -59,7 +59,7 @@ var _ = Describe("NEW_TOKEN frame", func() {
 
 		It("has the correct min length", func() {
 			frame := &NewTokenFrame{Token: []byte("foobar")}
-			Expect(frame.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(6) + 6))
+			Expect(frame.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(6)) + 6))
 		})
 	})
 })
@@ -0,0 +1,75 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// An ObservedAddressFrame is an OBSERVED_ADDRESS frame (draft-ietf-quic-address-discovery).
+// It carries the address that the sender observes for the receiver.
+type ObservedAddressFrame struct {
+	SequenceNumber uint64
+	Address        netip.AddrPort
+}
+
+func parseObservedAddressFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNumber) (*ObservedAddressFrame, error) {
+	seq, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	ipLen := 4
+	if typ == observedAddressIPv6FrameType {
+		ipLen = 16
+	}
+	ip := make([]byte, ipLen)
+	if _, err := io.ReadFull(r, ip); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	port, err := utils.BigEndian.ReadUint16(r)
+	if err != nil {
+		return nil, err
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return &ObservedAddressFrame{
+		SequenceNumber: seq,
+		Address:        netip.AddrPortFrom(addr, port),
+	}, nil
+}
+
+func (f *ObservedAddressFrame) isIPv4() bool {
+	return f.Address.Addr().Unmap().Is4()
+}
+
+func (f *ObservedAddressFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
+	if f.isIPv4() {
+		b = quicvarint.Append(b, observedAddressIPv4FrameType)
+	} else {
+		b = quicvarint.Append(b, observedAddressIPv6FrameType)
+	}
+	b = quicvarint.Append(b, f.SequenceNumber)
+	if f.isIPv4() {
+		ip := f.Address.Addr().Unmap().As4()
+		b = append(b, ip[:]...)
+	} else {
+		ip := f.Address.Addr().As16()
+		b = append(b, ip[:]...)
+	}
+	port := f.Address.Port()
+	return append(b, uint8(port>>8), uint8(port)), nil
+}
+
+// Length of a written frame
+func (f *ObservedAddressFrame) Length(protocol.VersionNumber) protocol.ByteCount {
+	if f.isIPv4() {
+		return protocol.ByteCount(quicvarint.Len(observedAddressIPv4FrameType)) + protocol.ByteCount(quicvarint.Len(f.SequenceNumber)) + 4 + 2
+	}
+	return protocol.ByteCount(quicvarint.Len(observedAddressIPv6FrameType)) + protocol.ByteCount(quicvarint.Len(f.SequenceNumber)) + 16 + 2
+}
@@ -0,0 +1,88 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"net/netip"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OBSERVED_ADDRESS frame", func() {
+	Context("when parsing", func() {
+		It("accepts a frame with an IPv4 address", func() {
+			data := encodeVarInt(0xdeadbeef)           // sequence number
+			data = append(data, []byte{1, 2, 3, 4}...) // IPv4 address
+			data = append(data, []byte{0x13, 0x37}...) // port
+			frame, err := parseObservedAddressFrame(bytes.NewReader(data), observedAddressIPv4FrameType, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
+			Expect(frame.Address).To(Equal(netip.MustParseAddrPort("1.2.3.4:4919")))
+		})
+
+		It("accepts a frame with an IPv6 address", func() {
+			ip := netip.MustParseAddr("2001:db8::1").As16()
+			data := encodeVarInt(42)                   // sequence number
+			data = append(data, ip[:]...)              // IPv6 address
+			data = append(data, []byte{0x13, 0x37}...) // port
+			frame, err := parseObservedAddressFrame(bytes.NewReader(data), observedAddressIPv6FrameType, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame.SequenceNumber).To(Equal(uint64(42)))
+			Expect(frame.Address).To(Equal(netip.MustParseAddrPort("[2001:db8::1]:4919")))
+		})
+
+		It("errors on EOFs", func() {
+			ip := netip.MustParseAddr("2001:db8::1").As16()
+			data := encodeVarInt(0xdeadbeef)
+			data = append(data, ip[:]...)
+			data = append(data, []byte{0x13, 0x37}...)
+			_, err := parseObservedAddressFrame(bytes.NewReader(data), observedAddressIPv6FrameType, protocol.Version1)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseObservedAddressFrame(bytes.NewReader(data[:i]), observedAddressIPv6FrameType, protocol.Version1)
+				Expect(err).To(MatchError(io.EOF))
+			}
+		})
+	})
+
+	Context("when writing", func() {
+		It("writes a frame with an IPv4 address", func() {
+			frame := &ObservedAddressFrame{SequenceNumber: 0x1337, Address: netip.MustParseAddrPort("1.2.3.4:4919")}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := quicvarint.Append(nil, observedAddressIPv4FrameType)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, []byte{1, 2, 3, 4, 0x13, 0x37}...)
+			Expect(b).To(Equal(expected))
+			Expect(b).To(HaveLen(int(frame.Length(protocol.Version1))))
+		})
+
+		It("writes IPv4-mapped IPv6 addresses as IPv4 addresses", func() {
+			frame := &ObservedAddressFrame{SequenceNumber: 0x1337, Address: netip.MustParseAddrPort("[::ffff:1.2.3.4]:4919")}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			expected := quicvarint.Append(nil, observedAddressIPv4FrameType)
+			expected = append(expected, encodeVarInt(0x1337)...)
+			expected = append(expected, []byte{1, 2, 3, 4, 0x13, 0x37}...)
+			Expect(b).To(Equal(expected))
+			Expect(b).To(HaveLen(int(frame.Length(protocol.Version1))))
+		})
+
+		It("writes a frame with an IPv6 address", func() {
+			frame := &ObservedAddressFrame{SequenceNumber: 0xdecafbad, Address: netip.MustParseAddrPort("[2001:db8::1]:4919")}
+			b, err := frame.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			ip := netip.MustParseAddr("2001:db8::1").As16()
+			expected := quicvarint.Append(nil, observedAddressIPv6FrameType)
+			expected = append(expected, encodeVarInt(0xdecafbad)...)
+			expected = append(expected, ip[:]...)
+			expected = append(expected, []byte{0x13, 0x37}...)
+			Expect(b).To(Equal(expected))
+			Expect(b).To(HaveLen(int(frame.Length(protocol.Version1))))
+		})
+	})
+})
@@ -12,15 +12,14 @@ type PathChallengeFrame struct {
 	Data [8]byte
 }
 
-func parsePathChallengeFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PathChallengeFrame, error) {
-	frame := &PathChallengeFrame{}
+func parsePathChallengeFrame(frame *PathChallengeFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	if _, err := io.ReadFull(r, frame.Data[:]); err != nil {
 		if err == io.ErrUnexpectedEOF {
-			return nil, io.EOF
+			return io.EOF
 		}
-		return nil, err
+		return err
 	}
-	return frame, nil
+	return nil
 }
 
 func (f *PathChallengeFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -14,7 +14,8 @@ var _ = Describe("PATH_CHALLENGE frame", func() {
 	Context("when parsing", func() {
 		It("accepts sample frame", func() {
 			b := bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})
-			f, err := parsePathChallengeFrame(b, protocol.Version1)
+			f := &PathChallengeFrame{}
+			err := parsePathChallengeFrame(f, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(b.Len()).To(BeZero())
 			Expect(f.Data).To(Equal([8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
@@ -23,10 +24,10 @@ var _ = Describe("PATH_CHALLENGE frame", func() {
 		It("errors on EOFs", func() {
 			data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
 			b := bytes.NewReader(data)
-			_, err := parsePathChallengeFrame(b, protocol.Version1)
+			err := parsePathChallengeFrame(&PathChallengeFrame{}, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			for i := range data {
-				_, err := parsePathChallengeFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parsePathChallengeFrame(&PathChallengeFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
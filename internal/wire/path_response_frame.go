@@ -12,15 +12,14 @@ type PathResponseFrame struct {
 	Data [8]byte
 }
 
-func parsePathResponseFrame(r *bytes.Reader, _ protocol.VersionNumber) (*PathResponseFrame, error) {
-	frame := &PathResponseFrame{}
+func parsePathResponseFrame(frame *PathResponseFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	if _, err := io.ReadFull(r, frame.Data[:]); err != nil {
 		if err == io.ErrUnexpectedEOF {
-			return nil, io.EOF
+			return io.EOF
 		}
-		return nil, err
+		return err
 	}
-	return frame, nil
+	return nil
 }
 
 func (f *PathResponseFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -14,7 +14,8 @@ var _ = Describe("PATH_RESPONSE frame", func() {
 	Context("when parsing", func() {
 		It("accepts sample frame", func() {
 			b := bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})
-			f, err := parsePathResponseFrame(b, protocol.Version1)
+			f := &PathResponseFrame{}
+			err := parsePathResponseFrame(f, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(b.Len()).To(BeZero())
 			Expect(f.Data).To(Equal([8]byte{1, 2, 3, 4, 5, 6, 7, 8}))
@@ -22,10 +23,10 @@ var _ = Describe("PATH_RESPONSE frame", func() {
 
 		It("errors on EOFs", func() {
 			data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
-			_, err := parsePathResponseFrame(bytes.NewReader(data), protocol.Version1)
+			err := parsePathResponseFrame(&PathResponseFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parsePathResponseFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parsePathResponseFrame(&PathResponseFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
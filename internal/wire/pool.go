@@ -4,11 +4,15 @@ import (
 	"sync"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 )
 
-var pool sync.Pool
+// allocatedPool holds StreamFrames without Data.
+// It is used when a custom BufferAllocator is set.
+var pool, allocatedPool sync.Pool
 
 func init() {
+	allocatedPool.New = func() interface{} { return &StreamFrame{} }
 	pool.New = func() interface{} {
 		return &StreamFrame{
 			Data:     make([]byte, 0, protocol.MaxPacketBufferSize),
@@ -18,6 +22,13 @@ func init() {
 }
 
 func GetStreamFrame() *StreamFrame {
+	if a := utils.GetBufferAllocator(); a != nil {
+		f := allocatedPool.Get().(*StreamFrame)
+		f.Data = utils.AllocateBuffer(a, utils.BufferSizeClassPacket)
+		f.fromPool = true
+		f.allocator = a
+		return f
+	}
 	f := pool.Get().(*StreamFrame)
 	return f
 }
@@ -26,6 +37,12 @@ func putStreamFrame(f *StreamFrame) {
 	if !f.fromPool {
 		return
 	}
+	if f.allocator != nil {
+		f.allocator.Put(utils.BufferSizeClassPacket, f.Data[:0])
+		*f = StreamFrame{}
+		allocatedPool.Put(f)
+		return
+	}
 	if protocol.ByteCount(cap(f.Data)) != protocol.MaxPacketBufferSize {
 		panic("wire.PutStreamFrame called with packet of wrong size!")
 	}
@@ -1,6 +1,9 @@
 package wire
 
 import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -21,4 +24,54 @@ var _ = Describe("Pool", func() {
 		f := &StreamFrame{Data: []byte("foobar")}
 		putStreamFrame(f)
 	})
+
+	Context("using a custom allocator", func() {
+		var allocator *countingAllocator
+
+		BeforeEach(func() {
+			allocator = &countingAllocator{}
+			utils.SetBufferAllocator(allocator)
+		})
+
+		AfterEach(func() { utils.SetBufferAllocator(nil) })
+
+		It("gets and puts STREAM frames", func() {
+			f := GetStreamFrame()
+			Expect(f.Data).To(BeEmpty())
+			Expect(f.Data).To(HaveCap(protocol.MaxPacketBufferSize))
+			Expect(allocator.gets).To(Equal(1))
+			putStreamFrame(f)
+			Expect(allocator.puts).To(Equal(1))
+		})
+
+		It("returns the buffer to the allocator it was obtained from", func() {
+			f := GetStreamFrame()
+			utils.SetBufferAllocator(nil)
+			putStreamFrame(f)
+			Expect(allocator.puts).To(Equal(1))
+		})
+
+		It("returns both buffers when splitting STREAM frames", func() {
+			f := GetStreamFrame()
+			f.Data = append(f.Data, make([]byte, 1000)...)
+			f.DataLenPresent = true
+			new, needsSplit := f.MaybeSplitOffFrame(500, protocol.Version1)
+			Expect(needsSplit).To(BeTrue())
+			Expect(allocator.gets).To(Equal(2))
+			new.PutBack()
+			f.PutBack()
+			Expect(allocator.puts).To(Equal(2))
+		})
+	})
 })
+
+type countingAllocator struct {
+	gets, puts int
+}
+
+func (a *countingAllocator) Get(class utils.BufferSizeClass) []byte {
+	a.gets++
+	return make([]byte, 0, class.Size())
+}
+
+func (a *countingAllocator) Put(utils.BufferSizeClass, []byte) { a.puts++ }
@@ -15,29 +15,23 @@ type ResetStreamFrame struct {
 	FinalSize protocol.ByteCount
 }
 
-func parseResetStreamFrame(r *bytes.Reader, _ protocol.VersionNumber) (*ResetStreamFrame, error) {
-	var streamID protocol.StreamID
-	var byteOffset protocol.ByteCount
+func parseResetStreamFrame(frame *ResetStreamFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	sid, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	streamID = protocol.StreamID(sid)
 	errorCode, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	bo, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	byteOffset = protocol.ByteCount(bo)
-
-	return &ResetStreamFrame{
-		StreamID:  streamID,
-		ErrorCode: qerr.StreamErrorCode(errorCode),
-		FinalSize: byteOffset,
-	}, nil
+	frame.StreamID = protocol.StreamID(sid)
+	frame.ErrorCode = qerr.StreamErrorCode(errorCode)
+	frame.FinalSize = protocol.ByteCount(bo)
+	return nil
 }
 
 func (f *ResetStreamFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -50,5 +44,5 @@ func (f *ResetStreamFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, e
 
 // Length of a written frame
 func (f *ResetStreamFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.ErrorCode)) + quicvarint.Len(uint64(f.FinalSize))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID))) + protocol.ByteCount(quicvarint.Len(uint64(f.ErrorCode))) + protocol.ByteCount(quicvarint.Len(uint64(f.FinalSize)))
 }
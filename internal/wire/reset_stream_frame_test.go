@@ -18,7 +18,8 @@ var _ = Describe("RESET_STREAM frame", func() {
 			data = append(data, encodeVarInt(0x1337)...)      // error code
 			data = append(data, encodeVarInt(0x987654321)...) // byte offset
 			b := bytes.NewReader(data)
-			frame, err := parseResetStreamFrame(b, protocol.Version1)
+			frame := &ResetStreamFrame{}
+			err := parseResetStreamFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
 			Expect(frame.FinalSize).To(Equal(protocol.ByteCount(0x987654321)))
@@ -29,10 +30,10 @@ var _ = Describe("RESET_STREAM frame", func() {
 			data := encodeVarInt(0xdeadbeef)                  // stream ID
 			data = append(data, encodeVarInt(0x1337)...)      // error code
 			data = append(data, encodeVarInt(0x987654321)...) // byte offset
-			_, err := parseResetStreamFrame(bytes.NewReader(data), protocol.Version1)
+			err := parseResetStreamFrame(&ResetStreamFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseResetStreamFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseResetStreamFrame(&ResetStreamFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(HaveOccurred())
 			}
 		})
@@ -60,7 +61,7 @@ var _ = Describe("RESET_STREAM frame", func() {
 				FinalSize: 0x1234567,
 				ErrorCode: 0xde,
 			}
-			expectedLen := 1 + quicvarint.Len(0x1337) + quicvarint.Len(0x1234567) + 2
+			expectedLen := 1 + protocol.ByteCount(quicvarint.Len(0x1337)) + protocol.ByteCount(quicvarint.Len(0x1234567)) + 2
 			Expect(rst.Length(protocol.Version1)).To(Equal(expectedLen))
 		})
 	})
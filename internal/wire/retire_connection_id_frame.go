@@ -12,12 +12,13 @@ type RetireConnectionIDFrame struct {
 	SequenceNumber uint64
 }
 
-func parseRetireConnectionIDFrame(r *bytes.Reader, _ protocol.VersionNumber) (*RetireConnectionIDFrame, error) {
+func parseRetireConnectionIDFrame(frame *RetireConnectionIDFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	seq, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &RetireConnectionIDFrame{SequenceNumber: seq}, nil
+	frame.SequenceNumber = seq
+	return nil
 }
 
 func (f *RetireConnectionIDFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -28,5 +29,5 @@ func (f *RetireConnectionIDFrame) Append(b []byte, _ protocol.VersionNumber) ([]
 
 // Length of a written frame
 func (f *RetireConnectionIDFrame) Length(protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(f.SequenceNumber)
+	return 1 + protocol.ByteCount(quicvarint.Len(f.SequenceNumber))
 }
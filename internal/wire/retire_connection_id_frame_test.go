@@ -15,17 +15,18 @@ var _ = Describe("NEW_CONNECTION_ID frame", func() {
 		It("accepts a sample frame", func() {
 			data := encodeVarInt(0xdeadbeef) // sequence number
 			b := bytes.NewReader(data)
-			frame, err := parseRetireConnectionIDFrame(b, protocol.Version1)
+			frame := &RetireConnectionIDFrame{}
+			err := parseRetireConnectionIDFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.SequenceNumber).To(Equal(uint64(0xdeadbeef)))
 		})
 
 		It("errors on EOFs", func() {
 			data := encodeVarInt(0xdeadbeef) // sequence number
-			_, err := parseRetireConnectionIDFrame(bytes.NewReader(data), protocol.Version1)
+			err := parseRetireConnectionIDFrame(&RetireConnectionIDFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseRetireConnectionIDFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseRetireConnectionIDFrame(&RetireConnectionIDFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
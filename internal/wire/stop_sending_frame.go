@@ -15,25 +15,23 @@ type StopSendingFrame struct {
 }
 
 // parseStopSendingFrame parses a STOP_SENDING frame
-func parseStopSendingFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StopSendingFrame, error) {
+func parseStopSendingFrame(frame *StopSendingFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	streamID, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	errorCode, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return &StopSendingFrame{
-		StreamID:  protocol.StreamID(streamID),
-		ErrorCode: qerr.StreamErrorCode(errorCode),
-	}, nil
+	frame.StreamID = protocol.StreamID(streamID)
+	frame.ErrorCode = qerr.StreamErrorCode(errorCode)
+	return nil
 }
 
 // Length of a written frame
 func (f *StopSendingFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.ErrorCode))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID))) + protocol.ByteCount(quicvarint.Len(uint64(f.ErrorCode)))
 }
 
 func (f *StopSendingFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
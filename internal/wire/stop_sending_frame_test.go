@@ -18,7 +18,8 @@ var _ = Describe("STOP_SENDING frame", func() {
 			data := encodeVarInt(0xdecafbad)             // stream ID
 			data = append(data, encodeVarInt(0x1337)...) // error code
 			b := bytes.NewReader(data)
-			frame, err := parseStopSendingFrame(b, protocol.Version1)
+			frame := &StopSendingFrame{}
+			err := parseStopSendingFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdecafbad)))
 			Expect(frame.ErrorCode).To(Equal(qerr.StreamErrorCode(0x1337)))
@@ -29,10 +30,10 @@ var _ = Describe("STOP_SENDING frame", func() {
 			data := encodeVarInt(0xdecafbad)               // stream ID
 			data = append(data, encodeVarInt(0x123456)...) // error code
 			b := bytes.NewReader(data)
-			_, err := parseStopSendingFrame(b, protocol.Version1)
+			err := parseStopSendingFrame(&StopSendingFrame{}, b, protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseStopSendingFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseStopSendingFrame(&StopSendingFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -57,7 +58,7 @@ var _ = Describe("STOP_SENDING frame", func() {
 				StreamID:  0xdeadbeef,
 				ErrorCode: 0x1234567,
 			}
-			Expect(frame.Length(protocol.Version1)).To(Equal(1 + quicvarint.Len(0xdeadbeef) + quicvarint.Len(0x1234567)))
+			Expect(frame.Length(protocol.Version1)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(0xdeadbeef)) + protocol.ByteCount(quicvarint.Len(0x1234567))))
 		})
 	})
 })
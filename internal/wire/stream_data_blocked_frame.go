@@ -13,20 +13,18 @@ type StreamDataBlockedFrame struct {
 	MaximumStreamData protocol.ByteCount
 }
 
-func parseStreamDataBlockedFrame(r *bytes.Reader, _ protocol.VersionNumber) (*StreamDataBlockedFrame, error) {
+func parseStreamDataBlockedFrame(frame *StreamDataBlockedFrame, r *bytes.Reader, _ protocol.VersionNumber) error {
 	sid, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	offset, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return &StreamDataBlockedFrame{
-		StreamID:          protocol.StreamID(sid),
-		MaximumStreamData: protocol.ByteCount(offset),
-	}, nil
+	frame.StreamID = protocol.StreamID(sid)
+	frame.MaximumStreamData = protocol.ByteCount(offset)
+	return nil
 }
 
 func (f *StreamDataBlockedFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -38,5 +36,5 @@ func (f *StreamDataBlockedFrame) Append(b []byte, _ protocol.VersionNumber) ([]b
 
 // Length of a written frame
 func (f *StreamDataBlockedFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.StreamID)) + quicvarint.Len(uint64(f.MaximumStreamData))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID))) + protocol.ByteCount(quicvarint.Len(uint64(f.MaximumStreamData)))
 }
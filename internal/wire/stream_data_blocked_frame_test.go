@@ -17,7 +17,8 @@ var _ = Describe("STREAM_DATA_BLOCKED frame", func() {
 			data := encodeVarInt(0xdeadbeef)                 // stream ID
 			data = append(data, encodeVarInt(0xdecafbad)...) // offset
 			b := bytes.NewReader(data)
-			frame, err := parseStreamDataBlockedFrame(b, protocol.Version1)
+			frame := &StreamDataBlockedFrame{}
+			err := parseStreamDataBlockedFrame(frame, b, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(frame.StreamID).To(Equal(protocol.StreamID(0xdeadbeef)))
 			Expect(frame.MaximumStreamData).To(Equal(protocol.ByteCount(0xdecafbad)))
@@ -27,10 +28,10 @@ var _ = Describe("STREAM_DATA_BLOCKED frame", func() {
 		It("errors on EOFs", func() {
 			data := encodeVarInt(0xdeadbeef)
 			data = append(data, encodeVarInt(0xc0010ff)...)
-			_, err := parseStreamDataBlockedFrame(bytes.NewReader(data), protocol.Version1)
+			err := parseStreamDataBlockedFrame(&StreamDataBlockedFrame{}, bytes.NewReader(data), protocol.Version1)
 			Expect(err).NotTo(HaveOccurred())
 			for i := range data {
-				_, err := parseStreamDataBlockedFrame(bytes.NewReader(data[:i]), protocol.Version1)
+				err := parseStreamDataBlockedFrame(&StreamDataBlockedFrame{}, bytes.NewReader(data[:i]), protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -42,7 +43,7 @@ var _ = Describe("STREAM_DATA_BLOCKED frame", func() {
 				StreamID:          0x1337,
 				MaximumStreamData: 0xdeadbeef,
 			}
-			Expect(f.Length(0)).To(Equal(1 + quicvarint.Len(0x1337) + quicvarint.Len(0xdeadbeef)))
+			Expect(f.Length(0)).To(Equal(1 + protocol.ByteCount(quicvarint.Len(0x1337)) + protocol.ByteCount(quicvarint.Len(0xdeadbeef))))
 		})
 
 		It("writes a sample frame", func() {
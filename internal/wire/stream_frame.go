@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/quicvarint"
 )
 
@@ -18,6 +19,9 @@ type StreamFrame struct {
 	DataLenPresent bool
 
 	fromPool bool
+	// allocator is the BufferAllocator that Data was obtained from.
+	// It is nil if Data was obtained from the internal pool.
+	allocator utils.BufferAllocator
 }
 
 func parseStreamFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNumber) (*StreamFrame, error) {
@@ -109,12 +113,12 @@ func (f *StreamFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error)
 
 // Length returns the total length of the STREAM frame
 func (f *StreamFrame) Length(version protocol.VersionNumber) protocol.ByteCount {
-	length := 1 + quicvarint.Len(uint64(f.StreamID))
+	length := 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID)))
 	if f.Offset != 0 {
-		length += quicvarint.Len(uint64(f.Offset))
+		length += protocol.ByteCount(quicvarint.Len(uint64(f.Offset)))
 	}
 	if f.DataLenPresent {
-		length += quicvarint.Len(uint64(f.DataLen()))
+		length += protocol.ByteCount(quicvarint.Len(uint64(f.DataLen())))
 	}
 	return length + f.DataLen()
 }
@@ -127,9 +131,9 @@ func (f *StreamFrame) DataLen() protocol.ByteCount {
 // MaxDataLen returns the maximum data length
 // If 0 is returned, writing will fail (a STREAM frame must contain at least 1 byte of data).
 func (f *StreamFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.VersionNumber) protocol.ByteCount {
-	headerLen := 1 + quicvarint.Len(uint64(f.StreamID))
+	headerLen := 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamID)))
 	if f.Offset != 0 {
-		headerLen += quicvarint.Len(uint64(f.Offset))
+		headerLen += protocol.ByteCount(quicvarint.Len(uint64(f.Offset)))
 	}
 	if f.DataLenPresent {
 		// pretend that the data size will be 1 bytes
@@ -140,7 +144,7 @@ func (f *StreamFrame) MaxDataLen(maxSize protocol.ByteCount, version protocol.Ve
 		return 0
 	}
 	maxDataLen := maxSize - headerLen
-	if f.DataLenPresent && quicvarint.Len(uint64(maxDataLen)) != 1 {
+	if f.DataLenPresent && protocol.ByteCount(quicvarint.Len(uint64(maxDataLen))) != 1 {
 		maxDataLen--
 	}
 	return maxDataLen
@@ -170,6 +174,7 @@ func (f *StreamFrame) MaybeSplitOffFrame(maxSize protocol.ByteCount, version pro
 	// swap the data slices
 	new.Data, f.Data = f.Data, new.Data
 	new.fromPool, f.fromPool = f.fromPool, new.fromPool
+	new.allocator, f.allocator = f.allocator, new.allocator
 
 	f.Data = f.Data[:protocol.ByteCount(len(new.Data))-n]
 	copy(f.Data, new.Data[n:])
@@ -14,8 +14,7 @@ type StreamsBlockedFrame struct {
 	StreamLimit protocol.StreamNum
 }
 
-func parseStreamsBlockedFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNumber) (*StreamsBlockedFrame, error) {
-	f := &StreamsBlockedFrame{}
+func parseStreamsBlockedFrame(f *StreamsBlockedFrame, r *bytes.Reader, typ uint64, _ protocol.VersionNumber) error {
 	switch typ {
 	case bidiStreamBlockedFrameType:
 		f.Type = protocol.StreamTypeBidi
@@ -24,13 +23,13 @@ func parseStreamsBlockedFrame(r *bytes.Reader, typ uint64, _ protocol.VersionNum
 	}
 	streamLimit, err := quicvarint.Read(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	f.StreamLimit = protocol.StreamNum(streamLimit)
 	if f.StreamLimit > protocol.MaxStreamCount {
-		return nil, fmt.Errorf("%d exceeds the maximum stream count", f.StreamLimit)
+		return fmt.Errorf("%d exceeds the maximum stream count", f.StreamLimit)
 	}
-	return f, nil
+	return nil
 }
 
 func (f *StreamsBlockedFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
@@ -46,5 +45,5 @@ func (f *StreamsBlockedFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte
 
 // Length of a written frame
 func (f *StreamsBlockedFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
-	return 1 + quicvarint.Len(uint64(f.StreamLimit))
+	return 1 + protocol.ByteCount(quicvarint.Len(uint64(f.StreamLimit)))
 }
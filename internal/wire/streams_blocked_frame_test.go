@@ -17,7 +17,8 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 		It("accepts a frame for bidirectional streams", func() {
 			expected := encodeVarInt(0x1337)
 			b := bytes.NewReader(expected)
-			f, err := parseStreamsBlockedFrame(b, bidiStreamBlockedFrameType, protocol.Version1)
+			f := &StreamsBlockedFrame{}
+			err := parseStreamsBlockedFrame(f, b, bidiStreamBlockedFrameType, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(f.Type).To(Equal(protocol.StreamTypeBidi))
 			Expect(f.StreamLimit).To(BeEquivalentTo(0x1337))
@@ -27,7 +28,8 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 		It("accepts a frame for unidirectional streams", func() {
 			expected := encodeVarInt(0x7331)
 			b := bytes.NewReader(expected)
-			f, err := parseStreamsBlockedFrame(b, uniStreamBlockedFrameType, protocol.Version1)
+			f := &StreamsBlockedFrame{}
+			err := parseStreamsBlockedFrame(f, b, uniStreamBlockedFrameType, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(f.Type).To(Equal(protocol.StreamTypeUni))
 			Expect(f.StreamLimit).To(BeEquivalentTo(0x7331))
@@ -37,10 +39,10 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 		It("errors on EOFs", func() {
 			data := encodeVarInt(0x12345678)
 			b := bytes.NewReader(data)
-			_, err := parseStreamsBlockedFrame(b, bidiStreamBlockedFrameType, protocol.Version1)
+			err := parseStreamsBlockedFrame(&StreamsBlockedFrame{}, b, bidiStreamBlockedFrameType, protocol.Version1)
 			Expect(err).ToNot(HaveOccurred())
 			for i := range data {
-				_, err := parseStreamsBlockedFrame(bytes.NewReader(data[:i]), bidiStreamBlockedFrameType, protocol.Version1)
+				err := parseStreamsBlockedFrame(&StreamsBlockedFrame{}, bytes.NewReader(data[:i]), bidiStreamBlockedFrameType, protocol.Version1)
 				Expect(err).To(MatchError(io.EOF))
 			}
 		})
@@ -58,8 +60,8 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 				r := bytes.NewReader(b)
 				typ, err := quicvarint.Read(r)
 				Expect(err).ToNot(HaveOccurred())
-				frame, err := parseStreamsBlockedFrame(r, typ, protocol.Version1)
-				Expect(err).ToNot(HaveOccurred())
+				frame := &StreamsBlockedFrame{}
+				Expect(parseStreamsBlockedFrame(frame, r, typ, protocol.Version1)).To(Succeed())
 				Expect(frame).To(Equal(f))
 			})
 
@@ -73,7 +75,7 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 				r := bytes.NewReader(b)
 				typ, err := quicvarint.Read(r)
 				Expect(err).ToNot(HaveOccurred())
-				_, err = parseStreamsBlockedFrame(r, typ, protocol.Version1)
+				err = parseStreamsBlockedFrame(&StreamsBlockedFrame{}, r, typ, protocol.Version1)
 				Expect(err).To(MatchError(fmt.Sprintf("%d exceeds the maximum stream count", protocol.MaxStreamCount+1)))
 			})
 		}
@@ -106,7 +108,7 @@ var _ = Describe("STREAMS_BLOCKED frame", func() {
 
 		It("has the correct min length", func() {
 			frame := StreamsBlockedFrame{StreamLimit: 0x123456}
-			Expect(frame.Length(0)).To(Equal(protocol.ByteCount(1) + quicvarint.Len(0x123456)))
+			Expect(frame.Length(0)).To(Equal(protocol.ByteCount(1) + protocol.ByteCount(quicvarint.Len(0x123456))))
 		})
 	})
 })
@@ -59,8 +59,12 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:             &protocol.StatelessResetToken{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 			ActiveConnectionIDLimit:         123,
 			MaxDatagramFrameSize:            876,
+			AddressDiscoveryMode:            protocol.AddressDiscoveryReceive,
+			ImmediateAck:                    true,
+			NullEncryption:                  true,
+			CustomParameters:                map[uint64][]byte{0x1337: nil, 0x42: []byte("foo")},
 		}
-		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876}"))
+		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876, AddressDiscoveryMode: receive, ImmediateAck: true, NullEncryption: true, CustomParameters: [0x42 0x1337]}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token, no Retry source connection id and no datagram support", func() {
@@ -103,6 +107,10 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         2 + getRandomValueUpTo(math.MaxInt64-2),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			AddressDiscoveryMode:            protocol.AddressDiscoveryProvideAndReceive,
+			ImmediateAck:                    true,
+			NullEncryption:                  true,
+			CustomParameters:                map[uint64][]byte{0x1337: {}, 0x42: []byte("foobar")},
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -124,6 +132,39 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.AddressDiscoveryMode).To(Equal(protocol.AddressDiscoveryProvideAndReceive))
+		Expect(p.ImmediateAck).To(BeTrue())
+		Expect(p.NullEncryption).To(BeTrue())
+		Expect(p.CustomParameters).To(Equal(params.CustomParameters))
+	})
+
+	It("marshals and unmarshals all address discovery modes", func() {
+		for _, mode := range []protocol.AddressDiscoveryMode{
+			protocol.AddressDiscoveryDisabled,
+			protocol.AddressDiscoveryProvide,
+			protocol.AddressDiscoveryReceive,
+			protocol.AddressDiscoveryProvideAndReceive,
+		} {
+			data := (&TransportParameters{
+				ActiveConnectionIDLimit: protocol.DefaultActiveConnectionIDLimit,
+				AddressDiscoveryMode:    mode,
+			}).Marshal(protocol.PerspectiveClient)
+			p := &TransportParameters{}
+			Expect(p.Unmarshal(data, protocol.PerspectiveClient)).To(Succeed())
+			Expect(p.AddressDiscoveryMode).To(Equal(mode))
+		}
+	})
+
+	It("errors when the address_discovery value is invalid", func() {
+		b := quicvarint.Append(nil, uint64(addressDiscoveryParameterID))
+		b = quicvarint.Append(b, 1)
+		b = quicvarint.Append(b, 3)
+		b = append(b, appendInitialSourceConnectionID(nil)...)
+		p := &TransportParameters{}
+		Expect(p.Unmarshal(b, protocol.PerspectiveClient)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "invalid value for address_discovery: 3",
+		}))
 	})
 
 	It("marshals additional transport parameters (used for testing large ClientHellos)", func() {
@@ -195,6 +236,26 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("errors when immediate_ack has content", func() {
+		b := quicvarint.Append(nil, uint64(immediateAckParameterID))
+		b = quicvarint.Append(b, 6)
+		b = append(b, []byte("foobar")...)
+		Expect((&TransportParameters{}).Unmarshal(b, protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for immediate_ack: 6 (expected empty)",
+		}))
+	})
+
+	It("errors when null_encryption has content", func() {
+		b := quicvarint.Append(nil, uint64(nullEncryptionParameterID))
+		b = quicvarint.Append(b, 6)
+		b = append(b, []byte("foobar")...)
+		Expect((&TransportParameters{}).Unmarshal(b, protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for null_encryption: 6 (expected empty)",
+		}))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := quicvarint.Append(nil, uint64(statelessResetTokenParameterID))
 		b = quicvarint.Append(b, 16)
@@ -242,7 +303,7 @@ var _ = Describe("Transport Parameters", func() {
 			}).Marshal(protocol.PerspectiveServer)
 			dataLen += len(data)
 		}
-		entryLen := quicvarint.Len(uint64(ackDelayExponentParameterID)) /* parameter id */ + quicvarint.Len(uint64(quicvarint.Len(uint64(maxAckDelay.Milliseconds())))) /*length */ + quicvarint.Len(uint64(maxAckDelay.Milliseconds())) /* value */
+		entryLen := protocol.ByteCount(quicvarint.Len(uint64(ackDelayExponentParameterID))) /* parameter id */ + protocol.ByteCount(quicvarint.Len(uint64(quicvarint.Len(uint64(maxAckDelay.Milliseconds()))))) /*length */ + protocol.ByteCount(quicvarint.Len(uint64(maxAckDelay.Milliseconds()))) /* value */
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+float32(entryLen), 1))
 	})
 
@@ -286,7 +347,7 @@ var _ = Describe("Transport Parameters", func() {
 			}).Marshal(protocol.PerspectiveServer)
 			dataLen += len(data)
 		}
-		entryLen := quicvarint.Len(uint64(ackDelayExponentParameterID)) /* parameter id */ + quicvarint.Len(uint64(quicvarint.Len(protocol.DefaultAckDelayExponent+1))) /* length */ + quicvarint.Len(protocol.DefaultAckDelayExponent+1) /* value */
+		entryLen := protocol.ByteCount(quicvarint.Len(uint64(ackDelayExponentParameterID))) /* parameter id */ + protocol.ByteCount(quicvarint.Len(uint64(quicvarint.Len(protocol.DefaultAckDelayExponent+1)))) /* length */ + protocol.ByteCount(quicvarint.Len(protocol.DefaultAckDelayExponent+1)) /* value */
 		Expect(float32(dataLen) / num).To(BeNumerically("~", float32(defaultLen)/num+float32(entryLen), 1))
 	})
 
@@ -349,7 +410,7 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
-	It("skips unknown parameters", func() {
+	It("retains unknown parameters", func() {
 		// write a known parameter
 		b := quicvarint.Append(nil, uint64(initialMaxStreamDataBidiLocalParameterID))
 		b = quicvarint.Append(b, uint64(quicvarint.Len(0x1337)))
@@ -358,6 +419,10 @@ var _ = Describe("Transport Parameters", func() {
 		b = quicvarint.Append(b, 0x42)
 		b = quicvarint.Append(b, 6)
 		b = append(b, []byte("foobar")...)
+		// write a reserved parameter
+		b = quicvarint.Append(b, 27+31*5)
+		b = quicvarint.Append(b, 3)
+		b = append(b, []byte("foo")...)
 		// write a known parameter
 		b = quicvarint.Append(b, uint64(initialMaxStreamDataBidiRemoteParameterID))
 		b = quicvarint.Append(b, uint64(quicvarint.Len(0x42)))
@@ -367,6 +432,14 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.Unmarshal(b, protocol.PerspectiveClient)).To(Succeed())
 		Expect(p.InitialMaxStreamDataBidiLocal).To(Equal(protocol.ByteCount(0x1337)))
 		Expect(p.InitialMaxStreamDataBidiRemote).To(Equal(protocol.ByteCount(0x42)))
+		Expect(p.CustomParameters).To(Equal(map[uint64][]byte{0x42: []byte("foobar")}))
+	})
+
+	It("knows which transport parameters are implemented", func() {
+		Expect(IsKnownTransportParameter(uint64(initialMaxDataParameterID))).To(BeTrue())
+		Expect(IsKnownTransportParameter(uint64(maxDatagramFrameSizeParameterID))).To(BeTrue())
+		Expect(IsKnownTransportParameter(uint64(addressDiscoveryParameterID))).To(BeTrue())
+		Expect(IsKnownTransportParameter(0x42)).To(BeFalse())
 	})
 
 	It("rejects duplicate parameters", func() {
@@ -528,7 +601,7 @@ var _ = Describe("Transport Parameters", func() {
 			var p TransportParameters
 			data := p.MarshalForSessionTicket(nil)
 			b := quicvarint.Append(nil, transportParameterMarshalingVersion+1)
-			b = append(b, data[quicvarint.Len(transportParameterMarshalingVersion):]...)
+			b = append(b, data[protocol.ByteCount(quicvarint.Len(transportParameterMarshalingVersion)):]...)
 			Expect(p.UnmarshalFromSessionTicket(bytes.NewReader(b))).To(MatchError(fmt.Sprintf("unknown transport parameter marshaling version: %d", transportParameterMarshalingVersion+1)))
 		})
 
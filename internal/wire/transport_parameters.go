@@ -47,8 +47,47 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// RFC 9221
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// draft-ietf-quic-address-discovery
+	addressDiscoveryParameterID transportParameterID = 0x9f81a176
+	// Advertises support for receiving IMMEDIATE_ACK frames (draft-ietf-quic-ack-frequency),
+	// without supporting the rest of the ACK frequency extension.
+	// This parameter is specific to quic-go.
+	immediateAckParameterID transportParameterID = 0xff04de1f
+	// Advertises support for protecting 1-RTT packets with a null AEAD.
+	// This parameter is specific to quic-go, and only sent by builds using the quic_nullcrypto build tag.
+	nullEncryptionParameterID transportParameterID = 0xff04de20
 )
 
+// IsKnownTransportParameter says if the transport parameter is defined by QUIC,
+// or by one of the extensions implemented by quic-go.
+func IsKnownTransportParameter(id uint64) bool {
+	switch transportParameterID(id) {
+	case originalDestinationConnectionIDParameterID,
+		maxIdleTimeoutParameterID,
+		statelessResetTokenParameterID,
+		maxUDPPayloadSizeParameterID,
+		initialMaxDataParameterID,
+		initialMaxStreamDataBidiLocalParameterID,
+		initialMaxStreamDataBidiRemoteParameterID,
+		initialMaxStreamDataUniParameterID,
+		initialMaxStreamsBidiParameterID,
+		initialMaxStreamsUniParameterID,
+		ackDelayExponentParameterID,
+		maxAckDelayParameterID,
+		disableActiveMigrationParameterID,
+		preferredAddressParameterID,
+		activeConnectionIDLimitParameterID,
+		initialSourceConnectionIDParameterID,
+		retrySourceConnectionIDParameterID,
+		maxDatagramFrameSizeParameterID,
+		addressDiscoveryParameterID,
+		immediateAckParameterID,
+		nullEncryptionParameterID:
+		return true
+	}
+	return false
+}
+
 // PreferredAddress is the value encoding in the preferred_address transport parameter
 type PreferredAddress struct {
 	IPv4                net.IP
@@ -88,6 +127,17 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	AddressDiscoveryMode protocol.AddressDiscoveryMode
+
+	ImmediateAck bool
+
+	NullEncryption bool
+
+	// CustomParameters are transport parameters that are not implemented by quic-go,
+	// e.g. the parameters used to negotiate support for extension frames.
+	// Reserved transport parameters (used for greasing) are not retained when parsing.
+	CustomParameters map[uint64][]byte
 }
 
 // Unmarshal the transport parameters
@@ -143,6 +193,7 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			initialMaxStreamsUniParameterID,
 			maxAckDelayParameterID,
 			maxDatagramFrameSizeParameterID,
+			addressDiscoveryParameterID,
 			ackDelayExponentParameterID:
 			if err := p.readNumericTransportParameter(r, paramID, int(paramLen)); err != nil {
 				return err
@@ -159,6 +210,16 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 			}
 			p.DisableActiveMigration = true
+		case immediateAckParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for immediate_ack: %d (expected empty)", paramLen)
+			}
+			p.ImmediateAck = true
+		case nullEncryptionParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for null_encryption: %d (expected empty)", paramLen)
+			}
+			p.NullEncryption = true
 		case statelessResetTokenParameterID:
 			if sentBy == protocol.PerspectiveClient {
 				return errors.New("client sent a stateless_reset_token")
@@ -185,7 +246,16 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 			connID, _ := protocol.ReadConnectionID(r, int(paramLen))
 			p.RetrySourceConnectionID = &connID
 		default:
-			r.Seek(int64(paramLen), io.SeekCurrent)
+			if uint64(paramID)%31 == 27 { // reserved transport parameter
+				r.Seek(int64(paramLen), io.SeekCurrent)
+				break
+			}
+			if p.CustomParameters == nil {
+				p.CustomParameters = make(map[uint64][]byte)
+			}
+			val := make([]byte, paramLen)
+			r.Read(val)
+			p.CustomParameters[uint64(paramID)] = val
 		}
 	}
 
@@ -317,6 +387,12 @@ func (p *TransportParameters) readNumericTransportParameter(
 		p.ActiveConnectionIDLimit = val
 	case maxDatagramFrameSizeParameterID:
 		p.MaxDatagramFrameSize = protocol.ByteCount(val)
+	case addressDiscoveryParameterID:
+		// 0: willing to provide, 1: willing to receive, 2: both
+		if val > 2 {
+			return fmt.Errorf("invalid value for address_discovery: %d", val)
+		}
+		p.AddressDiscoveryMode = protocol.AddressDiscoveryMode(val + 1)
 	default:
 		return fmt.Errorf("TransportParameter BUG: transport parameter %d not found", paramID)
 	}
@@ -413,6 +489,33 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		b = p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// address_discovery
+	if p.AddressDiscoveryMode != protocol.AddressDiscoveryDisabled {
+		b = p.marshalVarintParam(b, addressDiscoveryParameterID, uint64(p.AddressDiscoveryMode-1))
+	}
+	// immediate_ack
+	if p.ImmediateAck {
+		b = quicvarint.Append(b, uint64(immediateAckParameterID))
+		b = quicvarint.Append(b, 0)
+	}
+	// null_encryption
+	if p.NullEncryption {
+		b = quicvarint.Append(b, uint64(nullEncryptionParameterID))
+		b = quicvarint.Append(b, 0)
+	}
+
+	if len(p.CustomParameters) > 0 {
+		ids := make([]uint64, 0, len(p.CustomParameters))
+		for id := range p.CustomParameters {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			b = quicvarint.Append(b, id)
+			b = quicvarint.Append(b, uint64(len(p.CustomParameters[id])))
+			b = append(b, p.CustomParameters[id]...)
+		}
+	}
 
 	if pers == protocol.PerspectiveClient && len(AdditionalTransportParametersClient) > 0 {
 		for k, v := range AdditionalTransportParametersClient {
@@ -521,6 +624,25 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.AddressDiscoveryMode != protocol.AddressDiscoveryDisabled {
+		logString += ", AddressDiscoveryMode: %s"
+		logParams = append(logParams, p.AddressDiscoveryMode)
+	}
+	if p.ImmediateAck {
+		logString += ", ImmediateAck: true"
+	}
+	if p.NullEncryption {
+		logString += ", NullEncryption: true"
+	}
+	if len(p.CustomParameters) > 0 {
+		ids := make([]uint64, 0, len(p.CustomParameters))
+		for id := range p.CustomParameters {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		logString += ", CustomParameters: %#x"
+		logParams = append(logParams, ids)
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
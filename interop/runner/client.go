@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qtls"
+	"github.com/quic-go/quic-go/interop/http09"
+	"github.com/quic-go/quic-go/interop/utils"
+)
+
+type client struct {
+	env     *Environment
+	tlsConf *tls.Config
+}
+
+// RunClient runs the client for the test case configured in env.
+// It downloads the files from env.Requests and saves them to env.DownloadDir.
+// It returns ErrUnsupported if the test case is not supported.
+func RunClient(env *Environment) error {
+	keyLog, err := utils.GetSSLKeyLog()
+	if err != nil {
+		return fmt.Errorf("could not create key log: %w", err)
+	}
+	if keyLog != nil {
+		defer keyLog.Close()
+	}
+	c := &client{
+		env: env,
+		tlsConf: &tls.Config{
+			InsecureSkipVerify: true,
+			KeyLogWriter:       keyLog,
+		},
+	}
+	return c.run()
+}
+
+func (c *client) run() error {
+	urls := c.env.Requests
+	quicConf := &quic.Config{Tracer: utils.NewQLOGConnectionTracer}
+
+	if c.env.TestCase == "http3" {
+		r := &http3.RoundTripper{
+			TLSClientConfig: c.tlsConf,
+			QuicConfig:      quicConf,
+		}
+		defer r.Close()
+		return c.downloadFiles(r, urls, false)
+	}
+
+	r := &http09.RoundTripper{
+		TLSClientConfig: c.tlsConf,
+		QuicConfig:      quicConf,
+	}
+	defer r.Close()
+
+	switch c.env.TestCase {
+	case "handshake", "transfer", "multiplexing", "retry":
+	case "keyupdate":
+		handshake.FirstKeyUpdateInterval = 100
+	case "chacha20":
+		reset := qtls.SetCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256)
+		defer reset()
+	case "multiconnect":
+		return c.runMultiConnectTest(r, urls)
+	case "versionnegotiation":
+		return c.runVersionNegotiationTest(r, urls)
+	case "resumption":
+		return c.runResumptionTest(r, urls, false)
+	case "zerortt":
+		return c.runResumptionTest(r, urls, true)
+	default:
+		return ErrUnsupported
+	}
+
+	return c.downloadFiles(r, urls, false)
+}
+
+func (c *client) runVersionNegotiationTest(r *http09.RoundTripper, urls []string) error {
+	if len(urls) != 1 {
+		return errors.New("expected exactly 1 URL")
+	}
+	protocol.SupportedVersions = []protocol.VersionNumber{0x1a2a3a4a}
+	err := c.downloadFile(r, urls[0], false)
+	if err == nil {
+		return errors.New("expected version negotiation to fail")
+	}
+	if !strings.Contains(err.Error(), "No compatible QUIC version found") {
+		return fmt.Errorf("expect version negotiation error, got: %s", err.Error())
+	}
+	return nil
+}
+
+func (c *client) runMultiConnectTest(r *http09.RoundTripper, urls []string) error {
+	for _, url := range urls {
+		if err := c.downloadFile(r, url, false); err != nil {
+			return err
+		}
+		if err := r.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sessionCache struct {
+	tls.ClientSessionCache
+	put chan<- struct{}
+}
+
+func newSessionCache(c tls.ClientSessionCache) (tls.ClientSessionCache, <-chan struct{}) {
+	put := make(chan struct{}, 100)
+	return &sessionCache{ClientSessionCache: c, put: put}, put
+}
+
+func (c *sessionCache) Put(key string, cs *tls.ClientSessionState) {
+	c.ClientSessionCache.Put(key, cs)
+	c.put <- struct{}{}
+}
+
+func (c *client) runResumptionTest(r *http09.RoundTripper, urls []string, use0RTT bool) error {
+	if len(urls) < 2 {
+		return errors.New("expected at least 2 URLs")
+	}
+
+	var put <-chan struct{}
+	c.tlsConf.ClientSessionCache, put = newSessionCache(tls.NewLRUClientSessionCache(1))
+
+	// do the first transfer
+	if err := c.downloadFiles(r, urls[:1], false); err != nil {
+		return err
+	}
+
+	// wait for the session ticket to arrive
+	select {
+	case <-time.NewTimer(10 * time.Second).C:
+		return errors.New("expected to receive a session ticket within 10 seconds")
+	case <-put:
+	}
+
+	if err := r.Close(); err != nil {
+		return err
+	}
+
+	// reestablish the connection, using the session ticket that the server (hopefully provided)
+	defer r.Close()
+	return c.downloadFiles(r, urls[1:], use0RTT)
+}
+
+func (c *client) downloadFiles(cl http.RoundTripper, urls []string, use0RTT bool) error {
+	var g errgroup.Group
+	for _, u := range urls {
+		url := u
+		g.Go(func() error {
+			return c.downloadFile(cl, url, use0RTT)
+		})
+	}
+	return g.Wait()
+}
+
+func (c *client) downloadFile(cl http.RoundTripper, url string, use0RTT bool) error {
+	method := http.MethodGet
+	if use0RTT {
+		method = http09.MethodGet0RTT
+	}
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	rsp, err := cl.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	file, err := os.Create(filepath.Join(c.env.DownloadDir, req.URL.Path))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, rsp.Body)
+	return err
+}
@@ -0,0 +1,116 @@
+// Package runner implements the endpoints used by the quic-interop-runner (https://github.com/quic-interop/quic-interop-runner).
+//
+// The endpoints are configured using the environment variables set by the interop runner,
+// see EnvironmentFromEnv for details.
+// Forks of quic-go can use this package to build their own interop endpoint binaries,
+// and continuously validate protocol conformance:
+//
+//	func main() {
+//		runner.Main(runner.EnvironmentFromEnv())
+//	}
+//
+// Some test cases modify process-wide settings (e.g. the cipher suite or the key update interval),
+// so only a single endpoint should be run per process.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported is returned when running a test case that is not supported.
+var ErrUnsupported = errors.New("unsupported test case")
+
+// exitCodeUnsupported is the exit code the interop runner expects for unsupported test cases.
+const exitCodeUnsupported = 127
+
+const (
+	// RoleClient is the role of the client endpoint.
+	RoleClient = "client"
+	// RoleServer is the role of the server endpoint.
+	RoleServer = "server"
+)
+
+// Environment configures an interop endpoint.
+type Environment struct {
+	// Role is either RoleClient or RoleServer.
+	// It is read from ROLE.
+	Role string
+	// TestCase is the name of the test case to run.
+	// It is read from TESTCASE.
+	TestCase string
+	// Requests are the URLs that the client downloads.
+	// They are read from REQUESTS, as a space-separated list.
+	Requests []string
+	// ServerAddr is the address that the server listens on.
+	// It is read from SERVER_ADDR, and defaults to ":443".
+	ServerAddr string
+	// WWWDir is the directory that the server serves files from.
+	// It is read from WWW, and defaults to "/www".
+	WWWDir string
+	// DownloadDir is the directory that the client saves downloaded files to.
+	// It is read from DOWNLOADS, and defaults to "/downloads".
+	DownloadDir string
+	// CertsDir is the directory containing the server's certificate (cert.pem) and private key (priv.key).
+	// It is read from CERTS, and defaults to "/certs".
+	CertsDir string
+	// LogDir is the directory that Main writes the log file to.
+	// It is read from LOGS, and defaults to "/logs".
+	LogDir string
+}
+
+// EnvironmentFromEnv reads the Environment from the environment variables.
+// The TLS key log and the qlog files are written to SSLKEYLOGFILE and QLOGDIR, if set.
+func EnvironmentFromEnv() *Environment {
+	return &Environment{
+		Role:        os.Getenv("ROLE"),
+		TestCase:    os.Getenv("TESTCASE"),
+		Requests:    strings.Fields(os.Getenv("REQUESTS")),
+		ServerAddr:  getenv("SERVER_ADDR", ":443"),
+		WWWDir:      getenv("WWW", "/www"),
+		DownloadDir: getenv("DOWNLOADS", "/downloads"),
+		CertsDir:    getenv("CERTS", "/certs"),
+		LogDir:      getenv("LOGS", "/logs"),
+	}
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// Main runs the endpoint configured by env, and exits the process.
+// It uses the exit codes expected by the interop runner.
+func Main(env *Environment) {
+	logFile, err := os.Create(filepath.Join(env.LogDir, "log.txt"))
+	if err != nil {
+		fmt.Printf("Could not create log file: %s\n", err.Error())
+		os.Exit(1)
+	}
+	log.SetOutput(logFile)
+
+	switch env.Role {
+	case RoleClient:
+		err = RunClient(env)
+	case RoleServer:
+		err = RunServer(context.Background(), env)
+	default:
+		err = fmt.Errorf("invalid role: %q", env.Role)
+	}
+	logFile.Close()
+	if errors.Is(err, ErrUnsupported) {
+		fmt.Printf("unsupported test case: %s\n", env.TestCase)
+		os.Exit(exitCodeUnsupported)
+	}
+	if err != nil {
+		fmt.Printf("Running %s failed: %s\n", env.Role, err.Error())
+		os.Exit(1)
+	}
+}
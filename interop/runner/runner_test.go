@@ -0,0 +1,102 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Interop Runner", func() {
+	It("reads the environment", func() {
+		GinkgoT().Setenv("ROLE", "client")
+		GinkgoT().Setenv("TESTCASE", "handshake")
+		GinkgoT().Setenv("REQUESTS", "https://server:443/foo https://server:443/bar")
+		GinkgoT().Setenv("WWW", "/tmp/www")
+		env := EnvironmentFromEnv()
+		Expect(env.Role).To(Equal(RoleClient))
+		Expect(env.TestCase).To(Equal("handshake"))
+		Expect(env.Requests).To(Equal([]string{"https://server:443/foo", "https://server:443/bar"}))
+		Expect(env.WWWDir).To(Equal("/tmp/www"))
+		Expect(env.DownloadDir).To(Equal("/downloads"))
+		Expect(env.CertsDir).To(Equal("/certs"))
+		Expect(env.ServerAddr).To(Equal(":443"))
+	})
+
+	It("rejects unsupported test cases", func() {
+		env := &Environment{TestCase: "foobar"}
+		Expect(RunClient(env)).To(MatchError(ErrUnsupported))
+		Expect(RunServer(context.Background(), env)).To(MatchError(ErrUnsupported))
+	})
+
+	Context("running test cases", func() {
+		var (
+			serverEnv, clientEnv *Environment
+			file                 []byte
+		)
+
+		getFreePort := func() int {
+			conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+			return conn.LocalAddr().(*net.UDPAddr).Port
+		}
+
+		BeforeEach(func() {
+			certsDir := GinkgoT().TempDir()
+			certFile, keyFile := testdata.GetCertificatePaths()
+			for src, dst := range map[string]string{certFile: "cert.pem", keyFile: "priv.key"} {
+				data, err := os.ReadFile(src)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(os.WriteFile(filepath.Join(certsDir, dst), data, 0o644)).To(Succeed())
+			}
+			wwwDir := GinkgoT().TempDir()
+			file = make([]byte, 50<<10)
+			rand.Read(file)
+			Expect(os.WriteFile(filepath.Join(wwwDir, "file1"), file, 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(wwwDir, "file2"), file, 0o644)).To(Succeed())
+
+			addr := fmt.Sprintf("127.0.0.1:%d", getFreePort())
+			serverEnv = &Environment{Role: RoleServer, ServerAddr: addr, WWWDir: wwwDir, CertsDir: certsDir}
+			clientEnv = &Environment{
+				Role:        RoleClient,
+				Requests:    []string{fmt.Sprintf("https://%s/file1", addr), fmt.Sprintf("https://%s/file2", addr)},
+				DownloadDir: GinkgoT().TempDir(),
+			}
+		})
+
+		for _, tc := range []string{"handshake", "transfer", "multiplexing", "retry", "http3"} {
+			testcase := tc
+
+			It(fmt.Sprintf("runs the %s test case", testcase), func() {
+				serverEnv.TestCase = testcase
+				clientEnv.TestCase = testcase
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(RunServer(ctx, serverEnv)).To(Succeed())
+				}()
+				defer func() {
+					cancel()
+					Eventually(done).Should(BeClosed())
+				}()
+
+				Eventually(func() error { return RunClient(clientEnv) }).Should(Succeed())
+				for _, name := range []string{"file1", "file2"} {
+					data, err := os.ReadFile(filepath.Join(clientEnv.DownloadDir, name))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(file))
+				}
+			})
+		}
+	})
+})
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/internal/qtls"
+	"github.com/quic-go/quic-go/interop/http09"
+	"github.com/quic-go/quic-go/interop/utils"
+)
+
+// RunServer runs the server for the test case configured in env.
+// It serves the files in env.WWWDir until ctx is canceled.
+// It returns ErrUnsupported if the test case is not supported.
+func RunServer(ctx context.Context, env *Environment) error {
+	testcase := env.TestCase
+	switch testcase {
+	case "versionnegotiation", "handshake", "retry", "transfer", "multiplexing", "resumption", "multiconnect", "zerortt", "http3":
+	case "chacha20":
+		reset := qtls.SetCipherSuite(tls.TLS_CHACHA20_POLY1305_SHA256)
+		defer reset()
+	default:
+		return ErrUnsupported
+	}
+
+	keyLog, err := utils.GetSSLKeyLog()
+	if err != nil {
+		return fmt.Errorf("could not create key log: %w", err)
+	}
+	if keyLog != nil {
+		defer keyLog.Close()
+	}
+
+	quicConf := &quic.Config{
+		RequireAddressValidation: func(net.Addr) bool { return testcase == "retry" },
+		Allow0RTT:                testcase == "zerortt",
+		Tracer:                   utils.NewQLOGConnectionTracer,
+	}
+	cert, err := tls.LoadX509KeyPair(filepath.Join(env.CertsDir, "cert.pem"), filepath.Join(env.CertsDir, "priv.key"))
+	if err != nil {
+		return err
+	}
+	tlsConf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		KeyLogWriter: keyLog,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(env.WWWDir)))
+
+	var server interface {
+		ListenAndServe() error
+		Close() error
+	}
+	if testcase == "http3" {
+		server = &http3.Server{
+			Addr:       env.ServerAddr,
+			TLSConfig:  tlsConf,
+			QuicConfig: quicConf,
+			Handler:    mux,
+		}
+	} else {
+		server = &http09.Server{
+			Server: &http.Server{
+				Addr:      env.ServerAddr,
+				TLSConfig: tlsConf,
+				Handler:   mux,
+			},
+			QuicConfig: quicConf,
+		}
+	}
+
+	errChan := make(chan error, 1)
+	go func() { errChan <- server.ListenAndServe() }()
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		server.Close()
+		<-errChan
+		return nil
+	}
+}
@@ -34,9 +34,41 @@ type ConnectionTracer struct {
 	LossTimerExpired                 func(TimerType, EncryptionLevel)
 	LossTimerCanceled                func()
 	ECNStateUpdated                  func(state ECNState, trigger ECNStateTrigger)
+	// LostFrames is called with the frames carried by a packet that was declared lost.
+	LostFrames func(EncryptionLevel, PacketNumber, []Frame)
+	// RetransmittedStreamData is called when a range of stream data is queued for retransmission,
+	// after the packet carrying it was declared lost.
+	RetransmittedStreamData func(id StreamID, offset, length ByteCount)
+	// EnteredPersistentCongestion is called when persistent congestion is detected (see section 7.6 of RFC 9002).
+	EnteredPersistentCongestion func()
+	// ReachedHandshakeMilestone is called when the connection reaches a milestone of the handshake.
+	// Every milestone is reached at most once.
+	ReachedHandshakeMilestone func(HandshakeMilestone, time.Time)
+	// FailedHandshake is called when the connection is closed before the handshake completed.
+	// It is called before ClosedConnection, which receives the error wrapped in a HandshakeError.
+	FailedHandshake func(reason HandshakeFailureReason, encLevel EncryptionLevel, err error)
+	// IssuedConnectionID is called when a new connection ID is issued to the peer.
+	// The peer is asked to retire all connection IDs with a sequence number smaller than retirePriorTo.
+	IssuedConnectionID func(seq uint64, connID ConnectionID, retirePriorTo uint64)
+	// RetiredConnectionID is called when the peer retires a connection ID that was issued to it.
+	RetiredConnectionID func(seq uint64, connID ConnectionID)
+	// InitiatedKeyUpdate is called when a key update to the given generation is initiated by this endpoint.
+	// The counters are the numbers of packets sent and received, and the number of bytes sent, using the previous keys.
+	// It is followed by an UpdatedKey event.
+	InitiatedKeyUpdate func(generation KeyPhase, reason KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent ByteCount)
+	// DetectedMTUBlackHole is called when packets larger than newSize stopped being delivered,
+	// and the maximum packet size was decreased from oldSize to newSize.
+	DetectedMTUBlackHole func(oldSize, newSize ByteCount)
+	// DroppedDatagram is called when a received DATAGRAM frame is discarded,
+	// because the application didn't read the received datagrams fast enough.
+	DroppedDatagram func(length ByteCount)
 	// Close is called when the connection is closed.
 	Close func()
 	Debug func(name, msg string)
+
+	// OmitFrames disables the construction of frames for packet events.
+	// If set, the frame slices (and the ACK frame) passed to the packet events are always nil.
+	OmitFrames bool
 }
 
 // NewMultiplexedConnectionTracer creates a new connection tracer that multiplexes events to multiple tracers.
@@ -47,7 +79,15 @@ func NewMultiplexedConnectionTracer(tracers ...*ConnectionTracer) *ConnectionTra
 	if len(tracers) == 1 {
 		return tracers[0]
 	}
+	omitFrames := true
+	for _, t := range tracers {
+		if !t.OmitFrames {
+			omitFrames = false
+			break
+		}
+	}
 	return &ConnectionTracer{
+		OmitFrames: omitFrames,
 		StartedConnection: func(local, remote net.Addr, srcConnID, destConnID ConnectionID) {
 			for _, t := range tracers {
 				if t.StartedConnection != nil {
@@ -167,6 +207,55 @@ func NewMultiplexedConnectionTracer(tracers ...*ConnectionTracer) *ConnectionTra
 				}
 			}
 		},
+		LostFrames: func(encLevel EncryptionLevel, pn PacketNumber, frames []Frame) {
+			for _, t := range tracers {
+				if t.LostFrames != nil {
+					t.LostFrames(encLevel, pn, frames)
+				}
+			}
+		},
+		RetransmittedStreamData: func(id StreamID, offset, length ByteCount) {
+			for _, t := range tracers {
+				if t.RetransmittedStreamData != nil {
+					t.RetransmittedStreamData(id, offset, length)
+				}
+			}
+		},
+		EnteredPersistentCongestion: func() {
+			for _, t := range tracers {
+				if t.EnteredPersistentCongestion != nil {
+					t.EnteredPersistentCongestion()
+				}
+			}
+		},
+		ReachedHandshakeMilestone: func(milestone HandshakeMilestone, tm time.Time) {
+			for _, t := range tracers {
+				if t.ReachedHandshakeMilestone != nil {
+					t.ReachedHandshakeMilestone(milestone, tm)
+				}
+			}
+		},
+		FailedHandshake: func(reason HandshakeFailureReason, encLevel EncryptionLevel, err error) {
+			for _, t := range tracers {
+				if t.FailedHandshake != nil {
+					t.FailedHandshake(reason, encLevel, err)
+				}
+			}
+		},
+		IssuedConnectionID: func(seq uint64, connID ConnectionID, retirePriorTo uint64) {
+			for _, t := range tracers {
+				if t.IssuedConnectionID != nil {
+					t.IssuedConnectionID(seq, connID, retirePriorTo)
+				}
+			}
+		},
+		RetiredConnectionID: func(seq uint64, connID ConnectionID) {
+			for _, t := range tracers {
+				if t.RetiredConnectionID != nil {
+					t.RetiredConnectionID(seq, connID)
+				}
+			}
+		},
 		UpdatedCongestionState: func(state CongestionState) {
 			for _, t := range tracers {
 				if t.UpdatedCongestionState != nil {
@@ -195,6 +284,27 @@ func NewMultiplexedConnectionTracer(tracers ...*ConnectionTracer) *ConnectionTra
 				}
 			}
 		},
+		InitiatedKeyUpdate: func(generation KeyPhase, reason KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent ByteCount) {
+			for _, t := range tracers {
+				if t.InitiatedKeyUpdate != nil {
+					t.InitiatedKeyUpdate(generation, reason, packetsSent, packetsReceived, bytesSent)
+				}
+			}
+		},
+		DetectedMTUBlackHole: func(oldSize, newSize ByteCount) {
+			for _, t := range tracers {
+				if t.DetectedMTUBlackHole != nil {
+					t.DetectedMTUBlackHole(oldSize, newSize)
+				}
+			}
+		},
+		DroppedDatagram: func(length ByteCount) {
+			for _, t := range tracers {
+				if t.DroppedDatagram != nil {
+					t.DroppedDatagram(length)
+				}
+			}
+		},
 		DroppedEncryptionLevel: func(encLevel EncryptionLevel) {
 			for _, t := range tracers {
 				if t.DroppedEncryptionLevel != nil {
@@ -0,0 +1,128 @@
+package logging
+
+import "net"
+
+// An EventCategory is a set of related tracing events.
+// Categories can be combined using a bitwise OR.
+type EventCategory uint8
+
+const (
+	// EventCategoryConnectivity contains events related to the connection lifecycle:
+	// connection start and close, version negotiation and Retry.
+	EventCategoryConnectivity EventCategory = 1 << iota
+	// EventCategoryTransport contains events related to the transport parameters,
+	// to packets being sent, received, buffered or dropped, and to GSO being disabled.
+	EventCategoryTransport
+	// EventCategoryFrames contains the frames carried in sent and received packets.
+	// If this category is not subscribed to, packet events are still emitted,
+	// but the frames are neither constructed nor passed to the tracer.
+	EventCategoryFrames
+	// EventCategoryRecovery contains events related to loss detection and congestion control.
+	EventCategoryRecovery
+	// EventCategorySecurity contains events related to the installation and update of keys.
+	EventCategorySecurity
+	// EventCategoryDebug contains debug events.
+	EventCategoryDebug
+	// EventCategoryDatagrams contains events related to DATAGRAM frames (RFC 9221).
+	EventCategoryDatagrams
+
+	// EventCategoryAll subscribes to all events.
+	EventCategoryAll = EventCategoryConnectivity | EventCategoryTransport | EventCategoryFrames |
+		EventCategoryRecovery | EventCategorySecurity | EventCategoryDebug | EventCategoryDatagrams
+)
+
+// Has says if the category set c contains all the categories in o.
+func (c EventCategory) Has(o EventCategory) bool {
+	return c&o == o
+}
+
+// FilterTracer returns a tracer that only emits the events contained in categories.
+// Since quic-go doesn't construct events for which no callback is set,
+// unsubscribed events don't incur any tracing overhead.
+func FilterTracer(t *Tracer, categories EventCategory) *Tracer {
+	if t == nil {
+		return nil
+	}
+	f := &Tracer{}
+	if categories.Has(EventCategoryTransport) {
+		f.SentPacket = t.SentPacket
+		f.DroppedPacket = t.DroppedPacket
+		f.GSODisabled = t.GSODisabled
+		if f.SentPacket != nil && !categories.Has(EventCategoryFrames) {
+			f.SentPacket = withoutFrames(f.SentPacket)
+		}
+	}
+	if categories.Has(EventCategoryConnectivity) {
+		f.SentVersionNegotiationPacket = t.SentVersionNegotiationPacket
+	}
+	return f
+}
+
+// FilterConnectionTracer returns a connection tracer that only emits the events contained in categories.
+// Since quic-go doesn't construct events for which no callback is set,
+// unsubscribed events don't incur any tracing overhead.
+// Close is always passed through, so that tracers can release their resources.
+func FilterConnectionTracer(t *ConnectionTracer, categories EventCategory) *ConnectionTracer {
+	if t == nil {
+		return nil
+	}
+	f := &ConnectionTracer{Close: t.Close}
+	if categories.Has(EventCategoryConnectivity) {
+		f.StartedConnection = t.StartedConnection
+		f.NegotiatedVersion = t.NegotiatedVersion
+		f.ClosedConnection = t.ClosedConnection
+		f.ReceivedVersionNegotiationPacket = t.ReceivedVersionNegotiationPacket
+		f.ReceivedRetry = t.ReceivedRetry
+		f.ReachedHandshakeMilestone = t.ReachedHandshakeMilestone
+		f.FailedHandshake = t.FailedHandshake
+		f.IssuedConnectionID = t.IssuedConnectionID
+		f.RetiredConnectionID = t.RetiredConnectionID
+	}
+	if categories.Has(EventCategoryTransport) {
+		f.SentTransportParameters = t.SentTransportParameters
+		f.ReceivedTransportParameters = t.ReceivedTransportParameters
+		f.RestoredTransportParameters = t.RestoredTransportParameters
+		f.SentLongHeaderPacket = t.SentLongHeaderPacket
+		f.SentShortHeaderPacket = t.SentShortHeaderPacket
+		f.ReceivedLongHeaderPacket = t.ReceivedLongHeaderPacket
+		f.ReceivedShortHeaderPacket = t.ReceivedShortHeaderPacket
+		f.BufferedPacket = t.BufferedPacket
+		f.DroppedPacket = t.DroppedPacket
+		f.OmitFrames = t.OmitFrames || !categories.Has(EventCategoryFrames)
+	}
+	if categories.Has(EventCategoryRecovery) {
+		f.UpdatedMetrics = t.UpdatedMetrics
+		f.AcknowledgedPacket = t.AcknowledgedPacket
+		f.LostPacket = t.LostPacket
+		f.LostFrames = t.LostFrames
+		f.RetransmittedStreamData = t.RetransmittedStreamData
+		f.EnteredPersistentCongestion = t.EnteredPersistentCongestion
+		f.UpdatedCongestionState = t.UpdatedCongestionState
+		f.UpdatedPTOCount = t.UpdatedPTOCount
+		f.SetLossTimer = t.SetLossTimer
+		f.LossTimerExpired = t.LossTimerExpired
+		f.LossTimerCanceled = t.LossTimerCanceled
+		f.ECNStateUpdated = t.ECNStateUpdated
+		f.DetectedMTUBlackHole = t.DetectedMTUBlackHole
+	}
+	if categories.Has(EventCategorySecurity) {
+		f.UpdatedKeyFromTLS = t.UpdatedKeyFromTLS
+		f.UpdatedKey = t.UpdatedKey
+		f.InitiatedKeyUpdate = t.InitiatedKeyUpdate
+		f.DroppedEncryptionLevel = t.DroppedEncryptionLevel
+		f.DroppedKey = t.DroppedKey
+	}
+	if categories.Has(EventCategoryDebug) {
+		f.Debug = t.Debug
+	}
+	if categories.Has(EventCategoryDatagrams) {
+		f.DroppedDatagram = t.DroppedDatagram
+	}
+	return f
+}
+
+func withoutFrames(sent func(net.Addr, *Header, ByteCount, []Frame)) func(net.Addr, *Header, ByteCount, []Frame) {
+	return func(addr net.Addr, hdr *Header, size ByteCount, _ []Frame) {
+		sent(addr, hdr, size, nil)
+	}
+}
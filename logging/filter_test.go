@@ -0,0 +1,85 @@
+package logging_test
+
+import (
+	"net"
+
+	mocklogging "github.com/quic-go/quic-go/internal/mocks/logging"
+	. "github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Filtering", func() {
+	It("combines categories", func() {
+		c := EventCategoryTransport | EventCategoryRecovery
+		Expect(c.Has(EventCategoryTransport)).To(BeTrue())
+		Expect(c.Has(EventCategoryRecovery)).To(BeTrue())
+		Expect(c.Has(EventCategoryTransport | EventCategoryRecovery)).To(BeTrue())
+		Expect(c.Has(EventCategoryFrames)).To(BeFalse())
+		Expect(EventCategoryAll.Has(c | EventCategoryDebug)).To(BeTrue())
+	})
+
+	It("returns nil for a nil tracer", func() {
+		Expect(FilterTracer(nil, EventCategoryAll)).To(BeNil())
+		Expect(FilterConnectionTracer(nil, EventCategoryAll)).To(BeNil())
+	})
+
+	It("filters tracer events", func() {
+		t, tr := mocklogging.NewMockTracer(mockCtrl)
+		f := FilterTracer(t, EventCategoryTransport)
+		Expect(f.SentVersionNegotiationPacket).To(BeNil())
+		Expect(f.DroppedPacket).ToNot(BeNil())
+		remote := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4)}
+		hdr := &Header{}
+		tr.EXPECT().SentPacket(remote, hdr, ByteCount(1234), nil)
+		f.SentPacket(remote, hdr, 1234, []Frame{&PingFrame{}})
+	})
+
+	It("passes through the GSODisabled event", func() {
+		t, tr := mocklogging.NewMockTracer(mockCtrl)
+		Expect(FilterTracer(t, EventCategoryConnectivity).GSODisabled).To(BeNil())
+		f := FilterTracer(t, EventCategoryTransport)
+		Expect(f.GSODisabled).ToNot(BeNil())
+		tr.EXPECT().GSODisabled(GSODisabledBlackholed)
+		f.GSODisabled(GSODisabledBlackholed)
+	})
+
+	It("filters connection tracer events", func() {
+		t, _ := mocklogging.NewMockConnectionTracer(mockCtrl)
+		f := FilterConnectionTracer(t, EventCategoryRecovery)
+		Expect(f.UpdatedMetrics).ToNot(BeNil())
+		Expect(f.LostPacket).ToNot(BeNil())
+		Expect(f.Close).ToNot(BeNil())
+		Expect(f.StartedConnection).To(BeNil())
+		Expect(f.SentShortHeaderPacket).To(BeNil())
+		Expect(f.UpdatedKey).To(BeNil())
+		Expect(f.Debug).To(BeNil())
+		Expect(f.DroppedDatagram).To(BeNil())
+	})
+
+	It("filters datagram events", func() {
+		t, tr := mocklogging.NewMockConnectionTracer(mockCtrl)
+		f := FilterConnectionTracer(t, EventCategoryDatagrams)
+		Expect(f.LostPacket).To(BeNil())
+		Expect(f.DroppedDatagram).ToNot(BeNil())
+		tr.EXPECT().DroppedDatagram(ByteCount(42))
+		f.DroppedDatagram(42)
+		Expect(FilterConnectionTracer(t, EventCategoryAll).DroppedDatagram).ToNot(BeNil())
+	})
+
+	It("omits frames unless the frames category is subscribed to", func() {
+		t, _ := mocklogging.NewMockConnectionTracer(mockCtrl)
+		f := FilterConnectionTracer(t, EventCategoryTransport)
+		Expect(f.SentShortHeaderPacket).ToNot(BeNil())
+		Expect(f.OmitFrames).To(BeTrue())
+		Expect(FilterConnectionTracer(t, EventCategoryTransport|EventCategoryFrames).OmitFrames).To(BeFalse())
+	})
+
+	It("only omits frames in a multiplexed tracer if all tracers omit them", func() {
+		t1 := &ConnectionTracer{OmitFrames: true}
+		t2 := &ConnectionTracer{}
+		Expect(NewMultiplexedConnectionTracer(t1, t2).OmitFrames).To(BeFalse())
+		Expect(NewMultiplexedConnectionTracer(t1, &ConnectionTracer{OmitFrames: true}).OmitFrames).To(BeTrue())
+	})
+})
@@ -18,6 +18,8 @@ type (
 	DataBlockedFrame = wire.DataBlockedFrame
 	// A HandshakeDoneFrame is a HANDSHAKE_DONE frame.
 	HandshakeDoneFrame = wire.HandshakeDoneFrame
+	// An ImmediateAckFrame is an IMMEDIATE_ACK frame.
+	ImmediateAckFrame = wire.ImmediateAckFrame
 	// A MaxDataFrame is a MAX_DATA frame.
 	MaxDataFrame = wire.MaxDataFrame
 	// A MaxStreamDataFrame is a MAX_STREAM_DATA frame.
@@ -28,6 +30,8 @@ type (
 	NewConnectionIDFrame = wire.NewConnectionIDFrame
 	// A NewTokenFrame is a NEW_TOKEN frame.
 	NewTokenFrame = wire.NewTokenFrame
+	// An ObservedAddressFrame is an OBSERVED_ADDRESS frame.
+	ObservedAddressFrame = wire.ObservedAddressFrame
 	// A PathChallengeFrame is a PATH_CHALLENGE frame.
 	PathChallengeFrame = wire.PathChallengeFrame
 	// A PathResponseFrame is a PATH_RESPONSE frame.
@@ -64,3 +68,9 @@ type StreamFrame struct {
 type DatagramFrame struct {
 	Length ByteCount
 }
+
+// An ExtensionFrame is a frame of a type registered by the application.
+type ExtensionFrame struct {
+	Type   uint64
+	Length ByteCount
+}
@@ -52,6 +52,8 @@ type (
 	TransportError = qerr.TransportErrorCode
 	// An ApplicationError is an application-defined error code.
 	ApplicationError = qerr.TransportErrorCode
+	// A HandshakeFailureReason classifies why a handshake failed.
+	HandshakeFailureReason = qerr.HandshakeFailureReason
 
 	// The RTTStats contain statistics used by the congestion controller.
 	RTTStats = utils.RTTStats
@@ -95,6 +97,21 @@ const (
 	Encryption0RTT EncryptionLevel = protocol.Encryption0RTT
 )
 
+const (
+	// HandshakeFailureOther is used for failures that don't fall into any of the other categories
+	HandshakeFailureOther HandshakeFailureReason = qerr.HandshakeFailureOther
+	// HandshakeFailureNoCompatibleVersion is used when client and server don't support a common QUIC version
+	HandshakeFailureNoCompatibleVersion HandshakeFailureReason = qerr.HandshakeFailureNoCompatibleVersion
+	// HandshakeFailureALPNMismatch is used when client and server don't support a common application protocol
+	HandshakeFailureALPNMismatch HandshakeFailureReason = qerr.HandshakeFailureALPNMismatch
+	// HandshakeFailureCertificate is used when a certificate (chain) was rejected
+	HandshakeFailureCertificate HandshakeFailureReason = qerr.HandshakeFailureCertificate
+	// HandshakeFailureTimeout is used when the handshake didn't complete in time
+	HandshakeFailureTimeout HandshakeFailureReason = qerr.HandshakeFailureTimeout
+	// HandshakeFailureAmplificationLimited is used when the handshake timed out while the server was amplification limited
+	HandshakeFailureAmplificationLimited HandshakeFailureReason = qerr.HandshakeFailureAmplificationLimited
+)
+
 const (
 	// StreamTypeUni is a unidirectional stream
 	StreamTypeUni = protocol.StreamTypeUni
@@ -64,6 +64,12 @@ var _ = Describe("Tracing", func() {
 				tr2.EXPECT().DroppedPacket(remote, PacketTypeRetry, ByteCount(1024), PacketDropDuplicate)
 				tracer.DroppedPacket(remote, PacketTypeRetry, 1024, PacketDropDuplicate)
 			})
+
+			It("traces the GSODisabled event", func() {
+				tr1.EXPECT().GSODisabled(GSODisabledBlackholed)
+				tr2.EXPECT().GSODisabled(GSODisabledBlackholed)
+				tracer.GSODisabled(GSODisabledBlackholed)
+			})
 		})
 	})
 
@@ -233,6 +239,24 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedKey(KeyPhase(42), true)
 		})
 
+		It("traces the InitiatedKeyUpdate event", func() {
+			tr1.EXPECT().InitiatedKeyUpdate(KeyPhase(42), KeyUpdateByteInterval, uint64(100), uint64(200), ByteCount(1337))
+			tr2.EXPECT().InitiatedKeyUpdate(KeyPhase(42), KeyUpdateByteInterval, uint64(100), uint64(200), ByteCount(1337))
+			tracer.InitiatedKeyUpdate(KeyPhase(42), KeyUpdateByteInterval, 100, 200, 1337)
+		})
+
+		It("traces the DetectedMTUBlackHole event", func() {
+			tr1.EXPECT().DetectedMTUBlackHole(ByteCount(1400), ByteCount(1252))
+			tr2.EXPECT().DetectedMTUBlackHole(ByteCount(1400), ByteCount(1252))
+			tracer.DetectedMTUBlackHole(1400, 1252)
+		})
+
+		It("traces the DroppedDatagram event", func() {
+			tr1.EXPECT().DroppedDatagram(ByteCount(1337))
+			tr2.EXPECT().DroppedDatagram(ByteCount(1337))
+			tracer.DroppedDatagram(1337)
+		})
+
 		It("traces the DroppedEncryptionLevel event", func() {
 			tr1.EXPECT().DroppedEncryptionLevel(EncryptionHandshake)
 			tr2.EXPECT().DroppedEncryptionLevel(EncryptionHandshake)
@@ -252,6 +276,20 @@ var _ = Describe("Tracing", func() {
 			tracer.SetLossTimer(TimerTypePTO, EncryptionHandshake, now)
 		})
 
+		It("traces the ReachedHandshakeMilestone event", func() {
+			now := time.Now()
+			tr1.EXPECT().ReachedHandshakeMilestone(HandshakeMilestoneHandshakeConfirmed, now)
+			tr2.EXPECT().ReachedHandshakeMilestone(HandshakeMilestoneHandshakeConfirmed, now)
+			tracer.ReachedHandshakeMilestone(HandshakeMilestoneHandshakeConfirmed, now)
+		})
+
+		It("traces the FailedHandshake event", func() {
+			e := errors.New("test err")
+			tr1.EXPECT().FailedHandshake(HandshakeFailureTimeout, EncryptionHandshake, e)
+			tr2.EXPECT().FailedHandshake(HandshakeFailureTimeout, EncryptionHandshake, e)
+			tracer.FailedHandshake(HandshakeFailureTimeout, EncryptionHandshake, e)
+		})
+
 		It("traces the LossTimerExpired event", func() {
 			tr1.EXPECT().LossTimerExpired(TimerTypePTO, EncryptionHandshake)
 			tr2.EXPECT().LossTimerExpired(TimerTypePTO, EncryptionHandshake)
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+)
+
+// SampleConnectionTracer returns a function that can be used as quic.Config.Tracer.
+// It only creates a connection tracer (using newTracer) for a fraction of all connections.
+// The fraction is a value between 0 (no connections are traced) and 1 (all connections are traced).
+// If include is set, it is called for every connection, and connections for which it returns true are always traced.
+// This can be used to trace all connections from a certain client subnet:
+// the remote address of the connection is set on the context (see quic.RemoteAddrContextKey).
+func SampleConnectionTracer(
+	fraction float64,
+	include func(context.Context, Perspective, ConnectionID) bool,
+	newTracer func(context.Context, Perspective, ConnectionID) *ConnectionTracer,
+) func(context.Context, Perspective, ConnectionID) *ConnectionTracer {
+	var mutex sync.Mutex
+	r := rand.New(rand.NewSource(rand.Int63()))
+	sample := func() bool {
+		if fraction >= 1 {
+			return true
+		}
+		if fraction <= 0 {
+			return false
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		return r.Float64() < fraction
+	}
+
+	return func(ctx context.Context, p Perspective, odcid ConnectionID) *ConnectionTracer {
+		if (include != nil && include(ctx, p, odcid)) || sample() {
+			return newTracer(ctx, p, odcid)
+		}
+		return nil
+	}
+}
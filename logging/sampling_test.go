@@ -0,0 +1,51 @@
+package logging_test
+
+import (
+	"context"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	. "github.com/quic-go/quic-go/logging"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sampling", func() {
+	newTracer := func(context.Context, Perspective, ConnectionID) *ConnectionTracer {
+		return &ConnectionTracer{}
+	}
+
+	countTraced := func(tracer func(context.Context, Perspective, ConnectionID) *ConnectionTracer, n int) int {
+		var traced int
+		for i := 0; i < n; i++ {
+			if tracer(context.Background(), PerspectiveServer, protocol.ConnectionID{}) != nil {
+				traced++
+			}
+		}
+		return traced
+	}
+
+	It("traces all connections", func() {
+		Expect(countTraced(SampleConnectionTracer(1, nil, newTracer), 100)).To(Equal(100))
+	})
+
+	It("doesn't trace any connections", func() {
+		Expect(countTraced(SampleConnectionTracer(0, nil, newTracer), 100)).To(BeZero())
+	})
+
+	It("traces a fraction of the connections", func() {
+		const n = 10000
+		Expect(countTraced(SampleConnectionTracer(0.25, nil, newTracer), n)).To(BeNumerically("~", n/4, n/20))
+	})
+
+	It("always traces connections selected by the predicate", func() {
+		type ctxKey struct{}
+		tracer := SampleConnectionTracer(
+			0,
+			func(ctx context.Context, _ Perspective, _ ConnectionID) bool { return ctx.Value(ctxKey{}) == "foobar" },
+			newTracer,
+		)
+		Expect(tracer(context.Background(), PerspectiveClient, protocol.ConnectionID{})).To(BeNil())
+		Expect(tracer(context.WithValue(context.Background(), ctxKey{}, "foobar"), PerspectiveClient, protocol.ConnectionID{})).ToNot(BeNil())
+	})
+})
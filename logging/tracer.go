@@ -7,6 +7,8 @@ type Tracer struct {
 	SentPacket                   func(net.Addr, *Header, ByteCount, []Frame)
 	SentVersionNegotiationPacket func(_ net.Addr, dest, src ArbitraryLenConnectionID, _ []VersionNumber)
 	DroppedPacket                func(net.Addr, PacketType, ByteCount, PacketDropReason)
+	// GSODisabled is called when GSO is disabled for the Transport, because it doesn't work on the network path.
+	GSODisabled func(GSODisabledReason)
 }
 
 // NewMultiplexedTracer creates a new tracer that multiplexes events to multiple tracers.
@@ -39,5 +41,12 @@ func NewMultiplexedTracer(tracers ...*Tracer) *Tracer {
 				}
 			}
 		},
+		GSODisabled: func(reason GSODisabledReason) {
+			for _, t := range tracers {
+				if t.GSODisabled != nil {
+					t.GSODisabled(reason)
+				}
+			}
+		},
 	}
 }
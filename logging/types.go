@@ -107,6 +107,42 @@ const (
 	ECNStateCapable
 )
 
+// HandshakeMilestone is a milestone reached by a connection during (or shortly after) the handshake.
+type HandshakeMilestone uint8
+
+const (
+	// HandshakeMilestoneFirstInitialSent is reached when the first Initial packet is sent
+	HandshakeMilestoneFirstInitialSent HandshakeMilestone = iota
+	// HandshakeMilestoneRetryReceived is reached when a Retry packet is received (only for clients)
+	HandshakeMilestoneRetryReceived
+	// HandshakeMilestoneFirstHandshakePacketReceived is reached when the first Handshake packet is received
+	HandshakeMilestoneFirstHandshakePacketReceived
+	// HandshakeMilestoneHandshakeComplete is reached when the handshake completes
+	HandshakeMilestoneHandshakeComplete
+	// HandshakeMilestoneHandshakeConfirmed is reached when the handshake is confirmed
+	HandshakeMilestoneHandshakeConfirmed
+	// HandshakeMilestoneFirstAppDataSent is reached when the first 1-RTT packet carrying application data is sent
+	HandshakeMilestoneFirstAppDataSent
+	// HandshakeMilestoneFirstAppDataReceived is reached when the first 1-RTT packet carrying application data is received
+	HandshakeMilestoneFirstAppDataReceived
+)
+
+// KeyUpdateReason is the reason why a key update was initiated.
+type KeyUpdateReason uint8
+
+const (
+	// KeyUpdateFirst is the first key update, which is initiated shortly after the handshake
+	// to exercise the key update mechanism
+	KeyUpdateFirst KeyUpdateReason = iota
+	// KeyUpdatePacketInterval is used when the configured number of packets was sent or received with the current keys
+	KeyUpdatePacketInterval
+	// KeyUpdateByteInterval is used when the configured number of bytes was sent with the current keys
+	KeyUpdateByteInterval
+	// KeyUpdateConfidentialityLimit is used when the number of packets sent with the current keys
+	// approaches the confidentiality limit of the cipher suite
+	KeyUpdateConfidentialityLimit
+)
+
 // ECNStateTrigger is a trigger for an ECN state transition.
 type ECNStateTrigger uint8
 
@@ -126,3 +162,15 @@ const (
 	// ECNFailedManglingDetected is emitted when the path marks all ECN-marked packets as CE
 	ECNFailedManglingDetected
 )
+
+// GSODisabledReason is the reason why Generic Segmentation Offload (GSO) was disabled at runtime.
+type GSODisabledReason uint8
+
+const (
+	// GSODisabledSendError is used when sending a GSO batch failed with EIO or EINVAL,
+	// usually because the network interface or the driver doesn't support UDP segmentation offload
+	GSODisabledSendError GSODisabledReason = 1 + iota
+	// GSODisabledBlackholed is used when packets sent in GSO batches were repeatedly lost,
+	// while packets sent after them were acknowledged
+	GSODisabledBlackholed
+)
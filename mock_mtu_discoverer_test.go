@@ -69,6 +69,56 @@ func (mr *MockMTUDiscovererMockRecorder) GetPing() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPing", reflect.TypeOf((*MockMTUDiscoverer)(nil).GetPing))
 }
 
+// OnPTO mocks base method.
+func (m *MockMTUDiscoverer) OnPTO(arg0 uint32) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPTO", arg0)
+}
+
+// OnPTO indicates an expected call of OnPTO.
+func (mr *MockMTUDiscovererMockRecorder) OnPTO(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPTO", reflect.TypeOf((*MockMTUDiscoverer)(nil).OnPTO), arg0)
+}
+
+// OnPacketAcked mocks base method.
+func (m *MockMTUDiscoverer) OnPacketAcked(arg0 protocol.PacketNumber, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPacketAcked", arg0, arg1)
+}
+
+// OnPacketAcked indicates an expected call of OnPacketAcked.
+func (mr *MockMTUDiscovererMockRecorder) OnPacketAcked(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketAcked", reflect.TypeOf((*MockMTUDiscoverer)(nil).OnPacketAcked), arg0, arg1)
+}
+
+// OnPacketLost mocks base method.
+func (m *MockMTUDiscoverer) OnPacketLost(arg0 protocol.PacketNumber, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnPacketLost", arg0, arg1)
+}
+
+// OnPacketLost indicates an expected call of OnPacketLost.
+func (mr *MockMTUDiscovererMockRecorder) OnPacketLost(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnPacketLost", reflect.TypeOf((*MockMTUDiscoverer)(nil).OnPacketLost), arg0, arg1)
+}
+
+// RequestProbe mocks base method.
+func (m *MockMTUDiscoverer) RequestProbe(arg0 protocol.ByteCount) *mtuProbeRequest {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestProbe", arg0)
+	ret0, _ := ret[0].(*mtuProbeRequest)
+	return ret0
+}
+
+// RequestProbe indicates an expected call of RequestProbe.
+func (mr *MockMTUDiscovererMockRecorder) RequestProbe(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestProbe", reflect.TypeOf((*MockMTUDiscoverer)(nil).RequestProbe), arg0)
+}
+
 // ShouldSendProbe mocks base method.
 func (m *MockMTUDiscoverer) ShouldSendProbe(arg0 time.Time) bool {
 	m.ctrl.T.Helper()
@@ -55,6 +55,18 @@ func (mr *MockPackerMockRecorder) AppendPacket(arg0, arg1, arg2 any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendPacket", reflect.TypeOf((*MockPacker)(nil).AppendPacket), arg0, arg1, arg2)
 }
 
+// EnableImmediateAck mocks base method.
+func (m *MockPacker) EnableImmediateAck() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EnableImmediateAck")
+}
+
+// EnableImmediateAck indicates an expected call of EnableImmediateAck.
+func (mr *MockPackerMockRecorder) EnableImmediateAck() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableImmediateAck", reflect.TypeOf((*MockPacker)(nil).EnableImmediateAck))
+}
+
 // MaybePackProbePacket mocks base method.
 func (m *MockPacker) MaybePackProbePacket(arg0 protocol.EncryptionLevel, arg1 protocol.ByteCount, arg2 protocol.VersionNumber) (*coalescedPacket, error) {
 	m.ctrl.T.Helper()
@@ -12,6 +12,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	qerr "github.com/quic-go/quic-go/internal/qerr"
@@ -85,6 +86,20 @@ func (mr *MockQUICConnMockRecorder) CloseWithError(arg0, arg1 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQUICConn)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithTimeout mocks base method.
+func (m *MockQUICConn) CloseWithTimeout(arg0 qerr.ApplicationErrorCode, arg1 string, arg2 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithTimeout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithTimeout indicates an expected call of CloseWithTimeout.
+func (mr *MockQUICConnMockRecorder) CloseWithTimeout(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithTimeout", reflect.TypeOf((*MockQUICConn)(nil).CloseWithTimeout), arg0, arg1, arg2)
+}
+
 // ConnectionState mocks base method.
 func (m *MockQUICConn) ConnectionState() ConnectionState {
 	m.ctrl.T.Helper()
@@ -141,6 +156,20 @@ func (mr *MockQUICConnMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQUICConn)(nil).HandshakeComplete))
 }
 
+// HandshakeConfirmed mocks base method.
+func (m *MockQUICConn) HandshakeConfirmed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandshakeConfirmed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// HandshakeConfirmed indicates an expected call of HandshakeConfirmed.
+func (mr *MockQUICConnMockRecorder) HandshakeConfirmed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeConfirmed", reflect.TypeOf((*MockQUICConn)(nil).HandshakeConfirmed))
+}
+
 // LocalAddr mocks base method.
 func (m *MockQUICConn) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -169,6 +198,21 @@ func (mr *MockQUICConnMockRecorder) NextConnection() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NextConnection", reflect.TypeOf((*MockQUICConn)(nil).NextConnection))
 }
 
+// ObservedAddress mocks base method.
+func (m *MockQUICConn) ObservedAddress(arg0 context.Context) (net.Addr, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ObservedAddress", arg0)
+	ret0, _ := ret[0].(net.Addr)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ObservedAddress indicates an expected call of ObservedAddress.
+func (mr *MockQUICConnMockRecorder) ObservedAddress(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObservedAddress", reflect.TypeOf((*MockQUICConn)(nil).ObservedAddress), arg0)
+}
+
 // OpenStream mocks base method.
 func (m *MockQUICConn) OpenStream() (Stream, error) {
 	m.ctrl.T.Helper()
@@ -229,6 +273,63 @@ func (mr *MockQUICConnMockRecorder) OpenUniStreamSync(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQUICConn)(nil).OpenUniStreamSync), arg0)
 }
 
+// Ping mocks base method.
+func (m *MockQUICConn) Ping(arg0 context.Context) (time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", arg0)
+	ret0, _ := ret[0].(time.Duration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockQUICConnMockRecorder) Ping(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockQUICConn)(nil).Ping), arg0)
+}
+
+// ProbeMTU mocks base method.
+func (m *MockQUICConn) ProbeMTU(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProbeMTU", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ProbeMTU indicates an expected call of ProbeMTU.
+func (mr *MockQUICConnMockRecorder) ProbeMTU(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProbeMTU", reflect.TypeOf((*MockQUICConn)(nil).ProbeMTU), arg0, arg1)
+}
+
+// RTTStats mocks base method.
+func (m *MockQUICConn) RTTStats() RTTStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RTTStats")
+	ret0, _ := ret[0].(RTTStats)
+	return ret0
+}
+
+// RTTStats indicates an expected call of RTTStats.
+func (mr *MockQUICConnMockRecorder) RTTStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RTTStats", reflect.TypeOf((*MockQUICConn)(nil).RTTStats))
+}
+
+// Rebind mocks base method.
+func (m *MockQUICConn) Rebind(arg0 net.PacketConn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rebind", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Rebind indicates an expected call of Rebind.
+func (mr *MockQUICConnMockRecorder) Rebind(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rebind", reflect.TypeOf((*MockQUICConn)(nil).Rebind), arg0)
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockQUICConn) ReceiveMessage(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -258,6 +359,20 @@ func (mr *MockQUICConnMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQUICConn)(nil).RemoteAddr))
 }
 
+// SendExtensionFrame mocks base method.
+func (m *MockQUICConn) SendExtensionFrame(arg0 uint64, arg1 any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendExtensionFrame", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendExtensionFrame indicates an expected call of SendExtensionFrame.
+func (mr *MockQUICConnMockRecorder) SendExtensionFrame(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendExtensionFrame", reflect.TypeOf((*MockQUICConn)(nil).SendExtensionFrame), arg0, arg1)
+}
+
 // SendMessage mocks base method.
 func (m *MockQUICConn) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -272,6 +387,42 @@ func (mr *MockQUICConnMockRecorder) SendMessage(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQUICConn)(nil).SendMessage), arg0)
 }
 
+// SetIdleTimeout mocks base method.
+func (m *MockQUICConn) SetIdleTimeout(arg0 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetIdleTimeout", arg0)
+}
+
+// SetIdleTimeout indicates an expected call of SetIdleTimeout.
+func (mr *MockQUICConnMockRecorder) SetIdleTimeout(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIdleTimeout", reflect.TypeOf((*MockQUICConn)(nil).SetIdleTimeout), arg0)
+}
+
+// SetSendRateLimit mocks base method.
+func (m *MockQUICConn) SetSendRateLimit(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0, arg1)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockQUICConnMockRecorder) SetSendRateLimit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockQUICConn)(nil).SetSendRateLimit), arg0, arg1)
+}
+
+// closeLocal mocks base method.
+func (m *MockQUICConn) closeLocal(arg0 error) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "closeLocal", arg0)
+}
+
+// closeLocal indicates an expected call of closeLocal.
+func (mr *MockQUICConnMockRecorder) closeLocal(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "closeLocal", reflect.TypeOf((*MockQUICConn)(nil).closeLocal), arg0)
+}
+
 // destroy mocks base method.
 func (m *MockQUICConn) destroy(arg0 error) {
 	m.ctrl.T.Helper()
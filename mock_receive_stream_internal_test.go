@@ -53,6 +53,20 @@ func (mr *MockReceiveStreamIMockRecorder) CancelRead(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelRead), arg0)
 }
 
+// EarlyData mocks base method.
+func (m *MockReceiveStreamI) EarlyData() []EarlyDataRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyData")
+	ret0, _ := ret[0].([]EarlyDataRange)
+	return ret0
+}
+
+// EarlyData indicates an expected call of EarlyData.
+func (mr *MockReceiveStreamIMockRecorder) EarlyData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyData", reflect.TypeOf((*MockReceiveStreamI)(nil).EarlyData))
+}
+
 // Read mocks base method.
 func (m *MockReceiveStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -149,3 +163,29 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// receiveBufferedBytes mocks base method.
+func (m *MockReceiveStreamI) receiveBufferedBytes() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "receiveBufferedBytes")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// receiveBufferedBytes indicates an expected call of receiveBufferedBytes.
+func (mr *MockReceiveStreamIMockRecorder) receiveBufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receiveBufferedBytes", reflect.TypeOf((*MockReceiveStreamI)(nil).receiveBufferedBytes))
+}
+
+// receivedEarlyData mocks base method.
+func (m *MockReceiveStreamI) receivedEarlyData(arg0, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "receivedEarlyData", arg0, arg1)
+}
+
+// receivedEarlyData indicates an expected call of receivedEarlyData.
+func (mr *MockReceiveStreamIMockRecorder) receivedEarlyData(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receivedEarlyData", reflect.TypeOf((*MockReceiveStreamI)(nil).receivedEarlyData), arg0, arg1)
+}
@@ -13,6 +13,7 @@ import (
 	reflect "reflect"
 
 	protocol "github.com/quic-go/quic-go/internal/protocol"
+	logging "github.com/quic-go/quic-go/logging"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -108,3 +109,15 @@ func (mr *MockSendConnMockRecorder) capabilities() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "capabilities", reflect.TypeOf((*MockSendConn)(nil).capabilities))
 }
+
+// disableGSO mocks base method.
+func (m *MockSendConn) disableGSO(arg0 logging.GSODisabledReason) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "disableGSO", arg0)
+}
+
+// disableGSO indicates an expected call of disableGSO.
+func (mr *MockSendConnMockRecorder) disableGSO(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "disableGSO", reflect.TypeOf((*MockSendConn)(nil).disableGSO), arg0)
+}
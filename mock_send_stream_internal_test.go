@@ -43,6 +43,20 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// AvailableSendWindow mocks base method.
+func (m *MockSendStreamI) AvailableSendWindow() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AvailableSendWindow")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AvailableSendWindow indicates an expected call of AvailableSendWindow.
+func (mr *MockSendStreamIMockRecorder) AvailableSendWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AvailableSendWindow", reflect.TypeOf((*MockSendStreamI)(nil).AvailableSendWindow))
+}
+
 // CancelWrite mocks base method.
 func (m *MockSendStreamI) CancelWrite(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -83,6 +97,30 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// SetBlockedCallback mocks base method.
+func (m *MockSendStreamI) SetBlockedCallback(arg0 func(SendBlockedReason, bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBlockedCallback", arg0)
+}
+
+// SetBlockedCallback indicates an expected call of SetBlockedCallback.
+func (mr *MockSendStreamIMockRecorder) SetBlockedCallback(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlockedCallback", reflect.TypeOf((*MockSendStreamI)(nil).SetBlockedCallback), arg0)
+}
+
+// SetSendRateLimit mocks base method.
+func (m *MockSendStreamI) SetSendRateLimit(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0, arg1)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockSendStreamIMockRecorder) SetSendRateLimit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockSendStreamI)(nil).SetSendRateLimit), arg0, arg1)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -126,6 +164,21 @@ func (mr *MockSendStreamIMockRecorder) Write(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendStreamI)(nil).Write), arg0)
 }
 
+// WriteBuffer mocks base method.
+func (m *MockSendStreamI) WriteBuffer(arg0 []byte, arg1 func()) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffer", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffer indicates an expected call of WriteBuffer.
+func (mr *MockSendStreamIMockRecorder) WriteBuffer(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffer", reflect.TypeOf((*MockSendStreamI)(nil).WriteBuffer), arg0, arg1)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockSendStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -164,6 +217,18 @@ func (mr *MockSendStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockSendStreamI)(nil).hasData))
 }
 
+// onCongestionLimited mocks base method.
+func (m *MockSendStreamI) onCongestionLimited() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onCongestionLimited")
+}
+
+// onCongestionLimited indicates an expected call of onCongestionLimited.
+func (mr *MockSendStreamIMockRecorder) onCongestionLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onCongestionLimited", reflect.TypeOf((*MockSendStreamI)(nil).onCongestionLimited))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockSendStreamI) popStreamFrame(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
 	m.ctrl.T.Helper()
@@ -180,6 +245,20 @@ func (mr *MockSendStreamIMockRecorder) popStreamFrame(arg0, arg1 any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockSendStreamI)(nil).popStreamFrame), arg0, arg1)
 }
 
+// sendBufferedBytes mocks base method.
+func (m *MockSendStreamI) sendBufferedBytes() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "sendBufferedBytes")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// sendBufferedBytes indicates an expected call of sendBufferedBytes.
+func (mr *MockSendStreamIMockRecorder) sendBufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "sendBufferedBytes", reflect.TypeOf((*MockSendStreamI)(nil).sendBufferedBytes))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockSendStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
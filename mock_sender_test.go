@@ -64,6 +64,20 @@ func (mr *MockSenderMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSender)(nil).Close))
 }
 
+// QueuedBytes mocks base method.
+func (m *MockSender) QueuedBytes() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueuedBytes")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// QueuedBytes indicates an expected call of QueuedBytes.
+func (mr *MockSenderMockRecorder) QueuedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueuedBytes", reflect.TypeOf((*MockSender)(nil).QueuedBytes))
+}
+
 // Run mocks base method.
 func (m *MockSender) Run() error {
 	m.ctrl.T.Helper()
@@ -43,6 +43,20 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// AvailableSendWindow mocks base method.
+func (m *MockStreamI) AvailableSendWindow() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AvailableSendWindow")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// AvailableSendWindow indicates an expected call of AvailableSendWindow.
+func (mr *MockStreamIMockRecorder) AvailableSendWindow() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AvailableSendWindow", reflect.TypeOf((*MockStreamI)(nil).AvailableSendWindow))
+}
+
 // CancelRead mocks base method.
 func (m *MockStreamI) CancelRead(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -95,6 +109,20 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// EarlyData mocks base method.
+func (m *MockStreamI) EarlyData() []EarlyDataRange {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EarlyData")
+	ret0, _ := ret[0].([]EarlyDataRange)
+	return ret0
+}
+
+// EarlyData indicates an expected call of EarlyData.
+func (mr *MockStreamIMockRecorder) EarlyData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EarlyData", reflect.TypeOf((*MockStreamI)(nil).EarlyData))
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -110,6 +138,18 @@ func (mr *MockStreamIMockRecorder) Read(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), arg0)
 }
 
+// SetBlockedCallback mocks base method.
+func (m *MockStreamI) SetBlockedCallback(arg0 func(SendBlockedReason, bool)) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetBlockedCallback", arg0)
+}
+
+// SetBlockedCallback indicates an expected call of SetBlockedCallback.
+func (mr *MockStreamIMockRecorder) SetBlockedCallback(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBlockedCallback", reflect.TypeOf((*MockStreamI)(nil).SetBlockedCallback), arg0)
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -138,6 +178,18 @@ func (mr *MockStreamIMockRecorder) SetReadDeadline(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStreamI)(nil).SetReadDeadline), arg0)
 }
 
+// SetSendRateLimit mocks base method.
+func (m *MockStreamI) SetSendRateLimit(arg0, arg1 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSendRateLimit", arg0, arg1)
+}
+
+// SetSendRateLimit indicates an expected call of SetSendRateLimit.
+func (mr *MockStreamIMockRecorder) SetSendRateLimit(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSendRateLimit", reflect.TypeOf((*MockStreamI)(nil).SetSendRateLimit), arg0, arg1)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStreamI) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -181,6 +233,21 @@ func (mr *MockStreamIMockRecorder) Write(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), arg0)
 }
 
+// WriteBuffer mocks base method.
+func (m *MockStreamI) WriteBuffer(arg0 []byte, arg1 func()) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffer", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffer indicates an expected call of WriteBuffer.
+func (mr *MockStreamIMockRecorder) WriteBuffer(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffer", reflect.TypeOf((*MockStreamI)(nil).WriteBuffer), arg0, arg1)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -261,6 +328,18 @@ func (mr *MockStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockStreamI)(nil).hasData))
 }
 
+// onCongestionLimited mocks base method.
+func (m *MockStreamI) onCongestionLimited() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onCongestionLimited")
+}
+
+// onCongestionLimited indicates an expected call of onCongestionLimited.
+func (mr *MockStreamIMockRecorder) onCongestionLimited() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onCongestionLimited", reflect.TypeOf((*MockStreamI)(nil).onCongestionLimited))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockStreamI) popStreamFrame(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
 	m.ctrl.T.Helper()
@@ -277,6 +356,46 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(arg0, arg1 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), arg0, arg1)
 }
 
+// receiveBufferedBytes mocks base method.
+func (m *MockStreamI) receiveBufferedBytes() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "receiveBufferedBytes")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// receiveBufferedBytes indicates an expected call of receiveBufferedBytes.
+func (mr *MockStreamIMockRecorder) receiveBufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receiveBufferedBytes", reflect.TypeOf((*MockStreamI)(nil).receiveBufferedBytes))
+}
+
+// receivedEarlyData mocks base method.
+func (m *MockStreamI) receivedEarlyData(arg0, arg1 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "receivedEarlyData", arg0, arg1)
+}
+
+// receivedEarlyData indicates an expected call of receivedEarlyData.
+func (mr *MockStreamIMockRecorder) receivedEarlyData(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "receivedEarlyData", reflect.TypeOf((*MockStreamI)(nil).receivedEarlyData), arg0, arg1)
+}
+
+// sendBufferedBytes mocks base method.
+func (m *MockStreamI) sendBufferedBytes() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "sendBufferedBytes")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// sendBufferedBytes indicates an expected call of sendBufferedBytes.
+func (mr *MockStreamIMockRecorder) sendBufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "sendBufferedBytes", reflect.TypeOf((*MockStreamI)(nil).sendBufferedBytes))
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
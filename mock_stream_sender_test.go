@@ -63,6 +63,18 @@ func (mr *MockStreamSenderMockRecorder) onStreamCompleted(arg0 any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamCompleted", reflect.TypeOf((*MockStreamSender)(nil).onStreamCompleted), arg0)
 }
 
+// onStreamDataRetransmission mocks base method.
+func (m *MockStreamSender) onStreamDataRetransmission(arg0 protocol.StreamID, arg1, arg2 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamDataRetransmission", arg0, arg1, arg2)
+}
+
+// onStreamDataRetransmission indicates an expected call of onStreamDataRetransmission.
+func (mr *MockStreamSenderMockRecorder) onStreamDataRetransmission(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamDataRetransmission", reflect.TypeOf((*MockStreamSender)(nil).onStreamDataRetransmission), arg0, arg1, arg2)
+}
+
 // queueControlFrame mocks base method.
 func (m *MockStreamSender) queueControlFrame(arg0 wire.Frame) {
 	m.ctrl.T.Helper()
@@ -55,6 +55,24 @@ func (mr *MockUnpackerMockRecorder) UnpackLongHeader(arg0, arg1, arg2, arg3 any)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpackLongHeader", reflect.TypeOf((*MockUnpacker)(nil).UnpackLongHeader), arg0, arg1, arg2, arg3)
 }
 
+// UnpackOpenedShortHeader mocks base method.
+func (m *MockUnpacker) UnpackOpenedShortHeader(arg0 time.Time, arg1 *openedShortHeaderPacket) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnpackOpenedShortHeader", arg0, arg1)
+	ret0, _ := ret[0].(protocol.PacketNumber)
+	ret1, _ := ret[1].(protocol.PacketNumberLen)
+	ret2, _ := ret[2].(protocol.KeyPhaseBit)
+	ret3, _ := ret[3].([]byte)
+	ret4, _ := ret[4].(error)
+	return ret0, ret1, ret2, ret3, ret4
+}
+
+// UnpackOpenedShortHeader indicates an expected call of UnpackOpenedShortHeader.
+func (mr *MockUnpackerMockRecorder) UnpackOpenedShortHeader(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnpackOpenedShortHeader", reflect.TypeOf((*MockUnpacker)(nil).UnpackOpenedShortHeader), arg0, arg1)
+}
+
 // UnpackShortHeader mocks base method.
 func (m *MockUnpacker) UnpackShortHeader(arg0 time.Time, arg1 []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
 	m.ctrl.T.Helper()
@@ -1,7 +1,10 @@
 package quic
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
@@ -11,20 +14,48 @@ import (
 )
 
 type mtuDiscoverer interface {
+	// The mtuDiscoverer observes acknowledged and lost 1-RTT packets to detect PMTU black holes.
+	ackhandler.PacketObserver
+
 	// Start starts the MTU discovery process.
 	// It's unnecessary to call ShouldSendProbe before that.
 	Start(maxPacketSize protocol.ByteCount)
 	ShouldSendProbe(now time.Time) bool
 	CurrentSize() protocol.ByteCount
 	GetPing() (ping ackhandler.Frame, datagramSize protocol.ByteCount)
+	// RequestProbe requests sending a probe packet of the given size as soon as possible,
+	// independent of the probing schedule.
+	// It is safe to call from any goroutine.
+	RequestProbe(size protocol.ByteCount) *mtuProbeRequest
+}
+
+// An mtuProbeRequest is a probe packet requested by the application.
+type mtuProbeRequest struct {
+	size protocol.ByteCount
+	err  error // only valid once done is closed
+	done chan struct{}
 }
 
+func (r *mtuProbeRequest) complete(err error) {
+	r.err = err
+	close(r.done)
+}
+
+var errMTUProbeLost = errors.New("MTU probe packet lost")
+
 const (
 	// At some point, we have to stop searching for a higher MTU.
 	// We're happy to send a packet that's 10 bytes smaller than the actual MTU.
 	maxMTUDiff = 20
 	// send a probe packet every mtuProbeDelay RTTs
 	mtuProbeDelay = 5
+	// A PMTU black hole is detected when this number of packets larger than the last known-good size are lost,
+	// without any later packet of that size being acknowledged.
+	mtuBlackHoleLostPackets = 3
+	// A PMTU black hole is also detected when the PTO timer fires this many times in a row.
+	// This is necessary if all packets sent are larger than the last known-good size,
+	// since packets are only declared lost once a packet sent later is acknowledged.
+	mtuBlackHolePTOCount = 3
 )
 
 func getMaxPacketSize(addr net.Addr) protocol.ByteCount {
@@ -41,24 +72,70 @@ func getMaxPacketSize(addr net.Addr) protocol.ByteCount {
 	return maxSize
 }
 
+// getInitialPacketSize returns the size of the packets sent at the beginning of a connection.
+// If the conn declares its maximum packet size, and Path MTU Discovery can't be run on it, packets of that size are sent.
+func getInitialPacketSize(addr net.Addr, capabilities connCapabilities) protocol.ByteCount {
+	if capabilities.MaxPacketSize == 0 {
+		return getMaxPacketSize(addr)
+	}
+	if capabilities.DF {
+		return utils.Min(getMaxPacketSize(addr), capabilities.MaxPacketSize)
+	}
+	return capabilities.MaxPacketSize
+}
+
 type mtuFinder struct {
 	lastProbeTime time.Time
 	mtuIncreased  func(protocol.ByteCount)
+	// blackHoleDetected is called when the MTU is decreased after a PMTU black hole was detected.
+	blackHoleDetected func(oldSize, newSize protocol.ByteCount)
+	// requestedProbeSent is called when a probe packet requested by the application is sent.
+	// Probe packets don't arm the PTO timer. The connection needs to send an ack-eliciting packet after the probe packet,
+	// otherwise the loss of the probe packet wouldn't be detected if the connection is otherwise idle.
+	requestedProbeSent func()
 
 	rttStats *utils.RTTStats
+	clock    utils.Clock
 	inFlight protocol.ByteCount // the size of the probe packet currently in flight. InvalidByteCount if none is in flight
 	current  protocol.ByteCount
-	max      protocol.ByteCount // the maximum value, as advertised by the peer (or our maximum size buffer)
+	max      protocol.ByteCount // the maximum value that might work (initially the limit)
+	limit    protocol.ByteCount // the maximum value, as advertised by the peer (or our maximum size buffer)
+
+	start protocol.ByteCount
+	// The sizes that were in use before the current size, in increasing order.
+	// The last element is the last known-good size.
+	goodSizes []protocol.ByteCount
+	// the largest packet number of a packet larger than the last known-good size that was acknowledged
+	largestAckedLarge protocol.PacketNumber
+	// the number of packets larger than the last known-good size that were lost after largestAckedLarge
+	lostLarge int
+
+	inFlightRequest *mtuProbeRequest // the request that the probe packet in flight was sent for, if any
+
+	requestMutex sync.Mutex
+	requests     []*mtuProbeRequest
 }
 
 var _ mtuDiscoverer = &mtuFinder{}
 
-func newMTUDiscoverer(rttStats *utils.RTTStats, start protocol.ByteCount, mtuIncreased func(protocol.ByteCount)) *mtuFinder {
+func newMTUDiscoverer(
+	rttStats *utils.RTTStats,
+	clock utils.Clock,
+	start protocol.ByteCount,
+	mtuIncreased func(protocol.ByteCount),
+	requestedProbeSent func(),
+	blackHoleDetected func(oldSize, newSize protocol.ByteCount),
+) *mtuFinder {
 	return &mtuFinder{
-		inFlight:     protocol.InvalidByteCount,
-		current:      start,
-		rttStats:     rttStats,
-		mtuIncreased: mtuIncreased,
+		inFlight:           protocol.InvalidByteCount,
+		current:            start,
+		start:              start,
+		largestAckedLarge:  protocol.InvalidPacketNumber,
+		rttStats:           rttStats,
+		clock:              clock,
+		mtuIncreased:       mtuIncreased,
+		requestedProbeSent: requestedProbeSent,
+		blackHoleDetected:  blackHoleDetected,
 	}
 }
 
@@ -67,23 +144,67 @@ func (f *mtuFinder) done() bool {
 }
 
 func (f *mtuFinder) Start(maxPacketSize protocol.ByteCount) {
-	f.lastProbeTime = time.Now() // makes sure the first probe packet is not sent immediately
+	f.lastProbeTime = f.clock.Now() // makes sure the first probe packet is not sent immediately
 	f.max = maxPacketSize
+	f.limit = maxPacketSize
 }
 
 func (f *mtuFinder) ShouldSendProbe(now time.Time) bool {
 	if f.max == 0 || f.lastProbeTime.IsZero() {
 		return false
 	}
-	if f.inFlight != protocol.InvalidByteCount || f.done() {
+	if f.inFlight != protocol.InvalidByteCount {
+		return false
+	}
+	if f.hasRequest() {
+		return true
+	}
+	if f.done() {
 		return false
 	}
 	return !now.Before(f.lastProbeTime.Add(mtuProbeDelay * f.rttStats.SmoothedRTT()))
 }
 
+func (f *mtuFinder) RequestProbe(size protocol.ByteCount) *mtuProbeRequest {
+	r := &mtuProbeRequest{size: size, done: make(chan struct{})}
+	f.requestMutex.Lock()
+	f.requests = append(f.requests, r)
+	f.requestMutex.Unlock()
+	return r
+}
+
+// hasRequest says if there's a request that requires sending a probe packet.
+// Requests that can be answered without sending a probe packet are completed right away.
+func (f *mtuFinder) hasRequest() bool {
+	f.requestMutex.Lock()
+	defer f.requestMutex.Unlock()
+
+	for len(f.requests) > 0 {
+		r := f.requests[0]
+		switch {
+		case r.size <= f.current:
+			r.complete(nil)
+		case r.size > f.limit:
+			r.complete(fmt.Errorf("MTU probe size exceeds the maximum packet size (%d bytes)", f.limit))
+		default:
+			return true
+		}
+		f.requests = f.requests[1:]
+	}
+	return false
+}
+
 func (f *mtuFinder) GetPing() (ackhandler.Frame, protocol.ByteCount) {
 	size := (f.max + f.current) / 2
-	f.lastProbeTime = time.Now()
+	if f.hasRequest() {
+		f.requestMutex.Lock()
+		f.inFlightRequest = f.requests[0]
+		f.requests = f.requests[1:]
+		f.requestMutex.Unlock()
+		size = f.inFlightRequest.size
+		f.requestedProbeSent()
+	}
+	f.lastProbeTime = f.clock.Now()
 	f.inFlight = size
 	return ackhandler.Frame{
 		Frame:   &wire.PingFrame{},
@@ -95,6 +216,55 @@ func (f *mtuFinder) CurrentSize() protocol.ByteCount {
 	return f.current
 }
 
+// isLarge says if a packet of this size was only sent because of the last MTU increase.
+// Packets larger than the current size were sent before a PMTU black hole was detected, and are ignored.
+func (f *mtuFinder) isLarge(size protocol.ByteCount) bool {
+	return len(f.goodSizes) > 0 && size > f.goodSizes[len(f.goodSizes)-1] && size <= f.current
+}
+
+func (f *mtuFinder) OnPacketAcked(pn protocol.PacketNumber, size protocol.ByteCount) {
+	if !f.isLarge(size) {
+		return
+	}
+	if pn > f.largestAckedLarge {
+		f.largestAckedLarge = pn
+	}
+	f.lostLarge = 0
+}
+
+func (f *mtuFinder) OnPacketLost(pn protocol.PacketNumber, size protocol.ByteCount) {
+	// If a larger packet sent after this packet was acknowledged, this is a regular packet loss.
+	if !f.isLarge(size) || pn < f.largestAckedLarge {
+		return
+	}
+	f.lostLarge++
+	if f.lostLarge >= mtuBlackHoleLostPackets {
+		f.decreaseMTU(f.goodSizes[len(f.goodSizes)-1])
+	}
+}
+
+func (f *mtuFinder) OnPTO(ptoCount uint32) {
+	// Consecutive PTOs don't tell us which packet size still works.
+	// Fall back to the size used during the handshake.
+	if ptoCount == mtuBlackHolePTOCount && f.current > f.start {
+		f.decreaseMTU(f.start)
+	}
+}
+
+// decreaseMTU falls back to a smaller size after a PMTU black hole was detected.
+// MTU discovery continues, but it won't probe the size that just failed again.
+func (f *mtuFinder) decreaseMTU(size protocol.ByteCount) {
+	oldSize := f.current
+	for len(f.goodSizes) > 0 && f.goodSizes[len(f.goodSizes)-1] >= size {
+		f.goodSizes = f.goodSizes[:len(f.goodSizes)-1]
+	}
+	f.current = size
+	f.max = oldSize
+	f.lostLarge = 0
+	f.lastProbeTime = f.clock.Now()
+	f.blackHoleDetected(oldSize, size)
+}
+
 type mtuFinderAckHandler mtuFinder
 
 var _ ackhandler.FrameHandler = &mtuFinderAckHandler{}
@@ -105,8 +275,18 @@ func (h *mtuFinderAckHandler) OnAcked(wire.Frame) {
 		panic("OnAcked callback called although there's no MTU probe packet in flight")
 	}
 	h.inFlight = protocol.InvalidByteCount
+	h.goodSizes = append(h.goodSizes, h.current)
 	h.current = size
+	h.lostLarge = 0
 	h.mtuIncreased(size)
+	if r := h.inFlightRequest; r != nil {
+		h.inFlightRequest = nil
+		// The path might have changed, continue searching up to the limit.
+		if size >= h.max {
+			h.max = h.limit
+		}
+		r.complete(nil)
+	}
 }
 
 func (h *mtuFinderAckHandler) OnLost(wire.Frame) {
@@ -114,6 +294,12 @@ func (h *mtuFinderAckHandler) OnLost(wire.Frame) {
 	if size == protocol.InvalidByteCount {
 		panic("OnLost callback called although there's no MTU probe packet in flight")
 	}
-	h.max = size
+	if size < h.max {
+		h.max = size
+	}
 	h.inFlight = protocol.InvalidByteCount
+	if r := h.inFlightRequest; r != nil {
+		h.inFlightRequest = nil
+		r.complete(errMTUProbeLost)
+	}
 }
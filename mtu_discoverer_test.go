@@ -19,19 +19,32 @@ var _ = Describe("MTU Discoverer", func() {
 	)
 
 	var (
-		d             *mtuFinder
-		rttStats      *utils.RTTStats
-		now           time.Time
-		discoveredMTU protocol.ByteCount
+		d                  *mtuFinder
+		rttStats           *utils.RTTStats
+		now                time.Time
+		discoveredMTU      protocol.ByteCount
+		numRequestedProbes int
+		blackHoles         [][2]protocol.ByteCount
 	)
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
 		rttStats.SetInitialRTT(rtt)
 		Expect(rttStats.SmoothedRTT()).To(Equal(rtt))
-		d = newMTUDiscoverer(rttStats, startMTU, func(s protocol.ByteCount) { discoveredMTU = s })
+		blackHoles = nil
+		d = newMTUDiscoverer(
+			rttStats,
+			utils.DefaultClock{},
+			startMTU,
+			func(s protocol.ByteCount) { discoveredMTU = s },
+			func() { numRequestedProbes++ },
+			func(oldSize, newSize protocol.ByteCount) {
+				blackHoles = append(blackHoles, [2]protocol.ByteCount{oldSize, newSize})
+			},
+		)
 		d.Start(maxMTU)
 		now = time.Now()
+		numRequestedProbes = 0
 	})
 
 	It("only allows a probe 5 RTTs after the handshake completes", func() {
@@ -78,19 +91,186 @@ var _ = Describe("MTU Discoverer", func() {
 	})
 
 	It("doesn't do discovery before being started", func() {
-		d := newMTUDiscoverer(rttStats, startMTU, func(s protocol.ByteCount) {})
+		d := newMTUDiscoverer(rttStats, utils.DefaultClock{}, startMTU, func(s protocol.ByteCount) {}, func() {}, func(_, _ protocol.ByteCount) {})
 		for i := 0; i < 5; i++ {
 			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
 		}
 	})
 
+	Context("probes requested by the application", func() {
+		It("sends a requested probe immediately", func() {
+			Expect(d.ShouldSendProbe(now)).To(BeFalse())
+			r := d.RequestProbe(1800)
+			Expect(d.ShouldSendProbe(now)).To(BeTrue())
+			Expect(numRequestedProbes).To(BeZero())
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1800)))
+			Expect(numRequestedProbes).To(Equal(1))
+			Expect(d.ShouldSendProbe(now)).To(BeFalse()) // probe in flight
+			Expect(r.done).ToNot(BeClosed())
+			ping.Handler.OnAcked(ping.Frame)
+			Expect(r.done).To(BeClosed())
+			Expect(r.err).ToNot(HaveOccurred())
+			Expect(discoveredMTU).To(Equal(protocol.ByteCount(1800)))
+			// discovery continues according to the schedule
+			Expect(d.ShouldSendProbe(now)).To(BeFalse())
+			_, size = d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1900)))
+		})
+
+		It("reports lost probe packets", func() {
+			r := d.RequestProbe(1800)
+			Expect(d.ShouldSendProbe(now)).To(BeTrue())
+			ping, _ := d.GetPing()
+			ping.Handler.OnLost(ping.Frame)
+			Expect(r.done).To(BeClosed())
+			Expect(r.err).To(MatchError(errMTUProbeLost))
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1400)))
+		})
+
+		It("probes beyond the maximum found by discovery", func() {
+			ping, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1500)))
+			ping.Handler.OnLost(ping.Frame)
+			// the path changed, the application requests a probe
+			r := d.RequestProbe(1800)
+			Expect(d.ShouldSendProbe(now)).To(BeTrue())
+			ping, size = d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1800)))
+			ping.Handler.OnAcked(ping.Frame)
+			Expect(r.err).ToNot(HaveOccurred())
+			_, size = d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1900)))
+		})
+
+		It("completes requests that don't require a probe packet", func() {
+			r1 := d.RequestProbe(startMTU)
+			r2 := d.RequestProbe(maxMTU + 1)
+			Expect(d.ShouldSendProbe(now)).To(BeFalse())
+			Expect(r1.done).To(BeClosed())
+			Expect(r1.err).ToNot(HaveOccurred())
+			Expect(r2.done).To(BeClosed())
+			Expect(r2.err).To(MatchError("MTU probe size exceeds the maximum packet size (2000 bytes)"))
+		})
+
+		It("doesn't send requested probes before being started", func() {
+			d := newMTUDiscoverer(rttStats, utils.DefaultClock{}, startMTU, func(s protocol.ByteCount) {}, func() {}, func(_, _ protocol.ByteCount) {})
+			d.RequestProbe(1500)
+			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
+		})
+	})
+
+	Context("PMTU black hole detection", func() {
+		increaseMTU := func() {
+			ping, size := d.GetPing()
+			ping.Handler.OnAcked(ping.Frame)
+			Expect(d.CurrentSize()).To(Equal(size))
+		}
+
+		It("doesn't detect black holes before the MTU was increased", func() {
+			for i := 0; i < 10; i++ {
+				d.OnPacketLost(protocol.PacketNumber(i), startMTU)
+			}
+			d.OnPTO(mtuBlackHolePTOCount)
+			Expect(blackHoles).To(BeEmpty())
+			Expect(d.CurrentSize()).To(Equal(startMTU))
+		})
+
+		It("falls back to the last known-good size when large packets are lost", func() {
+			increaseMTU()
+			Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1500)))
+			d.OnPacketAcked(10, 1500)
+			for i := 0; i < mtuBlackHoleLostPackets-1; i++ {
+				d.OnPacketAcked(protocol.PacketNumber(20+i), 500) // small packets still get through
+				d.OnPacketLost(protocol.PacketNumber(11+i), 1500)
+			}
+			Expect(blackHoles).To(BeEmpty())
+			d.OnPacketLost(20, 1500)
+			Expect(blackHoles).To(Equal([][2]protocol.ByteCount{{1500, startMTU}}))
+			Expect(d.CurrentSize()).To(Equal(startMTU))
+			// Packets sent before the MTU was decreased don't trigger another decrease.
+			for i := 0; i < 10; i++ {
+				d.OnPacketLost(protocol.PacketNumber(30+i), 1500)
+			}
+			Expect(blackHoles).To(HaveLen(1))
+		})
+
+		It("continues MTU discovery below the size that failed", func() {
+			increaseMTU()
+			for i := 0; i < mtuBlackHoleLostPackets; i++ {
+				d.OnPacketLost(protocol.PacketNumber(i), 1500)
+			}
+			Expect(d.CurrentSize()).To(Equal(startMTU))
+			Expect(d.ShouldSendProbe(time.Now())).To(BeFalse())
+			Expect(d.ShouldSendProbe(time.Now().Add(mtuProbeDelay * rtt))).To(BeTrue())
+			_, size := d.GetPing()
+			Expect(size).To(Equal(protocol.ByteCount(1250)))
+		})
+
+		It("ignores the loss of packets if a packet sent later was acknowledged", func() {
+			increaseMTU()
+			d.OnPacketAcked(100, 1500)
+			for i := 0; i < 10; i++ {
+				d.OnPacketLost(protocol.PacketNumber(i), 1500)
+			}
+			Expect(blackHoles).To(BeEmpty())
+		})
+
+		It("resets the count of lost packets when a large packet is acknowledged", func() {
+			increaseMTU()
+			for i := 0; i < 10; i++ {
+				d.OnPacketLost(protocol.PacketNumber(2*i), 1500)
+				d.OnPacketAcked(protocol.PacketNumber(2*i+1), 1500)
+			}
+			Expect(blackHoles).To(BeEmpty())
+		})
+
+		It("ignores the loss of small packets", func() {
+			increaseMTU()
+			for i := 0; i < 10; i++ {
+				d.OnPacketLost(protocol.PacketNumber(i), startMTU)
+			}
+			Expect(blackHoles).To(BeEmpty())
+		})
+
+		It("falls back one size at a time", func() {
+			increaseMTU()
+			increaseMTU()
+			Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1750)))
+			for i := 0; i < mtuBlackHoleLostPackets; i++ {
+				d.OnPacketLost(protocol.PacketNumber(i), 1750)
+			}
+			Expect(d.CurrentSize()).To(Equal(protocol.ByteCount(1500)))
+			for i := 0; i < mtuBlackHoleLostPackets; i++ {
+				d.OnPacketLost(protocol.PacketNumber(10+i), 1500)
+			}
+			Expect(d.CurrentSize()).To(Equal(startMTU))
+			Expect(blackHoles).To(Equal([][2]protocol.ByteCount{{1750, 1500}, {1500, startMTU}}))
+		})
+
+		It("falls back to the start size after repeated PTOs", func() {
+			increaseMTU()
+			increaseMTU()
+			for i := uint32(1); i < mtuBlackHolePTOCount; i++ {
+				d.OnPTO(i)
+			}
+			Expect(blackHoles).To(BeEmpty())
+			d.OnPTO(mtuBlackHolePTOCount)
+			Expect(d.CurrentSize()).To(Equal(startMTU))
+			Expect(blackHoles).To(Equal([][2]protocol.ByteCount{{1750, startMTU}}))
+			d.OnPTO(mtuBlackHolePTOCount + 1)
+			Expect(blackHoles).To(HaveLen(1))
+		})
+	})
+
 	It("finds the MTU", func() {
 		const rep = 3000
 		var maxDiff protocol.ByteCount
 		for i := 0; i < rep; i++ {
 			max := protocol.ByteCount(rand.Intn(int(3000-startMTU))) + startMTU + 1
 			currentMTU := startMTU
-			d := newMTUDiscoverer(rttStats, startMTU, func(s protocol.ByteCount) { currentMTU = s })
+			d := newMTUDiscoverer(rttStats, utils.DefaultClock{}, startMTU, func(s protocol.ByteCount) { currentMTU = s }, func() {}, func(_, _ protocol.ByteCount) {})
 			d.Start(max)
 			now := time.Now()
 			realMTU := protocol.ByteCount(rand.Intn(int(max-startMTU))) + startMTU
@@ -0,0 +1,292 @@
+package quic
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// MultihomedConfig configures a MultihomedGroup.
+type MultihomedConfig struct {
+	// The length of the connection IDs issued by the server.
+	// It can be any value between 4 and 18.
+	// If unset, a 4 byte connection ID will be used.
+	ConnectionIDLength int
+
+	// The StatelessResetKey is used to generate stateless reset tokens, see Transport.StatelessResetKey.
+	// It is shared by all sockets of the group.
+	StatelessResetKey *StatelessResetKey
+
+	// The TokenGeneratorKey is used to encrypt session resumption tokens, see Transport.TokenGeneratorKey.
+	// It is shared by all sockets of the group.
+	// If no key is configured, a random key will be generated.
+	TokenGeneratorKey *TokenGeneratorKey
+
+	// SessionTicketKeys provides the keys used to encrypt TLS session tickets, see Transport.SessionTicketKeys.
+	// It is used by all sockets of the group.
+	SessionTicketKeys SessionTicketKeyProvider
+
+	// AdvertiseAlternateAddresses makes connections advertise another address of the group
+	// in the preferred_address transport parameter, inviting the client to migrate to that address.
+	// At most one IPv4 and one IPv6 address are advertised, and only addresses with a specified IP are used.
+	// See section 9.6 of RFC 9000 for details.
+	AdvertiseAlternateAddresses bool
+
+	// A Tracer traces events that don't belong to a single QUIC connection.
+	Tracer *logging.Tracer
+}
+
+// A MultihomedGroup runs a server on multiple local addresses, which together form a single logical endpoint.
+//
+// A connection established on one address can migrate to any other address of the group:
+// When a client starts sending packets to a different address (or from a different address),
+// the server validates the new path, and then continues the connection on that path.
+// The connection state is kept by the socket the connection was established on.
+// Similar to the ReusePortGroup, the index of that socket is encoded into all connection IDs issued by the server,
+// and packets arriving on a different socket are rerouted in userspace.
+type MultihomedGroup struct {
+	transports []*Transport
+	advertise  bool
+}
+
+// NewMultihomedGroup creates a UDP socket for each of the addresses, and a Transport for each of them.
+func NewMultihomedGroup(addrs []string, config *MultihomedConfig) (*MultihomedGroup, error) {
+	if config == nil {
+		config = &MultihomedConfig{}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("no addresses")
+	}
+	if len(addrs) > 256 {
+		return nil, fmt.Errorf("too many addresses: %d", len(addrs))
+	}
+	connIDLen := config.ConnectionIDLength
+	if connIDLen == 0 {
+		connIDLen = protocol.DefaultConnectionIDLength
+	}
+	if connIDLen < 4 || connIDLen > 18 {
+		return nil, fmt.Errorf("invalid connection ID length: %d", connIDLen)
+	}
+	tokenGeneratorKey := config.TokenGeneratorKey
+	if tokenGeneratorKey == nil {
+		var key TokenGeneratorKey
+		if _, err := rand.Read(key[:]); err != nil {
+			return nil, err
+		}
+		tokenGeneratorKey = &key
+	}
+
+	g := &MultihomedGroup{
+		transports: make([]*Transport, 0, len(addrs)),
+		advertise:  config.AdvertiseAlternateAddresses,
+	}
+	for i, addr := range addrs {
+		udpAddr, err := net.ResolveUDPAddr("udp", addr)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		conn, err := net.ListenUDP("udp", udpAddr)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		g.transports = append(g.transports, &Transport{
+			Conn: conn,
+			ConnectionIDGenerator: &socketIndexConnIDGenerator{
+				index:      uint8(i),
+				numSockets: len(addrs),
+				connIDLen:  connIDLen,
+			},
+			StatelessResetKey: config.StatelessResetKey,
+			TokenGeneratorKey: tokenGeneratorKey,
+			SessionTicketKeys: config.SessionTicketKeys,
+			Tracer:            config.Tracer,
+			createdConn:       true,
+			reroute:           g.reroute,
+			multihomed:        g,
+		})
+	}
+	// Initialize all Transports before any of them reroutes packets to another one.
+	for _, t := range g.transports {
+		if err := t.init(false); err != nil {
+			g.Close()
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Transports returns the Transports, one for every address.
+// They can be used to dial new connections.
+func (g *MultihomedGroup) Transports() []*Transport {
+	return g.transports
+}
+
+// Addrs returns the local network addresses of the group.
+func (g *MultihomedGroup) Addrs() []net.Addr {
+	addrs := make([]net.Addr, 0, len(g.transports))
+	for _, t := range g.transports {
+		addrs = append(addrs, t.Conn.LocalAddr())
+	}
+	return addrs
+}
+
+// Listen starts listening for incoming QUIC connections on all addresses.
+// There can only be a single listener per group.
+func (g *MultihomedGroup) Listen(tlsConf *tls.Config, conf *Config) (*MultihomedListener, error) {
+	gl, err := listenGroup(g.transports, g.transports[0].Conn.LocalAddr(), tlsConf, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &MultihomedListener{groupListener: gl}, nil
+}
+
+// Close closes all sockets.
+func (g *MultihomedGroup) Close() error {
+	var firstErr error
+	for _, t := range g.transports {
+		if err := t.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// reroute routes a short header packet that was received on a different socket
+// to the connection on the socket encoded in the connection ID.
+func (g *MultihomedGroup) reroute(p receivedPacket, connID protocol.ConnectionID) bool {
+	return rerouteBySocketIndex(g.transports, p, connID)
+}
+
+// newSendConn creates the sendConn for a new connection on one of the sockets of the group.
+func (g *MultihomedGroup) newSendConn(c *sconn) *migratingConn {
+	mc := &migratingConn{}
+	mc.current.Store(c)
+	if g.advertise {
+		mc.preferredAddress = g.preferredAddress(c.rawConn)
+	}
+	return mc
+}
+
+// preferredAddress returns the addresses advertised to connections established on the socket c.
+// The connection ID and the stateless reset token are filled in by the connection.
+// It returns nil if there are no other addresses to advertise.
+func (g *MultihomedGroup) preferredAddress(c rawConn) *wire.PreferredAddress {
+	var ipv4, ipv6 *net.UDPAddr
+	for _, t := range g.transports {
+		if t.conn == c {
+			continue
+		}
+		addr, ok := t.Conn.LocalAddr().(*net.UDPAddr)
+		if !ok || addr.IP.IsUnspecified() {
+			continue
+		}
+		if addr.IP.To4() != nil {
+			if ipv4 == nil {
+				ipv4 = addr
+			}
+		} else if ipv6 == nil {
+			ipv6 = addr
+		}
+	}
+	if ipv4 == nil && ipv6 == nil {
+		return nil
+	}
+	pa := &wire.PreferredAddress{IPv4: net.IPv4zero.To4(), IPv6: net.IPv6zero}
+	if ipv4 != nil {
+		pa.IPv4 = ipv4.IP.To4()
+		pa.IPv4Port = uint16(ipv4.Port)
+	}
+	if ipv6 != nil {
+		pa.IPv6 = ipv6.IP.To16()
+		pa.IPv6Port = uint16(ipv6.Port)
+	}
+	return pa
+}
+
+// A MultihomedListener accepts connections on all addresses of a MultihomedGroup.
+type MultihomedListener struct {
+	*groupListener
+}
+
+// A migratingConn is the sendConn of a server connection in a MultihomedGroup,
+// and of a client connection that can be rebound to a different socket.
+// It sends packets on the current path, which changes when the client migrates.
+type migratingConn struct {
+	current atomic.Pointer[sconn]
+
+	// The addresses advertised in the preferred_address transport parameter.
+	// Nil if no addresses are advertised.
+	preferredAddress *wire.PreferredAddress
+}
+
+var _ sendConn = &migratingConn{}
+
+func (c *migratingConn) Write(b []byte, gsoSize uint16, ecn protocol.ECN) error {
+	return c.current.Load().Write(b, gsoSize, ecn)
+}
+
+func (c *migratingConn) Close() error                   { return c.current.Load().Close() }
+func (c *migratingConn) LocalAddr() net.Addr            { return c.current.Load().LocalAddr() }
+func (c *migratingConn) RemoteAddr() net.Addr           { return c.current.Load().RemoteAddr() }
+func (c *migratingConn) capabilities() connCapabilities { return c.current.Load().capabilities() }
+
+func (c *migratingConn) disableGSO(reason logging.GSODisabledReason) {
+	c.current.Load().disableGSO(reason)
+}
+
+func (c *migratingConn) socketBufferTuner() *socketBufferTuner {
+	return c.current.Load().socketBufferTuner()
+}
+
+// isCurrentPath says if a packet received on the socket rcvConn from remoteAddr was received on the current path.
+func (c *migratingConn) isCurrentPath(rcvConn rawConn, remoteAddr net.Addr) bool {
+	current := c.current.Load()
+	return current.rawConn == rcvConn && isSameAddr(current.remoteAddr, remoteAddr)
+}
+
+// switchPath switches to a new path, and returns the previous path.
+func (c *migratingConn) switchPath(path *sconn) *sconn {
+	return c.current.Swap(path)
+}
+
+// A pathValidation is the validation of a path that the client migrated to, see section 8.2 of RFC 9000.
+// Clients validate the path when they are rebound to a new socket, see Connection.Rebind.
+type pathValidation struct {
+	challenge [8]byte
+	// The last validated path.
+	previous *sconn
+	// The socket the connection was rebound to. Only set for clients.
+	// It is closed if the path validation fails.
+	rebound rawConn
+	// If the path isn't validated by this time, the connection falls back to the previous path.
+	deadline time.Time
+}
+
+// isSameIP says if two addresses have the same IP, ignoring the port.
+func isSameIP(a, b net.Addr) bool {
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if ok1 && ok2 {
+		return ua.IP.Equal(ub.IP) && ua.Zone == ub.Zone
+	}
+	return isSameAddr(a, b)
+}
+
+func isSameAddr(a, b net.Addr) bool {
+	ua, ok1 := a.(*net.UDPAddr)
+	ub, ok2 := b.(*net.UDPAddr)
+	if ok1 && ok2 {
+		return ua.Port == ub.Port && ua.IP.Equal(ub.IP) && ua.Zone == ub.Zone
+	}
+	return a.String() == b.String()
+}
@@ -0,0 +1,83 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Multihomed Group", func() {
+	It("errors when no addresses are passed", func() {
+		_, err := NewMultihomedGroup(nil, nil)
+		Expect(err).To(MatchError("no addresses"))
+	})
+
+	It("rejects invalid connection ID lengths", func() {
+		_, err := NewMultihomedGroup([]string{"127.0.0.1:0"}, &MultihomedConfig{ConnectionIDLength: 3})
+		Expect(err).To(MatchError("invalid connection ID length: 3"))
+	})
+
+	It("encodes the index of the socket in the connection IDs", func() {
+		g, err := NewMultihomedGroup([]string{"127.0.0.1:0", "127.0.0.1:0", "127.0.0.1:0"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		Expect(g.Addrs()).To(HaveLen(3))
+		for i, t := range g.Transports() {
+			connID, err := t.connIDGenerator.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(Equal(protocol.DefaultConnectionIDLength))
+			Expect(int(connID.Bytes()[0]) % 3).To(Equal(i))
+		}
+	})
+
+	It("advertises the address of a different socket", func() {
+		g, err := NewMultihomedGroup([]string{"127.0.0.1:0", "127.0.0.1:0"}, &MultihomedConfig{AdvertiseAlternateAddresses: true})
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		addrs := g.Addrs()
+		pa := g.preferredAddress(g.transports[0].conn)
+		Expect(pa).ToNot(BeNil())
+		Expect(pa.IPv4.Equal(net.IPv4(127, 0, 0, 1))).To(BeTrue())
+		Expect(int(pa.IPv4Port)).To(Equal(addrs[1].(*net.UDPAddr).Port))
+		Expect(pa.IPv6.Equal(net.IPv6zero)).To(BeTrue())
+		Expect(pa.IPv6Port).To(BeZero())
+		pa = g.preferredAddress(g.transports[1].conn)
+		Expect(int(pa.IPv4Port)).To(Equal(addrs[0].(*net.UDPAddr).Port))
+	})
+
+	It("doesn't advertise unspecified addresses", func() {
+		g, err := NewMultihomedGroup([]string{"127.0.0.1:0", "0.0.0.0:0"}, &MultihomedConfig{AdvertiseAlternateAddresses: true})
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		Expect(g.preferredAddress(g.transports[0].conn)).To(BeNil())
+	})
+
+	It("compares IP addresses", func() {
+		Expect(isSameIP(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1}, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To4(), Port: 2})).To(BeTrue())
+		Expect(isSameIP(&net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1}, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 5), Port: 1})).To(BeFalse())
+		Expect(isSameIP(&net.UDPAddr{IP: net.IPv6loopback, Zone: "eth0"}, &net.UDPAddr{IP: net.IPv6loopback, Zone: "eth1"})).To(BeFalse())
+	})
+
+	It("switches paths", func() {
+		remote1 := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+		remote2 := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321}
+		c1 := NewMockRawConn(mockCtrl)
+		c1.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}).AnyTimes()
+		c2 := NewMockRawConn(mockCtrl)
+		c2.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2}).AnyTimes()
+		g := &MultihomedGroup{}
+		mc := g.newSendConn(newSendConn(c1, remote1, packetInfo{}, nil))
+		Expect(mc.isCurrentPath(c1, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4).To4(), Port: 1234})).To(BeTrue())
+		Expect(mc.isCurrentPath(c1, remote2)).To(BeFalse())
+		Expect(mc.isCurrentPath(c2, remote1)).To(BeFalse())
+
+		prev := mc.switchPath(newSendConn(c2, remote2, packetInfo{}, nil))
+		Expect(prev.RemoteAddr()).To(Equal(remote1))
+		Expect(mc.isCurrentPath(c2, remote2)).To(BeTrue())
+		Expect(mc.RemoteAddr()).To(Equal(remote2))
+		Expect(mc.LocalAddr().(*net.UDPAddr).Port).To(Equal(2))
+	})
+})
@@ -0,0 +1,270 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+const (
+	// defaultPunchInterval is the interval at which hole punching packets are sent,
+	// if PeerDialConfig.PunchInterval is not set.
+	defaultPunchInterval = 100 * time.Millisecond
+	// defaultPeerKeepAlivePeriod is used if the Config passed to DialPeer doesn't set a KeepAlivePeriod.
+	// NATs typically time out UDP mappings after 30 seconds.
+	defaultPeerKeepAlivePeriod = 15 * time.Second
+)
+
+// The punch packet is sent to open a mapping on NATs and firewalls on the path to the peer.
+// It's not a valid QUIC packet, and is dropped by the peer's Transport (or returned from ReadNonQUICPacket).
+var punchPacket = []byte{0}
+
+// A PeerDialConfig configures a connection attempt to a peer, see Transport.DialPeer.
+type PeerDialConfig struct {
+	// ConnectionID is the Destination Connection ID used in the Initial packets sent to the peer.
+	// It has to be at least 8 bytes long.
+	// If not set, a random connection ID is used.
+	ConnectionID ConnectionID
+	// RemoteConnectionID is the Destination Connection ID used in the peer's Initial packets.
+	// If set, only Initial packets using this connection ID are considered to be the peer's connection attempt.
+	// Other Initial packets received from the peer's address are passed to the Listener.
+	// It must be different from the ConnectionID.
+	RemoteConnectionID ConnectionID
+	// PunchInterval is the interval at which packets are sent to the peer to open a mapping on NATs on the path,
+	// until the handshake completes.
+	// If zero, 100ms is used. If negative, no hole punching packets are sent.
+	PunchInterval time.Duration
+}
+
+type peerDial struct {
+	// the Destination Connection ID used in our Initial packets
+	connID protocol.ConnectionID
+	// the Destination Connection ID used in the peer's Initial packets, if known in advance
+	remoteConnID protocol.ConnectionID
+	// the Destination Connection ID of the peer's Initial packets that lost the role resolution
+	droppedConnID protocol.ConnectionID
+
+	// isServer is set when the peer's connection attempt won the role resolution.
+	// Our client connection is then canceled, and the connection accepted from the peer is sent on serverConn.
+	isServer bool
+	claimed  bool
+	// established is set once DialPeer returned a connection.
+	// The roles are not changed any more after that.
+	established bool
+	cancelDial  context.CancelFunc
+	serverConn  chan quicConn
+}
+
+// DialPeer establishes a connection to a peer that might be dialing this Transport at the same time,
+// as is common for peer-to-peer connections through NATs.
+// The Transport needs to be listening (see Listen and ListenEarly), since the peer's connection attempt
+// might win, in which case this Transport acts as the server of the connection.
+// The connection attempt that uses the lower Destination Connection ID on its Initial packets wins.
+// If the peer's attempt wins, the connection is returned from DialPeer, not from the Listener's Accept,
+// and it uses the tls.Config and Config of the Listener.
+//
+// Until the handshake completes, packets are sent to the peer at regular intervals to open a mapping on NATs on the path.
+// If the Config doesn't set a KeepAlivePeriod, a KeepAlivePeriod of 15 seconds is used to keep that mapping alive.
+// Both peers are expected to call DialPeer at roughly the same time,
+// usually after exchanging their addresses (and optionally connection IDs) via a signaling channel.
+func (t *Transport) DialPeer(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config, peerConf *PeerDialConfig) (Connection, error) {
+	if peerConf == nil {
+		peerConf = &PeerDialConfig{}
+	}
+	connID := peerConf.ConnectionID
+	if connID.Len() == 0 {
+		var err error
+		connID, err = generateConnectionIDForInitial()
+		if err != nil {
+			return nil, err
+		}
+	} else if connID.Len() < protocol.MinConnectionIDLenInitial {
+		return nil, errors.New("quic: connection ID too short")
+	}
+	if connID == peerConf.RemoteConnectionID {
+		return nil, errors.New("quic: connection ID and remote connection ID must be different")
+	}
+	if conf == nil {
+		conf = &Config{}
+	}
+	if conf.KeepAlivePeriod == 0 {
+		conf = conf.Clone()
+		conf.KeepAlivePeriod = defaultPeerKeepAlivePeriod
+	}
+
+	dialCtx, cancelDial := context.WithCancel(ctx)
+	defer cancelDial()
+	pd := &peerDial{
+		connID:       connID,
+		remoteConnID: peerConf.RemoteConnectionID,
+		cancelDial:   cancelDial,
+		serverConn:   make(chan quicConn, 1),
+	}
+	key := addr.String()
+	t.mutex.Lock()
+	if t.server == nil {
+		t.mutex.Unlock()
+		return nil, errors.New("quic: DialPeer requires a listening Transport")
+	}
+	if _, ok := t.peerDials[key]; ok {
+		t.mutex.Unlock()
+		return nil, errors.New("quic: already dialing this peer")
+	}
+	if t.peerDials == nil {
+		t.peerDials = make(map[string]*peerDial)
+	}
+	t.peerDials[key] = pd
+	t.mutex.Unlock()
+
+	punchInterval := peerConf.PunchInterval
+	if punchInterval == 0 {
+		punchInterval = defaultPunchInterval
+	}
+	if punchInterval > 0 {
+		go t.punch(dialCtx, addr, punchInterval)
+	}
+
+	conn, err := t.dialPeer(ctx, dialCtx, addr, tlsConf, conf, pd)
+	if err != nil {
+		t.removePeerDial(key, pd)
+		return nil, err
+	}
+	// Keep the peerDial around for the lifetime of the connection,
+	// so that retransmissions of the peer's Initial packets that lost the role resolution are dropped.
+	go func() {
+		<-conn.Context().Done()
+		t.removePeerDial(key, pd)
+	}()
+	return conn, nil
+}
+
+func (t *Transport) dialPeer(ctx, dialCtx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config, pd *peerDial) (Connection, error) {
+	type dialResult struct {
+		conn Connection
+		err  error
+	}
+	clientResult := make(chan dialResult, 1)
+	go func() {
+		conn, err := t.dial(dialCtx, addr, "", tlsConf, conf, false, pd.connID, packetInfo{})
+		clientResult <- dialResult{conn: conn, err: err}
+	}()
+
+	var conn quicConn
+	select {
+	case r := <-clientResult:
+		t.mutex.Lock()
+		isServer := pd.isServer
+		if r.err == nil {
+			pd.established = true
+		}
+		t.mutex.Unlock()
+		if r.err == nil {
+			return r.conn, nil
+		}
+		if !isServer {
+			return nil, r.err
+		}
+		select {
+		case conn = <-pd.serverConn:
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-t.listening:
+			return nil, errors.New("quic: transport closed")
+		}
+	case conn = <-pd.serverConn:
+		<-clientResult // wait for the client connection to be shut down
+	}
+
+	select {
+	case <-conn.HandshakeComplete():
+		return conn, nil
+	case <-conn.Context().Done():
+		return nil, context.Cause(conn.Context())
+	case <-ctx.Done():
+		conn.shutdown()
+		return nil, context.Cause(ctx)
+	}
+}
+
+func (t *Transport) punch(ctx context.Context, addr net.Addr, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := t.conn.WritePacket(punchPacket, addr, nil, 0, protocol.ECNUnsupported); err != nil {
+			t.logger.Debugf("Sending hole punching packet to %s failed: %s", addr, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Transport) removePeerDial(key string, pd *peerDial) {
+	t.mutex.Lock()
+	if t.peerDials[key] == pd {
+		delete(t.peerDials, key)
+	}
+	t.mutex.Unlock()
+}
+
+// maybeDropPeerInitial resolves the roles when the Initial packets of two peers dialing each other cross.
+// It returns true if the packet belongs to the peer's connection attempt that lost, and needs to be dropped.
+// It must be called with the mutex locked.
+func (t *Transport) maybeDropPeerInitial(p receivedPacket) bool {
+	pd, ok := t.peerDials[p.remoteAddr.String()]
+	if !ok || pd.isServer {
+		return false
+	}
+	hdr, _, _, err := wire.ParsePacket(p.data)
+	if err != nil || hdr.Type != protocol.PacketTypeInitial {
+		return false
+	}
+	if pd.remoteConnID.Len() > 0 && hdr.DestConnectionID != pd.remoteConnID {
+		return false
+	}
+	if hdr.DestConnectionID == pd.droppedConnID {
+		p.buffer.MaybeRelease()
+		return true
+	}
+	if pd.established {
+		return false
+	}
+	if bytes.Compare(hdr.DestConnectionID.Bytes(), pd.connID.Bytes()) < 0 {
+		// The peer's connection attempt wins. Cancel our connection attempt,
+		// and hand the packet to the server.
+		t.logger.Debugf("Connection attempt from peer %s wins, acting as the server", p.remoteAddr)
+		pd.isServer = true
+		pd.cancelDial()
+		return false
+	}
+	t.logger.Debugf("Connection attempt to peer %s wins, dropping Initial packet with connection ID %s", p.remoteAddr, hdr.DestConnectionID)
+	pd.droppedConnID = hdr.DestConnectionID
+	p.buffer.MaybeRelease()
+	return true
+}
+
+// claimPeerConn is called by the server for every new connection.
+// It claims connections that were accepted from a peer that won the role resolution in DialPeer.
+func (t *Transport) claimPeerConn(conn quicConn) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.peerDials) == 0 {
+		return false
+	}
+	pd, ok := t.peerDials[conn.RemoteAddr().String()]
+	if !ok || !pd.isServer || pd.claimed || pd.established {
+		return false
+	}
+	pd.claimed = true
+	pd.serverConn <- conn
+	return true
+}
@@ -0,0 +1,223 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// A gatedConn drops all packets sent before the gate is opened.
+type gatedConn struct {
+	net.PacketConn
+	open chan struct{}
+}
+
+func (c *gatedConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.open:
+		return c.PacketConn.WriteTo(b, addr)
+	default:
+		return len(b), nil
+	}
+}
+
+var _ = Describe("Peer-to-peer connections", func() {
+	const alpn = "p2p"
+
+	var tr1, tr2 *Transport
+	var ln1, ln2 *Listener
+	// Packets sent by tr1 and tr2 are dropped until the gate is opened.
+	var gate chan struct{}
+
+	getClientTLSConfig := func() *tls.Config {
+		return &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{alpn}}
+	}
+
+	newTransport := func() (*Transport, *Listener) {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &Transport{Conn: &gatedConn{PacketConn: conn, open: gate}}
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{alpn}
+		ln, err := tr.Listen(tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return tr, ln
+	}
+
+	BeforeEach(func() {
+		gate = make(chan struct{})
+		tr1, ln1 = newTransport()
+		tr2, ln2 = newTransport()
+	})
+
+	AfterEach(func() {
+		ln1.Close()
+		ln2.Close()
+		tr1.Close()
+		tr2.Close()
+	})
+
+	type dialResult struct {
+		conn Connection
+		err  error
+	}
+
+	dialPeer := func(tr *Transport, addr net.Addr, peerConf *PeerDialConfig) <-chan dialResult {
+		c := make(chan dialResult, 1)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := tr.DialPeer(ctx, addr, getClientTLSConfig(), nil, peerConf)
+			c <- dialResult{conn: conn, err: err}
+		}()
+		return c
+	}
+
+	checkEcho := func(client, server Connection) {
+		str, err := client.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sstr, err := server.AcceptStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(sstr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	}
+
+	// makes sure that both transports registered the connection attempt before packets are exchanged
+	dialPeersGated := func(peerConf1, peerConf2 *PeerDialConfig) (<-chan dialResult, <-chan dialResult) {
+		numPeerDials := func(tr *Transport) int {
+			tr.mutex.Lock()
+			defer tr.mutex.Unlock()
+			return len(tr.peerDials)
+		}
+		c1 := dialPeer(tr1, tr2.Conn.LocalAddr(), peerConf1)
+		c2 := dialPeer(tr2, tr1.Conn.LocalAddr(), peerConf2)
+		Eventually(func() int { return numPeerDials(tr1) }).Should(Equal(1))
+		Eventually(func() int { return numPeerDials(tr2) }).Should(Equal(1))
+		close(gate)
+		return c1, c2
+	}
+
+	It("resolves the roles when both peers dial at the same time", func() {
+		lowConnID := protocol.ParseConnectionID([]byte{1, 1, 1, 1, 1, 1, 1, 1})
+		highConnID := protocol.ParseConnectionID([]byte{2, 2, 2, 2, 2, 2, 2, 2})
+		c1, c2 := dialPeersGated(
+			&PeerDialConfig{ConnectionID: highConnID, RemoteConnectionID: lowConnID},
+			&PeerDialConfig{ConnectionID: lowConnID, RemoteConnectionID: highConnID},
+		)
+		var r1, r2 dialResult
+		Eventually(c1, 5*time.Second).Should(Receive(&r1))
+		Eventually(c2, 5*time.Second).Should(Receive(&r2))
+		Expect(r1.err).ToNot(HaveOccurred())
+		Expect(r2.err).ToNot(HaveOccurred())
+		defer r1.conn.CloseWithError(0, "")
+		defer r2.conn.CloseWithError(0, "")
+
+		// The connection attempt using the lower connection ID wins.
+		Expect(r1.conn.(*connection).perspective).To(Equal(protocol.PerspectiveServer))
+		Expect(r2.conn.(*connection).perspective).To(Equal(protocol.PerspectiveClient))
+		checkEcho(r1.conn, r2.conn)
+		checkEcho(r2.conn, r1.conn)
+
+		// The connection is not returned from the Listener.
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := ln1.Accept(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("resolves the roles when using random connection IDs", func() {
+		c1, c2 := dialPeersGated(nil, nil)
+		var r1, r2 dialResult
+		Eventually(c1, 5*time.Second).Should(Receive(&r1))
+		Eventually(c2, 5*time.Second).Should(Receive(&r2))
+		Expect(r1.err).ToNot(HaveOccurred())
+		Expect(r2.err).ToNot(HaveOccurred())
+		defer r1.conn.CloseWithError(0, "")
+		defer r2.conn.CloseWithError(0, "")
+
+		Expect(r1.conn.(*connection).perspective).ToNot(Equal(r2.conn.(*connection).perspective))
+		checkEcho(r1.conn, r2.conn)
+	})
+
+	It("connects to a peer that doesn't dial", func() {
+		close(gate)
+		accepted := make(chan Connection, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln2.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}()
+
+		var r dialResult
+		Eventually(dialPeer(tr1, tr2.Conn.LocalAddr(), nil), 5*time.Second).Should(Receive(&r))
+		Expect(r.err).ToNot(HaveOccurred())
+		defer r.conn.CloseWithError(0, "")
+		var sconn Connection
+		Eventually(accepted).Should(Receive(&sconn))
+		checkEcho(r.conn, sconn)
+	})
+
+	It("sends hole punching packets", func() {
+		close(gate)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// nobody is listening on this address
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		go tr1.DialPeer(ctx, conn.LocalAddr(), getClientTLSConfig(), nil, &PeerDialConfig{PunchInterval: 10 * time.Millisecond})
+
+		var numPunched int
+		b := make([]byte, protocol.MaxPacketBufferSize)
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		for numPunched < 3 {
+			n, _, err := conn.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			if n == 1 {
+				Expect(b[:n]).To(Equal(punchPacket))
+				numPunched++
+			}
+		}
+	})
+
+	It("errors when the connection IDs are invalid", func() {
+		ctx := context.Background()
+		_, err := tr1.DialPeer(ctx, tr2.Conn.LocalAddr(), getClientTLSConfig(), nil, &PeerDialConfig{
+			ConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		})
+		Expect(err).To(MatchError("quic: connection ID too short"))
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		_, err = tr1.DialPeer(ctx, tr2.Conn.LocalAddr(), getClientTLSConfig(), nil, &PeerDialConfig{
+			ConnectionID:       connID,
+			RemoteConnectionID: connID,
+		})
+		Expect(err).To(MatchError("quic: connection ID and remote connection ID must be different"))
+	})
+
+	It("errors when the transport is not listening", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &Transport{Conn: conn}
+		defer tr.Close()
+		_, err = tr.DialPeer(context.Background(), tr2.Conn.LocalAddr(), getClientTLSConfig(), nil, nil)
+		Expect(err).To(MatchError("quic: DialPeer requires a listening Transport"))
+	})
+})
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"hash"
+	"hash/maphash"
 	"io"
 	"net"
 	"sync"
@@ -22,6 +23,11 @@ type connCapabilities struct {
 	GSO bool
 	// ECN (Explicit Congestion Notifications) supported
 	ECN bool
+	// Setting the source address and the outgoing interface of a packet is supported
+	PacketInfo bool
+	// The maximum size of a packet that can be sent.
+	// 0 if unknown, in which case it is derived from the remote address.
+	MaxPacketSize protocol.ByteCount
 }
 
 // rawConn is a connection that allow reading of a receivedPackeh.
@@ -44,9 +50,27 @@ type closePacket struct {
 	info    packetInfo
 }
 
+// numPacketHandlerMapShards is the number of shards of the packetHandlerMap.
+// It must be a power of 2.
+const numPacketHandlerMapShards = 64
+
+// A packetHandlerMapShard holds the handlers for a subset of the connection IDs.
+type packetHandlerMapShard struct {
+	mutex    sync.Mutex
+	handlers map[protocol.ConnectionID]packetHandler
+
+	// pad the shard to a full cache line, to avoid false sharing between neighboring shards
+	_ [64 - 8 - 8]byte
+}
+
 type packetHandlerMap struct {
-	mutex       sync.Mutex
-	handlers    map[protocol.ConnectionID]packetHandler
+	// The handlers are sharded by the hash of the connection ID.
+	// This avoids lock contention when routing packets to a large number of connections.
+	shards    []packetHandlerMapShard
+	shardMask uint64
+	shardSeed maphash.Seed
+
+	mutex       sync.Mutex // protects resetTokens and closed
 	resetTokens map[protocol.StatelessResetToken] /* stateless reset token */ packetHandler
 
 	closed    bool
@@ -67,12 +91,13 @@ var _ packetHandlerManager = &packetHandlerMap{}
 func newPacketHandlerMap(key *StatelessResetKey, enqueueClosePacket func(closePacket), logger utils.Logger) *packetHandlerMap {
 	h := &packetHandlerMap{
 		closeChan:               make(chan struct{}),
-		handlers:                make(map[protocol.ConnectionID]packetHandler),
+		shardSeed:               maphash.MakeSeed(),
 		resetTokens:             make(map[protocol.StatelessResetToken]packetHandler),
 		deleteRetiredConnsAfter: protocol.RetiredConnectionIDDeleteTimeout,
 		enqueueClosePacket:      enqueueClosePacket,
 		logger:                  logger,
 	}
+	h.initShards(numPacketHandlerMapShards)
 	if key != nil {
 		h.statelessResetHasher = hmac.New(sha256.New, key[:])
 	}
@@ -82,6 +107,52 @@ func newPacketHandlerMap(key *StatelessResetKey, enqueueClosePacket func(closePa
 	return h
 }
 
+func (h *packetHandlerMap) initShards(num int) {
+	if num&(num-1) != 0 {
+		panic("number of shards must be a power of 2")
+	}
+	h.shards = make([]packetHandlerMapShard, num)
+	for i := range h.shards {
+		h.shards[i].handlers = make(map[protocol.ConnectionID]packetHandler)
+	}
+	h.shardMask = uint64(num - 1)
+}
+
+func (h *packetHandlerMap) shardIndex(id protocol.ConnectionID) uint64 {
+	return maphash.Bytes(h.shardSeed, id.Bytes()) & h.shardMask
+}
+
+func (h *packetHandlerMap) shard(id protocol.ConnectionID) *packetHandlerMapShard {
+	return &h.shards[h.shardIndex(id)]
+}
+
+// numHandlers returns the number of connection IDs tracked.
+func (h *packetHandlerMap) numHandlers() int {
+	var n int
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.mutex.Lock()
+		n += len(s.handlers)
+		s.mutex.Unlock()
+	}
+	return n
+}
+
+// allHandlers returns all handlers.
+// A handler is returned multiple times if it is tracked under multiple connection IDs.
+func (h *packetHandlerMap) allHandlers() []packetHandler {
+	var handlers []packetHandler
+	for i := range h.shards {
+		s := &h.shards[i]
+		s.mutex.Lock()
+		for _, handler := range s.handlers {
+			handlers = append(handlers, handler)
+		}
+		s.mutex.Unlock()
+	}
+	return handlers
+}
+
 func (h *packetHandlerMap) logUsage() {
 	ticker := time.NewTicker(2 * time.Second)
 	var printedZero bool
@@ -92,8 +163,8 @@ func (h *packetHandlerMap) logUsage() {
 		case <-ticker.C:
 		}
 
+		numHandlers := h.numHandlers()
 		h.mutex.Lock()
-		numHandlers := len(h.handlers)
 		numTokens := len(h.resetTokens)
 		h.mutex.Unlock()
 		// If the number tracked handlers and tokens is zero, only print it a single time.
@@ -109,31 +180,45 @@ func (h *packetHandlerMap) logUsage() {
 }
 
 func (h *packetHandlerMap) Get(id protocol.ConnectionID) (packetHandler, bool) {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+	s := h.shard(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	handler, ok := h.handlers[id]
+	handler, ok := s.handlers[id]
 	return handler, ok
 }
 
 func (h *packetHandlerMap) Add(id protocol.ConnectionID, handler packetHandler) bool /* was added */ {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+	s := h.shard(id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	if _, ok := h.handlers[id]; ok {
+	if _, ok := s.handlers[id]; ok {
 		h.logger.Debugf("Not adding connection ID %s, as it already exists.", id)
 		return false
 	}
-	h.handlers[id] = handler
+	s.handlers[id] = handler
 	h.logger.Debugf("Adding connection ID %s.", id)
 	return true
 }
 
 func (h *packetHandlerMap) AddWithConnID(clientDestConnID, newConnID protocol.ConnectionID, fn func() (packetHandler, bool)) bool {
-	h.mutex.Lock()
-	defer h.mutex.Unlock()
+	// Both connection IDs need to be added atomically.
+	// Lock the shards in a consistent order, to avoid deadlocks.
+	i1 := h.shardIndex(clientDestConnID)
+	i2 := h.shardIndex(newConnID)
+	s1, s2 := &h.shards[i1], &h.shards[i2]
+	if i1 > i2 {
+		i1, i2 = i2, i1
+	}
+	h.shards[i1].mutex.Lock()
+	defer h.shards[i1].mutex.Unlock()
+	if i2 != i1 {
+		h.shards[i2].mutex.Lock()
+		defer h.shards[i2].mutex.Unlock()
+	}
 
-	if _, ok := h.handlers[clientDestConnID]; ok {
+	if _, ok := s1.handlers[clientDestConnID]; ok {
 		h.logger.Debugf("Not adding connection ID %s for a new connection, as it already exists.", clientDestConnID)
 		return false
 	}
@@ -141,25 +226,28 @@ func (h *packetHandlerMap) AddWithConnID(clientDestConnID, newConnID protocol.Co
 	if !ok {
 		return false
 	}
-	h.handlers[clientDestConnID] = conn
-	h.handlers[newConnID] = conn
+	s1.handlers[clientDestConnID] = conn
+	s2.handlers[newConnID] = conn
 	h.logger.Debugf("Adding connection IDs %s and %s for a new connection.", clientDestConnID, newConnID)
 	return true
 }
 
 func (h *packetHandlerMap) Remove(id protocol.ConnectionID) {
-	h.mutex.Lock()
-	delete(h.handlers, id)
-	h.mutex.Unlock()
+	h.remove(id)
 	h.logger.Debugf("Removing connection ID %s.", id)
 }
 
+func (h *packetHandlerMap) remove(id protocol.ConnectionID) {
+	s := h.shard(id)
+	s.mutex.Lock()
+	delete(s.handlers, id)
+	s.mutex.Unlock()
+}
+
 func (h *packetHandlerMap) Retire(id protocol.ConnectionID) {
 	h.logger.Debugf("Retiring connection ID %s in %s.", id, h.deleteRetiredConnsAfter)
 	time.AfterFunc(h.deleteRetiredConnsAfter, func() {
-		h.mutex.Lock()
-		delete(h.handlers, id)
-		h.mutex.Unlock()
+		h.remove(id)
 		h.logger.Debugf("Removing connection ID %s after it has been retired.", id)
 	})
 }
@@ -182,20 +270,19 @@ func (h *packetHandlerMap) ReplaceWithClosed(ids []protocol.ConnectionID, pers p
 		handler = newClosedRemoteConn(pers)
 	}
 
-	h.mutex.Lock()
 	for _, id := range ids {
-		h.handlers[id] = handler
+		s := h.shard(id)
+		s.mutex.Lock()
+		s.handlers[id] = handler
+		s.mutex.Unlock()
 	}
-	h.mutex.Unlock()
 	h.logger.Debugf("Replacing connection for connection IDs %s with a closed connection.", ids)
 
 	time.AfterFunc(h.deleteRetiredConnsAfter, func() {
-		h.mutex.Lock()
 		handler.shutdown()
 		for _, id := range ids {
-			delete(h.handlers, id)
+			h.remove(id)
 		}
-		h.mutex.Unlock()
 		h.logger.Debugf("Removing connection IDs %s for a closed connection after it has been retired.", ids)
 	})
 }
@@ -221,9 +308,8 @@ func (h *packetHandlerMap) GetByResetToken(token protocol.StatelessResetToken) (
 }
 
 func (h *packetHandlerMap) CloseServer() {
-	h.mutex.Lock()
 	var wg sync.WaitGroup
-	for _, handler := range h.handlers {
+	for _, handler := range h.allHandlers() {
 		if handler.getPerspective() == protocol.PerspectiveServer {
 			wg.Add(1)
 			go func(handler packetHandler) {
@@ -233,7 +319,6 @@ func (h *packetHandlerMap) CloseServer() {
 			}(handler)
 		}
 	}
-	h.mutex.Unlock()
 	wg.Wait()
 }
 
@@ -246,17 +331,17 @@ func (h *packetHandlerMap) Close(e error) {
 	}
 
 	close(h.closeChan)
+	h.closed = true
+	h.mutex.Unlock()
 
 	var wg sync.WaitGroup
-	for _, handler := range h.handlers {
+	for _, handler := range h.allHandlers() {
 		wg.Add(1)
 		go func(handler packetHandler) {
 			handler.destroy(e)
 			wg.Done()
 		}(handler)
 	}
-	h.closed = true
-	h.mutex.Unlock()
 	wg.Wait()
 }
 
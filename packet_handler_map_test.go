@@ -3,7 +3,9 @@ package quic
 import (
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"net"
+	"testing"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -73,6 +75,35 @@ var _ = Describe("Packet Handler Map", func() {
 		})).To(BeFalse())
 	})
 
+	It("adds newly to-be-constructed handlers, if both connection IDs map to the same shard", func() {
+		m := newPacketHandlerMap(nil, nil, utils.DefaultLogger)
+		m.initShards(1)
+		connID1 := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		connID2 := protocol.ParseConnectionID([]byte{4, 3, 2, 1})
+		handler := NewMockPacketHandler(mockCtrl)
+		Expect(m.AddWithConnID(connID1, connID2, func() (packetHandler, bool) { return handler, true })).To(BeTrue())
+		h, ok := m.Get(connID1)
+		Expect(ok).To(BeTrue())
+		Expect(h).To(Equal(handler))
+		h, ok = m.Get(connID2)
+		Expect(ok).To(BeTrue())
+		Expect(h).To(Equal(handler))
+	})
+
+	It("distributes connection IDs across shards", func() {
+		m := newPacketHandlerMap(nil, nil, utils.DefaultLogger)
+		const num = 100 * numPacketHandlerMapShards
+		for i := 0; i < num; i++ {
+			b := make([]byte, 8)
+			rand.Read(b)
+			Expect(m.Add(protocol.ParseConnectionID(b), NewMockPacketHandler(mockCtrl))).To(BeTrue())
+		}
+		Expect(m.numHandlers()).To(Equal(num))
+		for i := range m.shards {
+			Expect(m.shards[i].handlers).ToNot(BeEmpty())
+		}
+	})
+
 	It("adds, gets and removes reset tokens", func() {
 		m := newPacketHandlerMap(nil, nil, utils.DefaultLogger)
 		token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 0, 0xa, 0xb, 0xc, 0xd, 0xe, 0xf}
@@ -191,3 +222,32 @@ var _ = Describe("Packet Handler Map", func() {
 		m.Close(errors.New("close"))
 	})
 })
+
+func BenchmarkPacketHandlerMapGet(b *testing.B) {
+	const numConns = 10000
+	for _, numShards := range []int{1, numPacketHandlerMapShards} {
+		b.Run(fmt.Sprintf("%d shards", numShards), func(b *testing.B) {
+			m := newPacketHandlerMap(nil, nil, utils.DefaultLogger)
+			m.initShards(numShards)
+			connIDs := make([]protocol.ConnectionID, numConns)
+			for i := range connIDs {
+				b := make([]byte, 8)
+				rand.Read(b)
+				connIDs[i] = protocol.ParseConnectionID(b)
+				m.Add(connIDs[i], &closedRemoteConn{})
+			}
+
+			b.ResetTimer()
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					if _, ok := m.Get(connIDs[i%numConns]); !ok {
+						b.Fatal("connection ID not found")
+					}
+					i++
+				}
+			})
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -27,6 +28,7 @@ type packer interface {
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount, v protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)
 
 	SetToken([]byte)
+	EnableImmediateAck()
 }
 
 type sealer interface {
@@ -134,6 +136,10 @@ type packetPacker struct {
 	rand                rand.Rand
 
 	numNonAckElicitingAcks int
+	// immediateAck is set if the peer accepts IMMEDIATE_ACK frames.
+	immediateAck bool
+
+	paddingPolicy PaddingPolicy
 }
 
 var _ packer = &packetPacker{}
@@ -149,6 +155,7 @@ func newPacketPacker(
 	acks ackFrameSource,
 	datagramQueue *datagramQueue,
 	perspective protocol.Perspective,
+	paddingPolicy PaddingPolicy,
 ) *packetPacker {
 	var b [8]byte
 	_, _ = crand.Read(b[:])
@@ -166,6 +173,7 @@ func newPacketPacker(
 		acks:                acks,
 		rand:                *rand.New(rand.NewSource(binary.BigEndian.Uint64(b[:]))),
 		pnManager:           packetNumberManager,
+		paddingPolicy:       paddingPolicy,
 	}
 }
 
@@ -325,6 +333,19 @@ func (p *packetPacker) initialPaddingLen(frames []ackhandler.Frame, currentSize,
 	return maxPacketSize - currentSize
 }
 
+// policyPaddingLen returns the padding required by the padding policy for a datagram containing a 1-RTT packet.
+// size is the expected size of the datagram, if no padding was applied.
+func (p *packetPacker) policyPaddingLen(size, maxPacketSize protocol.ByteCount) protocol.ByteCount {
+	if p.paddingPolicy == nil || size >= maxPacketSize {
+		return 0
+	}
+	paddedSize := protocol.ByteCount(p.paddingPolicy.PaddedSize(int(size), int(maxPacketSize)))
+	if paddedSize <= size {
+		return 0
+	}
+	return utils.Min(paddedSize, maxPacketSize) - size
+}
+
 // PackCoalescedPacket packs a new packet.
 // It packs an Initial / Handshake if there is data to send in these packet number spaces.
 // It should only be called before the handshake is confirmed.
@@ -431,7 +452,12 @@ func (p *packetPacker) PackCoalescedPacket(onlyAck bool, maxPacketSize protocol.
 		}
 		packet.longHdrPackets = append(packet.longHdrPackets, longHdrPacket)
 	} else if oneRTTPayload.length > 0 {
-		shp, err := p.appendShortHeaderPacket(buffer, connID, oneRTTPacketNumber, oneRTTPacketNumberLen, kp, oneRTTPayload, 0, maxPacketSize, oneRTTSealer, false, v)
+		var padding protocol.ByteCount
+		// Datagrams containing an Initial packet were already padded.
+		if initialPayload.length == 0 {
+			padding = p.policyPaddingLen(size, maxPacketSize)
+		}
+		shp, err := p.appendShortHeaderPacket(buffer, connID, oneRTTPacketNumber, oneRTTPacketNumberLen, kp, oneRTTPayload, padding, maxPacketSize, oneRTTSealer, false, v)
 		if err != nil {
 			return nil, err
 		}
@@ -467,8 +493,9 @@ func (p *packetPacker) appendPacket(buf *packetBuffer, onlyAck bool, maxPacketSi
 		return shortHeaderPacket{}, errNothingToPack
 	}
 	kp := sealer.KeyPhase()
+	padding := p.policyPaddingLen(p.shortHeaderPacketLength(connID, pnLen, pl)+protocol.ByteCount(sealer.Overhead()), maxPacketSize)
 
-	return p.appendShortHeaderPacket(buf, connID, pn, pnLen, kp, pl, 0, maxPacketSize, sealer, false, v)
+	return p.appendShortHeaderPacket(buf, connID, pn, pnLen, kp, pl, padding, maxPacketSize, sealer, false, v)
 }
 
 func (p *packetPacker) maybeGetCryptoPacket(maxPacketSize protocol.ByteCount, encLevel protocol.EncryptionLevel, onlyAck, ackAllowed bool, v protocol.VersionNumber) (*wire.ExtendedHeader, payload) {
@@ -560,6 +587,16 @@ func (p *packetPacker) maybeGetShortHeaderPacket(sealer handshake.ShortHeaderSea
 
 func (p *packetPacker) maybeGetAppDataPacket(maxPayloadSize protocol.ByteCount, onlyAck, ackAllowed bool, v protocol.VersionNumber) payload {
 	pl := p.composeNextPacket(maxPayloadSize, onlyAck, ackAllowed, v)
+	// Only packets containing frames hold on to the pooled slices.
+	// They are returned to the pool by the sent packet handler.
+	if len(pl.frames) == 0 {
+		ackhandler.PutFrames(pl.frames)
+		pl.frames = nil
+	}
+	if len(pl.streamFrames) == 0 {
+		ackhandler.PutStreamFrames(pl.streamFrames)
+		pl.streamFrames = nil
+	}
 
 	// check if we have anything to send
 	if len(pl.frames) == 0 && len(pl.streamFrames) == 0 {
@@ -589,7 +626,10 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 		return payload{}
 	}
 
-	pl := payload{streamFrames: make([]ackhandler.StreamFrame, 0, 1)}
+	pl := payload{
+		frames:       ackhandler.GetFrames(),
+		streamFrames: ackhandler.GetStreamFrames(),
+	}
 
 	hasData := p.framer.HasData()
 	hasRetransmission := p.retransmissionQueue.HasAppData()
@@ -638,17 +678,35 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 		startLen := len(pl.frames)
 		pl.frames, lengthAdded = p.framer.AppendControlFrames(pl.frames, maxFrameSize-pl.length, v)
 		pl.length += lengthAdded
-		// add handlers for the control frames that were added
+		// add handlers for the control frames that were added (unless they were queued with their own handler)
 		for i := startLen; i < len(pl.frames); i++ {
-			pl.frames[i].Handler = p.retransmissionQueue.AppDataAckHandler()
+			if pl.frames[i].Handler == nil {
+				pl.frames[i].Handler = p.retransmissionQueue.AppDataAckHandler()
+			}
 		}
 
 		pl.streamFrames, lengthAdded = p.framer.AppendStreamFrames(pl.streamFrames, maxFrameSize-pl.length, v)
 		pl.length += lengthAdded
+		// If this packet empties the send queue, it is the last packet of a burst.
+		// Ask the peer to acknowledge it right away, so that a loss at the end of a transfer is detected quickly.
+		if p.immediateAck && len(pl.streamFrames) > 0 && pl.length < maxFrameSize && !p.framer.HasData() {
+			p.appendImmediateAck(&pl, v)
+		}
 	}
 	return pl
 }
 
+func (p *packetPacker) appendImmediateAck(pl *payload, v protocol.VersionNumber) {
+	for _, f := range pl.frames {
+		if _, ok := f.Frame.(*wire.ImmediateAckFrame); ok {
+			return
+		}
+	}
+	f := &wire.ImmediateAckFrame{}
+	pl.frames = append(pl.frames, ackhandler.Frame{Frame: f})
+	pl.length += f.Length(v)
+}
+
 func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel, maxPacketSize protocol.ByteCount, v protocol.VersionNumber) (*coalescedPacket, error) {
 	if encLevel == protocol.Encryption1RTT {
 		s, err := p.cryptoSetup.Get1RTTSealer()
@@ -659,13 +717,24 @@ func (p *packetPacker) MaybePackProbePacket(encLevel protocol.EncryptionLevel, m
 		connID := p.getDestConnID()
 		pn, pnLen := p.pnManager.PeekPacketNumber(protocol.Encryption1RTT)
 		hdrLen := wire.ShortHeaderLen(connID, pnLen)
-		pl := p.maybeGetAppDataPacket(maxPacketSize-protocol.ByteCount(s.Overhead())-hdrLen, false, true, v)
+		maxPayloadSize := maxPacketSize - protocol.ByteCount(s.Overhead()) - hdrLen
+		var immediateAckLen protocol.ByteCount
+		if p.immediateAck {
+			immediateAckLen = (&wire.ImmediateAckFrame{}).Length(v)
+		}
+		pl := p.maybeGetAppDataPacket(maxPayloadSize-immediateAckLen, false, true, v)
 		if pl.length == 0 {
 			return nil, nil
 		}
+		// A probe packet is sent when the PTO expires.
+		// Make sure the peer acknowledges it right away.
+		if p.immediateAck {
+			p.appendImmediateAck(&pl, v)
+		}
+		padding := p.policyPaddingLen(p.shortHeaderPacketLength(connID, pnLen, pl)+protocol.ByteCount(s.Overhead()), maxPacketSize)
 		buffer := getPacketBuffer()
 		packet := &coalescedPacket{buffer: buffer}
-		shp, err := p.appendShortHeaderPacket(buffer, connID, pn, pnLen, kp, pl, 0, maxPacketSize, s, false, v)
+		shp, err := p.appendShortHeaderPacket(buffer, connID, pn, pnLen, kp, pl, padding, maxPacketSize, s, false, v)
 		if err != nil {
 			return nil, err
 		}
@@ -898,3 +967,8 @@ func (p *packetPacker) encryptPacket(raw []byte, sealer sealer, pn protocol.Pack
 func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
+
+// EnableImmediateAck is called when the peer advertised support for IMMEDIATE_ACK frames.
+func (p *packetPacker) EnableImmediateAck() {
+	p.immediateAck = true
+}
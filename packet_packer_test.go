@@ -95,9 +95,9 @@ var _ = Describe("Packet packer", func() {
 		ackFramer = NewMockAckFrameSource(mockCtrl)
 		sealingManager = NewMockSealingManager(mockCtrl)
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
-		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
+		datagramQueue = newDatagramQueue(func() {}, nil, utils.DefaultLogger)
 
-		packer = newPacketPacker(protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), func() protocol.ConnectionID { return connID }, initialStream, handshakeStream, pnManager, retransmissionQueue, sealingManager, framer, ackFramer, datagramQueue, protocol.PerspectiveServer)
+		packer = newPacketPacker(protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), func() protocol.ConnectionID { return connID }, initialStream, handshakeStream, pnManager, retransmissionQueue, sealingManager, framer, ackFramer, datagramQueue, protocol.PerspectiveServer, nil)
 	})
 
 	Context("determining the maximum packet size", func() {
@@ -115,6 +115,17 @@ var _ = Describe("Packet packer", func() {
 			addr := &net.UDPAddr{IP: ip, Port: 1337}
 			Expect(getMaxPacketSize(addr)).To(BeEquivalentTo(protocol.InitialPacketSizeIPv6))
 		})
+
+		It("uses the maximum packet size declared by the conn", func() {
+			Expect(getInitialPacketSize(&net.TCPAddr{}, connCapabilities{MaxPacketSize: 1400})).To(BeEquivalentTo(1400))
+			Expect(getInitialPacketSize(&net.TCPAddr{}, connCapabilities{})).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+		})
+
+		It("starts with a smaller packet size if the conn runs Path MTU Discovery", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(11, 12, 13, 14), Port: 1337}
+			Expect(getInitialPacketSize(addr, connCapabilities{DF: true, MaxPacketSize: 1450})).To(BeEquivalentTo(protocol.InitialPacketSizeIPv4))
+			Expect(getInitialPacketSize(addr, connCapabilities{DF: true, MaxPacketSize: 1250})).To(BeEquivalentTo(1250))
+		})
 	})
 
 	Context("generating a packet header", func() {
@@ -537,6 +548,37 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).NotTo(HaveOccurred())
 				Expect(p).ToNot(BeNil())
 				Expect(p.Ack).To(Equal(ack))
+				// the pooled frame slices are only used for packets that contain frames
+				Expect(p.Frames).To(BeNil())
+				Expect(p.StreamFrames).To(BeNil())
+			})
+
+			It("pads packets according to the padding policy", func() {
+				packer.paddingPolicy = PadToFixedSizes(500)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 42, Smallest: 1}}}
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(ack)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				buffer := getPacketBuffer()
+				p, err := packer.AppendPacket(buffer, maxPacketSize, protocol.Version1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p.Length).To(BeEquivalentTo(500))
+				Expect(buffer.Data).To(HaveLen(500))
+				Expect(p.Ack).To(Equal(ack))
+			})
+
+			It("doesn't pad packets beyond the maximum packet size", func() {
+				packer.paddingPolicy = PaddingFunc(func(size, _ int) int { return size + 10000 })
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 42, Smallest: 1}}})
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p.Length).To(Equal(maxPacketSize))
 			})
 
 			It("packs control frames", func() {
@@ -562,6 +604,32 @@ var _ = Describe("Packet packer", func() {
 				Expect(buffer.Len()).ToNot(BeZero())
 			})
 
+			It("doesn't overwrite the handler of control frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				handler := newPingRequest(nil, nil, nil)
+				expectAppendControlFrames(
+					ackhandler.Frame{Frame: &wire.MaxDataFrame{}},
+					ackhandler.Frame{Frame: &wire.PingFrame{}, Handler: handler},
+				)
+				expectAppendStreamFrames()
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.Frames).To(HaveLen(2))
+				for _, f := range p.Frames {
+					switch f.Frame.(type) {
+					case *wire.PingFrame:
+						Expect(f.Handler).To(Equal(handler))
+					case *wire.MaxDataFrame:
+						Expect(f.Handler).ToNot(BeNil())
+						Expect(f.Handler).ToNot(Equal(handler))
+					}
+				}
+			})
+
 			It("packs DATAGRAM frames", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -674,7 +742,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false)
+				frameParser := wire.NewFrameParser(false, false, false)
 				l, frame, err := frameParser.ParseNext(data[len(data)-r.Len():], protocol.Encryption1RTT, protocol.Version1)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -711,7 +779,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(true)
+				frameParser := wire.NewFrameParser(true, false, false)
 				l, frame, err := frameParser.ParseNext(buffer.Data[len(data)-r.Len():], protocol.Encryption1RTT, protocol.Version1)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -755,6 +823,43 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.StreamFrames[2].Frame.Data).To(Equal([]byte("frame 3")))
 			})
 
+			Context("IMMEDIATE_ACK frames", func() {
+				BeforeEach(func() { packer.EnableImmediateAck() })
+
+				It("requests an immediate ACK for the last packet before the send queue runs empty", func() {
+					f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+					framer.EXPECT().HasData().Return(true)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+					expectAppendControlFrames()
+					expectAppendStreamFrames(ackhandler.StreamFrame{Frame: f})
+					framer.EXPECT().HasData().Return(false)
+					p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.StreamFrames).To(HaveLen(1))
+					Expect(p.Frames).To(HaveLen(1))
+					Expect(p.Frames[0].Frame).To(Equal(&wire.ImmediateAckFrame{}))
+					Expect(p.Frames[0].Handler).To(BeNil())
+				})
+
+				It("doesn't request an immediate ACK if there's more data to send", func() {
+					f := &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}
+					pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+					pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+					sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+					framer.EXPECT().HasData().Return(true).Times(2)
+					ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+					expectAppendControlFrames()
+					expectAppendStreamFrames(ackhandler.StreamFrame{Frame: f})
+					p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.StreamFrames).To(HaveLen(1))
+					Expect(p.Frames).To(BeEmpty())
+				})
+			})
+
 			Context("making ACK packets ack-eliciting", func() {
 				sendMaxNumNonAckElicitingAcks := func() {
 					for i := 0; i < protocol.MaxNonAckElicitingAcks; i++ {
@@ -1196,7 +1301,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false)
+				frameParser := wire.NewFrameParser(false, false, false)
 				l, frame, err := frameParser.ParseNext(data[len(data)-r.Len():], protocol.Encryption1RTT, protocol.Version1)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -1461,6 +1566,33 @@ var _ = Describe("Packet packer", func() {
 				Expect(packet.Length).To(Equal(maxPacketSize))
 			})
 
+			It("requests an immediate ACK for 1-RTT probe packets", func() {
+				packer.EnableImmediateAck()
+				f := &wire.StreamFrame{Data: make([]byte, 2000)}
+				retransmissionQueue.addInitial(f)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				framer.EXPECT().HasData().Return(true)
+				expectAppendControlFrames()
+				framer.EXPECT().AppendStreamFrames(gomock.Any(), gomock.Any(), protocol.Version1).DoAndReturn(func(fs []ackhandler.StreamFrame, maxSize protocol.ByteCount, v protocol.VersionNumber) ([]ackhandler.StreamFrame, protocol.ByteCount) {
+					sf, split := f.MaybeSplitOffFrame(maxSize, v)
+					Expect(split).To(BeTrue())
+					return append(fs, ackhandler.StreamFrame{Frame: sf}), sf.Length(v)
+				})
+
+				p, err := packer.MaybePackProbePacket(protocol.Encryption1RTT, maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p).ToNot(BeNil())
+				packet := p.shortHdrPacket
+				Expect(packet).ToNot(BeNil())
+				Expect(packet.StreamFrames).To(HaveLen(1))
+				Expect(packet.Frames).To(HaveLen(1))
+				Expect(packet.Frames[0].Frame).To(Equal(&wire.ImmediateAckFrame{}))
+				Expect(packet.Length).To(Equal(maxPacketSize))
+			})
+
 			It("returns nil if there's no probe data to send", func() {
 				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"time"
 
@@ -37,12 +38,12 @@ type unpackedPacket struct {
 type packetUnpacker struct {
 	cs handshake.CryptoSetup
 
-	shortHdrConnIDLen int
+	shortHdrConnIDLen shortHeaderConnIDLen
 }
 
 var _ unpacker = &packetUnpacker{}
 
-func newPacketUnpacker(cs handshake.CryptoSetup, shortHdrConnIDLen int) *packetUnpacker {
+func newPacketUnpacker(cs handshake.CryptoSetup, shortHdrConnIDLen shortHeaderConnIDLen) *packetUnpacker {
 	return &packetUnpacker{
 		cs:                cs,
 		shortHdrConnIDLen: shortHdrConnIDLen,
@@ -125,6 +126,31 @@ func (u *packetUnpacker) UnpackShortHeader(rcvTime time.Time, data []byte) (prot
 	return pn, pnLen, kp, decrypted, nil
 }
 
+// UnpackOpenedShortHeader finishes unpacking a short header packet that was opened by a decryption worker.
+func (u *packetUnpacker) UnpackOpenedShortHeader(rcvTime time.Time, p *openedShortHeaderPacket) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+	opener, err := u.cs.Get1RTTOpener()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	concurrentOpener, ok := opener.(handshake.ConcurrentShortHeaderOpener)
+	if !ok {
+		return 0, 0, 0, nil, errors.New("1-RTT opener doesn't support concurrent decryption")
+	}
+	if err := concurrentOpener.FinishOpen(rcvTime, p.pn, p.keyPhase, p.openErr); err != nil {
+		return 0, 0, 0, nil, err
+	}
+	if p.parseErr != nil {
+		return 0, 0, 0, nil, p.parseErr
+	}
+	if len(p.data) == 0 {
+		return 0, 0, 0, nil, &qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: "empty packet",
+		}
+	}
+	return p.pn, p.pnLen, p.kp, p.data, nil
+}
+
 func (u *packetUnpacker) unpackLongHeaderPacket(opener handshake.LongHeaderOpener, hdr *wire.Header, data []byte, v protocol.VersionNumber) (*wire.ExtendedHeader, []byte, error) {
 	extHdr, parseErr := u.unpackLongHeader(opener, hdr, data, v)
 	// If the reserved bits are set incorrectly, we still need to continue unpacking.
@@ -146,7 +172,11 @@ func (u *packetUnpacker) unpackLongHeaderPacket(opener handshake.LongHeaderOpene
 }
 
 func (u *packetUnpacker) unpackShortHeaderPacket(opener handshake.ShortHeaderOpener, rcvTime time.Time, data []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
-	l, pn, pnLen, kp, parseErr := u.unpackShortHeader(opener, data)
+	connIDLen, err := u.shortHdrConnIDLen(data)
+	if err != nil {
+		return 0, 0, 0, nil, &headerParseError{err}
+	}
+	l, pn, pnLen, kp, parseErr := unpackShortHeader(opener, data, connIDLen)
 	// If the reserved bits are set incorrectly, we still need to continue unpacking.
 	// This avoids a timing side-channel, which otherwise might allow an attacker
 	// to gain information about the header encryption.
@@ -161,8 +191,8 @@ func (u *packetUnpacker) unpackShortHeaderPacket(opener handshake.ShortHeaderOpe
 	return pn, pnLen, kp, decrypted, parseErr
 }
 
-func (u *packetUnpacker) unpackShortHeader(hd headerDecryptor, data []byte) (int, protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, error) {
-	hdrLen := 1 /* first header byte */ + u.shortHdrConnIDLen
+func unpackShortHeader(hd headerDecryptor, data []byte, connIDLen int) (int, protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, error) {
+	hdrLen := 1 /* first header byte */ + connIDLen
 	if len(data) < hdrLen+4+16 {
 		return 0, 0, 0, 0, fmt.Errorf("packet too small, expected at least 20 bytes after the header, got %d", len(data)-hdrLen)
 	}
@@ -175,7 +205,7 @@ func (u *packetUnpacker) unpackShortHeader(hd headerDecryptor, data []byte) (int
 		data[hdrLen:hdrLen+4],
 	)
 	// 3. parse the header (and learn the actual length of the packet number)
-	l, pn, pnLen, kp, parseErr := wire.ParseShortHeader(data, u.shortHdrConnIDLen)
+	l, pn, pnLen, kp, parseErr := wire.ParseShortHeader(data, connIDLen)
 	if parseErr != nil && parseErr != wire.ErrInvalidReservedBits {
 		return l, pn, pnLen, kp, parseErr
 	}
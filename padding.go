@@ -0,0 +1,65 @@
+package quic
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// A PaddingPolicy determines how much padding is added to packets, see Config.PaddingPolicy.
+// Padding hides the size of the data sent, which reduces the information available for traffic analysis,
+// at the cost of using more bandwidth. Padding counts towards the congestion window.
+//
+// The policy is applied to all datagrams containing a 1-RTT packet, with the exception of Path MTU Discovery probe packets.
+// Datagrams containing an Initial packet are always padded to at least 1200 bytes, as required by RFC 9000.
+type PaddingPolicy interface {
+	// PaddedSize returns the size that a datagram of size bytes is padded to.
+	// maxSize is the maximum size of the datagram.
+	// Return values smaller than size are ignored, and return values larger than maxSize are reduced to maxSize.
+	// PaddedSize is called concurrently by multiple connections using the same Config.
+	PaddedSize(size, maxSize int) int
+}
+
+// PaddingFunc is a PaddingPolicy that calls the function for every datagram.
+type PaddingFunc func(size, maxSize int) int
+
+var _ PaddingPolicy = PaddingFunc(nil)
+
+// PaddedSize calls f(size, maxSize).
+func (f PaddingFunc) PaddedSize(size, maxSize int) int { return f(size, maxSize) }
+
+type fixedSizePadding []int
+
+// PadToFixedSizes returns a PaddingPolicy that pads every datagram to the smallest of the sizes that it fits in.
+// Datagrams that are larger than all sizes are padded to the maximum datagram size.
+// Calling it without any sizes pads every datagram to the maximum datagram size.
+func PadToFixedSizes(sizes ...int) PaddingPolicy {
+	s := make(fixedSizePadding, len(sizes))
+	copy(s, sizes)
+	sort.Ints(s)
+	return s
+}
+
+func (s fixedSizePadding) PaddedSize(size, maxSize int) int {
+	if i := sort.SearchInts(s, size); i < len(s) {
+		return s[i]
+	}
+	return maxSize
+}
+
+type randomPadding struct{ minBytes, maxBytes int }
+
+// RandomPadding returns a PaddingPolicy that adds a random number of bytes in the range [minBytes, maxBytes] to every datagram,
+// as far as the maximum datagram size allows.
+func RandomPadding(minBytes, maxBytes int) PaddingPolicy {
+	if minBytes < 0 {
+		minBytes = 0
+	}
+	if maxBytes < minBytes {
+		maxBytes = minBytes
+	}
+	return randomPadding{minBytes: minBytes, maxBytes: maxBytes}
+}
+
+func (p randomPadding) PaddedSize(size, _ int) int {
+	return size + p.minBytes + rand.Intn(p.maxBytes-p.minBytes+1)
+}
@@ -0,0 +1,43 @@
+package quic
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Padding Policies", func() {
+	It("pads to fixed sizes", func() {
+		p := PadToFixedSizes(1000, 300, 600)
+		Expect(p.PaddedSize(100, 1400)).To(Equal(300))
+		Expect(p.PaddedSize(300, 1400)).To(Equal(300))
+		Expect(p.PaddedSize(301, 1400)).To(Equal(600))
+		Expect(p.PaddedSize(999, 1400)).To(Equal(1000))
+		// larger than all sizes
+		Expect(p.PaddedSize(1001, 1400)).To(Equal(1400))
+	})
+
+	It("pads to the maximum size, if no sizes are given", func() {
+		Expect(PadToFixedSizes().PaddedSize(100, 1400)).To(Equal(1400))
+	})
+
+	It("adds random padding", func() {
+		p := RandomPadding(10, 20)
+		seen := make(map[int]bool)
+		for i := 0; i < 1000; i++ {
+			size := p.PaddedSize(100, 1400)
+			Expect(size).To(And(BeNumerically(">=", 110), BeNumerically("<=", 120)))
+			seen[size] = true
+		}
+		Expect(seen).To(HaveLen(11))
+	})
+
+	It("adds a fixed amount of padding, if the range is empty", func() {
+		Expect(RandomPadding(10, 5).PaddedSize(100, 1400)).To(Equal(110))
+		Expect(RandomPadding(-5, 0).PaddedSize(100, 1400)).To(Equal(100))
+	})
+
+	It("calls the padding function", func() {
+		p := PaddingFunc(func(size, maxSize int) int { return (size + maxSize) / 2 })
+		Expect(p.PaddedSize(100, 1300)).To(Equal(700))
+	})
+})
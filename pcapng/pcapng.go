@@ -0,0 +1,219 @@
+// Package pcapng implements a connection tracer that writes the decrypted packets of a QUIC connection to a pcapng file.
+//
+// Since the tracer doesn't have access to the raw packet payload, it writes a synthesized plaintext representation
+// of every packet, using a custom link type (LINKTYPE_USER0).
+// This makes it possible to analyze a connection in Wireshark (using a custom dissector)
+// without exporting the TLS keys and the original ciphertext capture.
+//
+// Every packet record has the following format:
+//
+//	Direction (8): 0 for sent, 1 for received packets
+//	Packet Type (8): the logging.PacketType
+//	Packet Number (i): the packet number, encoded as a QUIC variable-length integer
+//	Destination Connection ID Length (8)
+//	Destination Connection ID (0..160)
+//	Frames (..): the frames, in QUIC wire encoding
+//
+// Since the payload of STREAM, CRYPTO and DATAGRAM frames is not available,
+// these frames are encoded with an explicit length field, but without the data.
+package pcapng
+
+import (
+	"encoding/binary"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// LinkType is the link type used for the synthesized packets (LINKTYPE_USER0).
+const LinkType = 147
+
+const (
+	directionSent     = 0
+	directionReceived = 1
+)
+
+const (
+	blockTypeSectionHeader        = 0x0a0d0d0a
+	blockTypeInterfaceDescription = 0x1
+	blockTypeEnhancedPacket       = 0x6
+
+	byteOrderMagic = 0x1a2b3c4d
+
+	optionEndOfOpt    = 0
+	optionComment     = 1
+	optionSHBUserAppl = 4
+)
+
+type connectionTracer struct {
+	mutex sync.Mutex
+
+	w        io.WriteCloser
+	writeErr error
+	buf      []byte
+}
+
+// NewConnectionTracer creates a new tracer that writes the (synthesized) plaintext packets of a connection to a pcapng file.
+func NewConnectionTracer(w io.WriteCloser, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+	t := &connectionTracer{w: w}
+	t.writeHeader(p, odcid)
+	return &logging.ConnectionTracer{
+		SentLongHeaderPacket: func(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			t.writePacket(directionSent, logging.PacketTypeFromHeader(&hdr.Header), hdr.PacketNumber, hdr.DestConnectionID, ack, frames)
+		},
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			t.writePacket(directionSent, logging.PacketType1RTT, hdr.PacketNumber, hdr.DestConnectionID, ack, frames)
+		},
+		ReceivedLongHeaderPacket: func(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ logging.ECN, frames []logging.Frame) {
+			t.writePacket(directionReceived, logging.PacketTypeFromHeader(&hdr.Header), hdr.PacketNumber, hdr.DestConnectionID, nil, frames)
+		},
+		ReceivedShortHeaderPacket: func(hdr *logging.ShortHeader, _ logging.ByteCount, _ logging.ECN, frames []logging.Frame) {
+			t.writePacket(directionReceived, logging.PacketType1RTT, hdr.PacketNumber, hdr.DestConnectionID, nil, frames)
+		},
+		Close: func() { t.Close() },
+	}
+}
+
+func (t *connectionTracer) writeHeader(p logging.Perspective, odcid logging.ConnectionID) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	// Section Header Block
+	body := make([]byte, 0, 64)
+	body = binary.LittleEndian.AppendUint32(body, byteOrderMagic)
+	body = binary.LittleEndian.AppendUint16(body, 1) // major version
+	body = binary.LittleEndian.AppendUint16(body, 0) // minor version
+	body = binary.LittleEndian.AppendUint64(body, 0xffffffffffffffff)
+	body = appendOption(body, optionSHBUserAppl, []byte("quic-go"))
+	body = appendOption(body, optionEndOfOpt, nil)
+	t.writeBlock(blockTypeSectionHeader, body)
+
+	// Interface Description Block
+	body = body[:0]
+	body = binary.LittleEndian.AppendUint16(body, LinkType)
+	body = binary.LittleEndian.AppendUint16(body, 0) // reserved
+	body = binary.LittleEndian.AppendUint32(body, 0) // no snap length
+	body = appendOption(body, optionComment, []byte(p.String()+" "+odcid.String()))
+	body = appendOption(body, optionEndOfOpt, nil)
+	t.writeBlock(blockTypeInterfaceDescription, body)
+}
+
+func (t *connectionTracer) writePacket(
+	direction uint8,
+	pt logging.PacketType,
+	pn logging.PacketNumber,
+	destConnID logging.ConnectionID,
+	ack *logging.AckFrame,
+	frames []logging.Frame,
+) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writeErr != nil {
+		return
+	}
+	data := make([]byte, 0, 128)
+	data = append(data, direction, uint8(pt))
+	data = quicvarint.Append(data, uint64(pn))
+	data = append(data, uint8(destConnID.Len()))
+	data = append(data, destConnID.Bytes()...)
+	if ack != nil {
+		data = appendFrame(data, ack)
+	}
+	for _, f := range frames {
+		data = appendFrame(data, f)
+	}
+
+	ts := uint64(time.Now().UnixMicro())
+	body := t.buf[:0]
+	body = binary.LittleEndian.AppendUint32(body, 0) // interface ID
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts>>32))
+	body = binary.LittleEndian.AppendUint32(body, uint32(ts))
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data))) // captured length
+	body = binary.LittleEndian.AppendUint32(body, uint32(len(data))) // original length
+	body = append(body, data...)
+	body = appendPadding(body)
+	t.writeBlock(blockTypeEnhancedPacket, body)
+	t.buf = body
+}
+
+// writeBlock writes a pcapng block.
+// The body must already be padded to a multiple of 4 bytes.
+func (t *connectionTracer) writeBlock(typ uint32, body []byte) {
+	if t.writeErr != nil {
+		return
+	}
+	length := uint32(len(body) + 12)
+	b := make([]byte, 0, length)
+	b = binary.LittleEndian.AppendUint32(b, typ)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	if _, err := t.w.Write(b); err != nil {
+		t.writeErr = err
+	}
+}
+
+func (t *connectionTracer) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.writeErr != nil {
+		log.Printf("writing pcapng failed: %s\n", t.writeErr)
+	}
+	if err := t.w.Close(); err != nil {
+		log.Printf("closing pcapng writer failed: %s\n", err)
+	}
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return appendPadding(b)
+}
+
+func appendPadding(b []byte) []byte {
+	for len(b)%4 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+type appendableFrame interface {
+	Append([]byte, protocol.VersionNumber) ([]byte, error)
+}
+
+func appendFrame(b []byte, frame logging.Frame) []byte {
+	switch f := frame.(type) {
+	case *logging.StreamFrame:
+		typ := uint64(0x8 | 0x4 | 0x2)
+		if f.Fin {
+			typ |= 0x1
+		}
+		b = quicvarint.Append(b, typ)
+		b = quicvarint.Append(b, uint64(f.StreamID))
+		b = quicvarint.Append(b, uint64(f.Offset))
+		return quicvarint.Append(b, uint64(f.Length))
+	case *logging.CryptoFrame:
+		b = quicvarint.Append(b, 0x6)
+		b = quicvarint.Append(b, uint64(f.Offset))
+		return quicvarint.Append(b, uint64(f.Length))
+	case *logging.DatagramFrame:
+		b = quicvarint.Append(b, 0x31)
+		return quicvarint.Append(b, uint64(f.Length))
+	case *logging.ExtensionFrame:
+		// the payload is not available
+		return quicvarint.Append(b, f.Type)
+	case appendableFrame:
+		if nb, err := f.Append(b, protocol.Version1); err == nil {
+			return nb
+		}
+	}
+	return b
+}
@@ -0,0 +1,13 @@
+package pcapng
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPcapng(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "pcapng Suite")
+}
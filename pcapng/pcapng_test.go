@@ -0,0 +1,144 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"os"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type nopWriteCloserImpl struct{ io.Writer }
+
+func (nopWriteCloserImpl) Close() error { return nil }
+
+type errorWriter struct{}
+
+func (errorWriter) Write([]byte) (int, error) { return 0, errors.New("writer broken") }
+func (errorWriter) Close() error              { return nil }
+
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+func parseBlocks(data []byte) []block {
+	var blocks []block
+	for len(data) > 0 {
+		ExpectWithOffset(1, len(data)).To(BeNumerically(">=", 12))
+		typ := binary.LittleEndian.Uint32(data)
+		length := binary.LittleEndian.Uint32(data[4:])
+		ExpectWithOffset(1, length%4).To(BeZero())
+		ExpectWithOffset(1, binary.LittleEndian.Uint32(data[length-4:])).To(Equal(length))
+		blocks = append(blocks, block{typ: typ, body: data[8 : length-4]})
+		data = data[length:]
+	}
+	return blocks
+}
+
+var _ = Describe("pcapng", func() {
+	var (
+		buf    *bytes.Buffer
+		tracer *logging.ConnectionTracer
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		tracer = NewConnectionTracer(
+			nopWriteCloserImpl{Writer: buf},
+			protocol.PerspectiveClient,
+			protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef}),
+		)
+	})
+
+	It("writes the section header and the interface description", func() {
+		tracer.Close()
+		blocks := parseBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(2))
+		Expect(blocks[0].typ).To(BeEquivalentTo(blockTypeSectionHeader))
+		Expect(binary.LittleEndian.Uint32(blocks[0].body)).To(BeEquivalentTo(byteOrderMagic))
+		Expect(blocks[1].typ).To(BeEquivalentTo(blockTypeInterfaceDescription))
+		Expect(binary.LittleEndian.Uint16(blocks[1].body)).To(BeEquivalentTo(LinkType))
+		Expect(string(blocks[1].body)).To(ContainSubstring("Client deadbeef"))
+	})
+
+	It("writes sent packets", func() {
+		tracer.SentShortHeaderPacket(
+			&logging.ShortHeader{DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}), PacketNumber: 1337},
+			1234,
+			logging.ECNUnsupported,
+			&logging.AckFrame{AckRanges: []logging.AckRange{{Smallest: 1, Largest: 10}}},
+			[]logging.Frame{
+				&logging.MaxDataFrame{MaximumData: 42},
+				&logging.StreamFrame{StreamID: 4, Offset: 100, Length: 200, Fin: true},
+			},
+		)
+		tracer.Close()
+		blocks := parseBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(3))
+		Expect(blocks[2].typ).To(BeEquivalentTo(blockTypeEnhancedPacket))
+		body := blocks[2].body
+		capLen := binary.LittleEndian.Uint32(body[12:])
+		Expect(binary.LittleEndian.Uint32(body[16:])).To(Equal(capLen))
+		data := body[20 : 20+capLen]
+		Expect(data[0]).To(BeEquivalentTo(directionSent))
+		Expect(data[1]).To(BeEquivalentTo(logging.PacketType1RTT))
+		r := bytes.NewReader(data[2:])
+		pn, err := quicvarint.Read(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pn).To(BeEquivalentTo(1337))
+		connIDLen, err := r.ReadByte()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connIDLen).To(BeEquivalentTo(4))
+		connID := make([]byte, connIDLen)
+		_, err = r.Read(connID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(Equal([]byte{1, 2, 3, 4}))
+		// ACK frame
+		typ, err := quicvarint.Read(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(typ).To(BeEquivalentTo(0x2))
+	})
+
+	It("writes received packets", func() {
+		tracer.ReceivedLongHeaderPacket(
+			&logging.ExtendedHeader{
+				Header: logging.Header{
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+					Version:          protocol.Version1,
+				},
+				PacketNumber: 42,
+			},
+			1234,
+			logging.ECNUnsupported,
+			[]logging.Frame{&logging.CryptoFrame{Offset: 10, Length: 20}},
+		)
+		tracer.Close()
+		blocks := parseBlocks(buf.Bytes())
+		Expect(blocks).To(HaveLen(3))
+		data := blocks[2].body[20:]
+		Expect(data[0]).To(BeEquivalentTo(directionReceived))
+		Expect(data[1]).To(BeEquivalentTo(logging.PacketTypeHandshake))
+		Expect(data[2]).To(BeEquivalentTo(42))
+		Expect(data[8:11]).To(Equal([]byte{0x6, 10, 20}))
+	})
+
+	It("logs write errors", func() {
+		b := &bytes.Buffer{}
+		log.SetOutput(b)
+		defer log.SetOutput(os.Stdout)
+		t := NewConnectionTracer(errorWriter{}, protocol.PerspectiveServer, protocol.ConnectionID{})
+		t.ReceivedShortHeaderPacket(&logging.ShortHeader{}, 100, logging.ECNUnsupported, nil)
+		t.Close()
+		Expect(b.String()).To(ContainSubstring("writer broken"))
+	})
+})
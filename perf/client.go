@@ -0,0 +1,286 @@
+package perf
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// A Client runs perf measurements against a Server.
+type Client struct {
+	// UploadBytes is the number of bytes sent to the server on every stream.
+	UploadBytes uint64
+	// DownloadBytes is the number of bytes requested from the server on every stream.
+	DownloadBytes uint64
+	// Bidirectional makes the server start sending right away, while the client is still uploading.
+	// By default, the server only starts sending once it received all data.
+	Bidirectional bool
+	// Streams is the number of transfers run in parallel, each on its own stream.
+	// If zero, a single stream is used.
+	Streams int
+
+	// ReportInterval is the interval at which OnReport is called.
+	// If zero, no interval reports are generated.
+	ReportInterval time.Duration
+	// OnReport is called with an IntervalReport for every ReportInterval,
+	// and for the last (potentially shorter) interval when the measurement completes.
+	OnReport func(IntervalReport)
+}
+
+// An IntervalReport contains the measurements taken during a reporting interval.
+type IntervalReport struct {
+	// Start is the start of the interval, relative to the start of the measurement.
+	Start time.Duration
+	// Duration is the duration of the interval.
+	Duration time.Duration
+	// BytesSent is the number of bytes sent during the interval.
+	BytesSent uint64
+	// BytesReceived is the number of bytes received during the interval.
+	BytesReceived uint64
+	// PacketsLost is the number of packets declared lost during the interval.
+	// Packet loss is only tracked for connections established by DialAndRun.
+	PacketsLost uint64
+	// SmoothedRTT is the smoothed RTT at the end of the interval.
+	SmoothedRTT time.Duration
+	// MinRTT is the minimum RTT at the end of the interval.
+	MinRTT time.Duration
+}
+
+// UploadGoodput is the rate at which data was sent during the interval, in bits per second.
+func (r IntervalReport) UploadGoodput() float64 { return goodput(r.BytesSent, r.Duration) }
+
+// DownloadGoodput is the rate at which data was received during the interval, in bits per second.
+func (r IntervalReport) DownloadGoodput() float64 { return goodput(r.BytesReceived, r.Duration) }
+
+func (r IntervalReport) String() string {
+	return fmt.Sprintf("%s - %s: sent %d bytes (%.2f Mbit/s), received %d bytes (%.2f Mbit/s), lost %d packets, RTT %s (min %s)",
+		r.Start, r.Start+r.Duration,
+		r.BytesSent, r.UploadGoodput()/1e6,
+		r.BytesReceived, r.DownloadGoodput()/1e6,
+		r.PacketsLost, r.SmoothedRTT, r.MinRTT,
+	)
+}
+
+// The Result of a measurement.
+type Result struct {
+	// Duration is the time it took to complete all transfers.
+	Duration time.Duration
+	// TimeToFirstByte is the time until the first byte of data sent by the server was received,
+	// measured from the start of the measurement.
+	TimeToFirstByte time.Duration
+	// BytesSent is the total number of bytes sent.
+	BytesSent uint64
+	// BytesReceived is the total number of bytes received.
+	BytesReceived uint64
+	// PacketsLost is the number of packets declared lost.
+	// Packet loss is only tracked for connections established by DialAndRun.
+	PacketsLost uint64
+	// RTT are the RTT statistics of the connection at the end of the measurement.
+	RTT quic.RTTStats
+}
+
+// UploadGoodput is the average rate at which data was sent, in bits per second.
+func (r *Result) UploadGoodput() float64 { return goodput(r.BytesSent, r.Duration) }
+
+// DownloadGoodput is the average rate at which data was received, in bits per second.
+func (r *Result) DownloadGoodput() float64 { return goodput(r.BytesReceived, r.Duration) }
+
+// DialAndRun dials addr and runs the measurement.
+// NextProto is added to the NextProtos of the tls.Config.
+// Unlike Run, it tracks the number of lost packets.
+// The connection is closed when the measurement completes.
+func (c *Client) DialAndRun(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (*Result, error) {
+	tlsConf = tlsConf.Clone()
+	tlsConf.NextProtos = []string{NextProto}
+	if conf == nil {
+		conf = &quic.Config{}
+	} else {
+		conf = conf.Clone()
+	}
+	var lost atomic.Uint64
+	origTracer := conf.Tracer
+	conf.Tracer = func(ctx context.Context, p logging.Perspective, connID quic.ConnectionID) *logging.ConnectionTracer {
+		t := &logging.ConnectionTracer{
+			LostPacket: func(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) { lost.Add(1) },
+		}
+		if origTracer != nil {
+			if orig := origTracer(ctx, p, connID); orig != nil {
+				return logging.NewMultiplexedConnectionTracer(orig, t)
+			}
+		}
+		return t
+	}
+	conn, err := quic.DialAddr(ctx, addr, tlsConf, conf)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.CloseWithError(0, "")
+	return c.run(ctx, conn, lost.Load)
+}
+
+// Run runs the measurement on conn.
+// The connection must have been established using the NextProto ALPN.
+func (c *Client) Run(ctx context.Context, conn quic.Connection) (*Result, error) {
+	return c.run(ctx, conn, func() uint64 { return 0 })
+}
+
+func (c *Client) run(ctx context.Context, conn quic.Connection, packetsLost func() uint64) (*Result, error) {
+	numStreams := c.Streams
+	if numStreams <= 0 {
+		numStreams = 1
+	}
+
+	var sent, received atomic.Uint64
+	var firstByte atomic.Int64 // time since start, in nanoseconds
+	start := time.Now()
+	onSent := func(n int) { sent.Add(uint64(n)) }
+	onReceived := func(n int) {
+		if received.Add(uint64(n)) == uint64(n) {
+			firstByte.CompareAndSwap(0, int64(time.Since(start)))
+		}
+	}
+
+	errChan := make(chan error, numStreams)
+	for i := 0; i < numStreams; i++ {
+		go func() { errChan <- c.runStream(ctx, conn, onSent, onReceived) }()
+	}
+
+	var reporter *intervalReporter
+	if c.ReportInterval > 0 && c.OnReport != nil {
+		reporter = &intervalReporter{
+			conn:        conn,
+			start:       start,
+			sent:        &sent,
+			received:    &received,
+			packetsLost: packetsLost,
+			onReport:    c.OnReport,
+		}
+	}
+	var ticker <-chan time.Time
+	if reporter != nil {
+		t := time.NewTicker(c.ReportInterval)
+		defer t.Stop()
+		ticker = t.C
+	}
+
+	var err error
+	for remaining := numStreams; remaining > 0; {
+		select {
+		case <-ticker:
+			reporter.report(time.Now())
+		case e := <-errChan:
+			remaining--
+			if e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	end := time.Now()
+	if err != nil {
+		return nil, err
+	}
+	if reporter != nil {
+		reporter.report(end)
+	}
+	return &Result{
+		Duration:        end.Sub(start),
+		TimeToFirstByte: time.Duration(firstByte.Load()),
+		BytesSent:       sent.Load(),
+		BytesReceived:   received.Load(),
+		PacketsLost:     packetsLost(),
+		RTT:             conn.RTTStats(),
+	}, nil
+}
+
+func (c *Client) runStream(ctx context.Context, conn quic.Connection, onSent, onReceived func(int)) error {
+	str, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			str.CancelRead(errorCodeCanceled)
+			str.CancelWrite(errorCodeCanceled)
+		case <-done:
+		}
+	}()
+
+	hdr := header{downloadBytes: c.DownloadBytes, bidirectional: c.Bidirectional}
+	if _, err := str.Write(hdr.Append(nil)); err != nil {
+		return err
+	}
+	upload := func() error {
+		if err := send(str, c.UploadBytes, onSent); err != nil {
+			return err
+		}
+		return str.Close()
+	}
+
+	var uploadErr error
+	var wg sync.WaitGroup
+	if c.Bidirectional {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			uploadErr = upload()
+		}()
+	} else if err := upload(); err != nil {
+		return err
+	}
+	n, err := receive(str, onReceived)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	if uploadErr != nil {
+		return uploadErr
+	}
+	if n != c.DownloadBytes {
+		return fmt.Errorf("perf: received %d bytes, expected %d", n, c.DownloadBytes)
+	}
+	return nil
+}
+
+type intervalReporter struct {
+	conn        quic.Connection
+	start       time.Time
+	sent        *atomic.Uint64
+	received    *atomic.Uint64
+	packetsLost func() uint64
+	onReport    func(IntervalReport)
+
+	lastReport                          time.Time
+	lastSent, lastReceived, lastPackets uint64
+}
+
+func (r *intervalReporter) report(now time.Time) {
+	if r.lastReport.IsZero() {
+		r.lastReport = r.start
+	}
+	sent := r.sent.Load()
+	received := r.received.Load()
+	lost := r.packetsLost()
+	rtt := r.conn.RTTStats()
+	r.onReport(IntervalReport{
+		Start:         r.lastReport.Sub(r.start),
+		Duration:      now.Sub(r.lastReport),
+		BytesSent:     sent - r.lastSent,
+		BytesReceived: received - r.lastReceived,
+		PacketsLost:   lost - r.lastPackets,
+		SmoothedRTT:   rtt.SmoothedRTT,
+		MinRTT:        rtt.MinRTT,
+	})
+	r.lastReport = now
+	r.lastSent = sent
+	r.lastReceived = received
+	r.lastPackets = lost
+}
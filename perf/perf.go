@@ -0,0 +1,116 @@
+// Package perf implements a simple protocol to measure the throughput and latency of QUIC connections,
+// similar to qperf and to the QUIC performance measurement protocol (draft-banks-quic-performance).
+// Since it runs on top of quic-go, it allows baselining a network path with the same QUIC stack that is deployed.
+//
+// For every transfer, the client opens a bidirectional stream and sends a header,
+// consisting of the number of bytes it requests from the server and a flags field (both encoded as QUIC varints),
+// followed by the data it uploads. It then closes the send direction of the stream.
+// The server discards the uploaded data and sends the requested number of bytes.
+// By default, the server only starts sending once it has received all data uploaded by the client.
+// In bidirectional mode, it starts sending right after receiving the header.
+package perf
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// NextProto is the ALPN protocol used by the perf protocol.
+const NextProto = "quic-go-perf"
+
+// flagBidirectional makes the server send data while it is still receiving data.
+const flagBidirectional = 1 << 0
+
+const (
+	// errorCodeRequestTooLarge is used when the client requests more data than the server is willing to send.
+	errorCodeRequestTooLarge quic.StreamErrorCode = 0x1
+	// errorCodeCanceled is used when a transfer is canceled.
+	errorCodeCanceled quic.StreamErrorCode = 0x2
+)
+
+const bufferSize = 32 << 10
+
+// zeros is the data sent by both client and server.
+var zeros [bufferSize]byte
+
+type header struct {
+	downloadBytes uint64
+	bidirectional bool
+}
+
+func (h *header) Append(b []byte) []byte {
+	b = quicvarint.Append(b, h.downloadBytes)
+	var flags uint64
+	if h.bidirectional {
+		flags |= flagBidirectional
+	}
+	return quicvarint.Append(b, flags)
+}
+
+func parseHeader(r io.ByteReader) (*header, error) {
+	downloadBytes, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := quicvarint.Read(r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return &header{downloadBytes: downloadBytes, bidirectional: flags&flagBidirectional > 0}, nil
+}
+
+// send writes n bytes to w, calling onSent after every write.
+func send(w io.Writer, n uint64, onSent func(int)) error {
+	for n > 0 {
+		b := zeros[:]
+		if n < uint64(len(b)) {
+			b = b[:n]
+		}
+		written, err := w.Write(b)
+		if written > 0 && onSent != nil {
+			onSent(written)
+		}
+		if err != nil {
+			return err
+		}
+		n -= uint64(written)
+	}
+	return nil
+}
+
+// receive reads from r until io.EOF, calling onReceived after every read.
+// It returns the number of bytes read.
+func receive(r io.Reader, onReceived func(int)) (uint64, error) {
+	buf := make([]byte, bufferSize)
+	var total uint64
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total += uint64(n)
+			if onReceived != nil {
+				onReceived(n)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// goodput calculates the goodput in bits per second.
+func goodput(bytes uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / d.Seconds()
+}
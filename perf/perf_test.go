@@ -0,0 +1,160 @@
+package perf
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header", func() {
+	It("writes and parses the header", func() {
+		for _, hdr := range []header{
+			{downloadBytes: 1337},
+			{downloadBytes: 1 << 40, bidirectional: true},
+		} {
+			parsed, err := parseHeader(bytes.NewReader(hdr.Append(nil)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*parsed).To(Equal(hdr))
+		}
+	})
+
+	It("errors on truncated headers", func() {
+		b := (&header{downloadBytes: 1337}).Append(nil)
+		_, err := parseHeader(bytes.NewReader(b[:len(b)-1]))
+		Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		_, err = parseHeader(bytes.NewReader(nil))
+		Expect(err).To(MatchError(io.EOF))
+	})
+})
+
+var _ = Describe("Client and Server", func() {
+	var (
+		ln   *quic.Listener
+		addr string
+	)
+
+	serverTLSConfig := func() *tls.Config {
+		conf := testdata.GetTLSConfig()
+		conf.NextProtos = []string{NextProto}
+		return conf
+	}
+	clientTLSConfig := func() *tls.Config {
+		return &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost"}
+	}
+
+	runServer := func(s *Server) {
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", serverTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		addr = fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(s.Serve(ln)).To(MatchError(quic.ErrServerClosed))
+		}()
+		DeferCleanup(func() {
+			ln.Close()
+			Eventually(done).Should(BeClosed())
+		})
+	}
+
+	It("runs a measurement", func() {
+		runServer(&Server{})
+		c := &Client{UploadBytes: 1 << 20, DownloadBytes: 2 << 20}
+		res, err := c.DialAndRun(context.Background(), addr, clientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.BytesSent).To(BeEquivalentTo(1 << 20))
+		Expect(res.BytesReceived).To(BeEquivalentTo(2 << 20))
+		Expect(res.Duration).To(BeNumerically(">", 0))
+		Expect(res.TimeToFirstByte).To(And(BeNumerically(">", 0), BeNumerically("<=", res.Duration)))
+		Expect(res.RTT.SmoothedRTT).ToNot(BeZero())
+		Expect(res.UploadGoodput()).To(BeNumerically(">", 0))
+		Expect(res.DownloadGoodput()).To(BeNumerically(">", 0))
+	})
+
+	It("runs a bidirectional measurement on multiple streams", func() {
+		runServer(&Server{})
+		c := &Client{UploadBytes: 1 << 20, DownloadBytes: 1 << 20, Bidirectional: true, Streams: 4}
+		res, err := c.DialAndRun(context.Background(), addr, clientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.BytesSent).To(BeEquivalentTo(4 << 20))
+		Expect(res.BytesReceived).To(BeEquivalentTo(4 << 20))
+	})
+
+	It("runs a measurement on an existing connection", func() {
+		runServer(&Server{})
+		tlsConf := clientTLSConfig()
+		tlsConf.NextProtos = []string{NextProto}
+		conn, err := quic.DialAddr(context.Background(), addr, tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		for i := 0; i < 2; i++ {
+			res, err := (&Client{DownloadBytes: 1000}).Run(context.Background(), conn)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.BytesSent).To(BeZero())
+			Expect(res.BytesReceived).To(BeEquivalentTo(1000))
+		}
+	})
+
+	It("reports at regular intervals", func() {
+		runServer(&Server{})
+		var mutex sync.Mutex
+		var reports []IntervalReport
+		c := &Client{
+			UploadBytes:    5 << 20,
+			DownloadBytes:  5 << 20,
+			ReportInterval: 5 * time.Millisecond,
+			OnReport: func(r IntervalReport) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				reports = append(reports, r)
+			},
+		}
+		res, err := c.DialAndRun(context.Background(), addr, clientTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(len(reports)).To(BeNumerically(">", 1))
+		var sent, received uint64
+		var duration time.Duration
+		for _, r := range reports {
+			Expect(r.Start).To(Equal(duration))
+			Expect(r.SmoothedRTT).ToNot(BeZero())
+			sent += r.BytesSent
+			received += r.BytesReceived
+			duration += r.Duration
+		}
+		Expect(sent).To(Equal(res.BytesSent))
+		Expect(received).To(Equal(res.BytesReceived))
+		Expect(duration).To(Equal(res.Duration))
+	})
+
+	It("rejects requests for too much data", func() {
+		runServer(&Server{MaxDownloadBytes: 1000})
+		_, err := (&Client{DownloadBytes: 1001}).DialAndRun(context.Background(), addr, clientTLSConfig(), nil)
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(Equal(errorCodeRequestTooLarge))
+	})
+
+	It("stops when the context is canceled", func() {
+		runServer(&Server{})
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := (&Client{DownloadBytes: 1 << 40}).DialAndRun(ctx, addr, clientTLSConfig(), nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,70 @@
+package perf
+
+import (
+	"context"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// A Server serves perf clients.
+// The tls.Config used to create the listener must contain NextProto in its NextProtos.
+type Server struct {
+	// MaxDownloadBytes is the maximum number of bytes a client can request per stream.
+	// Streams requesting more data are reset.
+	// If zero, the number of bytes is not limited.
+	MaxDownloadBytes uint64
+}
+
+// Serve accepts connections from ln and serves them, until ln is closed.
+func (s *Server) Serve(ln *quic.Listener) error {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single connection, until the connection is closed.
+func (s *Server) ServeConn(conn quic.Connection) error {
+	for {
+		str, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return err
+		}
+		go s.handleStream(str)
+	}
+}
+
+func (s *Server) handleStream(str quic.Stream) {
+	hdr, err := parseHeader(quicvarint.NewReader(str))
+	if err != nil {
+		str.CancelRead(errorCodeCanceled)
+		str.CancelWrite(errorCodeCanceled)
+		return
+	}
+	if s.MaxDownloadBytes > 0 && hdr.downloadBytes > s.MaxDownloadBytes {
+		str.CancelRead(errorCodeRequestTooLarge)
+		str.CancelWrite(errorCodeRequestTooLarge)
+		return
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		_, err := receive(str, nil)
+		received <- err
+	}()
+	if !hdr.bidirectional {
+		if err := <-received; err != nil {
+			str.CancelWrite(errorCodeCanceled)
+			return
+		}
+	}
+	if err := send(str, hdr.downloadBytes, nil); err != nil {
+		str.CancelRead(errorCodeCanceled)
+		return
+	}
+	str.Close()
+}
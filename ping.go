@@ -0,0 +1,73 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// A pingRequest is a PING frame sent by Connection.Ping.
+// It is completed when the PING frame is acknowledged.
+// The PING frame is first queued from the goroutine calling Ping, all other methods are called from the connection's run loop.
+type pingRequest struct {
+	clock utils.Clock
+	// ackDelay returns the ACK delay of the ACK frame that is currently being processed
+	ackDelay func() time.Duration
+	// queue queues the PING frame (again, if it was lost)
+	queue func(*pingRequest)
+
+	sentTime time.Time
+	acked    bool
+	rtt      time.Duration // only valid once done is closed
+	done     chan struct{}
+}
+
+var _ ackhandler.FrameHandler = &pingRequest{}
+
+func newPingRequest(clock utils.Clock, ackDelay func() time.Duration, queue func(*pingRequest)) *pingRequest {
+	return &pingRequest{
+		clock:    clock,
+		ackDelay: ackDelay,
+		queue:    queue,
+		done:     make(chan struct{}),
+	}
+}
+
+// SentPacket is called when the packet containing the PING frame is sent.
+func (r *pingRequest) SentPacket(now time.Time) {
+	r.sentTime = now
+}
+
+func (r *pingRequest) OnAcked(wire.Frame) {
+	// The PING frame might have been retransmitted after it was declared lost.
+	// Only the first acknowledgment counts.
+	if r.acked {
+		return
+	}
+	r.acked = true
+	r.rtt = r.clock.Now().Sub(r.sentTime)
+	// The peer might have delayed the acknowledgment.
+	// This delay is not part of the round-trip time.
+	if d := r.ackDelay(); r.rtt > d {
+		r.rtt -= d
+	}
+	close(r.done)
+}
+
+func (r *pingRequest) OnLost(wire.Frame) {
+	if r.acked {
+		return
+	}
+	r.queue(r)
+}
+
+// sentPingFrames informs the pingRequests of PING frames contained in a packet that it was sent.
+func sentPingFrames(frames []ackhandler.Frame, now time.Time) {
+	for _, f := range frames {
+		if r, ok := f.Handler.(*pingRequest); ok {
+			r.SentPacket(now)
+		}
+	}
+}
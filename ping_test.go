@@ -0,0 +1,83 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PING requests", func() {
+	var (
+		clock    *fakeClock
+		ackDelay time.Duration
+		queued   []*pingRequest
+		r        *pingRequest
+	)
+
+	BeforeEach(func() {
+		clock = &fakeClock{now: time.Now()}
+		ackDelay = 0
+		queued = nil
+		r = newPingRequest(
+			clock,
+			func() time.Duration { return ackDelay },
+			func(r *pingRequest) { queued = append(queued, r) },
+		)
+	})
+
+	It("measures the RTT when the PING frame is acknowledged", func() {
+		sentPingFrames([]ackhandler.Frame{
+			{Frame: &wire.MaxDataFrame{}},
+			{Frame: &wire.PingFrame{}, Handler: r},
+		}, clock.now)
+		Expect(r.done).ToNot(BeClosed())
+		clock.now = clock.now.Add(42 * time.Millisecond)
+		r.OnAcked(&wire.PingFrame{})
+		Expect(r.done).To(BeClosed())
+		Expect(r.rtt).To(Equal(42 * time.Millisecond))
+	})
+
+	It("subtracts the ACK delay", func() {
+		sentPingFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}, Handler: r}}, clock.now)
+		clock.now = clock.now.Add(42 * time.Millisecond)
+		ackDelay = 10 * time.Millisecond
+		r.OnAcked(&wire.PingFrame{})
+		Expect(r.rtt).To(Equal(32 * time.Millisecond))
+	})
+
+	It("doesn't subtract the ACK delay if it is larger than the RTT", func() {
+		sentPingFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}, Handler: r}}, clock.now)
+		clock.now = clock.now.Add(5 * time.Millisecond)
+		ackDelay = 10 * time.Millisecond
+		r.OnAcked(&wire.PingFrame{})
+		Expect(r.rtt).To(Equal(5 * time.Millisecond))
+	})
+
+	It("requeues the PING frame when it is lost", func() {
+		sentPingFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}, Handler: r}}, clock.now)
+		clock.now = clock.now.Add(time.Second)
+		r.OnLost(&wire.PingFrame{})
+		Expect(queued).To(Equal([]*pingRequest{r}))
+		// the retransmission is sent
+		sentPingFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}, Handler: r}}, clock.now)
+		clock.now = clock.now.Add(10 * time.Millisecond)
+		r.OnAcked(&wire.PingFrame{})
+		Expect(r.rtt).To(Equal(10 * time.Millisecond))
+	})
+
+	It("only uses the first acknowledgment", func() {
+		sentPingFrames([]ackhandler.Frame{{Frame: &wire.PingFrame{}, Handler: r}}, clock.now)
+		clock.now = clock.now.Add(10 * time.Millisecond)
+		r.OnAcked(&wire.PingFrame{})
+		clock.now = clock.now.Add(10 * time.Millisecond)
+		r.OnAcked(&wire.PingFrame{})
+		Expect(r.rtt).To(Equal(10 * time.Millisecond))
+		// a spuriously lost PING frame is not requeued
+		r.OnLost(&wire.PingFrame{})
+		Expect(queued).To(BeEmpty())
+	})
+})
@@ -0,0 +1,280 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by ConnectionPool.Checkout after the pool was closed.
+var ErrPoolClosed = errors.New("quic: connection pool closed")
+
+// A ConnectionPool maintains client connections to a set of remote addresses,
+// and hands out streams on these connections.
+// Streams are obtained using Checkout, and need to be returned to the pool once the application is done using them.
+// Connections are dialed lazily, when a stream is checked out. Connections that fail (or fail a health check)
+// are removed from the pool, and a new connection is dialed in the background.
+type ConnectionPool struct {
+	// Transport is used to dial new connections. It must be set.
+	Transport *Transport
+	// TLSConfig and Config are used to dial new connections.
+	TLSConfig *tls.Config
+	Config    *Config
+
+	// MaxConnectionsPerAddr is the maximum number of connections established to a single remote address.
+	// A new connection is only dialed when all existing connections have checked out streams.
+	// If zero, a single connection is used.
+	MaxConnectionsPerAddr int
+	// MaxStreamsPerConnection is the maximum number of streams checked out on a single connection.
+	// Once this limit is reached on all connections (and no more connections can be dialed),
+	// Checkout blocks until a stream is returned.
+	// If zero, the number of streams is only limited by the peer's stream limit.
+	MaxStreamsPerConnection int
+
+	// HealthCheckPeriod is the interval in which a PING frame is sent on every connection.
+	// Connections that don't acknowledge the PING frame within HealthCheckTimeout are closed.
+	// If zero, no health checks are performed.
+	HealthCheckPeriod time.Duration
+	// HealthCheckTimeout is the time to wait for a PING frame to be acknowledged.
+	// If zero, HealthCheckPeriod is used.
+	HealthCheckTimeout time.Duration
+
+	mutex  sync.Mutex
+	addrs  map[string]*poolAddr
+	closed bool
+}
+
+// poolAddr holds the connections to a single remote address.
+type poolAddr struct {
+	addr    net.Addr
+	conns   []*pooledConnection
+	dialing int
+	// changed is closed (and replaced) when a connection is added, or when a stream is returned
+	changed chan struct{}
+}
+
+type pooledConnection struct {
+	conn  Connection
+	inUse int // number of checked out streams
+}
+
+// A PooledStream is a stream obtained from the ConnectionPool.
+type PooledStream struct {
+	Stream
+
+	pool *ConnectionPool
+	addr *poolAddr
+	conn *pooledConnection
+	once sync.Once
+}
+
+// Connection returns the connection that the stream was opened on.
+func (s *PooledStream) Connection() Connection {
+	return s.conn.conn
+}
+
+// Return returns the stream to the pool, allowing a new stream to be checked out.
+// It must be called once the application is done using the stream.
+// It doesn't close the stream, the application is responsible for closing (or resetting) the stream.
+// It is safe to call Return multiple times.
+func (s *PooledStream) Return() {
+	s.once.Do(func() {
+		s.pool.mutex.Lock()
+		defer s.pool.mutex.Unlock()
+		s.conn.inUse--
+		s.addr.notify()
+	})
+}
+
+func (a *poolAddr) notify() {
+	close(a.changed)
+	a.changed = make(chan struct{})
+}
+
+func (p *ConnectionPool) maxConnections() int {
+	if p.MaxConnectionsPerAddr <= 0 {
+		return 1
+	}
+	return p.MaxConnectionsPerAddr
+}
+
+// Checkout opens a new bidirectional stream to addr.
+// It uses the least loaded connection to addr, dialing a new connection if necessary.
+// If the limit on the number of checked out streams was reached, it blocks until a stream is returned,
+// or until the context is canceled.
+func (p *ConnectionPool) Checkout(ctx context.Context, addr net.Addr) (*PooledStream, error) {
+	for {
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			return nil, ErrPoolClosed
+		}
+		a := p.getAddr(addr)
+		c := a.leastLoaded()
+		canDial := len(a.conns)+a.dialing < p.maxConnections()
+		// Use an existing connection, unless all connections are busy and another connection can be dialed.
+		if c != nil && (c.inUse == 0 || !canDial) && (p.MaxStreamsPerConnection <= 0 || c.inUse < p.MaxStreamsPerConnection) {
+			c.inUse++
+			p.mutex.Unlock()
+			str, err := c.conn.OpenStreamSync(ctx)
+			if err != nil {
+				p.mutex.Lock()
+				c.inUse--
+				a.notify()
+				p.mutex.Unlock()
+				// If the connection was closed, try again using a different connection.
+				if c.conn.Context().Err() != nil && ctx.Err() == nil {
+					p.remove(a, c)
+					continue
+				}
+				return nil, err
+			}
+			return &PooledStream{Stream: str, pool: p, addr: a, conn: c}, nil
+		}
+		if canDial {
+			a.dialing++
+			p.mutex.Unlock()
+			if err := p.dial(ctx, a); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		changed := a.changed
+		p.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// getAddr must be called with the mutex held.
+func (p *ConnectionPool) getAddr(addr net.Addr) *poolAddr {
+	if p.addrs == nil {
+		p.addrs = make(map[string]*poolAddr)
+	}
+	key := addr.String()
+	a, ok := p.addrs[key]
+	if !ok {
+		a = &poolAddr{addr: addr, changed: make(chan struct{})}
+		p.addrs[key] = a
+	}
+	return a
+}
+
+func (a *poolAddr) leastLoaded() *pooledConnection {
+	var c *pooledConnection
+	for _, conn := range a.conns {
+		if c == nil || conn.inUse < c.inUse {
+			c = conn
+		}
+	}
+	return c
+}
+
+// dial dials a new connection to the address.
+// The caller must have incremented a.dialing.
+func (p *ConnectionPool) dial(ctx context.Context, a *poolAddr) error {
+	conn, err := p.Transport.Dial(ctx, a.addr, p.TLSConfig, p.Config)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	a.dialing--
+	if err != nil {
+		a.notify()
+		return err
+	}
+	if p.closed {
+		conn.CloseWithError(0, "")
+		return ErrPoolClosed
+	}
+	c := &pooledConnection{conn: conn}
+	a.conns = append(a.conns, c)
+	a.notify()
+	go p.watch(a, c)
+	return nil
+}
+
+// watch runs the health checks for a connection, and removes the connection from the pool once it is closed.
+func (p *ConnectionPool) watch(a *poolAddr, c *pooledConnection) {
+	ctx := c.conn.Context()
+	if p.HealthCheckPeriod > 0 {
+		timeout := p.HealthCheckTimeout
+		if timeout <= 0 {
+			timeout = p.HealthCheckPeriod
+		}
+		ticker := time.NewTicker(p.HealthCheckPeriod)
+	healthCheck:
+		for {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				break healthCheck
+			case <-ticker.C:
+				pingCtx, cancel := context.WithTimeout(ctx, timeout)
+				_, err := c.conn.Ping(pingCtx)
+				cancel()
+				if err != nil && ctx.Err() == nil {
+					c.conn.CloseWithError(0, "health check failed")
+				}
+			}
+		}
+	}
+	<-ctx.Done()
+	if p.remove(a, c) {
+		p.redial(a)
+	}
+}
+
+// remove removes a connection from the pool.
+// It returns false if the connection was already removed, or if the pool was closed.
+func (p *ConnectionPool) remove(a *poolAddr, c *pooledConnection) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, conn := range a.conns {
+		if conn == c {
+			a.conns = append(a.conns[:i], a.conns[i+1:]...)
+			a.notify()
+			return !p.closed
+		}
+	}
+	return false
+}
+
+// redial replaces a failed connection by dialing a new connection in the background.
+// If dialing fails, a new connection will be dialed by the next call to Checkout.
+func (p *ConnectionPool) redial(a *poolAddr) {
+	p.mutex.Lock()
+	if p.closed || len(a.conns)+a.dialing >= p.maxConnections() {
+		p.mutex.Unlock()
+		return
+	}
+	a.dialing++
+	p.mutex.Unlock()
+
+	// The handshake is bounded by the HandshakeIdleTimeout.
+	p.dial(context.Background(), a)
+}
+
+// Close closes all connections in the pool.
+// Checked out streams are closed along with their connections.
+func (p *ConnectionPool) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	for _, a := range p.addrs {
+		for _, c := range a.conns {
+			c.conn.CloseWithError(0, "")
+		}
+		a.conns = nil
+		a.notify()
+	}
+	return nil
+}
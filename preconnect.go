@@ -0,0 +1,102 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// Preconnect establishes connections to a set of servers ahead of time,
+// so that the first request to any of these servers doesn't have to wait for the handshake.
+// It dials all addresses in parallel, and returns once all handshakes have completed.
+// If a connection to an address already exists, that address is skipped.
+// Dialing errors are combined into the returned error. The other connections are established nevertheless.
+//
+// The connections are kept alive using keep-alives. If the Config doesn't set a KeepAlivePeriod,
+// keep-alive packets are sent at half the idle timeout.
+// Connections are obtained using PreconnectedConnection. They are shared between all callers,
+// and remain owned by the Transport. Connections that are closed, e.g. because the server shut them down,
+// are removed, and not re-established automatically.
+func (t *Transport) Preconnect(ctx context.Context, addrs []net.Addr, tlsConf *tls.Config, conf *Config) error {
+	if conf == nil {
+		conf = &Config{}
+	}
+	if conf.KeepAlivePeriod == 0 {
+		conf = conf.Clone()
+		conf.KeepAlivePeriod = protocol.MaxKeepAliveInterval
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(addrs))
+	for i, addr := range addrs {
+		if t.PreconnectedConnection(addr) != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, addr net.Addr) {
+			defer wg.Done()
+			if err := t.preconnect(ctx, addr, tlsConf, conf); err != nil {
+				errs[i] = fmt.Errorf("quic: preconnecting to %s failed: %w", addr, err)
+			}
+		}(i, addr)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (t *Transport) preconnect(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config) error {
+	conn, err := t.Dial(ctx, addr, tlsConf, conf)
+	if err != nil {
+		return err
+	}
+	key := addr.String()
+	t.mutex.Lock()
+	if t.closed {
+		t.mutex.Unlock()
+		conn.CloseWithError(0, "")
+		return errors.New("quic: transport closed")
+	}
+	if _, ok := t.preconnected[key]; ok {
+		// A concurrent call to Preconnect was faster.
+		t.mutex.Unlock()
+		conn.CloseWithError(0, "")
+		return nil
+	}
+	if t.preconnected == nil {
+		t.preconnected = make(map[string]Connection)
+	}
+	t.preconnected[key] = conn
+	t.mutex.Unlock()
+
+	go func() {
+		<-conn.Context().Done()
+		t.mutex.Lock()
+		if t.preconnected[key] == conn {
+			delete(t.preconnected, key)
+		}
+		t.mutex.Unlock()
+	}()
+	return nil
+}
+
+// PreconnectedConnection returns the connection to addr that was established by Preconnect.
+// It returns nil if there's no such connection, or if the connection was closed.
+func (t *Transport) PreconnectedConnection(addr net.Addr) Connection {
+	t.mutex.Lock()
+	conn, ok := t.preconnected[addr.String()]
+	t.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-conn.Context().Done():
+		return nil
+	default:
+		return conn
+	}
+}
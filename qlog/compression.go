@@ -0,0 +1,23 @@
+package qlog
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+type gzipWriteCloser struct {
+	*gzip.Writer
+	closer io.Closer
+}
+
+func newGzipWriteCloser(w io.WriteCloser) io.WriteCloser {
+	return &gzipWriteCloser{Writer: gzip.NewWriter(w), closer: w}
+}
+
+// Close flushes the compressed data and closes the underlying io.WriteCloser.
+func (w *gzipWriteCloser) Close() error {
+	if err := w.Writer.Close(); err != nil {
+		return err
+	}
+	return w.closer.Close()
+}
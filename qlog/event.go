@@ -358,6 +358,26 @@ func (e eventKeyUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	}
 }
 
+type eventKeyUpdateInitiated struct {
+	Generation      protocol.KeyPhase
+	Reason          logging.KeyUpdateReason
+	PacketsSent     uint64
+	PacketsReceived uint64
+	BytesSent       protocol.ByteCount
+}
+
+func (e eventKeyUpdateInitiated) Category() category { return categorySecurity }
+func (e eventKeyUpdateInitiated) Name() string       { return "key_update_initiated" }
+func (e eventKeyUpdateInitiated) IsNil() bool        { return false }
+
+func (e eventKeyUpdateInitiated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("generation", uint64(e.Generation))
+	enc.StringKey("reason", keyUpdateReason(e.Reason).String())
+	enc.Uint64Key("packets_sent", e.PacketsSent)
+	enc.Uint64Key("packets_received", e.PacketsReceived)
+	enc.Uint64Key("bytes_sent", uint64(e.BytesSent))
+}
+
 type eventKeyDiscarded struct {
 	KeyType    keyType
 	Generation protocol.KeyPhase
@@ -404,6 +424,10 @@ type eventTransportParameters struct {
 	PreferredAddress *preferredAddress
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	AddressDiscoveryMode protocol.AddressDiscoveryMode
+	ImmediateAck         bool
+	NullEncryption       bool
 }
 
 func (e eventTransportParameters) Category() category { return categoryTransport }
@@ -449,6 +473,15 @@ func (e eventTransportParameters) MarshalJSONObject(enc *gojay.Encoder) {
 	if e.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		enc.Int64Key("max_datagram_frame_size", int64(e.MaxDatagramFrameSize))
 	}
+	if e.AddressDiscoveryMode != protocol.AddressDiscoveryDisabled {
+		enc.StringKey("address_discovery", e.AddressDiscoveryMode.String())
+	}
+	if e.ImmediateAck {
+		enc.BoolKey("immediate_ack", true)
+	}
+	if e.NullEncryption {
+		enc.BoolKey("null_encryption", true)
+	}
 }
 
 type preferredAddress struct {
@@ -538,6 +571,48 @@ func (e eventECNStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKeyOmitEmpty("trigger", ecnStateTrigger(e.trigger).String())
 }
 
+type eventMTUBlackHoleDetected struct {
+	oldSize protocol.ByteCount
+	newSize protocol.ByteCount
+}
+
+func (e eventMTUBlackHoleDetected) Category() category { return categoryRecovery }
+func (e eventMTUBlackHoleDetected) Name() string       { return "mtu_black_hole_detected" }
+func (e eventMTUBlackHoleDetected) IsNil() bool        { return false }
+
+func (e eventMTUBlackHoleDetected) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("old", uint64(e.oldSize))
+	enc.Uint64Key("new", uint64(e.newSize))
+}
+
+type eventHandshakeMilestone struct {
+	milestone logging.HandshakeMilestone
+}
+
+func (e eventHandshakeMilestone) Category() category { return categoryConnectivity }
+func (e eventHandshakeMilestone) Name() string       { return "handshake_milestone" }
+func (e eventHandshakeMilestone) IsNil() bool        { return false }
+
+func (e eventHandshakeMilestone) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("milestone", handshakeMilestone(e.milestone).String())
+}
+
+type eventHandshakeFailed struct {
+	reason   logging.HandshakeFailureReason
+	encLevel protocol.EncryptionLevel
+	err      error
+}
+
+func (e eventHandshakeFailed) Category() category { return categoryConnectivity }
+func (e eventHandshakeFailed) Name() string       { return "handshake_failed" }
+func (e eventHandshakeFailed) IsNil() bool        { return false }
+
+func (e eventHandshakeFailed) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("reason", handshakeFailureReason(e.reason).String())
+	enc.StringKey("packet_number_space", encLevelToPacketNumberSpace(e.encLevel))
+	enc.StringKey("error", e.err.Error())
+}
+
 type eventGeneric struct {
 	name string
 	msg  string
@@ -550,3 +625,15 @@ func (e eventGeneric) IsNil() bool        { return false }
 func (e eventGeneric) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("details", e.msg)
 }
+
+type eventEventsDropped struct {
+	Count uint64
+}
+
+func (e eventEventsDropped) Category() category { return categoryTransport }
+func (e eventEventsDropped) Name() string       { return "events_dropped" }
+func (e eventEventsDropped) IsNil() bool        { return false }
+
+func (e eventEventsDropped) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Uint64Key("count", e.Count)
+}
@@ -0,0 +1,100 @@
+package qlog
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultEventBufferSize = 1 << 10
+
+// The eventBuffer is a bounded ring buffer of events.
+// It decouples recording of events (on the connection's run loop) from serializing them.
+// If the buffer is full, new events are dropped, and the number of dropped events is counted.
+type eventBuffer struct {
+	mutex sync.Mutex
+
+	events []event
+	head   int // index of the oldest event
+	len    int
+
+	dropped        uint64
+	firstDroppedAt time.Duration // relative time of the first event dropped since the last Pop
+	closed         bool
+	notify         chan struct{}
+}
+
+func newEventBuffer(size int) *eventBuffer {
+	return &eventBuffer{
+		events: make([]event, size),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Push adds an event to the buffer. It never blocks.
+func (b *eventBuffer) Push(ev event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	if b.len == len(b.events) {
+		if b.dropped == 0 {
+			b.firstDroppedAt = ev.RelativeTime
+		}
+		b.dropped++
+		return
+	}
+	b.events[(b.head+b.len)%len(b.events)] = ev
+	b.len++
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Pop appends all buffered events to evs.
+// If events were dropped since the last call, an event recording the number of dropped events is appended last:
+// Events are only dropped when the buffer is full, so they occurred after all events in the buffer.
+// It blocks until at least one event is available.
+// It returns false when the buffer was closed and all events were consumed.
+func (b *eventBuffer) Pop(evs []event) ([]event, bool) {
+	for {
+		b.mutex.Lock()
+		if b.len > 0 || b.dropped > 0 {
+			for ; b.len > 0; b.len-- {
+				evs = append(evs, b.events[b.head])
+				b.events[b.head] = event{}
+				b.head = (b.head + 1) % len(b.events)
+			}
+			if b.dropped > 0 {
+				evs = append(evs, event{
+					RelativeTime: b.firstDroppedAt,
+					eventDetails: &eventEventsDropped{Count: b.dropped},
+				})
+				b.dropped = 0
+			}
+			b.mutex.Unlock()
+			return evs, true
+		}
+		if b.closed {
+			b.mutex.Unlock()
+			return evs, false
+		}
+		b.mutex.Unlock()
+		<-b.notify
+	}
+}
+
+// Close closes the buffer.
+// Events pushed after closing are discarded.
+func (b *eventBuffer) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.notify)
+}
@@ -0,0 +1,89 @@
+package qlog
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Event Buffer", func() {
+	newEvent := func(t time.Duration) event {
+		return event{RelativeTime: t, eventDetails: &eventUpdatedPTO{Value: uint32(t)}}
+	}
+
+	It("returns events in order", func() {
+		b := newEventBuffer(4)
+		for i := 1; i <= 3; i++ {
+			b.Push(newEvent(time.Duration(i)))
+		}
+		evs, ok := b.Pop(nil)
+		Expect(ok).To(BeTrue())
+		Expect(evs).To(Equal([]event{newEvent(1), newEvent(2), newEvent(3)}))
+		// wrap around
+		for i := 4; i <= 7; i++ {
+			b.Push(newEvent(time.Duration(i)))
+		}
+		evs, ok = b.Pop(evs[:0])
+		Expect(ok).To(BeTrue())
+		Expect(evs).To(Equal([]event{newEvent(4), newEvent(5), newEvent(6), newEvent(7)}))
+	})
+
+	It("drops events when full, and reports the number of dropped events", func() {
+		b := newEventBuffer(2)
+		for i := 1; i <= 5; i++ {
+			b.Push(newEvent(time.Duration(i)))
+		}
+		evs, ok := b.Pop(nil)
+		Expect(ok).To(BeTrue())
+		Expect(evs).To(HaveLen(3))
+		Expect(evs[:2]).To(Equal([]event{newEvent(1), newEvent(2)}))
+		Expect(evs[2].RelativeTime).To(Equal(time.Duration(3)))
+		Expect(evs[2].eventDetails).To(Equal(&eventEventsDropped{Count: 3}))
+		// the drop counter is reset
+		b.Push(newEvent(6))
+		evs, ok = b.Pop(nil)
+		Expect(ok).To(BeTrue())
+		Expect(evs).To(Equal([]event{newEvent(6)}))
+	})
+
+	It("blocks until an event is pushed", func() {
+		b := newEventBuffer(2)
+		done := make(chan []event)
+		go func() {
+			defer GinkgoRecover()
+			evs, ok := b.Pop(nil)
+			Expect(ok).To(BeTrue())
+			done <- evs
+		}()
+		Consistently(done).ShouldNot(Receive())
+		b.Push(newEvent(1))
+		Eventually(done).Should(Receive(Equal([]event{newEvent(1)})))
+	})
+
+	It("returns the remaining events after closing", func() {
+		b := newEventBuffer(2)
+		b.Push(newEvent(1))
+		b.Close()
+		b.Push(newEvent(2))
+		evs, ok := b.Pop(nil)
+		Expect(ok).To(BeTrue())
+		Expect(evs).To(Equal([]event{newEvent(1)}))
+		_, ok = b.Pop(nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("unblocks Pop when closed", func() {
+		b := newEventBuffer(2)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, ok := b.Pop(nil)
+			Expect(ok).To(BeFalse())
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		b.Close()
+		Eventually(done).Should(BeClosed())
+	})
+})
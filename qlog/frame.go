@@ -59,6 +59,12 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalHandshakeDoneFrame(enc, frame)
 	case *logging.DatagramFrame:
 		marshalDatagramFrame(enc, frame)
+	case *logging.ObservedAddressFrame:
+		marshalObservedAddressFrame(enc, frame)
+	case *logging.ImmediateAckFrame:
+		marshalImmediateAckFrame(enc, frame)
+	case *logging.ExtensionFrame:
+		marshalExtensionFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -225,3 +231,24 @@ func marshalDatagramFrame(enc *gojay.Encoder, f *logging.DatagramFrame) {
 	enc.StringKey("frame_type", "datagram")
 	enc.Int64Key("length", int64(f.Length))
 }
+
+func marshalObservedAddressFrame(enc *gojay.Encoder, f *logging.ObservedAddressFrame) {
+	enc.StringKey("frame_type", "observed_address")
+	enc.Uint64Key("sequence_number", f.SequenceNumber)
+	if addr := f.Address.Addr().Unmap(); addr.Is4() {
+		enc.StringKey("ip_v4", addr.String())
+	} else {
+		enc.StringKey("ip_v6", addr.String())
+	}
+	enc.Uint16Key("port", f.Address.Port())
+}
+
+func marshalImmediateAckFrame(enc *gojay.Encoder, _ *logging.ImmediateAckFrame) {
+	enc.StringKey("frame_type", "immediate_ack")
+}
+
+func marshalExtensionFrame(enc *gojay.Encoder, f *logging.ExtensionFrame) {
+	enc.StringKey("frame_type", "unknown")
+	enc.Uint64Key("raw_frame_type", f.Type)
+	enc.Int64Key("length", int64(f.Length))
+}
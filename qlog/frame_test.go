@@ -3,6 +3,7 @@ package qlog
 import (
 	"bytes"
 	"encoding/json"
+	"net/netip"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
@@ -364,6 +365,15 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals IMMEDIATE_ACK frames", func() {
+		check(
+			&logging.ImmediateAckFrame{},
+			map[string]interface{}{
+				"frame_type": "immediate_ack",
+			},
+		)
+	})
+
 	It("marshals DATAGRAM frames", func() {
 		check(
 			&logging.DatagramFrame{Length: 1337},
@@ -373,4 +383,36 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals extension frames", func() {
+		check(
+			&logging.ExtensionFrame{Type: 0x1337, Length: 42},
+			map[string]interface{}{
+				"frame_type":     "unknown",
+				"raw_frame_type": 0x1337,
+				"length":         42,
+			},
+		)
+	})
+
+	It("marshals OBSERVED_ADDRESS frames", func() {
+		check(
+			&logging.ObservedAddressFrame{SequenceNumber: 42, Address: netip.MustParseAddrPort("1.2.3.4:1337")},
+			map[string]interface{}{
+				"frame_type":      "observed_address",
+				"sequence_number": 42,
+				"ip_v4":           "1.2.3.4",
+				"port":            1337,
+			},
+		)
+		check(
+			&logging.ObservedAddressFrame{SequenceNumber: 43, Address: netip.MustParseAddrPort("[2001:db8::1]:1337")},
+			map[string]interface{}{
+				"frame_type":      "observed_address",
+				"sequence_number": 43,
+				"ip_v6":           "2001:db8::1",
+				"port":            1337,
+			},
+		)
+	})
 })
@@ -1,6 +1,7 @@
 package qlog
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -46,17 +47,66 @@ func init() {
 	}
 }
 
-const eventChanSize = 50
+// A Format is a qlog serialization format.
+type Format uint8
+
+const (
+	// FormatNDJSON serializes events as newline-delimited JSON.
+	FormatNDJSON Format = iota
+	// FormatJSONSeq serializes events as JSON text sequences (RFC 7464):
+	// every record is prefixed by an ASCII Record Separator and terminated by a line feed.
+	FormatJSONSeq
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatNDJSON:
+		return "NDJSON"
+	case FormatJSONSeq:
+		return "JSON-SEQ"
+	default:
+		return "unknown format"
+	}
+}
+
+// A Compression is the compression applied to the qlog output.
+type Compression uint8
+
+const (
+	// CompressionNone doesn't compress the output.
+	CompressionNone Compression = iota
+	// CompressionGzip compresses the output using gzip.
+	CompressionGzip
+)
+
+// Options configure the serialization of a qlog.
+type Options struct {
+	// Format is the serialization format.
+	// Defaults to FormatNDJSON.
+	Format Format
+	// Compression is the compression applied to the output.
+	// Defaults to CompressionNone.
+	Compression Compression
+	// EventBufferSize is the maximum number of events buffered before they are serialized.
+	// Events are serialized on a separate goroutine, so that tracing doesn't slow down the connection.
+	// If the writer can't keep up, events are dropped, and the number of dropped events is recorded
+	// in a transport:events_dropped event.
+	// Defaults to 1024.
+	EventBufferSize int
+}
+
+const recordSeparator = 0x1e
 
 type connectionTracer struct {
 	mutex sync.Mutex
 
 	w             io.WriteCloser
+	format        Format
 	odcid         protocol.ConnectionID
 	perspective   protocol.Perspective
 	referenceTime time.Time
 
-	events     chan event
+	events     *eventBuffer
 	encodeErr  error
 	runStopped chan struct{}
 
@@ -65,12 +115,30 @@ type connectionTracer struct {
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
 func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) *logging.ConnectionTracer {
+	return NewConnectionTracerWithOptions(w, p, odcid, nil)
+}
+
+// NewConnectionTracerWithOptions creates a new tracer to record a qlog for a connection,
+// using the serialization format and compression configured in the options.
+// If opts is nil, the qlog is written as uncompressed NDJSON.
+func NewConnectionTracerWithOptions(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID, opts *Options) *logging.ConnectionTracer {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.Compression == CompressionGzip {
+		w = newGzipWriteCloser(w)
+	}
+	bufferSize := opts.EventBufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
 	t := connectionTracer{
 		w:             w,
+		format:        opts.Format,
 		perspective:   p,
 		odcid:         odcid,
 		runStopped:    make(chan struct{}),
-		events:        make(chan event, eventChanSize),
+		events:        newEventBuffer(bufferSize),
 		referenceTime: time.Now(),
 	}
 	go t.run()
@@ -127,6 +195,9 @@ func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protoco
 		UpdatedKey: func(generation protocol.KeyPhase, remote bool) {
 			t.UpdatedKey(generation, remote)
 		},
+		InitiatedKeyUpdate: func(generation protocol.KeyPhase, reason logging.KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent protocol.ByteCount) {
+			t.InitiatedKeyUpdate(generation, reason, packetsSent, packetsReceived, bytesSent)
+		},
 		DroppedEncryptionLevel: func(encLevel protocol.EncryptionLevel) {
 			t.DroppedEncryptionLevel(encLevel)
 		},
@@ -145,6 +216,15 @@ func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protoco
 		ECNStateUpdated: func(state logging.ECNState, trigger logging.ECNStateTrigger) {
 			t.ECNStateUpdated(state, trigger)
 		},
+		DetectedMTUBlackHole: func(oldSize, newSize protocol.ByteCount) {
+			t.DetectedMTUBlackHole(oldSize, newSize)
+		},
+		ReachedHandshakeMilestone: func(milestone logging.HandshakeMilestone, _ time.Time) {
+			t.ReachedHandshakeMilestone(milestone)
+		},
+		FailedHandshake: func(reason logging.HandshakeFailureReason, encLevel logging.EncryptionLevel, err error) {
+			t.FailedHandshake(reason, encLevel, err)
+		},
 		Debug: func(name, msg string) {
 			t.Debug(name, msg)
 		},
@@ -159,6 +239,7 @@ func (t *connectionTracer) run() {
 	buf := &bytes.Buffer{}
 	enc := gojay.NewEncoder(buf)
 	tl := &topLevel{
+		format: t.format,
 		trace: trace{
 			VantagePoint: vantagePoint{Type: t.perspective},
 			CommonFields: commonFields{
@@ -168,6 +249,9 @@ func (t *connectionTracer) run() {
 			},
 		},
 	}
+	if t.format == FormatJSONSeq {
+		buf.WriteByte(recordSeparator)
+	}
 	if err := enc.Encode(tl); err != nil {
 		panic(fmt.Sprintf("qlog encoding into a bytes.Buffer failed: %s", err))
 	}
@@ -177,21 +261,45 @@ func (t *connectionTracer) run() {
 	if _, err := t.w.Write(buf.Bytes()); err != nil {
 		t.encodeErr = err
 	}
-	enc = gojay.NewEncoder(t.w)
-	for ev := range t.events {
-		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
-			continue
+	bw := bufio.NewWriter(t.w)
+	enc = gojay.NewEncoder(bw)
+	var events []event
+	for {
+		var ok bool
+		events, ok = t.events.Pop(events[:0])
+		if !ok {
+			break
 		}
-		if err := enc.Encode(ev); err != nil {
-			t.encodeErr = err
+		if t.encodeErr != nil { // if encoding failed, just continue draining the event buffer
 			continue
 		}
-		if _, err := t.w.Write([]byte{'\n'}); err != nil {
-			t.encodeErr = err
+		for _, ev := range events {
+			if err := t.encodeEvent(enc, bw, ev); err != nil {
+				t.encodeErr = err
+				break
+			}
+		}
+		// flush once the buffer is drained
+		if t.encodeErr == nil {
+			if err := bw.Flush(); err != nil {
+				t.encodeErr = err
+			}
 		}
 	}
 }
 
+func (t *connectionTracer) encodeEvent(enc *gojay.Encoder, w *bufio.Writer, ev event) error {
+	if t.format == FormatJSONSeq {
+		if err := w.WriteByte(recordSeparator); err != nil {
+			return err
+		}
+	}
+	if err := enc.Encode(ev); err != nil {
+		return err
+	}
+	return w.WriteByte('\n')
+}
+
 func (t *connectionTracer) Close() {
 	if err := t.export(); err != nil {
 		log.Printf("exporting qlog failed: %s\n", err)
@@ -200,7 +308,7 @@ func (t *connectionTracer) Close() {
 
 // export writes a qlog.
 func (t *connectionTracer) export() error {
-	close(t.events)
+	t.events.Close()
 	<-t.runStopped
 	if t.encodeErr != nil {
 		return t.encodeErr
@@ -209,10 +317,10 @@ func (t *connectionTracer) export() error {
 }
 
 func (t *connectionTracer) recordEvent(eventTime time.Time, details eventDetails) {
-	t.events <- event{
+	t.events.Push(event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
-	}
+	})
 }
 
 func (t *connectionTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID protocol.ConnectionID) {
@@ -325,6 +433,9 @@ func (t *connectionTracer) toTransportParameters(tp *wire.TransportParameters) *
 		InitialMaxStreamsUni:            int64(tp.MaxUniStreamNum),
 		PreferredAddress:                pa,
 		MaxDatagramFrameSize:            tp.MaxDatagramFrameSize,
+		AddressDiscoveryMode:            tp.AddressDiscoveryMode,
+		ImmediateAck:                    tp.ImmediateAck,
+		NullEncryption:                  tp.NullEncryption,
 	}
 }
 
@@ -526,6 +637,18 @@ func (t *connectionTracer) UpdatedKey(generation protocol.KeyPhase, remote bool)
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) InitiatedKeyUpdate(generation protocol.KeyPhase, reason logging.KeyUpdateReason, packetsSent, packetsReceived uint64, bytesSent protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventKeyUpdateInitiated{
+		Generation:      generation,
+		Reason:          reason,
+		PacketsSent:     packetsSent,
+		PacketsReceived: packetsReceived,
+		BytesSent:       bytesSent,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	t.mutex.Lock()
 	now := time.Now()
@@ -584,6 +707,24 @@ func (t *connectionTracer) ECNStateUpdated(state logging.ECNState, trigger loggi
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) DetectedMTUBlackHole(oldSize, newSize protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventMTUBlackHoleDetected{oldSize: oldSize, newSize: newSize})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) ReachedHandshakeMilestone(milestone logging.HandshakeMilestone) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventHandshakeMilestone{milestone: milestone})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) FailedHandshake(reason logging.HandshakeFailureReason, encLevel protocol.EncryptionLevel, err error) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventHandshakeFailed{reason: reason, encLevel: encLevel, err: err})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) Debug(name, msg string) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventGeneric{
@@ -2,6 +2,7 @@ package qlog
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
 	"io"
@@ -43,6 +44,17 @@ func (w *limitedWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// A blockingWriter blocks all writes until unblock is closed.
+type blockingWriter struct {
+	io.WriteCloser
+	unblock <-chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.unblock
+	return w.WriteCloser.Write(p)
+}
+
 type entry struct {
 	Time  time.Time
 	Name  string
@@ -68,6 +80,89 @@ var _ = Describe("Tracing", func() {
 		Expect(b.String()).To(ContainSubstring("writer full"))
 	})
 
+	Context("serialization options", func() {
+		It("uses NDJSON by default", func() {
+			buf := &bytes.Buffer{}
+			t := NewConnectionTracer(nopWriteCloser(buf), logging.PerspectiveServer, protocol.ConnectionID{})
+			t.Close()
+			m := make(map[string]interface{})
+			Expect(json.Unmarshal(buf.Bytes(), &m)).To(Succeed())
+			Expect(m).To(HaveKeyWithValue("qlog_format", "NDJSON"))
+		})
+
+		It("writes JSON-SEQ", func() {
+			buf := &bytes.Buffer{}
+			t := NewConnectionTracerWithOptions(
+				nopWriteCloser(buf),
+				logging.PerspectiveServer,
+				protocol.ConnectionID{},
+				&Options{Format: FormatJSONSeq},
+			)
+			t.UpdatedPTOCount(42)
+			t.Close()
+			records := bytes.Split(buf.Bytes(), []byte{recordSeparator})
+			Expect(records).To(HaveLen(3))
+			Expect(records[0]).To(BeEmpty())
+			m := make(map[string]interface{})
+			Expect(records[1]).To(HaveSuffix("\n"))
+			Expect(json.Unmarshal(records[1], &m)).To(Succeed())
+			Expect(m).To(HaveKeyWithValue("qlog_format", "JSON-SEQ"))
+			ev := make(map[string]interface{})
+			Expect(records[2]).To(HaveSuffix("\n"))
+			Expect(json.Unmarshal(records[2], &ev)).To(Succeed())
+			Expect(ev).To(HaveKeyWithValue("name", "recovery:metrics_updated"))
+		})
+
+		It("compresses the output", func() {
+			buf := &bytes.Buffer{}
+			t := NewConnectionTracerWithOptions(
+				nopWriteCloser(buf),
+				logging.PerspectiveServer,
+				protocol.ConnectionID{},
+				&Options{Compression: CompressionGzip},
+			)
+			t.UpdatedPTOCount(42)
+			t.Close()
+			r, err := gzip.NewReader(buf)
+			Expect(err).ToNot(HaveOccurred())
+			data, err := io.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			lines := bytes.Split(bytes.TrimSpace(data), []byte{'\n'})
+			Expect(lines).To(HaveLen(2))
+			m := make(map[string]interface{})
+			Expect(json.Unmarshal(lines[0], &m)).To(Succeed())
+			Expect(m).To(HaveKeyWithValue("qlog_format", "NDJSON"))
+		})
+
+		It("drops events if the writer can't keep up", func() {
+			buf := &bytes.Buffer{}
+			unblock := make(chan struct{})
+			t := NewConnectionTracerWithOptions(
+				&blockingWriter{WriteCloser: nopWriteCloser(buf), unblock: unblock},
+				logging.PerspectiveServer,
+				protocol.ConnectionID{},
+				&Options{EventBufferSize: 2},
+			)
+			for i := uint32(0); i < 10; i++ {
+				t.UpdatedPTOCount(i)
+			}
+			close(unblock)
+			t.Close()
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte{'\n'})
+			Expect(lines).To(HaveLen(4))
+			var names []string
+			for _, l := range lines[1:] {
+				ev := make(map[string]interface{})
+				Expect(json.Unmarshal(l, &ev)).To(Succeed())
+				names = append(names, ev["name"].(string))
+			}
+			Expect(names).To(Equal([]string{"recovery:metrics_updated", "recovery:metrics_updated", "transport:events_dropped"}))
+			ev := make(map[string]interface{})
+			Expect(json.Unmarshal(lines[3], &ev)).To(Succeed())
+			Expect(ev["data"]).To(HaveKeyWithValue("count", float64(8)))
+		})
+	})
+
 	Context("connection tracer", func() {
 		var (
 			tracer *logging.ConnectionTracer
@@ -311,6 +406,26 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).ToNot(HaveKey("max_datagram_frame_size"))
 			})
 
+			It("records transport parameters that enable IMMEDIATE_ACK frames", func() {
+				tracer.SentTransportParameters(&logging.TransportParameters{
+					MaxDatagramFrameSize: protocol.InvalidByteCount,
+					ImmediateAck:         true,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:parameters_set"))
+				Expect(entry.Event).To(HaveKeyWithValue("immediate_ack", true))
+			})
+
+			It("records transport parameters that enable null encryption", func() {
+				tracer.SentTransportParameters(&logging.TransportParameters{
+					MaxDatagramFrameSize: protocol.InvalidByteCount,
+					NullEncryption:       true,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:parameters_set"))
+				Expect(entry.Event).To(HaveKeyWithValue("null_encryption", true))
+			})
+
 			It("records the server's transport parameters, without a stateless reset token", func() {
 				tracer.SentTransportParameters(&logging.TransportParameters{
 					OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde}),
@@ -372,6 +487,18 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("max_datagram_frame_size", float64(1337)))
 			})
 
+			It("records transport parameters that enable the address discovery extension", func() {
+				tracer.SentTransportParameters(&logging.TransportParameters{
+					MaxDatagramFrameSize: protocol.InvalidByteCount,
+					AddressDiscoveryMode: protocol.AddressDiscoveryProvideAndReceive,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("transport:parameters_set"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("address_discovery", "provide and receive"))
+				Expect(ev).ToNot(HaveKey("max_datagram_frame_size"))
+			})
+
 			It("records received transport parameters", func() {
 				tracer.ReceivedTransportParameters(&logging.TransportParameters{})
 				entry := exportAndParseSingle()
@@ -762,6 +889,20 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).ToNot(HaveKey("new"))
 			})
 
+			It("records initiated key updates", func() {
+				tracer.InitiatedKeyUpdate(3, logging.KeyUpdateByteInterval, 100, 200, 1337)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("security:key_update_initiated"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(5))
+				Expect(ev).To(HaveKeyWithValue("generation", float64(3)))
+				Expect(ev).To(HaveKeyWithValue("reason", "byte_interval"))
+				Expect(ev).To(HaveKeyWithValue("packets_sent", float64(100)))
+				Expect(ev).To(HaveKeyWithValue("packets_received", float64(200)))
+				Expect(ev).To(HaveKeyWithValue("bytes_sent", float64(1337)))
+			})
+
 			It("records QUIC key updates", func() {
 				tracer.UpdatedKey(1337, true)
 				entries := exportAndParse()
@@ -886,6 +1027,39 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "ACK doesn't contain ECN marks"))
 			})
 
+			It("records detected MTU black holes", func() {
+				tracer.DetectedMTUBlackHole(1400, 1252)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:mtu_black_hole_detected"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(2))
+				Expect(ev).To(HaveKeyWithValue("old", float64(1400)))
+				Expect(ev).To(HaveKeyWithValue("new", float64(1252)))
+			})
+
+			It("records handshake milestones", func() {
+				tracer.ReachedHandshakeMilestone(logging.HandshakeMilestoneHandshakeComplete, time.Now())
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:handshake_milestone"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(1))
+				Expect(ev).To(HaveKeyWithValue("milestone", "handshake_complete"))
+			})
+
+			It("records failed handshakes", func() {
+				tracer.FailedHandshake(logging.HandshakeFailureTimeout, protocol.EncryptionHandshake, &quic.HandshakeTimeoutError{})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:handshake_failed"))
+				ev := entry.Event
+				Expect(ev).To(HaveLen(3))
+				Expect(ev).To(HaveKeyWithValue("reason", "timeout"))
+				Expect(ev).To(HaveKeyWithValue("packet_number_space", "handshake"))
+				Expect(ev).To(HaveKeyWithValue("error", "timeout: handshake did not complete in time"))
+			})
+
 			It("records a generic event", func() {
 				tracer.Debug("foo", "bar")
 				entry := exportAndParseSingle()
@@ -10,12 +10,13 @@ import (
 )
 
 type topLevel struct {
-	trace trace
+	format Format
+	trace  trace
 }
 
 func (topLevel) IsNil() bool { return false }
 func (l topLevel) MarshalJSONObject(enc *gojay.Encoder) {
-	enc.StringKey("qlog_format", "NDJSON")
+	enc.StringKey("qlog_format", l.format.String())
 	enc.StringKey("qlog_version", "draft-02")
 	enc.StringKeyOmitEmpty("title", "quic-go qlog")
 	enc.ObjectKey("configuration", configuration{Version: quicGoVersion})
@@ -85,6 +85,23 @@ func encLevelToPacketNumberSpace(encLevel protocol.EncryptionLevel) string {
 	}
 }
 
+type keyUpdateReason logging.KeyUpdateReason
+
+func (r keyUpdateReason) String() string {
+	switch logging.KeyUpdateReason(r) {
+	case logging.KeyUpdateFirst:
+		return "first_update"
+	case logging.KeyUpdatePacketInterval:
+		return "packet_interval"
+	case logging.KeyUpdateByteInterval:
+		return "byte_interval"
+	case logging.KeyUpdateConfidentialityLimit:
+		return "confidentiality_limit"
+	default:
+		return "unknown key update reason"
+	}
+}
+
 type keyType uint8
 
 const (
@@ -348,6 +365,50 @@ func (e ecnState) String() string {
 	}
 }
 
+type handshakeMilestone logging.HandshakeMilestone
+
+func (m handshakeMilestone) String() string {
+	switch logging.HandshakeMilestone(m) {
+	case logging.HandshakeMilestoneFirstInitialSent:
+		return "first_initial_sent"
+	case logging.HandshakeMilestoneRetryReceived:
+		return "retry_received"
+	case logging.HandshakeMilestoneFirstHandshakePacketReceived:
+		return "first_handshake_packet_received"
+	case logging.HandshakeMilestoneHandshakeComplete:
+		return "handshake_complete"
+	case logging.HandshakeMilestoneHandshakeConfirmed:
+		return "handshake_confirmed"
+	case logging.HandshakeMilestoneFirstAppDataSent:
+		return "first_app_data_sent"
+	case logging.HandshakeMilestoneFirstAppDataReceived:
+		return "first_app_data_received"
+	default:
+		return "unknown handshake milestone"
+	}
+}
+
+type handshakeFailureReason logging.HandshakeFailureReason
+
+func (r handshakeFailureReason) String() string {
+	switch logging.HandshakeFailureReason(r) {
+	case logging.HandshakeFailureOther:
+		return "other"
+	case logging.HandshakeFailureNoCompatibleVersion:
+		return "no_compatible_version"
+	case logging.HandshakeFailureALPNMismatch:
+		return "alpn_mismatch"
+	case logging.HandshakeFailureCertificate:
+		return "certificate"
+	case logging.HandshakeFailureTimeout:
+		return "timeout"
+	case logging.HandshakeFailureAmplificationLimited:
+		return "amplification_limited"
+	default:
+		return "unknown handshake failure reason"
+	}
+}
+
 type ecnStateTrigger logging.ECNStateTrigger
 
 func (e ecnStateTrigger) String() string {
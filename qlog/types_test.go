@@ -144,6 +144,35 @@ var _ = Describe("Types", func() {
 		Expect(ecnState(42).String()).To(Equal("unknown ECN state"))
 	})
 
+	It("has a string representation for the handshake milestone", func() {
+		Expect(handshakeMilestone(logging.HandshakeMilestoneFirstInitialSent).String()).To(Equal("first_initial_sent"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneRetryReceived).String()).To(Equal("retry_received"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneFirstHandshakePacketReceived).String()).To(Equal("first_handshake_packet_received"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneHandshakeComplete).String()).To(Equal("handshake_complete"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneHandshakeConfirmed).String()).To(Equal("handshake_confirmed"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneFirstAppDataSent).String()).To(Equal("first_app_data_sent"))
+		Expect(handshakeMilestone(logging.HandshakeMilestoneFirstAppDataReceived).String()).To(Equal("first_app_data_received"))
+		Expect(handshakeMilestone(42).String()).To(Equal("unknown handshake milestone"))
+	})
+
+	It("has a string representation for the key update reason", func() {
+		Expect(keyUpdateReason(logging.KeyUpdateFirst).String()).To(Equal("first_update"))
+		Expect(keyUpdateReason(logging.KeyUpdatePacketInterval).String()).To(Equal("packet_interval"))
+		Expect(keyUpdateReason(logging.KeyUpdateByteInterval).String()).To(Equal("byte_interval"))
+		Expect(keyUpdateReason(logging.KeyUpdateConfidentialityLimit).String()).To(Equal("confidentiality_limit"))
+		Expect(keyUpdateReason(42).String()).To(Equal("unknown key update reason"))
+	})
+
+	It("has a string representation for the handshake failure reason", func() {
+		Expect(handshakeFailureReason(logging.HandshakeFailureOther).String()).To(Equal("other"))
+		Expect(handshakeFailureReason(logging.HandshakeFailureNoCompatibleVersion).String()).To(Equal("no_compatible_version"))
+		Expect(handshakeFailureReason(logging.HandshakeFailureALPNMismatch).String()).To(Equal("alpn_mismatch"))
+		Expect(handshakeFailureReason(logging.HandshakeFailureCertificate).String()).To(Equal("certificate"))
+		Expect(handshakeFailureReason(logging.HandshakeFailureTimeout).String()).To(Equal("timeout"))
+		Expect(handshakeFailureReason(logging.HandshakeFailureAmplificationLimited).String()).To(Equal("amplification_limited"))
+		Expect(handshakeFailureReason(42).String()).To(Equal("unknown handshake failure reason"))
+	})
+
 	It("has a string representation for the ECN state trigger", func() {
 		Expect(ecnStateTrigger(logging.ECNTriggerNoTrigger).String()).To(Equal(""))
 		Expect(ecnStateTrigger(logging.ECNFailedNoECNCounts).String()).To(Equal("ACK doesn't contain ECN marks"))
@@ -0,0 +1,118 @@
+// Package quicdebug provides an HTTP handler and an expvar variable to inspect the QUIC connections
+// that are currently running in this process, similar to golang.org/x/net/trace's /debug/requests.
+//
+// Neither the handler nor the expvar variable is registered automatically.
+// Applications that want to expose them need to do so explicitly, for example:
+//
+//	http.Handle("/debug/quic", quicdebug.Handler())
+//	quicdebug.PublishExpvar("quic_connections")
+//
+// The information exposed includes the addresses of the peers, and should therefore not be made publicly accessible.
+package quicdebug
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// Handler returns an HTTP handler that lists all live connections.
+// The state of a single connection can be dumped by passing its tracing ID in the id query parameter.
+// Only connections started after the handler was created are listed, see quic.TrackLiveConnections.
+func Handler() http.Handler {
+	quic.TrackLiveConnections()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if idStr := r.URL.Query().Get("id"); idStr != "" {
+			id, err := strconv.ParseUint(idStr, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid connection ID", http.StatusBadRequest)
+				return
+			}
+			info, ok := quic.LiveConnection(id)
+			if !ok {
+				http.Error(w, "connection not found", http.StatusNotFound)
+				return
+			}
+			writeConnectionInfo(w, &info)
+			return
+		}
+		writeConnectionList(w, quic.LiveConnections())
+	})
+}
+
+// PublishExpvar publishes the state of all live connections as an expvar variable with the given name.
+// Only connections started after the variable was published are included, see quic.TrackLiveConnections.
+// Like expvar.Publish, it panics if a variable with this name was already published.
+func PublishExpvar(name string) {
+	quic.TrackLiveConnections()
+	expvar.Publish(name, expvar.Func(func() any { return quic.LiveConnections() }))
+}
+
+func writeConnectionList(w http.ResponseWriter, conns []quic.ConnectionInfo) {
+	fmt.Fprintf(w, "%d live connections\n\n", len(conns))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPerspective\tLocal\tRemote\tVersion\tHandshake\tSRTT\tCWND\tIn Flight\tBidi\tUni\tIdle")
+	for _, c := range conns {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\n",
+			c.TracingID,
+			c.Perspective,
+			c.LocalAddr,
+			c.RemoteAddr,
+			c.Version,
+			handshakeState(&c),
+			c.SmoothedRTT,
+			c.CongestionWindow,
+			c.BytesInFlight,
+			c.OpenBidiStreams,
+			c.OpenUniStreams,
+			c.IdleTime.Truncate(time.Millisecond),
+		)
+	}
+	tw.Flush()
+}
+
+func writeConnectionInfo(w http.ResponseWriter, c *quic.ConnectionInfo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Tracing ID:\t%d\n", c.TracingID)
+	fmt.Fprintf(tw, "Perspective:\t%s\n", c.Perspective)
+	fmt.Fprintf(tw, "Local Address:\t%s\n", c.LocalAddr)
+	fmt.Fprintf(tw, "Remote Address:\t%s\n", c.RemoteAddr)
+	fmt.Fprintf(tw, "Version:\t%s\n", c.Version)
+	fmt.Fprintf(tw, "Created:\t%s (%s ago)\n", c.CreationTime.Format(time.RFC3339Nano), time.Since(c.CreationTime).Truncate(time.Millisecond))
+	fmt.Fprintf(tw, "Handshake:\t%s\n", handshakeState(c))
+	fmt.Fprintf(tw, "Smoothed RTT:\t%s\n", c.SmoothedRTT)
+	fmt.Fprintf(tw, "Min RTT:\t%s\n", c.MinRTT)
+	fmt.Fprintf(tw, "Latest RTT:\t%s\n", c.LatestRTT)
+	fmt.Fprintf(tw, "RTT Mean Deviation:\t%s\n", c.MeanDeviation)
+	fmt.Fprintf(tw, "Congestion Window:\t%d\n", c.CongestionWindow)
+	fmt.Fprintf(tw, "Bytes in Flight:\t%d\n", c.BytesInFlight)
+	fmt.Fprintf(tw, "Open Bidirectional Streams:\t%d\n", c.OpenBidiStreams)
+	fmt.Fprintf(tw, "Open Unidirectional Streams:\t%d\n", c.OpenUniStreams)
+	fmt.Fprintf(tw, "Idle Time:\t%s\n", c.IdleTime.Truncate(time.Millisecond))
+	fmt.Fprintf(tw, "Idle Timeout:\t%s\n", c.IdleTimeout)
+	fmt.Fprintf(tw, "Memory:\t%d\n", c.Memory.Total())
+	fmt.Fprintf(tw, "  Stream Receive Buffers:\t%d\n", c.Memory.StreamReceiveBuffers)
+	fmt.Fprintf(tw, "  Stream Send Buffers:\t%d\n", c.Memory.StreamSendBuffers)
+	fmt.Fprintf(tw, "  Datagram Receive Queue:\t%d\n", c.Memory.DatagramReceiveQueue)
+	fmt.Fprintf(tw, "  Sent Packet History:\t%d\n", c.Memory.SentPacketHistory)
+	fmt.Fprintf(tw, "  Send Queue:\t%d\n", c.Memory.SendQueue)
+	fmt.Fprintf(tw, "  Receive Queue:\t%d\n", c.Memory.ReceiveQueue)
+	tw.Flush()
+}
+
+func handshakeState(c *quic.ConnectionInfo) string {
+	switch {
+	case c.HandshakeConfirmed:
+		return "confirmed"
+	case c.HandshakeComplete:
+		return "complete"
+	default:
+		return "in progress"
+	}
+}
@@ -0,0 +1,13 @@
+package quicdebug
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuicDebug(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "quicdebug Suite")
+}
@@ -0,0 +1,115 @@
+package quicdebug
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Debug Handler", func() {
+	var (
+		handler    http.Handler
+		ln         *quic.Listener
+		conn       quic.Connection
+		serverConn quic.Connection
+	)
+
+	BeforeEach(func() {
+		// create the handler first, since it enables tracking of the connections
+		handler = Handler()
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"quicdebug"}
+		var err error
+		ln, err = quic.ListenAddr("localhost:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		conn, err = quic.DialAddr(
+			context.Background(),
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"quicdebug"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverConn, err = ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		if conn != nil {
+			conn.CloseWithError(0, "")
+		}
+		ln.Close()
+	})
+
+	get := func(url string) (int, string) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		body, err := io.ReadAll(rec.Result().Body)
+		Expect(err).ToNot(HaveOccurred())
+		return rec.Code, string(body)
+	}
+
+	tracingID := func() uint64 {
+		return conn.Context().Value(quic.ConnectionTracingKey).(uint64)
+	}
+
+	It("lists live connections", func() {
+		code, body := get("/debug/quic")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(ContainSubstring("live connections"))
+		Expect(body).To(ContainSubstring(conn.LocalAddr().String()))
+		Expect(body).To(ContainSubstring(conn.RemoteAddr().String()))
+	})
+
+	It("dumps a single connection", func() {
+		code, body := get(fmt.Sprintf("/debug/quic?id=%d", tracingID()))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(ContainSubstring("Perspective:"))
+		Expect(body).To(MatchRegexp(`Local Address:\s+` + regexp.QuoteMeta(conn.LocalAddr().String())))
+		Expect(body).To(MatchRegexp(`Open Bidirectional Streams:\s+0`))
+	})
+
+	It("reports the memory usage", func() {
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(make([]byte, 10000))
+		Expect(err).ToNot(HaveOccurred())
+		// the server accepts the stream, but doesn't read the data
+		_, err = serverConn.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		serverTracingID := serverConn.Context().Value(quic.ConnectionTracingKey).(uint64)
+		Eventually(func() int64 {
+			info, ok := quic.LiveConnection(serverTracingID)
+			Expect(ok).To(BeTrue())
+			return info.Memory.StreamReceiveBuffers
+		}).Should(BeEquivalentTo(10000))
+		// the client's send buffer is released once the data has been acknowledged
+		Eventually(func() int64 {
+			info, ok := quic.LiveConnection(tracingID())
+			Expect(ok).To(BeTrue())
+			return info.Memory.StreamSendBuffers
+		}).Should(BeZero())
+
+		code, body := get(fmt.Sprintf("/debug/quic?id=%d", serverTracingID))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(body).To(MatchRegexp(`Stream Receive Buffers:\s+10000`))
+	})
+
+	It("returns 404 for unknown connections", func() {
+		code, _ := get("/debug/quic?id=1337133713371337")
+		Expect(code).To(Equal(http.StatusNotFound))
+		code, body := get("/debug/quic?id=foobar")
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(strings.TrimSpace(body)).To(Equal("invalid connection ID"))
+	})
+})
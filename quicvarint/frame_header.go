@@ -0,0 +1,72 @@
+package quicvarint
+
+import (
+	"errors"
+	"io"
+)
+
+// Many protocols built on top of QUIC encode their frames as a (type, length, value) tuple,
+// with the type and the length encoded as QUIC varints.
+// Examples are HTTP/3 frames (RFC 9114, section 7.1) and capsules (RFC 9297, section 3.2).
+// The functions in this file encode and decode the header of such a frame, i.e. its type and length.
+
+// ErrFrameTooLarge is returned by ReadFrameHeader and ParseFrameHeader
+// when the length of a frame exceeds the maximum length.
+var ErrFrameTooLarge = errors.New("quicvarint: frame too large")
+
+// FrameHeaderLen returns the number of bytes needed to encode the header of a frame.
+func FrameHeaderLen(frameType, length uint64) int {
+	return Len(frameType) + Len(length)
+}
+
+// AppendFrameHeader appends the header of a frame, i.e. its type and the length of its value.
+func AppendFrameHeader(b []byte, frameType, length uint64) []byte {
+	b = Append(b, frameType)
+	return Append(b, length)
+}
+
+// ReadFrameHeader reads the header of a frame from r.
+// If maxLength is non-zero, frames longer than maxLength are rejected with ErrFrameTooLarge.
+// It returns io.EOF if r is at EOF before the first byte of the header,
+// and io.ErrUnexpectedEOF if r ends in the middle of the header.
+func ReadFrameHeader(r io.ByteReader, maxLength uint64) (frameType, length uint64, err error) {
+	// The first byte is read separately, to distinguish EOF from a truncated header.
+	firstByte, err := r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	frameType, err = readWithFirstByte(firstByte, r)
+	if err == nil {
+		length, err = Read(r)
+	}
+	if err != nil {
+		if err == io.EOF {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if maxLength > 0 && length > maxLength {
+		return 0, 0, ErrFrameTooLarge
+	}
+	return frameType, length, nil
+}
+
+// ParseFrameHeader parses the header of a frame from the beginning of b.
+// It returns the number of bytes consumed.
+// If maxLength is non-zero, frames longer than maxLength are rejected with ErrFrameTooLarge.
+// It returns io.EOF if b is empty, and io.ErrUnexpectedEOF if b ends in the middle of the header.
+// It doesn't check that b contains the value of the frame.
+func ParseFrameHeader(b []byte, maxLength uint64) (frameType, length uint64, n int, err error) {
+	frameType, n1, err := Parse(b)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	length, n2, err := Parse(b[n1:])
+	if err != nil {
+		return 0, 0, 0, io.ErrUnexpectedEOF
+	}
+	if maxLength > 0 && length > maxLength {
+		return 0, 0, 0, ErrFrameTooLarge
+	}
+	return frameType, length, n1 + n2, nil
+}
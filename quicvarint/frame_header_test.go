@@ -0,0 +1,58 @@
+package quicvarint
+
+import (
+	"bytes"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Frame Header", func() {
+	It("writes and reads a frame header", func() {
+		b := AppendFrameHeader(nil, 0x1337, 0xdeadbeef)
+		Expect(b).To(HaveLen(FrameHeaderLen(0x1337, 0xdeadbeef)))
+		Expect(b).To(HaveLen(2 + 8))
+		frameType, length, err := ReadFrameHeader(bytes.NewReader(b), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frameType).To(BeEquivalentTo(0x1337))
+		Expect(length).To(BeEquivalentTo(0xdeadbeef))
+	})
+
+	It("parses a frame header", func() {
+		b := AppendFrameHeader(nil, 0x1337, 42)
+		frameType, length, n, err := ParseFrameHeader(append(b, []byte("foobar")...), 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frameType).To(BeEquivalentTo(0x1337))
+		Expect(length).To(BeEquivalentTo(42))
+		Expect(n).To(Equal(len(b)))
+	})
+
+	It("rejects frames that exceed the maximum length", func() {
+		b := AppendFrameHeader(nil, 0x42, 1001)
+		_, _, err := ReadFrameHeader(bytes.NewReader(b), 1000)
+		Expect(err).To(MatchError(ErrFrameTooLarge))
+		_, _, _, err = ParseFrameHeader(b, 1000)
+		Expect(err).To(MatchError(ErrFrameTooLarge))
+		_, length, _, err := ParseFrameHeader(b, 1001)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(length).To(BeEquivalentTo(1001))
+	})
+
+	It("returns io.EOF for empty input", func() {
+		_, _, err := ReadFrameHeader(bytes.NewReader(nil), 0)
+		Expect(err).To(MatchError(io.EOF))
+		_, _, _, err = ParseFrameHeader(nil, 0)
+		Expect(err).To(MatchError(io.EOF))
+	})
+
+	It("returns io.ErrUnexpectedEOF for truncated input", func() {
+		b := AppendFrameHeader(nil, 0x1337, 0xdeadbeef)
+		for i := 1; i < len(b); i++ {
+			_, _, err := ReadFrameHeader(bytes.NewReader(b[:i]), 0)
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+			_, _, _, err = ParseFrameHeader(b[:i], 0)
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		}
+	})
+})
@@ -3,8 +3,6 @@ package quicvarint
 import (
 	"fmt"
 	"io"
-
-	"github.com/quic-go/quic-go/internal/protocol"
 )
 
 // taken from the QUIC draft
@@ -27,6 +25,11 @@ func Read(r io.ByteReader) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
+	return readWithFirstByte(firstByte, r)
+}
+
+// readWithFirstByte reads the remaining bytes of a varint, after the first byte was read.
+func readWithFirstByte(firstByte byte, r io.ByteReader) (uint64, error) {
 	// the first two bits of the first byte encode the length
 	len := 1 << ((firstByte & 0xc0) >> 6)
 	b1 := firstByte & (0xff - 0xc0)
@@ -70,6 +73,25 @@ func Read(r io.ByteReader) (uint64, error) {
 	return uint64(b8) + uint64(b7)<<8 + uint64(b6)<<16 + uint64(b5)<<24 + uint64(b4)<<32 + uint64(b3)<<40 + uint64(b2)<<48 + uint64(b1)<<56, nil
 }
 
+// Parse reads a number in the QUIC varint format from the beginning of b.
+// It returns the number and the number of bytes consumed.
+// If b is too short to contain the number, io.ErrUnexpectedEOF is returned
+// (or io.EOF, if b is empty).
+func Parse(b []byte) (uint64 /* value */, int /* bytes consumed */, error) {
+	if len(b) == 0 {
+		return 0, 0, io.EOF
+	}
+	l := 1 << ((b[0] & 0xc0) >> 6)
+	if len(b) < l {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	v := uint64(b[0] & (0xff - 0xc0))
+	for i := 1; i < l; i++ {
+		v = v<<8 + uint64(b[i])
+	}
+	return v, l, nil
+}
+
 // Append appends i in the QUIC varint format.
 func Append(b []byte, i uint64) []byte {
 	if i <= maxVarInt1 {
@@ -91,7 +113,7 @@ func Append(b []byte, i uint64) []byte {
 }
 
 // AppendWithLen append i in the QUIC varint format with the desired length.
-func AppendWithLen(b []byte, i uint64, length protocol.ByteCount) []byte {
+func AppendWithLen(b []byte, i uint64, length int) []byte {
 	if length != 1 && length != 2 && length != 4 && length != 8 {
 		panic("invalid varint length")
 	}
@@ -109,17 +131,17 @@ func AppendWithLen(b []byte, i uint64, length protocol.ByteCount) []byte {
 	} else if length == 8 {
 		b = append(b, 0b11000000)
 	}
-	for j := protocol.ByteCount(1); j < length-l; j++ {
+	for j := 1; j < length-l; j++ {
 		b = append(b, 0)
 	}
-	for j := protocol.ByteCount(0); j < l; j++ {
+	for j := 0; j < l; j++ {
 		b = append(b, uint8(i>>(8*(l-1-j))))
 	}
 	return b
 }
 
 // Len determines the number of bytes that will be needed to write the number i.
-func Len(i uint64) protocol.ByteCount {
+func Len(i uint64) int {
 	if i <= maxVarInt1 {
 		return 1
 	}
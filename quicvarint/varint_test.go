@@ -2,6 +2,7 @@ package quicvarint
 
 import (
 	"bytes"
+	"io"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -60,6 +61,35 @@ var _ = Describe("Varint encoding / decoding", func() {
 		})
 	})
 
+	Context("parsing", func() {
+		It("parses numbers of all lengths", func() {
+			for _, tc := range []struct {
+				data []byte
+				val  uint64
+			}{
+				{data: []byte{0b00011001}, val: 25},
+				{data: []byte{0b01111011, 0xbd}, val: 15293},
+				{data: []byte{0b10011101, 0x7f, 0x3e, 0x7d}, val: 494878333},
+				{data: []byte{0b11000010, 0x19, 0x7c, 0x5e, 0xff, 0x14, 0xe8, 0x8c}, val: 151288809941952652},
+			} {
+				val, n, err := Parse(append(tc.data, 0x42)) // trailing data is not consumed
+				Expect(err).ToNot(HaveOccurred())
+				Expect(val).To(Equal(tc.val))
+				Expect(n).To(Equal(len(tc.data)))
+			}
+		})
+
+		It("returns io.EOF for empty input", func() {
+			_, _, err := Parse(nil)
+			Expect(err).To(MatchError(io.EOF))
+		})
+
+		It("returns io.ErrUnexpectedEOF for truncated input", func() {
+			_, _, err := Parse([]byte{0b10011101, 0x7f, 0x3e})
+			Expect(err).To(MatchError(io.ErrUnexpectedEOF))
+		})
+	})
+
 	Context("encoding", func() {
 		Context("with minimal length", func() {
 			It("writes a 1 byte number", func() {
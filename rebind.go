@@ -0,0 +1,105 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// Rebind moves the connection to a new local socket, see Connection.Rebind.
+func (s *connection) Rebind(pconn net.PacketConn) error {
+	if s.migratingConn == nil || s.perspective == protocol.PerspectiveServer {
+		return errors.New("quic: connection can't be rebound")
+	}
+	c, err := wrapConn(pconn)
+	if err != nil {
+		return err
+	}
+	var rebindErr error
+	if !s.runInLoop(func() { rebindErr = s.rebind(c) }) {
+		return context.Cause(s.ctx)
+	}
+	if rebindErr != nil {
+		return rebindErr
+	}
+	go s.readFromReboundConn(c)
+	return nil
+}
+
+// rebind switches to a path using the socket c, and starts validating this path.
+// It must be called from the run loop.
+func (s *connection) rebind(c rawConn) error {
+	if !s.handshakeConfirmed {
+		return errors.New("quic: can't rebind before the handshake is confirmed")
+	}
+	if s.peerParams.DisableActiveMigration {
+		return errors.New("quic: peer disabled active migration")
+	}
+	if s.pathValidation != nil {
+		return errors.New("quic: path validation in progress")
+	}
+	// Use a new connection ID on the new path,
+	// such that on-path observers can't correlate the packets sent on the old and on the new path.
+	// See section 9.5 of RFC 9000.
+	if !s.connIDManager.SwitchConnectionID() {
+		return errors.New("quic: no unused connection ID available")
+	}
+	s.logger.Debugf("Rebinding from %s to %s.", s.conn.LocalAddr(), c.LocalAddr())
+	// If only the port changed, the new path most likely has the same characteristics as the old one.
+	ipChanged := !isSameIP(s.conn.LocalAddr(), c.LocalAddr())
+	prev := s.migratingConn.switchPath(newSendConn(c, s.conn.RemoteAddr(), packetInfo{}, s.logger))
+	pto := s.rttStats.PTO(true)
+	s.sentPacketHandler.MigratedPath(0, ipChanged)
+	s.pathValidation = &pathValidation{
+		previous: prev,
+		rebound:  c,
+		deadline: s.clock.Now().Add(3 * utils.Max(pto, s.rttStats.PTO(true))),
+	}
+	rand.Read(s.pathValidation.challenge[:])
+	s.queueControlFrame(&wire.PathChallengeFrame{Data: s.pathValidation.challenge})
+	return nil
+}
+
+// readFromReboundConn reads packets from a socket that the connection was rebound to.
+// The socket is only used by this connection, so there's no need to demultiplex packets.
+// It returns once the socket is closed.
+func (s *connection) readFromReboundConn(c rawConn) {
+	for {
+		p, err := c.ReadPacket()
+		if err != nil {
+			//nolint:staticcheck // SA1019 ignore this!
+			if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+				continue
+			}
+			if isRecvMsgSizeErr(err) {
+				continue
+			}
+			return
+		}
+		s.handlePacket(p)
+	}
+}
+
+// reboundPathValidated is called when the path to the socket the connection was rebound to was validated.
+// The connection now owns this socket, and closes the socket it was previously rebound to (if any).
+func (s *connection) reboundPathValidated(c rawConn) {
+	if s.reboundConn != nil {
+		s.reboundConn.Close()
+	}
+	s.reboundConn = c
+}
+
+// closeReboundConns closes the sockets that the connection was rebound to.
+func (s *connection) closeReboundConns() {
+	if s.reboundConn != nil {
+		s.reboundConn.Close()
+	}
+	if s.pathValidation != nil && s.pathValidation.rebound != nil {
+		s.pathValidation.rebound.Close()
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
@@ -18,8 +17,10 @@ type receiveStreamI interface {
 
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	receivedEarlyData(start, end protocol.ByteCount)
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	receiveBufferedBytes() int
 }
 
 type receiveStream struct {
@@ -29,7 +30,7 @@ type receiveStream struct {
 
 	sender streamSender
 
-	frameQueue  *frameSorter
+	frameQueue  frameSorter
 	finalOffset protocol.ByteCount
 
 	currentFrame       []byte
@@ -42,11 +43,14 @@ type receiveStream struct {
 	cancelReadErr       error
 	resetRemotelyErr    *StreamError
 
-	readChan chan struct{}
-	readOnce chan struct{} // cap: 1, to protect against concurrent use of Read
-	deadline time.Time
+	readChan  chan struct{} // created when Read first needs to wait, cap: 1
+	readMutex sync.Mutex    // protects against concurrent use of Read
+	deadline  time.Time
 
-	flowController flowcontrol.StreamFlowController
+	// the byte ranges received in 0-RTT packets, sorted and non-overlapping
+	earlyData []EarlyDataRange
+
+	flowController *lazyFlowController
 }
 
 var (
@@ -57,15 +61,12 @@ var (
 func newReceiveStream(
 	streamID protocol.StreamID,
 	sender streamSender,
-	flowController flowcontrol.StreamFlowController,
+	flowController *lazyFlowController,
 ) *receiveStream {
 	return &receiveStream{
 		streamID:       streamID,
 		sender:         sender,
 		flowController: flowController,
-		frameQueue:     newFrameSorter(),
-		readChan:       make(chan struct{}, 1),
-		readOnce:       make(chan struct{}, 1),
 		finalOffset:    protocol.MaxByteCount,
 	}
 }
@@ -79,8 +80,8 @@ func (s *receiveStream) Read(p []byte) (int, error) {
 	// Concurrent use of Read is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
 	// Make sure that we only execute one call at any given time to avoid hard to debug failures.
-	s.readOnce <- struct{}{}
-	defer func() { <-s.readOnce }()
+	s.readMutex.Lock()
+	defer s.readMutex.Unlock()
 
 	s.mutex.Lock()
 	completed, n, err := s.readImpl(p)
@@ -144,12 +145,17 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 				break
 			}
 
+			// Most streams are never blocked on, so the channel is only allocated when it's needed.
+			if s.readChan == nil {
+				s.readChan = make(chan struct{}, 1)
+			}
+			readChan := s.readChan
 			s.mutex.Unlock()
 			if deadline.IsZero() {
-				<-s.readChan
+				<-readChan
 			} else {
 				select {
-				case <-s.readChan:
+				case <-readChan:
 				case <-deadlineTimer.Chan():
 					deadlineTimer.SetRead()
 				}
@@ -176,7 +182,7 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		if s.resetRemotelyErr == nil {
 			// 告诉流控又有多少steam又consumend多少data。
 			// 可能触发接收窗口的更新。
-			s.flowController.AddBytesRead(protocol.ByteCount(m))
+			s.flowController.get().AddBytesRead(protocol.ByteCount(m))
 		}
 
 		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
@@ -185,6 +191,7 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 			if s.currentFrameDone != nil {
 				s.currentFrameDone()
 			}
+			s.frameQueue.Discard()
 			return true, bytesRead, io.EOF
 		}
 	}
@@ -209,7 +216,7 @@ func (s *receiveStream) CancelRead(errorCode StreamErrorCode) {
 	s.mutex.Unlock()
 
 	if completed {
-		s.flowController.Abandon()
+		s.flowController.get().Abandon()
 		s.sender.onStreamCompleted(s.streamID)
 	}
 }
@@ -219,6 +226,7 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 		return false
 	}
 	s.cancelReadErr = &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: false}
+	s.frameQueue.Discard()
 	s.signalRead()
 	s.sender.queueControlFrame(&wire.StopSendingFrame{
 		StreamID:  s.streamID,
@@ -235,7 +243,7 @@ func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
 
 	// 抛弃所有未读的帧，提前终止读取
 	if completed {
-		s.flowController.Abandon()
+		s.flowController.get().Abandon()
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return err
@@ -244,7 +252,7 @@ func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
 func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /* completed */, error) {
 	maxOffset := frame.Offset + frame.DataLen()
 	// 增大流控的最大值
-	if err := s.flowController.UpdateHighestReceived(maxOffset, frame.Fin); err != nil {
+	if err := s.flowController.get().UpdateHighestReceived(maxOffset, frame.Fin); err != nil {
 		return false, err
 	}
 	var newlyRcvdFinalOffset bool
@@ -252,7 +260,8 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 		newlyRcvdFinalOffset = s.finalOffset == protocol.MaxByteCount
 		s.finalOffset = maxOffset
 	}
-	if s.cancelReadErr != nil {
+	// After the FIN was read, frames can only contain data that was already read.
+	if s.finRead || s.cancelReadErr != nil || s.resetRemotelyErr != nil || s.closeForShutdownErr != nil {
 		return newlyRcvdFinalOffset, nil
 	}
 	if err := s.frameQueue.Push(frame.Data, frame.Offset, frame.PutBack); err != nil {
@@ -262,13 +271,46 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 	return false, nil
 }
 
+// receivedEarlyData records that the data from start to end was received in a 0-RTT packet.
+func (s *receiveStream) receivedEarlyData(start, end protocol.ByteCount) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := EarlyDataRange{Start: uint64(start), End: uint64(end)}
+	// find the first range that the new range overlaps with, or is adjacent to
+	i := 0
+	for i < len(s.earlyData) && s.earlyData[i].End < r.Start {
+		i++
+	}
+	// merge all ranges that overlap with the new range
+	j := i
+	for j < len(s.earlyData) && s.earlyData[j].Start <= r.End {
+		r.Start = utils.Min(r.Start, s.earlyData[j].Start)
+		r.End = utils.Max(r.End, s.earlyData[j].End)
+		j++
+	}
+	s.earlyData = append(s.earlyData[:i], append([]EarlyDataRange{r}, s.earlyData[j:]...)...)
+}
+
+func (s *receiveStream) EarlyData() []EarlyDataRange {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.earlyData) == 0 {
+		return nil
+	}
+	ranges := make([]EarlyDataRange, len(s.earlyData))
+	copy(ranges, s.earlyData)
+	return ranges
+}
+
 func (s *receiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	s.mutex.Lock()
 	completed, err := s.handleResetStreamFrameImpl(frame)
 	s.mutex.Unlock()
 
 	if completed {
-		s.flowController.Abandon()
+		s.flowController.get().Abandon()
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return err
@@ -278,7 +320,7 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 	if s.closeForShutdownErr != nil {
 		return false, nil
 	}
-	if err := s.flowController.UpdateHighestReceived(frame.FinalSize, true); err != nil {
+	if err := s.flowController.get().UpdateHighestReceived(frame.FinalSize, true); err != nil {
 		return false, err
 	}
 	newlyRcvdFinalOffset := s.finalOffset == protocol.MaxByteCount
@@ -293,6 +335,7 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 		ErrorCode: frame.ErrorCode,
 		Remote:    true,
 	}
+	s.frameQueue.Discard()
 	s.signalRead()
 	return newlyRcvdFinalOffset, nil
 }
@@ -304,8 +347,8 @@ func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
 func (s *receiveStream) SetReadDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
-	s.mutex.Unlock()
 	s.signalRead()
+	s.mutex.Unlock()
 	return nil
 }
 
@@ -315,15 +358,24 @@ func (s *receiveStream) SetReadDeadline(t time.Time) error {
 func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closeForShutdownErr = err
-	s.mutex.Unlock()
+	s.frameQueue.Discard()
 	s.signalRead()
+	s.mutex.Unlock()
 }
 
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
-	return s.flowController.GetWindowUpdate()
+	return s.flowController.get().GetWindowUpdate()
+}
+
+// receiveBufferedBytes returns the number of bytes of received data that haven't been read by the application yet.
+func (s *receiveStream) receiveBufferedBytes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.frameQueue.bufferedBytes + len(s.currentFrame) - s.readPosInFrame
 }
 
-// signalRead performs a non-blocking send on the readChan
+// signalRead performs a non-blocking send on the readChan.
+// It must be called with the mutex held.
 func (s *receiveStream) signalRead() {
 	select {
 	case s.readChan <- struct{}{}:
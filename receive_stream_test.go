@@ -31,7 +31,7 @@ var _ = Describe("Receive Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newReceiveStream(streamID, mockSender, mockFC)
+		str = newReceiveStream(streamID, mockSender, &lazyFlowController{flowController: mockFC})
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutReader(str, timeout)
@@ -41,6 +41,40 @@ var _ = Describe("Receive Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	Context("early data", func() {
+		It("doesn't report any early data by default", func() {
+			Expect(str.EarlyData()).To(BeNil())
+		})
+
+		It("records the ranges received in 0-RTT", func() {
+			str.receivedEarlyData(10, 20)
+			str.receivedEarlyData(30, 40)
+			str.receivedEarlyData(0, 5)
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 0, End: 5}, {Start: 10, End: 20}, {Start: 30, End: 40}}))
+		})
+
+		It("merges overlapping and adjacent ranges", func() {
+			str.receivedEarlyData(10, 20)
+			str.receivedEarlyData(30, 40)
+			str.receivedEarlyData(15, 25)
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 10, End: 25}, {Start: 30, End: 40}}))
+			str.receivedEarlyData(25, 30)
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 10, End: 40}}))
+			str.receivedEarlyData(0, 50)
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 0, End: 50}}))
+			// duplicates don't change anything
+			str.receivedEarlyData(20, 30)
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 0, End: 50}}))
+		})
+
+		It("returns a copy of the ranges", func() {
+			str.receivedEarlyData(10, 20)
+			ranges := str.EarlyData()
+			ranges[0].End = 100
+			Expect(str.EarlyData()).To(Equal([]EarlyDataRange{{Start: 10, End: 20}}))
+		})
+	})
+
 	Context("reading", func() {
 		It("reads a single STREAM frame", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
@@ -58,6 +92,35 @@ var _ = Describe("Receive Stream", func() {
 			Expect(b).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
 		})
 
+		It("only allocates the read channel once Read blocks", func() {
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD}})).To(Succeed())
+			b := make([]byte, 2)
+			_, err := strWithTimeout.Read(b)
+			Expect(err).ToNot(HaveOccurred())
+			str.mutex.Lock()
+			Expect(str.readChan).To(BeNil())
+			str.mutex.Unlock()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xBE, 0xEF}))
+			}()
+			Eventually(func() chan struct{} {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return str.readChan
+			}).ShouldNot(BeNil())
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xBE, 0xEF}})).To(Succeed())
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("reads a single STREAM frame in multiple goes", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
 			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
@@ -346,6 +409,20 @@ var _ = Describe("Receive Stream", func() {
 					Expect(err).To(MatchError(io.EOF))
 				})
 
+				It("releases the frame sorter after reading the FIN", func() {
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true).Times(2)
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})).To(Succeed())
+					Expect(str.frameQueue.gaps).ToNot(BeNil())
+					mockSender.EXPECT().onStreamCompleted(streamID)
+					_, err := strWithTimeout.Read(make([]byte, 4))
+					Expect(err).To(MatchError(io.EOF))
+					Expect(str.frameQueue.gaps).To(BeNil())
+					// retransmissions are ignored
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})).To(Succeed())
+					Expect(str.frameQueue.gaps).To(BeNil())
+				})
+
 				It("handles out-of-order frames", func() {
 					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
 					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
@@ -474,6 +551,14 @@ var _ = Describe("Receive Stream", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("discards buffered data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("foobar")})).To(Succeed())
+				Expect(str.frameQueue.bufferedBytes).To(Equal(6))
+				str.closeForShutdown(testErr)
+				Expect(str.frameQueue.bufferedBytes).To(BeZero())
+			})
+
 			It("errors for all following reads", func() {
 				str.closeForShutdown(testErr)
 				b := make([]byte, 1)
@@ -652,6 +737,21 @@ var _ = Describe("Receive Stream", func() {
 				}))
 			})
 
+			It("discards buffered data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("foobar")})).To(Succeed())
+				Expect(str.frameQueue.bufferedBytes).To(Equal(6))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				Expect(str.frameQueue.bufferedBytes).To(BeZero())
+				// STREAM frames received after the RESET_STREAM frame are not buffered
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(20), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 14, Data: []byte("foobar")})).To(Succeed())
+				Expect(str.frameQueue.bufferedBytes).To(BeZero())
+			})
+
 			It("errors when receiving a RESET_STREAM with an inconsistent offset", func() {
 				testErr := errors.New("already received a different final offset before")
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Return(testErr)
@@ -176,5 +176,8 @@ type retransmissionQueueAppDataAckHandler retransmissionQueue
 
 func (q *retransmissionQueueAppDataAckHandler) OnAcked(wire.Frame) {}
 func (q *retransmissionQueueAppDataAckHandler) OnLost(f wire.Frame) {
+	if ef, ok := f.(*wire.ExtensionFrame); ok && !ef.RetransmitOnLoss {
+		return
+	}
 	(*retransmissionQueue)(q).addAppData(f)
 }
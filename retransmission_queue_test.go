@@ -215,6 +215,15 @@ var _ = Describe("Retransmission queue", func() {
 			Expect(q.GetAppDataFrame(protocol.MaxByteCount, protocol.Version1)).To(Equal(f))
 		})
 
+		It("only retransmits extension frames that are retransmitted on loss", func() {
+			q.AppDataAckHandler().OnLost(&wire.ExtensionFrame{FrameType: 0x42, Data: []byte("foo")})
+			Expect(q.HasAppData()).To(BeFalse())
+			f := &wire.ExtensionFrame{FrameType: 0x42, Data: []byte("bar"), RetransmitOnLoss: true}
+			q.AppDataAckHandler().OnLost(f)
+			Expect(q.HasAppData()).To(BeTrue())
+			Expect(q.GetAppDataFrame(protocol.MaxByteCount, protocol.Version1)).To(Equal(f))
+		})
+
 		It("adds a PING", func() {
 			q.AddPing(protocol.Encryption1RTT)
 			Expect(q.HasAppData()).To(BeTrue())
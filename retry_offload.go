@@ -0,0 +1,42 @@
+package quic
+
+import (
+	"errors"
+	"net"
+
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// NewRetryToken creates a Retry token for a client at remoteAddr.
+// It allows offloading the sending of Retry packets to an external frontend:
+// Tokens created using the same TokenGeneratorKey as configured on the Transport are accepted by
+// the server, the same way as Retry tokens created by the server itself.
+// The origDestConnID is the Destination Connection ID chosen by the client for its first Initial packet,
+// and retrySrcConnID the Source Connection ID used on the Retry packet.
+func NewRetryToken(key TokenGeneratorKey, remoteAddr net.Addr, origDestConnID, retrySrcConnID ConnectionID) ([]byte, error) {
+	return handshake.NewTokenGenerator(key).NewRetryToken(remoteAddr, origDestConnID, retrySrcConnID)
+}
+
+// AppendRetryPacket appends a Retry packet (including the Retry integrity tag) to b.
+// The destConnID is the Source Connection ID of the client's Initial packet,
+// and origDestConnID the Destination Connection ID of that packet.
+func AppendRetryPacket(b []byte, v VersionNumber, destConnID, srcConnID, origDestConnID ConnectionID, token []byte) ([]byte, error) {
+	if len(token) == 0 {
+		return nil, errors.New("quic: Retry packet requires a token")
+	}
+	hdr := &wire.ExtendedHeader{}
+	hdr.Type = protocol.PacketTypeRetry
+	hdr.Version = v
+	hdr.SrcConnectionID = srcConnID
+	hdr.DestConnectionID = destConnID
+	hdr.Token = token
+	start := len(b)
+	b, err := hdr.Append(b, v)
+	if err != nil {
+		return nil, err
+	}
+	tag := handshake.GetRetryIntegrityTag(b[start:], origDestConnID, v)
+	return append(b, tag[:]...), nil
+}
@@ -0,0 +1,60 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry offloading", func() {
+	It("creates Retry tokens that can be decoded using the same key", func() {
+		var key TokenGeneratorKey
+		key[0] = 42
+		raddr := &net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}
+		origDestConnID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+		retrySrcConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		token, err := NewRetryToken(key, raddr, origDestConnID, retrySrcConnID)
+		Expect(err).ToNot(HaveOccurred())
+
+		tok, err := handshake.NewTokenGenerator(key).DecodeToken(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tok.IsRetryToken).To(BeTrue())
+		Expect(tok.ValidateRemoteAddr(raddr)).To(BeTrue())
+		Expect(tok.OriginalDestConnectionID).To(Equal(origDestConnID))
+		Expect(tok.RetrySrcConnectionID).To(Equal(retrySrcConnID))
+
+		var otherKey TokenGeneratorKey
+		_, err = handshake.NewTokenGenerator(otherKey).DecodeToken(token)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("appends Retry packets", func() {
+		destConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		srcConnID := protocol.ParseConnectionID([]byte{5, 6, 7, 8, 9, 10, 11, 12})
+		origDestConnID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+		b, err := AppendRetryPacket([]byte("foo"), protocol.Version1, destConnID, srcConnID, origDestConnID, []byte("token"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:3]).To(Equal([]byte("foo")))
+		b = b[3:]
+		hdr, _, rest, err := wire.ParsePacket(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rest).To(BeEmpty())
+		Expect(hdr.Type).To(Equal(protocol.PacketTypeRetry))
+		Expect(hdr.Version).To(Equal(protocol.Version1))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+		Expect(hdr.Token).To(Equal([]byte("token")))
+		tag := handshake.GetRetryIntegrityTag(b[:len(b)-16], origDestConnID, protocol.Version1)
+		Expect(b[len(b)-16:]).To(Equal(tag[:]))
+	})
+
+	It("refuses to create Retry packets without a token", func() {
+		_, err := AppendRetryPacket(nil, protocol.Version1, protocol.ConnectionID{}, protocol.ConnectionID{}, protocol.ConnectionID{}, nil)
+		Expect(err).To(MatchError("quic: Retry packet requires a token"))
+	})
+})
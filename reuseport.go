@@ -0,0 +1,278 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"runtime"
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// ReusePortConfig configures a ReusePortGroup.
+type ReusePortConfig struct {
+	// NumSockets is the number of UDP sockets.
+	// It must not be larger than 256.
+	// If unset, one socket per CPU is used.
+	NumSockets int
+
+	// The length of the connection IDs issued by the server.
+	// It can be any value between 4 and 18.
+	// If unset, a 4 byte connection ID will be used.
+	ConnectionIDLength int
+
+	// The StatelessResetKey is used to generate stateless reset tokens, see Transport.StatelessResetKey.
+	// It is shared by all sockets of the group.
+	StatelessResetKey *StatelessResetKey
+
+	// The TokenGeneratorKey is used to encrypt session resumption tokens, see Transport.TokenGeneratorKey.
+	// It is shared by all sockets of the group.
+	// If no key is configured, a random key will be generated.
+	TokenGeneratorKey *TokenGeneratorKey
+
+	// A Tracer traces events that don't belong to a single QUIC connection.
+	Tracer *logging.Tracer
+}
+
+// A ReusePortGroup runs a server on multiple UDP sockets, which are bound to the same address using SO_REUSEPORT.
+// The kernel distributes incoming packets across these sockets based on the 4-tuple,
+// which allows receiving and processing packets on multiple cores in parallel.
+//
+// When a client migrates to a new path, the 4-tuple changes, and its packets might arrive on a different socket.
+// To route these packets to the correct connection, the index of the socket is encoded into all connection IDs
+// issued by the server: the first byte of the connection ID modulo the number of sockets is the index of the socket.
+// Packets that arrive on the wrong socket are rerouted in userspace.
+// The same rule can be used to steer packets in the kernel, using an eBPF program attached with SO_ATTACH_REUSEPORT_EBPF.
+//
+// SO_REUSEPORT is only supported on Linux, macOS and FreeBSD.
+type ReusePortGroup struct {
+	transports []*Transport
+}
+
+// NewReusePortGroup creates the UDP sockets bound to addr, and a Transport for each of them.
+// If the port of addr is 0, all sockets are bound to the port chosen for the first socket.
+func NewReusePortGroup(addr string, config *ReusePortConfig) (*ReusePortGroup, error) {
+	if config == nil {
+		config = &ReusePortConfig{}
+	}
+	numSockets := config.NumSockets
+	if numSockets == 0 {
+		numSockets = runtime.NumCPU()
+	}
+	if numSockets < 0 || numSockets > 256 {
+		return nil, fmt.Errorf("invalid number of sockets: %d", numSockets)
+	}
+	connIDLen := config.ConnectionIDLength
+	if connIDLen == 0 {
+		connIDLen = protocol.DefaultConnectionIDLength
+	}
+	if connIDLen < 4 || connIDLen > 18 {
+		return nil, fmt.Errorf("invalid connection ID length: %d", connIDLen)
+	}
+	tokenGeneratorKey := config.TokenGeneratorKey
+	if tokenGeneratorKey == nil {
+		var key TokenGeneratorKey
+		if _, err := rand.Read(key[:]); err != nil {
+			return nil, err
+		}
+		tokenGeneratorKey = &key
+	}
+
+	g := &ReusePortGroup{transports: make([]*Transport, 0, numSockets)}
+	for i := 0; i < numSockets; i++ {
+		conn, err := listenUDPReusePort(addr)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		if i == 0 {
+			// make sure all sockets are bound to the same port
+			addr = conn.LocalAddr().String()
+		}
+		g.transports = append(g.transports, &Transport{
+			Conn: conn,
+			ConnectionIDGenerator: &socketIndexConnIDGenerator{
+				index:      uint8(i),
+				numSockets: numSockets,
+				connIDLen:  connIDLen,
+			},
+			StatelessResetKey: config.StatelessResetKey,
+			TokenGeneratorKey: tokenGeneratorKey,
+			Tracer:            config.Tracer,
+			createdConn:       true,
+			reroute:           g.reroute,
+		})
+	}
+	// Initialize all Transports before any of them reroutes packets to another one.
+	for _, t := range g.transports {
+		if err := t.init(false); err != nil {
+			g.Close()
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// Transports returns the Transports, one for every socket.
+// They can be used to dial new connections.
+func (g *ReusePortGroup) Transports() []*Transport {
+	return g.transports
+}
+
+// Addr returns the local network address that all sockets are bound to.
+func (g *ReusePortGroup) Addr() net.Addr {
+	return g.transports[0].Conn.LocalAddr()
+}
+
+// Listen starts listening for incoming QUIC connections on all sockets.
+// There can only be a single listener per group.
+func (g *ReusePortGroup) Listen(tlsConf *tls.Config, conf *Config) (*ReusePortListener, error) {
+	gl, err := listenGroup(g.transports, g.Addr(), tlsConf, conf)
+	if err != nil {
+		return nil, err
+	}
+	return &ReusePortListener{groupListener: gl}, nil
+}
+
+// Close closes all sockets.
+func (g *ReusePortGroup) Close() error {
+	var firstErr error
+	for _, t := range g.transports {
+		if err := t.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// reroute routes a short header packet that was received on the wrong socket
+// to the connection on the socket encoded in the connection ID.
+func (g *ReusePortGroup) reroute(p receivedPacket, connID protocol.ConnectionID) bool {
+	return rerouteBySocketIndex(g.transports, p, connID)
+}
+
+// rerouteBySocketIndex routes a short header packet to the connection on the Transport
+// whose index is encoded in the connection ID, see socketIndexConnIDGenerator.
+func rerouteBySocketIndex(transports []*Transport, p receivedPacket, connID protocol.ConnectionID) bool {
+	if connID.Len() == 0 {
+		return false
+	}
+	t := transports[int(connID.Bytes()[0])%len(transports)]
+	handler, ok := t.handlerMap.Get(connID)
+	if !ok {
+		return false
+	}
+	handler.handlePacket(p)
+	return true
+}
+
+// A ReusePortListener accepts connections on all sockets of a ReusePortGroup.
+type ReusePortListener struct {
+	*groupListener
+}
+
+// A groupListener accepts connections on all Transports of a group.
+type groupListener struct {
+	addr      net.Addr
+	listeners []*Listener
+	conns     chan Connection
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+func listenGroup(transports []*Transport, addr net.Addr, tlsConf *tls.Config, conf *Config) (*groupListener, error) {
+	l := &groupListener{
+		addr:      addr,
+		conns:     make(chan Connection),
+		closeChan: make(chan struct{}),
+	}
+	for _, t := range transports {
+		ln, err := t.Listen(tlsConf, conf)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		l.listeners = append(l.listeners, ln)
+	}
+	for _, ln := range l.listeners {
+		go l.acceptLoop(ln)
+	}
+	return l, nil
+}
+
+func (l *groupListener) acceptLoop(ln *Listener) {
+	for {
+		conn, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		select {
+		case l.conns <- conn:
+		case <-l.closeChan:
+			conn.CloseWithError(0, "")
+			return
+		}
+	}
+}
+
+// Accept returns new connections. It should be called in a loop.
+func (l *groupListener) Accept(ctx context.Context) (Connection, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closeChan:
+		return nil, ErrServerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close closes the listeners on all sockets. All active connections will be closed.
+func (l *groupListener) Close() error {
+	var firstErr error
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+		for _, ln := range l.listeners {
+			if err := ln.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	})
+	return firstErr
+}
+
+// Addr returns the local network address that the listener is listening on.
+func (l *groupListener) Addr() net.Addr {
+	return l.addr
+}
+
+// The socketIndexConnIDGenerator generates random connection IDs that encode the index of the socket:
+// The first byte modulo the number of sockets is the index.
+type socketIndexConnIDGenerator struct {
+	index      uint8
+	numSockets int
+	connIDLen  int
+}
+
+var _ ConnectionIDGenerator = &socketIndexConnIDGenerator{}
+
+func (g *socketIndexConnIDGenerator) GenerateConnectionID() (ConnectionID, error) {
+	b := make([]byte, g.connIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return ConnectionID{}, err
+	}
+	// Keep the random bits above the index, so that the first byte doesn't have a fixed value.
+	first := int(b[0]) - int(b[0])%g.numSockets + int(g.index)
+	if first > 255 {
+		first -= g.numSockets
+	}
+	b[0] = byte(first)
+	return protocol.ParseConnectionID(b), nil
+}
+
+func (g *socketIndexConnIDGenerator) ConnectionIDLen() int { return g.connIDLen }
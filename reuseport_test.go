@@ -0,0 +1,242 @@
+//go:build darwin || linux || freebsd
+
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+// rebindingConn simulates a NAT rebinding:
+// after calling rebind, packets are sent from a new UDP socket.
+// Packets are read from all sockets.
+type rebindingConn struct {
+	mutex sync.Mutex
+	conns []*net.UDPConn
+
+	packets   chan rebindingConnPacket
+	closeChan chan struct{}
+}
+
+type rebindingConnPacket struct {
+	data []byte
+	addr net.Addr
+}
+
+var _ net.PacketConn = &rebindingConn{}
+
+func newRebindingConn() *rebindingConn {
+	c := &rebindingConn{
+		packets:   make(chan rebindingConnPacket, 1000),
+		closeChan: make(chan struct{}),
+	}
+	c.rebind()
+	return c
+}
+
+func (c *rebindingConn) rebind() {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+	Expect(err).ToNot(HaveOccurred())
+	go func() {
+		for {
+			b := make([]byte, protocol.MaxPacketBufferSize)
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			select {
+			case c.packets <- rebindingConnPacket{data: b[:n], addr: addr}:
+			case <-c.closeChan:
+				return
+			}
+		}
+	}()
+	c.mutex.Lock()
+	c.conns = append(c.conns, conn)
+	c.mutex.Unlock()
+}
+
+func (c *rebindingConn) current() *net.UDPConn {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.conns[len(c.conns)-1]
+}
+
+func (c *rebindingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.packets:
+		return copy(b, p.data), p.addr, nil
+	case <-c.closeChan:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (c *rebindingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.current().WriteTo(b, addr)
+}
+
+func (c *rebindingConn) Close() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+	close(c.closeChan)
+	return nil
+}
+
+func (c *rebindingConn) LocalAddr() net.Addr              { return c.current().LocalAddr() }
+func (c *rebindingConn) SetDeadline(time.Time) error      { return nil }
+func (c *rebindingConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *rebindingConn) SetWriteDeadline(time.Time) error { return nil }
+
+var _ = Describe("SO_REUSEPORT", func() {
+	It("encodes the socket index in the connection ID", func() {
+		for _, numSockets := range []int{1, 3, 4, 7, 256} {
+			for index := 0; index < numSockets; index++ {
+				g := &socketIndexConnIDGenerator{index: uint8(index), numSockets: numSockets, connIDLen: 6}
+				Expect(g.ConnectionIDLen()).To(Equal(6))
+				firstBytes := make(map[byte]struct{})
+				for i := 0; i < 100; i++ {
+					connID, err := g.GenerateConnectionID()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(connID.Len()).To(Equal(6))
+					Expect(int(connID.Bytes()[0]) % numSockets).To(Equal(index))
+					firstBytes[connID.Bytes()[0]] = struct{}{}
+				}
+				if numSockets < 128 {
+					Expect(len(firstBytes)).To(BeNumerically(">", 1))
+				}
+			}
+		}
+	})
+
+	It("reroutes packets to the socket encoded in the connection ID", func() {
+		phm0 := NewMockPacketHandlerManager(mockCtrl)
+		phm1 := NewMockPacketHandlerManager(mockCtrl)
+		g := &ReusePortGroup{transports: []*Transport{{handlerMap: phm0}, {handlerMap: phm1}}}
+
+		handler := NewMockPacketHandler(mockCtrl)
+		connID := protocol.ParseConnectionID([]byte{0x43, 1, 2, 3})
+		p := receivedPacket{data: []byte("foobar")}
+		phm1.EXPECT().Get(connID).Return(handler, true)
+		handler.EXPECT().handlePacket(p)
+		Expect(g.reroute(p, connID)).To(BeTrue())
+
+		// unknown connection ID
+		connID = protocol.ParseConnectionID([]byte{0x42, 1, 2, 3})
+		phm0.EXPECT().Get(connID).Return(nil, false)
+		Expect(g.reroute(p, connID)).To(BeFalse())
+	})
+
+	It("rejects invalid configurations", func() {
+		_, err := NewReusePortGroup("127.0.0.1:0", &ReusePortConfig{NumSockets: 257})
+		Expect(err).To(MatchError("invalid number of sockets: 257"))
+		_, err = NewReusePortGroup("127.0.0.1:0", &ReusePortConfig{ConnectionIDLength: 2})
+		Expect(err).To(MatchError("invalid connection ID length: 2"))
+	})
+
+	It("binds all sockets to the same address", func() {
+		g, err := NewReusePortGroup("127.0.0.1:0", &ReusePortConfig{NumSockets: 4})
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		Expect(g.Transports()).To(HaveLen(4))
+		for _, t := range g.Transports() {
+			Expect(t.Conn.LocalAddr()).To(Equal(g.Addr()))
+		}
+	})
+
+	It("accepts connections and reroutes packets after a NAT rebinding", func() {
+		g, err := NewReusePortGroup("127.0.0.1:0", &ReusePortConfig{NumSockets: 4})
+		Expect(err).ToNot(HaveOccurred())
+		defer g.Close()
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"reuseport"}
+		ln, err := g.Listen(tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		// echo all data sent on the first stream
+		go func() {
+			defer GinkgoRecover()
+			for {
+				conn, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					defer GinkgoRecover()
+					str, err := conn.AcceptStream(context.Background())
+					if err != nil {
+						return
+					}
+					io.Copy(str, str)
+				}()
+			}
+		}()
+
+		rebindingConn := newRebindingConn()
+		tr := &Transport{Conn: rebindingConn}
+		defer tr.Close()
+		defer rebindingConn.Close()
+		conn, err := tr.Dial(
+			context.Background(),
+			g.Addr(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"reuseport"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		str.SetDeadline(time.Now().Add(5 * time.Second))
+
+		// With 4 sockets, it's very unlikely that all new source ports map to the original socket.
+		for i := 0; i < 10; i++ {
+			if i > 0 {
+				rebindingConn.rebind()
+			}
+			msg := []byte("message " + string(rune('a'+i)))
+			_, err := str.Write(msg)
+			Expect(err).ToNot(HaveOccurred())
+			b := make([]byte, len(msg))
+			_, err = io.ReadFull(str, b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(Equal(msg))
+		}
+	})
+
+	It("reroutes short header packets with an unknown connection ID", func() {
+		phm := NewMockPacketHandlerManager(mockCtrl)
+		rerouted := make(chan protocol.ConnectionID, 1)
+		tr := &Transport{
+			handlerMap:        phm,
+			connIDLen:         4,
+			shortHdrConnIDLen: fixedConnIDLen(4),
+			reroute: func(_ receivedPacket, connID protocol.ConnectionID) bool {
+				rerouted <- connID
+				return true
+			},
+		}
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
+		b, err := wire.AppendShortHeader(nil, connID, 1337, protocol.PacketNumberLen2, protocol.KeyPhaseOne)
+		Expect(err).ToNot(HaveOccurred())
+		b = append(b, make([]byte, 20)...)
+		phm.EXPECT().GetByResetToken(gomock.Any())
+		phm.EXPECT().Get(connID)
+		tr.handlePacket(receivedPacket{data: b, buffer: getPacketBuffer()})
+		Expect(rerouted).To(Receive(Equal(connID)))
+	})
+})
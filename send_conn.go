@@ -2,9 +2,11 @@ package quic
 
 import (
 	"net"
+	"sync/atomic"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
@@ -15,6 +17,8 @@ type sendConn interface {
 	RemoteAddr() net.Addr
 
 	capabilities() connCapabilities
+	// disableGSO is called when it was detected that GSO doesn't work.
+	disableGSO(logging.GSODisabledReason)
 }
 
 type sconn struct {
@@ -27,14 +31,15 @@ type sconn struct {
 
 	packetInfoOOB []byte
 	// If GSO enabled, and we receive a GSO error for this remote address, GSO is disabled.
-	gotGSOError bool
+	// GSO is also disabled for all other connections using the same rawConn, if the rawConn supports that.
+	gsoDisabled atomic.Bool
 }
 
 var _ sendConn = &sconn{}
 
 func newSendConn(c rawConn, remote net.Addr, info packetInfo, logger utils.Logger) *sconn {
 	localAddr := c.LocalAddr()
-	if info.addr.IsValid() {
+	if info.addr.IsValid() && !info.addr.IsUnspecified() {
 		if udpAddr, ok := localAddr.(*net.UDPAddr); ok {
 			addrCopy := *udpAddr
 			addrCopy.IP = info.addr.AsSlice()
@@ -55,11 +60,19 @@ func newSendConn(c rawConn, remote net.Addr, info packetInfo, logger utils.Logge
 	}
 }
 
+// socketBufferTuner returns the socketBufferTuner of the underlying rawConn, if any.
+func (c *sconn) socketBufferTuner() *socketBufferTuner {
+	if t, ok := c.rawConn.(interface{ socketBufferTuner() *socketBufferTuner }); ok {
+		return t.socketBufferTuner()
+	}
+	return nil
+}
+
 func (c *sconn) Write(p []byte, gsoSize uint16, ecn protocol.ECN) error {
 	_, err := c.WritePacket(p, c.remoteAddr, c.packetInfoOOB, gsoSize, ecn)
 	if err != nil && isGSOError(err) {
 		// disable GSO for future calls
-		c.gotGSOError = true
+		c.disableGSO(logging.GSODisabledSendError)
 		if c.logger.Debug() {
 			c.logger.Debugf("GSO failed when sending to %s", c.remoteAddr)
 		}
@@ -82,10 +95,19 @@ func (c *sconn) Write(p []byte, gsoSize uint16, ecn protocol.ECN) error {
 func (c *sconn) capabilities() connCapabilities {
 	capabilities := c.rawConn.capabilities()
 	if capabilities.GSO {
-		capabilities.GSO = !c.gotGSOError
+		capabilities.GSO = !c.gsoDisabled.Load()
 	}
 	return capabilities
 }
 
+func (c *sconn) disableGSO(reason logging.GSODisabledReason) {
+	c.gsoDisabled.Store(true)
+	if d, ok := c.rawConn.(interface {
+		disableGSO(logging.GSODisabledReason)
+	}); ok {
+		d.disableGSO(reason)
+	}
+}
+
 func (c *sconn) RemoteAddr() net.Addr { return c.remoteAddr }
 func (c *sconn) LocalAddr() net.Addr  { return c.localAddr }
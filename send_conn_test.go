@@ -7,6 +7,7 @@ import (
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -75,5 +76,24 @@ var _ = Describe("Connection (for sending packets)", func() {
 			Expect(c.Write([]byte("foobar"), 4, protocol.ECNCE)).To(Succeed())
 			Expect(c.capabilities().GSO).To(BeFalse())
 		})
+
+		It("disables GSO for all connections using the same rawConn if sending fails", func() {
+			rawConn := NewMockRawConn(mockCtrl)
+			rawConn.EXPECT().LocalAddr().Times(2)
+			rawConn.EXPECT().capabilities().Return(connCapabilities{GSO: true}).AnyTimes()
+			var reasons []logging.GSODisabledReason
+			gsoConn := newGSOFallbackConn(rawConn, func(r logging.GSODisabledReason) { reasons = append(reasons, r) })
+			c1 := newSendConn(gsoConn, remoteAddr, packetInfo{}, utils.DefaultLogger)
+			c2 := newSendConn(gsoConn, &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 1337}, packetInfo{}, utils.DefaultLogger)
+			Expect(c2.capabilities().GSO).To(BeTrue())
+			gomock.InOrder(
+				rawConn.EXPECT().WritePacket([]byte("foobar"), remoteAddr, gomock.Any(), uint16(3), protocol.ECNCE).Return(0, errGSO),
+				rawConn.EXPECT().WritePacket([]byte("foo"), remoteAddr, gomock.Any(), uint16(0), protocol.ECNCE).Return(3, nil),
+				rawConn.EXPECT().WritePacket([]byte("bar"), remoteAddr, gomock.Any(), uint16(0), protocol.ECNCE).Return(3, nil),
+			)
+			Expect(c1.Write([]byte("foobar"), 3, protocol.ECNCE)).To(Succeed())
+			Expect(c2.capabilities().GSO).To(BeFalse())
+			Expect(reasons).To(Equal([]logging.GSODisabledReason{logging.GSODisabledSendError}))
+		})
 	}
 })
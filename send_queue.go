@@ -1,6 +1,10 @@
 package quic
 
-import "github.com/quic-go/quic-go/internal/protocol"
+import (
+	"sync/atomic"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
 
 type sender interface {
 	Send(p *packetBuffer, gsoSize uint16, ecn protocol.ECN)
@@ -8,6 +12,8 @@ type sender interface {
 	WouldBlock() bool
 	Available() <-chan struct{}
 	Close()
+	// QueuedBytes returns the size of the buffers of the packets that are queued for sending.
+	QueuedBytes() int
 }
 
 type queueEntry struct {
@@ -22,6 +28,8 @@ type sendQueue struct {
 	runStopped  chan struct{} // runStopped when the run loop returns
 	available   chan struct{}
 	conn        sendConn
+
+	queuedBytes atomic.Int64
 }
 
 var _ sender = &sendQueue{}
@@ -44,6 +52,7 @@ func newSendQueue(conn sendConn) sender {
 func (h *sendQueue) Send(p *packetBuffer, gsoSize uint16, ecn protocol.ECN) {
 	select {
 	case h.queue <- queueEntry{buf: p, gsoSize: gsoSize, ecn: ecn}:
+		h.queuedBytes.Add(int64(cap(p.Data)))
 		// clear available channel if we've reached capacity
 		if len(h.queue) == sendQueueCapacity {
 			select {
@@ -65,6 +74,10 @@ func (h *sendQueue) Available() <-chan struct{} {
 	return h.available
 }
 
+func (h *sendQueue) QueuedBytes() int {
+	return int(h.queuedBytes.Load())
+}
+
 func (h *sendQueue) Run() error {
 	defer close(h.runStopped)
 	var shouldClose bool
@@ -87,6 +100,7 @@ func (h *sendQueue) Run() error {
 					return err
 				}
 			}
+			h.queuedBytes.Add(-int64(cap(e.buf.Data)))
 			e.buf.Release()
 			select {
 			case h.available <- struct{}{}:
@@ -101,3 +115,43 @@ func (h *sendQueue) Close() {
 	// wait until the run loop returned
 	<-h.runStopped
 }
+
+// The inlineSender writes packets to the sendConn right away, on the connection's run loop.
+// It avoids running a separate goroutine for sending, at the cost of not being able to
+// pack new packets while previous packets are written to the socket.
+type inlineSender struct {
+	conn    sendConn
+	onError func(error)
+	failed  bool
+}
+
+var _ sender = &inlineSender{}
+
+func newInlineSender(conn sendConn, onError func(error)) sender {
+	return &inlineSender{conn: conn, onError: onError}
+}
+
+func (h *inlineSender) Send(p *packetBuffer, gsoSize uint16, ecn protocol.ECN) {
+	defer p.Release()
+	if h.failed {
+		return
+	}
+	if err := h.conn.Write(p.Data, gsoSize, ecn); err != nil && !isSendMsgSizeErr(err) {
+		h.failed = true
+		h.onError(err)
+	}
+}
+
+// WouldBlock always returns false, since packets are written synchronously.
+func (h *inlineSender) WouldBlock() bool { return false }
+
+// Available returns a nil channel, since the inlineSender never blocks.
+func (h *inlineSender) Available() <-chan struct{} { return nil }
+
+// QueuedBytes returns 0, since packets are never queued.
+func (h *inlineSender) QueuedBytes() int { return 0 }
+
+// Run returns immediately, since there's no separate send loop.
+func (h *inlineSender) Run() error { return nil }
+
+func (h *inlineSender) Close() {}
@@ -44,6 +44,26 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("reports the size of the queued packets", func() {
+		Expect(q.QueuedBytes()).To(BeZero())
+		p := getPacket([]byte("foobar"))
+		q.Send(p, 6, protocol.ECNNon)
+		Expect(q.QueuedBytes()).To(Equal(cap(p.Data)))
+
+		written := make(chan struct{})
+		c.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).Do(func([]byte, uint16, protocol.ECN) { close(written) })
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+		Eventually(written).Should(BeClosed())
+		Eventually(q.QueuedBytes).Should(BeZero())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
 	It("panics when Send() is called although there's no space in the queue", func() {
 		for i := 0; i < sendQueueCapacity; i++ {
 			Expect(q.WouldBlock()).To(BeFalse())
@@ -173,3 +193,36 @@ var _ = Describe("Send Queue", func() {
 		Eventually(closed).Should(BeClosed())
 	})
 })
+
+var _ = Describe("Inline Sender", func() {
+	var (
+		c        *MockSendConn
+		q        sender
+		reported []error
+	)
+
+	BeforeEach(func() {
+		c = NewMockSendConn(mockCtrl)
+		reported = nil
+		q = newInlineSender(c, func(err error) { reported = append(reported, err) })
+	})
+
+	It("sends packets right away", func() {
+		c.EXPECT().Write([]byte("foobar"), uint16(10), protocol.ECT1)
+		buf := getPacketBuffer()
+		buf.Data = append(buf.Data[:0], "foobar"...)
+		q.Send(buf, 10, protocol.ECT1)
+		Expect(q.WouldBlock()).To(BeFalse())
+		Expect(q.Run()).To(Succeed())
+		q.Close()
+	})
+
+	It("reports write errors, and stops sending", func() {
+		testErr := errors.New("test error")
+		c.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any()).Return(testErr)
+		q.Send(getPacketBuffer(), 0, protocol.ECNNon)
+		Expect(reported).To(Equal([]error{testErr}))
+		q.Send(getPacketBuffer(), 0, protocol.ECNNon)
+		Expect(reported).To(HaveLen(1))
+	})
+})
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
-	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
@@ -21,6 +20,8 @@ type sendStreamI interface {
 	popStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (frame ackhandler.StreamFrame, ok, hasMore bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
+	onCongestionLimited()
+	sendBufferedBytes() int
 }
 
 type sendStream struct {
@@ -29,8 +30,22 @@ type sendStream struct {
 	numOutstandingFrames int64
 	retransmissionQueue  []*wire.StreamFrame
 
-	ctx       context.Context
-	ctxCancel context.CancelCauseFunc
+	// retransmissionPolicy determines if lost data is sent before new data
+	retransmissionPolicy   RetransmissionPolicy
+	retransmissionDeadline time.Duration
+	// retransmittedBytes and newBytes count the data sent since the retransmission queue was last empty.
+	// They are used by RetransmitProportional.
+	retransmittedBytes, newBytes protocol.ByteCount
+	// sendTimes records when new data was sent, in increasing order of offsets.
+	// It is used by RetransmitDeadlineAware, and only contains entries younger than the deadline.
+	sendTimes []sentOffset
+
+	// The context is only created when Context is called.
+	// ctxCanceled and ctxCause record if (and why) it needs to be canceled.
+	ctx         context.Context
+	ctxCancel   context.CancelCauseFunc
+	ctxCanceled bool
+	ctxCause    error
 
 	streamID protocol.StreamID
 	sender   streamSender
@@ -47,11 +62,42 @@ type sendStream struct {
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
 	nextFrame      *wire.StreamFrame
 
-	writeChan chan struct{}
-	writeOnce chan struct{}
-	deadline  time.Time
+	// ownedBuffers are the buffers passed to WriteBuffer whose data hasn't been acknowledged yet.
+	// STREAM frames reference their data instead of holding a copy.
+	ownedBuffers []*ownedBuffer
+	// writingOwned is set while dataForWriting is an application-owned buffer (during a WriteBuffer call)
+	writingOwned bool
 
-	flowController flowcontrol.StreamFlowController
+	// bufferedBytes is the number of bytes of STREAM frame data held by this stream,
+	// until it is acknowledged. It is accounted against the global memory budget.
+	bufferedBytes int
+
+	writeChan  chan struct{} // created when Write first needs to wait, cap: 1
+	writeMutex sync.Mutex    // protects against concurrent use of Write
+	deadline   time.Time
+
+	clock utils.Clock
+
+	rateLimiter *tokenBucket // nil if no rate limit is applied
+	// stopRateLimitTimer is closed to stop the timer that queues the stream
+	// when sending can resume after being blocked by the rate limit.
+	// It is nil if the timer is not running.
+	stopRateLimitTimer chan struct{}
+
+	blockedCallback func(SendBlockedReason, bool)
+	blockedReason   SendBlockedReason // 0 if sending new data is not blocked
+
+	// availableSendWindow is the send window, as of the last time it was checked by the run loop.
+	// The flow controllers may only be accessed from the run loop, so AvailableSendWindow returns this copy.
+	availableSendWindow protocol.ByteCount
+
+	flowController *lazyFlowController
+}
+
+// A sentOffset records that data starting at offset was first sent at sendTime.
+type sentOffset struct {
+	offset   protocol.ByteCount
+	sendTime time.Time
 }
 
 var (
@@ -62,32 +108,74 @@ var (
 func newSendStream(
 	streamID protocol.StreamID,
 	sender streamSender,
-	flowController flowcontrol.StreamFlowController,
+	flowController *lazyFlowController,
+	clock utils.Clock,
 ) *sendStream {
-	s := &sendStream{
+	return &sendStream{
 		streamID:       streamID,
 		sender:         sender,
 		flowController: flowController,
-		writeChan:      make(chan struct{}, 1),
-		writeOnce:      make(chan struct{}, 1), // cap: 1, to protect against concurrent use of Write
+		clock:          clock,
 	}
-	s.ctx, s.ctxCancel = context.WithCancelCause(context.Background())
-	return s
 }
 
 func (s *sendStream) StreamID() protocol.StreamID {
 	return s.streamID // same for receiveStream and sendStream
 }
 
+// An ownedBuffer is an application-owned buffer passed to WriteBuffer.
+type ownedBuffer struct {
+	offset  protocol.ByteCount // the stream offset of the first byte of the buffer
+	length  protocol.ByteCount // the number of bytes written, only valid once writing is false
+	acked   protocol.ByteCount
+	writing bool
+	release func() // called once the stream doesn't reference the buffer any more
+	// released is set once release was (or is about to be) called
+	released bool
+}
+
+// overlap returns the number of bytes of the range [offset, offset+length) that belong to the buffer.
+func (b *ownedBuffer) overlap(offset, length protocol.ByteCount) protocol.ByteCount {
+	end := protocol.MaxByteCount
+	if !b.writing {
+		end = b.offset + b.length
+	}
+	return utils.Max(0, utils.Min(end, offset+length)-utils.Max(b.offset, offset))
+}
+
+func (b *ownedBuffer) done() bool {
+	return !b.writing && b.acked >= b.length
+}
+
 func (s *sendStream) Write(p []byte) (int, error) {
+	return s.write(p, nil)
+}
+
+func (s *sendStream) WriteBuffer(p []byte, release func()) (int, error) {
+	b := &ownedBuffer{release: release}
+	n, err := s.write(p, b)
+	s.mutex.Lock()
+	// Once the stream was canceled, frames won't be retransmitted any more.
+	done := !b.released && (b.done() || s.cancelWriteErr != nil || s.closeForShutdownErr != nil)
+	if done {
+		b.released = true
+		s.removeOwnedBuffer(b)
+	}
+	s.mutex.Unlock()
+	if done && b.release != nil {
+		b.release()
+	}
+	return n, err
+}
+
+// write writes p to the stream.
+// If owned is set, p is an application-owned buffer, and STREAM frames reference p instead of holding a copy.
+func (s *sendStream) write(p []byte, owned *ownedBuffer) (n int, err error) {
 	// Concurrent use of Write is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
 	// Make sure that we only execute one call at any given time to avoid hard to debug failures.
-
-	// 使用channel模拟的一个互斥锁，每次只能有一个goroutine执行write
-
-	s.writeOnce <- struct{}{}
-	defer func() { <-s.writeOnce }()
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
 
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
@@ -109,6 +197,22 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	}
 
 	s.dataForWriting = p
+	if owned != nil {
+		owned.offset = s.writeOffset
+		if s.nextFrame != nil {
+			owned.offset += s.nextFrame.DataLen()
+		}
+		owned.writing = true
+		s.ownedBuffers = append(s.ownedBuffers, owned)
+		s.writingOwned = true
+		// Don't retain a reference to p once WriteBuffer returns.
+		defer func() {
+			s.writingOwned = false
+			s.dataForWriting = nil
+			owned.writing = false
+			owned.length = protocol.ByteCount(n)
+		}()
+	}
 
 	var (
 		deadlineTimer  *utils.Timer
@@ -124,7 +228,8 @@ func (s *sendStream) Write(p []byte) (int, error) {
 		// When the user now calls Close(), this is much more likely to happen before we popped that last STREAM frame,
 		// allowing us to set the FIN bit on that frame (instead of sending an empty STREAM frame with FIN).
 		// 计算是否要分片
-		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
+		// Application-owned data is never copied, Write only returns once all data has been sent out.
+		if !s.writingOwned && s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
 			// 不需要分片
 			if s.nextFrame == nil {
 				// 将数据放入到一个帧上
@@ -140,6 +245,7 @@ func (s *sendStream) Write(p []byte) (int, error) {
 				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
 				copy(s.nextFrame.Data[l:], s.dataForWriting)
 			}
+			s.acquireBuffer(len(s.dataForWriting))
 			s.dataForWriting = nil
 			bytesWritten = len(p)
 			copied = true
@@ -163,6 +269,11 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			}
 		}
 
+		// Most streams are never blocked on, so the channel is only allocated when it's needed.
+		if !copied && s.writeChan == nil {
+			s.writeChan = make(chan struct{}, 1)
+		}
+		writeChan := s.writeChan
 		s.mutex.Unlock()
 		// 添加到活跃的发送队列
 		if !notifiedSender {
@@ -175,10 +286,10 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			break
 		}
 		if deadline.IsZero() {
-			<-s.writeChan
+			<-writeChan
 		} else {
 			select {
-			case <-s.writeChan:
+			case <-writeChan:
 			case <-deadlineTimer.Chan():
 				deadlineTimer.SetRead()
 			}
@@ -209,12 +320,22 @@ func (s *sendStream) canBufferStreamFrame() bool {
 // maxBytes is the maximum length this frame (including frame header) will have.
 func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (af ackhandler.StreamFrame, ok, hasMore bool) {
 	s.mutex.Lock()
+	oldBlockedReason := s.blockedReason
 	f, hasMoreData := s.popNewOrRetransmittedStreamFrame(maxBytes, v)
 	if f != nil {
 		s.numOutstandingFrames++
+		if s.rateLimiter != nil {
+			s.rateLimiter.Consume(f.Length(v))
+		}
 	}
+	blockedReason := s.blockedReason
+	blockedCallback := s.blockedCallback
 	s.mutex.Unlock()
 
+	if blockedCallback != nil && blockedReason != oldBlockedReason {
+		notifyBlocked(blockedCallback, oldBlockedReason, blockedReason)
+	}
+
 	if f == nil {
 		return ackhandler.StreamFrame{}, false, hasMoreData
 	}
@@ -229,15 +350,21 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		return nil, false
 	}
 
+	if s.rateLimiter != nil {
+		maxBytes = s.rateLimiter.Allowance(maxBytes)
+		if maxBytes < protocol.MinStreamFrameSize {
+			// Sending is blocked by the rate limit.
+			// The stream is dequeued, and the timer queues it again once enough tokens are available.
+			s.resetRateLimitTimer()
+			return nil, false
+		}
+	}
+
 	if len(s.retransmissionQueue) > 0 {
-		f, hasMoreRetransmissions := s.maybeGetRetransmission(maxBytes, v)
-		if f != nil || hasMoreRetransmissions {
-			if f == nil {
-				return nil, true
+		if i, ok := s.nextRetransmission(); ok {
+			if f, hasMore, ok := s.popRetransmission(i, maxBytes, v); ok {
+				return f, hasMore
 			}
-			// We always claim that we have more data to send.
-			// This might be incorrect, in which case there'll be a spurious call to popStreamFrame in the future.
-			return f, true
 		}
 	}
 
@@ -249,14 +376,32 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 				Offset:         s.writeOffset,
 				DataLenPresent: true,
 				Fin:            true,
-			}, false
+			}, len(s.retransmissionQueue) > 0
+		}
+		// There's no new data. Send the retransmissions that the retransmission policy deferred.
+		if len(s.retransmissionQueue) > 0 {
+			if f, hasMore, ok := s.popRetransmission(0, maxBytes, v); ok {
+				return f, hasMore
+			}
 		}
 		return nil, false
 	}
 
-	sendWindow := s.flowController.SendWindowSize()
+	sendWindow := s.flowController.get().SendWindowSize()
+	s.availableSendWindow = sendWindow
 	if sendWindow == 0 {
-		if isBlocked, offset := s.flowController.IsNewlyBlocked(); isBlocked {
+		if s.flowController.get().StreamSendWindowSize() == 0 {
+			s.blockedReason = SendBlockedStreamFlowControl
+		} else {
+			s.blockedReason = SendBlockedConnectionFlowControl
+		}
+		// Retransmissions are not subject to flow control.
+		if len(s.retransmissionQueue) > 0 {
+			if f, hasMore, ok := s.popRetransmission(0, maxBytes, v); ok {
+				return f, hasMore
+			}
+		}
+		if isBlocked, offset := s.flowController.get().IsNewlyBlocked(); isBlocked {
 			s.sender.queueControlFrame(&wire.StreamDataBlockedFrame{
 				StreamID:          s.streamID,
 				MaximumStreamData: offset,
@@ -268,14 +413,93 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 
 	f, hasMoreData := s.popNewStreamFrame(maxBytes, sendWindow, v)
 	if dataLen := f.DataLen(); dataLen > 0 {
+		s.blockedReason = 0
+		s.onNewDataSent(f.Offset, dataLen)
 		s.writeOffset += f.DataLen()
-		s.flowController.AddBytesSent(f.DataLen())
+		s.flowController.get().AddBytesSent(f.DataLen())
+		s.availableSendWindow -= f.DataLen()
 	}
 	f.Fin = s.finishedWriting && s.dataForWriting == nil && s.nextFrame == nil && !s.finSent
 	if f.Fin {
 		s.finSent = true
 	}
-	return f, hasMoreData
+	return f, hasMoreData || len(s.retransmissionQueue) > 0
+}
+
+// popRetransmission pops the i-th frame from the retransmission queue.
+// It returns false if there's nothing to send.
+func (s *sendStream) popRetransmission(i int, maxBytes protocol.ByteCount, v protocol.VersionNumber) (*wire.StreamFrame, bool /* has more data to send */, bool) {
+	f, hasMoreRetransmissions := s.maybeGetRetransmission(i, maxBytes, v)
+	if f == nil && !hasMoreRetransmissions {
+		return nil, false, false
+	}
+	if f == nil {
+		return nil, true, true
+	}
+	if len(s.retransmissionQueue) == 0 {
+		s.retransmittedBytes = 0
+		s.newBytes = 0
+	} else {
+		s.retransmittedBytes += f.DataLen()
+	}
+	// We always claim that we have more data to send.
+	// This might be incorrect, in which case there'll be a spurious call to popStreamFrame in the future.
+	return f, true, true
+}
+
+// nextRetransmission returns the index of the frame in the retransmission queue that should be sent before new data.
+// It returns false if new data should be sent first.
+func (s *sendStream) nextRetransmission() (int, bool) {
+	switch s.retransmissionPolicy {
+	case RetransmitProportional:
+		return 0, s.retransmittedBytes <= s.newBytes
+	case RetransmitDeadlineAware:
+		staleBefore := s.staleOffset(s.clock.Now())
+		for i, f := range s.retransmissionQueue {
+			if f.Offset >= staleBefore {
+				return i, true
+			}
+		}
+		return 0, false
+	default:
+		return 0, true
+	}
+}
+
+// onNewDataSent is called when new data is sent for the first time.
+func (s *sendStream) onNewDataSent(offset, length protocol.ByteCount) {
+	switch s.retransmissionPolicy {
+	case RetransmitProportional:
+		if len(s.retransmissionQueue) > 0 {
+			s.newBytes += length
+		}
+	case RetransmitDeadlineAware:
+		now := s.clock.Now()
+		s.staleOffset(now)
+		s.sendTimes = append(s.sendTimes, sentOffset{offset: offset, sendTime: now})
+	}
+}
+
+// staleOffset returns the offset below which all data was sent more than the retransmission deadline ago.
+// It drops the entries of sendTimes that are older than the deadline.
+func (s *sendStream) staleOffset(now time.Time) protocol.ByteCount {
+	var i int
+	for i < len(s.sendTimes) && now.Sub(s.sendTimes[i].sendTime) > s.retransmissionDeadline {
+		i++
+	}
+	s.sendTimes = s.sendTimes[i:]
+	if len(s.sendTimes) == 0 {
+		s.sendTimes = nil
+		return s.writeOffset
+	}
+	return s.sendTimes[0].offset
+}
+
+func (s *sendStream) setRetransmissionPolicy(policy RetransmissionPolicy, deadline time.Duration) {
+	s.mutex.Lock()
+	s.retransmissionPolicy = policy
+	s.retransmissionDeadline = deadline
+	s.mutex.Unlock()
 }
 
 func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount, v protocol.VersionNumber) (*wire.StreamFrame, bool) {
@@ -298,12 +522,18 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount,
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
 	}
 
-	f := wire.GetStreamFrame()
+	var f *wire.StreamFrame
+	if s.writingOwned {
+		// the frame references the application-owned buffer
+		f = &wire.StreamFrame{}
+	} else {
+		f = wire.GetStreamFrame()
+		f.Data = f.Data[:0]
+	}
 	f.Fin = false
 	f.StreamID = s.streamID
 	f.Offset = s.writeOffset
 	f.DataLenPresent = true
-	f.Data = f.Data[:0]
 
 	hasMoreData := s.popNewStreamFrameWithoutBuffer(f, maxBytes, sendWindow, v)
 	if len(f.Data) == 0 && !f.Fin {
@@ -323,16 +553,28 @@ func (s *sendStream) popNewStreamFrameWithoutBuffer(f *wire.StreamFrame, maxByte
 	return s.dataForWriting != nil || s.nextFrame != nil || s.finishedWriting
 }
 
-func (s *sendStream) maybeGetRetransmission(maxBytes protocol.ByteCount, v protocol.VersionNumber) (*wire.StreamFrame, bool /* has more retransmissions */) {
-	f := s.retransmissionQueue[0]
+func (s *sendStream) maybeGetRetransmission(i int, maxBytes protocol.ByteCount, v protocol.VersionNumber) (*wire.StreamFrame, bool /* has more retransmissions */) {
+	f := s.retransmissionQueue[i]
 	newFrame, needsSplit := f.MaybeSplitOffFrame(maxBytes, v)
 	if needsSplit {
 		return newFrame, true
 	}
-	s.retransmissionQueue = s.retransmissionQueue[1:]
+	if i == 0 {
+		s.retransmissionQueue = s.retransmissionQueue[1:]
+	} else {
+		s.retransmissionQueue = append(s.retransmissionQueue[:i], s.retransmissionQueue[i+1:]...)
+	}
 	return f, len(s.retransmissionQueue) > 0
 }
 
+// sendBufferedBytes returns the number of bytes of STREAM frame data held by this stream,
+// i.e. data that was written, but not yet acknowledged.
+func (s *sendStream) sendBufferedBytes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.bufferedBytes
+}
+
 func (s *sendStream) hasData() bool {
 	s.mutex.Lock()
 	hasData := len(s.dataForWriting) > 0
@@ -341,15 +583,27 @@ func (s *sendStream) hasData() bool {
 }
 
 func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.ByteCount) {
+	if s.writingOwned {
+		n := utils.Min(maxBytes, protocol.ByteCount(len(s.dataForWriting)))
+		f.Data = s.dataForWriting[:n:n]
+		s.dataForWriting = s.dataForWriting[n:]
+		if len(s.dataForWriting) == 0 {
+			s.dataForWriting = nil
+			s.signalWrite()
+		}
+		return
+	}
 	if protocol.ByteCount(len(s.dataForWriting)) <= maxBytes {
 		f.Data = f.Data[:len(s.dataForWriting)]
 		copy(f.Data, s.dataForWriting)
+		s.acquireBuffer(len(s.dataForWriting))
 		s.dataForWriting = nil
 		s.signalWrite()
 		return
 	}
 	f.Data = f.Data[:maxBytes]
 	copy(f.Data, s.dataForWriting)
+	s.acquireBuffer(int(maxBytes))
 	s.dataForWriting = s.dataForWriting[maxBytes:]
 	if s.canBufferStreamFrame() {
 		s.signalWrite()
@@ -360,6 +614,7 @@ func (s *sendStream) isNewlyCompleted() bool {
 	completed := (s.finSent || s.cancelWriteErr != nil) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
 	if completed && !s.completed {
 		s.completed = true
+		s.stopRateLimitTimerImpl()
 		return true
 	}
 	return false
@@ -375,7 +630,7 @@ func (s *sendStream) Close() error {
 		s.mutex.Unlock()
 		return fmt.Errorf("close called for canceled stream %d", s.streamID)
 	}
-	s.ctxCancel(nil)
+	s.cancelCtx(nil)
 	s.finishedWriting = true
 	s.mutex.Unlock()
 
@@ -395,13 +650,20 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, remote bool
 		return
 	}
 	s.cancelWriteErr = &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: remote}
-	s.ctxCancel(s.cancelWriteErr)
+	s.cancelCtx(s.cancelWriteErr)
+	s.stopRateLimitTimerImpl()
 	s.numOutstandingFrames = 0
 	s.retransmissionQueue = nil
+	s.releaseAllBuffers()
+	released := s.releaseOwnedBuffers()
 	newlyCompleted := s.isNewlyCompleted()
+	s.signalWrite()
 	s.mutex.Unlock()
 
-	s.signalWrite()
+	for _, b := range released {
+		b()
+	}
+
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
 		StreamID:  s.streamID,
 		FinalSize: s.writeOffset,
@@ -417,7 +679,11 @@ func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil
 	s.mutex.Unlock()
 
-	s.flowController.UpdateSendWindow(limit)
+	s.flowController.get().UpdateSendWindow(limit)
+	sendWindow := s.flowController.get().SendWindowSize()
+	s.mutex.Lock()
+	s.availableSendWindow = sendWindow
+	s.mutex.Unlock()
 	if hasStreamData {
 		s.sender.onHasStreamData(s.streamID)
 	}
@@ -428,29 +694,213 @@ func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
 }
 
 func (s *sendStream) Context() context.Context {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ctx == nil {
+		s.ctx, s.ctxCancel = context.WithCancelCause(context.Background())
+		if s.ctxCanceled {
+			s.ctxCancel(s.ctxCause)
+		}
+	}
 	return s.ctx
 }
 
+// cancelCtx cancels the context returned by Context.
+// Only the first call has an effect.
+// must be called after locking the mutex
+func (s *sendStream) cancelCtx(cause error) {
+	if s.ctxCanceled {
+		return
+	}
+	s.ctxCanceled = true
+	s.ctxCause = cause
+	if s.ctxCancel != nil {
+		s.ctxCancel(cause)
+	}
+}
+
 func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
-	s.mutex.Unlock()
 	s.signalWrite()
+	s.mutex.Unlock()
 	return nil
 }
 
+func (s *sendStream) SetSendRateLimit(bytesPerSecond, burst uint64) {
+	s.mutex.Lock()
+	if bytesPerSecond == 0 {
+		s.rateLimiter = nil
+		s.stopRateLimitTimerImpl()
+	} else if s.rateLimiter == nil {
+		s.rateLimiter = newTokenBucket(bytesPerSecond, burst, s.clock)
+	} else {
+		s.rateLimiter.SetLimit(bytesPerSecond, burst)
+	}
+	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil || len(s.retransmissionQueue) > 0
+	s.mutex.Unlock()
+
+	// the stream might have been blocked by the old limit
+	if hasStreamData {
+		s.sender.onHasStreamData(s.streamID)
+	}
+}
+
+func (s *sendStream) AvailableSendWindow() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return uint64(s.availableSendWindow)
+}
+
+func (s *sendStream) SetBlockedCallback(cb func(reason SendBlockedReason, blocked bool)) {
+	s.mutex.Lock()
+	s.blockedCallback = cb
+	s.mutex.Unlock()
+}
+
+// onCongestionLimited is called when the connection is blocked by the congestion controller,
+// while this stream has data to send.
+func (s *sendStream) onCongestionLimited() {
+	s.mutex.Lock()
+	if s.blockedCallback == nil || s.blockedReason != 0 || (len(s.dataForWriting) == 0 && s.nextFrame == nil) {
+		s.mutex.Unlock()
+		return
+	}
+	s.blockedReason = SendBlockedCongestion
+	blockedCallback := s.blockedCallback
+	s.mutex.Unlock()
+
+	blockedCallback(SendBlockedCongestion, true)
+}
+
+func notifyBlocked(cb func(SendBlockedReason, bool), oldReason, newReason SendBlockedReason) {
+	if oldReason != 0 {
+		cb(oldReason, false)
+	}
+	if newReason != 0 {
+		cb(newReason, true)
+	}
+}
+
+// must be called after locking the mutex
+func (s *sendStream) resetRateLimitTimer() {
+	s.stopRateLimitTimerImpl()
+	timer := s.clock.NewTimer(s.rateLimiter.NextSendTime().Sub(s.clock.Now()))
+	stop := make(chan struct{})
+	s.stopRateLimitTimer = stop
+	go func() {
+		select {
+		case <-timer.Chan():
+			s.sender.onHasStreamData(s.streamID)
+		case <-stop:
+			timer.Stop()
+		}
+	}()
+}
+
+// must be called after locking the mutex
+func (s *sendStream) stopRateLimitTimerImpl() {
+	if s.stopRateLimitTimer != nil {
+		close(s.stopRateLimitTimer)
+		s.stopRateLimitTimer = nil
+	}
+}
+
 // CloseForShutdown closes a stream abruptly.
 // It makes Write unblock (and return the error) immediately.
 // The peer will NOT be informed about this: the stream is closed without sending a FIN or RST.
 func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
-	s.ctxCancel(err)
+	s.cancelCtx(err)
 	s.closeForShutdownErr = err
-	s.mutex.Unlock()
+	s.releaseAllBuffers()
+	released := s.releaseOwnedBuffers()
+	s.stopRateLimitTimerImpl()
 	s.signalWrite()
+	s.mutex.Unlock()
+	for _, b := range released {
+		b()
+	}
+}
+
+// must be called after locking the mutex
+func (s *sendStream) acquireBuffer(n int) {
+	s.bufferedBytes += n
+	utils.GlobalMemoryBudget.Acquire(n)
+}
+
+// must be called after locking the mutex
+func (s *sendStream) releaseBuffer(n int) {
+	s.bufferedBytes -= n
+	utils.GlobalMemoryBudget.Release(n)
+}
+
+// releaseAllBuffers is called when the stream is canceled or closed.
+// Frames that are still outstanding at that point aren't tracked any more.
+// must be called after locking the mutex
+func (s *sendStream) releaseAllBuffers() {
+	s.releaseBuffer(s.bufferedBytes)
 }
 
-// signalWrite performs a non-blocking send on the writeChan
+// ackOwnedData accounts for acknowledged data that belongs to application-owned buffers.
+// It returns the number of bytes that belong to these buffers,
+// and the release callbacks of the buffers that are now fully acknowledged.
+// must be called after locking the mutex
+func (s *sendStream) ackOwnedData(offset, length protocol.ByteCount) (protocol.ByteCount, []func()) {
+	var owned protocol.ByteCount
+	var released []func()
+	for i := 0; i < len(s.ownedBuffers); i++ {
+		b := s.ownedBuffers[i]
+		n := b.overlap(offset, length)
+		if n == 0 {
+			continue
+		}
+		owned += n
+		b.acked += n
+		if b.done() {
+			s.ownedBuffers = append(s.ownedBuffers[:i], s.ownedBuffers[i+1:]...)
+			i--
+			b.released = true
+			if b.release != nil {
+				released = append(released, b.release)
+			}
+		}
+	}
+	return owned, released
+}
+
+// must be called after locking the mutex
+func (s *sendStream) removeOwnedBuffer(b *ownedBuffer) {
+	for i, ob := range s.ownedBuffers {
+		if ob == b {
+			s.ownedBuffers = append(s.ownedBuffers[:i], s.ownedBuffers[i+1:]...)
+			return
+		}
+	}
+}
+
+// releaseOwnedBuffers is called when the stream is canceled or closed.
+// It returns the release callbacks of all application-owned buffers, except for the one currently being written.
+// must be called after locking the mutex
+func (s *sendStream) releaseOwnedBuffers() []func() {
+	var released []func()
+	for _, b := range s.ownedBuffers {
+		if b.writing {
+			continue
+		}
+		b.released = true
+		if b.release != nil {
+			released = append(released, b.release)
+		}
+	}
+	s.ownedBuffers = nil
+	return released
+}
+
+// signalWrite performs a non-blocking send on the writeChan.
+// It must be called with the mutex held.
 func (s *sendStream) signalWrite() {
 	select {
 	case s.writeChan <- struct{}{}:
@@ -464,12 +914,21 @@ var _ ackhandler.FrameHandler = &sendStreamAckHandler{}
 
 func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
+	offset := sf.Offset
+	dataLen := sf.DataLen()
 	sf.PutBack()
 	s.mutex.Lock()
 	if s.cancelWriteErr != nil {
 		s.mutex.Unlock()
 		return
 	}
+	var released []func()
+	if s.closeForShutdownErr == nil {
+		var owned protocol.ByteCount
+		owned, released = (*sendStream)(s).ackOwnedData(offset, dataLen)
+		// Data held in application-owned buffers isn't accounted against the memory budget.
+		(*sendStream)(s).releaseBuffer(int(dataLen - owned))
+	}
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -477,6 +936,9 @@ func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 	newlyCompleted := (*sendStream)(s).isNewlyCompleted()
 	s.mutex.Unlock()
 
+	for _, release := range released {
+		release()
+	}
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
@@ -497,5 +959,6 @@ func (s *sendStreamAckHandler) OnLost(f wire.Frame) {
 	}
 	s.mutex.Unlock()
 
+	s.sender.onStreamDataRetransmission(s.streamID, sf.Offset, sf.DataLen())
 	s.sender.onHasStreamData(s.streamID)
 }
@@ -7,6 +7,8 @@ import (
 	"io"
 	mrand "math/rand"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/exp/rand"
@@ -14,6 +16,7 @@ import (
 	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -35,7 +38,7 @@ var _ = Describe("Send Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newSendStream(streamID, mockSender, mockFC)
+		str = newSendStream(streamID, mockSender, &lazyFlowController{flowController: mockFC}, utils.DefaultClock{})
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutWriter(str, timeout)
@@ -100,6 +103,56 @@ var _ = Describe("Send Stream", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("only allocates the write channel once Write blocks", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			str.mutex.Lock()
+			Expect(str.writeChan).To(BeNil())
+			str.mutex.Unlock()
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := strWithTimeout.Write(make([]byte, 2*protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			Eventually(func() chan struct{} {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return str.writeChan
+			}).ShouldNot(BeNil())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			for {
+				_, ok, _ := str.popStreamFrame(1000, protocol.Version1)
+				if !ok {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("reports the available send window", func() {
+			Expect(str.AvailableSendWindow()).To(BeZero())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(100))
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			_, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(str.AvailableSendWindow()).To(BeEquivalentTo(94))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("writes and gets data in two turns", func() {
 			done := make(chan struct{})
 			go func() {
@@ -322,9 +375,18 @@ var _ = Describe("Send Stream", func() {
 			Expect(context.Cause(str.Context())).To(MatchError(context.Canceled))
 		})
 
+		It("cancels the context when it's created after the stream was closed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.ctx).To(BeNil())
+			Expect(str.Close()).To(Succeed())
+			Expect(str.Context().Done()).To(BeClosed())
+			Expect(context.Cause(str.Context())).To(MatchError(context.Canceled))
+		})
+
 		Context("flow control blocking", func() {
 			It("queues a BLOCKED frame if the stream is flow control blocked", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
+				mockFC.EXPECT().StreamSendWindowSize()
 				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(12))
 				mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{
 					StreamID:          streamID,
@@ -368,6 +430,7 @@ var _ = Describe("Send Stream", func() {
 
 				// try to pop again, this time noticing that we're blocked
 				mockFC.EXPECT().SendWindowSize()
+				mockFC.EXPECT().StreamSendWindowSize()
 				// don't use offset 3 here, to make sure the BLOCKED frame contains the number returned by the flow controller
 				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(10))
 				mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{
@@ -381,6 +444,185 @@ var _ = Describe("Send Stream", func() {
 				str.closeForShutdown(nil)
 				Eventually(done).Should(BeClosed())
 			})
+
+			Context("reporting blocked sending", func() {
+				type blockedEvent struct {
+					reason  SendBlockedReason
+					blocked bool
+				}
+
+				var events []blockedEvent
+
+				BeforeEach(func() {
+					events = nil
+					str.SetBlockedCallback(func(reason SendBlockedReason, blocked bool) {
+						events = append(events, blockedEvent{reason: reason, blocked: blocked})
+					})
+				})
+
+				// write makes the stream send "foobar"
+				write := func() {
+					str.nextFrame = &wire.StreamFrame{StreamID: streamID, Data: []byte("foobar"), DataLenPresent: true}
+				}
+
+				It("reports when it is blocked by stream flow control", func() {
+					write()
+					mockFC.EXPECT().SendWindowSize()
+					mockFC.EXPECT().StreamSendWindowSize()
+					mockFC.EXPECT().IsNewlyBlocked()
+					_, ok, hasMoreData := str.popStreamFrame(1000, protocol.Version1)
+					Expect(ok).To(BeFalse())
+					Expect(hasMoreData).To(BeTrue())
+					Expect(events).To(Equal([]blockedEvent{{reason: SendBlockedStreamFlowControl, blocked: true}}))
+
+					mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(100))
+					mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+					_, ok, _ = str.popStreamFrame(1000, protocol.Version1)
+					Expect(ok).To(BeTrue())
+					Expect(events).To(Equal([]blockedEvent{
+						{reason: SendBlockedStreamFlowControl, blocked: true},
+						{reason: SendBlockedStreamFlowControl, blocked: false},
+					}))
+				})
+
+				It("reports when it is blocked by connection flow control", func() {
+					write()
+					mockFC.EXPECT().SendWindowSize().Times(2)
+					mockFC.EXPECT().StreamSendWindowSize().Return(protocol.ByteCount(100)).Times(2)
+					mockFC.EXPECT().IsNewlyBlocked().Times(2)
+					str.popStreamFrame(1000, protocol.Version1)
+					// only report the state change once
+					str.popStreamFrame(1000, protocol.Version1)
+					Expect(events).To(Equal([]blockedEvent{{reason: SendBlockedConnectionFlowControl, blocked: true}}))
+				})
+
+				It("reports when it is blocked by congestion control", func() {
+					write()
+					str.onCongestionLimited()
+					str.onCongestionLimited()
+					Expect(events).To(Equal([]blockedEvent{{reason: SendBlockedCongestion, blocked: true}}))
+
+					mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(100))
+					mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+					_, ok, _ := str.popStreamFrame(1000, protocol.Version1)
+					Expect(ok).To(BeTrue())
+					Expect(events).To(Equal([]blockedEvent{
+						{reason: SendBlockedCongestion, blocked: true},
+						{reason: SendBlockedCongestion, blocked: false},
+					}))
+				})
+
+				It("doesn't report congestion control blocking if there's no data to send", func() {
+					str.onCongestionLimited()
+					Expect(events).To(BeEmpty())
+				})
+
+				It("switches the reason", func() {
+					write()
+					str.onCongestionLimited()
+					mockFC.EXPECT().SendWindowSize()
+					mockFC.EXPECT().StreamSendWindowSize()
+					mockFC.EXPECT().IsNewlyBlocked()
+					str.popStreamFrame(1000, protocol.Version1)
+					Expect(events).To(Equal([]blockedEvent{
+						{reason: SendBlockedCongestion, blocked: true},
+						{reason: SendBlockedCongestion, blocked: false},
+						{reason: SendBlockedStreamFlowControl, blocked: true},
+					}))
+				})
+			})
+		})
+
+		Context("rate limiting", func() {
+			It("schedules the timer using the connection's clock", func() {
+				clock := &manualClock{now: time.Now().Add(-time.Hour)}
+				str = newSendStream(streamID, mockSender, &lazyFlowController{flowController: mockFC}, clock)
+				str.SetSendRateLimit(1000, 1000)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write(getData(1400))
+				Expect(err).ToNot(HaveOccurred())
+				_, ok, _ := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				_, ok, _ = str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				timer := clock.Timer()
+				Expect(timer).ToNot(BeNil())
+				// sending the remaining bytes takes roughly 400ms at 1000 bytes/s
+				Expect(timer.duration).To(BeNumerically(">", 100*time.Millisecond))
+				Expect(timer.duration).To(BeNumerically("<=", time.Second))
+				unblocked := make(chan struct{})
+				mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(unblocked) })
+				timer.fire()
+				Eventually(unblocked).Should(BeClosed())
+				str.closeForShutdown(nil)
+			})
+
+			It("stops the timer when the stream is canceled", func() {
+				clock := &manualClock{now: time.Now()}
+				str = newSendStream(streamID, mockSender, &lazyFlowController{flowController: mockFC}, clock)
+				str.SetSendRateLimit(1, 500)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write(getData(1000))
+				Expect(err).ToNot(HaveOccurred())
+				_, ok, _ := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				_, ok, _ = str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				timer := clock.Timer()
+				Expect(timer).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(gomock.Any()).MaxTimes(1)
+				str.CancelWrite(1234)
+				Eventually(timer.Stopped).Should(BeTrue())
+			})
+
+			It("limits the size of STREAM frames, and continues sending once enough tokens are available", func() {
+				str.SetSendRateLimit(100000, 1000)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write(getData(1400))
+				Expect(err).ToNot(HaveOccurred())
+				frame, ok, hasMoreData := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(hasMoreData).To(BeTrue())
+				Expect(frame.Frame.Length(protocol.Version1)).To(BeNumerically("<=", 1000))
+				firstLen := frame.Frame.DataLen()
+				// the bucket is now empty
+				unblocked := make(chan struct{})
+				mockSender.EXPECT().onHasStreamData(streamID).Do(func(protocol.StreamID) { close(unblocked) })
+				_, ok, hasMoreData = str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeFalse())
+				Eventually(unblocked).Should(BeClosed())
+				frame, ok, _ = str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.Offset).To(Equal(firstLen))
+			})
+
+			It("unblocks the stream when the rate limit is removed", func() {
+				str.SetSendRateLimit(1, 500)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write(getData(1000))
+				Expect(err).ToNot(HaveOccurred())
+				_, ok, _ := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				_, ok, hasMoreData := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeFalse())
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.SetSendRateLimit(0, 0)
+				frame, ok, _ := str.popStreamFrame(1500, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.Offset + frame.Frame.DataLen()).To(Equal(protocol.ByteCount(1000)))
+				str.closeForShutdown(nil)
+			})
 		})
 
 		Context("deadlines", func() {
@@ -678,11 +920,14 @@ var _ = Describe("Send Stream", func() {
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0x1000))
 			str.updateSendWindow(0x1337)
+			Expect(str.AvailableSendWindow()).To(BeEquivalentTo(0x1000))
 		})
 
 		It("says when it has data for sending", func() {
 			mockFC.EXPECT().UpdateSendWindow(gomock.Any())
+			mockFC.EXPECT().SendWindowSize().AnyTimes()
 			mockSender.EXPECT().onHasStreamData(streamID)
 			done := make(chan struct{})
 			go func() {
@@ -946,6 +1191,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0x42), protocol.ByteCount(6))
 			mockSender.EXPECT().onHasStreamData(streamID)
 			(*sendStreamAckHandler)(str).OnLost(f)
 			frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
@@ -964,6 +1210,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0x42), protocol.ByteCount(6))
 			mockSender.EXPECT().onHasStreamData(streamID)
 			(*sendStreamAckHandler)(str).OnLost(sf)
 			frame, ok, hasMoreData := str.popStreamFrame(sf.Length(protocol.Version1)-3, protocol.Version1)
@@ -990,6 +1237,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0x42), protocol.ByteCount(6))
 			mockSender.EXPECT().onHasStreamData(streamID)
 			(*sendStreamAckHandler)(str).OnLost(f)
 			_, ok, hasMoreData := str.popStreamFrame(2, protocol.Version1)
@@ -1016,6 +1264,7 @@ var _ = Describe("Send Stream", func() {
 			Expect(frame.Frame.Data).To(Equal([]byte("foobar")))
 
 			// now lose the frame
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0), protocol.ByteCount(6))
 			mockSender.EXPECT().onHasStreamData(streamID)
 			frame.Handler.OnLost(frame.Frame)
 			newFrame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
@@ -1051,6 +1300,336 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("retransmission policies", func() {
+		// queueLost queues a lost frame for retransmission
+		queueLost := func(offset protocol.ByteCount, data []byte) {
+			str.numOutstandingFrames++
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, offset, protocol.ByteCount(len(data)))
+			mockSender.EXPECT().onHasStreamData(streamID)
+			(*sendStreamAckHandler)(str).OnLost(&wire.StreamFrame{StreamID: streamID, Offset: offset, Data: data})
+		}
+
+		// setNewData makes the stream send data at offset 100
+		setNewData := func(data []byte) {
+			str.writeOffset = 100
+			str.nextFrame = &wire.StreamFrame{StreamID: streamID, Offset: 100, Data: data, DataLenPresent: true}
+		}
+
+		popFrame := func(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool) {
+			f, ok, hasMore := str.popStreamFrame(maxBytes, protocol.Version1)
+			ExpectWithOffset(1, ok).To(BeTrue())
+			return f.Frame, hasMore
+		}
+
+		It("sends retransmissions before new data by default", func() {
+			queueLost(0, []byte("foobar"))
+			queueLost(6, []byte("raboof"))
+			setNewData([]byte("new"))
+			f, hasMore := popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(BeZero())
+			Expect(hasMore).To(BeTrue())
+			f, hasMore = popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(6)))
+			Expect(hasMore).To(BeTrue())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			f, _ = popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(100)))
+			Expect(f.Data).To(Equal([]byte("new")))
+		})
+
+		It("interleaves retransmissions and new data, when using the proportional policy", func() {
+			str.setRetransmissionPolicy(RetransmitProportional, 0)
+			queueLost(0, []byte("foobar"))
+			queueLost(6, []byte("raboof"))
+			setNewData([]byte("foobarraboof"))
+			maxBytes := expectedFrameHeaderLen(100) + 6
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6)).Times(2)
+			f, _ := popFrame(maxBytes)
+			Expect(f.Offset).To(BeZero())
+			f, hasMore := popFrame(maxBytes)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(100)))
+			Expect(hasMore).To(BeTrue())
+			f, _ = popFrame(maxBytes)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(6)))
+			Expect(str.retransmissionQueue).To(BeEmpty())
+			f, _ = popFrame(maxBytes)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(106)))
+		})
+
+		It("sends stale retransmissions after new data, when using the deadline-aware policy", func() {
+			str.setRetransmissionPolicy(RetransmitDeadlineAware, time.Hour)
+			str.sendTimes = []sentOffset{
+				{offset: 0, sendTime: time.Now().Add(-2 * time.Hour)},
+				{offset: 6, sendTime: time.Now()},
+			}
+			queueLost(0, []byte("foobar"))
+			queueLost(6, []byte("raboof"))
+			setNewData([]byte("new"))
+			// the data at offset 6 is still fresh
+			f, _ := popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(6)))
+			// the data at offset 0 is stale
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			f, hasMore := popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(Equal(protocol.ByteCount(100)))
+			Expect(hasMore).To(BeTrue())
+			Expect(str.sendTimes).To(HaveLen(2))
+			Expect(str.sendTimes[1].offset).To(Equal(protocol.ByteCount(100)))
+			// there's no new data left, so the stale data is retransmitted
+			f, _ = popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(BeZero())
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(str.retransmissionQueue).To(BeEmpty())
+		})
+
+		It("uses the connection's clock to determine if retransmissions are stale", func() {
+			clock := &manualClock{now: time.Now().Add(-24 * time.Hour)}
+			str = newSendStream(streamID, mockSender, &lazyFlowController{flowController: mockFC}, clock)
+			str.setRetransmissionPolicy(RetransmitDeadlineAware, time.Hour)
+			str.sendTimes = []sentOffset{{offset: 0, sendTime: clock.Now()}}
+			queueLost(0, []byte("foobar"))
+			setNewData([]byte("new"))
+			// according to the wall clock, the data would be stale
+			f, _ := popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(BeZero())
+			Expect(f.Data).To(Equal([]byte("foobar")))
+		})
+
+		It("sends stale retransmissions when blocked by flow control, when using the deadline-aware policy", func() {
+			str.setRetransmissionPolicy(RetransmitDeadlineAware, time.Hour)
+			str.sendTimes = []sentOffset{{offset: 0, sendTime: time.Now().Add(-2 * time.Hour)}}
+			queueLost(0, []byte("foobar"))
+			setNewData([]byte("new"))
+			mockFC.EXPECT().SendWindowSize()
+			mockFC.EXPECT().StreamSendWindowSize()
+			f, hasMore := popFrame(protocol.MaxByteCount)
+			Expect(f.Offset).To(BeZero())
+			Expect(hasMore).To(BeTrue())
+		})
+	})
+
+	Context("writing application-owned buffers", func() {
+		var released chan struct{}
+
+		BeforeEach(func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			released = make(chan struct{}, 10)
+		})
+
+		release := func() { released <- struct{}{} }
+
+		// writeBuffer writes the data using WriteBuffer, and pops all STREAM frames
+		writeBuffer := func(data []byte) []ackhandler.StreamFrame {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := str.WriteBuffer(data, release)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(len(data)))
+			}()
+			waitForWrite()
+			var frames []ackhandler.StreamFrame
+			for {
+				frame, ok, hasMore := str.popStreamFrame(1000, protocol.Version1)
+				if ok {
+					frames = append(frames, frame)
+				}
+				if !hasMore {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+			return frames
+		}
+
+		It("references the buffer until all data is acknowledged", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			data := getData(3000)
+			frames := writeBuffer(data)
+			Expect(frames).To(HaveLen(4))
+			var dataLen protocol.ByteCount
+			for _, f := range frames {
+				Expect(f.Frame.Offset).To(Equal(dataLen))
+				Expect(f.Frame.Data).To(Equal(data[dataLen : dataLen+f.Frame.DataLen()]))
+				dataLen += f.Frame.DataLen()
+			}
+			Expect(dataLen).To(BeEquivalentTo(3000))
+			// the frames reference the buffer
+			data[0]++
+			Expect(frames[0].Frame.Data[0]).To(Equal(data[0]))
+			Expect(str.bufferedBytes).To(BeZero())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+
+			for _, f := range frames[1:] {
+				f.Handler.OnAcked(f.Frame)
+			}
+			Expect(released).To(BeEmpty())
+			frames[0].Handler.OnAcked(frames[0].Frame)
+			Expect(released).To(Receive())
+			Expect(str.ownedBuffers).To(BeEmpty())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		})
+
+		It("releases the buffer once retransmissions are acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			frames := writeBuffer(getData(500))
+			Expect(frames).To(HaveLen(1))
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0), protocol.ByteCount(500))
+			frames[0].Handler.OnLost(frames[0].Frame)
+			frame1, ok, _ := str.popStreamFrame(200, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			frame2, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(frame1.Frame.DataLen() + frame2.Frame.DataLen()).To(BeEquivalentTo(500))
+			frame2.Handler.OnAcked(frame2.Frame)
+			Expect(released).To(BeEmpty())
+			frame1.Handler.OnAcked(frame1.Frame)
+			Expect(released).To(Receive())
+		})
+
+		It("accounts for data written before and after the buffer", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			_, err := strWithTimeout.Write([]byte("foo"))
+			Expect(err).ToNot(HaveOccurred())
+			frame1, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(frame1.Frame.Data).To(Equal([]byte("foo")))
+			frames := writeBuffer(getData(100))
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame.Offset).To(BeEquivalentTo(3))
+			_, err = strWithTimeout.Write([]byte("bar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame2, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(frame2.Frame.Offset).To(BeEquivalentTo(103))
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+
+			frame1.Handler.OnAcked(frame1.Frame)
+			frame2.Handler.OnAcked(frame2.Frame)
+			Expect(released).To(BeEmpty())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+			frames[0].Handler.OnAcked(frames[0].Frame)
+			Expect(released).To(Receive())
+		})
+
+		It("releases the buffer when the stream is canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frames := writeBuffer(getData(100))
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(released).To(Receive())
+			// acknowledging the frame doesn't release the buffer again
+			frames[0].Handler.OnAcked(frames[0].Frame)
+			Expect(released).To(BeEmpty())
+		})
+
+		It("releases the buffer when the stream is canceled during the write", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := str.WriteBuffer(getData(5000), release)
+				errChan <- err
+			}()
+			waitForWrite()
+			frame, ok, _ := str.popStreamFrame(1000, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Eventually(errChan).Should(Receive(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234})))
+			Expect(released).To(Receive())
+			Expect(str.dataForWriting).To(BeNil())
+			frame.Handler.OnAcked(frame.Frame)
+			Expect(released).To(BeEmpty())
+		})
+
+		It("releases the buffer when the stream is closed for shutdown", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			writeBuffer(getData(100))
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(released).To(Receive())
+		})
+
+		It("releases the buffer immediately if writing fails", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			_, err := str.WriteBuffer(getData(100), release)
+			Expect(err).To(MatchError("write on closed stream 1337"))
+			Expect(released).To(Receive())
+		})
+	})
+
+	Context("memory budget", func() {
+		BeforeEach(func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		It("holds data until it is acknowledged", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.bufferedBytes).To(Equal(6))
+			frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(str.bufferedBytes).To(Equal(6))
+			// the frame is retransmitted in two parts
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0), protocol.ByteCount(6))
+			frame.Handler.OnLost(frame.Frame)
+			Expect(str.bufferedBytes).To(Equal(6))
+			frame1, ok, _ := str.popStreamFrame(frame.Frame.Length(protocol.Version1)-3, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			frame2, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+			Expect(frame1.Frame.Data).To(Equal([]byte("foo")))
+			frame1.Handler.OnAcked(frame1.Frame)
+			Expect(str.bufferedBytes).To(Equal(3))
+			frame2.Handler.OnAcked(frame2.Frame)
+			Expect(str.bufferedBytes).To(BeZero())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		})
+
+		It("releases all data when the stream is canceled", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.bufferedBytes).To(BeZero())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+			// acknowledging the frame doesn't release the data again
+			frame.Handler.OnAcked(frame.Frame)
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		})
+
+		It("releases all data when the stream is closed for shutdown", func() {
+			used := utils.GlobalMemoryBudget.Used()
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used + 6))
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(str.bufferedBytes).To(BeZero())
+			Expect(utils.GlobalMemoryBudget.Used()).To(Equal(used))
+		})
+	})
+
 	Context("determining when a stream is completed", func() {
 		BeforeEach(func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
@@ -1152,6 +1731,7 @@ var _ = Describe("Send Stream", func() {
 			for _, f := range frames[1:] {
 				f.Handler.OnAcked(f.Frame)
 			}
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, protocol.ByteCount(0), frames[0].Frame.DataLen())
 			mockSender.EXPECT().onHasStreamData(streamID)
 			frames[0].Handler.OnLost(frames[0].Frame)
 
@@ -1170,6 +1750,7 @@ var _ = Describe("Send Stream", func() {
 		It("retransmits data until everything has been acknowledged", func() {
 			const dataLen = 1 << 22 // 4 MB
 			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockSender.EXPECT().onStreamDataRetransmission(streamID, gomock.Any(), gomock.Any()).AnyTimes()
 			mockFC.EXPECT().SendWindowSize().DoAndReturn(func() protocol.ByteCount {
 				return protocol.ByteCount(mrand.Intn(500)) + 50
 			}).AnyTimes()
@@ -1213,3 +1794,47 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 })
+
+// manualClock is a clock whose timers only fire when told to.
+type manualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	timer *manualTimer
+}
+
+var _ utils.Clock = &manualClock{}
+
+func (c *manualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTimer(d time.Duration) utils.ClockTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timer = &manualTimer{duration: d, c: make(chan time.Time, 1)}
+	return c.timer
+}
+
+// Timer returns the timer that was created last.
+func (c *manualClock) Timer() *manualTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.timer
+}
+
+type manualTimer struct {
+	duration time.Duration
+	c        chan time.Time
+	stopped  atomic.Bool
+}
+
+func (t *manualTimer) Chan() <-chan time.Time { return t.c }
+func (t *manualTimer) Reset(time.Duration) bool {
+	t.stopped.Store(false)
+	return true
+}
+func (t *manualTimer) Stop() bool    { return !t.stopped.Swap(true) }
+func (t *manualTimer) Stopped() bool { return t.stopped.Load() }
+func (t *manualTimer) fire()         { t.c <- time.Time{} }
@@ -45,6 +45,7 @@ type quicConn interface {
 	GetVersion() protocol.VersionNumber
 	getPerspective() protocol.Perspective
 	run() error
+	closeLocal(error)
 	destroy(error)
 	shutdown()
 }
@@ -56,7 +57,8 @@ type zeroRTTQueue struct {
 
 type rejectedPacket struct {
 	receivedPacket
-	hdr *wire.Header
+	hdr       *wire.Header
+	errorCode qerr.TransportErrorCode // only used for packets in the connectionRefusedQueue
 }
 
 // A Listener of QUIC
@@ -115,6 +117,31 @@ type baseServer struct {
 
 	connQueue    chan quicConn
 	connQueueLen int32 // to be used as an atomic
+	// numConns is the number of connections that haven't been closed yet.
+	// It is only tracked if Config.AdmitConnection is set.
+	numConns int32 // to be used as an atomic
+	// conns contains all connections that haven't been closed yet.
+	conns         map[quicConn]serverConnState
+	numHandshakes int
+	// stats are protected by the mutex
+	stats ConnectionStats
+
+	limits connectionLimits
+	// Connection attempts queued because of the connection limits.
+	// Only accessed from the run loop.
+	queuedConnAttempts []rejectedPacket
+	// capacityAvailable is signaled when a connection is closed, or a handshake completes.
+	capacityAvailable chan struct{}
+
+	// shuttingDown is closed when Shutdown is called.
+	shuttingDown chan struct{}
+
+	// claimConn is called for every new connection.
+	// If it returns true, the connection is not returned from Accept.
+	// It is used by the Transport to hand connections to DialPeer.
+	claimConn func(quicConn) bool
+	// multihomed is set if the server is part of a MultihomedGroup.
+	multihomed *MultihomedGroup
 
 	tracer *logging.Tracer
 
@@ -137,6 +164,18 @@ func (l *Listener) Close() error {
 	return l.baseServer.Close()
 }
 
+// Shutdown gracefully shuts down the listener.
+// New connection attempts are refused, and Accept returns ErrServerClosed.
+// Connections that were not yet returned from Accept are closed right away.
+// For all other connections, notify is called (if not nil). It can be used to ask the peer to close the connection,
+// e.g. by sending an application-defined GOAWAY message, or to close the connection using CloseWithError.
+// Shutdown then waits until all connections are closed, or until the context is done.
+// Afterwards, it closes the listener just like Close does, closing all remaining connections.
+// If the context was done before all connections were closed, the context's error is returned.
+func (l *Listener) Shutdown(ctx context.Context, notify func(Connection)) error {
+	return l.baseServer.Shutdown(ctx, notify)
+}
+
 // Addr returns the local network address that the server is listening on.
 func (l *Listener) Addr() net.Addr {
 	return l.baseServer.Addr()
@@ -162,6 +201,11 @@ func (l *EarlyListener) Close() error {
 	return l.baseServer.Close()
 }
 
+// Shutdown gracefully shuts down the listener, see Listener.Shutdown for details.
+func (l *EarlyListener) Shutdown(ctx context.Context, notify func(Connection)) error {
+	return l.baseServer.Shutdown(ctx, notify)
+}
+
 // Addr returns the local network addr that the server is listening on.
 func (l *EarlyListener) Addr() net.Addr {
 	return l.baseServer.Addr()
@@ -238,6 +282,8 @@ func newServer(
 	maxTokenAge time.Duration,
 	disableVersionNegotiation bool,
 	acceptEarly bool,
+	limits connectionLimits,
+	logger utils.Logger,
 ) *baseServer {
 	s := &baseServer{
 		conn:                      conn,
@@ -248,6 +294,10 @@ func newServer(
 		connIDGenerator:           connIDGenerator,
 		connHandler:               connHandler,
 		connQueue:                 make(chan quicConn),
+		conns:                     make(map[quicConn]serverConnState),
+		limits:                    limits,
+		capacityAvailable:         make(chan struct{}, 1),
+		shuttingDown:              make(chan struct{}),
 		errorChan:                 make(chan struct{}),
 		running:                   make(chan struct{}),
 		receivedPackets:           make(chan receivedPacket, protocol.MaxServerUnprocessedPackets),
@@ -257,7 +307,7 @@ func newServer(
 		retryQueue:                make(chan rejectedPacket, 8),
 		newConn:                   newConnection,
 		tracer:                    tracer,
-		logger:                    utils.DefaultLogger.WithPrefix("server"),
+		logger:                    logger.WithPrefix("server"),
 		acceptEarlyConns:          acceptEarly,
 		disableVersionNegotiation: disableVersionNegotiation,
 		onClose:                   onClose,
@@ -286,6 +336,8 @@ func (s *baseServer) run() {
 			if bufferStillInUse := s.handlePacketImpl(p); !bufferStillInUse {
 				p.buffer.Release()
 			}
+		case <-s.capacityAvailable:
+			s.handleQueuedConnAttempts()
 		}
 	}
 }
@@ -320,11 +372,184 @@ func (s *baseServer) accept(ctx context.Context) (quicConn, error) {
 	case conn := <-s.connQueue:
 		atomic.AddInt32(&s.connQueueLen, -1)
 		return conn, nil
+	case <-s.shuttingDown:
+		return nil, ErrServerClosed
 	case <-s.errorChan:
 		return nil, s.serverError
 	}
 }
 
+func (s *baseServer) Shutdown(ctx context.Context, notify func(Connection)) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	select {
+	case <-s.shuttingDown:
+	default:
+		close(s.shuttingDown)
+	}
+	conns := make(map[quicConn]serverConnState, len(s.conns))
+	for conn, state := range s.conns {
+		conns[conn] = state
+	}
+	s.mutex.Unlock()
+
+	for conn, state := range conns {
+		if state != serverConnAccepted {
+			conn.closeLocal(&qerr.TransportError{ErrorCode: qerr.ConnectionRefused})
+			continue
+		}
+		if notify != nil {
+			notify(conn)
+		}
+	}
+
+	err := s.waitForConns(ctx)
+	s.Close()
+	return err
+}
+
+// waitForConns waits until all connections are closed, or until the context is done.
+func (s *baseServer) waitForConns(ctx context.Context) error {
+	for {
+		var conn quicConn
+		s.mutex.Lock()
+		for c := range s.conns {
+			conn = c
+			break
+		}
+		s.mutex.Unlock()
+		if conn == nil {
+			return nil
+		}
+		select {
+		case <-conn.Context().Done():
+			s.removeConn(conn)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *baseServer) addConn(conn quicConn) {
+	s.mutex.Lock()
+	s.conns[conn] = serverConnHandshaking
+	s.numHandshakes++
+	s.stats.TotalConnections++
+	s.mutex.Unlock()
+}
+
+func (s *baseServer) setConnState(conn quicConn, state serverConnState) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	oldState, ok := s.conns[conn]
+	if !ok {
+		return
+	}
+	s.conns[conn] = state
+	if oldState == serverConnHandshaking && state != serverConnHandshaking {
+		s.numHandshakes--
+		s.signalCapacityAvailable()
+	}
+}
+
+func (s *baseServer) removeConn(conn quicConn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	state, ok := s.conns[conn]
+	if !ok {
+		return
+	}
+	delete(s.conns, conn)
+	if state == serverConnHandshaking {
+		s.numHandshakes--
+	}
+	s.signalCapacityAvailable()
+}
+
+func (s *baseServer) signalCapacityAvailable() {
+	select {
+	case s.capacityAvailable <- struct{}{}:
+	default:
+	}
+}
+
+// atConnectionLimit says if a new connection would exceed the connection limits.
+func (s *baseServer) atConnectionLimit() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return (s.limits.maxConns > 0 && len(s.conns) >= s.limits.maxConns) ||
+		(s.limits.maxHandshakes > 0 && s.numHandshakes >= s.limits.maxHandshakes)
+}
+
+// handleConnectionLimit handles a connection attempt that exceeds the connection limits.
+// Depending on the configured behavior, it is either refused or queued.
+func (s *baseServer) handleConnectionLimit(p rejectedPacket) {
+	if s.limits.behavior == QueueConnections {
+		for _, queued := range s.queuedConnAttempts {
+			// the client retransmitted its Initial
+			if queued.hdr.DestConnectionID == p.hdr.DestConnectionID {
+				p.buffer.Release()
+				return
+			}
+		}
+	}
+	if s.limits.behavior == QueueConnections && len(s.queuedConnAttempts) < protocol.MaxQueuedConnectionAttempts {
+		s.logger.Debugf("Queueing new connection attempt. Connection limit reached.")
+		s.queuedConnAttempts = append(s.queuedConnAttempts, p)
+		s.mutex.Lock()
+		s.stats.QueuedConnectionAttempts = len(s.queuedConnAttempts)
+		s.mutex.Unlock()
+		return
+	}
+	s.logger.Debugf("Rejecting new connection. Connection limit reached.")
+	s.mutex.Lock()
+	s.stats.RefusedConnectionAttempts++
+	s.mutex.Unlock()
+	select {
+	case s.connectionRefusedQueue <- p:
+	default:
+		// drop packet if we can't send out the CONNECTION_REFUSED fast enough
+		p.buffer.Release()
+	}
+}
+
+// handleQueuedConnAttempts handles queued connection attempts, as long as the connection limits allow.
+func (s *baseServer) handleQueuedConnAttempts() {
+	var dropped uint64
+	for len(s.queuedConnAttempts) > 0 {
+		p := s.queuedConnAttempts[0]
+		if time.Since(p.rcvTime) > s.config.HandshakeIdleTimeout {
+			s.queuedConnAttempts = s.queuedConnAttempts[1:]
+			dropped++
+			p.buffer.Release()
+			continue
+		}
+		if s.atConnectionLimit() {
+			break
+		}
+		s.queuedConnAttempts = s.queuedConnAttempts[1:]
+		if err := s.handleInitialImpl(p.receivedPacket, p.hdr); err != nil {
+			s.logger.Errorf("Error occurred handling initial packet: %s", err)
+		}
+	}
+	s.mutex.Lock()
+	s.stats.DroppedConnectionAttempts += dropped
+	s.stats.QueuedConnectionAttempts = len(s.queuedConnAttempts)
+	s.mutex.Unlock()
+}
+
+func (s *baseServer) connectionStats() ConnectionStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats := s.stats
+	stats.Connections = len(s.conns)
+	stats.Handshakes = s.numHandshakes
+	return stats
+}
+
 // Close the server
 func (s *baseServer) Close() error {
 	s.mutex.Lock()
@@ -569,6 +794,7 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 	var (
 		token          *handshake.Token
 		retrySrcConnID *protocol.ConnectionID
+		externalToken  bool // the token was validated by the ValidateExternalRetryToken callback
 	)
 	origDestConnID := hdr.DestConnectionID
 	if len(hdr.Token) > 0 {
@@ -579,10 +805,17 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 				retrySrcConnID = &tok.RetrySrcConnectionID
 			}
 			token = tok
+		} else if s.config.ValidateExternalRetryToken != nil {
+			if connID, ok := s.config.ValidateExternalRetryToken(hdr.Token, p.remoteAddr); ok {
+				s.logger.Debugf("Accepting externally minted Retry token.")
+				origDestConnID = connID
+				retrySrcConnID = &hdr.DestConnectionID
+				externalToken = true
+			}
 		}
 	}
 
-	clientAddrIsValid := s.validateToken(token, p.remoteAddr)
+	clientAddrIsValid := externalToken || s.validateToken(token, p.remoteAddr)
 	if token != nil && !clientAddrIsValid {
 		// For invalid and expired non-retry tokens, we don't send an INVALID_TOKEN error.
 		// We just ignore them, and act as if there was no token on this packet at all.
@@ -602,7 +835,28 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 			return nil
 		}
 	}
-	if token == nil && s.config.RequireAddressValidation(p.remoteAddr) {
+	var requireRetry bool
+	if s.config.AdmitConnection != nil {
+		decision := s.config.AdmitConnection(s.admissionInfo(p, hdr, clientAddrIsValid))
+		switch decision.Action {
+		case AdmissionReject:
+			errorCode := decision.ErrorCode
+			if errorCode == 0 {
+				errorCode = qerr.ConnectionRefused
+			}
+			s.logger.Debugf("Rejecting new connection due to AdmitConnection callback (error code: %s)", errorCode)
+			select {
+			case s.connectionRefusedQueue <- rejectedPacket{receivedPacket: p, hdr: hdr, errorCode: errorCode}:
+			default:
+				// drop packet if we can't send out the CONNECTION_CLOSE fast enough
+				p.buffer.Release()
+			}
+			return nil
+		case AdmissionRequireRetry:
+			requireRetry = true
+		}
+	}
+	if !clientAddrIsValid && (requireRetry || s.config.RequireAddressValidation(p.remoteAddr)) {
 		// Retry invalidates all 0-RTT packets sent.
 		delete(s.zeroRTTQueues, hdr.DestConnectionID)
 		select {
@@ -614,6 +868,24 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 		return nil
 	}
 
+	select {
+	case <-s.shuttingDown:
+		s.logger.Debugf("Rejecting new connection. Server is shutting down.")
+		select {
+		case s.connectionRefusedQueue <- rejectedPacket{receivedPacket: p, hdr: hdr}:
+		default:
+			// drop packet if we can't send out the CONNECTION_REFUSED fast enough
+			p.buffer.Release()
+		}
+		return nil
+	default:
+	}
+
+	if s.atConnectionLimit() {
+		s.handleConnectionLimit(rejectedPacket{receivedPacket: p, hdr: hdr})
+		return nil
+	}
+
 	if queueLen := atomic.LoadInt32(&s.connQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
 		select {
@@ -642,17 +914,25 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 			}
 			config = populateConfig(conf)
 		}
+		// Use the same connection ID that is passed to the client's GetLogWriter callback.
+		tracingConnID := hdr.DestConnectionID
+		if origDestConnID.Len() > 0 {
+			tracingConnID = origDestConnID
+		}
 		var tracer *logging.ConnectionTracer
 		if config.Tracer != nil {
-			// Use the same connection ID that is passed to the client's GetLogWriter callback.
-			connID := hdr.DestConnectionID
-			if origDestConnID.Len() > 0 {
-				connID = origDestConnID
-			}
-			tracer = config.Tracer(context.WithValue(context.Background(), ConnectionTracingKey, tracingID), protocol.PerspectiveServer, connID)
+			ctx := context.WithValue(context.Background(), ConnectionTracingKey, tracingID)
+			ctx = context.WithValue(ctx, RemoteAddrContextKey, p.remoteAddr)
+			tracer = config.Tracer(ctx, protocol.PerspectiveServer, tracingConnID)
+		}
+		logger := connectionLogger(s.logger, config, tracingConnID, p.remoteAddr)
+		sc := newSendConn(s.conn, p.remoteAddr, p.info, logger)
+		var c sendConn = sc
+		if s.multihomed != nil {
+			c = s.multihomed.newSendConn(sc)
 		}
 		conn = s.newConn(
-			newSendConn(s.conn, p.remoteAddr, p.info, s.logger),
+			c,
 			s.connHandler,
 			origDestConnID,
 			retrySrcConnID,
@@ -667,7 +947,7 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 			clientAddrIsValid,
 			tracer,
 			tracingID,
-			s.logger,
+			logger,
 			hdr.Version,
 		)
 		conn.handlePacket(p)
@@ -689,7 +969,19 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 		}
 		return nil
 	}
-	go conn.run()
+	s.addConn(conn)
+	if s.config.AdmitConnection != nil {
+		atomic.AddInt32(&s.numConns, 1)
+	}
+	// Once the run loop returns, the connection is closed.
+	// This avoids spawning a separate goroutine per connection to wait for it to be closed.
+	go func() {
+		conn.run()
+		s.removeConn(conn)
+		if s.config.AdmitConnection != nil {
+			atomic.AddInt32(&s.numConns, -1)
+		}
+	}()
 	go s.handleNewConn(conn)
 	if conn == nil {
 		p.buffer.Release()
@@ -700,6 +992,10 @@ func (s *baseServer) handleInitialImpl(p receivedPacket, hdr *wire.Header) error
 
 func (s *baseServer) handleNewConn(conn quicConn) {
 	connCtx := conn.Context()
+	if s.claimConn != nil && s.claimConn(conn) {
+		s.setConnState(conn, serverConnAccepted)
+		return
+	}
 	if s.acceptEarlyConns {
 		// wait until the early connection is ready (or the handshake fails)
 		select {
@@ -716,10 +1012,12 @@ func (s *baseServer) handleNewConn(conn quicConn) {
 		}
 	}
 
+	s.setConnState(conn, serverConnReady)
 	atomic.AddInt32(&s.connQueueLen, 1)
 	select {
 	case s.connQueue <- conn:
 		// blocks until the connection is accepted
+		s.setConnState(conn, serverConnAccepted)
 	case <-connCtx.Done():
 		atomic.AddInt32(&s.connQueueLen, -1)
 		// don't pass connections that were already closed to Accept()
@@ -808,8 +1106,12 @@ func (s *baseServer) maybeSendInvalidToken(p rejectedPacket) {
 func (s *baseServer) sendConnectionRefused(p rejectedPacket) {
 	defer p.buffer.Release()
 	sealer, _ := handshake.NewInitialAEAD(p.hdr.DestConnectionID, protocol.PerspectiveServer, p.hdr.Version)
-	if err := s.sendError(p.remoteAddr, p.hdr, sealer, qerr.ConnectionRefused, p.info); err != nil {
-		s.logger.Debugf("Error sending CONNECTION_REFUSED error: %s", err)
+	errorCode := qerr.ConnectionRefused
+	if p.errorCode != 0 {
+		errorCode = p.errorCode
+	}
+	if err := s.sendError(p.remoteAddr, p.hdr, sealer, errorCode, p.info); err != nil {
+		s.logger.Debugf("Error sending %s error: %s", errorCode, err)
 	}
 }
 
@@ -894,5 +1196,13 @@ func (s *baseServer) maybeSendVersionNegotiationPacket(p receivedPacket) {
 	}
 	if _, err := s.conn.WritePacket(data, p.remoteAddr, p.info.OOB(), 0, protocol.ECNUnsupported); err != nil {
 		s.logger.Debugf("Error sending Version Negotiation: %s", err)
+		return
+	}
+	if s.config.SentVersionNegotiation != nil {
+		s.config.SentVersionNegotiation(&VersionNegotiationInfo{
+			RemoteAddr:     p.remoteAddr,
+			ClientVersion:  v,
+			ServerVersions: s.config.Versions,
+		})
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"errors"
+	"io"
 	"net"
 	"reflect"
 	"sync"
@@ -327,6 +328,89 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("creates a connection when an externally minted Retry token is accepted", func() {
+				serv.config.RequireAddressValidation = func(net.Addr) bool { return true }
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				serv.config.ValidateExternalRetryToken = func(token []byte, addr net.Addr) (ConnectionID, bool) {
+					Expect(token).To(Equal([]byte("external token")))
+					Expect(addr).To(Equal(raddr))
+					return protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde}), true
+				}
+				connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+				hdr := &wire.Header{
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}),
+					DestConnectionID: connID,
+					Version:          protocol.Version1,
+					Token:            []byte("external token"),
+				}
+				p := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				p.remoteAddr = raddr
+				run := make(chan struct{})
+				phm.EXPECT().Get(connID)
+				phm.EXPECT().AddWithConnID(connID, gomock.Any(), gomock.Any()).DoAndReturn(func(_, c protocol.ConnectionID, fn func() (packetHandler, bool)) bool {
+					phm.EXPECT().GetStatelessResetToken(gomock.Any())
+					_, ok := fn()
+					return ok
+				})
+				conn := NewMockQUICConn(mockCtrl)
+				serv.newConn = func(
+					_ sendConn,
+					_ connRunner,
+					origDestConnID protocol.ConnectionID,
+					retrySrcConnID *protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ protocol.ConnectionID,
+					_ ConnectionIDGenerator,
+					_ protocol.StatelessResetToken,
+					_ *Config,
+					_ *tls.Config,
+					_ *handshake.TokenGenerator,
+					clientAddressValidated bool,
+					_ *logging.ConnectionTracer,
+					_ uint64,
+					_ utils.Logger,
+					_ protocol.VersionNumber,
+				) quicConn {
+					Expect(origDestConnID).To(Equal(protocol.ParseConnectionID([]byte{0xde, 0xad, 0xc0, 0xde})))
+					Expect(*retrySrcConnID).To(Equal(connID))
+					Expect(clientAddressValidated).To(BeTrue())
+					conn.EXPECT().handlePacket(p)
+					conn.EXPECT().run().Do(func() { close(run) })
+					conn.EXPECT().Context().Return(context.Background())
+					conn.EXPECT().HandshakeComplete().Return(make(chan struct{}))
+					return conn
+				}
+				serv.handlePacket(p)
+				Eventually(run).Should(BeClosed())
+			})
+
+			It("sends a Retry if an external token is rejected", func() {
+				serv.config.RequireAddressValidation = func(net.Addr) bool { return true }
+				serv.config.ValidateExternalRetryToken = func([]byte, net.Addr) (ConnectionID, bool) { return ConnectionID{}, false }
+				hdr := &wire.Header{
+					Type:             protocol.PacketTypeInitial,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}),
+					DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}),
+					Version:          protocol.Version1,
+					Token:            []byte("external token"),
+				}
+				packet := getPacket(hdr, make([]byte, protocol.MinInitialPacketSize))
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				packet.remoteAddr = raddr
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				done := make(chan struct{})
+				conn.EXPECT().WriteTo(gomock.Any(), raddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+					defer close(done)
+					Expect(parseHeader(b).Type).To(Equal(protocol.PacketTypeRetry))
+					return len(b), nil
+				})
+				phm.EXPECT().Get(gomock.Any())
+				serv.handlePacket(packet)
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("sends a Version Negotiation Packet for unsupported versions", func() {
 				srcConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5})
 				destConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6})
@@ -357,6 +441,27 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("calls the SentVersionNegotiation callback", func() {
+				infoChan := make(chan *VersionNegotiationInfo, 1)
+				serv.config.SentVersionNegotiation = func(info *VersionNegotiationInfo) { infoChan <- info }
+				packet := getPacket(&wire.Header{
+					Type:             protocol.PacketTypeHandshake,
+					SrcConnectionID:  protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5}),
+					DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6}),
+					Version:          0x42,
+				}, make([]byte, protocol.MinUnknownVersionPacketSize))
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				packet.remoteAddr = raddr
+				tracer.EXPECT().SentVersionNegotiationPacket(packet.remoteAddr, gomock.Any(), gomock.Any(), gomock.Any())
+				conn.EXPECT().WriteTo(gomock.Any(), raddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) { return len(b), nil })
+				serv.handlePacket(packet)
+				var info *VersionNegotiationInfo
+				Eventually(infoChan).Should(Receive(&info))
+				Expect(info.RemoteAddr).To(Equal(raddr))
+				Expect(info.ClientVersion).To(Equal(protocol.VersionNumber(0x42)))
+				Expect(info.ServerVersions).To(Equal(serv.config.Versions))
+			})
+
 			It("doesn't send a Version Negotiation packets if sending them is disabled", func() {
 				serv.disableVersionNegotiation = true
 				srcConnID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5})
@@ -768,7 +873,7 @@ var _ = Describe("Server", func() {
 				Expect(err).ToNot(HaveOccurred())
 				data, err := opener.Open(nil, b[extHdr.ParsedLen():], extHdr.PacketNumber, b[:extHdr.ParsedLen()])
 				Expect(err).ToNot(HaveOccurred())
-				_, f, err := wire.NewFrameParser(false).ParseNext(data, protocol.EncryptionInitial, origHdr.Version)
+				_, f, err := wire.NewFrameParser(false, false, false).ParseNext(data, protocol.EncryptionInitial, origHdr.Version)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 				ccf := f.(*wire.ConnectionCloseFrame)
@@ -1073,6 +1178,104 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			Context("admission control", func() {
+				// getInitialWithClientHello returns an Initial packet containing a ClientHello generated by crypto/tls
+				getInitialWithClientHello := func(conf *tls.Config) (receivedPacket, *wire.Header) {
+					c1, c2 := net.Pipe()
+					defer c1.Close()
+					defer c2.Close()
+					go tls.Client(c1, conf).Handshake()
+					recordHdr := make([]byte, 5)
+					_, err := io.ReadFull(c2, recordHdr)
+					Expect(err).ToNot(HaveOccurred())
+					ch := make([]byte, int(recordHdr[3])<<8|int(recordHdr[4]))
+					_, err = io.ReadFull(c2, ch)
+					Expect(err).ToNot(HaveOccurred())
+
+					payload, err := (&wire.CryptoFrame{Data: ch}).Append(nil, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					if len(payload) < protocol.MinInitialPacketSize {
+						payload = append(payload, make([]byte, protocol.MinInitialPacketSize-len(payload))...)
+					}
+					hdr := &wire.Header{
+						Type:             protocol.PacketTypeInitial,
+						SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}),
+						DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}),
+						Version:          protocol.Version1,
+					}
+					return getPacket(hdr, payload), hdr
+				}
+
+				It("rejects connection attempts", func() {
+					infoChan := make(chan *AdmissionInfo, 1)
+					serv.config.AdmitConnection = func(info *AdmissionInfo) AdmissionDecision {
+						infoChan <- info
+						return AdmissionDecision{Action: AdmissionReject, ErrorCode: 0x178}
+					}
+					p, hdr := getInitialWithClientHello(&tls.Config{ServerName: "quic-go.net", NextProtos: []string{"h3", "foo"}})
+					phm.EXPECT().Get(hdr.DestConnectionID)
+					tracer.EXPECT().SentPacket(p.remoteAddr, gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ net.Addr, _ *logging.Header, _ logging.ByteCount, frames []logging.Frame) {
+						Expect(frames).To(HaveLen(1))
+						Expect(frames[0]).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
+						Expect(frames[0].(*wire.ConnectionCloseFrame).ErrorCode).To(BeEquivalentTo(0x178))
+					})
+					done := make(chan struct{})
+					conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+						defer close(done)
+						rejectHdr := parseHeader(b)
+						Expect(rejectHdr.Type).To(Equal(protocol.PacketTypeInitial))
+						Expect(rejectHdr.DestConnectionID).To(Equal(hdr.SrcConnectionID))
+						return len(b), nil
+					})
+					atomic.StoreInt32(&serv.numConns, 5)
+					atomic.StoreInt32(&serv.connQueueLen, 3)
+					serv.handlePacket(p)
+					var info *AdmissionInfo
+					Eventually(infoChan).Should(Receive(&info))
+					Expect(info.RemoteAddr).To(Equal(p.remoteAddr))
+					Expect(info.ServerName).To(Equal("quic-go.net"))
+					Expect(info.ALPN).To(Equal([]string{"h3", "foo"}))
+					Expect(info.AddressValidated).To(BeFalse())
+					Expect(info.ActiveConnections).To(Equal(5))
+					Expect(info.AcceptQueueLength).To(Equal(3))
+					Eventually(done).Should(BeClosed())
+				})
+
+				It("uses CONNECTION_REFUSED if no error code is set", func() {
+					serv.config.AdmitConnection = func(*AdmissionInfo) AdmissionDecision {
+						return AdmissionDecision{Action: AdmissionReject}
+					}
+					p, hdr := getInitialWithClientHello(&tls.Config{ServerName: "quic-go.net"})
+					phm.EXPECT().Get(hdr.DestConnectionID)
+					done := make(chan struct{})
+					tracer.EXPECT().SentPacket(p.remoteAddr, gomock.Any(), gomock.Any(), gomock.Any()).Do(func(_ net.Addr, _ *logging.Header, _ logging.ByteCount, frames []logging.Frame) {
+						defer close(done)
+						Expect(frames).To(HaveLen(1))
+						Expect(frames[0].(*wire.ConnectionCloseFrame).ErrorCode).To(BeEquivalentTo(qerr.ConnectionRefused))
+					})
+					conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr)
+					serv.handlePacket(p)
+					Eventually(done).Should(BeClosed())
+				})
+
+				It("sends a Retry", func() {
+					serv.config.AdmitConnection = func(*AdmissionInfo) AdmissionDecision {
+						return AdmissionDecision{Action: AdmissionRequireRetry}
+					}
+					p, hdr := getInitialWithClientHello(&tls.Config{ServerName: "quic-go.net"})
+					phm.EXPECT().Get(hdr.DestConnectionID)
+					tracer.EXPECT().SentPacket(p.remoteAddr, gomock.Any(), gomock.Any(), nil)
+					done := make(chan struct{})
+					conn.EXPECT().WriteTo(gomock.Any(), p.remoteAddr).DoAndReturn(func(b []byte, _ net.Addr) (int, error) {
+						defer close(done)
+						Expect(parseHeader(b).Type).To(Equal(protocol.PacketTypeRetry))
+						return len(b), nil
+					})
+					serv.handlePacket(p)
+					Eventually(done).Should(BeClosed())
+				})
+			})
+
 			It("accepts new connections when the handshake completes", func() {
 				conn := NewMockQUICConn(mockCtrl)
 
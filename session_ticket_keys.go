@@ -0,0 +1,240 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+const (
+	// sessionTicketKeyRefreshInterval is the interval at which the SessionTicketKeyProvider is queried for new keys.
+	sessionTicketKeyRefreshInterval = 5 * time.Minute
+	// sessionTicketKeyFetchTimeout is the timeout for a single call to the SessionTicketKeyProvider.
+	sessionTicketKeyFetchTimeout = 10 * time.Second
+)
+
+// A SessionTicketKey is a key used to encrypt and decrypt session tickets.
+// Session tickets are used for session resumption and for 0-RTT.
+type SessionTicketKey struct {
+	// ID identifies the key. It must be unique among the keys returned by a SessionTicketKeyProvider.
+	ID string
+	// Key is the key material.
+	// crypto/tls identifies the key used to encrypt a ticket by a name derived from the key material.
+	Key [32]byte
+	// NotBefore is the time from which on the key is used to encrypt new session tickets.
+	// Of all keys that are valid, the one with the latest NotBefore is used.
+	// Session tickets encrypted with this key are accepted even before NotBefore,
+	// allowing new keys to be distributed to all servers of a fleet before they are used.
+	NotBefore time.Time
+	// NotAfter is the time after which session tickets encrypted with this key are rejected.
+	// If zero, the key doesn't expire.
+	NotAfter time.Time
+}
+
+func (k *SessionTicketKey) expired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && !now.Before(k.NotAfter)
+}
+
+// A SessionTicketKeyProvider provides the keys used to encrypt and decrypt session tickets.
+// To keep session resumption working across a fleet of load-balanced servers,
+// all servers need to use the same keys, for example by fetching them from a central key management service.
+type SessionTicketKeyProvider interface {
+	// SessionTicketKeys returns the current set of keys.
+	// It is called when the server starts listening, and periodically afterwards.
+	// If it returns an error when refreshing the keys, the previous keys continue to be used.
+	SessionTicketKeys(context.Context) ([]SessionTicketKey, error)
+}
+
+// StaticSessionTicketKeys is a SessionTicketKeyProvider that always returns the same set of keys.
+// Rotation happens according to the NotBefore and NotAfter times of the keys.
+type StaticSessionTicketKeys []SessionTicketKey
+
+var _ SessionTicketKeyProvider = StaticSessionTicketKeys{}
+
+// SessionTicketKeys returns the keys.
+func (k StaticSessionTicketKeys) SessionTicketKeys(context.Context) ([]SessionTicketKey, error) {
+	return k, nil
+}
+
+func validateSessionTicketKeys(keys []SessionTicketKey) error {
+	ids := make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		if k.ID == "" {
+			return errors.New("quic: session ticket key without ID")
+		}
+		if _, ok := ids[k.ID]; ok {
+			return fmt.Errorf("quic: duplicate session ticket key ID: %s", k.ID)
+		}
+		ids[k.ID] = struct{}{}
+	}
+	return nil
+}
+
+// selectSessionTicketKeys selects the keys that are usable at time now.
+// It returns the key used to encrypt new session tickets (or nil, if there is no such key),
+// and all keys that session tickets are accepted from, newest first.
+// It also returns the next time at which the selection changes (or the zero time, if it never changes).
+func selectSessionTicketKeys(keys []SessionTicketKey, now time.Time) (issuing *SessionTicketKey, accepted []SessionTicketKey, next time.Time) {
+	updateNext := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for i := range keys {
+		k := &keys[i]
+		if k.expired(now) {
+			continue
+		}
+		updateNext(k.NotBefore)
+		updateNext(k.NotAfter)
+		accepted = append(accepted, *k)
+		if k.NotBefore.After(now) {
+			continue
+		}
+		if issuing == nil || k.NotBefore.After(issuing.NotBefore) {
+			issuing = k
+		}
+	}
+	// Put the newest keys first, so that decryption of recent tickets succeeds early.
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].NotBefore.After(accepted[j].NotBefore) })
+	return issuing, accepted, next
+}
+
+// The sessionTicketKeyRing installs the keys obtained from a SessionTicketKeyProvider on a tls.Config,
+// and rotates them as time progresses.
+type sessionTicketKeyRing struct {
+	provider SessionTicketKeyProvider
+	tlsConf  *tls.Config
+	logger   utils.Logger
+
+	keys []SessionTicketKey
+	next time.Time // the next time the keys need to be installed again
+	// fallbackKey is used to encrypt session tickets if none of the keys can be used.
+	// Tickets encrypted with this key can only be decrypted by this server.
+	fallbackKey [32]byte
+
+	mutex     sync.Mutex
+	installed [][32]byte // the keys currently installed on the tls.Config
+}
+
+// newSessionTicketKeyRing fetches the initial set of keys and installs them on tlsConf.
+// tlsConf must not be used by anybody else.
+func newSessionTicketKeyRing(provider SessionTicketKeyProvider, tlsConf *tls.Config, logger utils.Logger) (*sessionTicketKeyRing, error) {
+	r := &sessionTicketKeyRing{
+		provider: provider,
+		tlsConf:  tlsConf,
+		logger:   logger,
+	}
+	if _, err := rand.Read(r.fallbackKey[:]); err != nil {
+		return nil, err
+	}
+	keys, err := r.fetch(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("quic: fetching session ticket keys failed: %w", err)
+	}
+	r.keys = keys
+	r.next = r.install(time.Now())
+	if getConfigForClient := tlsConf.GetConfigForClient; getConfigForClient != nil {
+		tlsConf.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			conf, err := getConfigForClient(info)
+			if err != nil || conf == nil {
+				return conf, err
+			}
+			// crypto/tls uses the keys set on the returned config, if there are any.
+			// Clone the config, so we don't modify the application's config.
+			conf = conf.Clone()
+			conf.SetSessionTicketKeys(r.installedKeys())
+			return conf, nil
+		}
+	}
+	return r, nil
+}
+
+func (r *sessionTicketKeyRing) installedKeys() [][32]byte {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.installed
+}
+
+func (r *sessionTicketKeyRing) fetch(ctx context.Context) ([]SessionTicketKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, sessionTicketKeyFetchTimeout)
+	defer cancel()
+	keys, err := r.provider.SessionTicketKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSessionTicketKeys(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// install installs the keys usable at time now on the tls.Config.
+// It returns the next time at which the keys need to be installed again.
+func (r *sessionTicketKeyRing) install(now time.Time) time.Time {
+	issuing, accepted, next := selectSessionTicketKeys(r.keys, now)
+	keys := make([][32]byte, 0, len(accepted)+1)
+	if issuing != nil {
+		r.logger.Debugf("Encrypting session tickets with key %s", issuing.ID)
+		keys = append(keys, issuing.Key)
+	} else {
+		r.logger.Errorf("No valid session ticket key. Session tickets won't be accepted by other servers.")
+		keys = append(keys, r.fallbackKey)
+	}
+	for _, k := range accepted {
+		if issuing == nil || k.ID != issuing.ID {
+			keys = append(keys, k.Key)
+		}
+	}
+	r.tlsConf.SetSessionTicketKeys(keys)
+	r.mutex.Lock()
+	r.installed = keys
+	r.mutex.Unlock()
+	return next
+}
+
+// run periodically refreshes and rotates the keys, until done is closed.
+func (r *sessionTicketKeyRing) run(done <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	nextRefresh := time.Now().Add(sessionTicketKeyRefreshInterval)
+	wakeup := func() time.Duration {
+		if r.next.IsZero() || r.next.After(nextRefresh) {
+			return time.Until(nextRefresh)
+		}
+		return time.Until(r.next)
+	}
+	timer := time.NewTimer(wakeup())
+	defer timer.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		if !now.Before(nextRefresh) {
+			if keys, err := r.fetch(ctx); err != nil {
+				r.logger.Errorf("Refreshing session ticket keys failed: %s", err)
+			} else {
+				r.keys = keys
+			}
+			nextRefresh = now.Add(sessionTicketKeyRefreshInterval)
+		}
+		r.next = r.install(now)
+		timer.Reset(wakeup())
+	}
+}
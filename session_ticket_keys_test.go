@@ -0,0 +1,172 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type sessionTicketKeyProviderFunc func(context.Context) ([]SessionTicketKey, error)
+
+func (f sessionTicketKeyProviderFunc) SessionTicketKeys(ctx context.Context) ([]SessionTicketKey, error) {
+	return f(ctx)
+}
+
+var _ = Describe("Session Ticket Keys", func() {
+	now := time.Now()
+	ids := func(keys []SessionTicketKey) []string {
+		var ids []string
+		for _, k := range keys {
+			ids = append(ids, k.ID)
+		}
+		return ids
+	}
+
+	Context("selecting keys", func() {
+		It("issues with the newest valid key", func() {
+			keys := []SessionTicketKey{
+				{ID: "old", NotBefore: now.Add(-2 * time.Hour), NotAfter: now.Add(time.Hour)},
+				{ID: "current", NotBefore: now.Add(-time.Hour), NotAfter: now.Add(2 * time.Hour)},
+				{ID: "next", NotBefore: now.Add(30 * time.Minute), NotAfter: now.Add(3 * time.Hour)},
+				{ID: "expired", NotBefore: now.Add(-3 * time.Hour), NotAfter: now.Add(-time.Minute)},
+			}
+			issuing, accepted, next := selectSessionTicketKeys(keys, now)
+			Expect(issuing).ToNot(BeNil())
+			Expect(issuing.ID).To(Equal("current"))
+			// tickets encrypted with keys that will be used in the future are already accepted
+			Expect(ids(accepted)).To(Equal([]string{"next", "current", "old"}))
+			Expect(next).To(Equal(now.Add(30 * time.Minute)))
+
+			// the next key is used once its NotBefore time is reached
+			issuing, accepted, next = selectSessionTicketKeys(keys, next)
+			Expect(issuing.ID).To(Equal("next"))
+			Expect(ids(accepted)).To(Equal([]string{"next", "current", "old"}))
+			Expect(next).To(Equal(now.Add(time.Hour)))
+
+			// the old key expires
+			issuing, accepted, next = selectSessionTicketKeys(keys, next)
+			Expect(issuing.ID).To(Equal("next"))
+			Expect(ids(accepted)).To(Equal([]string{"next", "current"}))
+			Expect(next).To(Equal(now.Add(2 * time.Hour)))
+		})
+
+		It("handles keys without expiry", func() {
+			keys := []SessionTicketKey{{ID: "forever"}}
+			issuing, accepted, next := selectSessionTicketKeys(keys, now)
+			Expect(issuing.ID).To(Equal("forever"))
+			Expect(ids(accepted)).To(Equal([]string{"forever"}))
+			Expect(next).To(BeZero())
+		})
+
+		It("doesn't issue with keys that are not yet valid", func() {
+			keys := []SessionTicketKey{{ID: "future", NotBefore: now.Add(time.Minute)}}
+			issuing, accepted, next := selectSessionTicketKeys(keys, now)
+			Expect(issuing).To(BeNil())
+			Expect(ids(accepted)).To(Equal([]string{"future"}))
+			Expect(next).To(Equal(now.Add(time.Minute)))
+		})
+	})
+
+	Context("validating keys", func() {
+		It("rejects keys without ID", func() {
+			Expect(validateSessionTicketKeys([]SessionTicketKey{{ID: "foo"}, {}})).To(MatchError("quic: session ticket key without ID"))
+		})
+
+		It("rejects duplicate IDs", func() {
+			Expect(validateSessionTicketKeys([]SessionTicketKey{{ID: "foo"}, {ID: "bar"}, {ID: "foo"}})).To(MatchError("quic: duplicate session ticket key ID: foo"))
+		})
+	})
+
+	Context("key ring", func() {
+		It("fetches the keys from the provider", func() {
+			var called bool
+			provider := sessionTicketKeyProviderFunc(func(ctx context.Context) ([]SessionTicketKey, error) {
+				called = true
+				_, ok := ctx.Deadline()
+				Expect(ok).To(BeTrue())
+				return []SessionTicketKey{{ID: "foo", Key: [32]byte{1}}}, nil
+			})
+			r, err := newSessionTicketKeyRing(provider, &tls.Config{}, utils.DefaultLogger)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(called).To(BeTrue())
+			Expect(ids(r.keys)).To(Equal([]string{"foo"}))
+			Expect(r.next).To(BeZero())
+		})
+
+		It("returns the error from the provider", func() {
+			provider := sessionTicketKeyProviderFunc(func(context.Context) ([]SessionTicketKey, error) {
+				return nil, errors.New("KMS unavailable")
+			})
+			_, err := newSessionTicketKeyRing(provider, &tls.Config{}, utils.DefaultLogger)
+			Expect(err).To(MatchError("quic: fetching session ticket keys failed: KMS unavailable"))
+		})
+
+		It("rejects invalid keys", func() {
+			_, err := newSessionTicketKeyRing(StaticSessionTicketKeys{{ID: "foo"}, {ID: "foo"}}, &tls.Config{}, utils.DefaultLogger)
+			Expect(err).To(MatchError(ContainSubstring("duplicate session ticket key ID")))
+		})
+
+		It("stops when done is closed", func() {
+			r, err := newSessionTicketKeyRing(StaticSessionTicketKeys{{ID: "foo"}}, &tls.Config{}, utils.DefaultLogger)
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				r.run(done)
+			}()
+			Consistently(stopped).ShouldNot(BeClosed())
+			close(done)
+			Eventually(stopped).Should(BeClosed())
+		})
+
+		It("installs the keys on configs returned by GetConfigForClient", func() {
+			handshake := func(serverConf, clientConf *tls.Config) (didResume bool) {
+				c, s := net.Pipe()
+				go func() {
+					defer GinkgoRecover()
+					server := tls.Server(s, serverConf)
+					defer server.Close()
+					Expect(server.Handshake()).To(Succeed())
+					_, err := server.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				client := tls.Client(c, clientConf)
+				defer client.Close()
+				Expect(client.Handshake()).To(Succeed())
+				// reading the data processes the session ticket sent after the handshake
+				_, err := io.ReadFull(client, make([]byte, 6))
+				Expect(err).ToNot(HaveOccurred())
+				return client.ConnectionState().DidResume
+			}
+
+			key := SessionTicketKey{ID: "foo", Key: [32]byte{1}}
+			configForClient := testdata.GetTLSConfig()
+			configForClient.SetSessionTicketKeys([][32]byte{{42}})
+			tlsConf := testdata.GetTLSConfig()
+			tlsConf.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) { return configForClient, nil }
+			_, err := newSessionTicketKeyRing(StaticSessionTicketKeys{key}, tlsConf, utils.DefaultLogger)
+			Expect(err).ToNot(HaveOccurred())
+			clientConf := &tls.Config{
+				RootCAs:            testdata.GetRootCA(),
+				ServerName:         "localhost",
+				ClientSessionCache: tls.NewLRUClientSessionCache(1),
+			}
+			Expect(handshake(tlsConf, clientConf)).To(BeFalse())
+
+			// Another server using the same key can resume the session.
+			otherServerConf := testdata.GetTLSConfig()
+			otherServerConf.SetSessionTicketKeys([][32]byte{key.Key})
+			Expect(handshake(otherServerConf, clientConf)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,357 @@
+// Package simnet provides an in-memory network that can be used to test applications using QUIC
+// without opening real sockets.
+//
+// NewPair returns two connected net.PacketConns. Packets sent on one of them are delivered to the other one,
+// after being subjected to the conditions configured in the LinkConfig: latency, jitter, bandwidth, loss,
+// reordering and a maximum packet size.
+// All random decisions are drawn from a pseudo-random number generator seeded with LinkConfig.Seed,
+// such that the same sequence of packets experiences the same sequence of network conditions.
+//
+// The connections can be passed to quic.Transport like any other net.PacketConn:
+//
+//	clientConn, serverConn := simnet.NewPair(&simnet.LinkConfig{Latency: 20 * time.Millisecond, LossRate: 0.01})
+//	ln, err := (&quic.Transport{Conn: serverConn}).Listen(tlsConf, quicConf)
+//	// ...
+//	conn, err := (&quic.Transport{Conn: clientConn}).Dial(ctx, serverConn.LocalAddr(), tlsConf, quicConf)
+package simnet
+
+import (
+	"container/heap"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultReorderDelay is the additional delay applied to reordered packets,
+// if LinkConfig.ReorderDelay is not set.
+const DefaultReorderDelay = 10 * time.Millisecond
+
+// receiveQueueLen is the number of packets that can be queued on the receiving side
+// before packets are dropped.
+const receiveQueueLen = 1024
+
+// LinkConfig configures the conditions that packets experience when sent over a link.
+// The zero value is a link without any delay, loss or bandwidth limitation.
+type LinkConfig struct {
+	// Latency is the one-way delay applied to every packet.
+	Latency time.Duration
+	// Jitter is the maximum random variation of the latency.
+	// The delay of every packet is chosen uniformly from [Latency - Jitter, Latency + Jitter].
+	// Note that jitter can reorder packets.
+	Jitter time.Duration
+	// Bandwidth is the bandwidth of the link, in bytes per second.
+	// If zero, the bandwidth is unlimited.
+	Bandwidth uint64
+	// QueueSize is the maximum number of bytes that can be queued on a bandwidth-limited link.
+	// Packets that would exceed the queue size are dropped.
+	// If zero, the queue is unlimited. It has no effect if Bandwidth is zero.
+	QueueSize uint64
+	// LossRate is the probability that a packet is dropped, between 0 and 1.
+	LossRate float64
+	// ReorderRate is the probability that a packet is reordered, between 0 and 1.
+	// Reordered packets are delayed by an additional ReorderDelay.
+	ReorderRate float64
+	// ReorderDelay is the additional delay applied to reordered packets.
+	// If zero, DefaultReorderDelay is used.
+	ReorderDelay time.Duration
+	// MTU is the maximum size of a packet. Larger packets are dropped.
+	// If zero, the size of packets is not limited.
+	MTU int
+	// Seed is used to seed the pseudo-random number generator used for loss, jitter and reordering.
+	Seed int64
+}
+
+func (c *LinkConfig) reorderDelay() time.Duration {
+	if c.ReorderDelay == 0 {
+		return DefaultReorderDelay
+	}
+	return c.ReorderDelay
+}
+
+// NewPair creates two connected Conns.
+// Packets sent in both directions experience the network conditions described by the config.
+// A nil config is equivalent to an empty LinkConfig.
+func NewPair(config *LinkConfig) (*Conn, *Conn) {
+	return NewAsymmetricPair(config, config)
+}
+
+// NewAsymmetricPair creates two connected Conns.
+// Packets sent from the first to the second Conn experience the network conditions described by aToB,
+// packets sent in the opposite direction those described by bToA.
+// A nil config is equivalent to an empty LinkConfig.
+func NewAsymmetricPair(aToB, bToA *LinkConfig) (*Conn, *Conn) {
+	a := newConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4242})
+	b := newConn(&net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 4343})
+	a.remote = b.local
+	b.remote = a.local
+	a.link = newLink(aToB, a.local, b)
+	b.link = newLink(bToA, b.local, a)
+	return a, b
+}
+
+type packet struct {
+	data []byte
+	from net.Addr
+}
+
+// A Conn is one end of a simulated network link.
+// It implements the net.PacketConn interface.
+type Conn struct {
+	local, remote *net.UDPAddr
+	link          *link
+
+	incoming  chan packet
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mutex           sync.Mutex
+	readDeadline    time.Time
+	deadlineChanged chan struct{}
+}
+
+var _ net.PacketConn = &Conn{}
+
+func newConn(addr *net.UDPAddr) *Conn {
+	return &Conn{
+		local:           addr,
+		incoming:        make(chan packet, receiveQueueLen),
+		closed:          make(chan struct{}),
+		deadlineChanged: make(chan struct{}),
+	}
+}
+
+// ReadFrom reads a packet from the connection.
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mutex.Lock()
+		deadline := c.readDeadline
+		deadlineChanged := c.deadlineChanged
+		c.mutex.Unlock()
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return 0, nil, c.opError("read", os.ErrDeadlineExceeded)
+			}
+			timer = time.NewTimer(d)
+			timeout = timer.C
+		}
+
+		n, from, done, err := c.readOrWait(b, timeout, deadlineChanged)
+		if timer != nil {
+			timer.Stop()
+		}
+		if done {
+			return n, from, err
+		}
+	}
+}
+
+func (c *Conn) readOrWait(b []byte, timeout <-chan time.Time, deadlineChanged <-chan struct{}) (int, net.Addr, bool, error) {
+	select {
+	case p := <-c.incoming:
+		return copy(b, p.data), p.from, true, nil
+	case <-c.closed:
+		return 0, nil, true, c.opError("read", net.ErrClosed)
+	case <-timeout:
+		return 0, nil, true, c.opError("read", os.ErrDeadlineExceeded)
+	case <-deadlineChanged:
+		return 0, nil, false, nil
+	}
+}
+
+// WriteTo sends a packet to the peer.
+// Like for a UDP socket, packets addressed to anyone but the peer are silently dropped.
+func (c *Conn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, c.opError("write", net.ErrClosed)
+	default:
+	}
+	if addr.String() == c.remote.String() {
+		c.link.Send(b)
+	}
+	return len(b), nil
+}
+
+// Close closes the connection.
+// Packets that are still in flight towards the peer will be delivered.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// LocalAddr returns the local address.
+func (c *Conn) LocalAddr() net.Addr { return c.local }
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr { return c.remote }
+
+// SetDeadline sets the read deadline. Writes never block.
+func (c *Conn) SetDeadline(t time.Time) error { return c.SetReadDeadline(t) }
+
+// SetReadDeadline sets the read deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mutex.Lock()
+	c.readDeadline = t
+	close(c.deadlineChanged)
+	c.deadlineChanged = make(chan struct{})
+	c.mutex.Unlock()
+	return nil
+}
+
+// SetWriteDeadline is a no-op, since writes never block.
+func (c *Conn) SetWriteDeadline(time.Time) error { return nil }
+
+// SetReadBuffer is a no-op.
+// It is implemented to avoid warnings about the receive buffer size being logged by quic-go.
+func (c *Conn) SetReadBuffer(int) error { return nil }
+
+// SetWriteBuffer is a no-op.
+// It is implemented to avoid warnings about the send buffer size being logged by quic-go.
+func (c *Conn) SetWriteBuffer(int) error { return nil }
+
+func (c *Conn) deliver(p packet) {
+	select {
+	case <-c.closed:
+		return
+	default:
+	}
+	select {
+	case c.incoming <- p:
+	default: // receive queue full, drop the packet
+	}
+}
+
+func (c *Conn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "udp", Source: c.local, Addr: c.remote, Err: err}
+}
+
+type queuedPacket struct {
+	packet
+	deliveryTime time.Time
+	seq          uint64 // used to keep the order of packets with the same delivery time
+}
+
+type packetQueue []queuedPacket
+
+func (q packetQueue) Len() int { return len(q) }
+func (q packetQueue) Less(i, j int) bool {
+	if q[i].deliveryTime.Equal(q[j].deliveryTime) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].deliveryTime.Before(q[j].deliveryTime)
+}
+func (q packetQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *packetQueue) Push(x any)   { *q = append(*q, x.(queuedPacket)) }
+func (q *packetQueue) Pop() any {
+	old := *q
+	n := len(old)
+	p := old[n-1]
+	*q = old[:n-1]
+	return p
+}
+
+// A link transports packets in one direction.
+type link struct {
+	config LinkConfig
+	from   net.Addr
+	to     *Conn
+
+	mutex     sync.Mutex
+	rand      *rand.Rand
+	queue     packetQueue
+	seq       uint64
+	busyUntil time.Time
+	timer     *time.Timer
+}
+
+func newLink(config *LinkConfig, from net.Addr, to *Conn) *link {
+	if config == nil {
+		config = &LinkConfig{}
+	}
+	return &link{
+		config: *config,
+		from:   from,
+		to:     to,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+	}
+}
+
+func (l *link) Send(b []byte) {
+	if l.config.MTU > 0 && len(b) > l.config.MTU {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	departure := now
+	if l.config.Bandwidth > 0 {
+		if l.busyUntil.After(now) {
+			if l.config.QueueSize > 0 {
+				queued := uint64(l.busyUntil.Sub(now)) * l.config.Bandwidth / uint64(time.Second)
+				if queued+uint64(len(b)) > l.config.QueueSize {
+					return
+				}
+			}
+			departure = l.busyUntil
+		}
+		departure = departure.Add(time.Duration(uint64(len(b)) * uint64(time.Second) / l.config.Bandwidth))
+		l.busyUntil = departure
+	}
+	// Lost packets still consume bandwidth.
+	if l.config.LossRate > 0 && l.rand.Float64() < l.config.LossRate {
+		return
+	}
+	delay := l.config.Latency
+	if l.config.Jitter > 0 {
+		delay += time.Duration(l.rand.Int63n(int64(2*l.config.Jitter)+1)) - l.config.Jitter
+	}
+	if l.config.ReorderRate > 0 && l.rand.Float64() < l.config.ReorderRate {
+		delay += l.config.reorderDelay()
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+	heap.Push(&l.queue, queuedPacket{
+		packet:       packet{data: data, from: l.from},
+		deliveryTime: departure.Add(delay),
+		seq:          l.seq,
+	})
+	l.seq++
+	l.resetTimer(now)
+}
+
+// resetTimer must be called with the mutex held.
+func (l *link) resetTimer(now time.Time) {
+	if len(l.queue) == 0 {
+		return
+	}
+	d := l.queue[0].deliveryTime.Sub(now)
+	if l.timer == nil {
+		l.timer = time.AfterFunc(d, l.deliver)
+		return
+	}
+	l.timer.Reset(d)
+}
+
+func (l *link) deliver() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+	for len(l.queue) > 0 && !l.queue[0].deliveryTime.After(now) {
+		p := heap.Pop(&l.queue).(queuedPacket)
+		l.to.deliver(p.packet)
+	}
+	l.resetTimer(now)
+}
@@ -0,0 +1,243 @@
+package simnet
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Simulated Network", func() {
+	read := func(c *Conn) ([]byte, net.Addr) {
+		b := make([]byte, 1500)
+		n, addr, err := c.ReadFrom(b)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return b[:n], addr
+	}
+
+	// sendAndCount sends num packets, and counts how many of them arrive within the timeout
+	sendAndCount := func(a, b *Conn, num int, timeout time.Duration) int {
+		for i := 0; i < num; i++ {
+			_, err := a.WriteTo([]byte{byte(i)}, b.LocalAddr())
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		}
+		var count int
+		ExpectWithOffset(1, b.SetReadDeadline(time.Now().Add(timeout))).To(Succeed())
+		for {
+			if _, _, err := b.ReadFrom(make([]byte, 10)); err != nil {
+				ExpectWithOffset(1, errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+				return count
+			}
+			count++
+		}
+	}
+
+	It("delivers packets in both directions", func() {
+		a, b := NewPair(nil)
+		_, err := a.WriteTo([]byte("foobar"), b.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data, addr := read(b)
+		Expect(data).To(Equal([]byte("foobar")))
+		Expect(addr).To(Equal(a.LocalAddr()))
+		_, err = b.WriteTo([]byte("raboof"), addr)
+		Expect(err).ToNot(HaveOccurred())
+		data, addr = read(a)
+		Expect(data).To(Equal([]byte("raboof")))
+		Expect(addr).To(Equal(b.LocalAddr()))
+	})
+
+	It("copies the data", func() {
+		a, b := NewPair(nil)
+		data := []byte("foobar")
+		_, err := a.WriteTo(data, b.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data[0] = 'x'
+		received, _ := read(b)
+		Expect(received).To(Equal([]byte("foobar")))
+	})
+
+	It("drops packets sent to other addresses", func() {
+		a, b := NewPair(nil)
+		n, err := a.WriteTo([]byte("foobar"), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+		Expect(sendAndCount(a, b, 0, 50*time.Millisecond)).To(BeZero())
+	})
+
+	It("delays packets", func() {
+		a, b := NewAsymmetricPair(&LinkConfig{Latency: 50 * time.Millisecond}, nil)
+		start := time.Now()
+		_, err := a.WriteTo([]byte("foobar"), b.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		read(b)
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+		// the other direction doesn't have any latency
+		start = time.Now()
+		_, err = b.WriteTo([]byte("foobar"), a.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		read(a)
+		Expect(time.Since(start)).To(BeNumerically("<", 25*time.Millisecond))
+	})
+
+	It("limits the bandwidth", func() {
+		a, b := NewPair(&LinkConfig{Bandwidth: 100 * 1000}) // 100 kB/s
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			_, err := a.WriteTo(make([]byte, 1000), b.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		for i := 0; i < 5; i++ {
+			read(b)
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 50*time.Millisecond))
+	})
+
+	It("drops packets when the queue is full", func() {
+		a, b := NewPair(&LinkConfig{Bandwidth: 100 * 1000, QueueSize: 3000})
+		for i := 0; i < 5; i++ {
+			_, err := a.WriteTo(make([]byte, 1000), b.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(sendAndCount(a, b, 0, 100*time.Millisecond)).To(Equal(3))
+	})
+
+	It("drops packets larger than the MTU", func() {
+		a, b := NewPair(&LinkConfig{MTU: 1200})
+		_, err := a.WriteTo(make([]byte, 1201), b.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = a.WriteTo(make([]byte, 1200), b.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		data, _ := read(b)
+		Expect(data).To(HaveLen(1200))
+	})
+
+	It("drops packets", func() {
+		a, b := NewPair(&LinkConfig{LossRate: 0.5})
+		Expect(sendAndCount(a, b, 500, 50*time.Millisecond)).To(BeNumerically("~", 250, 50))
+	})
+
+	It("is deterministic", func() {
+		received := func() []byte {
+			a, b := NewPair(&LinkConfig{LossRate: 0.3, ReorderRate: 0.3, ReorderDelay: 5 * time.Millisecond, Seed: 42})
+			for i := 0; i < 100; i++ {
+				_, err := a.WriteTo([]byte{byte(i)}, b.LocalAddr())
+				Expect(err).ToNot(HaveOccurred())
+			}
+			var data []byte
+			Expect(b.SetReadDeadline(time.Now().Add(50 * time.Millisecond))).To(Succeed())
+			for {
+				buf := make([]byte, 10)
+				n, _, err := b.ReadFrom(buf)
+				if err != nil {
+					return data
+				}
+				data = append(data, buf[:n]...)
+			}
+		}
+		data := received()
+		Expect(len(data)).To(BeNumerically("<", 100))
+		Expect(received()).To(Equal(data))
+	})
+
+	It("reorders packets", func() {
+		a, b := NewPair(&LinkConfig{ReorderRate: 0.5, ReorderDelay: 10 * time.Millisecond})
+		for i := 0; i < 100; i++ {
+			_, err := a.WriteTo([]byte{byte(i)}, b.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		var reordered bool
+		var last byte
+		for i := 0; i < 100; i++ {
+			data, _ := read(b)
+			if i > 0 && data[0] < last {
+				reordered = true
+			}
+			last = data[0]
+		}
+		Expect(reordered).To(BeTrue())
+	})
+
+	It("unblocks reads when the deadline changes", func() {
+		_, b := NewPair(nil)
+		errChan := make(chan error, 1)
+		go func() {
+			_, _, err := b.ReadFrom(make([]byte, 10))
+			errChan <- err
+		}()
+		Consistently(errChan).ShouldNot(Receive())
+		Expect(b.SetReadDeadline(time.Now().Add(-time.Second))).To(Succeed())
+		var err error
+		Eventually(errChan).Should(Receive(&err))
+		Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+		var nerr net.Error
+		Expect(errors.As(err, &nerr)).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeTrue())
+	})
+
+	It("closes", func() {
+		a, b := NewPair(nil)
+		errChan := make(chan error, 1)
+		go func() {
+			_, _, err := a.ReadFrom(make([]byte, 10))
+			errChan <- err
+		}()
+		Expect(a.Close()).To(Succeed())
+		var err error
+		Eventually(errChan).Should(Receive(&err))
+		Expect(errors.Is(err, net.ErrClosed)).To(BeTrue())
+		_, err = a.WriteTo([]byte("foobar"), b.LocalAddr())
+		Expect(errors.Is(err, net.ErrClosed)).To(BeTrue())
+	})
+
+	It("runs a QUIC connection", func() {
+		clientConn, serverConn := NewPair(&LinkConfig{
+			Latency:  5 * time.Millisecond,
+			Jitter:   time.Millisecond,
+			LossRate: 0.02,
+			MTU:      1400,
+			Seed:     1,
+		})
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"simnet"}
+		ln, err := (&quic.Transport{Conn: serverConn}).Listen(tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		data := make([]byte, 100<<10)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := (&quic.Transport{Conn: clientConn}).Dial(
+			ctx,
+			serverConn.LocalAddr(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"simnet"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		str, err := conn.AcceptUniStream(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		received, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(received).To(Equal(data))
+	})
+})
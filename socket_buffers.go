@@ -0,0 +1,115 @@
+package quic
+
+import (
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// SocketBuffers describes the kernel buffers of the UDP socket used by a Transport.
+//
+// quic-go tries to size the buffers such that a full connection-level flow control window fits into them.
+// The buffers are sized when the Transport is first used, and enlarged when the flow control window
+// of a connection is auto-tuned beyond the current size.
+type SocketBuffers struct {
+	// DesiredReceiveBufferSize is the receive buffer size that quic-go last tried to set.
+	DesiredReceiveBufferSize int
+	// ReceiveBufferSize is the size of the receive buffer, as reported by the kernel.
+	// It is 0 if the size couldn't be determined.
+	ReceiveBufferSize int
+	// ReceiveBufferError is the error that occurred when increasing the receive buffer size.
+	// It is nil if the receive buffer is at least as large as the DesiredReceiveBufferSize.
+	ReceiveBufferError error
+
+	// DesiredSendBufferSize is the send buffer size that quic-go last tried to set.
+	DesiredSendBufferSize int
+	// SendBufferSize is the size of the send buffer, as reported by the kernel.
+	// It is 0 if the size couldn't be determined.
+	SendBufferSize int
+	// SendBufferError is the error that occurred when increasing the send buffer size.
+	// It is nil if the send buffer is at least as large as the DesiredSendBufferSize.
+	SendBufferError error
+}
+
+// The socketBufferTuner sizes the kernel buffers of a socket.
+// Buffers are only ever increased.
+type socketBufferTuner struct {
+	conn   net.PacketConn
+	logger utils.Logger
+
+	mutex   sync.Mutex
+	buffers SocketBuffers
+}
+
+func newSocketBufferTuner(conn net.PacketConn, logger utils.Logger) *socketBufferTuner {
+	return &socketBufferTuner{conn: conn, logger: logger}
+}
+
+// Init sizes the buffers to the default size.
+// If this fails, a warning is logged, unless disabled by the QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING environment variable.
+func (t *socketBufferTuner) Init() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.setBuffers(protocol.DesiredReceiveBufferSize, protocol.DesiredSendBufferSize)
+	for _, err := range []error{t.buffers.ReceiveBufferError, t.buffers.SendBufferError} {
+		if err == nil || strings.Contains(err.Error(), "use of closed network connection") {
+			continue
+		}
+		setBufferWarningOnce.Do(func() {
+			if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
+				return
+			}
+			log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
+		})
+	}
+}
+
+// EnsureWindow makes sure that the buffers are large enough to hold a flow control window of the given size.
+func (t *socketBufferTuner) EnsureWindow(window protocol.ByteCount) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	size := int(window)
+	if size <= t.buffers.DesiredReceiveBufferSize && size <= t.buffers.DesiredSendBufferSize {
+		return
+	}
+	t.setBuffers(utils.Max(size, t.buffers.DesiredReceiveBufferSize), utils.Max(size, t.buffers.DesiredSendBufferSize))
+	if t.buffers.ReceiveBufferError != nil {
+		t.logger.Debugf("Increasing the receive buffer for a flow control window of %d kiB failed: %s", size/1024, t.buffers.ReceiveBufferError)
+	}
+	if t.buffers.SendBufferError != nil {
+		t.logger.Debugf("Increasing the send buffer for a flow control window of %d kiB failed: %s", size/1024, t.buffers.SendBufferError)
+	}
+}
+
+func (t *socketBufferTuner) setBuffers(receive, send int) {
+	t.buffers.DesiredReceiveBufferSize = receive
+	t.buffers.ReceiveBufferSize, t.buffers.ReceiveBufferError = setReceiveBuffer(t.conn, receive)
+	t.buffers.DesiredSendBufferSize = send
+	t.buffers.SendBufferSize, t.buffers.SendBufferError = setSendBuffer(t.conn, send)
+}
+
+// Buffers returns the current state of the socket buffers.
+func (t *socketBufferTuner) Buffers() SocketBuffers {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.buffers
+}
+
+// The socketBufferConn makes the socketBufferTuner available to the connections using a rawConn.
+type socketBufferConn struct {
+	rawConn
+
+	tuner *socketBufferTuner
+}
+
+var _ rawConn = &socketBufferConn{}
+
+func (c *socketBufferConn) socketBufferTuner() *socketBufferTuner { return c.tuner }
@@ -0,0 +1,67 @@
+package quic
+
+import (
+	"net"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Socket Buffer Tuner", func() {
+	expectBuffersConsistent := func(b SocketBuffers) {
+		if b.ReceiveBufferError == nil {
+			Expect(b.ReceiveBufferSize).To(BeNumerically(">=", b.DesiredReceiveBufferSize))
+		}
+		if b.SendBufferError == nil {
+			Expect(b.SendBufferSize).To(BeNumerically(">=", b.DesiredSendBufferSize))
+		}
+	}
+
+	It("increases the buffers when the flow control window grows", func() {
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+
+		tuner := newSocketBufferTuner(c, utils.DefaultLogger)
+		tuner.EnsureWindow(protocol.DesiredReceiveBufferSize)
+		b := tuner.Buffers()
+		Expect(b.DesiredReceiveBufferSize).To(Equal(protocol.DesiredReceiveBufferSize))
+		Expect(b.DesiredSendBufferSize).To(Equal(protocol.DesiredReceiveBufferSize))
+		expectBuffersConsistent(b)
+
+		tuner.EnsureWindow(4 << 20)
+		b = tuner.Buffers()
+		Expect(b.DesiredReceiveBufferSize).To(Equal(4 << 20))
+		Expect(b.DesiredSendBufferSize).To(Equal(4 << 20))
+		expectBuffersConsistent(b)
+	})
+
+	It("never decreases the buffers", func() {
+		c, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+
+		tuner := newSocketBufferTuner(c, utils.DefaultLogger)
+		tuner.EnsureWindow(4 << 20)
+		tuner.EnsureWindow(1 << 20)
+		b := tuner.Buffers()
+		Expect(b.DesiredReceiveBufferSize).To(Equal(4 << 20))
+		Expect(b.DesiredSendBufferSize).To(Equal(4 << 20))
+	})
+
+	It("reports errors", func() {
+		// A plain net.PacketConn doesn't have SetReadBuffer and SetWriteBuffer methods.
+		tuner := newSocketBufferTuner(NewMockPacketConn(mockCtrl), utils.DefaultLogger)
+		tuner.EnsureWindow(1 << 20)
+		b := tuner.Buffers()
+		Expect(b.DesiredReceiveBufferSize).To(Equal(1 << 20))
+		Expect(b.ReceiveBufferSize).To(BeZero())
+		Expect(b.ReceiveBufferError).To(MatchError("connection doesn't allow setting of receive buffer size. Not a *net.UDPConn?"))
+		Expect(b.DesiredSendBufferSize).To(Equal(1 << 20))
+		Expect(b.SendBufferSize).To(BeZero())
+		Expect(b.SendBufferError).To(MatchError("connection doesn't allow setting of send buffer size. Not a *net.UDPConn?"))
+	})
+})
@@ -0,0 +1,379 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// A SOCKS5Proxy is a SOCKS5 proxy (RFC 1928) that supports the UDP ASSOCIATE command.
+type SOCKS5Proxy struct {
+	// Addr is the address of the proxy, e.g. "proxy.example.com:1080".
+	Addr string
+	// Username and Password are used for username / password authentication (RFC 1929).
+	// If Username is empty, no authentication is used.
+	Username, Password string
+}
+
+// DialAddrViaSOCKS5 establishes a new QUIC connection to a server through a SOCKS5 proxy.
+// It opens a TCP connection to the proxy, and sets up a UDP association. All QUIC packets
+// are then sent to (and received from) the UDP relay of the proxy, prefixed by a SOCKS5 UDP header.
+// The address of the server is resolved locally.
+// When the QUIC connection is closed, the UDP association is torn down. If the proxy closes the TCP connection,
+// the UDP association ends, and the QUIC connection eventually times out.
+// Fragmented SOCKS5 datagrams are not supported, and are dropped.
+// See DialAddr for more details.
+func DialAddrViaSOCKS5(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, proxy *SOCKS5Proxy) (Connection, error) {
+	return dialAddrViaSOCKS5(ctx, addr, tlsConf, conf, proxy, false)
+}
+
+// DialAddrEarlyViaSOCKS5 establishes a new 0-RTT QUIC connection to a server through a SOCKS5 proxy.
+// See DialAddrViaSOCKS5 for more details.
+func DialAddrEarlyViaSOCKS5(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, proxy *SOCKS5Proxy) (EarlyConnection, error) {
+	return dialAddrViaSOCKS5(ctx, addr, tlsConf, conf, proxy, true)
+}
+
+func dialAddrViaSOCKS5(ctx context.Context, addr string, tlsConf *tls.Config, conf *Config, proxy *SOCKS5Proxy, use0RTT bool) (EarlyConnection, error) {
+	if proxy == nil {
+		return nil, errors.New("quic: no SOCKS5 proxy")
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pconn, err := proxy.associate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tr, err := setupTransport(pconn, tlsConf, true)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	conn, err := tr.dial(ctx, udpAddr, addr, tlsConf, conf, use0RTT, protocol.ConnectionID{}, packetInfo{})
+	if err != nil {
+		tr.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+const (
+	socks5Version = 5
+
+	socks5AuthNone             = 0x0
+	socks5AuthUsernamePassword = 0x2
+	socks5AuthNoAcceptable     = 0xff
+
+	socks5CmdUDPAssociate = 0x3
+
+	socks5AddrIPv4 = 0x1
+	socks5AddrIPv6 = 0x4
+
+	socks5ReplySucceeded = 0x0
+
+	// 2 bytes reserved, 1 byte fragment number, 1 byte address type, 16 bytes IPv6 address, 2 bytes port
+	socks5MaxUDPHeaderLen = 2 + 1 + 1 + 16 + 2
+)
+
+// associate connects to the proxy, and sets up a UDP association.
+func (p *SOCKS5Proxy) associate(ctx context.Context) (*socks5PacketConn, error) {
+	var d net.Dialer
+	tcpConn, err := d.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4zero, Port: 0})
+	if err != nil {
+		tcpConn.Close()
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		tcpConn.SetDeadline(deadline)
+	}
+	// Interrupt the handshake when the context is canceled.
+	handshakeDone := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			tcpConn.SetDeadline(time.Unix(1, 0))
+		case <-handshakeDone:
+		}
+	}()
+	relayAddr, err := p.handshake(tcpConn, udpConn.LocalAddr().(*net.UDPAddr).Port)
+	close(handshakeDone)
+	if err != nil {
+		tcpConn.Close()
+		udpConn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	tcpConn.SetDeadline(time.Time{})
+	// Many proxies return an unspecified address, expecting the client to use the address of the proxy.
+	if relayAddr.Addr().IsUnspecified() {
+		proxyAddr := tcpConn.RemoteAddr().(*net.TCPAddr).AddrPort()
+		relayAddr = netip.AddrPortFrom(proxyAddr.Addr().Unmap(), relayAddr.Port())
+	}
+	return newSOCKS5PacketConn(tcpConn, udpConn, relayAddr), nil
+}
+
+// handshake performs the SOCKS5 handshake, and sends the UDP ASSOCIATE command.
+// It returns the address of the UDP relay.
+func (p *SOCKS5Proxy) handshake(c net.Conn, localPort int) (netip.AddrPort, error) {
+	methods := []byte{socks5AuthNone}
+	if p.Username != "" {
+		methods = []byte{socks5AuthNone, socks5AuthUsernamePassword}
+	}
+	b := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := c.Write(b); err != nil {
+		return netip.AddrPort{}, err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(c, resp[:]); err != nil {
+		return netip.AddrPort{}, err
+	}
+	if resp[0] != socks5Version {
+		return netip.AddrPort{}, fmt.Errorf("socks5: unexpected version: %d", resp[0])
+	}
+	switch resp[1] {
+	case socks5AuthNone:
+	case socks5AuthUsernamePassword:
+		if p.Username == "" {
+			return netip.AddrPort{}, errors.New("socks5: proxy requires authentication")
+		}
+		if err := p.authenticate(c); err != nil {
+			return netip.AddrPort{}, err
+		}
+	case socks5AuthNoAcceptable:
+		return netip.AddrPort{}, errors.New("socks5: no acceptable authentication method")
+	default:
+		return netip.AddrPort{}, fmt.Errorf("socks5: unexpected authentication method: %d", resp[1])
+	}
+
+	// The client's address is not known, since it might be behind a NAT.
+	// Use the unspecified address, but include the port, as recommended by RFC 1928.
+	req := []byte{socks5Version, socks5CmdUDPAssociate, 0}
+	req = appendSOCKS5Addr(req, netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(localPort)))
+	if _, err := c.Write(req); err != nil {
+		return netip.AddrPort{}, err
+	}
+	var hdr [3]byte
+	if _, err := io.ReadFull(c, hdr[:]); err != nil {
+		return netip.AddrPort{}, err
+	}
+	if hdr[0] != socks5Version {
+		return netip.AddrPort{}, fmt.Errorf("socks5: unexpected version: %d", hdr[0])
+	}
+	if hdr[1] != socks5ReplySucceeded {
+		return netip.AddrPort{}, fmt.Errorf("socks5: UDP ASSOCIATE failed: %s", socks5ReplyString(hdr[1]))
+	}
+	return readSOCKS5Addr(c)
+}
+
+func (p *SOCKS5Proxy) authenticate(c net.Conn) error {
+	if len(p.Username) > 255 || len(p.Password) > 255 {
+		return errors.New("socks5: username or password too long")
+	}
+	b := make([]byte, 0, 3+len(p.Username)+len(p.Password))
+	b = append(b, 1, byte(len(p.Username)))
+	b = append(b, p.Username...)
+	b = append(b, byte(len(p.Password)))
+	b = append(b, p.Password...)
+	if _, err := c.Write(b); err != nil {
+		return err
+	}
+	var resp [2]byte
+	if _, err := io.ReadFull(c, resp[:]); err != nil {
+		return err
+	}
+	if resp[1] != 0 {
+		return errors.New("socks5: authentication failed")
+	}
+	return nil
+}
+
+func socks5ReplyString(r byte) string {
+	switch r {
+	case 1:
+		return "general SOCKS server failure"
+	case 2:
+		return "connection not allowed by ruleset"
+	case 3:
+		return "network unreachable"
+	case 4:
+		return "host unreachable"
+	case 5:
+		return "connection refused"
+	case 6:
+		return "TTL expired"
+	case 7:
+		return "command not supported"
+	case 8:
+		return "address type not supported"
+	default:
+		return fmt.Sprintf("unknown error %d", r)
+	}
+}
+
+func appendSOCKS5Addr(b []byte, addr netip.AddrPort) []byte {
+	ip := addr.Addr().Unmap()
+	if ip.Is4() {
+		b = append(b, socks5AddrIPv4)
+	} else {
+		b = append(b, socks5AddrIPv6)
+	}
+	b = append(b, ip.AsSlice()...)
+	return binary.BigEndian.AppendUint16(b, addr.Port())
+}
+
+// readSOCKS5Addr reads the address in a reply to a command.
+// Domain names are not supported.
+func readSOCKS5Addr(r io.Reader) (netip.AddrPort, error) {
+	var atyp [1]byte
+	if _, err := io.ReadFull(r, atyp[:]); err != nil {
+		return netip.AddrPort{}, err
+	}
+	var ip []byte
+	switch atyp[0] {
+	case socks5AddrIPv4:
+		ip = make([]byte, 4)
+	case socks5AddrIPv6:
+		ip = make([]byte, 16)
+	default:
+		return netip.AddrPort{}, fmt.Errorf("socks5: unsupported address type: %d", atyp[0])
+	}
+	if _, err := io.ReadFull(r, ip); err != nil {
+		return netip.AddrPort{}, err
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return netip.AddrPort{}, err
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr.Unmap(), binary.BigEndian.Uint16(port[:])), nil
+}
+
+// parseSOCKS5UDPHeader parses the header of a datagram received from the UDP relay.
+// It returns the source address of the datagram, and the length of the header.
+func parseSOCKS5UDPHeader(b []byte) (*net.UDPAddr, int, error) {
+	if len(b) < 4 {
+		return nil, 0, io.EOF
+	}
+	if b[2] != 0 {
+		return nil, 0, errors.New("socks5: fragmented datagrams not supported")
+	}
+	var ipLen int
+	switch b[3] {
+	case socks5AddrIPv4:
+		ipLen = 4
+	case socks5AddrIPv6:
+		ipLen = 16
+	default:
+		return nil, 0, fmt.Errorf("socks5: unsupported address type: %d", b[3])
+	}
+	hdrLen := 4 + ipLen + 2
+	if len(b) < hdrLen {
+		return nil, 0, io.EOF
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, b[4:4+ipLen])
+	return &net.UDPAddr{IP: ip, Port: int(binary.BigEndian.Uint16(b[4+ipLen:]))}, hdrLen, nil
+}
+
+// A socks5PacketConn sends and receives datagrams via the UDP relay of a SOCKS5 proxy.
+type socks5PacketConn struct {
+	tcpConn   net.Conn // the UDP association ends when this connection is closed
+	conn      *net.UDPConn
+	relayAddr netip.AddrPort
+
+	readBuf []byte // only used by ReadFrom, which is not called concurrently
+	bufPool sync.Pool
+
+	closeOnce sync.Once
+}
+
+var _ net.PacketConn = &socks5PacketConn{}
+
+func newSOCKS5PacketConn(tcpConn net.Conn, conn *net.UDPConn, relayAddr netip.AddrPort) *socks5PacketConn {
+	c := &socks5PacketConn{
+		tcpConn:   tcpConn,
+		conn:      conn,
+		relayAddr: relayAddr,
+		readBuf:   make([]byte, socks5MaxUDPHeaderLen+protocol.MaxPacketBufferSize),
+		bufPool: sync.Pool{New: func() any {
+			b := make([]byte, 0, socks5MaxUDPHeaderLen+protocol.MaxPacketBufferSize)
+			return &b
+		}},
+	}
+	go func() {
+		// The proxy doesn't send anything on the TCP connection after the UDP association was set up.
+		// Reading only returns once the proxy closes the connection, which ends the UDP association.
+		io.Copy(io.Discard, tcpConn)
+		c.Close()
+	}()
+	return c
+}
+
+func (c *socks5PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		n, addr, err := c.conn.ReadFromUDPAddrPort(c.readBuf)
+		if err != nil {
+			return 0, nil, err
+		}
+		// drop datagrams that weren't sent by the relay
+		if netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port()) != c.relayAddr {
+			continue
+		}
+		src, hdrLen, err := parseSOCKS5UDPHeader(c.readBuf[:n])
+		if err != nil {
+			continue
+		}
+		return copy(b, c.readBuf[hdrLen:n]), src, nil
+	}
+}
+
+func (c *socks5PacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return 0, fmt.Errorf("socks5: unsupported address type: %T", addr)
+	}
+	bp := c.bufPool.Get().(*[]byte)
+	defer c.bufPool.Put(bp)
+	b := append((*bp)[:0], 0, 0, 0) // reserved, fragment number
+	b = appendSOCKS5Addr(b, udpAddr.AddrPort())
+	hdrLen := len(b)
+	b = append(b, p...)
+	*bp = b
+	n, err := c.conn.WriteToUDPAddrPort(b, c.relayAddr)
+	if n > hdrLen {
+		n -= hdrLen
+	} else {
+		n = 0
+	}
+	return n, err
+}
+
+func (c *socks5PacketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.conn.Close()
+		c.tcpConn.Close()
+	})
+	return err
+}
+
+func (c *socks5PacketConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *socks5PacketConn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *socks5PacketConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *socks5PacketConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
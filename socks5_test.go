@@ -0,0 +1,306 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeSOCKS5Proxy is a minimal SOCKS5 proxy that only supports the UDP ASSOCIATE command.
+type fakeSOCKS5Proxy struct {
+	ln net.Listener
+
+	username, password string
+	reply              byte // the reply code sent in response to the UDP ASSOCIATE command
+
+	relayed  atomic.Int64      // number of datagrams relayed from the client
+	tcpConns chan net.Conn     // TCP connections that set up a UDP association
+	relays   chan *net.UDPConn // the UDP relays
+}
+
+func newFakeSOCKS5Proxy() *fakeSOCKS5Proxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	p := &fakeSOCKS5Proxy{
+		ln:       ln,
+		tcpConns: make(chan net.Conn, 10),
+		relays:   make(chan *net.UDPConn, 10),
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.handleConn(c)
+		}
+	}()
+	return p
+}
+
+func (p *fakeSOCKS5Proxy) Addr() string { return p.ln.Addr().String() }
+func (p *fakeSOCKS5Proxy) Close()       { p.ln.Close() }
+
+func (p *fakeSOCKS5Proxy) handleConn(c net.Conn) {
+	defer c.Close()
+	var hdr [2]byte
+	if _, err := io.ReadFull(c, hdr[:]); err != nil {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return
+	}
+	if p.username == "" {
+		c.Write([]byte{5, socks5AuthNone})
+	} else {
+		c.Write([]byte{5, socks5AuthUsernamePassword})
+		var b [2]byte
+		if _, err := io.ReadFull(c, b[:]); err != nil {
+			return
+		}
+		username := make([]byte, b[1])
+		if _, err := io.ReadFull(c, username); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(c, b[:1]); err != nil {
+			return
+		}
+		password := make([]byte, b[0])
+		if _, err := io.ReadFull(c, password); err != nil {
+			return
+		}
+		if string(username) != p.username || string(password) != p.password {
+			c.Write([]byte{1, 1})
+			return
+		}
+		c.Write([]byte{1, 0})
+	}
+	// UDP ASSOCIATE, with an IPv4 address
+	var req [10]byte
+	if _, err := io.ReadFull(c, req[:]); err != nil {
+		return
+	}
+	if req[1] != socks5CmdUDPAssociate || req[3] != socks5AddrIPv4 {
+		return
+	}
+	if p.reply != socks5ReplySucceeded {
+		c.Write([]byte{5, p.reply, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	clientAddr := netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), binary.BigEndian.Uint16(req[8:]))
+	relay, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return
+	}
+	defer relay.Close()
+	// use the unspecified address, the client is expected to use the address of the proxy
+	resp := []byte{5, socks5ReplySucceeded, 0}
+	resp = appendSOCKS5Addr(resp, netip.AddrPortFrom(netip.IPv4Unspecified(), uint16(relay.LocalAddr().(*net.UDPAddr).Port)))
+	c.Write(resp)
+	p.tcpConns <- c
+	p.relays <- relay
+
+	go func() {
+		b := make([]byte, 2000)
+		for {
+			n, addr, err := relay.ReadFromUDPAddrPort(b)
+			if err != nil {
+				return
+			}
+			if addr == clientAddr {
+				dst, hdrLen, err := parseSOCKS5UDPHeader(b[:n])
+				if err != nil {
+					continue
+				}
+				p.relayed.Add(1)
+				relay.WriteToUDP(b[hdrLen:n], dst)
+				continue
+			}
+			out := appendSOCKS5Addr([]byte{0, 0, 0}, addr)
+			out = append(out, b[:n]...)
+			relay.WriteToUDPAddrPort(out, clientAddr)
+		}
+	}()
+	// the UDP association ends when the TCP connection is closed
+	io.Copy(io.Discard, c)
+}
+
+var _ = Describe("SOCKS5", func() {
+	It("dials a connection through the proxy", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"socks5"}
+		ln, err := ListenAddr("127.0.0.1:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.Copy(str, str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := DialAddrViaSOCKS5(
+			ctx,
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"socks5"}},
+			nil,
+			&SOCKS5Proxy{Addr: proxy.Addr()},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		Expect(conn.RemoteAddr().String()).To(Equal(ln.Addr().String()))
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		data, err := io.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		Eventually(done).Should(BeClosed())
+		Expect(proxy.relayed.Load()).To(BeNumerically(">", 0))
+	})
+
+	It("authenticates using username and password", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+		proxy.username = "user"
+		proxy.password = "secret"
+
+		c, err := (&SOCKS5Proxy{Addr: proxy.Addr(), Username: "user", Password: "secret"}).associate(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+		Expect(c.relayAddr.Addr()).To(Equal(netip.AddrFrom4([4]byte{127, 0, 0, 1})))
+		Expect(c.relayAddr.Port()).ToNot(BeZero())
+	})
+
+	It("errors when authentication fails", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+		proxy.username = "user"
+		proxy.password = "secret"
+
+		_, err := (&SOCKS5Proxy{Addr: proxy.Addr(), Username: "user", Password: "wrong"}).associate(context.Background())
+		Expect(err).To(MatchError("socks5: authentication failed"))
+		_, err = (&SOCKS5Proxy{Addr: proxy.Addr()}).associate(context.Background())
+		Expect(err).To(MatchError("socks5: proxy requires authentication"))
+	})
+
+	It("errors when the proxy rejects the UDP ASSOCIATE command", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+		proxy.reply = 7
+
+		_, err := (&SOCKS5Proxy{Addr: proxy.Addr()}).associate(context.Background())
+		Expect(err).To(MatchError("socks5: UDP ASSOCIATE failed: command not supported"))
+	})
+
+	It("aborts the handshake when the context is canceled", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			io.Copy(io.Discard, c) // never respond
+		}()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := (&SOCKS5Proxy{Addr: ln.Addr().String()}).associate(ctx)
+			errChan <- err
+		}()
+		Consistently(errChan, scaleDuration(20*time.Millisecond)).ShouldNot(Receive())
+		cancel()
+		Eventually(errChan).Should(Receive(MatchError(context.Canceled)))
+	})
+
+	It("sends and receives datagrams via the relay", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+
+		target, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer target.Close()
+
+		c, err := (&SOCKS5Proxy{Addr: proxy.Addr()}).associate(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+
+		n, err := c.WriteTo([]byte("foobar"), target.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(6))
+		b := make([]byte, 100)
+		n, relayAddr, err := target.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("foobar")))
+
+		clientAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: c.LocalAddr().(*net.UDPAddr).Port}
+		// datagrams that aren't sent by the relay are dropped
+		other, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer other.Close()
+		_, err = other.WriteTo(append([]byte{0, 0, 0}, b[:n]...), clientAddr)
+		Expect(err).ToNot(HaveOccurred())
+		// fragmented datagrams are dropped
+		var relay *net.UDPConn
+		Eventually(proxy.relays).Should(Receive(&relay))
+		frag := appendSOCKS5Addr([]byte{0, 0, 1}, target.LocalAddr().(*net.UDPAddr).AddrPort())
+		_, err = relay.WriteTo(append(frag, []byte("fragment")...), clientAddr)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = target.WriteTo([]byte("raboof"), relayAddr)
+		Expect(err).ToNot(HaveOccurred())
+		n, addr, err := c.ReadFrom(b)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[:n]).To(Equal([]byte("raboof")))
+		Expect(addr.(*net.UDPAddr).AddrPort()).To(Equal(target.LocalAddr().(*net.UDPAddr).AddrPort()))
+	})
+
+	It("closes when the proxy closes the TCP connection", func() {
+		proxy := newFakeSOCKS5Proxy()
+		defer proxy.Close()
+
+		c, err := (&SOCKS5Proxy{Addr: proxy.Addr()}).associate(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		defer c.Close()
+
+		errChan := make(chan error, 1)
+		go func() {
+			_, _, err := c.ReadFrom(make([]byte, 100))
+			errChan <- err
+		}()
+		Consistently(errChan, scaleDuration(20*time.Millisecond)).ShouldNot(Receive())
+		var tcpConn net.Conn
+		Eventually(proxy.tcpConns).Should(Receive(&tcpConn))
+		tcpConn.Close()
+		Eventually(errChan).Should(Receive(MatchError(net.ErrClosed)))
+	})
+})
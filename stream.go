@@ -9,6 +9,7 @@ import (
 	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -25,6 +26,8 @@ var errDeadline net.Error = &deadlineError{}
 type streamSender interface {
 	queueControlFrame(wire.Frame)
 	onHasStreamData(protocol.StreamID)
+	// called when lost stream data was queued for retransmission
+	onStreamDataRetransmission(_ protocol.StreamID, offset, length protocol.ByteCount)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -50,18 +53,50 @@ func (s *uniStreamSender) onStreamCompleted(protocol.StreamID) {
 
 var _ streamSender = &uniStreamSender{}
 
+// The lazyFlowController creates the flow controller of a stream when it is first used.
+// Many streams never carry any (or only very little) data,
+// so there's no need to allocate the flow controller when the stream is opened.
+// The two halves of a bidirectional stream share the same lazyFlowController.
+type lazyFlowController struct {
+	mutex sync.Mutex
+
+	streamID          protocol.StreamID
+	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	flowController    flowcontrol.StreamFlowController
+}
+
+func newLazyFlowController(id protocol.StreamID, newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController) *lazyFlowController {
+	return &lazyFlowController{streamID: id, newFlowController: newFlowController}
+}
+
+func (c *lazyFlowController) get() flowcontrol.StreamFlowController {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.flowController == nil {
+		c.flowController = c.newFlowController(c.streamID)
+		c.newFlowController = nil
+	}
+	return c.flowController
+}
+
 type streamI interface {
 	Stream
 	closeForShutdown(error)
 	// for receiving
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
+	receivedEarlyData(start, end protocol.ByteCount)
 	getWindowUpdate() protocol.ByteCount
 	// for sending
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)
 	updateSendWindow(protocol.ByteCount)
+	onCongestionLimited()
+	// for memory accounting
+	sendBufferedBytes() int
+	receiveBufferedBytes() int
 }
 
 var (
@@ -76,6 +111,8 @@ type stream struct {
 	receiveStream
 	sendStream
 
+	flowController lazyFlowController
+
 	completedMutex         sync.Mutex
 	sender                 streamSender
 	receiveStreamCompleted bool
@@ -87,9 +124,13 @@ var _ Stream = &stream{}
 // newStream creates a new Stream
 func newStream(streamID protocol.StreamID,
 	sender streamSender,
-	flowController flowcontrol.StreamFlowController,
+	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
+	clock utils.Clock,
 ) *stream {
-	s := &stream{sender: sender}
+	s := &stream{
+		sender:         sender,
+		flowController: lazyFlowController{streamID: streamID, newFlowController: newFlowController},
+	}
 	senderForSendStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -99,7 +140,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController)
+	s.sendStream = *newSendStream(streamID, senderForSendStream, &s.flowController, clock)
 	senderForReceiveStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -109,7 +150,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController)
+	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, &s.flowController)
 	return s
 }
 
@@ -7,8 +7,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -41,7 +43,7 @@ var _ = Describe("Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newStream(streamID, mockSender, mockFC)
+		str = newStream(streamID, mockSender, func(protocol.StreamID) flowcontrol.StreamFlowController { return mockFC }, utils.DefaultClock{})
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = struct {
@@ -57,6 +59,24 @@ var _ = Describe("Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("creates the flow controller when it is first used", func() {
+		var count int
+		str = newStream(streamID, mockSender, func(id protocol.StreamID) flowcontrol.StreamFlowController {
+			Expect(id).To(Equal(streamID))
+			count++
+			return mockFC
+		}, utils.DefaultClock{})
+		Expect(count).To(BeZero())
+		mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+		Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+		Expect(count).To(Equal(1))
+		// the send side uses the same flow controller
+		mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(1000))
+		mockFC.EXPECT().SendWindowSize()
+		str.updateSendWindow(1000)
+		Expect(count).To(Equal(1))
+	})
+
 	Context("deadlines", func() {
 		It("sets a write deadline, when SetDeadline is called", func() {
 			str.SetDeadline(time.Now().Add(-time.Second))
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
@@ -50,6 +52,11 @@ type streamsMap struct {
 	maxIncomingBidiStreams uint64
 	maxIncomingUniStreams  uint64
 
+	retransmissionPolicy   RetransmissionPolicy
+	retransmissionDeadline time.Duration
+
+	clock utils.Clock
+
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
 
@@ -68,6 +75,9 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	retransmissionPolicy RetransmissionPolicy,
+	retransmissionDeadline time.Duration,
+	clock utils.Clock,
 	perspective protocol.Perspective,
 ) streamManager {
 	m := &streamsMap{
@@ -75,6 +85,9 @@ func newStreamsMap(
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
+		retransmissionPolicy:   retransmissionPolicy,
+		retransmissionDeadline: retransmissionDeadline,
+		clock:                  clock,
 		sender:                 sender,
 	}
 	m.initMaps()
@@ -86,7 +99,9 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id))
+			str := newStream(id, m.sender, m.newFlowController, m.clock)
+			str.setRetransmissionPolicy(m.retransmissionPolicy, m.retransmissionDeadline)
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
@@ -94,7 +109,9 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id))
+			str := newStream(id, m.sender, m.newFlowController, m.clock)
+			str.setRetransmissionPolicy(m.retransmissionPolicy, m.retransmissionDeadline)
+			return str
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
@@ -105,7 +122,9 @@ func (m *streamsMap) initMaps() {
 		func(num protocol.StreamNum) sendStreamI {
 			// 根据类型和本端的角色，计算出了stream id
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id))
+			str := newSendStream(id, m.sender, newLazyFlowController(id, m.newFlowController), m.clock)
+			str.setRetransmissionPolicy(m.retransmissionPolicy, m.retransmissionDeadline)
+			return str
 		},
 		m.sender.queueControlFrame,
 	)
@@ -113,7 +132,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) receiveStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite())
-			return newReceiveStream(id, m.sender, m.newFlowController(id))
+			return newReceiveStream(id, m.sender, newLazyFlowController(id, m.newFlowController))
 		},
 		m.maxIncomingUniStreams,
 		m.sender.queueControlFrame,
@@ -300,6 +319,33 @@ func (m *streamsMap) CloseWithError(err error) {
 	m.incomingUniStreams.CloseWithError(err)
 }
 
+// NumStreams returns the number of open bidirectional and unidirectional streams.
+func (m *streamsMap) NumStreams() (bidi, uni int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	bidi = m.outgoingBidiStreams.NumStreams() + m.incomingBidiStreams.NumStreams()
+	uni = m.outgoingUniStreams.NumStreams() + m.incomingUniStreams.NumStreams()
+	return
+}
+
+// BufferedBytes returns the number of bytes buffered by all open streams,
+// for sending (data that hasn't been acknowledged yet) and for receiving (data that hasn't been read yet).
+func (m *streamsMap) BufferedBytes() (send, receive int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.outgoingBidiStreams.streams.Range(func(str streamI) {
+		send += str.sendBufferedBytes()
+		receive += str.receiveBufferedBytes()
+	})
+	m.incomingBidiStreams.streams.Range(func(e incomingStreamEntry[streamI]) {
+		send += e.stream.sendBufferedBytes()
+		receive += e.stream.receiveBufferedBytes()
+	})
+	m.outgoingUniStreams.streams.Range(func(str sendStreamI) { send += str.sendBufferedBytes() })
+	m.incomingUniStreams.streams.Range(func(e incomingStreamEntry[receiveStreamI]) { receive += e.stream.receiveBufferedBytes() })
+	return
+}
+
 // ResetFor0RTT resets is used when 0-RTT is rejected. In that case, the streams maps are
 // 1. closed with an Err0RTTRejected, making calls to Open{Uni}Stream{Sync} / Accept{Uni}Stream return that error.
 // 2. reset to their initial state, such that we can immediately process new incoming stream data.
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/wire"
@@ -20,14 +21,17 @@ type incomingStreamEntry[T incomingStream] struct {
 }
 
 type incomingStreamsMap[T incomingStream] struct {
-	mutex         sync.RWMutex
+	mutex         sync.Mutex
 	newStreamChan chan struct{}
 
 	streamType protocol.StreamType
-	streams    map[protocol.StreamNum]incomingStreamEntry[T]
+	// The streams are looked up without holding the mutex.
+	// They're only added and modified while holding the mutex, and added before nextStreamToOpen is incremented.
+	streams    shardedStreams[incomingStreamEntry[T]]
+	numStreams int
 
 	nextStreamToAccept protocol.StreamNum // the next stream that will be returned by AcceptStream()
-	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer opened
+	nextStreamToOpen   atomic.Int64       // the highest stream that the peer opened, plus one
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
 	maxNumStreams      uint64             // maximum number of streams
 
@@ -43,17 +47,17 @@ func newIncomingStreamsMap[T incomingStream](
 	maxStreams uint64,
 	queueControlFrame func(wire.Frame),
 ) *incomingStreamsMap[T] {
-	return &incomingStreamsMap[T]{
+	m := &incomingStreamsMap[T]{
 		newStreamChan:      make(chan struct{}, 1),
 		streamType:         streamType,
-		streams:            make(map[protocol.StreamNum]incomingStreamEntry[T]),
 		maxStream:          protocol.StreamNum(maxStreams),
 		maxNumStreams:      maxStreams,
 		newStream:          newStream,
-		nextStreamToOpen:   1,
 		nextStreamToAccept: 1,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
 	}
+	m.nextStreamToOpen.Store(1)
+	return m
 }
 
 func (m *incomingStreamsMap[T]) AcceptStream(ctx context.Context) (T, error) {
@@ -74,7 +78,7 @@ func (m *incomingStreamsMap[T]) AcceptStream(ctx context.Context) (T, error) {
 			return *new(T), m.closeErr
 		}
 		var ok bool
-		entry, ok = m.streams[num]
+		entry, ok = m.streams.Load(num)
 		if ok {
 			break
 		}
@@ -99,43 +103,49 @@ func (m *incomingStreamsMap[T]) AcceptStream(ctx context.Context) (T, error) {
 }
 
 func (m *incomingStreamsMap[T]) GetOrOpenStream(num protocol.StreamNum) (T, error) {
-	m.mutex.RLock()
-	if num > m.maxStream {
-		m.mutex.RUnlock()
-		return *new(T), streamError{
-			message: "peer tried to open stream %d (current limit: %d)",
-			nums:    []protocol.StreamNum{num, m.maxStream},
-		}
-	}
 	// if the num is smaller than the highest we accepted
 	// * this stream exists in the map, and we can return it, or
 	// * this stream was already closed, then we can return the nil
-	if num < m.nextStreamToOpen {
+	// This is the common case, and doesn't require holding the mutex.
+	if num < protocol.StreamNum(m.nextStreamToOpen.Load()) {
 		var s T
 		// If the stream was already queued for deletion, and is just waiting to be accepted, don't return it.
-		if entry, ok := m.streams[num]; ok && !entry.shouldDelete {
+		if entry, ok := m.streams.Load(num); ok && !entry.shouldDelete {
 			s = entry.stream
 		}
-		m.mutex.RUnlock()
 		return s, nil
 	}
-	m.mutex.RUnlock()
 
 	m.mutex.Lock()
-	// no need to check the two error conditions from above again
-	// * maxStream can only increase, so if the id was valid before, it definitely is valid now
-	// * highestStream is only modified by this function
-	for newNum := m.nextStreamToOpen; newNum <= num; newNum++ {
-		m.streams[newNum] = incomingStreamEntry[T]{stream: m.newStream(newNum)}
+	defer m.mutex.Unlock()
+
+	if num > m.maxStream {
+		return *new(T), streamError{
+			message: "peer tried to open stream %d (current limit: %d)",
+			nums:    []protocol.StreamNum{num, m.maxStream},
+		}
+	}
+	nextStreamToOpen := protocol.StreamNum(m.nextStreamToOpen.Load())
+	if num < nextStreamToOpen {
+		// The stream was opened concurrently.
+		var s T
+		if entry, ok := m.streams.Load(num); ok && !entry.shouldDelete {
+			s = entry.stream
+		}
+		return s, nil
+	}
+	var str T
+	for newNum := nextStreamToOpen; newNum <= num; newNum++ {
+		str = m.newStream(newNum)
+		m.streams.Store(newNum, incomingStreamEntry[T]{stream: str})
+		m.numStreams++
 		select {
 		case m.newStreamChan <- struct{}{}:
 		default:
 		}
 	}
-	m.nextStreamToOpen = num + 1
-	entry := m.streams[num]
-	m.mutex.Unlock()
-	return entry.stream, nil
+	m.nextStreamToOpen.Store(int64(num + 1))
+	return str, nil
 }
 
 func (m *incomingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
@@ -146,7 +156,8 @@ func (m *incomingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
 }
 
 func (m *incomingStreamsMap[T]) deleteStream(num protocol.StreamNum) error {
-	if _, ok := m.streams[num]; !ok {
+	entry, ok := m.streams.Load(num)
+	if !ok {
 		return streamError{
 			message: "tried to delete unknown incoming stream %d",
 			nums:    []protocol.StreamNum{num},
@@ -156,22 +167,22 @@ func (m *incomingStreamsMap[T]) deleteStream(num protocol.StreamNum) error {
 	// Don't delete this stream yet, if it was not yet accepted.
 	// Just save it to streamsToDelete map, to make sure it is deleted as soon as it gets accepted.
 	if num >= m.nextStreamToAccept {
-		entry, ok := m.streams[num]
-		if ok && entry.shouldDelete {
+		if entry.shouldDelete {
 			return streamError{
 				message: "tried to delete incoming stream %d multiple times",
 				nums:    []protocol.StreamNum{num},
 			}
 		}
 		entry.shouldDelete = true
-		m.streams[num] = entry // can't assign to struct in map, so we need to reassign
+		m.streams.Store(num, entry)
 		return nil
 	}
 
-	delete(m.streams, num)
+	m.streams.Delete(num)
+	m.numStreams--
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
-		maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
+	if m.maxNumStreams > uint64(m.numStreams) {
+		maxStream := protocol.StreamNum(m.nextStreamToOpen.Load()) + protocol.StreamNum(m.maxNumStreams-uint64(m.numStreams)) - 1
 		// Never send a value larger than protocol.MaxStreamCount.
 		if maxStream <= protocol.MaxStreamCount {
 			m.maxStream = maxStream
@@ -184,12 +195,17 @@ func (m *incomingStreamsMap[T]) deleteStream(num protocol.StreamNum) error {
 	return nil
 }
 
+// NumStreams returns the number of open streams.
+func (m *incomingStreamsMap[T]) NumStreams() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.numStreams
+}
+
 func (m *incomingStreamsMap[T]) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
-	for _, entry := range m.streams {
-		entry.stream.closeForShutdown(err)
-	}
+	m.streams.Range(func(entry incomingStreamEntry[T]) { entry.stream.closeForShutdown(err) })
 	m.mutex.Unlock()
 	close(m.newStreamChan)
 }
@@ -3,6 +3,7 @@ package quic
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/wire"
@@ -14,16 +15,18 @@ type outgoingStream interface {
 }
 
 type outgoingStreamsMap[T outgoingStream] struct {
-	mutex sync.RWMutex
+	mutex sync.Mutex
 
 	streamType protocol.StreamType
-	streams    map[protocol.StreamNum]T
+	// The streams are looked up without holding the mutex.
+	// They're only added while holding the mutex, before nextStream is incremented.
+	streams shardedStreams[T]
 
 	openQueue      map[uint64]chan struct{}
 	lowestInQueue  uint64
 	highestInQueue uint64
 
-	nextStream  protocol.StreamNum // stream ID of the stream returned by OpenStream(Sync)
+	nextStream  atomic.Int64       // stream number of the stream returned by OpenStream(Sync)
 	maxStream   protocol.StreamNum // the maximum stream ID we're allowed to open
 	blockedSent bool               // was a STREAMS_BLOCKED sent for the current maxStream
 
@@ -38,15 +41,15 @@ func newOutgoingStreamsMap[T outgoingStream](
 	newStream func(protocol.StreamNum) T,
 	queueControlFrame func(wire.Frame),
 ) *outgoingStreamsMap[T] {
-	return &outgoingStreamsMap[T]{
+	m := &outgoingStreamsMap[T]{
 		streamType:           streamType,
-		streams:              make(map[protocol.StreamNum]T),
 		openQueue:            make(map[uint64]chan struct{}),
 		maxStream:            protocol.InvalidStreamNum,
-		nextStream:           1,
 		newStream:            newStream,
 		queueStreamIDBlocked: func(f *wire.StreamsBlockedFrame) { queueControlFrame(f) },
 	}
+	m.nextStream.Store(1)
+	return m
 }
 
 func (m *outgoingStreamsMap[T]) OpenStream() (T, error) {
@@ -58,7 +61,7 @@ func (m *outgoingStreamsMap[T]) OpenStream() (T, error) {
 	}
 
 	// if there are OpenStreamSync calls waiting, return an error here
-	if len(m.openQueue) > 0 || m.nextStream > m.maxStream {
+	if len(m.openQueue) > 0 || protocol.StreamNum(m.nextStream.Load()) > m.maxStream {
 		m.maybeSendBlockedFrame()
 		return *new(T), streamOpenErr{errTooManyOpenStreams}
 	}
@@ -82,7 +85,7 @@ func (m *outgoingStreamsMap[T]) OpenStreamSync(ctx context.Context) (T, error) {
 	// 但是在定义里有如下注释
 	// // stream ID of the stream returned by OpenStream(Sync)
 	// 但是从后续代码看，该id 是一个递增的数值，从协议出发，估计这是去掉最低两位后的数值
-	if len(m.openQueue) == 0 && m.nextStream <= m.maxStream {
+	if len(m.openQueue) == 0 && protocol.StreamNum(m.nextStream.Load()) <= m.maxStream {
 		return m.openStream(), nil
 	}
 
@@ -109,7 +112,7 @@ func (m *outgoingStreamsMap[T]) OpenStreamSync(ctx context.Context) (T, error) {
 		if m.closeErr != nil {
 			return *new(T), m.closeErr
 		}
-		if m.nextStream > m.maxStream {
+		if protocol.StreamNum(m.nextStream.Load()) > m.maxStream {
 			// no stream available. Continue waiting
 			continue
 		}
@@ -125,11 +128,12 @@ func (m *outgoingStreamsMap[T]) openStream() T {
 	// 在初始化结构体时传入该函数，
 	// streams_map.go:103
 	// T 为 sendStreamI
-	s := m.newStream(m.nextStream)
+	num := protocol.StreamNum(m.nextStream.Load())
+	s := m.newStream(num)
 
-	m.streams[m.nextStream] = s
+	m.streams.Store(num, s)
 	// 递增，产生下一个streamID，这个推测是不包括最低两位的
-	m.nextStream++
+	m.nextStream.Add(1)
 	return s
 }
 
@@ -152,30 +156,23 @@ func (m *outgoingStreamsMap[T]) maybeSendBlockedFrame() {
 }
 
 func (m *outgoingStreamsMap[T]) GetStream(num protocol.StreamNum) (T, error) {
-	m.mutex.RLock()
-	if num >= m.nextStream {
-		m.mutex.RUnlock()
+	if num >= protocol.StreamNum(m.nextStream.Load()) {
 		return *new(T), streamError{
 			message: "peer attempted to open stream %d",
 			nums:    []protocol.StreamNum{num},
 		}
 	}
-	s := m.streams[num]
-	m.mutex.RUnlock()
+	s, _ := m.streams.Load(num)
 	return s, nil
 }
 
 func (m *outgoingStreamsMap[T]) DeleteStream(num protocol.StreamNum) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	if _, ok := m.streams[num]; !ok {
+	if !m.streams.Delete(num) {
 		return streamError{
 			message: "tried to delete unknown outgoing stream %d",
 			nums:    []protocol.StreamNum{num},
 		}
 	}
-	delete(m.streams, num)
 	return nil
 }
 
@@ -188,7 +185,7 @@ func (m *outgoingStreamsMap[T]) SetMaxStream(num protocol.StreamNum) {
 	}
 	m.maxStream = num
 	m.blockedSent = false
-	if m.maxStream < m.nextStream-1+protocol.StreamNum(len(m.openQueue)) {
+	if m.maxStream < protocol.StreamNum(m.nextStream.Load())-1+protocol.StreamNum(len(m.openQueue)) {
 		m.maybeSendBlockedFrame()
 	}
 	m.unblockOpenSync()
@@ -199,9 +196,7 @@ func (m *outgoingStreamsMap[T]) SetMaxStream(num protocol.StreamNum) {
 // We might need to update the send window, in case the server increased it.
 func (m *outgoingStreamsMap[T]) UpdateSendWindow(limit protocol.ByteCount) {
 	m.mutex.Lock()
-	for _, str := range m.streams {
-		str.updateSendWindow(limit)
-	}
+	m.streams.Range(func(str T) { str.updateSendWindow(limit) })
 	m.mutex.Unlock()
 }
 
@@ -225,12 +220,15 @@ func (m *outgoingStreamsMap[T]) unblockOpenSync() {
 	}
 }
 
+// NumStreams returns the number of open streams.
+func (m *outgoingStreamsMap[T]) NumStreams() int {
+	return m.streams.Len()
+}
+
 func (m *outgoingStreamsMap[T]) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
-	for _, str := range m.streams {
-		str.closeForShutdown(err)
-	}
+	m.streams.Range(func(str T) { str.closeForShutdown(err) })
 	for _, c := range m.openQueue {
 		if c != nil {
 			close(c)
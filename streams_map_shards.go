@@ -0,0 +1,84 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// numStreamShards is the number of shards a shardedStreams map is split into.
+const numStreamShards = 16
+
+type streamShard[T any] struct {
+	mutex   sync.RWMutex
+	streams map[protocol.StreamNum]T
+}
+
+// A shardedStreams map holds the streams of one stream type.
+// Connections can have hundreds of thousands of concurrent streams.
+// Looking up a stream only locks the shard that the stream belongs to,
+// so that lookups from the run loop don't contend with streams being opened and closed.
+// The zero value is ready to use.
+type shardedStreams[T any] struct {
+	shards [numStreamShards]streamShard[T]
+}
+
+func (s *shardedStreams[T]) shard(num protocol.StreamNum) *streamShard[T] {
+	return &s.shards[uint64(num)%numStreamShards]
+}
+
+func (s *shardedStreams[T]) Load(num protocol.StreamNum) (T, bool) {
+	sh := s.shard(num)
+	sh.mutex.RLock()
+	str, ok := sh.streams[num]
+	sh.mutex.RUnlock()
+	return str, ok
+}
+
+func (s *shardedStreams[T]) Store(num protocol.StreamNum, str T) {
+	sh := s.shard(num)
+	sh.mutex.Lock()
+	if sh.streams == nil {
+		sh.streams = make(map[protocol.StreamNum]T)
+	}
+	sh.streams[num] = str
+	sh.mutex.Unlock()
+}
+
+// Delete deletes a stream.
+// It returns false if the stream doesn't exist.
+func (s *shardedStreams[T]) Delete(num protocol.StreamNum) bool {
+	sh := s.shard(num)
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	if _, ok := sh.streams[num]; !ok {
+		return false
+	}
+	delete(sh.streams, num)
+	return true
+}
+
+func (s *shardedStreams[T]) Len() int {
+	var n int
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mutex.RLock()
+		n += len(sh.streams)
+		sh.mutex.RUnlock()
+	}
+	return n
+}
+
+// Range calls f for all streams.
+// f must not modify the shardedStreams map.
+func (s *shardedStreams[T]) Range(f func(T)) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mutex.RLock()
+		for _, str := range sh.streams {
+			f(str)
+		}
+		sh.mutex.RUnlock()
+	}
+}
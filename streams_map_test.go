@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"testing"
 
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -87,7 +89,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, RetransmitFirst, 0, utils.DefaultClock{}, perspective).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -372,6 +374,12 @@ var _ = Describe("Streams Map", func() {
 					flowControllers := make(map[protocol.StreamID]*mocks.MockStreamFlowController)
 					m.newFlowController = func(id protocol.StreamID) flowcontrol.StreamFlowController {
 						fc := mocks.NewMockStreamFlowController(mockCtrl)
+						if id.Type() == protocol.StreamTypeBidi {
+							fc.EXPECT().UpdateSendWindow(protocol.ByteCount(4321))
+						} else {
+							fc.EXPECT().UpdateSendWindow(protocol.ByteCount(1234))
+						}
+						fc.EXPECT().SendWindowSize()
 						flowControllers[id] = fc
 						return fc
 					}
@@ -380,11 +388,8 @@ var _ = Describe("Streams Map", func() {
 					Expect(err).ToNot(HaveOccurred())
 					unistr, err := m.OpenUniStream()
 					Expect(err).ToNot(HaveOccurred())
-
-					Expect(flowControllers).To(HaveKey(str.StreamID()))
-					flowControllers[str.StreamID()].EXPECT().UpdateSendWindow(protocol.ByteCount(4321))
-					Expect(flowControllers).To(HaveKey(unistr.StreamID()))
-					flowControllers[unistr.StreamID()].EXPECT().UpdateSendWindow(protocol.ByteCount(1234))
+					// the flow controllers are only created when they're first used
+					Expect(flowControllers).To(BeEmpty())
 
 					m.UpdateLimits(&wire.TransportParameters{
 						MaxBidiStreamNum:               1000,
@@ -392,6 +397,8 @@ var _ = Describe("Streams Map", func() {
 						MaxUniStreamNum:                1000,
 						InitialMaxStreamDataBidiRemote: 4321,
 					})
+					Expect(flowControllers).To(HaveKey(str.StreamID()))
+					Expect(flowControllers).To(HaveKey(unistr.StreamID()))
 				})
 			}
 
@@ -496,3 +503,27 @@ var _ = Describe("Streams Map", func() {
 		})
 	}
 })
+
+func BenchmarkStreamsMapGetStream(b *testing.B) {
+	const numStreams = 100000
+	m := newStreamsMap(NewMockStreamSender(gomock.NewController(b)), nil, numStreams, numStreams, RetransmitFirst, 0, utils.DefaultClock{}, protocol.PerspectiveServer)
+	ids := make([]protocol.StreamID, numStreams)
+	for i := range ids {
+		ids[i] = protocol.StreamNum(i+1).StreamID(protocol.StreamTypeBidi, protocol.PerspectiveClient)
+	}
+	if _, err := m.GetOrOpenReceiveStream(ids[numStreams-1]); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			if str, err := m.GetOrOpenSendStream(ids[i%numStreams]); err != nil || str == nil {
+				b.Fatalf("stream %d not found", ids[i%numStreams])
+			}
+			i++
+		}
+	})
+}
@@ -1,16 +1,15 @@
 package quic
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net"
-	"os"
-	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
 )
 
 // OOBCapablePacketConn is a connection that allows the reading of ECN bits from the IP header.
@@ -26,28 +25,59 @@ type OOBCapablePacketConn interface {
 
 var _ OOBCapablePacketConn = &net.UDPConn{}
 
+// PacketConnCapabilities declares what a CapablePacketConn supports.
+type PacketConnCapabilities struct {
+	// MaxDatagramSize is the size of the largest datagram that can be sent on the PacketConn.
+	// Both endpoints must be able to receive datagrams of this size.
+	// If PathMTUDiscovery is disabled, QUIC packets of this size are sent right from the start of the connection.
+	// If PathMTUDiscovery is enabled, it is the upper bound for Path MTU Discovery.
+	// It must be at least 1200 bytes. Values larger than 1452 bytes are reduced to 1452 bytes.
+	// If 0, 1200 bytes are used.
+	MaxDatagramSize int
+	// PathMTUDiscovery says if Path MTU Discovery (RFC 8899) can be run on the PacketConn.
+	// This requires that datagrams exceeding the path MTU are dropped, instead of being fragmented.
+	PathMTUDiscovery bool
+	// ECN says if the PacketConn supports sending and receiving ECN markings.
+	// The PacketConn must then implement the ECNPacketConn interface.
+	ECN bool
+	// Batching says if the PacketConn supports sending multiple datagrams in a single call.
+	// The PacketConn must then implement the BatchPacketConn interface.
+	Batching bool
+}
+
+// A CapablePacketConn is a PacketConn that declares its capabilities.
+// This allows running QUIC over PacketConns that are not UDP sockets,
+// e.g. userspace tunnels, Unix datagram sockets or in-memory pipes.
+// If the PacketConn passed to Dial or Listen satisfies this interface, quic-go won't apply any of the
+// optimizations for UDP sockets, and solely rely on the capabilities declared.
+type CapablePacketConn interface {
+	net.PacketConn
+	// Capabilities returns the capabilities of the PacketConn.
+	// It is called once, when the PacketConn is first used.
+	Capabilities() PacketConnCapabilities
+}
+
+// An ECNPacketConn is a PacketConn that supports sending and receiving ECN markings.
+type ECNPacketConn interface {
+	// ReadFromECN reads a datagram, and returns the ECN marking it was received with.
+	ReadFromECN(b []byte) (n int, addr net.Addr, ecn logging.ECN, err error)
+	// WriteToECN writes a datagram, marked with the given ECN codepoint.
+	WriteToECN(b []byte, addr net.Addr, ecn logging.ECN) (int, error)
+}
+
+// A BatchPacketConn is a PacketConn that supports sending multiple datagrams in a single call.
+type BatchPacketConn interface {
+	// WriteBatch writes multiple datagrams to addr.
+	// b contains the datagrams back to back. All datagrams have a size of segmentSize bytes,
+	// except for the last one, which may be smaller.
+	// ecn is logging.ECNUnsupported, unless the PacketConn declared support for ECN.
+	WriteBatch(b []byte, segmentSize int, addr net.Addr, ecn logging.ECN) (int, error)
+}
+
 func wrapConn(pc net.PacketConn) (rawConn, error) {
-	if err := setReceiveBuffer(pc); err != nil {
-		if !strings.Contains(err.Error(), "use of closed network connection") {
-			setBufferWarningOnce.Do(func() {
-				if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
-					return
-				}
-				log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
-			})
-		}
-	}
-	if err := setSendBuffer(pc); err != nil {
-		if !strings.Contains(err.Error(), "use of closed network connection") {
-			setBufferWarningOnce.Do(func() {
-				if disable, _ := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_RECEIVE_BUFFER_WARNING")); disable {
-					return
-				}
-				log.Printf("%s. See https://github.com/quic-go/quic-go/wiki/UDP-Buffer-Sizes for details.", err)
-			})
-		}
+	if c, ok := pc.(CapablePacketConn); ok {
+		return newCapableConn(c)
 	}
-
 	conn, ok := pc.(interface {
 		SyscallConn() (syscall.RawConn, error)
 	})
@@ -69,7 +99,7 @@ func wrapConn(pc net.PacketConn) (rawConn, error) {
 	}
 	c, ok := pc.(OOBCapablePacketConn)
 	if !ok {
-		utils.DefaultLogger.Infof("PacketConn is not a net.UDPConn. Disabling optimizations possible on UDP connections.")
+		utils.DefaultLogger.Infof("PacketConn is not a net.UDPConn. Disabling optimizations possible on UDP connections. Implement CapablePacketConn to declare the capabilities of the PacketConn.")
 		return &basicConn{PacketConn: pc, supportsDF: supportsDF}, nil
 	}
 	return newConn(c, supportsDF)
@@ -115,3 +145,90 @@ func (c *basicConn) WritePacket(b []byte, addr net.Addr, _ []byte, gsoSize uint1
 }
 
 func (c *basicConn) capabilities() connCapabilities { return connCapabilities{DF: c.supportsDF} }
+
+// The capableConn is the rawConn used for a CapablePacketConn.
+type capableConn struct {
+	net.PacketConn
+
+	ecnConn   ECNPacketConn   // only set if the PacketConn supports ECN
+	batchConn BatchPacketConn // only set if the PacketConn supports batching
+	cap       connCapabilities
+}
+
+var _ rawConn = &capableConn{}
+
+func newCapableConn(pc CapablePacketConn) (*capableConn, error) {
+	caps := pc.Capabilities()
+	c := &capableConn{PacketConn: pc}
+	switch {
+	case caps.MaxDatagramSize == 0:
+		c.cap.MaxPacketSize = protocol.MinInitialPacketSize
+	case caps.MaxDatagramSize < protocol.MinInitialPacketSize:
+		return nil, fmt.Errorf("quic: invalid maximum datagram size: %d bytes (minimum: %d bytes)", caps.MaxDatagramSize, protocol.MinInitialPacketSize)
+	default:
+		c.cap.MaxPacketSize = utils.Min(protocol.ByteCount(caps.MaxDatagramSize), protocol.MaxPacketBufferSize)
+	}
+	c.cap.DF = caps.PathMTUDiscovery
+	if caps.ECN {
+		ecnConn, ok := pc.(ECNPacketConn)
+		if !ok {
+			return nil, errors.New("quic: PacketConn declares support for ECN, but doesn't implement ECNPacketConn")
+		}
+		c.ecnConn = ecnConn
+		c.cap.ECN = true
+	}
+	if caps.Batching {
+		batchConn, ok := pc.(BatchPacketConn)
+		if !ok {
+			return nil, errors.New("quic: PacketConn declares support for batching, but doesn't implement BatchPacketConn")
+		}
+		c.batchConn = batchConn
+		c.cap.GSO = true
+	}
+	return c, nil
+}
+
+func (c *capableConn) ReadPacket() (receivedPacket, error) {
+	buffer := getPacketBuffer()
+	buffer.Data = buffer.Data[:protocol.MaxPacketBufferSize]
+	var (
+		n    int
+		addr net.Addr
+		ecn  protocol.ECN
+		err  error
+	)
+	if c.ecnConn != nil {
+		n, addr, ecn, err = c.ecnConn.ReadFromECN(buffer.Data)
+	} else {
+		n, addr, err = c.PacketConn.ReadFrom(buffer.Data)
+	}
+	if err != nil {
+		buffer.Release()
+		return receivedPacket{}, err
+	}
+	return receivedPacket{
+		remoteAddr: addr,
+		rcvTime:    time.Now(),
+		data:       buffer.Data[:n],
+		ecn:        ecn,
+		buffer:     buffer,
+	}, nil
+}
+
+func (c *capableConn) WritePacket(b []byte, addr net.Addr, _ []byte, gsoSize uint16, ecn protocol.ECN) (int, error) {
+	if ecn != protocol.ECNUnsupported && c.ecnConn == nil {
+		panic("tried to send a ECN-marked packet although ECN is disabled")
+	}
+	if gsoSize > 0 {
+		if c.batchConn == nil {
+			panic("cannot send a batch of packets on a PacketConn that doesn't support batching")
+		}
+		return c.batchConn.WriteBatch(b, int(gsoSize), addr, ecn)
+	}
+	if ecn != protocol.ECNUnsupported {
+		return c.ecnConn.WriteToECN(b, addr, ecn)
+	}
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func (c *capableConn) capabilities() connCapabilities { return c.cap }
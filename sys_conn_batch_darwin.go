@@ -0,0 +1,284 @@
+//go:build darwin
+
+package quic
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// msghdrX is the struct msghdr_x used by the recvmsg_x and sendmsg_x syscalls.
+//
+//	struct msghdr_x {
+//		void         *msg_name;       /* optional address */
+//		socklen_t    msg_namelen;     /* size of address */
+//		struct iovec *msg_iov;        /* scatter/gather array */
+//		int          msg_iovlen;      /* # elements in msg_iov */
+//		void         *msg_control;    /* ancillary data, see below */
+//		socklen_t    msg_controllen;  /* ancillary data buffer len */
+//		int          msg_flags;       /* flags on received message */
+//		size_t       msg_datalen;     /* byte length of buffer in msg_iov */
+//	};
+type msghdrX struct {
+	Name       *byte
+	Namelen    uint32
+	Iov        *unix.Iovec
+	Iovlen     int32
+	Control    *byte
+	Controllen uint32
+	Flags      int32
+	Datalen    uint
+}
+
+// The darwinBatchConn reads and writes multiple packets per syscall, using the recvmsg_x and sendmsg_x syscalls.
+// These syscalls are not part of the public API, but they have been available since macOS 10.11 and iOS 9.
+// If a syscall turns out to be unusable the first time it is used, we fall back to one packet per syscall.
+type darwinBatchConn struct {
+	conn     OOBCapablePacketConn
+	rawConn  syscall.RawConn
+	isIPv6   bool // the address family of the socket
+	fallback batchConn
+
+	recvDisabled atomic.Bool
+	recvWorked   bool // only accessed from ReadBatch, which is never called concurrently
+	sendDisabled atomic.Bool
+	sendWorked   atomic.Bool
+
+	// used by ReadBatch
+	recvHdrs  []msghdrX
+	recvIovs  []unix.Iovec
+	recvNames [][unix.SizeofSockaddrAny]byte
+
+	sendBatches sync.Pool
+}
+
+var (
+	_ batchConn   = &darwinBatchConn{}
+	_ batchWriter = &darwinBatchConn{}
+)
+
+// A sendBatch holds the memory needed for one sendmsg_x call.
+type sendBatch struct {
+	hdrs []msghdrX
+	iovs []unix.Iovec
+	name [unix.SizeofSockaddrInet6]byte
+}
+
+func newBatchConn(c OOBCapablePacketConn, rawConn syscall.RawConn) batchConn {
+	var isIPv6 bool
+	if err := rawConn.Control(func(fd uintptr) {
+		if sa, err := unix.Getsockname(int(fd)); err == nil {
+			_, isIPv6 = sa.(*unix.SockaddrInet6)
+		}
+	}); err != nil {
+		return ipv4.NewPacketConn(c)
+	}
+	return &darwinBatchConn{
+		conn:      c,
+		rawConn:   rawConn,
+		isIPv6:    isIPv6,
+		fallback:  ipv4.NewPacketConn(c),
+		recvHdrs:  make([]msghdrX, batchSize),
+		recvIovs:  make([]unix.Iovec, batchSize),
+		recvNames: make([][unix.SizeofSockaddrAny]byte, batchSize),
+	}
+}
+
+// isBatchSyscallUnsupported says if an error returned by recvmsg_x or sendmsg_x means
+// that the syscall can't be used on this system.
+func isBatchSyscallUnsupported(errno syscall.Errno) bool {
+	return errno == unix.ENOSYS || errno == unix.EOPNOTSUPP || errno == unix.EPERM || errno == unix.EINVAL
+}
+
+func (c *darwinBatchConn) ReadBatch(ms []ipv4.Message, flags int) (int, error) {
+	if c.recvDisabled.Load() {
+		return c.fallback.ReadBatch(ms, flags)
+	}
+	if len(ms) > len(c.recvHdrs) {
+		ms = ms[:len(c.recvHdrs)]
+	}
+	for i := range ms {
+		buf := ms[i].Buffers[0]
+		c.recvIovs[i] = unix.Iovec{Base: &buf[0]}
+		c.recvIovs[i].SetLen(len(buf))
+		c.recvHdrs[i] = msghdrX{
+			Name:    &c.recvNames[i][0],
+			Namelen: unix.SizeofSockaddrAny,
+			Iov:     &c.recvIovs[i],
+			Iovlen:  1,
+			Datalen: uint(len(buf)),
+		}
+		if len(ms[i].OOB) > 0 {
+			c.recvHdrs[i].Control = &ms[i].OOB[0]
+			c.recvHdrs[i].Controllen = uint32(len(ms[i].OOB))
+		}
+	}
+	var n uintptr
+	var errno syscall.Errno
+	if err := c.rawConn.Read(func(fd uintptr) bool {
+		n, _, errno = unix.Syscall6(unix.SYS_RECVMSG_X, fd, uintptr(unsafe.Pointer(&c.recvHdrs[0])), uintptr(len(ms)), uintptr(flags), 0, 0)
+		return errno != unix.EAGAIN
+	}); err != nil {
+		return 0, err
+	}
+	if errno != 0 {
+		if !c.recvWorked && isBatchSyscallUnsupported(errno) {
+			utils.DefaultLogger.Debugf("recvmsg_x failed (%s). Reading one packet per syscall.", errno)
+			c.recvDisabled.Store(true)
+			return c.fallback.ReadBatch(ms, flags)
+		}
+		return 0, os.NewSyscallError("recvmsg_x", errno)
+	}
+	c.recvWorked = true
+	for i := 0; i < int(n); i++ {
+		hdr := &c.recvHdrs[i]
+		ms[i].N = int(hdr.Datalen)
+		ms[i].NN = int(hdr.Controllen)
+		ms[i].Flags = int(hdr.Flags)
+		ms[i].Addr = parseSockaddr(c.recvNames[i][:hdr.Namelen])
+	}
+	return int(n), nil
+}
+
+// WriteBatch sends the packets contained in b, each of them segmentSize bytes long (except for the last one).
+// It uses the same control message for every packet.
+func (c *darwinBatchConn) WriteBatch(b []byte, segmentSize int, oob []byte, addr *net.UDPAddr) (int, error) {
+	if c.sendDisabled.Load() {
+		return c.writeOneByOne(b, segmentSize, oob, addr)
+	}
+	batch, _ := c.sendBatches.Get().(*sendBatch)
+	if batch == nil {
+		batch = &sendBatch{}
+	}
+	defer c.sendBatches.Put(batch)
+
+	nameLen := c.putSockaddr(batch.name[:], addr)
+	batch.hdrs = batch.hdrs[:0]
+	batch.iovs = batch.iovs[:0]
+	for i := 0; i < len(b); i += segmentSize {
+		l := utils.Min(segmentSize, len(b)-i)
+		iov := unix.Iovec{Base: &b[i]}
+		iov.SetLen(l)
+		batch.iovs = append(batch.iovs, iov)
+	}
+	for i := range batch.iovs {
+		hdr := msghdrX{
+			Name:    &batch.name[0],
+			Namelen: uint32(nameLen),
+			Iov:     &batch.iovs[i],
+			Iovlen:  1,
+			Datalen: uint(batch.iovs[i].Len),
+		}
+		if len(oob) > 0 {
+			hdr.Control = &oob[0]
+			hdr.Controllen = uint32(len(oob))
+		}
+		batch.hdrs = append(batch.hdrs, hdr)
+	}
+
+	hdrs := batch.hdrs
+	for len(hdrs) > 0 {
+		var n uintptr
+		var errno syscall.Errno
+		if err := c.rawConn.Write(func(fd uintptr) bool {
+			n, _, errno = unix.Syscall6(unix.SYS_SENDMSG_X, fd, uintptr(unsafe.Pointer(&hdrs[0])), uintptr(len(hdrs)), 0, 0, 0)
+			return errno != unix.EAGAIN
+		}); err != nil {
+			return 0, err
+		}
+		if errno != 0 {
+			if !c.sendWorked.Load() && isBatchSyscallUnsupported(errno) {
+				utils.DefaultLogger.Debugf("sendmsg_x failed (%s). Sending one packet per syscall.", errno)
+				c.sendDisabled.Store(true)
+				// Only packets that haven't been sent yet need to be sent.
+				sent := (len(batch.hdrs) - len(hdrs)) * segmentSize
+				n, err := c.writeOneByOne(b[sent:], segmentSize, oob, addr)
+				return sent + n, err
+			}
+			return 0, os.NewSyscallError("sendmsg_x", errno)
+		}
+		c.sendWorked.Store(true)
+		hdrs = hdrs[n:]
+	}
+	return len(b), nil
+}
+
+func (c *darwinBatchConn) writeOneByOne(b []byte, segmentSize int, oob []byte, addr *net.UDPAddr) (int, error) {
+	var n int
+	for len(b) > 0 {
+		l := utils.Min(segmentSize, len(b))
+		if _, _, err := c.conn.WriteMsgUDP(b[:l], oob, addr); err != nil {
+			return n, err
+		}
+		n += l
+		b = b[l:]
+	}
+	return n, nil
+}
+
+// putSockaddr serializes addr into b, and returns the length of the sockaddr.
+// IPv4 addresses are converted to IPv4-mapped IPv6 addresses on IPv6 sockets.
+func (c *darwinBatchConn) putSockaddr(b []byte, addr *net.UDPAddr) int {
+	if ip4 := addr.IP.To4(); ip4 != nil && !c.isIPv6 {
+		sa := (*unix.RawSockaddrInet4)(unsafe.Pointer(&b[0]))
+		*sa = unix.RawSockaddrInet4{Len: unix.SizeofSockaddrInet4, Family: unix.AF_INET}
+		binary.BigEndian.PutUint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:], uint16(addr.Port))
+		copy(sa.Addr[:], ip4)
+		return unix.SizeofSockaddrInet4
+	}
+	sa := (*unix.RawSockaddrInet6)(unsafe.Pointer(&b[0]))
+	*sa = unix.RawSockaddrInet6{Len: unix.SizeofSockaddrInet6, Family: unix.AF_INET6}
+	binary.BigEndian.PutUint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:], uint16(addr.Port))
+	copy(sa.Addr[:], addr.IP.To16())
+	if addr.Zone != "" {
+		if ifi, err := net.InterfaceByName(addr.Zone); err == nil {
+			sa.Scope_id = uint32(ifi.Index)
+		} else if idx, err := strconv.Atoi(addr.Zone); err == nil {
+			sa.Scope_id = uint32(idx)
+		}
+	}
+	return unix.SizeofSockaddrInet6
+}
+
+func parseSockaddr(b []byte) *net.UDPAddr {
+	if len(b) < 2 {
+		return nil
+	}
+	switch b[1] {
+	case unix.AF_INET:
+		if len(b) < unix.SizeofSockaddrInet4 {
+			return nil
+		}
+		sa := (*unix.RawSockaddrInet4)(unsafe.Pointer(&b[0]))
+		return &net.UDPAddr{
+			IP:   net.IPv4(sa.Addr[0], sa.Addr[1], sa.Addr[2], sa.Addr[3]),
+			Port: int(binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:])),
+		}
+	case unix.AF_INET6:
+		if len(b) < unix.SizeofSockaddrInet6 {
+			return nil
+		}
+		sa := (*unix.RawSockaddrInet6)(unsafe.Pointer(&b[0]))
+		addr := &net.UDPAddr{
+			IP:   make(net.IP, net.IPv6len),
+			Port: int(binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:])),
+		}
+		copy(addr.IP, sa.Addr[:])
+		if sa.Scope_id != 0 {
+			addr.Zone = strconv.Itoa(int(sa.Scope_id))
+		}
+		return addr
+	}
+	return nil
+}
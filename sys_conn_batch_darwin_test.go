@@ -0,0 +1,87 @@
+//go:build darwin
+
+package quic
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/ipv4"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Darwin Batch Conn", func() {
+	newBatchConnForAddr := func(network string, addr *net.UDPAddr) (*net.UDPConn, *darwinBatchConn) {
+		conn, err := net.ListenUDP(network, addr)
+		Expect(err).ToNot(HaveOccurred())
+		rawConn, err := conn.SyscallConn()
+		Expect(err).ToNot(HaveOccurred())
+		bc, ok := newBatchConn(conn, rawConn).(*darwinBatchConn)
+		Expect(ok).To(BeTrue())
+		return conn, bc
+	}
+
+	DescribeTable("serializing and parsing socket addresses",
+		func(network string, addr *net.UDPAddr) {
+			conn, bc := newBatchConnForAddr(network, &net.UDPAddr{IP: addr.IP})
+			defer conn.Close()
+			b := make([]byte, 128)
+			l := bc.putSockaddr(b, addr)
+			parsed := parseSockaddr(b[:l])
+			Expect(parsed.IP.Equal(addr.IP)).To(BeTrue())
+			Expect(parsed.Port).To(Equal(addr.Port))
+		},
+		Entry("IPv4", "udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}),
+		Entry("IPv6", "udp6", &net.UDPAddr{IP: net.IPv6loopback, Port: 4321}),
+	)
+
+	It("reads multiple packets in one batch", func() {
+		conn, bc := newBatchConnForAddr("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		defer conn.Close()
+		sender, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer sender.Close()
+		for _, data := range []string{"foo", "bar", "foobar"} {
+			_, err := sender.WriteTo([]byte(data), conn.LocalAddr())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		time.Sleep(scaleDuration(10 * time.Millisecond))
+
+		ms := make([]ipv4.Message, batchSize)
+		for i := range ms {
+			ms[i].Buffers = [][]byte{make([]byte, protocol.MaxPacketBufferSize)}
+			ms[i].OOB = make([]byte, oobBufferSize)
+		}
+		n, err := bc.ReadBatch(ms, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(3))
+		Expect(string(ms[0].Buffers[0][:ms[0].N])).To(Equal("foo"))
+		Expect(string(ms[1].Buffers[0][:ms[1].N])).To(Equal("bar"))
+		Expect(string(ms[2].Buffers[0][:ms[2].N])).To(Equal("foobar"))
+		Expect(ms[0].Addr.(*net.UDPAddr).Port).To(Equal(sender.LocalAddr().(*net.UDPAddr).Port))
+	})
+
+	It("writes multiple packets in one batch", func() {
+		conn, bc := newBatchConnForAddr("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		defer conn.Close()
+		receiver, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer receiver.Close()
+
+		n, err := bc.WriteBatch([]byte("foobarbaz"), 4, nil, receiver.LocalAddr().(*net.UDPAddr))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(9))
+
+		receiver.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, 100)
+		for _, expected := range []string{"foob", "arba", "z"} {
+			n, _, err := receiver.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b[:n])).To(Equal(expected))
+		}
+	})
+})
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package quic
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+func bindToDevice(syscall.RawConn, string, *net.Interface) error {
+	return errors.New("quic: binding a socket to a network interface is not supported on this platform")
+}
@@ -0,0 +1,25 @@
+//go:build darwin
+
+package quic
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func bindToDevice(c syscall.RawConn, network string, iface *net.Interface) error {
+	var serr error
+	if err := c.Control(func(fd uintptr) {
+		if network == "udp4" {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_BOUND_IF, iface.Index)
+		} else {
+			serr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_BOUND_IF, iface.Index)
+		}
+	}); err != nil {
+		return err
+	}
+	return os.NewSyscallError("setsockopt", serr)
+}
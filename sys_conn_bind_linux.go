@@ -0,0 +1,21 @@
+//go:build linux
+
+package quic
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func bindToDevice(c syscall.RawConn, _ string, iface *net.Interface) error {
+	var serr error
+	if err := c.Control(func(fd uintptr) {
+		serr = unix.BindToDevice(int(fd), iface.Name)
+	}); err != nil {
+		return err
+	}
+	return os.NewSyscallError("setsockopt", serr)
+}
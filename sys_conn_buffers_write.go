@@ -9,14 +9,15 @@ import (
 	"net"
 	"syscall"
 
-	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
-func setSendBuffer(c net.PacketConn) error {
+// setSendBuffer tries to increase the send buffer of the connection to at least desired bytes.
+// It returns the size of the send buffer after the attempt, or 0 if that size can't be determined.
+func setSendBuffer(c net.PacketConn, desired int) (int, error) {
 	conn, ok := c.(interface{ SetWriteBuffer(int) error })
 	if !ok {
-		return errors.New("connection doesn't allow setting of send buffer size. Not a *net.UDPConn?")
+		return 0, errors.New("connection doesn't allow setting of send buffer size. Not a *net.UDPConn?")
 	}
 
 	var syscallConn syscall.RawConn
@@ -34,37 +35,37 @@ func setSendBuffer(c net.PacketConn) error {
 	// net.PacketConn interface and the SetWriteBuffer method.
 	// We have no way of checking if increasing the buffer size actually worked.
 	if syscallConn == nil {
-		return conn.SetWriteBuffer(protocol.DesiredSendBufferSize)
+		return 0, conn.SetWriteBuffer(desired)
 	}
 
 	size, err := inspectWriteBuffer(syscallConn)
 	if err != nil {
-		return fmt.Errorf("failed to determine send buffer size: %w", err)
+		return 0, fmt.Errorf("failed to determine send buffer size: %w", err)
 	}
-	if size >= protocol.DesiredSendBufferSize {
-		utils.DefaultLogger.Debugf("Conn has send buffer of %d kiB (wanted: at least %d kiB)", size/1024, protocol.DesiredSendBufferSize/1024)
-		return nil
+	if size >= desired {
+		utils.DefaultLogger.Debugf("Conn has send buffer of %d kiB (wanted: at least %d kiB)", size/1024, desired/1024)
+		return size, nil
 	}
 	// Ignore the error. We check if we succeeded by querying the buffer size afterward.
-	_ = conn.SetWriteBuffer(protocol.DesiredSendBufferSize)
+	_ = conn.SetWriteBuffer(desired)
 	newSize, err := inspectWriteBuffer(syscallConn)
-	if newSize < protocol.DesiredSendBufferSize {
+	if newSize < desired {
 		// Try again with RCVBUFFORCE on Linux
-		_ = forceSetSendBuffer(syscallConn, protocol.DesiredSendBufferSize)
+		_ = forceSetSendBuffer(syscallConn, desired)
 		newSize, err = inspectWriteBuffer(syscallConn)
 		if err != nil {
-			return fmt.Errorf("failed to determine send buffer size: %w", err)
+			return size, fmt.Errorf("failed to determine send buffer size: %w", err)
 		}
 	}
 	if err != nil {
-		return fmt.Errorf("failed to determine send buffer size: %w", err)
+		return size, fmt.Errorf("failed to determine send buffer size: %w", err)
 	}
 	if newSize == size {
-		return fmt.Errorf("failed to increase send buffer size (wanted: %d kiB, got %d kiB)", protocol.DesiredSendBufferSize/1024, newSize/1024)
+		return newSize, fmt.Errorf("failed to increase send buffer size (wanted: %d kiB, got %d kiB)", desired/1024, newSize/1024)
 	}
-	if newSize < protocol.DesiredSendBufferSize {
-		return fmt.Errorf("failed to sufficiently increase send buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)", size/1024, protocol.DesiredSendBufferSize/1024, newSize/1024)
+	if newSize < desired {
+		return newSize, fmt.Errorf("failed to sufficiently increase send buffer size (was: %d kiB, wanted: %d kiB, got: %d kiB)", size/1024, desired/1024, newSize/1024)
 	}
 	utils.DefaultLogger.Debugf("Increased send buffer size to %d kiB", newSize/1024)
-	return nil
+	return newSize, nil
 }
@@ -17,9 +17,12 @@ const (
 
 const ecnIPv4DataLen = 4
 
-// ReadBatch only returns a single packet on OSX,
+const sendsPacketInfo = true
+
+// ReadBatch of the ipv4.PacketConn only returns a single packet on OSX,
 // see https://godoc.org/golang.org/x/net/ipv4#PacketConn.ReadBatch.
-const batchSize = 1
+// The darwinBatchConn uses recvmsg_x to read multiple packets.
+const batchSize = 8
 
 func parseIPv4PktInfo(body []byte) (ip netip.Addr, ifIndex uint32, ok bool) {
 	// struct in_pktinfo {
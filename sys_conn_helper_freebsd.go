@@ -6,6 +6,7 @@ import (
 	"net/netip"
 	"syscall"
 
+	"golang.org/x/net/ipv4"
 	"golang.org/x/sys/unix"
 )
 
@@ -16,8 +17,13 @@ const (
 
 const ecnIPv4DataLen = 4
 
+// The source address of IPv4 packets is set using IP_SENDSRCADDR, which is not supported.
+const sendsPacketInfo = false
+
 const batchSize = 8
 
+func newBatchConn(c OOBCapablePacketConn, _ syscall.RawConn) batchConn { return ipv4.NewPacketConn(c) }
+
 func parseIPv4PktInfo(body []byte) (ip netip.Addr, _ uint32, ok bool) {
 	// struct in_pktinfo {
 	// 	struct in_addr ipi_addr;     /* Header Destination address */
@@ -7,10 +7,10 @@ import (
 	"errors"
 	"net/netip"
 	"os"
-	"strconv"
 	"syscall"
 	"unsafe"
 
+	"golang.org/x/net/ipv4"
 	"golang.org/x/sys/unix"
 )
 
@@ -21,8 +21,12 @@ const (
 
 const ecnIPv4DataLen = 4
 
+const sendsPacketInfo = true
+
 const batchSize = 8 // needs to smaller than MaxUint8 (otherwise the type of oobConn.readPos has to be changed)
 
+func newBatchConn(c OOBCapablePacketConn, _ syscall.RawConn) batchConn { return ipv4.NewPacketConn(c) }
+
 func forceSetReceiveBuffer(c syscall.RawConn, bytes int) error {
 	var serr error
 	if err := c.Control(func(fd uintptr) {
@@ -58,8 +62,7 @@ func parseIPv4PktInfo(body []byte) (ip netip.Addr, ifIndex uint32, ok bool) {
 // isGSOSupported tests if the kernel supports GSO.
 // Sending with GSO might still fail later on, if the interface doesn't support it (see isGSOError).
 func isGSOSupported(conn syscall.RawConn) bool {
-	disabled, err := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_GSO"))
-	if err == nil && disabled {
+	if isGSODisabled() {
 		return false
 	}
 	var serr error
@@ -93,7 +96,9 @@ func isGSOError(err error) bool {
 		// which is a hard requirement of UDP_SEGMENT. See:
 		// https://git.kernel.org/pub/scm/docs/man-pages/man-pages.git/tree/man7/udp.7?id=806eabd74910447f21005160e90957bde4db0183#n228
 		// https://git.kernel.org/pub/scm/linux/kernel/git/torvalds/linux.git/tree/net/ipv4/udp.c?h=v6.2&id=c9c3395d5e3dcc6daee66c6908354d47bf98cb0c#n942
-		return serr.Err == unix.EIO
+		// EINVAL is returned by some drivers, and if the GSO batch can't be segmented on the outgoing interface
+		// (e.g. because the segment size exceeds the MTU of the interface).
+		return serr.Err == unix.EIO || serr.Err == unix.EINVAL
 	}
 	return false
 }
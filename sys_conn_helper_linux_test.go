@@ -72,6 +72,8 @@ var _ = Describe("forcing a change of send and receive buffer sizes", func() {
 
 	It("detects GSO errors", func() {
 		Expect(isGSOError(errGSO)).To(BeTrue())
+		Expect(isGSOError(&os.SyscallError{Syscall: "sendmsg", Err: unix.EINVAL})).To(BeTrue())
+		Expect(isGSOError(&os.SyscallError{Syscall: "sendmsg", Err: unix.EAGAIN})).To(BeFalse())
 		Expect(isGSOError(nil)).To(BeFalse())
 		Expect(isGSOError(errors.New("test"))).To(BeFalse())
 	})
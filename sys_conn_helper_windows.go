@@ -0,0 +1,62 @@
+//go:build windows
+
+package quic
+
+import (
+	"encoding/binary"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// UDP_SEND_MSG_SIZE is the socket option / control message used for UDP Segmentation Offload (USO).
+// See https://learn.microsoft.com/en-us/windows/win32/winsock/ipproto-udp-socket-options.
+const udpSendMsgSize = 2
+
+// wsaCmsghdr is the WSACMSGHDR struct, the header of a control message.
+type wsaCmsghdr struct {
+	Len   uintptr
+	Level int32
+	Type  int32
+}
+
+// Control messages are aligned to the natural alignment of the platform.
+func wsaCmsgAlign(n int) int {
+	const align = int(unsafe.Sizeof(uintptr(0)))
+	return (n + align - 1) &^ (align - 1)
+}
+
+// isGSOSupported tests if the OS supports UDP Segmentation Offload.
+// If the network interface doesn't support USO, Windows segments the packets in software.
+func isGSOSupported(conn syscall.RawConn) bool {
+	disabled, err := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_GSO"))
+	if err == nil && disabled {
+		return false
+	}
+	var serr error
+	if err := conn.Control(func(fd uintptr) {
+		_, serr = windows.GetsockoptInt(windows.Handle(fd), windows.IPPROTO_UDP, udpSendMsgSize)
+	}); err != nil {
+		return false
+	}
+	return serr == nil
+}
+
+func appendUDPSegmentSizeMsg(b []byte, size uint16) []byte {
+	startLen := len(b)
+	const dataLen = 4 // payload is a DWORD
+	hdrLen := wsaCmsgAlign(int(unsafe.Sizeof(wsaCmsghdr{})))
+	b = append(b, make([]byte, hdrLen+wsaCmsgAlign(dataLen))...)
+	h := (*wsaCmsghdr)(unsafe.Pointer(&b[startLen]))
+	h.Level = windows.IPPROTO_UDP
+	h.Type = udpSendMsgSize
+	h.Len = uintptr(hdrLen + dataLen)
+	binary.LittleEndian.PutUint32(b[startLen+hdrLen:], uint32(size))
+	return b
+}
+
+// Windows doesn't return an error if the interface doesn't support USO, see isGSOSupported.
+func isGSOError(error) bool { return false }
@@ -18,4 +18,6 @@ type packetInfo struct {
 	addr netip.Addr
 }
 
+func newPacketInfo(addr netip.Addr, _ uint32) packetInfo { return packetInfo{addr: addr} }
+
 func (i *packetInfo) OOB() []byte { return nil }
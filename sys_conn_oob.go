@@ -37,6 +37,12 @@ type batchConn interface {
 	ReadBatch(ms []ipv4.Message, flags int) (int, error)
 }
 
+// A batchWriter sends multiple packets using a single syscall.
+// It is used to send GSO batches on platforms that don't support GSO in the kernel.
+type batchWriter interface {
+	WriteBatch(b []byte, segmentSize int, oob []byte, addr *net.UDPAddr) (int, error)
+}
+
 func inspectReadBuffer(c syscall.RawConn) (int, error) {
 	var size int
 	var serr error
@@ -64,9 +70,16 @@ func isECNDisabled() bool {
 	return err == nil && disabled
 }
 
+func isGSODisabled() bool {
+	disabled, err := strconv.ParseBool(os.Getenv("QUIC_GO_DISABLE_GSO"))
+	return err == nil && disabled
+}
+
 type oobConn struct {
 	OOBCapablePacketConn
 	batchConn batchConn
+	// batchWriter is set if the batchConn can send GSO batches.
+	batchWriter batchWriter
 
 	readPos uint8
 	// Packets received from the kernel, but not yet returned by ReadPacket().
@@ -132,8 +145,9 @@ func newConn(c OOBCapablePacketConn, supportsDF bool) (*oobConn, error) {
 	if ibc, ok := c.(batchConn); ok {
 		bc = ibc
 	} else {
-		bc = ipv4.NewPacketConn(c)
+		bc = newBatchConn(c, rawConn)
 	}
+	bw, _ := bc.(batchWriter)
 
 	msgs := make([]ipv4.Message, batchSize)
 	for i := range msgs {
@@ -143,12 +157,14 @@ func newConn(c OOBCapablePacketConn, supportsDF bool) (*oobConn, error) {
 	oobConn := &oobConn{
 		OOBCapablePacketConn: c,
 		batchConn:            bc,
+		batchWriter:          bw,
 		messages:             msgs,
 		readPos:              batchSize,
 		cap: connCapabilities{
-			DF:  supportsDF,
-			GSO: isGSOSupported(rawConn),
-			ECN: !isECNDisabled(),
+			DF:         supportsDF,
+			GSO:        isGSOSupported(rawConn) || (bw != nil && !isGSODisabled()),
+			ECN:        !isECNDisabled(),
+			PacketInfo: sendsPacketInfo,
 		},
 	}
 	for i := 0; i < batchSize; i++ {
@@ -243,7 +259,9 @@ func (c *oobConn) WritePacket(b []byte, addr net.Addr, packetInfoOOB []byte, gso
 		if !c.capabilities().GSO {
 			panic("GSO disabled")
 		}
-		oob = appendUDPSegmentSizeMsg(oob, gsoSize)
+		if c.batchWriter == nil {
+			oob = appendUDPSegmentSizeMsg(oob, gsoSize)
+		}
 	}
 	if ecn != protocol.ECNUnsupported {
 		if !c.capabilities().ECN {
@@ -257,6 +275,9 @@ func (c *oobConn) WritePacket(b []byte, addr net.Addr, packetInfoOOB []byte, gso
 			}
 		}
 	}
+	if gsoSize > 0 && c.batchWriter != nil {
+		return c.batchWriter.WriteBatch(b, int(gsoSize), oob, addr.(*net.UDPAddr))
+	}
 	n, _, err := c.OOBCapablePacketConn.WriteMsgUDP(b, oob, addr.(*net.UDPAddr))
 	return n, err
 }
@@ -270,6 +291,10 @@ type packetInfo struct {
 	ifIndex uint32
 }
 
+func newPacketInfo(addr netip.Addr, ifIndex uint32) packetInfo {
+	return packetInfo{addr: addr, ifIndex: ifIndex}
+}
+
 func (info *packetInfo) OOB() []byte {
 	if info == nil {
 		return nil
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+	"github.com/quic-go/quic-go/logging"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -30,3 +32,129 @@ var _ = Describe("Basic Conn Test", func() {
 		Expect(p.remoteAddr).To(Equal(addr))
 	})
 })
+
+type capablePacketConn struct {
+	*MockPacketConn
+	caps PacketConnCapabilities
+
+	ecn     logging.ECN // the ECN marking of received packets
+	sentECN []logging.ECN
+	batches [][]byte
+}
+
+func (c *capablePacketConn) Capabilities() PacketConnCapabilities { return c.caps }
+
+func (c *capablePacketConn) ReadFromECN(b []byte) (int, net.Addr, logging.ECN, error) {
+	n, addr, err := c.ReadFrom(b)
+	return n, addr, c.ecn, err
+}
+
+func (c *capablePacketConn) WriteToECN(b []byte, addr net.Addr, ecn logging.ECN) (int, error) {
+	c.sentECN = append(c.sentECN, ecn)
+	return c.WriteTo(b, addr)
+}
+
+func (c *capablePacketConn) WriteBatch(b []byte, segmentSize int, _ net.Addr, _ logging.ECN) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		l := utils.Min(segmentSize, len(b))
+		c.batches = append(c.batches, b[:l])
+		b = b[l:]
+	}
+	return n, nil
+}
+
+var _ = Describe("Capable Conn Test", func() {
+	addr := &net.UnixAddr{Name: "/tmp/quic.sock", Net: "unixgram"}
+
+	It("uses the declared capabilities", func() {
+		conn, err := wrapConn(&capablePacketConn{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps: PacketConnCapabilities{
+				MaxDatagramSize:  1400,
+				PathMTUDiscovery: true,
+				ECN:              true,
+				Batching:         true,
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.capabilities()).To(Equal(connCapabilities{DF: true, ECN: true, GSO: true, MaxPacketSize: 1400}))
+	})
+
+	It("limits the maximum packet size", func() {
+		conn, err := wrapConn(&capablePacketConn{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{MaxDatagramSize: 65535},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.capabilities().MaxPacketSize).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+		conn, err = wrapConn(&capablePacketConn{MockPacketConn: NewMockPacketConn(mockCtrl)})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.capabilities().MaxPacketSize).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+	})
+
+	It("rejects invalid capabilities", func() {
+		_, err := wrapConn(&capablePacketConn{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{MaxDatagramSize: 1000},
+		})
+		Expect(err).To(MatchError("quic: invalid maximum datagram size: 1000 bytes (minimum: 1200 bytes)"))
+	})
+
+	It("rejects PacketConns declaring capabilities they don't implement", func() {
+		_, err := wrapConn(&capablePacketConnWithoutExtensions{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{ECN: true},
+		})
+		Expect(err).To(MatchError("quic: PacketConn declares support for ECN, but doesn't implement ECNPacketConn"))
+		_, err = wrapConn(&capablePacketConnWithoutExtensions{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{Batching: true},
+		})
+		Expect(err).To(MatchError("quic: PacketConn declares support for batching, but doesn't implement BatchPacketConn"))
+	})
+
+	It("reads and writes ECN-marked packets", func() {
+		c := &capablePacketConn{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{ECN: true},
+			ecn:            logging.ECT1,
+		}
+		c.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func(b []byte) (int, net.Addr, error) {
+			return copy(b, "foobar"), addr, nil
+		})
+		conn, err := wrapConn(c)
+		Expect(err).ToNot(HaveOccurred())
+		p, err := conn.ReadPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(p.data).To(Equal([]byte("foobar")))
+		Expect(p.remoteAddr).To(Equal(addr))
+		Expect(p.ecn).To(Equal(protocol.ECT1))
+
+		c.EXPECT().WriteTo([]byte("foo"), addr).Return(3, nil).Times(2)
+		_, err = conn.WritePacket([]byte("foo"), addr, nil, 0, protocol.ECT0)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = conn.WritePacket([]byte("foo"), addr, nil, 0, protocol.ECNUnsupported)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.sentECN).To(Equal([]logging.ECN{logging.ECT0}))
+	})
+
+	It("sends batches of packets", func() {
+		c := &capablePacketConn{
+			MockPacketConn: NewMockPacketConn(mockCtrl),
+			caps:           PacketConnCapabilities{Batching: true},
+		}
+		conn, err := wrapConn(c)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = conn.WritePacket([]byte("foobarbaz"), addr, nil, 4, protocol.ECNUnsupported)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.batches).To(Equal([][]byte{[]byte("foob"), []byte("arba"), []byte("z")}))
+	})
+})
+
+type capablePacketConnWithoutExtensions struct {
+	*MockPacketConn
+	caps PacketConnCapabilities
+}
+
+func (c *capablePacketConnWithoutExtensions) Capabilities() PacketConnCapabilities { return c.caps }
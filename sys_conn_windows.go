@@ -3,14 +3,58 @@
 package quic
 
 import (
+	"net"
 	"net/netip"
 	"syscall"
 
 	"golang.org/x/sys/windows"
+
+	"github.com/quic-go/quic-go/internal/protocol"
 )
 
-func newConn(c OOBCapablePacketConn, supportsDF bool) (*basicConn, error) {
-	return &basicConn{PacketConn: c, supportsDF: supportsDF}, nil
+// The windowsConn is a basicConn that uses UDP Segmentation Offload (USO) when sending packets, if supported.
+// With USO, a batch of packets is passed to the kernel using a single WSASendMsg call.
+type windowsConn struct {
+	basicConn
+
+	udpConn     OOBCapablePacketConn
+	supportsGSO bool
+}
+
+var _ rawConn = &windowsConn{}
+
+func newConn(c OOBCapablePacketConn, supportsDF bool) (*windowsConn, error) {
+	rawConn, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	return &windowsConn{
+		basicConn:   basicConn{PacketConn: c, supportsDF: supportsDF},
+		udpConn:     c,
+		supportsGSO: isGSOSupported(rawConn),
+	}, nil
+}
+
+func (c *windowsConn) WritePacket(b []byte, addr net.Addr, packetInfoOOB []byte, gsoSize uint16, ecn protocol.ECN) (int, error) {
+	if gsoSize == 0 {
+		return c.basicConn.WritePacket(b, addr, packetInfoOOB, 0, ecn)
+	}
+	if !c.supportsGSO {
+		panic("GSO disabled")
+	}
+	if ecn != protocol.ECNUnsupported {
+		panic("cannot use ECN with a windowsConn")
+	}
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		panic("cannot use GSO with a non-UDP address")
+	}
+	n, _, err := c.udpConn.WriteMsgUDP(b, appendUDPSegmentSizeMsg(packetInfoOOB, gsoSize), udpAddr)
+	return n, err
+}
+
+func (c *windowsConn) capabilities() connCapabilities {
+	return connCapabilities{DF: c.supportsDF, GSO: c.supportsGSO}
 }
 
 func inspectReadBuffer(c syscall.RawConn) (int, error) {
@@ -39,4 +83,6 @@ type packetInfo struct {
 	addr netip.Addr
 }
 
+func newPacketInfo(addr netip.Addr, _ uint32) packetInfo { return packetInfo{addr: addr} }
+
 func (i *packetInfo) OOB() []byte { return nil }
@@ -3,7 +3,12 @@
 package quic
 
 import (
+	"encoding/binary"
 	"net"
+	"time"
+	"unsafe"
+
+	"github.com/quic-go/quic-go/internal/protocol"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,4 +36,44 @@ var _ = Describe("Windows Conn Test", func() {
 		Expect(conn.Close()).To(Succeed())
 		Expect(conn.capabilities().DF).To(BeFalse())
 	})
+
+	It("appends the UDP_SEND_MSG_SIZE control message", func() {
+		prefix := []byte("foobar")
+		b := appendUDPSegmentSizeMsg(prefix, 1337)
+		Expect(b[:len(prefix)]).To(Equal(prefix))
+		b = b[len(prefix):]
+		h := (*wsaCmsghdr)(unsafe.Pointer(&b[0]))
+		Expect(h.Level).To(BeEquivalentTo(17)) // IPPROTO_UDP
+		Expect(h.Type).To(BeEquivalentTo(udpSendMsgSize))
+		hdrLen := wsaCmsgAlign(int(unsafe.Sizeof(wsaCmsghdr{})))
+		Expect(h.Len).To(BeEquivalentTo(hdrLen + 4))
+		Expect(binary.LittleEndian.Uint32(b[hdrLen:])).To(BeEquivalentTo(1337))
+	})
+
+	It("sends a batch of packets using USO", func() {
+		receiver, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer receiver.Close()
+		udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		conn, err := newConn(udpConn, true)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+		if !conn.capabilities().GSO {
+			Skip("USO not supported")
+		}
+
+		// two full-sized packets, and a shorter last packet
+		n, err := conn.WritePacket(make([]byte, 2500), receiver.LocalAddr(), nil, 1000, protocol.ECNUnsupported)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(2500))
+
+		receiver.SetReadDeadline(time.Now().Add(time.Second))
+		b := make([]byte, 2000)
+		for _, size := range []int{1000, 1000, 500} {
+			n, _, err := receiver.ReadFrom(b)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(size))
+		}
+	})
 })
@@ -0,0 +1,387 @@
+//go:build linux && quic_xdp
+
+package quic
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// This file contains an experimental AF_XDP receive path.
+// It is only compiled when using the quic_xdp build tag.
+
+const (
+	xdpDefaultNumFrames = 4096
+	xdpFrameSize        = 4096
+	xdpDescSize         = int(unsafe.Sizeof(unix.XDPDesc{}))
+)
+
+// XDPConfig configures an XDPConn.
+type XDPConfig struct {
+	// Interface is the name of the network interface to receive packets on.
+	Interface string
+	// QueueID is the index of the NIC receive queue the socket is bound to.
+	// Only packets arriving on this queue are received,
+	// so the NIC needs to be configured to steer QUIC packets to this queue (e.g. using ethtool).
+	QueueID int
+	// XSKMapFD is the file descriptor of the BPF_MAP_TYPE_XSKMAP used by the XDP program attached to the interface.
+	// The socket is inserted into the map at index QueueID.
+	// Loading and attaching an XDP program that redirects the QUIC packets to this map is the application's responsibility.
+	XSKMapFD int
+	// LocalAddr is the local UDP address.
+	// Received packets addressed to a different port are dropped.
+	// Packets are sent using a regular UDP socket bound to this address.
+	LocalAddr *net.UDPAddr
+	// NumFrames is the number of frames in the UMEM, which is also the size of the fill and the RX ring.
+	// It must be a power of two. If zero, 4096 frames are used.
+	NumFrames int
+	// ZeroCopy requests the zero-copy mode, in which the NIC writes packets directly into the UMEM.
+	// This requires driver support. If false, the kernel copies packets into the UMEM.
+	ZeroCopy bool
+}
+
+// An XDPConn is a net.PacketConn that receives packets from an AF_XDP socket,
+// bypassing the kernel's network stack, and sends packets using a regular UDP socket.
+// It can be used as the Conn of a Transport.
+// This is experimental and only available when using the quic_xdp build tag.
+type XDPConn struct {
+	fd      int
+	eventFD int // used to interrupt the poll when the deadline changes or the conn is closed
+	udpConn *net.UDPConn
+	port    uint16
+
+	umem    []byte
+	rxMem   []byte
+	fillMem []byte
+	rx      xdpRing
+	fill    xdpRing
+
+	readMutex    sync.Mutex
+	readDeadline atomic.Pointer[time.Time]
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+var _ net.PacketConn = &XDPConn{}
+
+// xdpRing is a single-producer single-consumer ring shared with the kernel.
+type xdpRing struct {
+	producer *uint32
+	consumer *uint32
+	descs    unsafe.Pointer
+	mask     uint32
+}
+
+func newXDPRing(mem []byte, off unix.XDPRingOffset, size uint32) xdpRing {
+	return xdpRing{
+		producer: (*uint32)(unsafe.Pointer(&mem[off.Producer])),
+		consumer: (*uint32)(unsafe.Pointer(&mem[off.Consumer])),
+		descs:    unsafe.Pointer(&mem[off.Desc]),
+		mask:     size - 1,
+	}
+}
+
+// ListenXDP creates an AF_XDP socket according to the configuration.
+func ListenXDP(conf *XDPConfig) (*XDPConn, error) {
+	if conf.LocalAddr == nil || conf.LocalAddr.Port == 0 {
+		return nil, errors.New("xdp: a local address with a port is required")
+	}
+	numFrames := conf.NumFrames
+	if numFrames == 0 {
+		numFrames = xdpDefaultNumFrames
+	}
+	if numFrames < 0 || numFrames&(numFrames-1) != 0 {
+		return nil, fmt.Errorf("xdp: the number of frames must be a power of two: %d", numFrames)
+	}
+	iface, err := net.InterfaceByName(conf.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &XDPConn{fd: -1, eventFD: -1, port: uint16(conf.LocalAddr.Port)}
+	if err := c.setup(conf, iface.Index, uint32(numFrames)); err != nil {
+		c.close()
+		return nil, err
+	}
+	udpConn, err := net.ListenUDP("udp", conf.LocalAddr)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	c.udpConn = udpConn
+	return c, nil
+}
+
+func (c *XDPConn) setup(conf *XDPConfig, ifindex int, numFrames uint32) error {
+	fd, err := unix.Socket(unix.AF_XDP, unix.SOCK_RAW|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	c.fd = fd
+	c.eventFD, err = unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		return os.NewSyscallError("eventfd", err)
+	}
+
+	// register the UMEM
+	c.umem, err = unix.Mmap(-1, 0, int(numFrames)*xdpFrameSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANONYMOUS|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	reg := unix.XDPUmemReg{
+		Addr: uint64(uintptr(unsafe.Pointer(&c.umem[0]))),
+		Len:  uint64(len(c.umem)),
+		Size: xdpFrameSize,
+	}
+	if err := xdpSetsockopt(fd, unix.XDP_UMEM_REG, unsafe.Pointer(&reg), unsafe.Sizeof(reg)); err != nil {
+		return os.NewSyscallError("setsockopt XDP_UMEM_REG", err)
+	}
+	// The kernel requires a completion ring, even though we never transmit packets on this socket.
+	for _, opt := range []int{unix.XDP_UMEM_FILL_RING, unix.XDP_UMEM_COMPLETION_RING, unix.XDP_RX_RING} {
+		if err := unix.SetsockoptInt(fd, unix.SOL_XDP, opt, int(numFrames)); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+
+	// map the rings
+	var offsets unix.XDPMmapOffsets
+	if err := xdpGetsockopt(fd, unix.XDP_MMAP_OFFSETS, unsafe.Pointer(&offsets), unsafe.Sizeof(offsets)); err != nil {
+		return os.NewSyscallError("getsockopt XDP_MMAP_OFFSETS", err)
+	}
+	c.rxMem, err = unix.Mmap(fd, unix.XDP_PGOFF_RX_RING, int(offsets.Rx.Desc)+int(numFrames)*xdpDescSize, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap RX ring", err)
+	}
+	c.rx = newXDPRing(c.rxMem, offsets.Rx, numFrames)
+	c.fillMem, err = unix.Mmap(fd, unix.XDP_UMEM_PGOFF_FILL_RING, int(offsets.Fr.Desc)+int(numFrames)*8, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		return os.NewSyscallError("mmap fill ring", err)
+	}
+	c.fill = newXDPRing(c.fillMem, offsets.Fr, numFrames)
+	// hand all frames to the kernel
+	for i := uint32(0); i < numFrames; i++ {
+		*(*uint64)(unsafe.Add(c.fill.descs, 8*uintptr(i))) = uint64(i) * xdpFrameSize
+	}
+	atomic.StoreUint32(c.fill.producer, numFrames)
+
+	flags := uint16(unix.XDP_COPY)
+	if conf.ZeroCopy {
+		flags = unix.XDP_ZEROCOPY
+	}
+	if err := unix.Bind(fd, &unix.SockaddrXDP{Flags: flags, Ifindex: uint32(ifindex), QueueID: uint32(conf.QueueID)}); err != nil {
+		return os.NewSyscallError("bind", err)
+	}
+	if err := xskMapUpdate(conf.XSKMapFD, uint32(conf.QueueID), uint32(fd)); err != nil {
+		return os.NewSyscallError("bpf BPF_MAP_UPDATE_ELEM", err)
+	}
+	return nil
+}
+
+func xdpSetsockopt(fd, opt int, val unsafe.Pointer, size uintptr) error {
+	_, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), unix.SOL_XDP, uintptr(opt), uintptr(val), size, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func xdpGetsockopt(fd, opt int, val unsafe.Pointer, size uintptr) error {
+	l := uint32(size)
+	_, _, errno := unix.Syscall6(unix.SYS_GETSOCKOPT, uintptr(fd), unix.SOL_XDP, uintptr(opt), uintptr(val), uintptr(unsafe.Pointer(&l)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// xskMapUpdate inserts the AF_XDP socket into the XSKMAP.
+func xskMapUpdate(mapFD int, key, value uint32) error {
+	attr := struct {
+		mapFD uint32
+		_     uint32
+		key   uint64
+		value uint64
+		flags uint64
+	}{
+		mapFD: uint32(mapFD),
+		key:   uint64(uintptr(unsafe.Pointer(&key))),
+		value: uint64(uintptr(unsafe.Pointer(&value))),
+	}
+	_, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_MAP_UPDATE_ELEM, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ReadFrom reads the UDP payload of the next packet from the RX ring.
+func (c *XDPConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.readMutex.Lock()
+	defer c.readMutex.Unlock()
+
+	for {
+		if c.closed.Load() {
+			return 0, nil, net.ErrClosed
+		}
+		cons := *c.rx.consumer
+		for cons != atomic.LoadUint32(c.rx.producer) {
+			desc := (*unix.XDPDesc)(unsafe.Add(c.rx.descs, uintptr(cons&c.rx.mask)*uintptr(xdpDescSize)))
+			payload, addr, ok := parseXDPPacket(c.umem[desc.Addr:desc.Addr+uint64(desc.Len)], c.port)
+			var n int
+			if ok {
+				n = copy(b, payload)
+			}
+			cons++
+			atomic.StoreUint32(c.rx.consumer, cons)
+			// return the frame to the kernel
+			prod := *c.fill.producer
+			*(*uint64)(unsafe.Add(c.fill.descs, 8*uintptr(prod&c.fill.mask))) = desc.Addr &^ (xdpFrameSize - 1)
+			atomic.StoreUint32(c.fill.producer, prod+1)
+			if ok {
+				return n, addr, nil
+			}
+		}
+		if err := c.wait(); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// wait blocks until packets are received, the read deadline expires, or the conn is closed.
+func (c *XDPConn) wait() error {
+	timeout := -1
+	if d := c.readDeadline.Load(); d != nil && !d.IsZero() {
+		until := time.Until(*d)
+		if until <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		timeout = int(until.Milliseconds()) + 1
+	}
+	fds := []unix.PollFd{{Fd: int32(c.fd), Events: unix.POLLIN}, {Fd: int32(c.eventFD), Events: unix.POLLIN}}
+	if _, err := unix.Poll(fds, timeout); err != nil && err != unix.EINTR {
+		return os.NewSyscallError("poll", err)
+	}
+	if fds[1].Revents != 0 {
+		var buf [8]byte
+		unix.Read(c.eventFD, buf[:])
+	}
+	return nil
+}
+
+func (c *XDPConn) wakeup() {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], 1)
+	unix.Write(c.eventFD, buf[:])
+}
+
+// parseXDPPacket parses the Ethernet, IP and UDP headers of a packet.
+// It returns false if the packet is not a UDP packet sent to port.
+func parseXDPPacket(data []byte, port uint16) ([]byte, *net.UDPAddr, bool) {
+	if len(data) < 14 {
+		return nil, nil, false
+	}
+	etherType := binary.BigEndian.Uint16(data[12:14])
+	data = data[14:]
+	if etherType == 0x8100 { // 802.1Q VLAN tag
+		if len(data) < 4 {
+			return nil, nil, false
+		}
+		etherType = binary.BigEndian.Uint16(data[2:4])
+		data = data[4:]
+	}
+	var srcIP netip.Addr
+	switch etherType {
+	case 0x0800: // IPv4
+		if len(data) < 20 || data[0]>>4 != 4 {
+			return nil, nil, false
+		}
+		ihl := int(data[0]&0xf) * 4
+		// fragmented packets are not supported
+		if ihl < 20 || len(data) < ihl || data[9] != unix.IPPROTO_UDP || binary.BigEndian.Uint16(data[6:8])&0x3fff != 0 {
+			return nil, nil, false
+		}
+		srcIP = netip.AddrFrom4([4]byte(data[12:16]))
+		data = data[ihl:]
+	case 0x86dd: // IPv6
+		// extension headers are not supported
+		if len(data) < 40 || data[0]>>4 != 6 || data[6] != unix.IPPROTO_UDP {
+			return nil, nil, false
+		}
+		srcIP = netip.AddrFrom16([16]byte(data[8:24]))
+		data = data[40:]
+	default:
+		return nil, nil, false
+	}
+	if len(data) < 8 || binary.BigEndian.Uint16(data[2:4]) != port {
+		return nil, nil, false
+	}
+	udpLen := int(binary.BigEndian.Uint16(data[4:6]))
+	if udpLen < 8 || udpLen > len(data) {
+		return nil, nil, false
+	}
+	srcPort := binary.BigEndian.Uint16(data[0:2])
+	return data[8:udpLen], net.UDPAddrFromAddrPort(netip.AddrPortFrom(srcIP, srcPort)), true
+}
+
+// WriteTo sends a packet using the UDP socket.
+func (c *XDPConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return c.udpConn.WriteTo(b, addr)
+}
+
+// Close closes the AF_XDP socket and the UDP socket.
+func (c *XDPConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		c.wakeup()
+		// wait for a concurrent ReadFrom to return before unmapping the rings
+		c.readMutex.Lock()
+		defer c.readMutex.Unlock()
+		err = c.udpConn.Close()
+		c.close()
+	})
+	return err
+}
+
+func (c *XDPConn) close() {
+	for _, mem := range [][]byte{c.rxMem, c.fillMem, c.umem} {
+		if mem != nil {
+			unix.Munmap(mem)
+		}
+	}
+	if c.fd >= 0 {
+		unix.Close(c.fd)
+	}
+	if c.eventFD >= 0 {
+		unix.Close(c.eventFD)
+	}
+}
+
+// LocalAddr returns the local address of the UDP socket.
+func (c *XDPConn) LocalAddr() net.Addr { return c.udpConn.LocalAddr() }
+
+func (c *XDPConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *XDPConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.Store(&t)
+	c.wakeup()
+	return nil
+}
+
+func (c *XDPConn) SetWriteDeadline(t time.Time) error { return c.udpConn.SetWriteDeadline(t) }
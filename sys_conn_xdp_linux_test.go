@@ -0,0 +1,115 @@
+//go:build linux && quic_xdp
+
+package quic
+
+import (
+	"encoding/binary"
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AF_XDP", func() {
+	appendUDP := func(b []byte, srcPort, dstPort uint16, payload []byte) []byte {
+		b = binary.BigEndian.AppendUint16(b, srcPort)
+		b = binary.BigEndian.AppendUint16(b, dstPort)
+		b = binary.BigEndian.AppendUint16(b, uint16(8+len(payload)))
+		b = append(b, 0, 0) // checksum
+		return append(b, payload...)
+	}
+
+	ipv4Packet := func(src net.IP, srcPort, dstPort uint16, payload []byte) []byte {
+		b := make([]byte, 12, 100)
+		b = binary.BigEndian.AppendUint16(b, 0x0800)
+		ip := make([]byte, 20)
+		ip[0] = 0x45
+		ip[9] = 17 // UDP
+		copy(ip[12:16], src.To4())
+		b = append(b, ip...)
+		return appendUDP(b, srcPort, dstPort, payload)
+	}
+
+	ipv6Packet := func(src net.IP, srcPort, dstPort uint16, payload []byte) []byte {
+		b := make([]byte, 12, 100)
+		b = binary.BigEndian.AppendUint16(b, 0x86dd)
+		ip := make([]byte, 40)
+		ip[0] = 0x60
+		ip[6] = 17 // UDP
+		copy(ip[8:24], src.To16())
+		b = append(b, ip...)
+		return appendUDP(b, srcPort, dstPort, payload)
+	}
+
+	Context("parsing packets", func() {
+		It("parses IPv4 packets", func() {
+			payload, addr, ok := parseXDPPacket(ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 443, []byte("foobar")), 443)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal([]byte("foobar")))
+			Expect(addr.String()).To(Equal("192.168.1.2:1234"))
+		})
+
+		It("parses IPv6 packets", func() {
+			payload, addr, ok := parseXDPPacket(ipv6Packet(net.ParseIP("2001:db8::1"), 1234, 443, []byte("foobar")), 443)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal([]byte("foobar")))
+			Expect(addr.String()).To(Equal("[2001:db8::1]:1234"))
+		})
+
+		It("parses packets with a VLAN tag", func() {
+			b := ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 443, []byte("foobar"))
+			tagged := append([]byte{}, b[:12]...)
+			tagged = append(tagged, 0x81, 0x00, 0x00, 0x2a)
+			tagged = append(tagged, b[12:]...)
+			payload, _, ok := parseXDPPacket(tagged, 443)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal([]byte("foobar")))
+		})
+
+		It("ignores trailing padding", func() {
+			b := append(ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 443, []byte("foo")), 0, 0, 0)
+			payload, _, ok := parseXDPPacket(b, 443)
+			Expect(ok).To(BeTrue())
+			Expect(payload).To(Equal([]byte("foo")))
+		})
+
+		It("drops packets sent to a different port", func() {
+			_, _, ok := parseXDPPacket(ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 4433, []byte("foobar")), 443)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("drops non-UDP packets", func() {
+			b := ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 443, []byte("foobar"))
+			b[14+9] = 6 // TCP
+			_, _, ok := parseXDPPacket(b, 443)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("drops fragmented packets", func() {
+			b := ipv4Packet(net.IPv4(192, 168, 1, 2), 1234, 443, []byte("foobar"))
+			b[14+6] = 0x20 // more fragments
+			_, _, ok := parseXDPPacket(b, 443)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("drops truncated packets", func() {
+			b := ipv6Packet(net.ParseIP("2001:db8::1"), 1234, 443, []byte("foobar"))
+			for i := 0; i < len(b)-len("foobar"); i++ {
+				_, _, ok := parseXDPPacket(b[:i], 443)
+				Expect(ok).To(BeFalse())
+			}
+		})
+	})
+
+	Context("validating the config", func() {
+		It("requires a local port", func() {
+			_, err := ListenXDP(&XDPConfig{Interface: "lo", LocalAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}})
+			Expect(err).To(MatchError("xdp: a local address with a port is required"))
+		})
+
+		It("requires the number of frames to be a power of two", func() {
+			_, err := ListenXDP(&XDPConfig{Interface: "lo", LocalAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 443}, NumFrames: 1000})
+			Expect(err).To(MatchError("xdp: the number of frames must be a power of two: 1000"))
+		})
+	})
+})
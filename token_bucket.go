@@ -0,0 +1,104 @@
+package quic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
+)
+
+// A tokenBucket limits the rate at which STREAM data is sent on a connection.
+// Tokens (bytes) are added at a constant rate, up to the burst size.
+// A rate of 0 disables rate limiting.
+// The limit can be changed while the connection is running.
+type tokenBucket struct {
+	clock utils.Clock
+
+	mutex      sync.Mutex
+	rate       uint64 // bytes per second
+	burst      protocol.ByteCount
+	tokens     float64
+	lastUpdate time.Time
+	// blockedUntil is the time at which enough tokens will be available to send the next packet.
+	// It is zero if sending is not blocked by the rate limit.
+	blockedUntil time.Time
+}
+
+func newTokenBucket(rate, burst uint64, clock utils.Clock) *tokenBucket {
+	b := &tokenBucket{clock: clock}
+	b.SetLimit(rate, burst)
+	return b
+}
+
+// SetLimit sets the rate (in bytes per second) and the burst size (in bytes).
+// If the burst size is 0, it defaults to 100ms worth of data, but at least one packet.
+// The bucket starts out full.
+func (b *tokenBucket) SetLimit(rate, burst uint64) {
+	if burst == 0 {
+		burst = utils.Max(rate/10, uint64(protocol.MaxPacketBufferSize))
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.rate = rate
+	b.burst = protocol.ByteCount(burst)
+	b.tokens = float64(burst)
+	b.lastUpdate = b.clock.Now()
+	b.blockedUntil = time.Time{}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.lastUpdate) {
+		b.tokens += now.Sub(b.lastUpdate).Seconds() * float64(b.rate)
+		b.lastUpdate = now
+	}
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+}
+
+// Allowance returns the number of bytes that can be sent right now, capped at maxLen.
+// To avoid sending lots of small packets, it returns 0 unless maxLen bytes
+// (or the burst size, if that is smaller) can be sent.
+func (b *tokenBucket) Allowance(maxLen protocol.ByteCount) protocol.ByteCount {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate == 0 {
+		return maxLen
+	}
+	now := b.clock.Now()
+	b.refill(now)
+	needed := utils.Min(maxLen, b.burst)
+	if b.tokens < float64(needed) {
+		missing := float64(needed) - b.tokens
+		b.blockedUntil = now.Add(time.Duration(missing / float64(b.rate) * float64(time.Second)))
+		return 0
+	}
+	b.blockedUntil = time.Time{}
+	return utils.Min(maxLen, protocol.ByteCount(b.tokens))
+}
+
+// Consume removes n bytes worth of tokens from the bucket.
+func (b *tokenBucket) Consume(n protocol.ByteCount) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate == 0 {
+		return
+	}
+	b.tokens -= float64(n)
+}
+
+// NextSendTime returns the time when sending, which is currently blocked by the rate limit, can resume.
+// It returns the zero value if sending is not blocked.
+func (b *tokenBucket) NextSendTime() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.blockedUntil.IsZero() || !b.blockedUntil.After(b.clock.Now()) {
+		return time.Time{}
+	}
+	return b.blockedUntil
+}
@@ -0,0 +1,84 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Bucket", func() {
+	var clock *fakeClock
+
+	BeforeEach(func() {
+		clock = &fakeClock{now: time.Now()}
+	})
+
+	It("doesn't limit if the rate is 0", func() {
+		b := newTokenBucket(0, 0, clock)
+		Expect(b.Allowance(1 << 20)).To(Equal(protocol.ByteCount(1 << 20)))
+		b.Consume(1 << 20)
+		Expect(b.Allowance(1 << 20)).To(Equal(protocol.ByteCount(1 << 20)))
+		Expect(b.NextSendTime()).To(BeZero())
+	})
+
+	It("starts with a full bucket", func() {
+		b := newTokenBucket(10000, 5000, clock)
+		Expect(b.Allowance(1000)).To(Equal(protocol.ByteCount(1000)))
+		Expect(b.Allowance(10000)).To(Equal(protocol.ByteCount(5000)))
+	})
+
+	It("uses a default burst size", func() {
+		b := newTokenBucket(1e6, 0, clock)
+		Expect(b.Allowance(1 << 20)).To(Equal(protocol.ByteCount(1e5)))
+		b = newTokenBucket(1000, 0, clock)
+		Expect(b.Allowance(1 << 20)).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+	})
+
+	It("blocks until enough tokens are available", func() {
+		b := newTokenBucket(10000, 5000, clock)
+		b.Consume(4500)
+		Expect(b.NextSendTime()).To(BeZero())
+		// 500 bytes are available, but we'd like to send a full packet
+		Expect(b.Allowance(1000)).To(BeZero())
+		Expect(b.NextSendTime()).To(Equal(clock.now.Add(50 * time.Millisecond)))
+		clock.now = clock.now.Add(25 * time.Millisecond)
+		Expect(b.Allowance(1000)).To(BeZero())
+		Expect(b.NextSendTime()).To(Equal(clock.now.Add(25 * time.Millisecond)))
+		clock.now = clock.now.Add(25 * time.Millisecond)
+		Expect(b.NextSendTime()).To(BeZero())
+		Expect(b.Allowance(1000)).To(Equal(protocol.ByteCount(1000)))
+		Expect(b.NextSendTime()).To(BeZero())
+	})
+
+	It("doesn't accumulate more tokens than the burst size", func() {
+		b := newTokenBucket(10000, 5000, clock)
+		clock.now = clock.now.Add(time.Hour)
+		Expect(b.Allowance(1 << 20)).To(Equal(protocol.ByteCount(5000)))
+	})
+
+	It("allows sending small packets if the burst size is small", func() {
+		b := newTokenBucket(10000, 500, clock)
+		Expect(b.Allowance(1000)).To(Equal(protocol.ByteCount(500)))
+		b.Consume(500)
+		Expect(b.Allowance(1000)).To(BeZero())
+		Expect(b.NextSendTime()).To(Equal(clock.now.Add(50 * time.Millisecond)))
+	})
+
+	It("changes the limit", func() {
+		b := newTokenBucket(10000, 5000, clock)
+		b.Consume(5000)
+		Expect(b.Allowance(1000)).To(BeZero())
+		Expect(b.NextSendTime()).ToNot(BeZero())
+		b.SetLimit(20000, 2000)
+		Expect(b.NextSendTime()).To(BeZero())
+		Expect(b.Allowance(5000)).To(Equal(protocol.ByteCount(2000)))
+		b.Consume(2000)
+		Expect(b.Allowance(1000)).To(BeZero())
+		Expect(b.NextSendTime()).To(Equal(clock.now.Add(50 * time.Millisecond)))
+		b.SetLimit(0, 0)
+		Expect(b.Allowance(5000)).To(Equal(protocol.ByteCount(5000)))
+	})
+})
@@ -43,13 +43,24 @@ type Transport struct {
 	// The length of the connection ID in bytes.
 	// It can be 0, or any value between 4 and 18.
 	// If unset, a 4 byte connection ID will be used.
+	// To use zero-length connection IDs, set ZeroLengthConnectionIDs.
 	ConnectionIDLength int
 
+	// ZeroLengthConnectionIDs makes the Transport use zero-length connection IDs.
+	// This saves between 4 and 18 bytes per packet sent by the peer, which can be significant on constrained links.
+	// Since incoming packets can then only be associated with a connection by their remote address,
+	// the Transport can only be used for a single outgoing connection at a time, and it can't be used to listen
+	// for incoming connections.
+	// It can't be combined with ConnectionIDLength or ConnectionIDGenerator.
+	ZeroLengthConnectionIDs bool
+
 	// Use for generating new connection IDs.
 	// This allows the application to control of the connection IDs used,
 	// which allows routing / load balancing based on connection IDs.
 	// All Connection IDs returned by the ConnectionIDGenerator MUST
 	// have the same length.
+	// If the generator implements RotatingConnectionIDGenerator, connections replace their connection IDs
+	// whenever the epoch changes.
 	ConnectionIDGenerator ConnectionIDGenerator
 
 	// The StatelessResetKey is used to generate stateless reset tokens.
@@ -72,16 +83,71 @@ type Transport struct {
 	// See section 8.1.3 of RFC 9000 for details.
 	MaxTokenAge time.Duration
 
+	// SessionTicketKeys provides the keys used to encrypt and decrypt TLS session tickets,
+	// which are used for session resumption and 0-RTT.
+	// If multiple servers are authoritative for the same domain, they should use the same keys,
+	// in order for session resumption to work across servers.
+	// If set, it takes precedence over any session ticket keys set on the tls.Config,
+	// including the configs returned by its GetConfigForClient callback.
+	// If not set, crypto/tls automatically generates and rotates session ticket keys.
+	SessionTicketKeys SessionTicketKeyProvider
+
 	// DisableVersionNegotiationPackets disables the sending of Version Negotiation packets.
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for clients.
 	DisableVersionNegotiationPackets bool
 
+	// MaxConnections is the maximum number of incoming connections that haven't been closed yet,
+	// including connections that are still handshaking.
+	// If zero, the number of connections is not limited.
+	MaxConnections int
+	// MaxConcurrentHandshakes is the maximum number of incoming connections that are handshaking at the same time.
+	// If zero, the number of concurrent handshakes is not limited.
+	MaxConcurrentHandshakes int
+	// ConnectionLimitBehavior determines how connection attempts exceeding
+	// MaxConnections or MaxConcurrentHandshakes are handled.
+	// By default, they are refused.
+	ConnectionLimitBehavior ConnectionLimitBehavior
+
 	// A Tracer traces events that don't belong to a single QUIC connection.
 	Tracer *logging.Tracer
 
+	// Logger is used for logging, instead of the default logger (configured by the QUIC_GO_LOG_LEVEL environment variable).
+	// Log messages of connections carry the connection ID and the remote address as attributes,
+	// see Config.Logger for setting a different logger per connection.
+	Logger Logger
+	// LogLevels sets the log level for individual subsystems, e.g. "server", "client" or "session tickets".
+	// The subsystem is logged as the "subsystem" attribute.
+	// Messages of other subsystems are passed to the Logger, which is responsible for filtering them.
+	// It is only used if a Logger is set.
+	LogLevels map[string]LogLevel
+
+	// FilterIncomingPacket is called for every packet received on the Conn,
+	// with the sender's address and the first byte of the packet, before the packet is parsed.
+	// If it returns false, the packet is dropped, without being traced.
+	// This allows implementing blocklists and rate limits at a very low cost per packet.
+	// It is called on the Transport's receive loop, and therefore must not block.
+	FilterIncomingPacket func(remoteAddr net.Addr, firstByte byte) bool
+
+	// ImpairIncomingPacket is called for every packet received on the Conn, before it is processed.
+	// ImpairOutgoingPacket is called for every packet before it is sent on the Conn.
+	// The returned PacketImpairment determines if the packet is dropped, delayed or duplicated.
+	// The callbacks may modify the packet data in place, e.g. to simulate corruption.
+	// Delayed packets that are still pending when the Transport is closed are dropped.
+	// They are intended for fault-injection testing, and must not be set in production.
+	ImpairIncomingPacket func(remoteAddr net.Addr, data []byte) PacketImpairment
+	ImpairOutgoingPacket func(remoteAddr net.Addr, data []byte) PacketImpairment
+
 	handlerMap packetHandlerManager
 
+	// reroute is set for Transports that are part of a ReusePortGroup.
+	// It is called for short header packets with an unknown connection ID,
+	// and returns true if the packet was handed to a connection on a different socket.
+	// Since all sockets of the group are bound to the same address, these Transports are not registered with the multiplexer.
+	reroute func(receivedPacket, protocol.ConnectionID) bool
+	// multihomed is set for Transports that are part of a MultihomedGroup.
+	multihomed *MultihomedGroup
+
 	mutex    sync.Mutex
 	initOnce sync.Once
 	initErr  error
@@ -92,19 +158,35 @@ type Transport struct {
 	// Set in init.
 	// If no ConnectionIDGenerator is set, this is set to a default.
 	connIDGenerator ConnectionIDGenerator
+	// Set in init.
+	shortHdrConnIDLen shortHeaderConnIDLen
 
 	server *baseServer
+	// peerDials contains the connection attempts started by DialPeer, indexed by the peer's address.
+	peerDials map[string]*peerDial
+	// preconnected contains the connections established by Preconnect, indexed by the server's address.
+	preconnected map[string]Connection
 
 	conn rawConn
+	// socketBuffers sizes the kernel buffers of the Conn.
+	// It is nil if the Conn was already a rawConn.
+	socketBuffers atomic.Pointer[socketBufferTuner]
+	// gsoConn allows disabling GSO at runtime. It is set when the Transport is first used.
+	gsoConn atomic.Pointer[gsoFallbackConn]
 
 	closeQueue          chan closePacket
 	statelessResetQueue chan receivedPacket
+	// delayedPackets holds the packets delayed by ImpairIncomingPacket and ImpairOutgoingPacket.
+	delayedPackets delayQueue
 
 	listening   chan struct{} // is closed when listen returns
 	closed      bool
 	createdConn bool
 	isSingleUse bool // was created for a single server or client, i.e. by calling quic.Listen or quic.Dial
 
+	// When using zero-length connection IDs, this is the remote address of the connection.
+	zeroLenConnRemoteAddr atomic.Pointer[string]
+
 	readingNonQUICPackets atomic.Bool
 	nonQUICPackets        chan receivedPacket
 
@@ -151,6 +233,22 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 	if err := t.init(false); err != nil {
 		return nil, err
 	}
+	if t.ZeroLengthConnectionIDs {
+		return nil, errors.New("quic: can't listen on a Transport using zero-length connection IDs")
+	}
+	var sessionTicketKeys *sessionTicketKeyRing
+	if t.SessionTicketKeys != nil {
+		// Clone the tls.Config, so that the session ticket keys can be rotated without modifying the user's config.
+		tlsConf = tlsConf.Clone()
+		var err error
+		sessionTicketKeys, err = newSessionTicketKeyRing(t.SessionTicketKeys, tlsConf, t.logger.WithPrefix("session tickets"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tuner := t.socketBuffers.Load(); tuner != nil {
+		tuner.EnsureWindow(protocol.ByteCount(conf.InitialConnectionReceiveWindow))
+	}
 	s := newServer(
 		t.conn,
 		t.handlerMap,
@@ -163,22 +261,36 @@ func (t *Transport) createServer(tlsConf *tls.Config, conf *Config, allow0RTT bo
 		t.MaxTokenAge,
 		t.DisableVersionNegotiationPackets,
 		allow0RTT,
+		connectionLimits{
+			maxConns:      t.MaxConnections,
+			maxHandshakes: t.MaxConcurrentHandshakes,
+			behavior:      t.ConnectionLimitBehavior,
+		},
+		t.logger,
 	)
+	s.claimConn = t.claimPeerConn
+	s.multihomed = t.multihomed
+	if sessionTicketKeys != nil {
+		go sessionTicketKeys.run(s.running)
+	}
 	t.server = s
 	return s, nil
 }
 
 // Dial dials a new connection to a remote host (not using 0-RTT).
 func (t *Transport) Dial(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config) (Connection, error) {
-	return t.dial(ctx, addr, "", tlsConf, conf, false)
+	return t.dial(ctx, addr, "", tlsConf, conf, false, protocol.ConnectionID{}, packetInfo{})
 }
 
 // DialEarly dials a new connection, attempting to use 0-RTT if possible.
 func (t *Transport) DialEarly(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *Config) (EarlyConnection, error) {
-	return t.dial(ctx, addr, "", tlsConf, conf, true)
+	return t.dial(ctx, addr, "", tlsConf, conf, true, protocol.ConnectionID{}, packetInfo{})
 }
 
-func (t *Transport) dial(ctx context.Context, addr net.Addr, host string, tlsConf *tls.Config, conf *Config, use0RTT bool) (EarlyConnection, error) {
+// dial dials a new connection.
+// If destConnID is set, it is used as the Destination Connection ID of the Initial packets.
+// The packet info selects the local address and the network interface, if set.
+func (t *Transport) dial(ctx context.Context, addr net.Addr, host string, tlsConf *tls.Config, conf *Config, use0RTT bool, destConnID protocol.ConnectionID, info packetInfo) (EarlyConnection, error) {
 	if err := validateConfig(conf); err != nil {
 		return nil, err
 	}
@@ -186,14 +298,42 @@ func (t *Transport) dial(ctx context.Context, addr net.Addr, host string, tlsCon
 	if err := t.init(t.isSingleUse); err != nil {
 		return nil, err
 	}
+	if tuner := t.socketBuffers.Load(); tuner != nil {
+		tuner.EnsureWindow(protocol.ByteCount(conf.InitialConnectionReceiveWindow))
+	}
 	var onClose func()
 	if t.isSingleUse {
 		onClose = func() { t.Close() }
 	}
+	var zeroLenConnRemoteAddr *string
+	if t.connIDLen == 0 {
+		// Packets using zero-length connection IDs can only be demultiplexed using the remote address.
+		remoteAddr := addr.String()
+		zeroLenConnRemoteAddr = &remoteAddr
+		t.mutex.Lock()
+		_, ok := t.handlerMap.Get(protocol.ConnectionID{})
+		if ok || t.zeroLenConnRemoteAddr.Load() != nil {
+			t.mutex.Unlock()
+			return nil, errors.New("quic: a Transport using zero-length connection IDs can only be used for a single connection at a time")
+		}
+		t.zeroLenConnRemoteAddr.Store(zeroLenConnRemoteAddr)
+		t.mutex.Unlock()
+		closeTransport := onClose
+		onClose = func() {
+			t.zeroLenConnRemoteAddr.CompareAndSwap(zeroLenConnRemoteAddr, nil)
+			if closeTransport != nil {
+				closeTransport()
+			}
+		}
+	}
 	tlsConf = tlsConf.Clone()
 	tlsConf.MinVersion = tls.VersionTLS13
 	setTLSConfigServerName(tlsConf, addr, host)
-	return dial(ctx, newSendConn(t.conn, addr, packetInfo{}, utils.DefaultLogger), t.connIDGenerator, t.handlerMap, tlsConf, conf, onClose, use0RTT)
+	conn, err := dial(ctx, newSendConn(t.conn, addr, info, t.logger), t.connIDGenerator, destConnID, t.handlerMap, tlsConf, conf, onClose, use0RTT, t.logger)
+	if err != nil && zeroLenConnRemoteAddr != nil {
+		t.zeroLenConnRemoteAddr.CompareAndSwap(zeroLenConnRemoteAddr, nil)
+	}
+	return conn, err
 }
 
 func (t *Transport) init(allowZeroLengthConnIDs bool) error {
@@ -202,6 +342,9 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 		if c, ok := t.Conn.(rawConn); ok {
 			conn = c
 		} else {
+			tuner := newSocketBufferTuner(t.Conn, utils.DefaultLogger)
+			tuner.Init()
+			t.socketBuffers.Store(tuner)
 			var err error
 			conn, err = wrapConn(t.Conn)
 			if err != nil {
@@ -210,8 +353,25 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			}
 		}
 
-		t.logger = utils.DefaultLogger // TODO: make this configurable
-		t.conn = conn
+		t.logger = utils.DefaultLogger
+		if t.Logger != nil {
+			t.logger = utils.NewStructuredLogger(t.Logger, t.LogLevels)
+		}
+
+		if t.ImpairOutgoingPacket != nil {
+			conn = &impairedConn{
+				rawConn: conn,
+				impair:  t.ImpairOutgoingPacket,
+				delayed: &t.delayedPackets,
+				logger:  t.logger,
+			}
+		}
+		if tuner := t.socketBuffers.Load(); tuner != nil {
+			conn = &socketBufferConn{rawConn: conn, tuner: tuner}
+		}
+		gsoConn := newGSOFallbackConn(conn, t.onGSODisabled)
+		t.gsoConn.Store(gsoConn)
+		t.conn = gsoConn
 		t.handlerMap = newPacketHandlerMap(t.StatelessResetKey, t.enqueueClosePacket, t.logger)
 		t.listening = make(chan struct{})
 
@@ -226,25 +386,78 @@ func (t *Transport) init(allowZeroLengthConnIDs bool) error {
 			t.TokenGeneratorKey = &key
 		}
 
+		if t.ZeroLengthConnectionIDs && (t.ConnectionIDLength != 0 || t.ConnectionIDGenerator != nil) {
+			t.initErr = errors.New("quic: ZeroLengthConnectionIDs can't be combined with ConnectionIDLength or ConnectionIDGenerator")
+			return
+		}
 		if t.ConnectionIDGenerator != nil {
 			t.connIDGenerator = t.ConnectionIDGenerator
 			t.connIDLen = t.ConnectionIDGenerator.ConnectionIDLen()
 		} else {
 			connIDLen := t.ConnectionIDLength
-			if t.ConnectionIDLength == 0 && !allowZeroLengthConnIDs {
+			if t.ConnectionIDLength == 0 && !allowZeroLengthConnIDs && !t.ZeroLengthConnectionIDs {
 				connIDLen = protocol.DefaultConnectionIDLength
 			}
 			t.connIDLen = connIDLen
 			t.connIDGenerator = &protocol.DefaultConnectionIDGenerator{ConnLen: t.connIDLen}
 		}
+		t.shortHdrConnIDLen = newShortHeaderConnIDLen(t.connIDLen, t.connIDGenerator)
 
-		getMultiplexer().AddConn(t.Conn)
+		if t.reroute == nil {
+			getMultiplexer().AddConn(t.Conn)
+		}
 		go t.listen(conn)
 		go t.runSendQueue()
 	})
 	return t.initErr
 }
 
+// SocketBuffers returns the sizes of the kernel buffers of the UDP socket,
+// and the errors that occurred when quic-go tried to increase them.
+// It returns the zero value if the Transport hasn't been used yet, or if quic-go doesn't manage the buffers of the Conn.
+func (t *Transport) SocketBuffers() SocketBuffers {
+	tuner := t.socketBuffers.Load()
+	if tuner == nil {
+		return SocketBuffers{}
+	}
+	return tuner.Buffers()
+}
+
+// OffloadState returns the current state of Generic Segmentation Offload (GSO).
+// GSO is disabled automatically if sending GSO batches fails, or if GSO batches are dropped on the path.
+// It returns the zero value if the Transport hasn't been used yet.
+func (t *Transport) OffloadState() OffloadState {
+	c := t.gsoConn.Load()
+	if c == nil {
+		return OffloadState{}
+	}
+	return c.offloadState()
+}
+
+func (t *Transport) onGSODisabled(reason logging.GSODisabledReason) {
+	switch reason {
+	case logging.GSODisabledSendError:
+		t.logger.Infof("Disabling GSO: sending a GSO batch failed.")
+	case logging.GSODisabledBlackholed:
+		t.logger.Infof("Disabling GSO: GSO batches are being dropped.")
+	}
+	if t.Tracer != nil && t.Tracer.GSODisabled != nil {
+		t.Tracer.GSODisabled(reason)
+	}
+}
+
+// ConnectionStats returns statistics about the incoming connections.
+// If the Transport is not listening, the zero value is returned.
+func (t *Transport) ConnectionStats() ConnectionStats {
+	t.mutex.Lock()
+	s := t.server
+	t.mutex.Unlock()
+	if s == nil {
+		return ConnectionStats{}
+	}
+	return s.connectionStats()
+}
+
 // WriteTo sends a packet on the underlying connection.
 func (t *Transport) WriteTo(b []byte, addr net.Addr) (int, error) {
 	if err := t.init(false); err != nil {
@@ -298,6 +511,7 @@ func (t *Transport) closeServer() {
 	t.mutex.Lock()
 	t.server = nil
 	if t.isSingleUse {
+		t.delayedPackets.Close()
 		t.closed = true
 	}
 	t.mutex.Unlock()
@@ -324,6 +538,7 @@ func (t *Transport) close(e error) {
 	if t.server != nil {
 		t.server.setCloseError(e)
 	}
+	t.delayedPackets.Close()
 	t.closed = true
 }
 
@@ -332,7 +547,9 @@ var setBufferWarningOnce sync.Once
 
 func (t *Transport) listen(conn rawConn) {
 	defer close(t.listening)
-	defer getMultiplexer().RemoveConn(t.Conn)
+	if t.reroute == nil {
+		defer getMultiplexer().RemoveConn(t.Conn)
+	}
 
 	for {
 		p, err := conn.ReadPacket()
@@ -358,6 +575,14 @@ func (t *Transport) listen(conn rawConn) {
 			t.close(err)
 			return
 		}
+		if t.FilterIncomingPacket != nil && len(p.data) > 0 && !t.FilterIncomingPacket(p.remoteAddr, p.data[0]) {
+			p.buffer.MaybeRelease()
+			continue
+		}
+		if t.ImpairIncomingPacket != nil {
+			t.handleIncomingPacket(p)
+			continue
+		}
 		t.handlePacket(p)
 	}
 }
@@ -370,7 +595,7 @@ func (t *Transport) handlePacket(p receivedPacket) {
 		t.handleNonQUICPacket(p)
 		return
 	}
-	connID, err := wire.ParseConnectionID(p.data, t.connIDLen)
+	connID, err := parseConnectionID(p.data, t.shortHdrConnIDLen)
 	if err != nil {
 		t.logger.Debugf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
 		if t.Tracer != nil && t.Tracer.DroppedPacket != nil {
@@ -379,21 +604,41 @@ func (t *Transport) handlePacket(p receivedPacket) {
 		p.buffer.MaybeRelease()
 		return
 	}
+	if connID.Len() == 0 && t.connIDLen == 0 {
+		if remoteAddr := t.zeroLenConnRemoteAddr.Load(); remoteAddr != nil && *remoteAddr != p.remoteAddr.String() {
+			t.logger.Debugf("received a packet with a zero-length connection ID from unexpected address %s", p.remoteAddr)
+			if t.Tracer != nil && t.Tracer.DroppedPacket != nil {
+				t.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropUnknownConnectionID)
+			}
+			p.buffer.MaybeRelease()
+			return
+		}
+	}
 
 	if isStatelessReset := t.maybeHandleStatelessReset(p.data); isStatelessReset {
 		return
 	}
+	if t.multihomed != nil {
+		// Connections use this to detect that the client migrated to a different address of the group.
+		p.rcvConn = t.conn
+	}
 	if handler, ok := t.handlerMap.Get(connID); ok {
 		handler.handlePacket(p)
 		return
 	}
 	if !wire.IsLongHeaderPacket(p.data[0]) {
+		if t.reroute != nil && t.reroute(p, connID) {
+			return
+		}
 		t.maybeSendStatelessReset(p)
 		return
 	}
 
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	if len(t.peerDials) > 0 && t.maybeDropPeerInitial(p) {
+		return
+	}
 	if t.server == nil { // no server set
 		t.logger.Debugf("received a packet with an unexpected connection ID %s", connID)
 		return
@@ -425,7 +670,7 @@ func (t *Transport) maybeSendStatelessReset(p receivedPacket) {
 func (t *Transport) sendStatelessReset(p receivedPacket) {
 	defer p.buffer.Release()
 
-	connID, err := wire.ParseConnectionID(p.data, t.connIDLen)
+	connID, err := parseConnectionID(p.data, t.shortHdrConnIDLen)
 	if err != nil {
 		t.logger.Errorf("error parsing connection ID on packet from %s: %s", p.remoteAddr, err)
 		return
@@ -0,0 +1,133 @@
+package quic
+
+import (
+	"net/netip"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// TransportParameters are the transport parameters sent by the peer during the handshake,
+// see section 18 of RFC 9000.
+// Parameters that the peer didn't send have their default values.
+type TransportParameters struct {
+	// MaxIdleTimeout is the peer's idle timeout. Zero means that the peer doesn't have an idle timeout.
+	MaxIdleTimeout time.Duration
+	// MaxUDPPayloadSize is the maximum size of UDP payloads the peer is willing to receive.
+	MaxUDPPayloadSize uint64
+
+	// InitialMaxData is the initial connection-level flow control limit.
+	InitialMaxData uint64
+	// InitialMaxStreamDataBidiLocal is the initial flow control limit for bidirectional streams opened by the peer.
+	InitialMaxStreamDataBidiLocal uint64
+	// InitialMaxStreamDataBidiRemote is the initial flow control limit for bidirectional streams opened by us.
+	InitialMaxStreamDataBidiRemote uint64
+	// InitialMaxStreamDataUni is the initial flow control limit for unidirectional streams opened by us.
+	InitialMaxStreamDataUni uint64
+	// InitialMaxStreamsBidi is the initial number of bidirectional streams we're allowed to open.
+	InitialMaxStreamsBidi int64
+	// InitialMaxStreamsUni is the initial number of unidirectional streams we're allowed to open.
+	InitialMaxStreamsUni int64
+
+	// AckDelayExponent is the exponent used by the peer to encode the ACK Delay field of ACK frames.
+	AckDelayExponent uint8
+	// MaxAckDelay is the maximum time the peer delays sending of acknowledgments.
+	MaxAckDelay time.Duration
+
+	// DisableActiveMigration says if the peer doesn't support active connection migration.
+	DisableActiveMigration bool
+	// PreferredAddress is the server's preferred address. Only sent by servers.
+	PreferredAddress *PreferredAddress
+	// ActiveConnectionIDLimit is the maximum number of connection IDs the peer is willing to store.
+	ActiveConnectionIDLimit uint64
+
+	// OriginalDestinationConnectionID is the Destination Connection ID of the client's first Initial packet.
+	// Only sent by servers.
+	OriginalDestinationConnectionID ConnectionID
+	// InitialSourceConnectionID is the Source Connection ID the peer used on its first Initial packet.
+	InitialSourceConnectionID ConnectionID
+	// RetrySourceConnectionID is the Source Connection ID of the Retry packet.
+	// Only sent by servers, and only if a Retry was performed.
+	RetrySourceConnectionID *ConnectionID
+	// StatelessResetToken is the stateless reset token. Only sent by servers.
+	StatelessResetToken *[16]byte
+
+	// MaxDatagramFrameSize is the maximum size of DATAGRAM frames the peer is willing to receive (RFC 9221).
+	// Zero means that the peer doesn't support datagrams.
+	MaxDatagramFrameSize uint64
+	// AddressDiscoveryMode is the mode of the QUIC Address Discovery extension advertised by the peer.
+	AddressDiscoveryMode AddressDiscoveryMode
+	// ImmediateAck says if the peer supports receiving IMMEDIATE_ACK frames.
+	ImmediateAck bool
+	// NullEncryption says if the peer supports protecting 1-RTT packets with a null AEAD.
+	NullEncryption bool
+
+	// UnknownParameters are the transport parameters that are not implemented by quic-go,
+	// e.g. the parameters used to negotiate support for extension frames, keyed by their ID.
+	// Reserved transport parameters (used for greasing) are not included.
+	UnknownParameters map[uint64][]byte
+}
+
+// PreferredAddress is the value of the preferred_address transport parameter, see section 9.6 of RFC 9000.
+type PreferredAddress struct {
+	// IPv4 is the IPv4 address. It is the zero value if the server didn't provide an IPv4 address.
+	IPv4 netip.AddrPort
+	// IPv6 is the IPv6 address. It is the zero value if the server didn't provide an IPv6 address.
+	IPv6                netip.AddrPort
+	ConnectionID        ConnectionID
+	StatelessResetToken [16]byte
+}
+
+func newTransportParameters(p *wire.TransportParameters) *TransportParameters {
+	tp := &TransportParameters{
+		MaxIdleTimeout:                  p.MaxIdleTimeout,
+		MaxUDPPayloadSize:               uint64(p.MaxUDPPayloadSize),
+		InitialMaxData:                  uint64(p.InitialMaxData),
+		InitialMaxStreamDataBidiLocal:   uint64(p.InitialMaxStreamDataBidiLocal),
+		InitialMaxStreamDataBidiRemote:  uint64(p.InitialMaxStreamDataBidiRemote),
+		InitialMaxStreamDataUni:         uint64(p.InitialMaxStreamDataUni),
+		InitialMaxStreamsBidi:           int64(p.MaxBidiStreamNum),
+		InitialMaxStreamsUni:            int64(p.MaxUniStreamNum),
+		AckDelayExponent:                p.AckDelayExponent,
+		MaxAckDelay:                     p.MaxAckDelay,
+		DisableActiveMigration:          p.DisableActiveMigration,
+		ActiveConnectionIDLimit:         p.ActiveConnectionIDLimit,
+		OriginalDestinationConnectionID: p.OriginalDestinationConnectionID,
+		InitialSourceConnectionID:       p.InitialSourceConnectionID,
+		AddressDiscoveryMode:            p.AddressDiscoveryMode,
+		ImmediateAck:                    p.ImmediateAck,
+		NullEncryption:                  p.NullEncryption,
+	}
+	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
+		tp.MaxDatagramFrameSize = uint64(p.MaxDatagramFrameSize)
+	}
+	if p.RetrySourceConnectionID != nil {
+		connID := *p.RetrySourceConnectionID
+		tp.RetrySourceConnectionID = &connID
+	}
+	if p.StatelessResetToken != nil {
+		token := [16]byte(*p.StatelessResetToken)
+		tp.StatelessResetToken = &token
+	}
+	if pa := p.PreferredAddress; pa != nil {
+		tp.PreferredAddress = &PreferredAddress{
+			ConnectionID:        pa.ConnectionID,
+			StatelessResetToken: pa.StatelessResetToken,
+		}
+		// An address of all zeros (and port 0) means that the server didn't provide an address of this family.
+		if ip, ok := netip.AddrFromSlice(pa.IPv4); ok && (!ip.IsUnspecified() || pa.IPv4Port != 0) {
+			tp.PreferredAddress.IPv4 = netip.AddrPortFrom(ip.Unmap(), pa.IPv4Port)
+		}
+		if ip, ok := netip.AddrFromSlice(pa.IPv6); ok && (!ip.IsUnspecified() || pa.IPv6Port != 0) {
+			tp.PreferredAddress.IPv6 = netip.AddrPortFrom(ip, pa.IPv6Port)
+		}
+	}
+	if len(p.CustomParameters) > 0 {
+		tp.UnknownParameters = make(map[uint64][]byte, len(p.CustomParameters))
+		for id, val := range p.CustomParameters {
+			tp.UnknownParameters[id] = append([]byte(nil), val...)
+		}
+	}
+	return tp
+}
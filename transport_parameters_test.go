@@ -0,0 +1,94 @@
+package quic
+
+import (
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transport Parameters", func() {
+	It("converts the transport parameters", func() {
+		retrySrcConnID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+		token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		params := &wire.TransportParameters{
+			InitialMaxStreamDataBidiLocal:   1,
+			InitialMaxStreamDataBidiRemote:  2,
+			InitialMaxStreamDataUni:         3,
+			InitialMaxData:                  4,
+			MaxAckDelay:                     5 * time.Millisecond,
+			AckDelayExponent:                6,
+			DisableActiveMigration:          true,
+			MaxUDPPayloadSize:               1400,
+			MaxUniStreamNum:                 7,
+			MaxBidiStreamNum:                8,
+			MaxIdleTimeout:                  9 * time.Second,
+			OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{5, 6, 7, 8}),
+			RetrySourceConnectionID:         &retrySrcConnID,
+			StatelessResetToken:             &token,
+			ActiveConnectionIDLimit:         10,
+			MaxDatagramFrameSize:            1200,
+			AddressDiscoveryMode:            protocol.AddressDiscoveryProvide,
+			ImmediateAck:                    true,
+			CustomParameters:                map[uint64][]byte{0x1337: []byte("foobar")},
+		}
+		tp := newTransportParameters(params)
+		Expect(*tp).To(Equal(TransportParameters{
+			MaxIdleTimeout:                  9 * time.Second,
+			MaxUDPPayloadSize:               1400,
+			InitialMaxData:                  4,
+			InitialMaxStreamDataBidiLocal:   1,
+			InitialMaxStreamDataBidiRemote:  2,
+			InitialMaxStreamDataUni:         3,
+			InitialMaxStreamsBidi:           8,
+			InitialMaxStreamsUni:            7,
+			AckDelayExponent:                6,
+			MaxAckDelay:                     5 * time.Millisecond,
+			DisableActiveMigration:          true,
+			ActiveConnectionIDLimit:         10,
+			OriginalDestinationConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			InitialSourceConnectionID:       protocol.ParseConnectionID([]byte{5, 6, 7, 8}),
+			RetrySourceConnectionID:         &retrySrcConnID,
+			StatelessResetToken:             (*[16]byte)(&token),
+			MaxDatagramFrameSize:            1200,
+			AddressDiscoveryMode:            AddressDiscoveryProvide,
+			ImmediateAck:                    true,
+			UnknownParameters:               map[uint64][]byte{0x1337: []byte("foobar")},
+		}))
+
+		// the returned values don't alias the transport parameters
+		params.CustomParameters[0x1337][0] = 'x'
+		*params.RetrySourceConnectionID = protocol.ParseConnectionID([]byte{1})
+		Expect(tp.UnknownParameters[0x1337]).To(Equal([]byte("foobar")))
+		Expect(*tp.RetrySourceConnectionID).To(Equal(protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})))
+	})
+
+	It("reports a zero datagram frame size if datagrams are not supported", func() {
+		tp := newTransportParameters(&wire.TransportParameters{MaxDatagramFrameSize: protocol.InvalidByteCount})
+		Expect(tp.MaxDatagramFrameSize).To(BeZero())
+		Expect(tp.UnknownParameters).To(BeNil())
+	})
+
+	It("converts the preferred address", func() {
+		tp := newTransportParameters(&wire.TransportParameters{
+			PreferredAddress: &wire.PreferredAddress{
+				IPv4:                net.IPv4(127, 0, 0, 1).To4(),
+				IPv4Port:            42,
+				IPv6:                net.IPv6zero,
+				ConnectionID:        protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+				StatelessResetToken: protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+			},
+		})
+		Expect(tp.PreferredAddress).ToNot(BeNil())
+		Expect(tp.PreferredAddress.IPv4).To(Equal(netip.MustParseAddrPort("127.0.0.1:42")))
+		Expect(tp.PreferredAddress.IPv6.IsValid()).To(BeFalse())
+		Expect(tp.PreferredAddress.ConnectionID).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+		Expect(tp.PreferredAddress.StatelessResetToken).To(Equal([16]byte{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
+	})
+})
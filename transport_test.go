@@ -146,6 +146,44 @@ var _ = Describe("Transport", func() {
 		tr.Close()
 	})
 
+	It("filters packets before parsing them", func() {
+		blocked := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
+		allowed := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+		packetChan := make(chan packetToRead)
+		filtered := make(chan byte, 10)
+		tr := &Transport{
+			Conn: newMockPacketConn(packetChan),
+			FilterIncomingPacket: func(addr net.Addr, firstByte byte) bool {
+				filtered <- firstByte
+				return addr.String() != blocked.String()
+			},
+		}
+		tr.init(true)
+		phm := NewMockPacketHandlerManager(mockCtrl)
+		tr.handlerMap = phm
+
+		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		handled := make(chan receivedPacket, 1)
+		phm.EXPECT().Get(connID).DoAndReturn(func(protocol.ConnectionID) (packetHandler, bool) {
+			h := NewMockPacketHandler(mockCtrl)
+			h.EXPECT().handlePacket(gomock.Any()).Do(func(p receivedPacket) { handled <- p })
+			return h, true
+		})
+		// the packet handler manager is only called for packets that passed the filter
+		packetChan <- packetToRead{addr: blocked, data: getPacket(connID)}
+		packetChan <- packetToRead{addr: allowed, data: getPacket(connID)}
+		var p receivedPacket
+		Eventually(handled).Should(Receive(&p))
+		Expect(p.remoteAddr).To(Equal(allowed))
+		Expect(filtered).To(HaveLen(2))
+		Expect(<-filtered).To(Equal(getPacket(connID)[0]))
+
+		// shutdown
+		phm.EXPECT().Close(gomock.Any())
+		close(packetChan)
+		tr.Close()
+	})
+
 	It("closes when reading from the conn fails", func() {
 		packetChan := make(chan packetToRead)
 		tr := Transport{Conn: newMockPacketConn(packetChan)}
@@ -325,6 +363,53 @@ var _ = Describe("Transport", func() {
 		Expect(len(conns)).To(BeZero())
 	})
 
+	It("reports the socket buffer sizes", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		tr := &Transport{Conn: conn}
+		defer tr.Close()
+		Expect(tr.SocketBuffers()).To(BeZero())
+
+		const window = 3 << 20
+		_, err = tr.Listen(&tls.Config{}, &Config{InitialConnectionReceiveWindow: window})
+		Expect(err).ToNot(HaveOccurred())
+		b := tr.SocketBuffers()
+		Expect(b.DesiredReceiveBufferSize).To(Equal(window))
+		Expect(b.DesiredSendBufferSize).To(Equal(window))
+		if b.ReceiveBufferError == nil {
+			Expect(b.ReceiveBufferSize).To(BeNumerically(">=", window))
+		}
+	})
+
+	It("reports the offload state", func() {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 0})
+		Expect(err).ToNot(HaveOccurred())
+		var reasons []logging.GSODisabledReason
+		tr := &Transport{
+			Conn:   conn,
+			Tracer: &logging.Tracer{GSODisabled: func(r logging.GSODisabledReason) { reasons = append(reasons, r) }},
+		}
+		defer tr.Close()
+		Expect(tr.OffloadState()).To(BeZero())
+
+		_, err = tr.Listen(&tls.Config{}, nil)
+		Expect(err).ToNot(HaveOccurred())
+		state := tr.OffloadState()
+		Expect(state.GSOEnabled).To(Equal(state.GSOSupported))
+		Expect(state.GSODisabledReason).To(BeZero())
+		if !state.GSOSupported {
+			Skip("GSO not supported on this platform")
+		}
+
+		tr.conn.(*gsoFallbackConn).disableGSO(logging.GSODisabledBlackholed)
+		state = tr.OffloadState()
+		Expect(state.GSOSupported).To(BeTrue())
+		Expect(state.GSOEnabled).To(BeFalse())
+		Expect(state.GSODisabledReason).To(Equal(logging.GSODisabledBlackholed))
+		Expect(tr.conn.capabilities().GSO).To(BeFalse())
+		Expect(reasons).To(Equal([]logging.GSODisabledReason{logging.GSODisabledBlackholed}))
+	})
+
 	It("allows receiving non-QUIC packets", func() {
 		remoteAddr := &net.UDPAddr{IP: net.IPv4(9, 8, 7, 6), Port: 1234}
 		packetChan := make(chan packetToRead)
@@ -406,6 +491,116 @@ var _ = Describe("Transport", func() {
 		Entry("removes the port from the hostname", "golang.org", &tls.Config{}, remoteAddr, "golang.org:1234"),
 		Entry("uses the IP", "1.3.5.7", &tls.Config{}, remoteAddr, ""),
 	)
+
+	Context("zero-length connection IDs", func() {
+		It("uses zero-length connection IDs", func() {
+			packetChan := make(chan packetToRead)
+			tr := &Transport{Conn: newMockPacketConn(packetChan), ZeroLengthConnectionIDs: true}
+			defer tr.Close()
+			defer close(packetChan)
+			Expect(tr.init(false)).To(Succeed())
+			Expect(tr.connIDLen).To(BeZero())
+			connID, err := tr.connIDGenerator.GenerateConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(BeZero())
+		})
+
+		It("errors when combined with a connection ID length", func() {
+			packetChan := make(chan packetToRead)
+			defer close(packetChan)
+			tr := &Transport{Conn: newMockPacketConn(packetChan), ZeroLengthConnectionIDs: true, ConnectionIDLength: 8}
+			Expect(tr.init(false)).To(MatchError("quic: ZeroLengthConnectionIDs can't be combined with ConnectionIDLength or ConnectionIDGenerator"))
+		})
+
+		It("refuses to listen", func() {
+			packetChan := make(chan packetToRead)
+			tr := &Transport{Conn: newMockPacketConn(packetChan), ZeroLengthConnectionIDs: true}
+			defer tr.Close()
+			defer close(packetChan)
+			_, err := tr.Listen(&tls.Config{}, nil)
+			Expect(err).To(MatchError("quic: can't listen on a Transport using zero-length connection IDs"))
+		})
+
+		It("allows listening when using a ConnectionIDGenerator that generates zero-length connection IDs", func() {
+			packetChan := make(chan packetToRead)
+			tr := &Transport{
+				Conn:                  newMockPacketConn(packetChan),
+				ConnectionIDGenerator: &protocol.DefaultConnectionIDGenerator{ConnLen: 0},
+			}
+			defer tr.Close()
+			defer close(packetChan)
+			ln, err := tr.Listen(&tls.Config{}, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tr.connIDLen).To(BeZero())
+			Expect(ln.Close()).To(Succeed())
+		})
+
+		It("clears the remote address when dialing fails", func() {
+			packetChan := make(chan packetToRead)
+			conn := newMockPacketConn(packetChan)
+			conn.EXPECT().WriteTo(gomock.Any(), gomock.Any()).AnyTimes()
+			tr := &Transport{Conn: conn, ZeroLengthConnectionIDs: true}
+			defer tr.Close()
+			defer close(packetChan)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := tr.Dial(ctx, &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, &tls.Config{}, nil)
+			Expect(err).To(MatchError(context.Canceled))
+			Expect(tr.zeroLenConnRemoteAddr.Load()).To(BeNil())
+		})
+
+		It("only allows a single connection at a time", func() {
+			packetChan := make(chan packetToRead)
+			tr := &Transport{Conn: newMockPacketConn(packetChan), ZeroLengthConnectionIDs: true}
+			defer tr.Close()
+			defer close(packetChan)
+			Expect(tr.init(false)).To(Succeed())
+			conn := NewMockPacketHandler(mockCtrl)
+			conn.EXPECT().destroy(gomock.Any()).AnyTimes() // when closing the Transport
+			Expect(tr.handlerMap.Add(protocol.ConnectionID{}, conn)).To(BeTrue())
+			_, err := tr.Dial(context.Background(), &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}, &tls.Config{}, nil)
+			Expect(err).To(MatchError("quic: a Transport using zero-length connection IDs can only be used for a single connection at a time"))
+		})
+
+		It("drops packets from other addresses", func() {
+			remoteAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+			otherAddr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 4321}
+			packetChan := make(chan packetToRead)
+			t, tracer := mocklogging.NewMockTracer(mockCtrl)
+			tr := &Transport{Conn: newMockPacketConn(packetChan), ZeroLengthConnectionIDs: true, Tracer: t}
+			Expect(tr.init(false)).To(Succeed())
+			phm := NewMockPacketHandlerManager(mockCtrl)
+			tr.handlerMap = phm
+			addr := remoteAddr.String()
+			tr.zeroLenConnRemoteAddr.Store(&addr)
+
+			b, err := wire.AppendShortHeader(nil, protocol.ConnectionID{}, 1337, protocol.PacketNumberLen2, protocol.KeyPhaseOne)
+			Expect(err).ToNot(HaveOccurred())
+			b = append(b, make([]byte, 20)...)
+			dropped := make(chan struct{})
+			tracer.EXPECT().DroppedPacket(otherAddr, logging.PacketTypeNotDetermined, protocol.ByteCount(len(b)), logging.PacketDropUnknownConnectionID).Do(
+				func(net.Addr, logging.PacketType, protocol.ByteCount, logging.PacketDropReason) { close(dropped) },
+			)
+			packetChan <- packetToRead{addr: otherAddr, data: b}
+			Eventually(dropped).Should(BeClosed())
+
+			conn := NewMockPacketHandler(mockCtrl)
+			handled := make(chan struct{})
+			phm.EXPECT().GetByResetToken(gomock.Any())
+			phm.EXPECT().Get(protocol.ConnectionID{}).Return(conn, true)
+			conn.EXPECT().handlePacket(gomock.Any()).Do(func(p receivedPacket) {
+				Expect(p.remoteAddr).To(Equal(remoteAddr))
+				close(handled)
+			})
+			packetChan <- packetToRead{addr: remoteAddr, data: b}
+			Eventually(handled).Should(BeClosed())
+
+			// shutdown
+			phm.EXPECT().Close(gomock.Any())
+			close(packetChan)
+			tr.Close()
+		})
+	})
 })
 
 type mockSyscallConn struct {
@@ -0,0 +1,165 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// A UniStreamAcceptor accepts unidirectional streams opened by the peer and classifies them by their stream type.
+// Many application protocols (e.g. HTTP/3) start every unidirectional stream with a variable-length integer
+// that identifies the stream type. The UniStreamAcceptor reads this stream type, and queues the stream,
+// such that it can be obtained by calling Accept for that stream type.
+//
+// Once the first stream was accepted, the UniStreamAcceptor has to be the only user of Connection.AcceptUniStream,
+// and its fields must not be modified.
+type UniStreamAcceptor struct {
+	// Connection is the connection to accept streams from. It must be set.
+	Connection Connection
+	// StreamTypes are the stream types expected by the application.
+	StreamTypes []uint64
+	// DiscardUnknown discards streams of types not listed in StreamTypes,
+	// by calling CancelRead with the UnknownStreamErrorCode.
+	// If not set, these streams are returned by AcceptUnknown.
+	DiscardUnknown bool
+	// UnknownStreamErrorCode is the error code used when discarding streams of unknown types.
+	UnknownStreamErrorCode StreamErrorCode
+
+	startOnce sync.Once
+
+	mutex   sync.Mutex
+	queues  map[uint64]*uniStreamQueue
+	unknown uniStreamQueue
+	err     error // set when accepting streams failed
+	closed  chan struct{}
+}
+
+type uniStreamQueue struct {
+	streams   []ReceiveStream
+	types     []uint64      // the stream type of every queued stream
+	available chan struct{} // a signal is sent when a stream is queued
+}
+
+func newUniStreamQueue() *uniStreamQueue {
+	return &uniStreamQueue{available: make(chan struct{}, 1)}
+}
+
+func (q *uniStreamQueue) push(str ReceiveStream, streamType uint64) {
+	q.streams = append(q.streams, str)
+	q.types = append(q.types, streamType)
+	select {
+	case q.available <- struct{}{}:
+	default:
+	}
+}
+
+func (a *UniStreamAcceptor) start() {
+	a.startOnce.Do(func() {
+		a.queues = make(map[uint64]*uniStreamQueue, len(a.StreamTypes))
+		for _, t := range a.StreamTypes {
+			a.queues[t] = newUniStreamQueue()
+		}
+		a.unknown.available = make(chan struct{}, 1)
+		a.closed = make(chan struct{})
+		go a.run()
+	})
+}
+
+func (a *UniStreamAcceptor) run() {
+	for {
+		str, err := a.Connection.AcceptUniStream(context.Background())
+		if err != nil {
+			a.mutex.Lock()
+			a.err = err
+			a.mutex.Unlock()
+			close(a.closed)
+			return
+		}
+		// Read the stream type in a separate go routine,
+		// such that a slow stream doesn't block the streams opened after it.
+		go a.classify(str)
+	}
+}
+
+func (a *UniStreamAcceptor) classify(str ReceiveStream) {
+	streamType, err := quicvarint.Read(quicvarint.NewReader(str))
+	if err != nil {
+		// The peer reset the stream, or the connection was closed.
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if q, ok := a.queues[streamType]; ok {
+		q.push(str, streamType)
+		return
+	}
+	if a.DiscardUnknown {
+		str.CancelRead(a.UnknownStreamErrorCode)
+		return
+	}
+	a.unknown.push(str, streamType)
+}
+
+// Accept returns the next unidirectional stream of the given stream type,
+// blocking until one is available.
+// The stream type has already been read from the stream.
+// The stream type must be one of the StreamTypes.
+func (a *UniStreamAcceptor) Accept(ctx context.Context, streamType uint64) (ReceiveStream, error) {
+	a.start()
+	q, ok := a.queues[streamType]
+	if !ok {
+		return nil, fmt.Errorf("quic: stream type %d not registered", streamType)
+	}
+	str, _, err := a.accept(ctx, q)
+	return str, err
+}
+
+// AcceptUnknown returns the next unidirectional stream of a type not listed in StreamTypes,
+// as well as its stream type, blocking until one is available.
+// It must not be used if DiscardUnknown is set.
+func (a *UniStreamAcceptor) AcceptUnknown(ctx context.Context) (uint64, ReceiveStream, error) {
+	if a.DiscardUnknown {
+		return 0, nil, errors.New("quic: streams of unknown types are discarded")
+	}
+	a.start()
+	str, streamType, err := a.accept(ctx, &a.unknown)
+	return streamType, str, err
+}
+
+func (a *UniStreamAcceptor) accept(ctx context.Context, q *uniStreamQueue) (ReceiveStream, uint64, error) {
+	for {
+		a.mutex.Lock()
+		if len(q.streams) > 0 {
+			str := q.streams[0]
+			streamType := q.types[0]
+			q.streams = q.streams[1:]
+			q.types = q.types[1:]
+			// wake up the next caller waiting for this stream type
+			if len(q.streams) > 0 {
+				select {
+				case q.available <- struct{}{}:
+				default:
+				}
+			}
+			a.mutex.Unlock()
+			return str, streamType, nil
+		}
+		if a.err != nil {
+			err := a.err
+			a.mutex.Unlock()
+			return nil, 0, err
+		}
+		a.mutex.Unlock()
+
+		select {
+		case <-q.available:
+		case <-a.closed:
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/quic-go/quic-go/internal/testdata"
+	"github.com/quic-go/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unidirectional Stream Acceptor", func() {
+	var (
+		clientConn, serverConn Connection
+		ln                     *Listener
+	)
+
+	BeforeEach(func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"uni-stream-acceptor"}
+		var err error
+		ln, err = ListenAddr("127.0.0.1:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		clientConn, err = DialAddr(
+			ctx,
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"uni-stream-acceptor"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		serverConn, err = ln.Accept(ctx)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		clientConn.CloseWithError(0, "")
+		serverConn.CloseWithError(0, "")
+		ln.Close()
+		// the Transport created by DialAddr is closed asynchronously
+		Eventually(areTransportsRunning).Should(BeFalse())
+	})
+
+	openStream := func(streamType uint64, data string) SendStream {
+		str, err := clientConn.OpenUniStream()
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		_, err = str.Write(append(quicvarint.Append(nil, streamType), data...))
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		ExpectWithOffset(1, str.Close()).To(Succeed())
+		return str
+	}
+
+	readStream := func(str ReceiveStream) string {
+		data, err := io.ReadAll(str)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return string(data)
+	}
+
+	It("classifies streams by their type", func() {
+		a := &UniStreamAcceptor{Connection: serverConn, StreamTypes: []uint64{0x42, 0x1337}}
+		openStream(0x1337, "foo")
+		openStream(0x42, "bar")
+		openStream(0x1337, "baz")
+
+		str, err := a.Accept(context.Background(), 0x42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readStream(str)).To(Equal("bar"))
+		str1, err := a.Accept(context.Background(), 0x1337)
+		Expect(err).ToNot(HaveOccurred())
+		str2, err := a.Accept(context.Background(), 0x1337)
+		Expect(err).ToNot(HaveOccurred())
+		Expect([]string{readStream(str1), readStream(str2)}).To(ConsistOf("foo", "baz"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+		defer cancel()
+		_, err = a.Accept(ctx, 0x42)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("errors when accepting an unregistered stream type", func() {
+		a := &UniStreamAcceptor{Connection: serverConn, StreamTypes: []uint64{0x42}}
+		_, err := a.Accept(context.Background(), 0x43)
+		Expect(err).To(MatchError("quic: stream type 67 not registered"))
+	})
+
+	It("returns streams of unknown types", func() {
+		a := &UniStreamAcceptor{Connection: serverConn, StreamTypes: []uint64{0x42}}
+		openStream(0x1337, "foobar")
+		streamType, str, err := a.AcceptUnknown(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streamType).To(BeEquivalentTo(0x1337))
+		Expect(readStream(str)).To(Equal("foobar"))
+	})
+
+	It("discards streams of unknown types", func() {
+		a := &UniStreamAcceptor{
+			Connection:             serverConn,
+			StreamTypes:            []uint64{0x42},
+			DiscardUnknown:         true,
+			UnknownStreamErrorCode: 0x1234,
+		}
+		_, _, err := a.AcceptUnknown(context.Background())
+		Expect(err).To(MatchError("quic: streams of unknown types are discarded"))
+
+		// Send a large amount of data, such that the stream is still open when the server discards it.
+		unknownStr, err := clientConn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		errChan := make(chan error, 1)
+		go func() {
+			if _, err := unknownStr.Write(quicvarint.Append(nil, 0x1337)); err != nil {
+				errChan <- err
+				return
+			}
+			for {
+				if _, err := unknownStr.Write(make([]byte, 1<<10)); err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}()
+		openStream(0x42, "foobar")
+		str, err := a.Accept(context.Background(), 0x42)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readStream(str)).To(Equal("foobar"))
+
+		var err2 error
+		Eventually(errChan).Should(Receive(&err2))
+		var streamErr *StreamError
+		Expect(errors.As(err2, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(BeEquivalentTo(0x1234))
+		Expect(streamErr.Remote).To(BeTrue())
+	})
+
+	It("returns the connection error", func() {
+		a := &UniStreamAcceptor{Connection: serverConn, StreamTypes: []uint64{0x42}}
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := a.Accept(context.Background(), 0x42)
+			errChan <- err
+		}()
+		Consistently(errChan, scaleDuration(20*time.Millisecond)).ShouldNot(Receive())
+		clientConn.CloseWithError(0x1337, "")
+		var err error
+		Eventually(errChan).Should(Receive(&err))
+		var appErr *ApplicationError
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(appErr.ErrorCode).To(BeEquivalentTo(0x1337))
+	})
+})
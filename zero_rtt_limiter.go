@@ -0,0 +1,74 @@
+package quic
+
+import (
+	"errors"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+)
+
+// errExceeded0RTTLimits is returned when handling a frame received in a 0-RTT packet would exceed
+// Config.Max0RTTData or Config.Max0RTTStreams.
+// The packet is dropped without being acknowledged, and the client retransmits its frames after the handshake.
+var errExceeded0RTTLimits = errors.New("exceeded 0-RTT limits")
+
+// The zeroRTTLimiter limits the amount of stream data and the number of streams
+// that the server accepts in 0-RTT packets.
+type zeroRTTLimiter struct {
+	maxData    protocol.ByteCount // 0 if unlimited
+	maxStreams int                // 0 if unlimited
+
+	data protocol.ByteCount
+	// the highest offset received on every stream opened by the client in 0-RTT packets
+	streams map[protocol.StreamID]protocol.ByteCount
+}
+
+// newZeroRTTLimiter creates a new zeroRTTLimiter.
+// It returns nil if no limits are configured.
+func newZeroRTTLimiter(maxData uint64, maxStreams int64) *zeroRTTLimiter {
+	if maxData == 0 && maxStreams == 0 {
+		return nil
+	}
+	return &zeroRTTLimiter{
+		maxData:    protocol.ByteCount(maxData),
+		maxStreams: int(maxStreams),
+		streams:    make(map[protocol.StreamID]protocol.ByteCount),
+	}
+}
+
+// Allow says if a frame received in a 0-RTT packet can be handled without exceeding the limits.
+// If it can, the stream data and the stream are accounted for.
+// Retransmissions of stream data are only accounted for once.
+func (l *zeroRTTLimiter) Allow(f wire.Frame) bool {
+	var id protocol.StreamID
+	var end protocol.ByteCount
+	switch frame := f.(type) {
+	case *wire.StreamFrame:
+		id = frame.StreamID
+		end = frame.Offset + frame.DataLen()
+	case *wire.ResetStreamFrame:
+		id = frame.StreamID
+	case *wire.StopSendingFrame:
+		id = frame.StreamID
+	case *wire.MaxStreamDataFrame:
+		id = frame.StreamID
+	default:
+		return true
+	}
+	if id.InitiatedBy() != protocol.PerspectiveClient {
+		return true
+	}
+	highest, ok := l.streams[id]
+	if !ok && l.maxStreams > 0 && len(l.streams) >= l.maxStreams {
+		return false
+	}
+	if end > highest {
+		if l.maxData > 0 && l.data+end-highest > l.maxData {
+			return false
+		}
+		l.data += end - highest
+		highest = end
+	}
+	l.streams[id] = highest
+	return true
+}
@@ -0,0 +1,58 @@
+package quic
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("0-RTT limiter", func() {
+	streamFrame := func(id protocol.StreamID, offset protocol.ByteCount, dataLen int) *wire.StreamFrame {
+		return &wire.StreamFrame{StreamID: id, Offset: offset, Data: make([]byte, dataLen)}
+	}
+
+	It("isn't created if no limits are configured", func() {
+		Expect(newZeroRTTLimiter(0, 0)).To(BeNil())
+	})
+
+	It("limits the amount of stream data", func() {
+		l := newZeroRTTLimiter(1000, 0)
+		Expect(l.Allow(streamFrame(0, 0, 400))).To(BeTrue())
+		Expect(l.Allow(streamFrame(4, 0, 500))).To(BeTrue())
+		Expect(l.Allow(streamFrame(8, 0, 101))).To(BeFalse())
+		Expect(l.Allow(streamFrame(0, 400, 100))).To(BeTrue())
+		Expect(l.Allow(streamFrame(0, 500, 1))).To(BeFalse())
+		// frames without stream data are not limited
+		Expect(l.Allow(&wire.ResetStreamFrame{StreamID: 8, FinalSize: 1000})).To(BeTrue())
+		Expect(l.Allow(&wire.PingFrame{})).To(BeTrue())
+	})
+
+	It("only accounts for retransmitted data once", func() {
+		l := newZeroRTTLimiter(1000, 0)
+		Expect(l.Allow(streamFrame(0, 0, 800))).To(BeTrue())
+		Expect(l.Allow(streamFrame(0, 0, 800))).To(BeTrue())
+		Expect(l.Allow(streamFrame(0, 400, 600))).To(BeTrue())
+		Expect(l.Allow(streamFrame(4, 0, 1))).To(BeFalse())
+	})
+
+	It("limits the number of streams", func() {
+		l := newZeroRTTLimiter(0, 2)
+		Expect(l.Allow(streamFrame(0, 0, 100))).To(BeTrue())
+		Expect(l.Allow(&wire.StopSendingFrame{StreamID: 4})).To(BeTrue())
+		Expect(l.Allow(streamFrame(8, 0, 100))).To(BeFalse())
+		Expect(l.Allow(&wire.ResetStreamFrame{StreamID: 2})).To(BeFalse())
+		Expect(l.Allow(&wire.MaxStreamDataFrame{StreamID: 6})).To(BeFalse())
+		// frames for streams that are already open are allowed
+		Expect(l.Allow(streamFrame(4, 0, 1<<20))).To(BeTrue())
+		Expect(l.Allow(&wire.ResetStreamFrame{StreamID: 0})).To(BeTrue())
+	})
+
+	It("doesn't account for server-initiated streams", func() {
+		l := newZeroRTTLimiter(0, 1)
+		Expect(l.Allow(&wire.MaxStreamDataFrame{StreamID: 1})).To(BeTrue())
+		Expect(l.Allow(&wire.StopSendingFrame{StreamID: 3})).To(BeTrue())
+		Expect(l.Allow(streamFrame(0, 0, 100))).To(BeTrue())
+	})
+})